@@ -13,6 +13,8 @@
 //    - ローカルのノート操作を担当
 //    - ノートの作成、読み込み、保存、削除
 //    - ノートリストの管理とメタデータの同期
+//    - 全文検索インデックスの管理
+//    - ノートの変更履歴（リビジョン）の管理
 //
 // 3. FileNoteService (file_note_service.go)
 //    - ローカルのファイルノート操作を担当
@@ -41,6 +43,15 @@
 // - app.go: メインアプリケーションロジック
 // - auth_service.go: 認証管理の実装
 // - note_service.go: ノート操作の実装
+// - search_index.go: ノート全文検索インデックスの実装
+// - note_revision_store.go: ノート変更履歴の保存と復元
+// - note_tags.go: ノートのタグ操作と同期時のタグのマージ
+// - folder_tree.go: フォルダの入れ子（親子関係）の操作と修復
+// - text_diff.go: 行単位のテキスト差分
+// - note_merge.go: 両端末で編集されたノートの 3-way マージ（前回同期時点の本文を base に行単位で合わせる）
+// - conflict_resolution.go: 競合バックアップと現在のノートの比較、解決方法の適用
+// - sync_journal.go: 同期の実行ごとのきっかけとノート単位の処理の履歴
+// - local_only.go: 同期しない（この端末だけに置く）ノート・フォルダの扱い
 // - drive_service.go: Google Drive連携の中核実装
 // - drive_sync_service.go: 同期ロジックの中レベル実装
 // - drive_operations.go: Drive操作の低レベル実装
@@ -48,6 +59,26 @@
 // - settings_service.go: 設定管理の実装
 // - file_note_service.go: ファイルノート操作の実装
 // - file_service.go: ファイル操作の実装
+// - notes_cli.go: ウィンドウを開かずにノートを操作する notes サブコマンド
+// - data_dir_lock.go: GUI と CLI が appDataDir を同時に書き換えないための排他ロック
+// - local_api_server.go: 自動化ツール向けのローカル HTTP API（opt-in、ループバックのみ）
+// - path_operations.go: パスをファイルIDとして扱う DriveOperations の共通部品
+// - webdav_operations.go: WebDAV サーバー上で DriveOperations を実装（PROPFIND/ETag で変更検出）
+// - local_folder_operations.go: 任意のディレクトリ上で DriveOperations を実装（走査と MD5 で変更検出）
+// - drive_sync_provider.go: 同期プロバイダーの切り替えと接続設定の保存
+// - sync_encryption.go: 同期データのエンドツーエンド暗号化（scrypt + AES-GCM、鍵確認ファイル）
+// - note_lock.go: ノート単位の暗号化とロック解除セッション
+// - note_trash.go: ゴミ箱（削除したノート・フォルダの保持、復元、期限切れの削除、同期時のマージ）
+// - vault_export.go: 全ノートの Markdown 保管庫形式での書き出し（フォルダ=ディレクトリ、front matter）
+// - note_import.go: ディレクトリ・Obsidian 保管庫・ENEX からの一括取り込み（ドライラン、内容の重複除外）
+// - data_backup.go: ローカルデータの zip バックアップと復元（manifest のハッシュ検証、自動バックアップ）
+// - file_watcher.go: 開いているファイルの外部での変更・名前の変更・削除の監視（fsnotify）
+// - profiles.go: プロファイル（仕事用・個人用など）ごとのデータディレクトリの一覧と作成
+// - file_encoding.go: ファイルノートの文字コード・BOM・改行コードの検出と、同じ形式での書き戻し
+// - large_file.go: 大きなファイルの読み取り専用モード（行の索引による部分読み込み、検索、追記の追従）
+// - binary_file.go: バイナリファイルの判定（マジックナンバー・NUL・読めないバイト）と 16 進ダンプ
+// - safe_save.go: ファイルノートの安全な保存（一時ファイルと rename、上書き前のバックアップ、外部での変更の 3-way 比較）
+// - linked_files.go: ノートとこの端末のファイルのリンク（保存時の書き出し、外部での変更の取り込み、競合バックアップ）
 
 package backend
 
@@ -59,6 +90,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"time"
 
@@ -93,6 +125,18 @@ func (c *Context) ShouldSkipBeforeClose() bool {
 	return c.skipBeforeClose
 }
 
+// 既定のアプリケーションデータディレクトリのパスを返す（GUI と CLI で共通） ------------------------------------------------------------
+func defaultAppDataDir() string {
+	appData, err := os.UserConfigDir()
+	if err != nil {
+		appData, err = os.UserHomeDir()
+		if err != nil {
+			appData = "."
+		}
+	}
+	return filepath.Join(appData, "monaco-notepad")
+}
+
 // 新しいAppインスタンスを作成 ------------------------------------------------------------
 func NewApp() *App {
 	return &App{
@@ -109,16 +153,9 @@ func NewApp() *App {
 func (a *App) Startup(ctx context.Context) {
 	a.ctx.ctx = ctx
 
-	// アプリケーションデータディレクトリの設定
-	appData, err := os.UserConfigDir()
-	if err != nil {
-		appData, err = os.UserHomeDir()
-		if err != nil {
-			appData = "."
-		}
-	}
-
-	a.appDataDir = filepath.Join(appData, "monaco-notepad")
+	// アプリケーションデータディレクトリの設定（使用中のプロファイルのディレクトリ）
+	a.rootDataDir = defaultAppDataDir()
+	a.appDataDir = activeProfileDataDir(a.rootDataDir)
 	a.notesDir = filepath.Join(a.appDataDir, "notes")
 
 	// ディレクトリの作成
@@ -130,77 +167,268 @@ func (a *App) Startup(ctx context.Context) {
 	// ロガー初期化後に出力して初期化前アクセスを避ける
 	a.logger.Console("appDataDir: %s", a.appDataDir)
 
-	// FileServiceの初期化
+	// フロントエンドへの通知をローカル API のイベントストリームにも流す
+	a.attachLocalAPIEvents()
+
+	// notes サブコマンドの実行中は終わるまで待ち、以後は終了までロックを保持する
+	// ロックなしで動くと CLI の変更を上書きしてしまうため、取得できなければ起動しない
+	lock, err := waitDataDirLock(ctx, a.appDataDir, dataDirLockStartupWait, func(err error) {
+		a.logger.Console("Waiting for app data directory: %v", err)
+	})
+	if err != nil {
+		a.logger.Console("Failed to lock app data directory: %v", err)
+		wailsRuntime.MessageDialog(ctx, wailsRuntime.MessageDialogOptions{
+			Type:    wailsRuntime.ErrorDialog,
+			Title:   "Monaco Notepad",
+			Message: fmt.Sprintf("Failed to lock the app data directory.\n%s\n\n%v", a.appDataDir, err),
+		})
+		a.ctx.SkipBeforeClose(true)
+		wailsRuntime.Quit(ctx)
+		return
+	}
+	a.dataDirLock = lock
+
+	// FileServiceの初期化（ファイルを上書きする前のバックアップは設定で有効な場合のみ）
 	a.fileService = NewFileService(a.ctx)
+	a.fileService.beforeOverwrite = a.backupFileBeforeSave
+
+	// 大きなファイルの読み取り専用モード（行の索引と追記の追従）
+	a.largeFiles = newLargeFileService(func(event string, data interface{}) {
+		wailsRuntime.EventsEmit(ctx, event, data)
+	})
+
+	// 設定・ノート・同期状態などデータディレクトリ上のサービスの初期化
+	a.openDataServices()
+
+	// 保持期間を過ぎたゴミ箱のアイテムを削除
+	a.purgeExpiredTrash()
 
-	// SettingsServiceの初期化
-	a.settingsService = NewSettingsService(a.appDataDir)
+	// ファイルの監視・CLI inbox・自動バックアップ・リンクしたファイルの確認
+	a.startBackgroundLoops()
+}
 
-	// FileNoteServiceの初期化
-	a.fileNoteService = NewFileNoteService(a.appDataDir)
+// appDataDir 上のサービス（設定・ファイルノート・ノート・同期状態）を初期化する
+// 起動時、ローカルデータの削除後、プロファイルの切り替え時に呼ぶ。
+// 作り終えてから servicesMu の下でまとめて差し替えるので、並行するバインディングは
+// 切り替え前か後のどちらかのサービスだけを使う。
+func (a *App) openDataServices() {
+	appDataDir, notesDir := a.currentAppDataDir(), a.currentNotesDir()
 
-	// RecentFilesServiceの初期化
-	a.recentFilesService = NewRecentFilesService(a.appDataDir)
+	// SettingsService・FileNoteService・RecentFilesServiceの初期化
+	settingsService := NewSettingsService(appDataDir)
+	fileNoteService := NewFileNoteService(appDataDir)
+	recentFilesService := NewRecentFilesService(appDataDir)
 
-	migrated, err := migration.RunIfNeeded(a.appDataDir, a.notesDir)
+	migrated, err := migration.RunIfNeeded(appDataDir, notesDir)
 	if err != nil {
 		a.logger.Console("Warning: migration failed: %v", err)
 	} else if migrated {
-		a.migrationMessage = "noteList v1 → v2 migration completed"
+		a.migrationMessage = "noteList migration completed"
 		a.logger.Console(a.migrationMessage)
 	}
 
 	// NoteServiceの初期化 (NoteList読み込みを含む)
-	ns, err := NewNoteService(a.notesDir, a.logger)
+	ns, err := NewNoteService(notesDir, a.logger)
 	if err != nil {
 		a.logger.ErrorCode(err, MsgSystemNoteServiceInitFailed, nil)
 		a.logger.Console("Creating empty note service as last resort")
-		ns = NewEmptyNoteService(a.notesDir, a.logger)
+		ns = NewEmptyNoteService(notesDir, a.logger)
 	}
-	a.noteService = ns
+	// 同期などで書き込まれたノートをリンクしたファイルに反映する
+	ns.SetNoteWrittenHandler(a.onNoteWritten)
 
 	// SyncStateの初期化
-	a.syncState = NewSyncState(a.appDataDir)
-	if err := a.syncState.Load(); err != nil {
+	syncState := NewSyncState(appDataDir)
+	if err := syncState.Load(); err != nil {
 		a.logger.Console("Warning: failed to load sync state: %v", err)
 	}
+
+	// ノートにリンクしたファイル（この端末だけ）
+	ctx := a.ctx.ctx
+	linkedFiles := newLinkedFileService(appDataDir, func(event string, data interface{}) {
+		wailsRuntime.EventsEmit(ctx, event, data)
+	})
+
+	a.servicesMu.Lock()
+	defer a.servicesMu.Unlock()
+	a.settingsService = settingsService
+	a.fileNoteService = fileNoteService
+	a.recentFilesService = recentFilesService
+	a.noteService = ns
+	a.syncState = syncState
+	a.linkedFiles = linkedFiles
 }
 
-// フロントエンドにDOMが読み込まれたときに呼び出される関数 ------------------------------------------------------------
-func (a *App) DomReady(ctx context.Context) {
-	a.logger.Console("DomReady called")
+// reopenDataDir は dataDir でログの出力先とサービスを作り直し、バックグラウンドの処理を始める
+// ローカルデータの削除後とプロファイルの切り替え時に、stopBackgroundLoops の後で呼ぶ。
+func (a *App) reopenDataDir(dataDir string) {
+	a.logger.SetLogDir(dataDir)
+	a.servicesMu.Lock()
+	a.appDataDir = dataDir
+	a.notesDir = filepath.Join(dataDir, "notes")
+	a.servicesMu.Unlock()
 
-	// ネイティブメニューの言語を設定に合わせて反映
-	if settings, err := a.settingsService.LoadSettings(); err == nil {
-		a.applyNativeMenuLocalization(settings.UILanguage)
-	} else {
-		a.applyNativeMenuLocalization(LocaleSystem)
+	a.openDataServices()
+	a.purgeExpiredTrash()
+	a.startBackgroundLoops()
+}
+
+// 使用中のプロファイルのデータディレクトリとサービスを返す
+// SwitchProfile / DeleteLocalAppData が servicesMu の下で差し替えるため、
+// バインディングやバックグラウンドの処理はフィールドを直接読まずにこれらを使う。
+func (a *App) currentAppDataDir() string {
+	a.servicesMu.RLock()
+	defer a.servicesMu.RUnlock()
+	return a.appDataDir
+}
+
+func (a *App) currentNotesDir() string {
+	a.servicesMu.RLock()
+	defer a.servicesMu.RUnlock()
+	return a.notesDir
+}
+
+func (a *App) currentNoteService() *noteService {
+	a.servicesMu.RLock()
+	defer a.servicesMu.RUnlock()
+	return a.noteService
+}
+
+func (a *App) currentSyncState() *SyncState {
+	a.servicesMu.RLock()
+	defer a.servicesMu.RUnlock()
+	return a.syncState
+}
+
+// currentNoteServices はノートサービスと同期状態を同じプロファイルの組として返す
+// （別々に読むと、間に SwitchProfile が入ったときに新旧のプロファイルが混ざる）
+func (a *App) currentNoteServices() (*noteService, *SyncState) {
+	a.servicesMu.RLock()
+	defer a.servicesMu.RUnlock()
+	return a.noteService, a.syncState
+}
+
+func (a *App) currentSettingsService() *settingsService {
+	a.servicesMu.RLock()
+	defer a.servicesMu.RUnlock()
+	return a.settingsService
+}
+
+func (a *App) currentFileNoteService() *fileNoteService {
+	a.servicesMu.RLock()
+	defer a.servicesMu.RUnlock()
+	return a.fileNoteService
+}
+
+func (a *App) currentRecentFilesService() *recentFilesService {
+	a.servicesMu.RLock()
+	defer a.servicesMu.RUnlock()
+	return a.recentFilesService
+}
+
+func (a *App) currentFileWatcher() *fileWatcher {
+	a.servicesMu.RLock()
+	defer a.servicesMu.RUnlock()
+	return a.fileWatcher
+}
+
+func (a *App) currentLinkedFiles() *linkedFileService {
+	a.servicesMu.RLock()
+	defer a.servicesMu.RUnlock()
+	return a.linkedFiles
+}
+
+func (a *App) currentAuthService() AuthService {
+	a.servicesMu.RLock()
+	defer a.servicesMu.RUnlock()
+	return a.authService
+}
+
+func (a *App) currentDriveService() DriveService {
+	a.servicesMu.RLock()
+	defer a.servicesMu.RUnlock()
+	return a.driveService
+}
+
+// 最後に選択されたノートを返す
+func (a *App) lastActiveNote() (string, bool) {
+	a.servicesMu.RLock()
+	defer a.servicesMu.RUnlock()
+	return a.lastActiveNoteId, a.lastActiveNoteIsFile
+}
+
+// startBackgroundLoops は使用中のプロファイルのファイルの監視・CLI inbox・自動バックアップを始める
+// プロファイルを切り替える前に stopBackgroundLoops で止める。
+func (a *App) startBackgroundLoops() {
+	// 開いているファイルとリンクしたファイルの外部での変更の監視
+	a.startFileWatcher()
+
+	// 起動中に notes サブコマンドから渡されたコマンドを受け取る
+	a.startNotesCLIInbox()
+
+	ctx, cancel := context.WithCancel(a.ctx.ctx)
+	a.backgroundCancel = cancel
+	a.backgroundLoops.Add(1)
+
+	// 設定で有効な場合は定期的にローカルデータを自動バックアップ
+	go func() {
+		defer a.backgroundLoops.Done()
+		a.runAutoBackupLoop(ctx)
+	}()
+}
+
+// stopBackgroundLoops はバックグラウンドの処理を止め、実行中のバックアップの終了を待つ
+func (a *App) stopBackgroundLoops() {
+	if a.backgroundCancel != nil {
+		a.backgroundCancel()
+		a.backgroundCancel = nil
 	}
+	a.backgroundLoops.Wait()
+	a.stopNotesCLIInbox()
+	a.stopFileWatcher()
+}
+
+// 認証・同期サービスを作成し、フロントエンドの準備完了後に保存済みの接続で同期を始める
+// サービスは ctx から派生したコンテキストで動かし、stopSyncServices でまとめて止める。
+func (a *App) startSyncServices(ctx context.Context) {
+	syncCtx, cancel := context.WithCancel(ctx)
+	a.syncCancel = cancel
+
+	appDataDir, notesDir := a.currentAppDataDir(), a.currentNotesDir()
+	noteService := a.currentNoteService()
+
+	// 暗号化したノートがセッション切れでロックされたらフロントエンドに知らせる
+	noteService.SetNoteLockedHandler(func(noteID string) {
+		wailsRuntime.EventsEmit(ctx, "note:locked", noteID)
+	})
 
 	// AuthServiceの初期化
 	authService := NewAuthService(
-		ctx,
-		a.appDataDir,
-		a.notesDir,
-		a.noteService,
+		syncCtx,
+		appDataDir,
+		notesDir,
+		noteService,
 		credentialsJSON,
 		a.logger,
 		false,
 	)
-	a.authService = authService
 
 	// DriveServiceの初期化
 	driveService := NewDriveService(
-		ctx,
-		a.appDataDir,
-		a.notesDir,
-		a.noteService,
+		syncCtx,
+		appDataDir,
+		notesDir,
+		noteService,
 		credentialsJSON,
 		a.logger,
 		authService,
-		a.syncState,
+		a.currentSyncState(),
 	)
+
+	a.servicesMu.Lock()
+	a.authService = authService
 	a.driveService = driveService
+	a.servicesMu.Unlock()
 
 	// Google Driveの初期化はフロントエンド準備完了後に実行
 	// （DomReady時点ではReactのuseEffect登録が完了していない可能性があるため）
@@ -210,13 +438,48 @@ func (a *App) DomReady(ctx context.Context) {
 				a.logger.Console(fmt.Sprintf("PANIC in Drive initialization: %v\n%s", r, string(debug.Stack())))
 			}
 		}()
-		<-authService.GetFrontendReadyChan()
+		select {
+		case <-authService.GetFrontendReadyChan():
+		case <-syncCtx.Done():
+			return
+		}
 		a.logger.Console("Frontend ready - initializing Google Drive...")
 		if err := driveService.InitializeDrive(); err != nil {
 			a.logger.ErrorCode(err, MsgSystemDriveInitFailed, nil)
 			wailsRuntime.EventsEmit(ctx, "drive:status", "offline")
 		}
 	}()
+}
+
+// 同期サービスを止める（ポーリング・操作キューを止め、進行中の同期の終了を待つ）
+func (a *App) stopSyncServices() {
+	if a.syncCancel != nil {
+		a.syncCancel()
+		a.syncCancel = nil
+	}
+	if driveService := a.currentDriveService(); driveService != nil {
+		driveService.Shutdown()
+	}
+}
+
+// フロントエンドにDOMが読み込まれたときに呼び出される関数 ------------------------------------------------------------
+func (a *App) DomReady(ctx context.Context) {
+	a.logger.Console("DomReady called")
+
+	// ネイティブメニューの言語を設定に合わせて反映
+	if settings, err := a.currentSettingsService().LoadSettings(); err == nil {
+		a.applyNativeMenuLocalization(settings.UILanguage)
+	} else {
+		a.applyNativeMenuLocalization(LocaleSystem)
+	}
+
+	// 認証・同期サービスの初期化
+	a.startSyncServices(ctx)
+
+	// ローカル HTTP API（設定で有効な場合のみ）
+	if err := a.startLocalAPIIfEnabled(); err != nil {
+		a.logger.Console("Failed to start local API: %v", err)
+	}
 
 	a.logger.Console("Emitting backend:ready event")
 	wailsRuntime.EventsEmit(ctx, "backend:ready")
@@ -224,6 +487,10 @@ func (a *App) DomReady(ctx context.Context) {
 
 // アプリケーション終了前に呼び出される処理 ------------------------------------------------------------
 func (a *App) BeforeClose(ctx context.Context) (prevent bool) {
+	// 終了後に CLI が応答を待ち続けないよう、先に inbox を含むバックグラウンドの処理を止める
+	a.profileMu.Lock()
+	a.stopBackgroundLoops()
+	a.profileMu.Unlock()
 	if a.ctx.ShouldSkipBeforeClose() {
 		return false
 	}
@@ -232,7 +499,8 @@ func (a *App) BeforeClose(ctx context.Context) (prevent bool) {
 	wailsRuntime.EventsEmit(ctx, "app:beforeclose")
 
 	// ウィンドウの状態と最後に選択されたノート情報を保存
-	settings, err := a.settingsService.LoadSettings()
+	settingsService := a.currentSettingsService()
+	settings, err := settingsService.LoadSettings()
 	if err != nil {
 		return false
 	}
@@ -253,10 +521,9 @@ func (a *App) BeforeClose(ctx context.Context) (prevent bool) {
 	settings.WindowX = x
 	settings.WindowY = y
 
-	settings.LastActiveNoteId = a.lastActiveNoteId
-	settings.LastActiveNoteIsFile = a.lastActiveNoteIsFile
+	settings.LastActiveNoteId, settings.LastActiveNoteIsFile = a.lastActiveNote()
 
-	a.settingsService.SaveSettings(settings)
+	settingsService.SaveSettings(settings)
 
 	return false
 }
@@ -271,9 +538,11 @@ func (a *App) DestroyApp() {
 
 // フロントエンドの準備完了を通知する ------------------------------------------------------------
 func (a *App) NotifyFrontendReady() {
+	driveService := a.currentDriveService()
+	noteService := a.currentNoteService()
 	a.logger.Console("App.NotifyFrontendReady called")
-	if a.driveService != nil {
-		a.driveService.NotifyFrontendReady()
+	if driveService != nil {
+		driveService.NotifyFrontendReady()
 	} else {
 		a.logger.Console("Warning: driveService is nil")
 	}
@@ -281,8 +550,8 @@ func (a *App) NotifyFrontendReady() {
 		a.logger.Info(a.migrationMessage)
 		a.migrationMessage = ""
 	}
-	if a.noteService != nil {
-		if recovery := a.noteService.DrainRecoveryApplied(); recovery != "" {
+	if noteService != nil {
+		if recovery := noteService.DrainRecoveryApplied(); recovery != "" {
 			switch recovery {
 			case "rebuild":
 				a.logger.NotifyIntegrityIssues(a.ctx.ctx, []IntegrityIssue{
@@ -299,11 +568,11 @@ func (a *App) NotifyFrontendReady() {
 			}
 		}
 
-		if repairs := a.noteService.DrainPendingIntegrityRepairs(); len(repairs) > 0 {
+		if repairs := noteService.DrainPendingIntegrityRepairs(); len(repairs) > 0 {
 			a.logger.InfoCode(MsgSystemIntegrityAutoRepaired, map[string]interface{}{"count": len(repairs)})
 		}
 
-		if recoveries := a.noteService.DrainPendingOrphanRecoveries(); len(recoveries) > 0 {
+		if recoveries := noteService.DrainPendingOrphanRecoveries(); len(recoveries) > 0 {
 			for _, r := range recoveries {
 				if r.Source == "local" {
 					a.logger.InfoCode(MsgOrphanLocalRecoveryDone, map[string]interface{}{
@@ -315,7 +584,7 @@ func (a *App) NotifyFrontendReady() {
 			wailsRuntime.EventsEmit(a.ctx.ctx, "notes:orphans-recovered", recoveries)
 		}
 
-		if issues := a.noteService.DrainPendingIntegrityIssues(); len(issues) > 0 {
+		if issues := noteService.DrainPendingIntegrityIssues(); len(issues) > 0 {
 			a.logger.NotifyIntegrityIssues(a.ctx.ctx, issues)
 		}
 	}
@@ -332,36 +601,93 @@ func (a *App) Console(format string, args ...interface{}) {
 
 // 全てのノートのリストを返す ------------------------------------------------------------
 func (a *App) ListNotes() ([]Note, error) {
-	return a.noteService.ListNotes()
+	return a.currentNoteService().ListNotes()
+}
+
+// ノートを全文検索する ------------------------------------------------------------
+func (a *App) SearchNotes(query string, options SearchOptions) ([]SearchHit, error) {
+	return a.currentNoteService().SearchNotes(query, options)
 }
 
 // 指定されたIDのノートを読み込む ------------------------------------------------------------
 func (a *App) LoadNote(id string) (*Note, error) {
-	return a.noteService.LoadNote(id)
+	return a.currentNoteService().LoadNote(id)
 }
 
 // ノートを保存する（アーカイブも含む） ------------------------------------------------------------
 func (a *App) SaveNote(note *Note, action string) error {
+	noteService, syncState := a.currentNoteServices()
 	if action != "create" {
 		action = "update"
 	}
 
 	// まずノートサービスでローカルに保存
-	if err := a.noteService.SaveNote(note); err != nil {
+	if err := noteService.SaveNote(note); err != nil {
 		return err
 	}
+	return a.noteSaved(syncState, note.ID)
+}
+
+// noteSaved はローカルに保存したノートを同期対象に積み、リンクしたファイルにも書き出す
+// ローカル API のように noteService で直接保存した場合も、これを呼んで SaveNote と同じ扱いにする。
+func (a *App) noteSaved(syncState *SyncState, noteID string) error {
+	if syncState != nil {
+		syncState.MarkNoteDirty(noteID)
+	}
 
-	if a.syncState != nil {
-		a.syncState.MarkNoteDirty(note.ID)
+	a.triggerSyncIfConnected()
+
+	// リンクしたファイルにも書き出す（外部でも変更されていればノートを優先し、ファイルの内容は競合バックアップに残す）
+	if a.currentLinkedFiles() != nil {
+		if err := a.syncLinkedFile(noteID, true); err != nil {
+			return fmt.Errorf("note saved but failed to update linked file: %w", err)
+		}
 	}
+	return nil
+}
 
+// ノートをパスフレーズで暗号化する ------------------------------------------------------------
+// 暗号化後は一定時間ロック解除された状態になり、その間は平文のまま SaveNote できる。
+func (a *App) EncryptNote(id string, passphrase string) error {
+	noteService, syncState := a.currentNoteServices()
+	if err := noteService.EncryptNote(id, passphrase); err != nil {
+		return err
+	}
+	if syncState != nil {
+		syncState.MarkNoteDirty(id)
+	}
 	a.triggerSyncIfConnected()
 	return nil
 }
 
+// 暗号化したノートのロックを解除し、平文の本文を返す ------------------------------------------------------------
+// 一定時間操作が無いと自動でロックされ、"note:locked" イベントで通知する。
+func (a *App) UnlockNote(id string, passphrase string) (*Note, error) {
+	return a.currentNoteService().UnlockNote(id, passphrase)
+}
+
+// 暗号化したノートをただちにロックする ------------------------------------------------------------
+func (a *App) LockNote(id string) {
+	a.currentNoteService().LockNote(id)
+}
+
+// ノートの暗号化を解除して平文に戻す ------------------------------------------------------------
+func (a *App) DecryptNote(id string, passphrase string) (*Note, error) {
+	noteService, syncState := a.currentNoteServices()
+	note, err := noteService.DecryptNote(id, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if syncState != nil {
+		syncState.MarkNoteDirty(id)
+	}
+	a.triggerSyncIfConnected()
+	return note, nil
+}
+
 // 整合性修復の選択を適用する ------------------------------------------------------------
 func (a *App) ApplyIntegrityFixes(selections []IntegrityFixSelection) (IntegrityRepairSummary, error) {
-	summary, err := a.noteService.ApplyIntegrityFixes(selections)
+	summary, err := a.currentNoteService().ApplyIntegrityFixes(selections)
 	if err != nil {
 		return summary, err
 	}
@@ -373,30 +699,92 @@ func (a *App) ApplyIntegrityFixes(selections []IntegrityFixSelection) (Integrity
 
 // ノートリストを保存する ------------------------------------------------------------
 func (a *App) SaveNoteList() error {
+	noteService, syncState := a.currentNoteServices()
 	a.logger.Console("SaveNoteList called")
 
 	// まずノートサービスでローカルに保存
-	if err := a.noteService.saveNoteList(); err != nil {
+	if err := noteService.saveNoteList(); err != nil {
 		return err
 	}
 
-	if a.syncState != nil {
-		a.syncState.MarkDirty()
+	if syncState != nil {
+		syncState.MarkDirty()
 	}
 
 	a.triggerSyncIfConnected()
 	return nil
 }
 
-// 指定されたIDのノートを削除する ------------------------------------------------------------
+// 指定されたIDのノートをゴミ箱に移す ------------------------------------------------------------
+// ファイルはゴミ箱を空にするか保持期間が過ぎるまで残す (noteList の変更として同期する)。
 func (a *App) DeleteNote(id string) error {
-	// まずノートサービスで削除
-	if err := a.noteService.DeleteNote(id); err != nil {
+	noteService, syncState := a.currentNoteServices()
+	if err := noteService.TrashNote(id); err != nil {
 		return err
 	}
 
-	if a.syncState != nil {
-		a.syncState.MarkNoteDeleted(id)
+	if syncState != nil {
+		syncState.MarkDirty()
+	}
+
+	a.triggerSyncIfConnected()
+	return nil
+}
+
+// ノートの変更履歴を新しい順に返す ------------------------------------------------------------
+func (a *App) ListNoteRevisions(noteID string) ([]NoteRevision, error) {
+	return a.currentNoteService().ListNoteRevisions(noteID)
+}
+
+// 指定されたリビジョンを本文付きで返す ------------------------------------------------------------
+func (a *App) GetNoteRevision(noteID string, revisionID string) (*NoteRevision, error) {
+	return a.currentNoteService().GetNoteRevision(noteID, revisionID)
+}
+
+// 2 つのリビジョンの unified diff を返す（toRevisionID が空なら現在のノートと比較） ------------------------------------------------------------
+func (a *App) DiffNoteRevisions(noteID string, fromRevisionID string, toRevisionID string) (string, error) {
+	return a.currentNoteService().DiffNoteRevisions(noteID, fromRevisionID, toRevisionID)
+}
+
+// 指定されたリビジョンの内容でノートを復元する ------------------------------------------------------------
+// 通常の保存と同じく dirty を立てて同期するため、他の端末にも復元結果が反映される。
+func (a *App) RestoreNoteRevision(noteID string, revisionID string) (*Note, error) {
+	noteService, syncState := a.currentNoteServices()
+	note, err := noteService.RestoreNoteRevision(noteID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if syncState != nil {
+		syncState.MarkNoteDirty(noteID)
+	}
+
+	a.triggerSyncIfConnected()
+	return note, nil
+}
+
+// 全てのタグを使用ノート数付きで返す ------------------------------------------------------------
+func (a *App) ListTags() []TagCount {
+	return a.currentNoteService().ListTags()
+}
+
+// 指定タグが付いたノートのリストを返す ------------------------------------------------------------
+func (a *App) ListNotesByTag(tag string) ([]Note, error) {
+	return a.currentNoteService().ListNotesByTag(tag)
+}
+
+// タグを全ノートで改名する（既存のタグ名を指定するとマージ） ------------------------------------------------------------
+func (a *App) RenameTag(oldTag string, newTag string) error {
+	noteService, syncState := a.currentNoteServices()
+	changedIDs, err := noteService.RenameTag(oldTag, newTag)
+	if syncState != nil {
+		// 途中で失敗しても、書き換え済みのノートは同期対象に積む
+		for _, id := range changedIDs {
+			syncState.MarkNoteDirty(id)
+		}
+	}
+	if err != nil {
+		return err
 	}
 
 	a.triggerSyncIfConnected()
@@ -405,18 +793,19 @@ func (a *App) DeleteNote(id string) error {
 
 // アーカイブされたノートの完全なデータを読み込む ------------------------------------------------------------
 func (a *App) LoadArchivedNote(id string) (*Note, error) {
-	return a.noteService.LoadArchivedNote(id)
+	return a.currentNoteService().LoadArchivedNote(id)
 }
 
 // ノートの順序を更新する ------------------------------------------------------------
 func (a *App) UpdateNoteOrder(noteID string, newIndex int) error {
+	noteService, syncState := a.currentNoteServices()
 	a.logger.Console("UpdateNoteOrder called")
-	if err := a.noteService.UpdateNoteOrder(noteID, newIndex); err != nil {
+	if err := noteService.UpdateNoteOrder(noteID, newIndex); err != nil {
 		return fmt.Errorf("error updating note order: %v", err)
 	}
 
-	if a.syncState != nil {
-		a.syncState.MarkDirty()
+	if syncState != nil {
+		syncState.MarkDirty()
 	}
 
 	a.triggerSyncIfConnected()
@@ -425,46 +814,101 @@ func (a *App) UpdateNoteOrder(noteID string, newIndex int) error {
 
 // フォルダのリストを返す ------------------------------------------------------------
 func (a *App) ListFolders() []Folder {
-	return a.noteService.ListFolders()
+	return a.currentNoteService().ListFolders()
 }
 
 // フォルダを作成する ------------------------------------------------------------
 func (a *App) CreateFolder(name string) (*Folder, error) {
-	folder, err := a.noteService.CreateFolder(name)
+	noteService, syncState := a.currentNoteServices()
+	folder, err := noteService.CreateFolder(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if syncState != nil {
+		syncState.MarkDirty()
+	}
+
+	a.triggerSyncIfConnected()
+	return folder, nil
+}
+
+// 親フォルダを指定してフォルダを作成する ------------------------------------------------------------
+func (a *App) CreateSubfolder(parentID string, name string) (*Folder, error) {
+	noteService, syncState := a.currentNoteServices()
+	folder, err := noteService.CreateSubfolder(parentID, name)
 	if err != nil {
 		return nil, err
 	}
 
-	if a.syncState != nil {
-		a.syncState.MarkDirty()
+	if syncState != nil {
+		syncState.MarkDirty()
 	}
 
 	a.triggerSyncIfConnected()
 	return folder, nil
 }
 
+// フォルダを別のフォルダの下へ移動する ------------------------------------------------------------
+func (a *App) MoveFolder(folderID string, parentID string) error {
+	noteService, syncState := a.currentNoteServices()
+	localOnlyBefore, _ := noteService.LocalOnlyIDs()
+	if err := noteService.MoveFolder(folderID, parentID); err != nil {
+		return err
+	}
+
+	if syncState != nil {
+		syncState.MarkDirty()
+		markLocalOnlyChanges(noteService, syncState, localOnlyBefore)
+	}
+
+	a.triggerSyncIfConnected()
+	return nil
+}
+
+// 同じ親を持つフォルダの並び順を更新する ------------------------------------------------------------
+func (a *App) UpdateFolderOrder(parentID string, folderIDs []string) error {
+	noteService, syncState := a.currentNoteServices()
+	if err := noteService.UpdateFolderOrder(parentID, folderIDs); err != nil {
+		return err
+	}
+
+	if syncState != nil {
+		syncState.MarkDirty()
+	}
+
+	a.triggerSyncIfConnected()
+	return nil
+}
+
 // フォルダ名を変更する ------------------------------------------------------------
 func (a *App) RenameFolder(id string, name string) error {
-	if err := a.noteService.RenameFolder(id, name); err != nil {
+	noteService, syncState := a.currentNoteServices()
+	if err := noteService.RenameFolder(id, name); err != nil {
 		return err
 	}
 
-	if a.syncState != nil {
-		a.syncState.MarkDirty()
+	if syncState != nil {
+		syncState.MarkDirty()
 	}
 
 	a.triggerSyncIfConnected()
 	return nil
 }
 
-// フォルダを削除する ------------------------------------------------------------
+// フォルダをゴミ箱に移す（ノートも子フォルダも無い場合のみ） ------------------------------------------------------------
 func (a *App) DeleteFolder(id string) error {
-	if err := a.noteService.DeleteFolder(id); err != nil {
+	noteService, syncState := a.currentNoteServices()
+	folderIDs, noteIDs := noteService.CollectFolderSubtree(id)
+	if len(folderIDs) > 1 || len(noteIDs) > 0 {
+		return errFolderNotEmpty
+	}
+	if err := noteService.TrashFolder(id); err != nil {
 		return err
 	}
 
-	if a.syncState != nil {
-		a.syncState.MarkFolderDeleted(id)
+	if syncState != nil {
+		syncState.MarkDirty()
 	}
 
 	a.triggerSyncIfConnected()
@@ -473,36 +917,85 @@ func (a *App) DeleteFolder(id string) error {
 
 // ノートをフォルダに移動する ------------------------------------------------------------
 func (a *App) MoveNoteToFolder(noteID string, folderID string) error {
-	if err := a.noteService.MoveNoteToFolder(noteID, folderID); err != nil {
+	noteService, syncState := a.currentNoteServices()
+	if err := noteService.MoveNoteToFolder(noteID, folderID); err != nil {
+		return err
+	}
+
+	// 同期しないフォルダへ移したノートは次の同期でクラウドから消え、そこから出したノートは上がる
+	if syncState != nil {
+		syncState.MarkDirty()
+		syncState.MarkNoteDirty(noteID)
+	}
+
+	a.triggerSyncIfConnected()
+	return nil
+}
+
+// ノートを同期しない（この端末だけに置く）かどうかを切り替える ------------------------------------------------------------
+// 同期済みのノートに印を付けると、次の同期でクラウドから消す（ローカルには残す）
+func (a *App) SetNoteLocalOnly(noteID string, localOnly bool) error {
+	noteService, syncState := a.currentNoteServices()
+	localOnlyBefore, _ := noteService.LocalOnlyIDs()
+	if err := noteService.SetNoteLocalOnly(noteID, localOnly); err != nil {
+		return err
+	}
+
+	if syncState != nil {
+		syncState.MarkDirty()
+		markLocalOnlyChanges(noteService, syncState, localOnlyBefore)
+	}
+
+	a.triggerSyncIfConnected()
+	return nil
+}
+
+// フォルダ（配下のフォルダとノートを含む）を同期しないかどうかを切り替える ------------------------------------------------------------
+func (a *App) SetFolderLocalOnly(folderID string, localOnly bool) error {
+	noteService, syncState := a.currentNoteServices()
+	localOnlyBefore, _ := noteService.LocalOnlyIDs()
+	if err := noteService.SetFolderLocalOnly(folderID, localOnly); err != nil {
 		return err
 	}
 
-	if a.syncState != nil {
-		a.syncState.MarkDirty()
-		a.syncState.MarkNoteDirty(noteID)
+	if syncState != nil {
+		syncState.MarkDirty()
+		markLocalOnlyChanges(noteService, syncState, localOnlyBefore)
 	}
 
 	a.triggerSyncIfConnected()
 	return nil
 }
 
+// markLocalOnlyChanges は同期対象に戻ったノートを dirty にして、次の同期で上げる。
+// 同期しなくなったノートは、同期済みであれば次の同期でクラウドから消える。
+func markLocalOnlyChanges(noteService *noteService, syncState *SyncState, localOnlyBefore map[string]bool) {
+	localOnlyAfter, _ := noteService.LocalOnlyIDs()
+	for id := range localOnlyBefore {
+		if !localOnlyAfter[id] {
+			syncState.MarkNoteDirty(id)
+		}
+	}
+}
+
 // トップレベルの表示順序を返す ------------------------------------------------------------
 func (a *App) GetTopLevelOrder() []TopLevelItem {
-	return a.noteService.GetTopLevelOrder()
+	return a.currentNoteService().GetTopLevelOrder()
 }
 
 func (a *App) GetCollapsedFolderIDs() []string {
-	return a.noteService.noteList.CollapsedFolderIDs
+	return a.currentNoteService().noteList.CollapsedFolderIDs
 }
 
 func (a *App) UpdateCollapsedFolderIDs(ids []string) error {
-	a.noteService.noteList.CollapsedFolderIDs = ids
-	if err := a.noteService.saveNoteList(); err != nil {
+	noteService, syncState := a.currentNoteServices()
+	noteService.noteList.CollapsedFolderIDs = ids
+	if err := noteService.saveNoteList(); err != nil {
 		return err
 	}
 
-	if a.syncState != nil {
-		a.syncState.MarkDirty()
+	if syncState != nil {
+		syncState.MarkDirty()
 	}
 
 	a.triggerSyncIfConnected()
@@ -511,12 +1004,13 @@ func (a *App) UpdateCollapsedFolderIDs(ids []string) error {
 
 // トップレベルの表示順序を更新する ------------------------------------------------------------
 func (a *App) UpdateTopLevelOrder(order []TopLevelItem) error {
-	if err := a.noteService.UpdateTopLevelOrder(order); err != nil {
+	noteService, syncState := a.currentNoteServices()
+	if err := noteService.UpdateTopLevelOrder(order); err != nil {
 		return err
 	}
 
-	if a.syncState != nil {
-		a.syncState.MarkDirty()
+	if syncState != nil {
+		syncState.MarkDirty()
 	}
 
 	a.triggerSyncIfConnected()
@@ -525,11 +1019,12 @@ func (a *App) UpdateTopLevelOrder(order []TopLevelItem) error {
 
 // フォルダをアーカイブする ------------------------------------------------------------
 func (a *App) ArchiveFolder(id string) error {
-	if err := a.noteService.ArchiveFolder(id); err != nil {
+	noteService, syncState := a.currentNoteServices()
+	if err := noteService.ArchiveFolder(id); err != nil {
 		return err
 	}
-	if a.syncState != nil {
-		a.syncState.MarkDirty()
+	if syncState != nil {
+		syncState.MarkDirty()
 	}
 	a.triggerSyncIfConnected()
 	return nil
@@ -537,67 +1032,130 @@ func (a *App) ArchiveFolder(id string) error {
 
 // アーカイブされたフォルダを復元する ------------------------------------------------------------
 func (a *App) UnarchiveFolder(id string) error {
-	if err := a.noteService.UnarchiveFolder(id); err != nil {
+	noteService, syncState := a.currentNoteServices()
+	if err := noteService.UnarchiveFolder(id); err != nil {
 		return err
 	}
-	if a.syncState != nil {
-		a.syncState.MarkDirty()
+	if syncState != nil {
+		syncState.MarkDirty()
 	}
 	a.triggerSyncIfConnected()
 	return nil
 }
 
-// アーカイブされたフォルダを削除する ------------------------------------------------------------
+// アーカイブされたフォルダをゴミ箱に移す（配下のフォルダとノートも一緒に移す） ------------------------------------------------------------
 func (a *App) DeleteArchivedFolder(id string) error {
-	var noteIDs []string
-	for _, note := range a.noteService.noteList.Notes {
-		if note.FolderID == id {
-			noteIDs = append(noteIDs, note.ID)
-		}
+	noteService, syncState := a.currentNoteServices()
+	if err := noteService.TrashFolder(id); err != nil {
+		return err
+	}
+
+	if syncState != nil {
+		syncState.MarkDirty()
 	}
 
-	if err := a.noteService.DeleteArchivedFolder(id); err != nil {
+	a.triggerSyncIfConnected()
+	return nil
+}
+
+// ゴミ箱の中身を返す（保持期間を過ぎたものは先に削除する） ------------------------------------------------------------
+func (a *App) ListTrash() []TrashItem {
+	a.purgeExpiredTrash()
+	return a.currentNoteService().ListTrash()
+}
+
+// ゴミ箱のノートまたはフォルダを元の場所に戻す ------------------------------------------------------------
+func (a *App) RestoreFromTrash(id string) error {
+	noteService, syncState := a.currentNoteServices()
+	item, err := noteService.RestoreFromTrash(id)
+	if err != nil {
 		return err
 	}
 
-	if a.syncState != nil {
-		for _, noteID := range noteIDs {
-			a.syncState.MarkNoteDeleted(noteID)
+	// 他端末でクラウドから消されないよう、戻したノートは再アップロードする
+	if syncState != nil {
+		for _, note := range item.Notes {
+			syncState.MarkNoteDirty(note.ID)
 		}
-		a.syncState.MarkFolderDeleted(id)
+		syncState.MarkDirty()
 	}
 
 	a.triggerSyncIfConnected()
 	return nil
 }
 
+// ゴミ箱を空にする（ノートファイルをクラウドからも削除する） ------------------------------------------------------------
+func (a *App) EmptyTrash() error {
+	noteIDs, folderIDs, err := a.currentNoteService().EmptyTrash()
+	if err != nil {
+		return err
+	}
+	a.markTrashPurged(noteIDs, folderIDs)
+	a.triggerSyncIfConnected()
+	return nil
+}
+
+// 保持期間を過ぎたゴミ箱のアイテムを削除する
+func (a *App) purgeExpiredTrash() {
+	retentionDays := 0
+	if settings, err := a.currentSettingsService().LoadSettings(); err == nil {
+		retentionDays = settings.TrashRetentionDays
+	}
+	noteIDs, folderIDs, err := a.currentNoteService().PurgeExpiredTrash(retentionDays)
+	if err != nil {
+		a.logger.Console("Failed to purge expired trash: %v", err)
+		return
+	}
+	if len(noteIDs) > 0 || len(folderIDs) > 0 {
+		a.logger.Console("Purged %d notes and %d folders from trash", len(noteIDs), len(folderIDs))
+		a.markTrashPurged(noteIDs, folderIDs)
+		a.triggerSyncIfConnected()
+	}
+}
+
+// ゴミ箱から完全に削除したノートとフォルダを同期で削除するよう登録する
+func (a *App) markTrashPurged(noteIDs []string, folderIDs []string) {
+	syncState := a.currentSyncState()
+	if syncState == nil {
+		return
+	}
+	for _, noteID := range noteIDs {
+		syncState.MarkNoteDeleted(noteID)
+	}
+	for _, folderID := range folderIDs {
+		syncState.MarkFolderDeleted(folderID)
+	}
+	syncState.MarkDirty()
+}
+
 // アーカイブされたアイテムの表示順序を返す ------------------------------------------------------------
 func (a *App) GetArchivedTopLevelOrder() []TopLevelItem {
-	return a.noteService.GetArchivedTopLevelOrder()
+	return a.currentNoteService().GetArchivedTopLevelOrder()
 }
 
 // アーカイブされたアイテムの表示順序を更新する ------------------------------------------------------------
 func (a *App) UpdateArchivedTopLevelOrder(order []TopLevelItem) error {
-	if err := a.noteService.UpdateArchivedTopLevelOrder(order); err != nil {
+	noteService, syncState := a.currentNoteServices()
+	if err := noteService.UpdateArchivedTopLevelOrder(order); err != nil {
 		return err
 	}
-	if a.syncState != nil {
-		a.syncState.MarkDirty()
+	if syncState != nil {
+		syncState.MarkDirty()
 	}
 	a.triggerSyncIfConnected()
 	return nil
 }
 
 func (a *App) triggerSyncIfConnected() {
-	if a.driveService != nil && a.driveService.IsConnected() {
+	if driveService := a.currentDriveService(); driveService != nil && driveService.IsConnected() {
 		go func() {
 			defer func() {
 				if r := recover(); r != nil {
 					a.logger.Console(fmt.Sprintf("PANIC in triggerSyncIfConnected: %v\n%s", r, string(debug.Stack())))
 				}
 			}()
-			if err := a.driveService.SyncNotes(); err != nil {
-				a.authService.HandleOfflineTransition(err)
+			if err := driveService.SyncNotesWithTrigger(SyncTriggerSave); err != nil {
+				a.currentAuthService().HandleOfflineTransition(err)
 			}
 		}()
 	}
@@ -609,111 +1167,237 @@ func (a *App) triggerSyncIfConnected() {
 
 // Google Drive APIの初期化 ------------------------------------------------------------
 func (a *App) InitializeDrive() error {
-	if a.driveService == nil {
-		return a.authService.HandleOfflineTransition(fmt.Errorf("driveService not initialized yet"))
+	driveService := a.currentDriveService()
+	if driveService == nil {
+		return a.currentAuthService().HandleOfflineTransition(fmt.Errorf("driveService not initialized yet"))
 	}
-	return a.driveService.InitializeDrive()
+	return driveService.InitializeDrive()
 }
 
 // Google Driveに手動ログイン
 func (a *App) AuthorizeDrive() error {
-	if a.driveService == nil {
+	driveService := a.currentDriveService()
+	if driveService == nil {
 		return fmt.Errorf("drive service is not initialized")
 	}
 
+	// WebDAV・同期フォルダで接続中なら切断してから Google Drive に切り替える
+	if driveService.IsConnected() && a.currentSyncProvider() != syncProviderGoogleDrive {
+		if err := driveService.LogoutDrive(); err != nil {
+			a.logger.Console("AuthorizeDrive: failed to disconnect sync provider: %v", err)
+		}
+	}
+
 	a.logger.NotifyDriveStatus(a.ctx.ctx, "logging in")
 	a.logger.Console("Waiting for login...")
-	if err := a.driveService.AuthorizeDrive(); err != nil {
-		return a.authService.HandleOfflineTransition(err)
+	if err := driveService.AuthorizeDrive(); err != nil {
+		return a.currentAuthService().HandleOfflineTransition(err)
 	}
 	a.logger.Console("AuthorizeDrive success")
-	return nil
+	return a.saveSyncProvider(syncProviderGoogleDrive)
 }
 
 // 認証をキャンセル ------------------------------------------------------------
 func (a *App) CancelLoginDrive() error {
-	if a.driveService != nil {
-		return a.driveService.CancelLoginDrive()
+	driveService := a.currentDriveService()
+	if driveService != nil {
+		return driveService.CancelLoginDrive()
 	}
-	return a.authService.HandleOfflineTransition(fmt.Errorf("drive service is not initialized"))
+	return a.currentAuthService().HandleOfflineTransition(fmt.Errorf("drive service is not initialized"))
 }
 
 // Google Driveからログアウト ------------------------------------------------------------
 func (a *App) LogoutDrive() error {
-	return a.driveService.LogoutDrive()
+	return a.currentDriveService().LogoutDrive()
 }
 
 // Google Drive 上の全データを削除してログアウトする ------------------------------------------------------------
 // 設定ダイアログの「Googleドライブのデータを削除」から呼ばれる危険な操作。
 // フロントエンド側で確認ダイアログ必須。
 func (a *App) DeleteAllDriveData() error {
-	if a.driveService == nil {
+	driveService := a.currentDriveService()
+	if driveService == nil {
 		return fmt.Errorf("drive service is not initialized")
 	}
-	return a.driveService.DeleteAllDriveData()
+	return driveService.DeleteAllDriveData()
 }
 
-// この端末に保存されたアプリデータを全削除する ------------------------------------------------------------
-// Google Drive 上のデータは削除しない。ローカルノート、設定、同期状態、OAuth token、
-// 最近使ったファイル履歴など、appDataDir 配下のデータを削除して空のサービス状態へ戻す。
-func (a *App) DeleteLocalAppData() error {
-	if a.driveService != nil {
-		if err := a.driveService.LogoutDrive(); err != nil {
-			a.logger.Console("DeleteLocalAppData: logout failed before deletion: %v", err)
-		}
+// WebDAV サーバーに接続して同期プロバイダーを WebDAV に切り替える ------------------------------------------------------------
+func (a *App) ConnectWebDAV(config WebDAVConfig) error {
+	driveService := a.currentDriveService()
+	if driveService == nil {
+		return fmt.Errorf("drive service is not initialized")
 	}
-	if a.logger != nil {
-		// Windows では開いたログファイルが残っていると RemoveAll に失敗し得るため、
-		// 先にデバッグログを閉じてから appDataDir を削除する。
-		a.logger.SetDebugMode(false)
+	if driveService.IsConnected() {
+		if err := driveService.LogoutDrive(); err != nil {
+			a.logger.Console("ConnectWebDAV: logout failed before switching: %v", err)
+		}
 	}
-	if err := os.RemoveAll(a.appDataDir); err != nil {
-		return fmt.Errorf("failed to delete local app data: %w", err)
+	if err := driveService.ConnectWebDAV(config); err != nil {
+		a.logger.NotifyDriveStatus(a.ctx.ctx, "offline")
+		return err
 	}
-	if err := os.MkdirAll(a.notesDir, 0755); err != nil {
-		return fmt.Errorf("failed to recreate notes directory: %w", err)
+	return a.saveSyncProvider(syncProviderWebDAV)
+}
+
+// 保存済みの WebDAV 接続設定を返す (パスワードは返さない) ------------------------------------------------------------
+func (a *App) GetWebDAVConfig() (WebDAVConfig, error) {
+	config, err := loadWebDAVConfig(a.currentAppDataDir())
+	if err != nil || config == nil {
+		return WebDAVConfig{}, err
 	}
+	config.Password = ""
+	return *config, nil
+}
 
-	a.logger = NewAppLogger(a.ctx.ctx, false, a.appDataDir)
-	a.fileService = NewFileService(a.ctx)
-	a.settingsService = NewSettingsService(a.appDataDir)
-	a.fileNoteService = NewFileNoteService(a.appDataDir)
-	a.recentFilesService = NewRecentFilesService(a.appDataDir)
+// ローカルフォルダを同期先にして同期プロバイダーを切り替える ------------------------------------------------------------
+// Syncthing の共有フォルダや NAS のマウント先など、任意のディレクトリを同期先にできる。
+func (a *App) ConnectLocalFolder(config LocalFolderConfig) error {
+	driveService := a.currentDriveService()
+	if driveService == nil {
+		return fmt.Errorf("drive service is not initialized")
+	}
+	if driveService.IsConnected() {
+		if err := driveService.LogoutDrive(); err != nil {
+			a.logger.Console("ConnectLocalFolder: logout failed before switching: %v", err)
+		}
+	}
+	if err := driveService.ConnectLocalFolder(config); err != nil {
+		a.logger.NotifyDriveStatus(a.ctx.ctx, "offline")
+		return err
+	}
+	return a.saveSyncProvider(syncProviderLocalFolder)
+}
+
+// 保存済みの同期フォルダ設定を返す ------------------------------------------------------------
+func (a *App) GetLocalFolderConfig() (LocalFolderConfig, error) {
+	config, err := loadLocalFolderConfig(a.currentAppDataDir())
+	if err != nil || config == nil {
+		return LocalFolderConfig{}, err
+	}
+	return *config, nil
+}
 
-	ns, err := NewNoteService(a.notesDir, a.logger)
+// 同期先フォルダの選択ダイアログを表示 ------------------------------------------------------------
+func (a *App) SelectSyncFolder() (string, error) {
+	return a.fileService.SelectDirectory("Select a sync folder")
+}
+
+// 同期プロバイダーを切り替える ("google" / "webdav" / "folder") ------------------------------------------------------------
+// 接続中のプロバイダーと異なる場合は切断する。新しいプロバイダーへの接続は
+// AuthorizeDrive / ConnectWebDAV で行う。
+func (a *App) SetSyncProvider(provider string) error {
+	normalized, err := normalizeSyncProvider(provider)
 	if err != nil {
-		a.logger.Console("DeleteLocalAppData: creating empty note service: %v", err)
-		ns = NewEmptyNoteService(a.notesDir, a.logger)
+		return err
 	}
-	a.noteService = ns
-	a.syncState = NewSyncState(a.appDataDir)
-	if err := a.syncState.Load(); err != nil {
-		a.logger.Console("DeleteLocalAppData: failed to load fresh sync state: %v", err)
+	if normalized == a.currentSyncProvider() {
+		return nil
 	}
+	if driveService := a.currentDriveService(); driveService != nil && driveService.IsConnected() {
+		if err := driveService.LogoutDrive(); err != nil {
+			a.logger.Console("SetSyncProvider: logout failed: %v", err)
+		}
+	}
+	return a.saveSyncProvider(normalized)
+}
 
-	authService := NewAuthService(
-		a.ctx.ctx,
-		a.appDataDir,
-		a.notesDir,
-		a.noteService,
-		credentialsJSON,
-		a.logger,
-		false,
-	)
-	authService.NotifyFrontendReady()
-	a.authService = authService
-	a.driveService = NewDriveService(
-		a.ctx.ctx,
-		a.appDataDir,
-		a.notesDir,
-		a.noteService,
-		credentialsJSON,
-		a.logger,
-		authService,
-		a.syncState,
-	)
-	a.lastActiveNoteId = ""
-	a.lastActiveNoteIsFile = false
+// 同期データのエンドツーエンド暗号化を有効にする ------------------------------------------------------------
+// 他の端末で既に暗号化されている場合は、同じパスフレーズを入力するとロックが解除される。
+func (a *App) EnableSyncEncryption(passphrase string) error {
+	driveService := a.currentDriveService()
+	if driveService == nil {
+		return fmt.Errorf("drive service is not initialized")
+	}
+	return driveService.EnableSyncEncryption(passphrase)
+}
+
+// 同期データの暗号化を無効にし、クラウドのデータを平文に戻す ------------------------------------------------------------
+func (a *App) DisableSyncEncryption() error {
+	driveService := a.currentDriveService()
+	if driveService == nil {
+		return fmt.Errorf("drive service is not initialized")
+	}
+	return driveService.DisableSyncEncryption()
+}
+
+// 同期データの暗号化の状態を返す ------------------------------------------------------------
+func (a *App) GetSyncEncryptionStatus() SyncEncryptionStatus {
+	driveService := a.currentDriveService()
+	if driveService == nil {
+		return SyncEncryptionStatus{}
+	}
+	return driveService.GetSyncEncryptionStatus()
+}
+
+func (a *App) currentSyncProvider() string {
+	settings, err := a.currentSettingsService().LoadSettings()
+	if err != nil {
+		return syncProviderGoogleDrive
+	}
+	provider, err := normalizeSyncProvider(settings.SyncProvider)
+	if err != nil {
+		return syncProviderGoogleDrive
+	}
+	return provider
+}
+
+func (a *App) saveSyncProvider(provider string) error {
+	settingsService := a.currentSettingsService()
+	settings, err := settingsService.LoadSettings()
+	if err != nil {
+		return err
+	}
+	settings.SyncProvider = provider
+	return settingsService.SaveSettings(settings)
+}
+
+// この端末に保存されたアプリデータを全削除する ------------------------------------------------------------
+// Google Drive 上のデータは削除しない。ローカルノート、設定、同期状態、OAuth token、
+// 最近使ったファイル履歴など、appDataDir 配下のデータを削除して空のサービス状態へ戻す。
+func (a *App) DeleteLocalAppData() error {
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+
+	appDataDir := a.currentAppDataDir()
+	if driveService := a.currentDriveService(); driveService != nil {
+		if err := driveService.LogoutDrive(); err != nil {
+			a.logger.Console("DeleteLocalAppData: logout failed before deletion: %v", err)
+		}
+	}
+	a.stopSyncServices()
+	// トークンも削除されるため、ローカル API は停止したままにする
+	a.stopLocalAPI()
+	a.stopBackgroundLoops()
+	if a.logger != nil {
+		// Windows では開いたログファイルが残っていると RemoveAll に失敗し得るため、
+		// 先にデバッグログを閉じてから appDataDir を削除する。
+		a.logger.SetDebugMode(false)
+	}
+	// ロックファイルも appDataDir 内にあるため、一旦解放して削除後に取り直す
+	if err := a.dataDirLock.Release(); err != nil {
+		a.logger.Console("DeleteLocalAppData: failed to release data directory lock: %v", err)
+	}
+	a.dataDirLock = nil
+	// 既定のプロファイルでも、他のプロファイルのデータは残す
+	if err := removeProfileData(a.rootDataDir, appDataDir); err != nil {
+		return fmt.Errorf("failed to delete local app data: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(appDataDir, "notes"), 0755); err != nil {
+		return fmt.Errorf("failed to recreate notes directory: %w", err)
+	}
+	lock, err := waitDataDirLock(a.ctx.ctx, appDataDir, dataDirLockStartupWait, func(err error) {
+		a.logger.Console("DeleteLocalAppData: waiting for app data directory: %v", err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to lock app data directory: %w", err)
+	}
+	a.dataDirLock = lock
+
+	a.reopenDataDir(appDataDir)
+	a.startSyncServices(a.ctx.ctx)
+	a.currentAuthService().NotifyFrontendReady()
+	a.SetLastActiveNote("", false)
 
 	wailsRuntime.EventsEmit(a.ctx.ctx, "drive:status", "offline")
 	wailsRuntime.EventsEmit(a.ctx.ctx, "notes:reload")
@@ -722,26 +1406,131 @@ func (a *App) DeleteLocalAppData() error {
 
 // 手動でただちに同期を開始 ------------------------------------------------------------
 func (a *App) SyncNow() error {
-	if a.driveService != nil && a.driveService.IsConnected() {
-		return a.driveService.SyncNotes()
+	driveService := a.currentDriveService()
+	if driveService != nil && driveService.IsConnected() {
+		return driveService.SyncNotes()
 	}
-	return a.authService.HandleOfflineTransition(fmt.Errorf("drive service is not initialized or not connected"))
+	return a.currentAuthService().HandleOfflineTransition(fmt.Errorf("drive service is not initialized or not connected"))
 }
 
 // Google Driveとの接続状態をチェック ------------------------------------------------------------
 func (a *App) CheckDriveConnection() bool {
-	if a.driveService == nil {
+	driveService := a.currentDriveService()
+	if driveService == nil {
 		return false
 	}
-	return a.driveService.IsConnected()
+	return driveService.IsConnected()
 }
 
 // RespondToMigration はDriveストレージマイグレーションのユーザー選択を処理する
 // choice: "migrate_delete" (移行+旧データ削除), "migrate_keep" (移行+旧データ保持), "skip" (スキップ)
 func (a *App) RespondToMigration(choice string) {
-	if a.driveService != nil {
-		a.driveService.RespondToMigration(choice)
+	driveService := a.currentDriveService()
+	if driveService != nil {
+		driveService.RespondToMigration(choice)
+	}
+}
+
+// ------------------------------------------------------------
+// プロファイル関連の操作
+// ------------------------------------------------------------
+
+// プロファイルの一覧と使用中のプロファイルを返す ------------------------------------------------------------
+func (a *App) ListProfiles() (ProfileList, error) {
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+	list, err := loadProfiles(a.rootDataDir)
+	if err != nil {
+		return ProfileList{}, err
+	}
+	return *list, nil
+}
+
+// 新しいプロファイルを作成する（切り替えは SwitchProfile で行う） ------------------------------------------------------------
+func (a *App) CreateProfile(name string) (*Profile, error) {
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+	profile, err := createProfile(a.rootDataDir, name, a.currentAppDataDir())
+	if err != nil {
+		return nil, err
+	}
+	a.logger.Console("Created profile %s (%s)", profile.Name, profile.ID)
+	return profile, nil
+}
+
+// プロファイルを切り替える ------------------------------------------------------------
+// ポーリングと操作キューを止めてから、切り替え先のデータディレクトリでサービスを作り直す
+// （アプリの再起動は不要）。編集中の内容はフロントエンドで保存してから呼ぶこと。
+func (a *App) SwitchProfile(profileID string) error {
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+
+	list, err := loadProfiles(a.rootDataDir)
+	if err != nil {
+		return err
+	}
+	profile, ok := findProfile(list, profileID)
+	if !ok {
+		return fmt.Errorf("profile not found: %s", profileID)
+	}
+	dataDir := profileDataDir(a.rootDataDir, profile.ID)
+	if filepath.Clean(dataDir) == filepath.Clean(a.currentAppDataDir()) {
+		return nil
+	}
+
+	// 切り替え先を CLI が使用中なら、今のプロファイルのまま続ける
+	if err := os.MkdirAll(filepath.Join(dataDir, "notes"), 0755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	lock, err := acquireDataDirLock(dataDir, dataDirLockCLIWait)
+	if err != nil {
+		return err
+	}
+	list.ActiveID = profile.ID
+	if err := saveProfiles(a.rootDataDir, list); err != nil {
+		lock.Release()
+		return err
+	}
+	a.logger.Console("Switching profile to %s (%s)", profile.Name, profile.ID)
+
+	// 今のプロファイルの同期とバックグラウンドの処理を止め、ノートリストと最後に開いたノートを書き戻す
+	a.stopSyncServices()
+	a.stopLocalAPI()
+	a.stopBackgroundLoops()
+	noteService := a.currentNoteService()
+	noteService.WithLock(func() {
+		if err := noteService.saveNoteList(); err != nil {
+			a.logger.Console("SwitchProfile: failed to save note list: %v", err)
+		}
+	})
+	settingsService := a.currentSettingsService()
+	if settings, err := settingsService.LoadSettings(); err == nil {
+		settings.LastActiveNoteId, settings.LastActiveNoteIsFile = a.lastActiveNote()
+		if err := settingsService.SaveSettings(settings); err != nil {
+			a.logger.Console("SwitchProfile: failed to save settings: %v", err)
+		}
 	}
+	if err := a.dataDirLock.Release(); err != nil {
+		a.logger.Console("SwitchProfile: failed to release data directory lock: %v", err)
+	}
+	a.dataDirLock = lock
+
+	// 切り替え先のデータディレクトリでサービスを作り直す
+	a.reopenDataDir(dataDir)
+	a.startSyncServices(a.ctx.ctx)
+	a.currentAuthService().NotifyFrontendReady()
+	a.SetLastActiveNote("", false)
+	if settings, err := a.currentSettingsService().LoadSettings(); err == nil {
+		a.SetLastActiveNote(settings.LastActiveNoteId, settings.LastActiveNoteIsFile)
+	}
+	if err := a.startLocalAPIIfEnabled(); err != nil {
+		a.logger.Console("Failed to start local API: %v", err)
+	}
+
+	wailsRuntime.EventsEmit(a.ctx.ctx, "drive:status", "offline")
+	wailsRuntime.EventsEmit(a.ctx.ctx, "profile:changed", profile)
+	wailsRuntime.EventsEmit(a.ctx.ctx, "notes:reload")
+	return nil
 }
 
 // ------------------------------------------------------------
@@ -750,12 +1539,20 @@ func (a *App) RespondToMigration(choice string) {
 
 // ファイルノートを読み込む
 func (a *App) LoadFileNotes() ([]FileNote, error) {
-	return a.fileNoteService.LoadFileNotes()
+	return a.currentFileNoteService().LoadFileNotes()
 }
 
-// ファイルノートを保存する
+// ファイルノートを保存する（監視するパスもこの一覧に合わせる）
 func (a *App) SaveFileNotes(list []FileNote) (string, error) {
-	return a.fileNoteService.SaveFileNotes(list)
+	fileNoteService, watcher := a.currentFileNoteService(), a.currentFileWatcher()
+	path, err := fileNoteService.SaveFileNotes(list)
+	if err != nil {
+		return "", err
+	}
+	if watcher != nil {
+		watcher.Sync(list)
+	}
+	return path, nil
 }
 
 // ------------------------------------------------------------
@@ -791,8 +1588,231 @@ func (a *App) SelectSaveFileUri(fileName string, extension string) (string, erro
 // 指定されたパスにコンテンツを保存する。
 // 戻り値は保存後の実ディスク mtime (RFC3339Nano)。フロントエンドはこれを
 // FileNote.modifiedTime として保持し、次回フォーカス時の外部編集チェックに使う。
+// 開いたときの文字コード・BOM・改行コードで書き戻す（表せない文字があればエラーで知らせる）。
 func (a *App) SaveFile(filePath string, content string) (string, error) {
-	return a.fileService.SaveFile(filePath, content)
+	watcher := a.currentFileWatcher()
+	if err := a.checkWritableFile(filePath); err != nil {
+		return "", err
+	}
+	var modifiedTime string
+	var err error
+	if format, ok := a.fileNoteFormat(filePath); ok {
+		modifiedTime, err = a.fileService.SaveFileWithFormat(filePath, content, format)
+	} else {
+		modifiedTime, err = a.fileService.SaveFile(filePath, content)
+	}
+	if err != nil {
+		return "", err
+	}
+	// 自分で保存した内容を外部での変更として通知しない
+	if watcher != nil {
+		watcher.RecordSaved(filePath)
+	}
+	return modifiedTime, nil
+}
+
+// 開いた後に外部で変更されていなければ保存する ------------------------------------------------------------
+// lastModifiedTime は FileNote.modifiedTime（開いた・前回保存したときの mtime）。
+// ディスクの内容が変わっていれば書き込まず、開いた時点・ディスク・バッファの 3-way の差分を Conflict で返す。
+// 差分を確かめた上で上書きするときは SaveFile を使う。
+func (a *App) SaveFileChecked(filePath string, content string, lastModifiedTime string) (*FileSaveResult, error) {
+	if lastModifiedTime != "" {
+		modified, err := a.fileService.CheckFileModified(filePath, lastModifiedTime)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if modified {
+			conflict, err := a.fileSaveConflict(filePath, content)
+			if err != nil {
+				return nil, err
+			}
+			if conflict != nil {
+				return &FileSaveResult{Conflict: conflict}, nil
+			}
+		}
+	}
+	modifiedTime, err := a.SaveFile(filePath, content)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSaveResult{ModifiedTime: modifiedTime}, nil
+}
+
+// fileSaveConflict はディスクの内容が開いた時点から変わっていれば 3-way の比較を返す
+// (mtime だけが変わった・バッファと同じ内容になっている場合は nil)
+func (a *App) fileSaveConflict(filePath string, content string) (*FileSaveConflict, error) {
+	fileNoteService := a.currentFileNoteService()
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	disk, _ := decodeText(data)
+	original := ""
+	if fileNoteService != nil {
+		if note, ok := fileNoteService.findFileNoteByPath(filePath); ok {
+			original = note.OriginalContent
+		}
+	}
+	if sameTextIgnoringLineEndings(disk, original) || sameTextIgnoringLineEndings(disk, content) {
+		return nil, nil
+	}
+	return buildFileSaveConflict(filePath, original, disk, content, info.ModTime().Format(time.RFC3339Nano)), nil
+}
+
+// backupFileBeforeSave は設定に応じて上書きする前のファイルを残す（失敗しても保存は続ける）
+func (a *App) backupFileBeforeSave(filePath string) {
+	settingsService := a.currentSettingsService()
+	if settingsService == nil {
+		return
+	}
+	settings, err := settingsService.LoadSettings()
+	if err != nil {
+		return
+	}
+	if err := backupFileBeforeSave(a.currentAppDataDir(), filePath, settings.FileBackupMode, settings.FileBackupKeep, time.Now()); err != nil {
+		a.logger.Console("Failed to back up %s before saving: %v", filePath, err)
+	}
+}
+
+// 文字コード・BOM・改行コードを指定して保存する ------------------------------------------------------------
+// 以降の保存もこの形式になるよう、ファイルノートに記録する。
+func (a *App) SaveFileWithEncoding(filePath string, content string, format TextFormat) (string, error) {
+	fileNoteService := a.currentFileNoteService()
+	watcher := a.currentFileWatcher()
+	if err := a.checkWritableFile(filePath); err != nil {
+		return "", err
+	}
+	modifiedTime, err := a.fileService.SaveFileWithFormat(filePath, content, format)
+	if err != nil {
+		return "", err
+	}
+	if watcher != nil {
+		watcher.RecordSaved(filePath)
+	}
+	if fileNoteService != nil {
+		if err := fileNoteService.updateFileNoteFormat(filePath, format, modifiedTime); err != nil {
+			a.logger.Console("Failed to record file encoding: %v", err)
+		}
+	}
+	return modifiedTime, nil
+}
+
+// 指定した文字コードで表せない文字の位置を返す ------------------------------------------------------------
+func (a *App) FindUnencodableChars(content string, encodingName string) ([]EncodingIssue, error) {
+	enc, err := textEncodingByName(encodingName, false)
+	if err != nil {
+		return nil, err
+	}
+	if enc == nil {
+		return []EncodingIssue{}, nil
+	}
+	issues := findUnencodableChars(content, enc)
+	if issues == nil {
+		issues = []EncodingIssue{}
+	}
+	return issues, nil
+}
+
+// 保存に使える文字コードの一覧 ------------------------------------------------------------
+func (a *App) ListTextEncodings() []string {
+	return append([]string(nil), textEncodings...)
+}
+
+// checkWritableFile は大きなファイルのモード（読み取り専用）で開いているファイルと、バイナリへの保存を拒む
+func (a *App) checkWritableFile(filePath string) error {
+	if a.largeFiles != nil && a.largeFiles.IsOpen(filePath) {
+		return fmt.Errorf("%s is open read-only in large file mode", filePath)
+	}
+	if isBinaryFile(filePath) {
+		return fmt.Errorf("%s is a binary file and cannot be saved as text", filePath)
+	}
+	return nil
+}
+
+// バイナリファイルの offset から length バイトを 16 進ダンプで読む ------------------------------------------------------------
+// OpenFile が Binary=true を返したファイルは、内容をこれで少しずつ読む。
+func (a *App) ReadHexDump(filePath string, offset int64, length int) (*HexDump, error) {
+	return a.fileService.ReadHexDump(filePath, offset, length)
+}
+
+// 大きなファイルの start 行目（1 始まり）から count 行を読む ------------------------------------------------------------
+// OpenFile が LargeFile=true を返したファイルは、内容をこれで少しずつ読む。
+func (a *App) ReadFileLines(filePath string, start int, count int) (*LargeFileLines, error) {
+	return a.largeFiles.ReadLines(filePath, start, count)
+}
+
+// 大きなファイル全体を検索する ------------------------------------------------------------
+func (a *App) SearchLargeFile(filePath string, query string, options LargeFileSearchOptions) (*LargeFileSearchResult, error) {
+	return a.largeFiles.Search(a.ctx.ctx, filePath, query, options)
+}
+
+// 大きなファイルへの追記を追従する（tail -f、追記は file:appended で通知） ------------------------------------------------------------
+func (a *App) FollowLargeFile(filePath string) error {
+	return a.largeFiles.Follow(filePath)
+}
+
+// 大きなファイルへの追記の追従をやめる ------------------------------------------------------------
+func (a *App) UnfollowLargeFile(filePath string) {
+	a.largeFiles.Unfollow(filePath)
+}
+
+// 大きなファイルを閉じる（追従をやめて行の索引を捨てる） ------------------------------------------------------------
+func (a *App) CloseLargeFile(filePath string) {
+	a.largeFiles.Close(filePath)
+}
+
+// ノートをこの端末のファイルにリンクする ------------------------------------------------------------
+// 以後ノートを保存するとファイルにも書き出し、ファイルが外部で変更されるとノートに取り込む。
+// ファイルが無ければノートの内容で作り、内容が違えばファイルの内容を取り込む（ノートは競合バックアップに残す）。
+// リンクは同期しない（linkedFiles.json）。
+func (a *App) LinkNoteToFile(noteID string, filePath string) (*LinkedFile, error) {
+	return a.linkNoteToFile(noteID, filePath)
+}
+
+// ノートとファイルのリンクを外す（ノートとファイルはそのまま残す） ------------------------------------------------------------
+func (a *App) UnlinkNote(noteID string) error {
+	if err := a.currentLinkedFiles().Unlink(noteID); err != nil {
+		return err
+	}
+	a.watchLinkedFiles()
+	return nil
+}
+
+// ノートにリンクしたファイルを返す（リンクしていなければ nil） ------------------------------------------------------------
+func (a *App) GetLinkedFile(noteID string) *LinkedFile {
+	link, ok := a.currentLinkedFiles().Get(noteID)
+	if !ok {
+		return nil
+	}
+	return &link
+}
+
+// この端末のリンクをパスの順に返す ------------------------------------------------------------
+func (a *App) ListLinkedFiles() []LinkedFile {
+	return a.currentLinkedFiles().List()
+}
+
+// リンクしたファイルの外部での変更をただちに確認する（ウィンドウのフォーカス時など） ------------------------------------------------------------
+// 取り込んだ変更は note:linked-file-changed で通知する。
+func (a *App) CheckLinkedFiles() error {
+	return a.checkLinkedFiles()
+}
+
+// fileNoteFormat は指定パスのファイルノートに記録された文字コード・BOM・改行コードを返す
+func (a *App) fileNoteFormat(filePath string) (TextFormat, bool) {
+	fileNoteService := a.currentFileNoteService()
+	if fileNoteService == nil {
+		return TextFormat{}, false
+	}
+	note, ok := fileNoteService.findFileNoteByPath(filePath)
+	if !ok {
+		return TextFormat{}, false
+	}
+	return note.textFormat()
 }
 
 // OpenFileFromExternal は外部からファイルを開く際の処理を行います
@@ -813,17 +1833,181 @@ func (a *App) OpenFileFromExternal(filePath string) error {
 		"path":           filePath,
 		"content":        result.Content,
 		"sourceEncoding": result.SourceEncoding,
+		"encoding":       result.Encoding,
+		"lineEnding":     result.LineEnding,
+		"largeFile":      strconv.FormatBool(result.LargeFile),
+		"binary":         strconv.FormatBool(result.Binary),
+		"binaryFormat":   result.BinaryFormat,
+	})
+	return nil
+}
+
+// 書き出し先フォルダの選択ダイアログを表示 ------------------------------------------------------------
+func (a *App) SelectExportDirectory() (string, error) {
+	return a.fileService.SelectDirectory("Select an export folder")
+}
+
+// 全ノートを Markdown 保管庫形式（フォルダ=ディレクトリ）で書き出す ------------------------------------------------------------
+// destDir は存在しないか空のディレクトリを指定する。
+func (a *App) ExportVault(destDir string, options VaultExportOptions) (*VaultExportResult, error) {
+	result, err := a.currentNoteService().ExportVault(destDir, options)
+	if err != nil {
+		return nil, err
+	}
+	a.logger.Console("Exported %d notes and %d folders to %s", result.NoteCount, result.FolderCount, destDir)
+	return result, nil
+}
+
+// 取り込み元フォルダの選択ダイアログを表示 ------------------------------------------------------------
+func (a *App) SelectImportDirectory() (string, error) {
+	return a.fileService.SelectDirectory("Select a folder to import")
+}
+
+// ディレクトリ（Obsidian の保管庫を含む）または Evernote の .enex ファイルからノートを一括で取り込む ------------------------------------------------------------
+// options.DryRun の場合は何も作らず、取り込む予定の一覧だけを返す。
+// 同期は取り込んだノートをまとめて dirty にしてから 1 回だけ起こす。
+func (a *App) ImportNotes(sourcePath string, options ImportOptions) (*ImportReport, error) {
+	noteService, syncState := a.currentNoteServices()
+	report, importErr := noteService.ImportNotes(sourcePath, options)
+	if report == nil {
+		return nil, importErr
+	}
+	if !report.DryRun {
+		var noteIDs []string
+		for _, item := range report.Items {
+			if item.Status == importStatusImport && item.NoteID != "" {
+				noteIDs = append(noteIDs, item.NoteID)
+			}
+		}
+		if len(noteIDs) > 0 {
+			if syncState != nil {
+				syncState.MarkNotesDirty(noteIDs)
+			}
+			a.logger.Console("Imported %d notes and %d folders from %s", len(noteIDs), report.FolderCount, sourcePath)
+			a.triggerSyncIfConnected()
+		}
+	}
+	return report, importErr
+}
+
+// ローカルデータ（ノート・noteList・設定など）を zip にバックアップする ------------------------------------------------------------
+func (a *App) CreateBackup(path string) (*BackupInfo, error) {
+	if !filepath.IsAbs(path) {
+		return nil, fmt.Errorf("backup path must be absolute: %s", path)
+	}
+	var info *BackupInfo
+	var err error
+	a.currentNoteService().WithLock(func() {
+		info, err = createDataBackup(a.currentAppDataDir(), path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.logger.Console("Created backup with %d notes: %s", info.NoteCount, path)
+	return info, nil
+}
+
+// バックアップの zip からローカルデータを復元する ------------------------------------------------------------
+// 復元したノートは全て再アップロードし、クラウド側もバックアップの状態にそろえる。
+// 設定のうちローカル API・同期先・ファイル保存時のバックアップ・ウィンドウの位置はこの端末のものを残す。
+func (a *App) RestoreBackup(path string) error {
+	var noteIDs []string
+	var err error
+	noteService, syncState := a.currentNoteServices()
+	noteService.WithLock(func() {
+		if _, err = restoreDataBackup(a.currentAppDataDir(), path); err != nil {
+			return
+		}
+		if err = noteService.reloadFromDiskLocked(); err != nil {
+			return
+		}
+		noteIDs = noteService.allNoteIDsLocked()
 	})
+	if err != nil {
+		return err
+	}
+	a.logger.Console("Restored %d notes from backup: %s", len(noteIDs), path)
+
+	if syncState != nil {
+		syncState.MarkForFullReupload(noteIDs)
+	}
+	// ローカル API の有効・ポート・トークンはこの端末のものを引き継ぐので、起動し直さない
+	wailsRuntime.EventsEmit(a.ctx.ctx, "notes:reload")
+	a.triggerSyncIfConnected()
 	return nil
 }
 
+// 自動バックアップの一覧を新しい順に返す ------------------------------------------------------------
+func (a *App) ListBackups() ([]BackupInfo, error) {
+	backupDir := filepath.Join(a.currentAppDataDir(), autoBackupDirName)
+	names, err := listAutoBackups(backupDir)
+	if err != nil {
+		return nil, err
+	}
+	backups := make([]BackupInfo, 0, len(names))
+	for _, name := range names {
+		info, err := readBackupInfo(filepath.Join(backupDir, name))
+		if err != nil {
+			a.logger.Console("Skipping unreadable backup %s: %v", name, err)
+			continue
+		}
+		backups = append(backups, *info)
+	}
+	return backups, nil
+}
+
+// 設定の間隔を過ぎていれば自動バックアップを作り、古いものを消す
+func (a *App) runAutoBackupIfDue(now time.Time) {
+	settings, err := a.currentSettingsService().LoadSettings()
+	if err != nil || settings.AutoBackupIntervalHours <= 0 {
+		return
+	}
+	backupDir := filepath.Join(a.currentAppDataDir(), autoBackupDirName)
+	due, err := autoBackupDue(backupDir, time.Duration(settings.AutoBackupIntervalHours)*time.Hour, now)
+	if err != nil || !due {
+		return
+	}
+	path := filepath.Join(backupDir, autoBackupFilePrefix+now.Format(autoBackupTimeLayout)+".zip")
+	if _, err := a.CreateBackup(path); err != nil {
+		a.logger.Console("Automatic backup failed: %v", err)
+		return
+	}
+	removed, err := rotateAutoBackups(backupDir, settings.AutoBackupKeep)
+	if err != nil {
+		a.logger.Console("Failed to rotate automatic backups: %v", err)
+	}
+	if len(removed) > 0 {
+		a.logger.Console("Removed %d old automatic backups", len(removed))
+	}
+}
+
+// 起動直後と 1 時間ごとに自動バックアップの時期かを確かめる
+func (a *App) runAutoBackupLoop(ctx context.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.logger.Console(fmt.Sprintf("PANIC in runAutoBackupLoop: %v\n%s", r, string(debug.Stack())))
+		}
+	}()
+	a.runAutoBackupIfDue(time.Now())
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			a.runAutoBackupIfDue(now)
+		}
+	}
+}
+
 // ------------------------------------------------------------
 // 設定関連の操作
 // ------------------------------------------------------------
 
 // 設定を読み込む
 func (a *App) LoadSettings() (*Settings, error) {
-	settings, err := a.settingsService.LoadSettings()
+	settings, err := a.currentSettingsService().LoadSettings()
 	if err != nil {
 		a.logger.Console("failed to load settings: %v", err)
 		return nil, err
@@ -836,7 +2020,17 @@ func (a *App) LoadSettings() (*Settings, error) {
 
 // 設定を保存する
 func (a *App) SaveSettings(settings *Settings) error {
-	if err := a.settingsService.SaveSettings(settings); err != nil {
+	settingsService := a.currentSettingsService()
+	// ローカル API と同期プロバイダーの設定は専用のメソッドでのみ変更する（エディタ設定の保存で消さない）
+	if settings != nil {
+		if current, err := settingsService.LoadSettings(); err == nil {
+			settings.LocalAPIEnabled = current.LocalAPIEnabled
+			settings.LocalAPIPort = current.LocalAPIPort
+			settings.LocalAPIToken = current.LocalAPIToken
+			settings.SyncProvider = current.SyncProvider
+		}
+	}
+	if err := settingsService.SaveSettings(settings); err != nil {
 		return err
 	}
 	if settings != nil {
@@ -845,15 +2039,85 @@ func (a *App) SaveSettings(settings *Settings) error {
 	return nil
 }
 
+// ローカル HTTP API の状態を返す ------------------------------------------------------------
+func (a *App) GetLocalAPIInfo() (LocalAPIInfo, error) {
+	settings, err := a.currentSettingsService().LoadSettings()
+	if err != nil {
+		return LocalAPIInfo{}, err
+	}
+
+	a.localAPIMu.Lock()
+	defer a.localAPIMu.Unlock()
+	info := LocalAPIInfo{
+		Enabled: settings.LocalAPIEnabled,
+		Token:   settings.LocalAPIToken,
+	}
+	if a.localAPI != nil {
+		info.Running = true
+		info.URL = a.localAPI.baseURL()
+	}
+	return info, nil
+}
+
+// ローカル HTTP API を有効化・無効化する ------------------------------------------------------------
+func (a *App) SetLocalAPIEnabled(enabled bool) (LocalAPIInfo, error) {
+	settingsService := a.currentSettingsService()
+	settings, err := settingsService.LoadSettings()
+	if err != nil {
+		return LocalAPIInfo{}, err
+	}
+	settings.LocalAPIEnabled = enabled
+	if err := settingsService.SaveSettings(settings); err != nil {
+		return LocalAPIInfo{}, err
+	}
+
+	a.stopLocalAPI()
+	if enabled {
+		if err := a.startLocalAPIIfEnabled(); err != nil {
+			info, _ := a.GetLocalAPIInfo()
+			info.Error = err.Error()
+			return info, nil
+		}
+	}
+	return a.GetLocalAPIInfo()
+}
+
+// ローカル HTTP API のトークンを作り直す（古いトークンは直ちに無効になる） ------------------------------------------------------------
+func (a *App) RegenerateLocalAPIToken() (LocalAPIInfo, error) {
+	settingsService := a.currentSettingsService()
+	token, err := generateLocalAPIToken()
+	if err != nil {
+		return LocalAPIInfo{}, err
+	}
+	settings, err := settingsService.LoadSettings()
+	if err != nil {
+		return LocalAPIInfo{}, err
+	}
+	settings.LocalAPIToken = token
+	if err := settingsService.SaveSettings(settings); err != nil {
+		return LocalAPIInfo{}, err
+	}
+
+	a.stopLocalAPI()
+	if err := a.startLocalAPIIfEnabled(); err != nil {
+		info, _ := a.GetLocalAPIInfo()
+		info.Error = err.Error()
+		return info, nil
+	}
+	return a.GetLocalAPIInfo()
+}
+
 // 最後に選択されたノートの情報を記録する（終了時にsettings.jsonへ保存される）
 func (a *App) SetLastActiveNote(noteId string, isFile bool) {
+	a.servicesMu.Lock()
+	defer a.servicesMu.Unlock()
 	a.lastActiveNoteId = noteId
 	a.lastActiveNoteIsFile = isFile
 }
 
 // ウィンドウの状態を保存する
 func (a *App) SaveWindowState(ctx *Context) error {
-	return a.settingsService.SaveWindowState(ctx)
+	return a.currentSettingsService().SaveWindowState(ctx)
 }
 
 // ウィンドウを前面に表示する
@@ -895,12 +2159,12 @@ func (a *App) OpenURL(url string) error {
 
 // OpenAppFolder はアプリケーションデータフォルダをOSのファイルマネージャーで開きます
 func (a *App) OpenAppFolder() error {
-	return a.fileService.OpenFolder(a.appDataDir)
+	return a.fileService.OpenFolder(a.currentAppDataDir())
 }
 
 // OpenConflictBackupFolder は競合バックアップフォルダをOSのファイルマネージャーで開きます
 func (a *App) OpenConflictBackupFolder() error {
-	backupDir := filepath.Join(a.appDataDir, cloudWinBackupDirName)
+	backupDir := filepath.Join(a.currentAppDataDir(), cloudWinBackupDirName)
 	if err := os.MkdirAll(backupDir, 0755); err != nil {
 		return err
 	}
@@ -909,22 +2173,64 @@ func (a *App) OpenConflictBackupFolder() error {
 
 // ListCloudConflictBackups は競合バックアップを新しい順に列挙して返します
 func (a *App) ListCloudConflictBackups() ([]ConflictBackupEntry, error) {
-	backupDir := filepath.Join(a.appDataDir, cloudWinBackupDirName)
+	backupDir := filepath.Join(a.currentAppDataDir(), cloudWinBackupDirName)
 	return listCloudConflictBackups(backupDir)
 }
 
 // DeleteCloudConflictBackup は指定されたファイル名のバックアップを 1 件削除します
 func (a *App) DeleteCloudConflictBackup(filename string) error {
-	backupDir := filepath.Join(a.appDataDir, cloudWinBackupDirName)
+	backupDir := filepath.Join(a.currentAppDataDir(), cloudWinBackupDirName)
 	return deleteCloudConflictBackup(backupDir, filename)
 }
 
+// DiffCloudConflictBackup は競合バックアップと現在のノートの行単位の差分を返します
+func (a *App) DiffCloudConflictBackup(filename string) (*ConflictBackupDiff, error) {
+	backupDir := filepath.Join(a.currentAppDataDir(), cloudWinBackupDirName)
+	record, err := readCloudConflictBackup(backupDir, filename)
+	if err != nil {
+		return nil, err
+	}
+	return a.currentNoteService().DiffConflictBackup(filename, record)
+}
+
+// ResolveCloudConflictBackup は競合バックアップの解決（ローカル版・クラウド版・マージした本文・別ノートとして復元）を適用します
+// 変更したノートは dirty にして同期し、解決済みのバックアップは削除します
+func (a *App) ResolveCloudConflictBackup(filename string, resolution ConflictResolution) (*Note, error) {
+	noteService, syncState := a.currentNoteServices()
+	backupDir := filepath.Join(a.currentAppDataDir(), cloudWinBackupDirName)
+	record, err := readCloudConflictBackup(backupDir, filename)
+	if err != nil {
+		return nil, err
+	}
+	note, changed, err := noteService.ResolveConflictBackup(record, resolution)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		if syncState != nil {
+			syncState.MarkNoteDirty(note.ID)
+		}
+		a.triggerSyncIfConnected()
+	}
+	if err := deleteCloudConflictBackup(backupDir, filename); err != nil {
+		a.logger.Console("Failed to remove resolved conflict backup %s: %v", filename, err)
+	}
+	return note, nil
+}
+
 // DeleteAllCloudConflictBackups はすべての競合バックアップを削除します
 func (a *App) DeleteAllCloudConflictBackups() error {
-	backupDir := filepath.Join(a.appDataDir, cloudWinBackupDirName)
+	backupDir := filepath.Join(a.currentAppDataDir(), cloudWinBackupDirName)
 	return deleteAllCloudConflictBackups(backupDir)
 }
 
+// GetSyncHistory はこの端末での同期の記録を新しい順に最大 limit 件（0 以下なら全件）返します
+// 同期ごとのきっかけと、ノートごとの処理（アップロード・ダウンロード・バックアップ・削除・延期）とその理由を含みます
+func (a *App) GetSyncHistory(limit int) ([]SyncRun, error) {
+	// 履歴ファイルは置き換えで書き込むので、同期中に別インスタンスから読んでも壊れた内容は見えない
+	return newSyncJournal(a.currentAppDataDir()).list(limit)
+}
+
 // CheckFileExists は指定されたパスのファイルが存在するかチェックします
 func (a *App) CheckFileExists(path string) bool {
 	return a.fileService.CheckFileExists(path)
@@ -932,12 +2238,12 @@ func (a *App) CheckFileExists(path string) bool {
 
 // LoadRecentFiles は最近開いたファイルのパスリストを返します
 func (a *App) LoadRecentFiles() ([]string, error) {
-	return a.recentFilesService.LoadRecentFiles()
+	return a.currentRecentFilesService().LoadRecentFiles()
 }
 
 // SaveRecentFiles は最近開いたファイルのパスリストを保存します
 func (a *App) SaveRecentFiles(list []string) error {
-	return a.recentFilesService.SaveRecentFiles(list)
+	return a.currentRecentFilesService().SaveRecentFiles(list)
 }
 
 // GetSystemLocale はOSのシステムロケールを返します
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sync"
 	"time"
 
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
@@ -32,16 +33,23 @@ type AppLogger interface {
 	ErrorCode(err error, code string, args map[string]interface{}) error           // 多言語対応エラーメッセージ
 	ErrorWithNotifyCode(err error, code string, args map[string]interface{}) error // 多言語対応エラーメッセージ（ダイアログ通知付き）
 	IsTestMode() bool
-	SetDebugMode(isDebug bool) // デバッグモードの設定
+	SetDebugMode(isDebug bool)                                  // デバッグモードの設定
+	SetLogDir(appDataDir string)                                // ログの出力先をプロファイルのデータディレクトリに切り替える
+	SetEventMirror(mirror func(event string, data interface{})) // フロントエンドへの通知を他の購読者（ローカル API）にも流す
 }
 
 // appLoggerImpl はAppLoggerの実装
 type appLoggerImpl struct {
 	ctx        context.Context
 	isTestMode bool
-	logFile    *os.File
-	logDir     string
-	isDebug    bool
+
+	fileMu  sync.Mutex // logFile / logDir / isDebug の排他（プロファイルの切り替えとログ出力が並行する）
+	logFile *os.File
+	logDir  string
+	isDebug bool
+
+	mirrorMu sync.Mutex
+	mirror   func(event string, data interface{})
 }
 
 // NewAppLogger は新しいAppLoggerインスタンスを作成
@@ -59,6 +67,8 @@ func NewAppLogger(ctx context.Context, isTestMode bool, appDataDir string) AppLo
 
 // SetDebugMode はデバッグモードを設定し、必要に応じてログファイルを開く/閉じる
 func (l *appLoggerImpl) SetDebugMode(isDebug bool) {
+	l.fileMu.Lock()
+	defer l.fileMu.Unlock()
 	if l.isDebug == isDebug {
 		return
 	}
@@ -83,8 +93,26 @@ func (l *appLoggerImpl) SetDebugMode(isDebug bool) {
 	}
 }
 
+// SetLogDir はデバッグログを閉じて出力先を appDataDir/logs に切り替える
+// ロガーは作り直さずに使い続けるので、サービスが保持している参照もそのまま新しい出力先に書く。
+func (l *appLoggerImpl) SetLogDir(appDataDir string) {
+	logDir := filepath.Join(appDataDir, "logs")
+	os.MkdirAll(logDir, 0755)
+
+	l.fileMu.Lock()
+	defer l.fileMu.Unlock()
+	if l.logFile != nil {
+		l.logFile.Close()
+		l.logFile = nil
+	}
+	l.isDebug = false
+	l.logDir = logDir
+}
+
 // writeToLog はログファイルに書き込みを行う
 func (l *appLoggerImpl) writeToLog(message string) {
+	l.fileMu.Lock()
+	defer l.fileMu.Unlock()
 	if l.logFile != nil && l.isDebug {
 		message = redactLogMessage(message)
 		timestamp := time.Now().Format("2006-01-02 15:04:05")
@@ -101,29 +129,47 @@ func (l *appLoggerImpl) writeToLog(message string) {
 
 // ドライブの状態をフロントエンドに通知
 func (l *appLoggerImpl) NotifyDriveStatus(ctx context.Context, status string) {
-	if !l.isTestMode {
-		wailsRuntime.EventsEmit(l.ctx, "drive:status", status)
-	}
+	l.emitEvent("drive:status", status)
 }
 
 // フロントエンドに同期完了を通知してリロード
 func (l *appLoggerImpl) NotifyFrontendSyncedAndReload(ctx context.Context) {
-	if !l.isTestMode {
-		wailsRuntime.EventsEmit(l.ctx, "notes:updated")
-		wailsRuntime.EventsEmit(l.ctx, "notes:reload")
-	}
+	l.emitEvent("notes:updated", nil)
+	l.emitEvent("notes:reload", nil)
 }
 
 // 整合性修復の確認が必要な通知
 func (l *appLoggerImpl) NotifyIntegrityIssues(ctx context.Context, issues []IntegrityIssue) {
-	if !l.isTestMode {
-		wailsRuntime.EventsEmit(l.ctx, "notes:integrity-issues", issues)
-	}
+	l.emitEvent("notes:integrity-issues", issues)
 }
 
 func (l *appLoggerImpl) NotifyOrphanRecoveries(ctx context.Context, recoveries []OrphanRecoveryInfo) {
+	l.emitEvent("notes:orphans-recovered", recoveries)
+}
+
+// SetEventMirror はフロントエンドへ通知したイベントを mirror にも渡すよう設定する（nil で解除）
+func (l *appLoggerImpl) SetEventMirror(mirror func(event string, data interface{})) {
+	l.mirrorMu.Lock()
+	defer l.mirrorMu.Unlock()
+	l.mirror = mirror
+}
+
+// emitEvent はフロントエンドにイベントを発行し、mirror が設定されていればそちらにも渡す。
+// mirror はテストモードでも呼ぶ（Wails のランタイムに依存しないため）。
+func (l *appLoggerImpl) emitEvent(event string, data interface{}) {
 	if !l.isTestMode {
-		wailsRuntime.EventsEmit(l.ctx, "notes:orphans-recovered", recoveries)
+		if data == nil {
+			wailsRuntime.EventsEmit(l.ctx, event)
+		} else {
+			wailsRuntime.EventsEmit(l.ctx, event, data)
+		}
+	}
+
+	l.mirrorMu.Lock()
+	mirror := l.mirror
+	l.mirrorMu.Unlock()
+	if mirror != nil {
+		mirror(event, data)
 	}
 }
 
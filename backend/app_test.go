@@ -246,14 +246,21 @@ func TestDeleteNoteWithSync(t *testing.T) {
 	err = helper.app.DeleteNote(note.ID)
 	assert.NoError(t, err)
 
-	// ノートが削除されたことを確認
-	_, err = helper.app.LoadNote(note.ID)
-	assert.Error(t, err)
-
-	// メタデータから削除されていることを確認
+	// メタデータから削除され、ゴミ箱に移っていることを確認
 	notes, err := helper.app.ListNotes()
 	assert.NoError(t, err)
 	assert.Empty(t, notes)
+	trash := helper.app.ListTrash()
+	if assert.Len(t, trash, 1) {
+		assert.Equal(t, note.ID, trash[0].ID)
+	}
+	assert.FileExists(t, filepath.Join(helper.app.notesDir, note.ID+".json"))
+
+	// ゴミ箱を空にするとファイルも削除される
+	assert.NoError(t, helper.app.EmptyTrash())
+	_, err = helper.app.LoadNote(note.ID)
+	assert.Error(t, err)
+	assert.Empty(t, helper.app.ListTrash())
 }
 
 // TestSaveNoteListWithSync はノートリストの保存と同期をテストします
@@ -0,0 +1,212 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// ------------------------------------------------------------
+// バイナリファイルの判定と 16 進ダンプ
+// ------------------------------------------------------------
+//
+// OpenFile は先頭の binarySniffSize バイトを調べ、バイナリなら内容を返さずに Binary=true を返す。
+// - 既知のマジックナンバー (PNG・ZIP・ELF など) で始まる
+// - NUL を含む (BOM 付きの UTF-16 は除く)
+// - どの文字コードで読んでも不正なバイトが残る、または制御文字が多い
+// バイナリは ReadHexDump で 16 進と ASCII の表示を少しずつ読む (読み取り専用)。
+// テキストとして保存すると壊れるので、ディスク上のファイルがバイナリなら SaveFile を拒む。
+
+const (
+	// 先頭のこのバイト数でバイナリかを判定する
+	binarySniffSize = 8000
+	// ダンプ 1 行あたりのバイト数
+	hexDumpRowBytes = 16
+	// ReadHexDump で一度に返す最大バイト数
+	hexDumpMaxBytes = 64 << 10
+)
+
+// binaryMagic はバイナリ形式を表す先頭のバイト列
+type binaryMagic struct {
+	offset int
+	magic  string
+	format string
+	weak   bool // テキストの書き出しとしてもあり得るので、名前を付けるだけ（判定は内容で行う）
+}
+
+var binaryMagics = []binaryMagic{
+	{0, "\x89PNG\r\n\x1a\n", "png", false},
+	{0, "\xff\xd8\xff", "jpeg", false},
+	{0, "GIF87a", "gif", false},
+	{0, "GIF89a", "gif", false},
+	{0, "%PDF-", "pdf", false},
+	{0, "PK\x03\x04", "zip", false},
+	{0, "PK\x05\x06", "zip", false},
+	{0, "\x1f\x8b", "gzip", false},
+	{0, "7z\xbc\xaf\x27\x1c", "7z", false},
+	{0, "Rar!\x1a\x07", "rar", false},
+	{0, "\x7fELF", "elf", false},
+	{0, "\x00asm", "wasm", false},
+	{0, "SQLite format 3\x00", "sqlite", false},
+	{0, "\xfe\xed\xfa\xce", "mach-o", false},
+	{0, "\xfe\xed\xfa\xcf", "mach-o", false},
+	{0, "\xce\xfa\xed\xfe", "mach-o", false},
+	{0, "\xcf\xfa\xed\xfe", "mach-o", false},
+	{0, "\xca\xfe\xba\xbe", "java-class", false},
+	{0, "II*\x00", "tiff", false},
+	{0, "MM\x00*", "tiff", false},
+	{0, "RIFF", "riff", false},
+	{0, "OggS", "ogg", false},
+	{4, "ftyp", "mp4", false},
+	{0, "MZ", "exe", true},
+	{0, "BM", "bmp", true},
+	{0, "ID3", "mp3", true},
+}
+
+// HexDumpRow はダンプの 1 行
+type HexDumpRow struct {
+	Offset int64  `json:"offset"` // 行の先頭のファイル内位置
+	Hex    string `json:"hex"`    // 16 進表記（8 バイトごとに区切る）
+	ASCII  string `json:"ascii"`  // 表示できない文字は "."
+}
+
+// HexDump は ReadHexDump の結果
+type HexDump struct {
+	FilePath string       `json:"filePath"`
+	Offset   int64        `json:"offset"` // Rows[0] の位置（hexDumpRowBytes の倍数）
+	Size     int64        `json:"size"`   // ファイルのバイト数
+	Rows     []HexDumpRow `json:"rows"`
+}
+
+// detectBinary は先頭部分がバイナリかを判定し、分かれば形式の名前も返す。
+// truncated はファイルの途中で切った先頭部分かどうか。
+func detectBinary(sample []byte, truncated bool) (bool, string) {
+	format, weak := magicBinaryFormat(sample)
+	if format != "" && !weak {
+		return true, format
+	}
+	if truncated {
+		sample = trimTextSample(sample)
+	}
+	if looksBinary(sample) {
+		return true, format
+	}
+	return false, ""
+}
+
+func magicBinaryFormat(data []byte) (string, bool) {
+	for _, m := range binaryMagics {
+		if len(data) >= m.offset+len(m.magic) && string(data[m.offset:m.offset+len(m.magic)]) == m.magic {
+			return m.format, m.weak
+		}
+	}
+	return "", false
+}
+
+// looksBinary はどの文字コードでもテキストとして読めないかを返す
+func looksBinary(data []byte) bool {
+	content, format := decodeText(data)
+	utf16 := format.Encoding == textEncodingUTF16LE || format.Encoding == textEncodingUTF16BE
+	if !utf16 && bytes.IndexByte(data, 0) >= 0 {
+		return true
+	}
+	// UTF-8 として正しいデータの U+FFFD は本文の一部だが、それ以外は読めなかったバイト
+	if format.Encoding != textEncodingUTF8 && strings.ContainsRune(content, utf8.RuneError) {
+		return true
+	}
+	controls, total := 0, 0
+	for _, r := range content {
+		total++
+		if (r < 0x20 && !strings.ContainsRune("\t\n\r\f\v\x1b", r)) || r == 0x7f {
+			controls++
+		}
+	}
+	return controls*10 > total
+}
+
+// isBinaryData は読み込んだ内容がバイナリかを返す
+func isBinaryData(data []byte) bool {
+	binary, _ := detectBinary(data[:min(len(data), binarySniffSize)], len(data) > binarySniffSize)
+	return binary
+}
+
+// sniffBinaryFile はファイルの先頭を読んでバイナリかを判定する
+func sniffBinaryFile(path string) (bool, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, "", err
+	}
+	defer f.Close()
+	sample := make([]byte, binarySniffSize+1)
+	n, err := io.ReadFull(f, sample)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, "", err
+	}
+	truncated := n > binarySniffSize
+	binary, format := detectBinary(sample[:min(n, binarySniffSize)], truncated)
+	return binary, format, nil
+}
+
+// isBinaryFile はディスク上のファイルがバイナリかを返す (無い・読めないファイルは false)
+func isBinaryFile(path string) bool {
+	binary, _, err := sniffBinaryFile(path)
+	return err == nil && binary
+}
+
+// ReadHexDump は offset から length バイトを 16 進と ASCII で返します
+// offset は行の先頭に切り下げ、length は hexDumpMaxBytes までにします
+func (s *fileService) ReadHexDump(filePath string, offset int64, length int) (*HexDump, error) {
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("invalid range: offset %d, length %d", offset, length)
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	offset -= offset % hexDumpRowBytes
+	length = min(length, hexDumpMaxBytes)
+	dump := &HexDump{FilePath: filePath, Offset: offset, Size: info.Size(), Rows: []HexDumpRow{}}
+	if offset >= info.Size() || length == 0 {
+		return dump, nil
+	}
+	data := make([]byte, min(int64(length), info.Size()-offset))
+	n, err := f.ReadAt(data, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	data = data[:n]
+	for start := 0; start < len(data); start += hexDumpRowBytes {
+		row := data[start:min(start+hexDumpRowBytes, len(data))]
+		dump.Rows = append(dump.Rows, formatHexDumpRow(offset+int64(start), row))
+	}
+	return dump, nil
+}
+
+func formatHexDumpRow(offset int64, row []byte) HexDumpRow {
+	parts := make([]string, 0, len(row))
+	ascii := make([]byte, len(row))
+	for i, b := range row {
+		parts = append(parts, hex.EncodeToString([]byte{b}))
+		if b >= 0x20 && b < 0x7f {
+			ascii[i] = b
+		} else {
+			ascii[i] = '.'
+		}
+	}
+	hexText := strings.Join(parts[:min(len(parts), 8)], " ")
+	if len(parts) > 8 {
+		hexText += "  " + strings.Join(parts[8:], " ")
+	}
+	return HexDumpRow{Offset: offset, Hex: hexText, ASCII: string(ascii)}
+}
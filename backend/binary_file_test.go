@@ -0,0 +1,100 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/japanese"
+	textunicode "golang.org/x/text/encoding/unicode"
+)
+
+func TestDetectBinary(t *testing.T) {
+	utf16, err := textunicode.UTF16(textunicode.LittleEndian, textunicode.UseBOM).NewEncoder().Bytes([]byte("テキスト\r\n"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		binary bool
+		format string
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), true, "png"},
+		{"zip", []byte("PK\x03\x04\x14\x00\x00\x00"), true, "zip"},
+		{"NUL", []byte("abc\x00def"), true, ""},
+		{"control characters", []byte("\x01\x02\x03\x04abc"), true, ""},
+		{"undefined windows-1252 bytes", []byte("abc\x81\x8d\x8f"), true, ""},
+		{"exe", []byte("MZ\x90\x00\x03\x00"), true, "exe"},
+		{"text starting like exe", []byte("MZ is not always an executable\n"), false, ""},
+		{"UTF-8", []byte("日本語\tテキスト\n"), false, ""},
+		{"UTF-16 with BOM", utf16, false, ""},
+		{"Shift_JIS", mustEncode(t, japanese.ShiftJIS, "日本語のテキスト\n"), false, ""},
+		{"ANSI colored log", []byte("\x1b[31mERROR\x1b[0m failed\n"), false, ""},
+		{"empty", []byte{}, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binary, format := detectBinary(tt.data, false)
+			assert.Equal(t, tt.binary, binary)
+			assert.Equal(t, tt.format, format)
+		})
+	}
+
+	// 先頭部分の末尾で切れた文字はバイナリの根拠にしない
+	sample := []byte(strings.Repeat("あ", binarySniffSize/3+1))[:binarySniffSize]
+	binary, _ := detectBinary(sample, true)
+	assert.False(t, binary)
+}
+
+func TestOpenFile_BinaryIsNotDecoded(t *testing.T) {
+	fs := NewFileService(&Context{})
+	path := filepath.Join(t.TempDir(), "image.png")
+	data := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 40)...)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+
+	result, err := fs.OpenFile(path)
+	require.NoError(t, err)
+	assert.True(t, result.Binary)
+	assert.Equal(t, "png", result.BinaryFormat)
+	assert.Empty(t, result.Content)
+	assert.Equal(t, int64(len(data)), result.Size)
+	assert.True(t, isBinaryFile(path))
+
+	app := &App{fileService: fs}
+	_, err = app.SaveFile(path, "text")
+	assert.Error(t, err)
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, data, after, "a binary file is never overwritten with text")
+}
+
+func TestReadHexDump(t *testing.T) {
+	fs := NewFileService(&Context{})
+	path := filepath.Join(t.TempDir(), "data.bin")
+	data := []byte("0123456789abcdef\x00\x01\x02ABC\xff")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+
+	dump, err := fs.ReadHexDump(path, 5, 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), dump.Offset, "offset is rounded down to a row")
+	assert.Equal(t, int64(len(data)), dump.Size)
+	require.Len(t, dump.Rows, 2)
+	assert.Equal(t, "30 31 32 33 34 35 36 37  38 39 61 62 63 64 65 66", dump.Rows[0].Hex)
+	assert.Equal(t, "0123456789abcdef", dump.Rows[0].ASCII)
+	assert.Equal(t, HexDumpRow{Offset: 16, Hex: "00 01 02 41 42 43 ff", ASCII: "...ABC."}, dump.Rows[1])
+
+	dump, err = fs.ReadHexDump(path, 16, 4)
+	require.NoError(t, err)
+	require.Len(t, dump.Rows, 1)
+	assert.Equal(t, "00 01 02 41", dump.Rows[0].Hex)
+
+	dump, err = fs.ReadHexDump(path, 1000, 16)
+	require.NoError(t, err)
+	assert.Empty(t, dump.Rows)
+
+	_, err = fs.ReadHexDump(path, -1, 16)
+	assert.Error(t, err)
+}
@@ -243,3 +243,110 @@ func TestListCloudConflictBackups_FallsBackToFileMTimeWhenCreatedAtMissing(t *te
 	// CreatedAt が mtime にフォールバックされていること
 	assert.True(t, strings.HasPrefix(got[0].CreatedAt, "2024-08-15"), "createdAt=%s", got[0].CreatedAt)
 }
+
+func TestDiffConflictBackup(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+	backupDir := filepath.Join(helper.tempDir, cloudWinBackupDirName)
+
+	require.NoError(t, ns.SaveNote(&Note{ID: "note1", Title: "note1", Content: "a\nb\ncloud\n", Language: "plaintext"}))
+	name := writeTestBackup(t, backupDir, cloudBackupFilePrefixWins, time.Now(), "note1", cloudWinBackupRecord{
+		LocalNote: &Note{ID: "note1", Title: "note1", Content: "a\nlocal\n", Language: "plaintext"},
+		CloudNote: &Note{ID: "note1", Title: "note1", Content: "a\nb\ncloud\n", Language: "plaintext"},
+	})
+
+	_, err := readCloudConflictBackup(backupDir, "../"+name)
+	assert.Error(t, err)
+	record, err := readCloudConflictBackup(backupDir, name)
+	require.NoError(t, err)
+
+	diff, err := ns.DiffConflictBackup(name, record)
+	require.NoError(t, err)
+	assert.Equal(t, "cloud_wins", diff.Kind)
+	assert.False(t, diff.Identical)
+	require.NotNil(t, diff.Current)
+	assert.Equal(t, []DiffLine{
+		{Kind: "equal", Text: "a", OldLine: 1, NewLine: 1},
+		{Kind: "delete", Text: "local", OldLine: 2},
+		{Kind: "insert", Text: "b", NewLine: 2},
+		{Kind: "insert", Text: "cloud", NewLine: 3},
+	}, diff.Lines)
+	assert.Equal(t, "a\n<<<<<<< local\nlocal\n=======\nb\ncloud\n>>>>>>> cloud\n", diff.Merged)
+	assert.True(t, hasConflictMarkers(diff.Merged))
+
+	// 削除されたノートのバックアップは現在のノート無しで比較する
+	deleted := writeTestBackup(t, backupDir, cloudBackupFilePrefixDelete, time.Now(), "gone", cloudWinBackupRecord{
+		LocalNote: &Note{ID: "gone", Title: "gone", Content: "x\n", Language: "plaintext"},
+	})
+	record, err = readCloudConflictBackup(backupDir, deleted)
+	require.NoError(t, err)
+	diff, err = ns.DiffConflictBackup(deleted, record)
+	require.NoError(t, err)
+	assert.Nil(t, diff.Current)
+	assert.Equal(t, []DiffLine{{Kind: "delete", Text: "x", OldLine: 1}}, diff.Lines)
+}
+
+func TestResolveConflictBackup(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+	load := func(id string) *Note {
+		note, err := ns.LoadNote(id)
+		require.NoError(t, err)
+		return note
+	}
+
+	folder, err := ns.CreateFolder("work")
+	require.NoError(t, err)
+	require.NoError(t, ns.SaveNote(&Note{ID: "note1", Title: "note1", Content: "cloud", Language: "plaintext"}))
+	require.NoError(t, ns.MoveNoteToFolder("note1", folder.ID))
+	record := &cloudWinBackupRecord{
+		NoteID:    "note1",
+		LocalNote: &Note{ID: "note1", Title: "local title", Content: "local", Language: "markdown", Tags: []string{"kept"}},
+	}
+
+	note, changed, err := ns.ResolveConflictBackup(record, ConflictResolution{Action: conflictResolutionCloud})
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, "cloud", note.Content)
+
+	_, _, err = ns.ResolveConflictBackup(record, ConflictResolution{Action: conflictResolutionMerged, MergedText: "<<<<<<< local\nlocal\n=======\ncloud\n>>>>>>> cloud\n"})
+	assert.ErrorContains(t, err, "conflict markers")
+	note, changed, err = ns.ResolveConflictBackup(record, ConflictResolution{Action: conflictResolutionMerged, MergedText: "local\ncloud\n"})
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "note1", note.Title)
+	assert.Equal(t, "local\ncloud\n", load("note1").Content)
+
+	note, _, err = ns.ResolveConflictBackup(record, ConflictResolution{Action: conflictResolutionLocal})
+	require.NoError(t, err)
+	loaded := load("note1")
+	assert.Equal(t, "local", loaded.Content)
+	assert.Equal(t, "local title", loaded.Title)
+	assert.Equal(t, []string{"kept"}, loaded.Tags)
+	assert.Equal(t, folder.ID, note.FolderID)
+
+	note, _, err = ns.ResolveConflictBackup(record, ConflictResolution{Action: conflictResolutionNew})
+	require.NoError(t, err)
+	assert.NotEqual(t, "note1", note.ID)
+	assert.Equal(t, "local", load(note.ID).Content)
+	for _, metadata := range ns.noteList.Notes {
+		if metadata.ID == note.ID {
+			assert.Equal(t, folder.ID, metadata.FolderID)
+		}
+	}
+	assert.NotContains(t, ns.GetTopLevelOrder(), TopLevelItem{Type: "note", ID: note.ID})
+
+	_, _, err = ns.ResolveConflictBackup(record, ConflictResolution{Action: "unknown"})
+	assert.Error(t, err)
+
+	// クラウドで削除されたノートはローカル版で作り直せる
+	deleted := &cloudWinBackupRecord{NoteID: "gone", LocalNote: &Note{ID: "gone", Title: "gone", Content: "keep me", Language: "plaintext"}}
+	_, _, err = ns.ResolveConflictBackup(deleted, ConflictResolution{Action: conflictResolutionMerged, MergedText: "x"})
+	assert.Error(t, err)
+	_, changed, err = ns.ResolveConflictBackup(deleted, ConflictResolution{Action: conflictResolutionLocal})
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "keep me", load("gone").Content)
+}
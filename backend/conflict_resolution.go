@@ -0,0 +1,201 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+// 競合バックアップの比較と解決
+// ------------------------------------------------------------
+
+// 競合バックアップの解決方法 (ConflictResolution.Action)
+const (
+	conflictResolutionLocal  = "local"  // バックアップ (ローカル版) の内容で現在のノートを置き換える
+	conflictResolutionCloud  = "cloud"  // 現在のノート (クラウド版) をそのまま残す
+	conflictResolutionMerged = "merged" // 手でマージした本文を現在のノートに書き込む
+	conflictResolutionNew    = "new"    // バックアップを同じフォルダに別のノートとして復元する
+)
+
+// readCloudConflictBackup は 1 件のバックアップを読み込む
+func readCloudConflictBackup(backupDir, filename string) (*cloudWinBackupRecord, error) {
+	if err := validateCloudConflictBackupFilename(filename); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(backupDir, filename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conflict backup %s: %w", filename, err)
+	}
+	var record cloudWinBackupRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse conflict backup %s: %w", filename, err)
+	}
+	if record.LocalNote == nil {
+		return nil, fmt.Errorf("conflict backup %s has no local note", filename)
+	}
+	return &record, nil
+}
+
+// diffLineRecords は from → to の行単位の差分を、表示用の行の並びにする
+func diffLineRecords(from, to string) []DiffLine {
+	fromLines := splitLinesKeepEnds(from)
+	toLines := splitLinesKeepEnds(to)
+	edits := diffLines(fromLines, toLines)
+	lines := make([]DiffLine, 0, len(edits))
+	for _, e := range edits {
+		switch e.kind {
+		case lineEditEqual:
+			lines = append(lines, DiffLine{Kind: "equal", Text: trimLineEnd(fromLines[e.aIndex]), OldLine: e.aIndex + 1, NewLine: e.bIndex + 1})
+		case lineEditDelete:
+			lines = append(lines, DiffLine{Kind: "delete", Text: trimLineEnd(fromLines[e.aIndex]), OldLine: e.aIndex + 1})
+		case lineEditInsert:
+			lines = append(lines, DiffLine{Kind: "insert", Text: trimLineEnd(toLines[e.bIndex]), NewLine: e.bIndex + 1})
+		}
+	}
+	return lines
+}
+
+func trimLineEnd(line string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+}
+
+// noteFolderIDLocked は noteList 上のノートの所属フォルダを返す (ノートファイルには保存されないため)
+func (s *noteService) noteFolderIDLocked(noteID string) (folderID string, ok bool) {
+	for _, metadata := range s.noteList.Notes {
+		if metadata.ID == noteID {
+			return metadata.FolderID, true
+		}
+	}
+	return "", false
+}
+
+// 競合バックアップと現在のノートの差分を返す ------------------------------------------------------------
+// 暗号化されたノートは本文が暗号文なので比較できない。
+func (s *noteService) DiffConflictBackup(filename string, record *cloudWinBackupRecord) (*ConflictBackupDiff, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	backup := record.LocalNote
+	var current *Note
+	if _, listed := s.noteFolderIDLocked(backup.ID); listed {
+		note, err := s.loadNoteLocked(backup.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load note %s: %w", backup.ID, err)
+		}
+		current = note
+	}
+	if backup.Encrypted || (current != nil && current.Encrypted) {
+		return nil, fmt.Errorf("cannot diff encrypted note %s", backup.ID)
+	}
+
+	currentContent := ""
+	if current != nil {
+		currentContent = current.Content
+	}
+	merged, _ := markLineConflicts(backup.Content, currentContent)
+	return &ConflictBackupDiff{
+		Filename:  filename,
+		Kind:      conflictBackupKindFromName(filename),
+		Backup:    backup,
+		Current:   current,
+		Cloud:     record.CloudNote,
+		Lines:     diffLineRecords(backup.Content, currentContent),
+		Merged:    merged,
+		Identical: current != nil && current.Content == backup.Content,
+	}, nil
+}
+
+// 競合バックアップの解決を適用し、結果のノートを返す ------------------------------------------------------------
+// "cloud" は現在のノートを変えないので、戻り値の changed が false になる (同期不要)。
+func (s *noteService) ResolveConflictBackup(record *cloudWinBackupRecord, resolution ConflictResolution) (note *Note, changed bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	backup := record.LocalNote
+	folderID, listed := s.noteFolderIDLocked(backup.ID)
+	var current *Note
+	if listed {
+		if current, err = s.loadNoteLocked(backup.ID); err != nil {
+			return nil, false, fmt.Errorf("failed to load note %s: %w", backup.ID, err)
+		}
+	}
+
+	switch resolution.Action {
+	case conflictResolutionCloud:
+		return current, false, nil
+
+	case conflictResolutionLocal:
+		resolved := *backup
+		if current != nil {
+			resolved = *current
+			resolved.Title = backup.Title
+			resolved.Content = backup.Content
+			resolved.Language = backup.Language
+			resolved.Tags = backup.Tags
+			resolved.Encrypted = backup.Encrypted
+		}
+		resolved.FolderID = folderID
+		resolved.ContentHeader = ""
+		if err := s.saveNoteLocked(&resolved, NoteRevisionSourceRestore); err != nil {
+			return nil, false, err
+		}
+		return &resolved, true, nil
+
+	case conflictResolutionMerged:
+		if current == nil {
+			return nil, false, fmt.Errorf("note %s no longer exists", backup.ID)
+		}
+		if current.Encrypted || backup.Encrypted {
+			return nil, false, fmt.Errorf("cannot merge encrypted note %s", backup.ID)
+		}
+		if hasConflictMarkers(resolution.MergedText) {
+			return nil, false, fmt.Errorf("merged text still contains conflict markers")
+		}
+		resolved := *current
+		resolved.Content = resolution.MergedText
+		resolved.FolderID = folderID
+		resolved.ContentHeader = ""
+		if err := s.saveNoteLocked(&resolved, NoteRevisionSourceRestore); err != nil {
+			return nil, false, err
+		}
+		return &resolved, true, nil
+
+	case conflictResolutionNew:
+		if backup.Encrypted {
+			// 暗号文はノート ID に結び付いているので、別のノートにはコピーできない
+			return nil, false, fmt.Errorf("cannot restore encrypted note %s as a new note", backup.ID)
+		}
+		restored := *backup
+		restored.ID = uuid.New().String()
+		restored.FolderID = ""
+		restored.ContentHeader = ""
+		if _, exists := s.folderMapLocked()[backup.FolderID]; !listed && exists {
+			folderID = backup.FolderID
+		}
+		if err := s.saveNoteLocked(&restored, NoteRevisionSourceRestore); err != nil {
+			return nil, false, err
+		}
+		if folderID != "" && !restored.Archived {
+			// 新規ノートはトップレベルに追加されるので、元のノートと同じフォルダへ移す
+			for i := range s.noteList.Notes {
+				if s.noteList.Notes[i].ID == restored.ID {
+					s.noteList.Notes[i].FolderID = folderID
+				}
+			}
+			s.removeFromTopLevelOrder(restored.ID)
+			if err := s.saveNoteList(); err != nil {
+				return nil, false, err
+			}
+			restored.FolderID = folderID
+		}
+		return &restored, true, nil
+
+	default:
+		return nil, false, fmt.Errorf("unknown conflict resolution: %s", resolution.Action)
+	}
+}
@@ -0,0 +1,563 @@
+package backend
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"monaco-notepad/backend/migration"
+)
+
+// ------------------------------------------------------------
+// ローカルデータのバックアップと復元 (zip)
+// ------------------------------------------------------------
+//
+// appDataDir のうちノートと設定に関わるもの (backupEntries) だけを 1 つの zip にまとめる。
+// 同期状態・認証トークン・同期先の接続設定・変更履歴・ログは含めない (復元先の端末のものを使う)。
+// zip には manifest.json を入れ、各ファイルのサイズと SHA-256 を記録する。
+//
+// 復元は次の順で行う。
+//  1. manifest を検証しながら restore_staging に展開する (ハッシュ不一致や想定外のパスは中止)。
+//  2. バックアップの noteList が古い形式なら backend/migration で最新まで上げる。
+//     settings.json のうち端末ごとの設定 (deviceSettingsKeys) は今のものを引き継ぐ。
+//  3. 現在のデータを restore_previous に rename で退避し、展開したものを rename で置く。
+//     途中で失敗したら置いたものを消して退避したものを戻す。
+//
+// 自動バックアップは appDataDir/backups に auto-<時刻>.zip として作り、古いものから消す。
+
+const (
+	backupFormat          = "monaco-notepad-backup"
+	backupFormatVersion   = 1
+	backupManifestName    = "manifest.json"
+	backupStagingDirName  = "restore_staging"
+	backupPreviousDirName = "restore_previous"
+	autoBackupDirName     = "backups"
+	autoBackupFilePrefix  = "auto-"
+	autoBackupTimeLayout  = "20060102-150405"
+	defaultAutoBackupKeep = 10
+)
+
+// バックアップに含める appDataDir 直下のファイルとディレクトリ
+var backupEntries = []string{
+	"notes",
+	"noteList_v2.json",
+	"settings.json",
+	"fileNotes.json",
+	"recentFiles.json",
+	cloudWinBackupDirName,
+}
+
+// 復元しても今の端末の値を残す settings.json のキー
+// (ローカル API のトークンが戻るとスクリプトが 401 になり、同期先は webdav.json などと食い違うため)
+var deviceSettingsKeys = []string{
+	"localApiEnabled",
+	"localApiPort",
+	"localApiToken",
+	"syncProvider",
+	"fileBackupMode",
+	"fileBackupKeep",
+	"windowWidth",
+	"windowHeight",
+	"windowX",
+	"windowY",
+	"isMaximized",
+}
+
+// 復元時に現在のデータから退避するだけのもの (古い noteList のバックアップや書き込み途中のファイル)
+var backupStaleEntries = []string{
+	"noteList_v2.json.bak",
+	"noteList_v2.json.tmp",
+}
+
+// manifest.json
+type backupManifest struct {
+	Format          string               `json:"format"`
+	FormatVersion   int                  `json:"formatVersion"`
+	NoteListVersion string               `json:"noteListVersion"` // バックアップした noteList の形式のバージョン
+	CreatedAt       string               `json:"createdAt"`       // RFC3339
+	Files           []backupManifestFile `json:"files"`
+}
+
+// manifest に記録する 1 ファイル分
+type backupManifestFile struct {
+	Path   string `json:"path"` // appDataDir からの相対パス ("/" 区切り)
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// isBackupEntryPath はバックアップに入れてよい相対パスかを返す
+func isBackupEntryPath(rel string) bool {
+	if rel == "" || path.IsAbs(rel) || strings.Contains(rel, `\`) || path.Clean(rel) != rel || strings.HasPrefix(rel, "../") || rel == ".." {
+		return false
+	}
+	top, _, _ := strings.Cut(rel, "/")
+	for _, entry := range backupEntries {
+		if top == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// readNoteListVersion は noteList の version を返す (読めなければ空文字)
+func readNoteListVersion(noteListPath string) string {
+	data, err := os.ReadFile(noteListPath)
+	if err != nil {
+		return ""
+	}
+	var list struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return ""
+	}
+	return list.Version
+}
+
+// createDataBackup は appDataDir のデータを destPath の zip に書き出す。
+// 一時ファイルに書いてから rename するので、失敗しても destPath に壊れた zip は残らない。
+// ノートの書き込みと並行しないよう、caller は noteService のロックを握っておく。
+func createDataBackup(appDataDir string, destPath string) (*BackupInfo, error) {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	tmpPath := destPath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	manifest := backupManifest{
+		Format:          backupFormat,
+		FormatVersion:   backupFormatVersion,
+		NoteListVersion: readNoteListVersion(filepath.Join(appDataDir, "noteList_v2.json")),
+		CreatedAt:       time.Now().Format(time.RFC3339),
+	}
+	writer := zip.NewWriter(file)
+	addFile := func(fullPath string, rel string, info fs.FileInfo) error {
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = rel
+		header.Method = zip.Deflate
+		entry, err := writer.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		source, err := os.Open(fullPath)
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+		hash := sha256.New()
+		size, err := io.Copy(io.MultiWriter(entry, hash), source)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, backupManifestFile{Path: rel, Size: size, SHA256: fmt.Sprintf("%x", hash.Sum(nil))})
+		return nil
+	}
+
+	noteCount := 0
+	for _, name := range backupEntries {
+		root := filepath.Join(appDataDir, name)
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(appDataDir, p)
+			if err != nil {
+				return err
+			}
+			if name == "notes" {
+				noteCount++
+			}
+			return addFile(p, filepath.ToSlash(rel), info)
+		})
+		if err != nil {
+			writer.Close()
+			file.Close()
+			return nil, fmt.Errorf("failed to add %s to backup: %w", name, err)
+		}
+	}
+
+	// 中身を書き終えてから manifest を最後に入れる
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err == nil {
+		var entry io.Writer
+		if entry, err = writer.Create(backupManifestName); err == nil {
+			_, err = entry.Write(data)
+		}
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	info, err := os.Stat(destPath)
+	if err != nil {
+		return nil, err
+	}
+	return &BackupInfo{Path: destPath, CreatedAt: manifest.CreatedAt, Size: info.Size(), NoteCount: noteCount}, nil
+}
+
+// readBackupManifest は zip の manifest.json を読んで形式を確かめる
+func readBackupManifest(reader *zip.Reader) (*backupManifest, error) {
+	for _, file := range reader.File {
+		if file.Name != backupManifestName {
+			continue
+		}
+		source, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer source.Close()
+		var manifest backupManifest
+		if err := json.NewDecoder(source).Decode(&manifest); err != nil {
+			return nil, fmt.Errorf("invalid backup manifest: %w", err)
+		}
+		if manifest.Format != backupFormat {
+			return nil, fmt.Errorf("not a monaco-notepad backup")
+		}
+		if manifest.FormatVersion > backupFormatVersion {
+			return nil, fmt.Errorf("backup format %d is newer than this app supports", manifest.FormatVersion)
+		}
+		if manifest.NoteListVersion != "" && migration.VersionLess(CurrentVersion, manifest.NoteListVersion) {
+			return nil, fmt.Errorf("backup note list version %s is newer than this app supports (%s)", manifest.NoteListVersion, CurrentVersion)
+		}
+		return &manifest, nil
+	}
+	return nil, fmt.Errorf("backup manifest not found")
+}
+
+// extractDataBackup は manifest の各ファイルを検証しながら destDir に展開する
+func extractDataBackup(reader *zip.Reader, manifest *backupManifest, destDir string) error {
+	expected := make(map[string]backupManifestFile, len(manifest.Files))
+	for _, file := range manifest.Files {
+		if !isBackupEntryPath(file.Path) {
+			return fmt.Errorf("unexpected path in backup manifest: %s", file.Path)
+		}
+		expected[file.Path] = file
+	}
+
+	extracted := make(map[string]bool, len(expected))
+	for _, file := range reader.File {
+		if file.Name == backupManifestName || strings.HasSuffix(file.Name, "/") {
+			continue
+		}
+		want, ok := expected[file.Name]
+		if !ok {
+			return fmt.Errorf("file not listed in backup manifest: %s", file.Name)
+		}
+		if err := extractBackupFile(file, want, filepath.Join(destDir, filepath.FromSlash(file.Name))); err != nil {
+			return err
+		}
+		extracted[file.Name] = true
+	}
+	for rel := range expected {
+		if !extracted[rel] {
+			return fmt.Errorf("backup is missing %s", rel)
+		}
+	}
+	return nil
+}
+
+func extractBackupFile(file *zip.File, want backupManifestFile, destPath string) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return err
+	}
+	source, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	dest, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	// manifest のサイズより大きい中身は読まない (展開後のサイズを偽った zip 対策)
+	size, copyErr := io.Copy(io.MultiWriter(dest, hash), io.LimitReader(source, want.Size+1))
+	if err := dest.Close(); copyErr == nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		return fmt.Errorf("failed to extract %s: %w", file.Name, copyErr)
+	}
+	if size != want.Size || fmt.Sprintf("%x", hash.Sum(nil)) != want.SHA256 {
+		return fmt.Errorf("backup is corrupted: checksum mismatch for %s", file.Name)
+	}
+	return nil
+}
+
+// swapInRestoredData は stagingDir の内容で appDataDir のバックアップ対象を置き換える。
+// 途中で失敗した場合は元の状態に戻してからエラーを返す。
+func swapInRestoredData(appDataDir string, stagingDir string) error {
+	previousDir := filepath.Join(appDataDir, backupPreviousDirName)
+	if err := os.RemoveAll(previousDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(previousDir, 0755); err != nil {
+		return err
+	}
+
+	var moved, placed []string
+	rollback := func() {
+		for _, name := range placed {
+			_ = os.RemoveAll(filepath.Join(appDataDir, name))
+		}
+		for _, name := range moved {
+			_ = os.Rename(filepath.Join(previousDir, name), filepath.Join(appDataDir, name))
+		}
+	}
+	for _, name := range append(append([]string(nil), backupEntries...), backupStaleEntries...) {
+		current := filepath.Join(appDataDir, name)
+		if _, err := os.Lstat(current); err == nil {
+			if err := os.Rename(current, filepath.Join(previousDir, name)); err != nil {
+				rollback()
+				return fmt.Errorf("failed to move aside %s: %w", name, err)
+			}
+			moved = append(moved, name)
+		}
+		staged := filepath.Join(stagingDir, name)
+		if _, err := os.Lstat(staged); err == nil {
+			if err := os.Rename(staged, current); err != nil {
+				rollback()
+				return fmt.Errorf("failed to restore %s: %w", name, err)
+			}
+			placed = append(placed, name)
+		}
+	}
+	return os.RemoveAll(previousDir)
+}
+
+// restoreDataBackup は zipPath のバックアップを検証して appDataDir に復元する。
+// ノートの読み書きと並行しないよう、caller は noteService のロックを握っておく。
+func restoreDataBackup(appDataDir string, zipPath string) (*backupManifest, error) {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer reader.Close()
+
+	manifest, err := readBackupManifest(&reader.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	stagingDir := filepath.Join(appDataDir, backupStagingDirName)
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stagingDir)
+	if err := extractDataBackup(&reader.Reader, manifest, stagingDir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(stagingDir, "notes"), 0755); err != nil {
+		return nil, err
+	}
+
+	// 古い形式の noteList は差し替える前に最新の形式に上げる
+	if manifest.NoteListVersion != "" && migration.VersionLess(manifest.NoteListVersion, CurrentVersion) {
+		if _, err := migration.RunIfNeeded(stagingDir, filepath.Join(stagingDir, "notes")); err != nil {
+			return nil, fmt.Errorf("failed to migrate backup: %w", err)
+		}
+	}
+
+	if err := keepDeviceSettings(appDataDir, stagingDir); err != nil {
+		return nil, fmt.Errorf("failed to keep device settings: %w", err)
+	}
+
+	if err := swapInRestoredData(appDataDir, stagingDir); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// keepDeviceSettings は展開した settings.json の端末ごとの設定を今の settings.json の値に置き換える
+// バックアップに settings.json が無ければ今のものをそのまま使う。知らないキーはそのまま残す。
+func keepDeviceSettings(appDataDir string, stagingDir string) error {
+	currentData, err := os.ReadFile(filepath.Join(appDataDir, "settings.json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	stagedPath := filepath.Join(stagingDir, "settings.json")
+	stagedData, err := os.ReadFile(stagedPath)
+	if os.IsNotExist(err) {
+		return os.WriteFile(stagedPath, currentData, 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	var current, staged map[string]json.RawMessage
+	if err := json.Unmarshal(currentData, &current); err != nil {
+		// 今の設定が壊れていれば、バックアップの設定をそのまま使う
+		return nil
+	}
+	if err := json.Unmarshal(stagedData, &staged); err != nil {
+		return fmt.Errorf("invalid settings.json in backup: %w", err)
+	}
+	if staged == nil {
+		staged = map[string]json.RawMessage{}
+	}
+	for _, key := range deviceSettingsKeys {
+		if value, ok := current[key]; ok {
+			staged[key] = value
+		} else {
+			delete(staged, key)
+		}
+	}
+	data, err := json.MarshalIndent(staged, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(stagedPath, data, 0644)
+}
+
+// readBackupInfo は zip の manifest からバックアップの情報を返す
+func readBackupInfo(zipPath string) (*BackupInfo, error) {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	manifest, err := readBackupManifest(&reader.Reader)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	info := &BackupInfo{Path: zipPath, CreatedAt: manifest.CreatedAt, Size: stat.Size()}
+	for _, file := range manifest.Files {
+		if strings.HasPrefix(file.Path, "notes/") {
+			info.NoteCount++
+		}
+	}
+	return info, nil
+}
+
+// listAutoBackups は自動バックアップのファイル名を新しい順に返す
+func listAutoBackups(backupDir string) ([]string, error) {
+	entries, err := os.ReadDir(backupDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, autoBackupFilePrefix) && strings.HasSuffix(name, ".zip") {
+			names = append(names, name)
+		}
+	}
+	// 時刻はゼロ埋めなので名前の逆順が新しい順になる
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	return names, nil
+}
+
+// autoBackupTime は自動バックアップのファイル名から作成時刻を返す
+func autoBackupTime(name string) (time.Time, bool) {
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, autoBackupFilePrefix), ".zip")
+	t, err := time.ParseInLocation(autoBackupTimeLayout, stamp, time.Local)
+	return t, err == nil
+}
+
+// autoBackupDue は最後の自動バックアップから interval が過ぎているかを返す
+func autoBackupDue(backupDir string, interval time.Duration, now time.Time) (bool, error) {
+	names, err := listAutoBackups(backupDir)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range names {
+		if last, ok := autoBackupTime(name); ok {
+			return now.Sub(last) >= interval, nil
+		}
+	}
+	return true, nil
+}
+
+// rotateAutoBackups は新しい keep 件を残して古い自動バックアップを消す
+func rotateAutoBackups(backupDir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		keep = defaultAutoBackupKeep
+	}
+	names, err := listAutoBackups(backupDir)
+	if err != nil || len(names) <= keep {
+		return nil, err
+	}
+	var removed []string
+	for _, name := range names[keep:] {
+		if err := os.Remove(filepath.Join(backupDir, name)); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
+
+// reloadFromDiskLocked は差し替えた noteList とノートを読み直す (caller が s.mu を握っている前提)。
+// ロック解除中のセッションとキャッシュ、検索インデックスは作り直す。
+func (s *noteService) reloadFromDiskLocked() error {
+	for noteID := range s.unlockedNotes {
+		s.endUnlockSessionLocked(noteID)
+	}
+	s.noteCache = make(map[string]*Note)
+	s.searchIndex = nil
+	s.pendingIntegrityIssues = nil
+	s.pendingIntegrityRepairs = nil
+	s.pendingOrphanRecoveries = nil
+	s.noteList = &NoteList{
+		Version: CurrentVersion,
+		Notes:   []NoteMetadata{},
+	}
+	return s.loadNoteList()
+}
+
+// allNoteIDsLocked は noteList とゴミ箱にある全ノートの ID を返す (caller が s.mu を握っている前提)
+func (s *noteService) allNoteIDsLocked() []string {
+	ids := make([]string, 0, len(s.noteList.Notes))
+	for _, metadata := range s.noteList.Notes {
+		ids = append(ids, metadata.ID)
+	}
+	for id := range trashNoteIDs(s.noteList.Trash) {
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,220 @@
+package backend
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestBackupZip は files と、それに合わせた manifest を持つ zip を作る
+func writeTestBackupZip(t *testing.T, zipPath string, noteListVersion string, files map[string]string, tamper map[string]string) {
+	t.Helper()
+	file, err := os.Create(zipPath)
+	require.NoError(t, err)
+	defer file.Close()
+	writer := zip.NewWriter(file)
+	manifest := backupManifest{Format: backupFormat, FormatVersion: backupFormatVersion, NoteListVersion: noteListVersion}
+	for name, content := range files {
+		manifest.Files = append(manifest.Files, backupManifestFile{
+			Path:   name,
+			Size:   int64(len(content)),
+			SHA256: fmt.Sprintf("%x", sha256.Sum256([]byte(content))),
+		})
+		if replaced, ok := tamper[name]; ok {
+			content = replaced
+		}
+		entry, err := writer.Create(name)
+		require.NoError(t, err)
+		_, err = entry.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	entry, err := writer.Create(backupManifestName)
+	require.NoError(t, err)
+	_, err = entry.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+}
+
+func TestDataBackup_CreateAndRestore(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+	appDataDir := helper.tempDir
+
+	require.NoError(t, ns.SaveNote(&Note{ID: "kept", Title: "kept", Content: "original", Language: "plaintext"}))
+	require.NoError(t, ns.SaveNote(&Note{ID: "removed-later", Title: "removed", Content: "bye", Language: "plaintext"}))
+	require.NoError(t, ns.TrashNote("removed-later"))
+	require.NoError(t, os.WriteFile(filepath.Join(appDataDir, "settings.json"), []byte(`{"fontSize":14}`), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(appDataDir, cloudWinBackupDirName), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(appDataDir, cloudWinBackupDirName, "cloud_wins_1.json"), []byte("{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(appDataDir, "sync_state.json"), []byte(`{"dirty":false}`), 0644))
+
+	zipPath := filepath.Join(t.TempDir(), "backup.zip")
+	var info *BackupInfo
+	var err error
+	ns.WithLock(func() { info, err = createDataBackup(appDataDir, zipPath) })
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.NoteCount)
+	assert.NoFileExists(t, zipPath+".tmp")
+
+	listed, err := readBackupInfo(zipPath)
+	require.NoError(t, err)
+	assert.Equal(t, info.NoteCount, listed.NoteCount)
+
+	// バックアップ後の変更は復元で消える
+	kept, err := ns.LoadNote("kept")
+	require.NoError(t, err)
+	kept.Content = "edited after backup"
+	require.NoError(t, ns.SaveNote(kept))
+	require.NoError(t, ns.SaveNote(&Note{ID: "new-note", Title: "new", Content: "new", Language: "plaintext"}))
+	require.NoError(t, os.WriteFile(filepath.Join(appDataDir, "settings.json"), []byte(`{"fontSize":20}`), 0644))
+	require.NoError(t, os.RemoveAll(filepath.Join(appDataDir, cloudWinBackupDirName)))
+
+	var noteIDs []string
+	ns.WithLock(func() {
+		if _, err = restoreDataBackup(appDataDir, zipPath); err == nil {
+			err = ns.reloadFromDiskLocked()
+			noteIDs = ns.allNoteIDsLocked()
+		}
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"kept", "removed-later"}, noteIDs)
+
+	restored, err := ns.LoadNote("kept")
+	require.NoError(t, err)
+	assert.Equal(t, "original", restored.Content)
+	_, err = ns.LoadNote("new-note")
+	assert.Error(t, err)
+	require.Len(t, ns.ListTrash(), 1)
+	hits, err := ns.SearchNotes("edited", SearchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+
+	settings, err := os.ReadFile(filepath.Join(appDataDir, "settings.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"fontSize":14}`, string(settings))
+	assert.FileExists(t, filepath.Join(appDataDir, cloudWinBackupDirName, "cloud_wins_1.json"))
+	// 同期状態はバックアップに含めず、端末のものを残す
+	assert.FileExists(t, filepath.Join(appDataDir, "sync_state.json"))
+	assert.NoDirExists(t, filepath.Join(appDataDir, backupStagingDirName))
+	assert.NoDirExists(t, filepath.Join(appDataDir, backupPreviousDirName))
+}
+
+func TestDataBackup_RestoreKeepsDeviceSettings(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+	appDataDir := helper.tempDir
+	settingsPath := filepath.Join(appDataDir, "settings.json")
+	noteList := `{"version":"` + CurrentVersion + `","notes":[]}`
+	restore := func(name string, files map[string]string) {
+		zipPath := filepath.Join(t.TempDir(), name)
+		writeTestBackupZip(t, zipPath, CurrentVersion, files, nil)
+		var err error
+		ns.WithLock(func() {
+			if _, err = restoreDataBackup(appDataDir, zipPath); err == nil {
+				err = ns.reloadFromDiskLocked()
+			}
+		})
+		require.NoError(t, err)
+	}
+
+	current := `{"fontSize":20,"localApiEnabled":true,"localApiToken":"new","syncProvider":"webdav","fileBackupMode":"rotate"}`
+	require.NoError(t, os.WriteFile(settingsPath, []byte(current), 0644))
+	restore("other-device.zip", map[string]string{
+		"noteList_v2.json": noteList,
+		"settings.json":    `{"fontSize":14,"localApiToken":"old","syncProvider":"drive","windowWidth":800}`,
+	})
+	settings, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	// 端末ごとの設定は今のものを残し、それ以外はバックアップの値になる
+	assert.JSONEq(t, `{"fontSize":14,"localApiEnabled":true,"localApiToken":"new","syncProvider":"webdav","fileBackupMode":"rotate"}`, string(settings))
+
+	// settings.json を含まないバックアップでは今の設定をそのまま残す
+	restore("no-settings.zip", map[string]string{"noteList_v2.json": noteList})
+	settings, err = os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"fontSize":14,"localApiEnabled":true,"localApiToken":"new","syncProvider":"webdav","fileBackupMode":"rotate"}`, string(settings))
+}
+
+func TestDataBackup_RestoreValidatesAndMigrates(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+	appDataDir := helper.tempDir
+	require.NoError(t, ns.SaveNote(&Note{ID: "current", Title: "current", Content: "current", Language: "plaintext"}))
+
+	oldNoteList := `{"version":"2.1","notes":[{"id":"old","title":"old","language":"plaintext","modifiedTime":"2024-01-01T00:00:00Z"}]}`
+	oldNote := `{"id":"old","title":"old","content":"from 2.1","language":"plaintext","modifiedTime":"2024-01-01T00:00:00Z"}`
+	files := map[string]string{"noteList_v2.json": oldNoteList, "notes/old.json": oldNote}
+	restore := func(name string, version string, files map[string]string, tamper map[string]string) error {
+		zipPath := filepath.Join(t.TempDir(), name)
+		writeTestBackupZip(t, zipPath, version, files, tamper)
+		var err error
+		ns.WithLock(func() {
+			if _, err = restoreDataBackup(appDataDir, zipPath); err == nil {
+				err = ns.reloadFromDiskLocked()
+			}
+		})
+		return err
+	}
+
+	assert.ErrorContains(t, restore("tampered.zip", "2.1", files, map[string]string{"notes/old.json": `{"id":"old","content":"evil"}`}), "checksum mismatch")
+	assert.ErrorContains(t, restore("escape.zip", "2.1", map[string]string{"../evil.json": "x"}, nil), "unexpected path")
+	assert.ErrorContains(t, restore("newer.zip", "9.0", files, nil), "newer")
+	// 失敗した復元では何も変わらない
+	current, err := ns.LoadNote("current")
+	require.NoError(t, err)
+	assert.Equal(t, "current", current.Content)
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(appDataDir), "evil.json"))
+
+	require.NoError(t, restore("old.zip", "2.1", files, nil))
+	assert.Equal(t, CurrentVersion, readNoteListVersion(filepath.Join(appDataDir, "noteList_v2.json")))
+	old, err := ns.LoadNote("old")
+	require.NoError(t, err)
+	assert.Equal(t, "from 2.1", old.Content)
+	assert.NoFileExists(t, filepath.Join(helper.notesDir, "current.json"))
+}
+
+func TestAutoBackupDueAndRotation(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.Local)
+
+	due, err := autoBackupDue(dir, 24*time.Hour, now)
+	require.NoError(t, err)
+	assert.True(t, due)
+
+	for i := 0; i < 5; i++ {
+		name := autoBackupFilePrefix + now.Add(-time.Duration(i)*6*time.Hour).Format(autoBackupTimeLayout) + ".zip"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("zip"), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manual.zip"), []byte("zip"), 0644))
+
+	due, err = autoBackupDue(dir, 24*time.Hour, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, due)
+	due, err = autoBackupDue(dir, 24*time.Hour, now.Add(25*time.Hour))
+	require.NoError(t, err)
+	assert.True(t, due)
+
+	removed, err := rotateAutoBackups(dir, 2)
+	require.NoError(t, err)
+	assert.Len(t, removed, 3)
+	names, err := listAutoBackups(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		autoBackupFilePrefix + now.Format(autoBackupTimeLayout) + ".zip",
+		autoBackupFilePrefix + now.Add(-6*time.Hour).Format(autoBackupTimeLayout) + ".zip",
+	}, names)
+	assert.FileExists(t, filepath.Join(dir, "manual.zip"))
+}
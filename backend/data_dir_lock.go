@@ -0,0 +1,112 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// appDataDir の排他ロック
+//
+// GUI は noteList を起動中ずっとメモリに保持し、任意のタイミングで noteList_v2.json や
+// sync_state.json を書き戻す。CLI (notes サブコマンド) が並行して書き換えると片方の変更が
+// 失われるため、appDataDir に書き込むプロセスは常に排他ロックを保持する。
+// 読み取りだけの CLI (list / show / grep) は共有ロックを取り、互いを待たせない。
+// GUI の起動中はどちらのロックも取れないため、CLI はコマンドを GUI に受け渡す (notes_cli_handoff.go)。
+// ロックは OS のファイルロックなので、プロセスが異常終了しても自動的に解放される。
+
+const (
+	dataDirLockFileName    = "monaco-notepad.lock"
+	dataDirLockRetryDelay  = 100 * time.Millisecond
+	dataDirLockStartupWait = 10 * time.Second // GUI がロックを待つ間、ログを出す間隔
+	dataDirLockCLIWait     = 2 * time.Second  // CLI 同士が重なった場合に待つ時間
+)
+
+// errDataDirLocked は他のプロセスが appDataDir を使用中であることを表す
+var errDataDirLocked = errors.New("app data directory is in use by another monaco-notepad process")
+
+type dataDirLock struct {
+	file *os.File
+}
+
+// acquireDataDirLock は appDataDir の排他ロックを取得する。
+// 他のプロセスが保持している場合は wait の間だけ再試行し、取得できなければ errDataDirLocked を返す。
+func acquireDataDirLock(appDataDir string, wait time.Duration) (*dataDirLock, error) {
+	return acquireDataDirLockMode(appDataDir, wait, true)
+}
+
+// acquireDataDirLockShared は読み取り用の共有ロックを取得する。
+// 共有ロック同士は同時に持てるが、排他ロック（GUI・書き込む CLI）とは重ならない。
+func acquireDataDirLockShared(appDataDir string, wait time.Duration) (*dataDirLock, error) {
+	return acquireDataDirLockMode(appDataDir, wait, false)
+}
+
+func acquireDataDirLockMode(appDataDir string, wait time.Duration, exclusive bool) (*dataDirLock, error) {
+	if err := os.MkdirAll(appDataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create app data directory: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(appDataDir, dataDirLockFileName), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		err = lockFile(file, exclusive)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errDataDirLocked) || !time.Now().Before(deadline) {
+			file.Close()
+			return nil, err
+		}
+		time.Sleep(dataDirLockRetryDelay)
+	}
+
+	// 調査用に保持しているプロセスのPIDを書いておく（ロック自体には使わない）
+	if exclusive {
+		if err := file.Truncate(0); err == nil {
+			_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+		}
+	}
+	return &dataDirLock{file: file}, nil
+}
+
+// waitDataDirLock は appDataDir の排他ロックを取得できるまで待ち続ける。
+// GUI はロックなしでは動かない（CLI と同時に書き込むと変更が失われる）ため、他のプロセスが
+// 使用中の間は wait ごとに onWaiting を呼んで再試行する。ctx の終了とロック以外のエラーは返す。
+func waitDataDirLock(ctx context.Context, appDataDir string, wait time.Duration, onWaiting func(error)) (*dataDirLock, error) {
+	for {
+		lock, err := acquireDataDirLock(appDataDir, wait)
+		if err == nil {
+			return lock, nil
+		}
+		if !errors.Is(err, errDataDirLocked) {
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%w: %v", err, ctx.Err())
+		}
+		if onWaiting != nil {
+			onWaiting(err)
+		}
+	}
+}
+
+// Release はロックを解放する。nil や解放済みでも安全に呼べる。
+func (l *dataDirLock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	unlockErr := unlockFile(l.file)
+	closeErr := l.file.Close()
+	l.file = nil
+	if unlockErr != nil {
+		return unlockErr
+	}
+	return closeErr
+}
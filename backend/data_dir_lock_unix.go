@@ -0,0 +1,26 @@
+//go:build !windows
+
+package backend
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile はファイル全体に排他ロック（exclusive=false なら共有ロック）をかける（待たずに失敗する）
+func lockFile(file *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	err := syscall.Flock(int(file.Fd()), how|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errDataDirLocked
+	}
+	return err
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
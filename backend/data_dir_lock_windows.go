@@ -0,0 +1,60 @@
+//go:build windows
+
+package backend
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 0x00000001
+	lockfileExclusiveLock   = 0x00000002
+	// ERROR_LOCK_VIOLATION: 他のプロセスがロック済み
+	errorLockViolation syscall.Errno = 33
+)
+
+// lockFile はファイル先頭 1 バイトに排他ロック（exclusive=false なら共有ロック）をかける（待たずに失敗する）
+func lockFile(file *os.File, exclusive bool) error {
+	flags := uintptr(lockfileFailImmediately)
+	if exclusive {
+		flags |= lockfileExclusiveLock
+	}
+	var overlapped syscall.Overlapped
+	result, _, err := procLockFileEx.Call(
+		file.Fd(),
+		flags,
+		0,
+		1,
+		0,
+		uintptr(unsafe.Pointer(&overlapped)),
+	)
+	if result != 0 {
+		return nil
+	}
+	if err == errorLockViolation {
+		return errDataDirLocked
+	}
+	return err
+}
+
+func unlockFile(file *os.File) error {
+	var overlapped syscall.Overlapped
+	result, _, err := procUnlockFileEx.Call(
+		file.Fd(),
+		0,
+		1,
+		0,
+		uintptr(unsafe.Pointer(&overlapped)),
+	)
+	if result == 0 {
+		return err
+	}
+	return nil
+}
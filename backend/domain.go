@@ -14,7 +14,8 @@ import (
 // アプリケーションのメインの構造体
 type App struct {
 	ctx              *Context         // アプリケーションのコンテキスト
-	appDataDir       string           // アプリケーションデータディレクトリのパス
+	rootDataDir      string           // プロファイル一覧を置くルートディレクトリのパス（既定のプロファイルのデータディレクトリを兼ねる）
+	appDataDir       string           // アプリケーションデータディレクトリのパス（使用中のプロファイルのディレクトリ）
 	notesDir         string           // ノートファイル保存ディレクトリのパス
 	authService      AuthService      // Google Drive認証サービス
 	noteService      *noteService     // ノート操作サービス
@@ -23,12 +24,25 @@ type App struct {
 	fileService      *fileService     // ファイル操作サービス
 	fileNoteService    *fileNoteService    // ファイルノート操作サービス
 	recentFilesService *recentFilesService // 最近開いたファイル操作サービス
+	fileWatcher        *fileWatcher        // 開いているファイルの外部での変更の監視
+	largeFiles         *largeFileService   // 大きなファイルの行の索引と追記の追従
+	linkedFiles        *linkedFileService  // ノートにリンクしたこの端末のファイル
+	notesCLIInbox      *notesCLIInbox      // 起動中に notes サブコマンドから渡されたコマンドの受け取り
 	syncState        *SyncState       // 同期状態管理（dirtyフラグ方式）
 	migrationMessage     string           // マイグレーション結果メッセージ（フロントエンド準備後に通知）
 	frontendReady        chan struct{}    // フロントエンドの準備完了を通知するチャネル
 	logger               AppLogger        // アプリケーションのロガー
 	lastActiveNoteId     string           // 最後に選択されたノートID（終了時にsettings.jsonへ保存）
 	lastActiveNoteIsFile bool             // 最後に選択されたノートがファイルノートかどうか
+	dataDirLock          *dataDirLock     // appDataDir の排他ロック（CLI との同時書き込み防止）
+	apiEvents            *localAPIEventHub // ローカル API の SSE に流すイベントの購読者
+	localAPI             *localAPIServer   // ローカル HTTP API サーバー（無効時は nil）
+	localAPIMu           sync.Mutex        // localAPI の起動・停止の排他
+	syncCancel           context.CancelFunc // 認証・同期サービスのコンテキストを止める（プロファイルの切り替え時など）
+	profileMu            sync.Mutex         // プロファイルの作成・切り替え・ローカルデータの削除の排他
+	servicesMu           sync.RWMutex       // プロファイルごとのサービス・データディレクトリ・最後に選択されたノートの差し替えの排他
+	backgroundCancel     context.CancelFunc // 自動バックアップを止める
+	backgroundLoops      sync.WaitGroup     // 実行中の自動バックアップ
 }
 
 // アプリケーションのコンテキストを管理
@@ -37,6 +51,15 @@ type Context struct {
 	skipBeforeClose bool // アプリケーション終了前の保存処理をスキップするかどうか
 }
 
+// ローカル HTTP API の状態
+type LocalAPIInfo struct {
+	Enabled bool   `json:"enabled"`         // 設定で有効になっているか
+	Running bool   `json:"running"`         // 待ち受け中か
+	URL     string `json:"url,omitempty"`   // ベース URL（例: http://127.0.0.1:53682/api/v1）
+	Token   string `json:"token,omitempty"` // Authorization: Bearer に指定するトークン
+	Error   string `json:"error,omitempty"` // 起動に失敗した場合のエラー
+}
+
 // トップレベルの表示順序を管理するアイテム
 type TopLevelItem struct {
 	Type string `json:"type"` // "note" or "folder"
@@ -48,31 +71,40 @@ type Folder struct {
 	ID       string `json:"id"`                 // フォルダの一意識別子
 	Name     string `json:"name"`               // フォルダ名
 	Archived bool   `json:"archived,omitempty"` // アーカイブ状態（true=アーカイブ済み）
+	ParentID string `json:"parentId,omitempty"` // 親フォルダID（空文字=トップレベル）
+	// 同期しない（この端末だけに置く）。配下のフォルダ・ノートにも及ぶ
+	LocalOnly bool `json:"localOnly,omitempty"`
 }
 
 // ノートの基本情報
 type Note struct {
-	ID            string `json:"id"`                 // ノートの一意識別子
-	Title         string `json:"title"`              // ノートのタイトル
-	Content       string `json:"content"`            // ノートの本文内容
-	ContentHeader string `json:"contentHeader"`      // アーカイブ時に表示される内容のプレビュー
-	Language      string `json:"language"`           // ノートで使用されているプログラミング言語
-	ModifiedTime  string `json:"modifiedTime"`       // 最終更新日時
-	Archived      bool   `json:"archived"`           // アーカイブ状態（true=アーカイブ済み）
-	FolderID      string `json:"folderId,omitempty"` // 所属フォルダID（空文字=未分類）
-	Syncing       bool   `json:"syncing,omitempty"`  // 同期中フラグ（ダウンロード未完了）
+	ID            string   `json:"id"`                  // ノートの一意識別子
+	Title         string   `json:"title"`               // ノートのタイトル
+	Content       string   `json:"content"`             // ノートの本文内容
+	ContentHeader string   `json:"contentHeader"`       // アーカイブ時に表示される内容のプレビュー
+	Language      string   `json:"language"`            // ノートで使用されているプログラミング言語
+	ModifiedTime  string   `json:"modifiedTime"`        // 最終更新日時
+	Archived      bool     `json:"archived"`            // アーカイブ状態（true=アーカイブ済み）
+	FolderID      string   `json:"folderId,omitempty"`  // 所属フォルダID（空文字=未分類）
+	Syncing       bool     `json:"syncing,omitempty"`   // 同期中フラグ（ダウンロード未完了）
+	Tags          []string `json:"tags,omitempty"`      // タグ（正規化済み・昇順）
+	Encrypted     bool     `json:"encrypted,omitempty"` // 本文をパスフレーズで暗号化しているか（Content は暗号文）
+	LocalOnly     bool     `json:"localOnly,omitempty"` // 同期しない（この端末だけに置く）
 }
 
 // ノートのメタデータのみを保持
 type NoteMetadata struct {
-	ID            string `json:"id"`
-	Title         string `json:"title"`
-	ContentHeader string `json:"contentHeader"`
-	Language      string `json:"language"`
-	ModifiedTime  string `json:"modifiedTime"`
-	Archived      bool   `json:"archived"`
-	ContentHash   string `json:"contentHash"`
-	FolderID      string `json:"folderId,omitempty"`
+	ID            string   `json:"id"`
+	Title         string   `json:"title"`
+	ContentHeader string   `json:"contentHeader"`
+	Language      string   `json:"language"`
+	ModifiedTime  string   `json:"modifiedTime"`
+	Archived      bool     `json:"archived"`
+	ContentHash   string   `json:"contentHash"`
+	FolderID      string   `json:"folderId,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	Encrypted     bool     `json:"encrypted,omitempty"`
+	LocalOnly     bool     `json:"localOnly,omitempty"`
 }
 
 // ノートのリストを管理
@@ -83,6 +115,17 @@ type NoteList struct {
 	TopLevelOrder         []TopLevelItem `json:"topLevelOrder,omitempty"`
 	ArchivedTopLevelOrder []TopLevelItem `json:"archivedTopLevelOrder,omitempty"`
 	CollapsedFolderIDs    []string       `json:"collapsedFolderIDs,omitempty"`
+	Trash                 []TrashItem    `json:"trash"` // nil = ゴミ箱を知らないクライアント（旧モバイル版など）が書いた noteList
+}
+
+// ゴミ箱に移したノートまたはフォルダ（ノートファイルは完全に削除されるまで残す）
+type TrashItem struct {
+	ID        string         `json:"id"`                // ノートまたはフォルダのID
+	Type      string         `json:"type"`              // "note" or "folder"
+	DeletedAt string         `json:"deletedAt"`         // ゴミ箱に移した日時（RFC3339）
+	Order     int            `json:"order"`             // 削除前の (Archived)TopLevelOrder 内の位置（-1=ルートに無かった）
+	Notes     []NoteMetadata `json:"notes,omitempty"`   // 削除したノート（フォルダの場合は配下のノート全て）
+	Folders   []Folder       `json:"folders,omitempty"` // 削除したフォルダと配下のフォルダ（先頭が削除したフォルダ）
 }
 
 // アプリケーションの設定を管理
@@ -109,6 +152,70 @@ type Settings struct {
 	UILanguage              string  `json:"uiLanguage,omitempty"`              // UI言語設定（"system", "en", "ja"）
 	LastActiveNoteId        string  `json:"lastActiveNoteId,omitempty"`        // 最後に選択されたノートID
 	LastActiveNoteIsFile    bool    `json:"lastActiveNoteIsFile,omitempty"`    // 最後に選択されたノートがファイルノートか
+	LocalAPIEnabled         bool    `json:"localApiEnabled,omitempty"`         // ローカル HTTP API を有効にするか
+	LocalAPIPort            int     `json:"localApiPort,omitempty"`            // ローカル HTTP API の待ち受けポート（0=初回起動時に自動選択）
+	LocalAPIToken           string  `json:"localApiToken,omitempty"`           // ローカル HTTP API の認証トークン
+	SyncProvider            string  `json:"syncProvider,omitempty"`            // 同期先（"" または "google"=Google Drive, "webdav"=WebDAV, "folder"=ローカルフォルダ）
+	TrashRetentionDays      int     `json:"trashRetentionDays,omitempty"`      // ゴミ箱の保持日数（0=既定の30日）
+	AutoBackupIntervalHours int     `json:"autoBackupIntervalHours,omitempty"` // 自動バックアップの間隔（時間、0=無効）
+	AutoBackupKeep          int     `json:"autoBackupKeep,omitempty"`          // 残す自動バックアップの数（0=既定の10件）
+	FileBackupMode          string  `json:"fileBackupMode,omitempty"`          // ファイル保存時のバックアップ（""=なし, "bak"=同じ場所に .bak, "rotate"=appDataDir に世代を残す）
+	FileBackupKeep          int     `json:"fileBackupKeep,omitempty"`          // rotate で残す世代の数（0=既定の5件）
+}
+
+// WebDAV 同期の接続設定（appDataDir/webdav.json に保存）
+type WebDAVConfig struct {
+	URL      string `json:"url"`                // ベース URL（この下に monaco-notepad フォルダを作る）
+	Username string `json:"username,omitempty"` // Basic 認証のユーザー名
+	Password string `json:"password,omitempty"` // Basic 認証のパスワード（アプリパスワード推奨）
+}
+
+// ローカルフォルダ同期の設定（appDataDir/sync_folder.json に保存）
+type LocalFolderConfig struct {
+	Path string `json:"path"` // 同期先ディレクトリの絶対パス（この下に monaco-notepad フォルダを作る）
+}
+
+// 同期データの暗号化の状態
+type SyncEncryptionStatus struct {
+	Enabled bool   `json:"enabled"`         // この端末で暗号化してアップロードしているか
+	Locked  bool   `json:"locked"`          // クラウドが暗号化されていてパスフレーズの入力が必要か
+	KeyID   string `json:"keyId,omitempty"` // 使用中の鍵の識別子（鍵そのものではない）
+}
+
+// 同期 1 回分の記録（同期履歴）
+type SyncRun struct {
+	ID             string       `json:"id"`                       // 記録の識別子
+	Device         string       `json:"device"`                   // 同期した端末（ホスト名）
+	Trigger        string       `json:"trigger"`                  // きっかけ: "startup" | "poll" | "manual" | "save" | "encryption"
+	Mode           string       `json:"mode"`                     // "firstPush" | "push" | "pull" | "merge"
+	StartedAt      string       `json:"startedAt"`                // 開始日時（RFC3339Nano）
+	FinishedAt     string       `json:"finishedAt"`               // 終了日時（RFC3339Nano）
+	Result         string       `json:"result"`                   // "ok" | "deferred" | "error"
+	Error          string       `json:"error,omitempty"`          // 失敗・延期の理由
+	Actions        []SyncAction `json:"actions"`                  // ノートごとの処理
+	DroppedActions int          `json:"droppedActions,omitempty"` // 記録しきれず省いた処理の件数
+}
+
+// 同期中のノート 1 件分の処理
+type SyncAction struct {
+	Time   string `json:"time"`             // 処理した日時（RFC3339Nano）
+	NoteID string `json:"noteId"`           // ノートID
+	Title  string `json:"title"`            // その時点のタイトル
+	Action string `json:"action"`           // "uploaded" | "downloaded" | "merged" | "conflictBackedUp" | "deleted" | "deferred" | "failed"
+	Reason string `json:"reason,omitempty"` // 処理の理由
+}
+
+// データディレクトリを切り替えて使うプロファイル（仕事用・個人用など）
+type Profile struct {
+	ID        string `json:"id"`                  // プロファイルの識別子（"default" は従来のデータディレクトリ）
+	Name      string `json:"name"`                // 表示名
+	CreatedAt string `json:"createdAt,omitempty"` // 作成日時（RFC3339）
+}
+
+// プロファイルの一覧と使用中のプロファイル（ルートの profiles.json に保存）
+type ProfileList struct {
+	ActiveID string    `json:"activeId"` // 使用中のプロファイルID
+	Profiles []Profile `json:"profiles"` // 作成順
 }
 
 // ノートリスト整合性チェックの問題
@@ -146,16 +253,157 @@ type IntegrityRepairSummary struct {
 	Messages []string `json:"messages,omitempty"`
 }
 
+// ノートの変更履歴（1 リビジョン分）
+type NoteRevision struct {
+	ID          string `json:"id"`                // リビジョンID（作成時刻、辞書順で時系列）
+	NoteID      string `json:"noteId"`            // 対象ノートID
+	Title       string `json:"title"`             // その時点のタイトル
+	Language    string `json:"language"`          // その時点の言語
+	Content     string `json:"content,omitempty"` // その時点の本文（一覧では空）
+	ContentHash string `json:"contentHash"`       // タイトル・本文・言語のハッシュ
+	Size        int    `json:"size"`              // 本文のバイト数
+	Source      string `json:"source"`            // "local" | "sync" | "baseline" | "restore"
+	CreatedAt   string `json:"createdAt"`         // 作成時刻 (RFC3339Nano)
+	UpdatedAt   string `json:"updatedAt"`         // 最終更新時刻（連続保存をまとめた場合に進む）
+}
+
+// 全文検索の対象範囲（アーカイブ状態）
+const (
+	SearchArchivedExclude = ""         // アクティブなノートのみ（既定）
+	SearchArchivedOnly    = "archived" // アーカイブ済みノートのみ
+	SearchArchivedAll     = "all"      // 両方
+)
+
+// 全文検索の一致フィールド
+const (
+	SearchFieldTitle   = "title"
+	SearchFieldContent = "content"
+)
+
+// 全文検索のオプション
+type SearchOptions struct {
+	FolderID          string `json:"folderId,omitempty"`          // 指定フォルダのノートに限定（空文字=全て）
+	Language          string `json:"language,omitempty"`          // 指定言語のノートに限定（空文字=全て）
+	Archived          string `json:"archived,omitempty"`          // "" | "archived" | "all"
+	Limit             int    `json:"limit,omitempty"`             // 最大ヒット件数（0=既定値）
+	MaxMatchesPerNote int    `json:"maxMatchesPerNote,omitempty"` // 1ノートあたりの最大一致件数（0=既定値）
+}
+
+// 全文検索の一致箇所（Line/Column は 1 始まり、Column/Length は UTF-16 単位）
+type SearchMatch struct {
+	Field              string `json:"field"`              // "title" | "content"
+	Line               int    `json:"line"`               // 一致した行
+	Column             int    `json:"column"`             // 一致した列
+	Length             int    `json:"length"`             // 一致範囲の長さ
+	Snippet            string `json:"snippet"`            // 一致した行の抜粋
+	SnippetMatchStart  int    `json:"snippetMatchStart"`  // 抜粋内の一致開始位置（0 始まり）
+	SnippetMatchLength int    `json:"snippetMatchLength"` // 抜粋内の一致範囲の長さ
+}
+
+// 全文検索のヒット（スコアの降順で返す）
+type SearchHit struct {
+	NoteID       string        `json:"noteId"`
+	Title        string        `json:"title"`
+	FolderID     string        `json:"folderId,omitempty"`
+	Language     string        `json:"language"`
+	Archived     bool          `json:"archived"`
+	ModifiedTime string        `json:"modifiedTime"`
+	Score        float64       `json:"score"`
+	MatchCount   int           `json:"matchCount"` // 一致の総数（Matches は上限で切られる）
+	Matches      []SearchMatch `json:"matches"`
+}
+
+// タグ一覧の 1 件（使用ノート数付き）
+type TagCount struct {
+	Tag           string `json:"tag"`
+	Count         int    `json:"count"`         // タグが付いたノート数（アーカイブ済みを含む）
+	ArchivedCount int    `json:"archivedCount"` // そのうちアーカイブ済みのノート数
+}
+
+// Markdown 保管庫形式での書き出しのオプション
+type VaultExportOptions struct {
+	FrontMatter     bool `json:"frontMatter"`     // Markdown ノートに YAML front matter (id, modifiedTime, language, tags) を付ける
+	IncludeArchived bool `json:"includeArchived"` // アーカイブ済みのノートとフォルダを _archive 以下に書き出す
+	OrderPrefix     bool `json:"orderPrefix"`     // 表示順をファイル名・ディレクトリ名の連番 ("01 ") で表す
+}
+
+// Markdown 保管庫形式での書き出し結果
+type VaultExportResult struct {
+	NoteCount      int `json:"noteCount"`      // 書き出したノート数
+	FolderCount    int `json:"folderCount"`    // 作成したディレクトリ数（_archive を除く）
+	EncryptedCount int `json:"encryptedCount"` // 暗号文のまま書き出した暗号化ノートの数
+}
+
+// 一括取り込みのオプション
+type ImportOptions struct {
+	DryRun bool `json:"dryRun"` // ノートを作らず、取り込む予定の一覧だけを返す
+}
+
+// 一括取り込みの 1 件分 (ディレクトリでは 1 ファイル、ENEX では 1 ノート)
+type ImportItem struct {
+	Source   string `json:"source"`           // 取り込み元での位置 (ディレクトリからの相対パス、ENEX は "ファイル名#番号")
+	Title    string `json:"title"`            // 作成するノートのタイトル
+	Language string `json:"language"`         // 拡張子または front matter から決めた言語
+	Folder   string `json:"folder"`           // 取り込み先フォルダのパス ("/" 区切り)
+	Status   string `json:"status"`           // "import" | "duplicate" | "skipped"
+	Reason   string `json:"reason,omitempty"` // duplicate / skipped の理由
+	NoteID   string `json:"noteId,omitempty"` // 作成したノート、duplicate では同じ内容の既存ノート
+}
+
+// 一括取り込みの結果 (DryRun では取り込んだ場合の見込み)
+type ImportReport struct {
+	DryRun         bool         `json:"dryRun"`
+	Items          []ImportItem `json:"items"`
+	ImportedCount  int          `json:"importedCount"`
+	DuplicateCount int          `json:"duplicateCount"`
+	SkippedCount   int          `json:"skippedCount"`
+	FolderCount    int          `json:"folderCount"` // 新しく作るフォルダの数
+}
+
+// ローカルデータのバックアップ（zip）の情報
+type BackupInfo struct {
+	Path      string `json:"path"`      // zip ファイルのパス
+	CreatedAt string `json:"createdAt"` // 作成日時（RFC3339）
+	Size      int64  `json:"size"`      // ファイルサイズ（バイト）
+	NoteCount int    `json:"noteCount"` // 含まれるノートファイルの数
+}
+
 // 競合バックアップ一覧表示用エントリ
 // cloudWinBackupRecord のうちフロントエンドが表示・復元に必要な部分のみを公開する
 type ConflictBackupEntry struct {
 	ID        string `json:"id"`        // ファイル名（一意キー）
 	Filename  string `json:"filename"`  // バックアップファイル名
-	Kind      string `json:"kind"`      // "cloud_wins" | "cloud_delete"
+	Kind      string `json:"kind"`      // "cloud_wins" | "cloud_delete" | "linked_file"
 	CreatedAt string `json:"createdAt"` // バックアップ作成時刻 (RFC3339Nano)
 	Note      *Note  `json:"note"`      // バックアップされていたローカル版ノート
 }
 
+// 行単位の差分の 1 行
+type DiffLine struct {
+	Kind    string `json:"kind"`    // "equal" | "delete" | "insert"
+	Text    string `json:"text"`    // 行の内容（改行を除く）
+	OldLine int    `json:"oldLine"` // 変更前（バックアップ側）の行番号。1 始まり、insert では 0
+	NewLine int    `json:"newLine"` // 変更後（現在のノート側）の行番号。1 始まり、delete では 0
+}
+
+// 競合バックアップと現在のノートの比較結果
+type ConflictBackupDiff struct {
+	Filename  string     `json:"filename"`  // バックアップファイル名
+	Kind      string     `json:"kind"`      // "cloud_wins" | "cloud_delete" | "linked_file"
+	Backup    *Note      `json:"backup"`    // バックアップされていたローカル版ノート
+	Current   *Note      `json:"current"`   // 現在のノート（削除済みなら nil）
+	Cloud     *Note      `json:"cloud"`     // 上書き時点のクラウド版ノート（記録が無ければ nil）
+	Lines     []DiffLine `json:"lines"`     // バックアップ → 現在のノートの行単位の差分
+	Merged    string     `json:"merged"`    // 異なる部分を競合マーカーで並べた本文（手でマージする際のたたき台）
+	Identical bool       `json:"identical"` // 本文が同じか
+}
+
+// 競合バックアップの解決方法
+type ConflictResolution struct {
+	Action     string `json:"action"`     // "local" | "cloud" | "merged" | "new"
+	MergedText string `json:"mergedText"` // action が "merged" のときの本文
+}
+
 // Google Driveとの同期機能を管理
 type DriveSync struct {
 	service       *drive.Service // Google Driveサービスのインスタンス
@@ -271,6 +519,7 @@ const (
 	MsgDriveNoteMissingUploadLocal  = "drive.noteMissingUploadLocal"
 	MsgDriveConflictKeepLocal       = "drive.conflict.keepLocal"
 	MsgDriveConflictKeepCloud       = "drive.conflict.keepCloud"
+	MsgDriveConflictMerged          = "drive.conflict.merged"
 	MsgDriveDeferNoteListUpload     = "drive.deferNoteListUpload"
 	MsgDriveDeferCloudApply         = "drive.deferCloudApply"
 	MsgDriveDeferConflictMerge      = "drive.deferConflictMerge"
@@ -283,6 +532,7 @@ const (
 	MsgDriveCheckingCloudFiles      = "drive.checkingCloudFiles"
 	MsgDriveCheckingDuplicates      = "drive.checkingDuplicates"
 	MsgDriveReconnected             = "drive.reconnected"
+	MsgDriveEncryptionRequired      = "drive.encryptionRequired"
 
 	// マイグレーション関連
 	MsgDriveMigrationNeeded      = "drive.migration.needed"
@@ -419,6 +419,8 @@ func (s *driveService) cleanupLegacyOrphansBeforeMigration(legacyOps DriveOperat
 			ModifiedTime:  note.ModifiedTime,
 			ContentHash:   computeContentHash(&note),
 			FolderID:      recoveryFolderID,
+			Tags:          note.Tags,
+			Encrypted:     note.Encrypted,
 		})
 
 		recoveredCount++
@@ -231,6 +231,11 @@ func (d *driveOperationsImpl) GetFileID(fileName string, noteFolderID string, ro
 
 // 複数のファイルから最新のものを返す ------------------------------------------------------------
 func (d *driveOperationsImpl) FindLatestFile(files []*drive.File) *drive.File {
+	return findLatestDriveFile(files)
+}
+
+// findLatestDriveFile は ModifiedTime が最も新しいファイルを返す (WebDAV 版と共通)
+func findLatestDriveFile(files []*drive.File) *drive.File {
 	if len(files) == 0 {
 		return nil
 	}
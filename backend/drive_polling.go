@@ -100,7 +100,7 @@ func (p *DrivePollingService) WaitForFrontendAndStartSync() {
 
 	// 起動時点より前に発生したクラウド差分を取りこぼさないため、
 	// ポーリング開始前に必ず一度フル同期判定を実行する
-	if err := p.driveService.SyncNotes(); err != nil {
+	if err := p.driveService.SyncNotesWithTrigger(SyncTriggerStartup); err != nil {
 		p.logger.ErrorCode(err, MsgDriveErrorInitialSync, nil)
 	}
 
@@ -203,7 +203,7 @@ func (p *DrivePollingService) StartPolling() {
 				p.logger.ErrorCode(syncErr, MsgDriveErrorSyncFailed, nil)
 				interval = initialInterval
 			} else if hasChanges {
-				if err := p.driveService.SyncNotes(); err != nil {
+				if err := p.driveService.SyncNotesWithTrigger(SyncTriggerPoll); err != nil {
 					p.logger.ErrorCode(err, MsgDriveErrorSyncFailed, nil)
 					interval = initialInterval
 				} else {
@@ -255,7 +255,7 @@ func (p *DrivePollingService) checkForChanges() (bool, error) {
 	currentToken := p.getChangePageToken()
 	if currentToken == "" {
 		p.logger.Console("No change token available, performing full sync")
-		if err := p.driveService.SyncNotes(); err != nil {
+		if err := p.driveService.SyncNotesWithTrigger(SyncTriggerPoll); err != nil {
 			return false, err
 		}
 		p.initChangeToken()
@@ -3,12 +3,16 @@ package backend
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"monaco-notepad/backend/migration"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
@@ -23,15 +27,24 @@ type DriveService interface {
 	LogoutDrive() error      // ログアウト
 	CancelLoginDrive() error // 認証キャンセル
 	DeleteAllDriveData() error // Drive 上の全データを削除してログアウト
+	ConnectWebDAV(config WebDAVConfig) error // WebDAV サーバーに接続して同期を開始
+	ConnectLocalFolder(config LocalFolderConfig) error // ローカルフォルダを同期先にして同期を開始
+	Shutdown()                                         // 同期を止める（トークン・接続設定は残す）
 
 	// ---- ノート同期系 ----
 	CreateNote(note *Note) error                           // ノート作成
 	UpdateNote(note *Note) error                           // ノート更新
 	DeleteNoteDrive(noteID string) error                   // ノート削除
 	SyncNotes() error                                      // ノートをただちに同期
+	SyncNotesWithTrigger(trigger string) error             // きっかけを同期履歴に残して同期
 	UpdateNoteList() error                                 // ノートリスト更新
 	SaveNoteAndUpdateList(note *Note, isCreate bool) error // ノート保存+リスト更新をアトミックに実行
 
+	// ---- 暗号化 ----
+	EnableSyncEncryption(passphrase string) error  // 同期データの暗号化を有効化（2 台目以降はロック解除）
+	DisableSyncEncryption() error                  // 同期データの暗号化を無効化
+	GetSyncEncryptionStatus() SyncEncryptionStatus // 暗号化の状態を取得
+
 	// ---- ユーティリティ ----
 	NotifyFrontendReady()                           // フロントエンド準備完了通知
 	RespondToMigration(choice string)               // マイグレーション選択を受信
@@ -58,6 +71,11 @@ type driveService struct {
 	migrationChoiceWait time.Duration
 	syncMu              sync.Mutex
 	syncState           *SyncState
+	webdavConfig        atomic.Pointer[WebDAVConfig]      // WebDAV で同期中の接続設定（それ以外は nil）
+	localFolderConfig   atomic.Pointer[LocalFolderConfig] // ローカルフォルダで同期中の設定（それ以外は nil）
+	encryption          *syncEncryption                   // 同期データの暗号化（nil なら暗号化しない）
+	journal             *syncJournal                      // 同期履歴（nil なら記録しない）
+	currentSyncRun      *SyncRun                          // 進行中の同期の記録（syncMu を握っている間だけ有効）
 }
 
 const (
@@ -65,6 +83,7 @@ const (
 	maxCloudWinBackupFiles      = 100
 	cloudBackupFilePrefixWins   = "cloud_wins_"
 	cloudBackupFilePrefixDelete = "cloud_delete_"
+	linkedFileBackupFilePrefix  = "linked_file_"
 )
 
 type cloudWinBackupRecord struct {
@@ -110,6 +129,8 @@ func NewDriveService(
 		migrationChoiceChan: make(chan string, 1),
 		migrationChoiceWait: 5 * time.Minute,
 		syncState:           syncState,
+		encryption:          newSyncEncryption(appDataDir),
+		journal:             newSyncJournal(appDataDir),
 	}
 
 	ds.pollingService = NewDrivePollingService(ctx, ds)
@@ -120,11 +141,22 @@ func (s *driveService) newDriveOperations(useAppDataFolder bool) DriveOperations
 	if s.driveOpsFactory != nil {
 		return s.driveOpsFactory(useAppDataFolder)
 	}
+	if s.usesPathProvider() {
+		ops, err := s.newPathOperations()
+		if err != nil {
+			s.logger.Console("Failed to create sync provider operations: %v", err)
+			return nil
+		}
+		return ops
+	}
 	return NewDriveOperations(s.auth.GetDriveSync().service, s.logger, useAppDataFolder)
 }
 
 // Google Drive APIの初期化 (保存済みトークンがあれば自動ログイン)
 func (s *driveService) InitializeDrive() error {
+	if provider := s.syncProvider(); provider != syncProviderGoogleDrive {
+		return s.initializePathProvider(provider)
+	}
 	if success, err := s.auth.InitializeWithSavedToken(); err != nil {
 		return s.auth.HandleOfflineTransition(err)
 	} else if success {
@@ -136,6 +168,7 @@ func (s *driveService) InitializeDrive() error {
 
 // Google Driveに手動ログイン
 func (s *driveService) AuthorizeDrive() error {
+	s.clearPathProvider(false)
 	s.logger.NotifyDriveStatus(s.ctx, "logging in")
 	s.logger.Console("Waiting for login...")
 	if err := s.auth.StartManualAuth(); err != nil {
@@ -152,12 +185,19 @@ func (s *driveService) reconnect() error {
 		return nil
 	}
 
-	success, err := s.auth.InitializeWithSavedToken()
-	if err != nil {
-		return fmt.Errorf("reconnect: auth failed: %w", err)
-	}
-	if !success {
-		return fmt.Errorf("reconnect: no valid token available")
+	if s.usesPathProvider() {
+		if err := s.pingPathProvider(); err != nil {
+			return fmt.Errorf("reconnect: %w", err)
+		}
+		s.auth.GetDriveSync().SetConnected(true)
+	} else {
+		success, err := s.auth.InitializeWithSavedToken()
+		if err != nil {
+			return fmt.Errorf("reconnect: auth failed: %w", err)
+		}
+		if !success {
+			return fmt.Errorf("reconnect: no valid token available")
+		}
 	}
 
 	if !s.IsConnected() {
@@ -186,7 +226,7 @@ func (s *driveService) reconnect() error {
 	s.driveOps = s.operationsQueue
 
 	rootID, notesID := s.auth.GetDriveSync().FolderIDs()
-	s.driveSync = NewDriveSyncService(s.driveOps, notesID, rootID, s.logger)
+	s.driveSync = newDriveSyncService(s.driveOps, notesID, rootID, s.logger, s.encryption)
 	if s.driveSync == nil {
 		return fmt.Errorf("reconnect: failed to create DriveSyncService")
 	}
@@ -204,11 +244,15 @@ func (s *driveService) onConnected() error {
 	s.logger.Console("Initializing DriveOperations...")
 	legacyOps := s.newDriveOperations(false)
 
-	// マイグレーション判定
+	// マイグレーション判定 (WebDAV・ローカルフォルダには appDataFolder が無いため行わない)
 	migrationState := s.loadMigrationState()
 	useAppData := migrationState.Migrated
+	isPathProvider := s.usesPathProvider()
+	if isPathProvider {
+		useAppData = false
+	}
 
-	if !useAppData {
+	if !useAppData && !isPathProvider {
 		appDataOps := s.newDriveOperations(true)
 		appDataExists := s.checkAppDataFolderExists(appDataOps)
 		legacyExists := s.checkOldDriveFoldersExist(legacyOps)
@@ -307,16 +351,22 @@ func (s *driveService) onConnected() error {
 
 	s.logger.Console("Initializing Drive sync service...")
 	rootID, notesID := s.auth.GetDriveSync().FolderIDs()
-	s.driveSync = NewDriveSyncService(
+	s.driveSync = newDriveSyncService(
 		s.driveOps,
 		notesID,
 		rootID,
 		s.logger,
+		s.encryption,
 	)
 	if s.driveSync == nil {
 		return s.auth.HandleOfflineTransition(fmt.Errorf("failed to create DriveSyncService"))
 	}
 
+	s.logger.Console("Checking sync encryption...")
+	if err := s.refreshEncryptionState(); err != nil {
+		return s.auth.HandleOfflineTransition(err)
+	}
+
 	s.logger.Console("Ensuring note list...")
 	if err := s.ensureNoteList(); err != nil {
 		s.logger.ErrorCode(err, MsgDriveErrorNoteListSetup, nil)
@@ -335,9 +385,27 @@ func (s *driveService) LogoutDrive() error {
 	if s.operationsQueue != nil {
 		s.operationsQueue.Cleanup()
 	}
+	// WebDAV・同期フォルダの接続情報は token.json と同じくログアウトで削除する
+	s.clearPathProvider(true)
 	return s.auth.LogoutDrive()
 }
 
+// Shutdown はポーリングと操作キューを止め、進行中の同期が終わるのを待つ。
+// LogoutDrive と異なり token.json や WebDAV・同期フォルダの接続設定は消さないので、
+// 同じデータディレクトリで作り直したサービスは InitializeDrive で再接続できる（プロファイルの切り替え用）。
+func (s *driveService) Shutdown() {
+	s.logger.Console("Shutting down sync...")
+	s.pollingService.StopPolling()
+	if s.operationsQueue != nil {
+		s.operationsQueue.Cleanup()
+	}
+	s.auth.stopAuthServer(context.Background())
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	s.auth.GetDriveSync().SetConnected(false)
+}
+
 // DeleteAllDriveData は Drive 上の monaco-notepad フォルダを削除してログアウトする。
 // appDataFolder 空間とレガシー Drive 空間の両方から同名フォルダを削除し、
 // 完了後に LogoutDrive 相当の処理で token.json も削除する。
@@ -517,7 +585,11 @@ func (s *driveService) SaveNoteAndUpdateList(note *Note, isCreate bool) error {
 		return s.auth.HandleOfflineTransition(fmt.Errorf("not connected to Google Drive"))
 	}
 
-	if isCreate {
+	// 同期しないノートは上げず、ノートリストだけ更新する (リストからも取り除かれる)
+	localOnlyNoteIDs, _ := s.noteService.LocalOnlyIDs()
+	upload := !localOnlyNoteIDs[note.ID]
+
+	if upload && isCreate {
 		s.logger.InfoCode(MsgDriveUploading, map[string]interface{}{"noteTitle": note.Title})
 		err := s.driveSync.CreateNote(s.ctx, note)
 		if err != nil {
@@ -526,7 +598,7 @@ func (s *driveService) SaveNoteAndUpdateList(note *Note, isCreate bool) error {
 			}
 			return s.auth.HandleOfflineTransition(fmt.Errorf("failed to create note: %v", err))
 		}
-	} else {
+	} else if upload {
 		s.logger.InfoCode(MsgDriveUpdating, map[string]interface{}{"noteId": note.ID})
 		err := s.driveSync.UpdateNote(s.ctx, note)
 		if err != nil {
@@ -556,7 +628,7 @@ func (s *driveService) updateNoteListInternal() error {
 	// noteList は UI 編集と共有なので、アップロード前に snapshot を取って渡す
 	// (UpdateNoteList 内部で MarshalIndent + Notes 上書きするため、
 	// 直接ポインタを渡すと UI の SaveNote と race する)。
-	noteListSnapshot := s.noteService.SnapshotNoteList()
+	noteListSnapshot := s.noteService.SnapshotCloudNoteList()
 	s.logger.Console("Modifying note list, Notes count: %d", len(noteListSnapshot.Notes))
 
 	err := s.driveSync.UpdateNoteList(s.ctx, noteListSnapshot, s.auth.GetDriveSync().NoteListID())
@@ -576,6 +648,11 @@ func (s *driveService) updateNoteListInternal() error {
 
 // ノート同期: SyncNotes (今すぐ同期)
 func (s *driveService) SyncNotes() error {
+	return s.SyncNotesWithTrigger(SyncTriggerManual)
+}
+
+// SyncNotesWithTrigger は同期を実行し、きっかけとノートごとの処理を同期履歴に残す
+func (s *driveService) SyncNotesWithTrigger(trigger string) error {
 	s.syncMu.Lock()
 	defer s.syncMu.Unlock()
 
@@ -586,11 +663,38 @@ func (s *driveService) SyncNotes() error {
 		return fmt.Errorf("drive sync service not yet initialized")
 	}
 
+	if s.journal == nil {
+		return s.syncNotesLocked()
+	}
+	s.currentSyncRun = s.journal.begin(trigger)
+	err := s.syncNotesLocked()
+	if journalErr := s.journal.finish(s.currentSyncRun, err); journalErr != nil {
+		s.logger.Console("Sync: failed to record sync history: %v", journalErr)
+	}
+	s.currentSyncRun = nil
+	return err
+}
+
+// syncNotesLocked は同期の本体 (syncMu を握っている前提)
+func (s *driveService) syncNotesLocked() error {
+
+	// クラウドが暗号化されていて鍵が無い間は同期しない（他の端末で無効化されていれば再開する）
+	if s.encryption.isLocked() {
+		if err := s.refreshEncryptionState(); err != nil {
+			return s.auth.HandleOfflineTransition(err)
+		}
+		if s.encryption.isLocked() {
+			s.logger.Console("Sync: skipped (passphrase required)")
+			return nil
+		}
+	}
+
 	s.logger.NotifyDriveStatus(s.ctx, "syncing")
 
 	noteListID := s.auth.GetDriveSync().NoteListID()
 	if noteListID == "" {
 		s.logger.InfoCode(MsgDriveSyncFirstPush, nil)
+		s.setSyncRunMode(syncModeFirstPush)
 		return s.pushLocalChanges()
 	}
 
@@ -604,6 +708,16 @@ func (s *driveService) SyncNotes() error {
 	cloudChanged := cloudModifiedTime != s.syncState.LastSyncedDriveTs
 	localDirty := s.syncState.IsDirty()
 
+	// 他の端末で暗号化が無効化・鍵の変更がされていないか確認する
+	if cloudChanged && s.encryption.enabledKeyCheck() != nil {
+		if err := s.refreshEncryptionState(); err != nil {
+			return s.auth.HandleOfflineTransition(err)
+		}
+		if s.encryption.isLocked() {
+			return nil
+		}
+	}
+
 	switch {
 	case !cloudChanged && !localDirty:
 		s.logger.Console("Sync: no changes detected")
@@ -612,14 +726,17 @@ func (s *driveService) SyncNotes() error {
 
 	case !cloudChanged && localDirty:
 		s.logger.InfoCode(MsgDriveSyncPushLocalChanges, nil)
+		s.setSyncRunMode(syncModePush)
 		return s.pushLocalChanges()
 
 	case cloudChanged && !localDirty:
 		s.logger.InfoCode(MsgDriveSyncPullCloudChanges, nil)
+		s.setSyncRunMode(syncModePull)
 		return s.pullCloudChanges(noteListID)
 
 	default:
 		s.logger.InfoCode(MsgDriveSyncConflictDetected, nil)
+		s.setSyncRunMode(syncModeMerge)
 		return s.resolveConflict(noteListID)
 	}
 }
@@ -630,6 +747,7 @@ func (s *driveService) pushLocalChanges() error {
 	uploadFailures := 0
 	deleteFailures := 0
 	uploadedHashes := make(map[string]string, len(dirtyIDs))
+	localOnlyNoteIDs, _ := s.noteService.LocalOnlyIDs()
 
 	// Pass 1: dirty 全件の hash を計算して、実際に Drive へ上げる必要があるノートだけ
 	// リストアップする。map イテレーション順がランダムでも、ここで順序を固定すれば
@@ -642,8 +760,14 @@ func (s *driveService) pushLocalChanges() error {
 	toUpload := make([]pendingUpload, 0, len(dirtyIDs))
 	for id := range dirtyIDs {
 		note, err := s.noteService.LoadNote(id)
+		if err == nil && localOnlyNoteIDs[id] {
+			// 同期しないノートは上げない (処理済みとして扱い、dirty は最後にまとめて落とす)
+			uploadedHashes[id] = computeContentHash(note)
+			continue
+		}
 		if err != nil {
 			s.logger.ErrorCode(err, MsgDriveErrorLoadDirtyNote, map[string]interface{}{"noteId": id})
+			s.recordSyncAction(syncActionFailed, id, "", "failed to load local note: "+err.Error())
 			uploadFailures++
 			continue
 		}
@@ -670,11 +794,14 @@ func (s *driveService) pushLocalChanges() error {
 		// CreateNote は内部で 1 回だけ GetFileID を叩いて upsert する。
 		if err := s.driveSync.CreateNote(s.ctx, p.note); err != nil {
 			s.logger.ErrorCode(err, MsgDriveErrorCreateNote, map[string]interface{}{"noteId": p.id})
+			s.recordSyncAction(syncActionFailed, p.id, p.note.Title, "upload failed: "+err.Error())
 			uploadFailures++
 			continue
 		}
 		// 個別に永続化: 次回起動時の resume に使う
 		s.syncState.UpdateSyncedNoteHash(p.id, p.hash)
+		s.syncState.UpdateSyncedNoteBase(p.note)
+		s.recordSyncAction(syncActionUploaded, p.id, p.note.Title, "changed on this device")
 	}
 
 	for id := range deletedIDs {
@@ -682,17 +809,30 @@ func (s *driveService) pushLocalChanges() error {
 		if err := s.driveSync.DeleteNote(s.ctx, id); err != nil {
 			if isDriveNotFoundError(err) {
 				s.logger.InfoCode(MsgDriveNoteAlreadyAbsent, map[string]interface{}{"noteId": id})
+				s.recordSyncAction(syncActionDeleted, id, "", "deleted on this device (already absent from cloud)")
 			} else {
 				s.logger.ErrorCode(err, MsgDriveErrorDeleteNote, map[string]interface{}{"noteId": id})
+				s.recordSyncAction(syncActionFailed, id, "", "cloud delete failed: "+err.Error())
 				deleteFailures++
 			}
+		} else {
+			s.recordSyncAction(syncActionDeleted, id, "", "deleted on this device")
 		}
 		// orphan復元ループ防止: ローカル物理ファイルも確実に削除
-		_ = s.noteService.DeleteNoteFromSync(id)
+		if !localOnlyNoteIDs[id] {
+			_ = s.noteService.DeleteNoteFromSync(id)
+		}
 	}
 
+	// 同期済みのノートに同期しない印を付けたら、クラウドからだけ消す
+	deleteFailures += s.removeLocalOnlyNotesFromCloud(localOnlyNoteIDs, func(id string) bool {
+		_, synced := lastSyncedHashes[id]
+		return synced && !deletedIDs[id]
+	})
+
 	if uploadFailures > 0 || deleteFailures > 0 {
 		s.logger.InfoCode(MsgDrivePartialPushDeferred, map[string]interface{}{"uploadFailures": uploadFailures, "deleteFailures": deleteFailures})
+		s.deferSyncRun(fmt.Sprintf("%d uploads and %d deletes failed; retrying on next sync", uploadFailures, deleteFailures))
 		s.pollingService.RefreshChangeToken()
 		return nil
 	}
@@ -715,6 +855,7 @@ func (s *driveService) pushLocalChanges() error {
 			currentHashes,
 		) {
 			s.logger.InfoCode(MsgDriveDeferNoteListUpload, nil)
+			s.deferSyncRun("notes changed during push; note list upload deferred")
 			s.pollingService.RefreshChangeToken()
 			return nil
 		}
@@ -728,7 +869,7 @@ func (s *driveService) pushLocalChanges() error {
 	}
 
 	// アップロード前に snapshot 取得 (UI と共有しているスライスをそのまま渡さない)
-	noteListSnapshotForUpload := s.noteService.SnapshotNoteList()
+	noteListSnapshotForUpload := s.noteService.SnapshotCloudNoteList()
 
 	noteListID := s.auth.GetDriveSync().NoteListID()
 	if noteListID == "" {
@@ -758,12 +899,19 @@ func (s *driveService) pushLocalChanges() error {
 		driveTs = meta.ModifiedTime
 	}
 	var noteHashes map[string]string
+	var noteTags map[string][]string
 	s.noteService.WithLock(func() {
 		noteHashes = make(map[string]string, len(s.noteService.noteList.Notes))
+		localOnlyNoteIDs, _ := localOnlyIDs(s.noteService.noteList)
 		for _, n := range s.noteService.noteList.Notes {
-			noteHashes[n.ID] = n.ContentHash
+			// 同期しないノートはクラウドに無いので、同期済みとして記録しない
+			if !localOnlyNoteIDs[n.ID] {
+				noteHashes[n.ID] = n.ContentHash
+			}
 		}
+		noteTags = noteTagsByID(s.noteService.noteList.Notes)
 	})
+	s.syncState.UpdateSyncedNoteTags(noteTags)
 	if !s.syncState.ClearDirtyIfUnchanged(clearSnapshotRevision, driveTs, noteHashes) {
 		s.logger.Console("Sync state changed during push; retaining dirty flags for next sync")
 		s.syncState.UpdateSyncedState(driveTs, noteHashes)
@@ -782,6 +930,10 @@ func (s *driveService) pullCloudChanges(noteListID string) error {
 	_, _, _, _, snapshotRevision := s.syncState.GetDirtySnapshotWithRevision()
 
 	cloudNoteList, err := s.driveSync.DownloadNoteList(s.ctx, noteListID)
+	if errors.Is(err, errSyncEncryptionLocked) {
+		s.markEncryptionLocked()
+		return nil
+	}
 	if err != nil {
 		return s.auth.HandleOfflineTransition(fmt.Errorf("failed to download note list: %w", err))
 	}
@@ -803,11 +955,16 @@ func (s *driveService) pullCloudChanges(noteListID string) error {
 		cloudMap[n.ID] = n
 	}
 	backupEnabled := s.isCloudConflictBackupEnabled()
+	localOnlyNoteIDs, _ := s.noteService.LocalOnlyIDs()
 
 	downloadCount := 0
 	missingCloudNoteIDs := make(map[string]bool)
 	stagedDownloads := make(map[string]*Note)
 	for _, cloudNote := range cloudNoteList.Notes {
+		if localOnlyNoteIDs[cloudNote.ID] {
+			// 同期しないノートはクラウドに古い版が残っていても取り込まない
+			continue
+		}
 		localNote, exists := localMap[cloudNote.ID]
 		if !exists || localNote.ContentHash != cloudNote.ContentHash {
 			s.logger.InfoCode(MsgDriveSyncDownloadNote, map[string]interface{}{"noteId": cloudNote.ID})
@@ -819,6 +976,7 @@ func (s *driveService) pullCloudChanges(noteListID string) error {
 					continue
 				}
 				s.logger.ErrorCode(dlErr, MsgDriveErrorDownloadNote, map[string]interface{}{"noteId": cloudNote.ID})
+				s.recordSyncAction(syncActionFailed, cloudNote.ID, cloudNote.Title, "download failed: "+dlErr.Error())
 				continue
 			}
 			stagedDownloads[cloudNote.ID] = note
@@ -844,6 +1002,8 @@ func (s *driveService) pullCloudChanges(noteListID string) error {
 	_, _, _, _, latestRevision := s.syncState.GetDirtySnapshotWithRevision()
 	if latestRevision != snapshotRevision {
 		s.logger.InfoCode(MsgDriveDeferCloudApply, nil)
+		s.deferSyncRun("local changes arrived during pull; applying cloud changes on next sync")
+		s.recordDeferredDownloads(stagedDownloads)
 		s.pollingService.RefreshChangeToken()
 		return nil
 	}
@@ -857,18 +1017,25 @@ func (s *driveService) pullCloudChanges(noteListID string) error {
 		for _, id := range downloadIDs {
 			if err := s.noteService.SaveNoteFromSync(stagedDownloads[id]); err != nil {
 				s.logger.ErrorCode(err, MsgDriveErrorSaveDownloadedNote, map[string]interface{}{"noteId": id})
+				s.recordSyncAction(syncActionFailed, id, stagedDownloads[id].Title, "failed to save downloaded note: "+err.Error())
 				continue
 			}
+			s.syncState.UpdateSyncedNoteBase(stagedDownloads[id])
+			s.recordSyncAction(syncActionDownloaded, id, stagedDownloads[id].Title, "changed on another device")
 		}
 	}
 
+	// 他端末でゴミ箱に移したノートは元に戻せるよう手元にもファイルを用意する
+	s.downloadMissingTrashNotes(cloudNoteList.Trash)
+	cloudTrashNoteIDs := trashNoteIDs(cloudNoteList.Trash)
+
 	// snapshot を取って iterate (UI 編集中の slice をそのまま走査しない)
 	var localNotesSnapshot []NoteMetadata
 	s.noteService.WithLock(func() {
 		localNotesSnapshot = append([]NoteMetadata(nil), s.noteService.noteList.Notes...)
 	})
 	for _, localNote := range localNotesSnapshot {
-		if _, exists := cloudMap[localNote.ID]; !exists {
+		if _, exists := cloudMap[localNote.ID]; !exists && !cloudTrashNoteIDs[localNote.ID] && !localOnlyNoteIDs[localNote.ID] {
 			s.logger.InfoCode(MsgDriveSyncRemoveLocalDeleted, map[string]interface{}{"noteId": localNote.ID})
 			if backupEnabled {
 				backupPath, backupErr := s.backupLocalNoteBeforeCloudDelete(localNote.ID, "cloud-delete-during-pull")
@@ -876,10 +1043,14 @@ func (s *driveService) pullCloudChanges(noteListID string) error {
 					s.logger.Console("Drive: failed to backup local note %s before cloud deletion: %v", localNote.ID, backupErr)
 				} else {
 					s.logger.Console("Drive: backed up local note %s before cloud deletion: %s", localNote.ID, backupPath)
+					s.recordSyncAction(syncActionConflictBackedUp, localNote.ID, localNote.Title, "deleted on another device; local copy saved to "+filepath.Base(backupPath))
 				}
 			}
 			if err := s.noteService.DeleteNoteFromSync(localNote.ID); err != nil {
 				s.logger.ErrorCode(err, MsgDriveErrorRemoveLocalNote, map[string]interface{}{"noteId": localNote.ID})
+				s.recordSyncAction(syncActionFailed, localNote.ID, localNote.Title, "failed to remove note deleted on another device: "+err.Error())
+			} else {
+				s.recordSyncAction(syncActionDeleted, localNote.ID, localNote.Title, "deleted on another device")
 			}
 		}
 	}
@@ -889,12 +1060,28 @@ func (s *driveService) pullCloudChanges(noteListID string) error {
 	// MarshalIndent 中に slice が変更されて panic する。
 	var pullSaveErr error
 	s.noteService.WithLock(func() {
-		s.noteService.noteList.Version = cloudNoteList.Version
+		// 古いクライアントが上げた noteList でローカルのバージョンを巻き戻さない
+		// (巻き戻すと起動のたびにローカルのマイグレーションが走り直す)
+		if migration.VersionLess(s.noteService.noteList.Version, cloudNoteList.Version) {
+			s.noteService.noteList.Version = cloudNoteList.Version
+		}
+		localBeforePull := *s.noteService.noteList
 		s.noteService.noteList.Notes = cloudNoteList.Notes
 		s.noteService.noteList.Folders = cloudNoteList.Folders
 		s.noteService.noteList.TopLevelOrder = cloudNoteList.TopLevelOrder
 		s.noteService.noteList.ArchivedTopLevelOrder = cloudNoteList.ArchivedTopLevelOrder
 		s.noteService.noteList.CollapsedFolderIDs = cloudNoteList.CollapsedFolderIDs
+		previousTrash := s.noteService.noteList.Trash
+		if cloudNoteList.Trash != nil {
+			s.noteService.noteList.Trash = cloudNoteList.Trash
+		} else {
+			s.logger.Console("Drive: cloud note list has no trash (written by an older client); keeping the local trash")
+		}
+		// 同期しないノート・フォルダはクラウドに無いので、手元のものを残す
+		restoreLocalOnlyItems(&localBeforePull, s.noteService.noteList)
+		if cloudNoteList.Trash != nil {
+			s.noteService.deleteUnlistedTrashNotesLocked(previousTrash)
+		}
 		pullSaveErr = s.noteService.saveNoteList()
 	})
 	if pullSaveErr != nil {
@@ -910,6 +1097,7 @@ func (s *driveService) pullCloudChanges(noteListID string) error {
 	for _, n := range cloudNoteList.Notes {
 		noteHashes[n.ID] = n.ContentHash
 	}
+	s.syncState.UpdateSyncedNoteTags(noteTagsByID(cloudNoteList.Notes))
 	if !s.syncState.ClearDirtyIfUnchanged(snapshotRevision, driveTs, noteHashes) {
 		s.logger.Console("Sync state changed during pull; retaining dirty flags for next sync")
 		s.syncState.UpdateSyncedState(driveTs, noteHashes)
@@ -925,8 +1113,14 @@ func (s *driveService) resolveConflict(noteListID string) error {
 	clearSnapshotRevision := snapshotRevision
 	processedDirtyHashes := make(map[string]string, len(dirtyIDs))
 	backupEnabled := s.isCloudConflictBackupEnabled()
+	syncedTags, hasTagBase := s.syncState.GetSyncedNoteTags()
+	localOnlyNoteIDs, _ := s.noteService.LocalOnlyIDs()
 
 	cloudNoteList, err := s.driveSync.DownloadNoteList(s.ctx, noteListID)
+	if errors.Is(err, errSyncEncryptionLocked) {
+		s.markEncryptionLocked()
+		return nil
+	}
 	if err != nil {
 		return s.auth.HandleOfflineTransition(fmt.Errorf("failed to download note list: %w", err))
 	}
@@ -943,6 +1137,8 @@ func (s *driveService) resolveConflict(noteListID string) error {
 	deleteFailures := 0
 	dirtySynced := make(map[string]bool, len(dirtyIDs))
 	stagedDownloads := make(map[string]*Note)
+	// 適用時に履歴へ残す処理と理由 (Time / NoteID / Title は適用時に埋める)
+	stagedActions := make(map[string]SyncAction)
 	stagedCloudWinOverrides := make(map[string]stagedCloudWinOverride)
 
 	// ローカルで削除済みのフォルダ配下ノートは削除対象として扱う
@@ -958,7 +1154,7 @@ func (s *driveService) resolveConflict(noteListID string) error {
 	uploadTotal := 0
 	for id := range dirtyIDs {
 		cloudNote, existsInCloud := cloudMap[id]
-		if !existsInCloud || cloudNote.ContentHash == lastSyncedHashes[id] {
+		if !localOnlyNoteIDs[id] && (!existsInCloud || cloudNote.ContentHash == lastSyncedHashes[id]) {
 			uploadTotal++
 		}
 	}
@@ -968,10 +1164,19 @@ func (s *driveService) resolveConflict(noteListID string) error {
 		cloudNote, existsInCloud := cloudMap[id]
 		lastHash := lastSyncedHashes[id]
 
+		if localOnlyNoteIDs[id] {
+			// 同期しないノートは上げない (処理済みとして扱い、ローカルのメタデータを残す)
+			if note, err := s.noteService.LoadNote(id); err == nil {
+				processedDirtyHashes[id] = computeContentHash(note)
+			}
+			continue
+		}
+
 		if !existsInCloud || cloudNote.ContentHash == lastHash {
 			note, err := s.noteService.LoadNote(id)
 			if err != nil {
 				s.logger.ErrorCode(err, MsgDriveErrorLoadDirtyNote, map[string]interface{}{"noteId": id})
+				s.recordSyncAction(syncActionFailed, id, "", "failed to load local note: "+err.Error())
 				uploadFailures++
 				continue
 			}
@@ -984,47 +1189,107 @@ func (s *driveService) resolveConflict(noteListID string) error {
 			// CreateNote は upsert なので Create/Update 分岐は不要 (pushLocalChanges と同様)
 			if err := s.driveSync.CreateNote(s.ctx, note); err != nil {
 				s.logger.ErrorCode(err, MsgDriveErrorCreateNote, map[string]interface{}{"noteId": id})
+				s.recordSyncAction(syncActionFailed, id, note.Title, "upload failed: "+err.Error())
 				uploadFailures++
 				continue
 			}
 			processedDirtyHashes[id] = computeContentHash(note)
 			dirtySynced[id] = true
+			s.syncState.UpdateSyncedNoteBase(note)
+			s.recordSyncAction(syncActionUploaded, id, note.Title, "changed on this device")
 		} else {
 			localNote, err := s.noteService.LoadNote(id)
 			if err != nil {
 				s.logger.ErrorCode(err, MsgDriveErrorLoadLocalForConflict, map[string]interface{}{"noteId": id})
+				s.recordSyncAction(syncActionFailed, id, "", "failed to load local note: "+err.Error())
 				uploadFailures++
 				continue
 			}
+			// タグは本文の勝敗とは別に、前回同期時のタグを base にノート単位でマージする
+			_, syncedBefore := lastSyncedHashes[id]
+			mergedTags := mergeNoteTags(syncedTags[id], hasTagBase && syncedBefore, localNote.Tags, cloudNote.Tags)
+
+			// 本文も前回同期時点の base が分かれば 3-way マージし、変更が重ならなければ両方を残す
+			var prefetched *Note
+			if base, ok := s.syncState.GetSyncedNoteBase(id); ok && !localNote.Encrypted && !cloudNote.Encrypted {
+				if cloudFull, dlErr := s.driveSync.DownloadNote(s.ctx, id); dlErr == nil {
+					prefetched = cloudFull
+					if merged, ok := mergeNoteForSync(base, localNote, cloudFull, mergedTags); ok {
+						s.logger.InfoCode(MsgDriveConflictMerged, map[string]interface{}{"noteId": id})
+						if err := s.driveSync.UpdateNote(s.ctx, merged); err != nil {
+							s.logger.ErrorCode(err, MsgDriveErrorUploadNote, map[string]interface{}{"noteId": id})
+							s.recordSyncAction(syncActionFailed, id, localNote.Title, "upload of merged note failed: "+err.Error())
+							uploadFailures++
+							continue
+						}
+						stagedDownloads[id] = merged
+						stagedActions[id] = SyncAction{Action: syncActionMerged, Reason: "edited on both devices; changes merged"}
+						processedDirtyHashes[id] = computeContentHash(merged)
+						dirtySynced[id] = true
+						continue
+					}
+				}
+			}
+
 			if isModifiedTimeAfter(localNote.ModifiedTime, cloudNote.ModifiedTime) {
 				s.logger.InfoCode(MsgDriveConflictKeepLocal, map[string]interface{}{"noteId": id})
+				if !slices.Equal(mergedTags, localNote.Tags) {
+					merged, err := s.noteService.SaveTagsFromSync(localNote, mergedTags)
+					if err != nil {
+						s.logger.ErrorCode(err, MsgDriveErrorSaveDownloadedNote, map[string]interface{}{"noteId": id})
+						uploadFailures++
+						continue
+					}
+					localNote = merged
+				}
 				if err := s.driveSync.UpdateNote(s.ctx, localNote); err != nil {
 					s.logger.ErrorCode(err, MsgDriveErrorUploadNote, map[string]interface{}{"noteId": id})
+					s.recordSyncAction(syncActionFailed, id, localNote.Title, "upload failed: "+err.Error())
 					uploadFailures++
 				} else {
 					processedDirtyHashes[id] = computeContentHash(localNote)
+					s.syncState.UpdateSyncedNoteBase(localNote)
+					s.recordSyncAction(syncActionUploaded, id, localNote.Title, "edited on both devices; this device's version is newer")
 				}
 			} else {
 				s.logger.InfoCode(MsgDriveConflictKeepCloud, map[string]interface{}{"noteId": id})
-				downloaded, dlErr := s.driveSync.DownloadNote(s.ctx, id)
+				downloaded, dlErr := prefetched, error(nil)
+				if downloaded == nil {
+					downloaded, dlErr = s.driveSync.DownloadNote(s.ctx, id)
+				}
 				if dlErr != nil {
 					if isDriveNotFoundError(dlErr) {
 						s.logger.InfoCode(MsgDriveNoteMissingUploadLocal, map[string]interface{}{"noteId": id})
 						if err := s.driveSync.CreateNote(s.ctx, localNote); err != nil {
 							s.logger.ErrorCode(err, MsgDriveErrorRecreateMissingNote, map[string]interface{}{"noteId": id})
+							s.recordSyncAction(syncActionFailed, id, localNote.Title, "failed to re-create note missing from cloud: "+err.Error())
 							missingCloudNoteIDs[id] = true
 							uploadFailures++
 							continue
 						}
 						processedDirtyHashes[id] = computeContentHash(localNote)
 						dirtySynced[id] = true
+						s.syncState.UpdateSyncedNoteBase(localNote)
+						s.recordSyncAction(syncActionUploaded, id, localNote.Title, "missing from cloud; re-created from this device")
 						continue
 					}
 					s.logger.ErrorCode(dlErr, MsgDriveErrorDownloadNote, map[string]interface{}{"noteId": id})
+					s.recordSyncAction(syncActionFailed, id, localNote.Title, "download failed: "+dlErr.Error())
 					uploadFailures++
 					continue
 				}
 
+				if !slices.Equal(mergedTags, downloaded.Tags) {
+					// ローカルで付けたタグを残した版をクラウドにも上げ直す
+					downloaded.Tags = mergedTags
+					if err := s.driveSync.UpdateNote(s.ctx, downloaded); err != nil {
+						s.logger.ErrorCode(err, MsgDriveErrorUploadNote, map[string]interface{}{"noteId": id})
+						s.recordSyncAction(syncActionFailed, id, localNote.Title, "upload of merged tags failed: "+err.Error())
+						uploadFailures++
+						continue
+					}
+				}
+
 				if backupEnabled {
 					stagedCloudWinOverrides[id] = stagedCloudWinOverride{
 						localNote: localNote,
@@ -1033,6 +1298,7 @@ func (s *driveService) resolveConflict(noteListID string) error {
 					}
 				}
 				stagedDownloads[id] = downloaded
+				stagedActions[id] = SyncAction{Action: syncActionDownloaded, Reason: "edited on both devices; the other device's version is newer"}
 				processedDirtyHashes[id] = computeContentHash(downloaded)
 				dirtySynced[id] = true
 			}
@@ -1040,24 +1306,33 @@ func (s *driveService) resolveConflict(noteListID string) error {
 	}
 
 	for id := range deletedIDs {
-		if _, exists := cloudMap[id]; exists {
+		if cloudNote, exists := cloudMap[id]; exists {
 			s.logger.InfoCode(MsgDriveSyncDeleteNote, map[string]interface{}{"noteId": id})
 			if err := s.driveSync.DeleteNote(s.ctx, id); err != nil {
 				if isDriveNotFoundError(err) {
 					s.logger.InfoCode(MsgDriveNoteAlreadyAbsent, map[string]interface{}{"noteId": id})
+					s.recordSyncAction(syncActionDeleted, id, cloudNote.Title, "deleted on this device (already absent from cloud)")
 					continue
 				}
 				s.logger.ErrorCode(err, MsgDriveErrorDeleteNote, map[string]interface{}{"noteId": id})
+				s.recordSyncAction(syncActionFailed, id, cloudNote.Title, "cloud delete failed: "+err.Error())
 				deleteFailures++
+				continue
 			}
+			s.recordSyncAction(syncActionDeleted, id, cloudNote.Title, "deleted on this device")
 		}
 	}
+	deleteFailures += s.removeLocalOnlyNotesFromCloud(localOnlyNoteIDs, func(id string) bool {
+		_, inCloud := cloudMap[id]
+		return inCloud && !deletedIDs[id]
+	})
 
 	var localMap map[string]NoteMetadata
 	var localFoldersSnapshot []Folder
 	var localTopLevelSnapshot []TopLevelItem
 	var localArchivedTopLevelSnapshot []TopLevelItem
 	var localCollapsedFolderSnapshot []string
+	var localTrashSnapshot []TrashItem
 	s.noteService.WithLock(func() {
 		localMap = make(map[string]NoteMetadata, len(s.noteService.noteList.Notes))
 		for _, n := range s.noteService.noteList.Notes {
@@ -1067,9 +1342,10 @@ func (s *driveService) resolveConflict(noteListID string) error {
 		localTopLevelSnapshot = append([]TopLevelItem(nil), s.noteService.noteList.TopLevelOrder...)
 		localArchivedTopLevelSnapshot = append([]TopLevelItem(nil), s.noteService.noteList.ArchivedTopLevelOrder...)
 		localCollapsedFolderSnapshot = append([]string(nil), s.noteService.noteList.CollapsedFolderIDs...)
+		localTrashSnapshot = cloneTrashItems(s.noteService.noteList.Trash)
 	})
 	for _, cloudNote := range cloudNoteList.Notes {
-		if dirtyIDs[cloudNote.ID] || deletedIDs[cloudNote.ID] {
+		if dirtyIDs[cloudNote.ID] || deletedIDs[cloudNote.ID] || localOnlyNoteIDs[cloudNote.ID] {
 			continue
 		}
 		localNote, exists := localMap[cloudNote.ID]
@@ -1083,9 +1359,11 @@ func (s *driveService) resolveConflict(noteListID string) error {
 					continue
 				}
 				s.logger.ErrorCode(dlErr, MsgDriveErrorDownloadNote, map[string]interface{}{"noteId": cloudNote.ID})
+				s.recordSyncAction(syncActionFailed, cloudNote.ID, cloudNote.Title, "download failed: "+dlErr.Error())
 				continue
 			}
 			stagedDownloads[cloudNote.ID] = downloaded
+			stagedActions[cloudNote.ID] = SyncAction{Action: syncActionDownloaded, Reason: "changed on another device"}
 		}
 	}
 
@@ -1116,6 +1394,8 @@ func (s *driveService) resolveConflict(noteListID string) error {
 			currentHashes,
 		) {
 			s.logger.InfoCode(MsgDriveDeferConflictMerge, nil)
+			s.deferSyncRun("local changes arrived during conflict resolution; applying cloud changes on next sync")
+			s.recordDeferredDownloads(stagedDownloads)
 			s.pollingService.RefreshChangeToken()
 			return nil
 		}
@@ -1142,15 +1422,21 @@ func (s *driveService) resolveConflict(noteListID string) error {
 						s.logger.Console("Drive: failed to backup local note %s before cloud overwrite: %v", id, backupErr)
 					} else {
 						s.logger.Console("Drive: backed up local note %s before cloud overwrite: %s", id, backupPath)
+						s.recordSyncAction(syncActionConflictBackedUp, id, override.localNote.Title, "edited on both devices; this device's version saved to "+filepath.Base(backupPath))
 					}
 				}
 			}
 
 			if err := s.noteService.SaveNoteFromSync(stagedDownloads[id]); err != nil {
 				s.logger.ErrorCode(err, MsgDriveErrorSaveDownloadedNote, map[string]interface{}{"noteId": id})
+				s.recordSyncAction(syncActionFailed, id, stagedDownloads[id].Title, "failed to save downloaded note: "+err.Error())
 				uploadFailures++
 				continue
 			}
+			s.syncState.UpdateSyncedNoteBase(stagedDownloads[id])
+			if action, ok := stagedActions[id]; ok {
+				s.recordSyncAction(action.Action, id, stagedDownloads[id].Title, action.Reason)
+			}
 		}
 	}
 
@@ -1158,17 +1444,24 @@ func (s *driveService) resolveConflict(noteListID string) error {
 	// （前回の整合性修復やsync中断で物理ファイルが残っている可能性があるため、
 	//   ValidateIntegrity がorphanとして復元→再削除の無限ループを防止する）
 	for id := range deletedIDs {
+		if localOnlyNoteIDs[id] {
+			continue
+		}
 		if err := s.noteService.DeleteNoteFromSync(id); err != nil {
 			s.logger.Console("Drive: failed to clean up local file for deleted note %s: %v", id, err)
 		}
 	}
 
+	// 他端末でゴミ箱に移したノートは元に戻せるよう手元にもファイルを用意する
+	s.downloadMissingTrashNotes(cloudNoteList.Trash)
+	cloudTrashNoteIDs := trashNoteIDs(cloudNoteList.Trash)
+
 	var localNotesSnapshot2 []NoteMetadata
 	s.noteService.WithLock(func() {
 		localNotesSnapshot2 = append([]NoteMetadata(nil), s.noteService.noteList.Notes...)
 	})
 	for _, localNote := range localNotesSnapshot2 {
-		if _, inCloud := cloudMap[localNote.ID]; !inCloud && !dirtyIDs[localNote.ID] && !deletedIDs[localNote.ID] {
+		if _, inCloud := cloudMap[localNote.ID]; !inCloud && !dirtyIDs[localNote.ID] && !deletedIDs[localNote.ID] && !cloudTrashNoteIDs[localNote.ID] && !localOnlyNoteIDs[localNote.ID] {
 			s.logger.InfoCode(MsgDriveSyncRemoveLocalDeleted, map[string]interface{}{"noteId": localNote.ID})
 			if backupEnabled {
 				backupPath, backupErr := s.backupLocalNoteBeforeCloudDelete(localNote.ID, "cloud-delete-during-conflict-merge")
@@ -1176,10 +1469,14 @@ func (s *driveService) resolveConflict(noteListID string) error {
 					s.logger.Console("Drive: failed to backup local note %s before cloud deletion: %v", localNote.ID, backupErr)
 				} else {
 					s.logger.Console("Drive: backed up local note %s before cloud deletion: %s", localNote.ID, backupPath)
+					s.recordSyncAction(syncActionConflictBackedUp, localNote.ID, localNote.Title, "deleted on another device; local copy saved to "+filepath.Base(backupPath))
 				}
 			}
 			if err := s.noteService.DeleteNoteFromSync(localNote.ID); err != nil {
 				s.logger.ErrorCode(err, MsgDriveErrorRemoveLocalNote, map[string]interface{}{"noteId": localNote.ID})
+				s.recordSyncAction(syncActionFailed, localNote.ID, localNote.Title, "failed to remove note deleted on another device: "+err.Error())
+			} else {
+				s.recordSyncAction(syncActionDeleted, localNote.ID, localNote.Title, "deleted on another device")
 			}
 		}
 	}
@@ -1220,6 +1517,7 @@ func (s *driveService) resolveConflict(noteListID string) error {
 	// LoadNote / buildNoteMetadata は loadNoteLocked / buildNoteMetadata で代替。
 	var conflictSaveErr error
 	s.noteService.WithLock(func() {
+		localBeforeMerge := *s.noteService.noteList
 		s.noteService.noteList.Folders = filteredFolders
 		s.noteService.noteList.TopLevelOrder = filteredTopLevelOrder
 		s.noteService.noteList.ArchivedTopLevelOrder = filteredArchivedTopLevelOrder
@@ -1286,6 +1584,38 @@ func (s *driveService) resolveConflict(noteListID string) error {
 		s.noteService.noteList.Notes = mergedNotes
 		s.noteService.noteList.Notes = applyLocalStructureForUnchangedNotes(s.noteService.noteList.Notes, localMap)
 		s.noteService.noteList.Folders = mergeFoldersPreferLocal(localFoldersSnapshot, filteredFolders, deletedFolderIDs)
+
+		// ゴミ箱のマージ。ゴミ箱に入ったノート・フォルダは並び順のマージより前に外す
+		knownInCloud := make(map[string]bool, len(cloudNoteList.Notes)+len(cloudNoteList.Folders)+len(dirtyIDs))
+		for _, n := range cloudNoteList.Notes {
+			knownInCloud[n.ID] = true
+		}
+		for _, folder := range cloudNoteList.Folders {
+			knownInCloud[folder.ID] = true
+		}
+		for id := range dirtyIDs {
+			knownInCloud[id] = true
+		}
+		// trash の無い noteList (ゴミ箱を知らないクライアントが書いたもの) では手元のゴミ箱をそのまま残す
+		cloudTrash := cloudNoteList.Trash
+		if cloudTrash == nil {
+			cloudTrash = localTrashSnapshot
+		}
+		s.noteService.noteList.Trash, s.noteService.noteList.Notes, s.noteService.noteList.Folders = mergeTrash(
+			localTrashSnapshot,
+			cloudTrash,
+			s.noteService.noteList.Notes,
+			s.noteService.noteList.Folders,
+			knownInCloud,
+			deletedIDs,
+			deletedFolderIDs,
+		)
+		// 同期しないノート・フォルダはクラウドに無いので、手元のものを残す
+		restoreLocalOnlyItems(&localBeforeMerge, s.noteService.noteList)
+		if cloudNoteList.Trash != nil {
+			s.noteService.deleteUnlistedTrashNotesLocked(localTrashSnapshot)
+		}
+
 		s.noteService.noteList.TopLevelOrder = mergeTopLevelOrderPreferLocal(
 			localTopLevelSnapshot,
 			s.noteService.noteList.TopLevelOrder,
@@ -1314,6 +1644,7 @@ func (s *driveService) resolveConflict(noteListID string) error {
 
 	if uploadFailures > 0 || deleteFailures > 0 {
 		s.logger.InfoCode(MsgDrivePartialConflictDeferred, map[string]interface{}{"uploadFailures": uploadFailures, "deleteFailures": deleteFailures})
+		s.deferSyncRun(fmt.Sprintf("%d uploads and %d deletes failed; retrying on next sync", uploadFailures, deleteFailures))
 		s.pollingService.RefreshChangeToken()
 		s.logger.NotifyFrontendSyncedAndReload(s.ctx)
 		return nil
@@ -1321,7 +1652,7 @@ func (s *driveService) resolveConflict(noteListID string) error {
 
 	// (saveNoteList は既に上の WithLock 内で実行済みなので不要)
 	noteListID2 := s.auth.GetDriveSync().NoteListID()
-	noteListSnapshotForUpload := s.noteService.SnapshotNoteList()
+	noteListSnapshotForUpload := s.noteService.SnapshotCloudNoteList()
 	if err := s.driveSync.UpdateNoteList(s.ctx, noteListSnapshotForUpload, noteListID2); err != nil {
 		return s.auth.HandleOfflineTransition(fmt.Errorf("failed to upload note list: %w", err))
 	}
@@ -1332,12 +1663,19 @@ func (s *driveService) resolveConflict(noteListID string) error {
 		driveTs = meta2.ModifiedTime
 	}
 	var noteHashes map[string]string
+	var noteTags map[string][]string
 	s.noteService.WithLock(func() {
 		noteHashes = make(map[string]string, len(s.noteService.noteList.Notes))
+		localOnlyNoteIDs, _ := localOnlyIDs(s.noteService.noteList)
 		for _, n := range s.noteService.noteList.Notes {
-			noteHashes[n.ID] = n.ContentHash
+			// 同期しないノートはクラウドに無いので、同期済みとして記録しない
+			if !localOnlyNoteIDs[n.ID] {
+				noteHashes[n.ID] = n.ContentHash
+			}
 		}
+		noteTags = noteTagsByID(s.noteService.noteList.Notes)
 	})
+	s.syncState.UpdateSyncedNoteTags(noteTags)
 	if !s.syncState.ClearDirtyIfUnchanged(clearSnapshotRevision, driveTs, noteHashes) {
 		s.logger.Console("Sync state changed during conflict resolution; retaining dirty flags for next sync")
 		s.syncState.UpdateSyncedState(driveTs, noteHashes)
@@ -1349,6 +1687,17 @@ func (s *driveService) resolveConflict(noteListID string) error {
 	return nil
 }
 
+// noteTagsByID はノートごとのタグを返す (次回のタグの 3-way マージの base として記録する)
+func noteTagsByID(notes []NoteMetadata) map[string][]string {
+	tags := make(map[string][]string, len(notes))
+	for _, n := range notes {
+		if len(n.Tags) > 0 {
+			tags[n.ID] = n.Tags
+		}
+	}
+	return tags
+}
+
 func isDriveNotFoundError(err error) bool {
 	if err == nil {
 		return false
@@ -1411,14 +1760,19 @@ func (s *driveService) backupLocalNoteBeforeCloudDelete(noteID string, reason st
 }
 
 func (s *driveService) writeCloudConflictBackup(record cloudWinBackupRecord, filePrefix string) (string, error) {
+	return writeConflictBackupRecord(s.appDataDir, record, filePrefix)
+}
+
+// writeConflictBackupRecord は競合バックアップを 1 件書き込み、古いものを間引く
+func writeConflictBackupRecord(appDataDir string, record cloudWinBackupRecord, filePrefix string) (string, error) {
 	if record.NoteID == "" {
 		return "", fmt.Errorf("note id is empty")
 	}
-	if strings.TrimSpace(s.appDataDir) == "" {
+	if strings.TrimSpace(appDataDir) == "" {
 		return "", fmt.Errorf("app data dir is empty")
 	}
 
-	backupDir := filepath.Join(s.appDataDir, cloudWinBackupDirName)
+	backupDir := filepath.Join(appDataDir, cloudWinBackupDirName)
 	if err := os.MkdirAll(backupDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create backup directory: %w", err)
 	}
@@ -1509,7 +1863,8 @@ func isCloudConflictBackupFile(name string) bool {
 	if !strings.HasSuffix(name, ".json") {
 		return false
 	}
-	return strings.HasPrefix(name, cloudBackupFilePrefixWins) || strings.HasPrefix(name, cloudBackupFilePrefixDelete)
+	return strings.HasPrefix(name, cloudBackupFilePrefixWins) || strings.HasPrefix(name, cloudBackupFilePrefixDelete) ||
+		strings.HasPrefix(name, linkedFileBackupFilePrefix)
 }
 
 // conflictBackupKindFromName はバックアップファイル名から kind を判定する
@@ -1523,6 +1878,9 @@ func conflictBackupKindFromName(name string) string {
 	if strings.HasPrefix(name, cloudBackupFilePrefixDelete) {
 		return "cloud_delete"
 	}
+	if strings.HasPrefix(name, linkedFileBackupFilePrefix) {
+		return "linked_file"
+	}
 	return ""
 }
 
@@ -1788,7 +2146,9 @@ func mergeFoldersPreferLocal(localFolders []Folder, cloudFolders []Folder, delet
 		added[folder.ID] = true
 	}
 
-	return result
+	// 端末ごとに別のフォルダを移動していると、合わせた結果に循環や存在しない親が生じ得る
+	normalized, _ := normalizeFolderTree(result)
+	return normalized
 }
 
 func mergeTopLevelOrderPreferLocal(
@@ -1820,7 +2180,7 @@ func mergeTopLevelOrderPreferLocal(
 			if !ok {
 				return false
 			}
-			return folder.Archived == archived
+			return isFolderOrderRoot(folder, folderMap, archived)
 		default:
 			return false
 		}
@@ -2058,7 +2418,7 @@ func (s *driveService) ensureNoteList() error {
 		return nil
 	}
 
-	if err := s.driveSync.CreateNoteList(s.ctx, s.noteService.noteList); err != nil {
+	if err := s.driveSync.CreateNoteList(s.ctx, s.noteService.SnapshotCloudNoteList()); err != nil {
 		return err
 	}
 	_, notesID := s.auth.GetDriveSync().FolderIDs()
@@ -2112,7 +2472,9 @@ func (ds *driveService) recoverOrphanCloudNotes(files []*drive.File, ops DriveOp
 	// loadNoteLocked を使う。
 	noteIDSet := make(map[string]bool)
 	existingHashes := make(map[string]bool)
+	var localOnlyNoteIDs map[string]bool
 	ds.noteService.WithLock(func() {
+		localOnlyNoteIDs, _ = localOnlyIDs(ds.noteService.noteList)
 		for _, metadata := range ds.noteService.noteList.Notes {
 			noteIDSet[metadata.ID] = true
 			note, err := ds.noteService.loadNoteLocked(metadata.ID)
@@ -2121,6 +2483,9 @@ func (ds *driveService) recoverOrphanCloudNotes(files []*drive.File, ops DriveOp
 			}
 			existingHashes[computeConflictCopyDedupHash(note)] = true
 		}
+		for id := range trashNoteIDs(ds.noteService.noteList.Trash) {
+			noteIDSet[id] = true
+		}
 	})
 
 	type orphanEntry struct {
@@ -2129,6 +2494,15 @@ func (ds *driveService) recoverOrphanCloudNotes(files []*drive.File, ops DriveOp
 	}
 	var orphans []orphanEntry
 	for noteID, file := range latestFiles {
+		if localOnlyNoteIDs[noteID] {
+			// 同期しないノートがクラウドに残っていれば消す (他の端末で孤立ノートとして復元されないように)
+			if err := ops.DeleteFile(file.Id); err != nil {
+				ds.logger.Console("Failed to delete local-only note from Drive %s: %v", noteID, err)
+			} else {
+				ds.logger.Console("Deleted local-only note from Drive: %s", noteID)
+			}
+			continue
+		}
 		if !noteIDSet[noteID] {
 			orphans = append(orphans, orphanEntry{noteID, file})
 		}
@@ -2147,6 +2521,11 @@ func (ds *driveService) recoverOrphanCloudNotes(files []*drive.File, ops DriveOp
 			ds.logger.Console("Failed to download orphan cloud note %s: %v", entry.noteID, err)
 			continue
 		}
+		content, err = ds.encryption.open(content, syncNoteAAD(entry.noteID))
+		if err != nil {
+			ds.logger.Console("Skipped encrypted orphan cloud note %s: %v", entry.noteID, err)
+			continue
+		}
 
 		var note Note
 		if err := json.Unmarshal(content, &note); err != nil {
@@ -95,6 +95,28 @@ func (m *mockDriveService) DeleteAllDriveData() error {
 	return nil
 }
 
+func (m *mockDriveService) ConnectWebDAV(config WebDAVConfig) error {
+	return nil
+}
+
+func (m *mockDriveService) ConnectLocalFolder(config LocalFolderConfig) error {
+	return nil
+}
+
+func (m *mockDriveService) Shutdown() {}
+
+func (m *mockDriveService) EnableSyncEncryption(passphrase string) error {
+	return nil
+}
+
+func (m *mockDriveService) DisableSyncEncryption() error {
+	return nil
+}
+
+func (m *mockDriveService) GetSyncEncryptionStatus() SyncEncryptionStatus {
+	return SyncEncryptionStatus{}
+}
+
 func (m *mockDriveService) CreateNote(note *Note) error {
 	if !m.isTestMode {
 		return nil
@@ -134,6 +156,10 @@ func (m *mockDriveService) DeleteNoteDrive(noteID string) error {
 	return m.driveSync.DeleteNote(m.ctx, noteID)
 }
 
+func (m *mockDriveService) SyncNotesWithTrigger(trigger string) error {
+	return m.SyncNotes()
+}
+
 func (m *mockDriveService) SyncNotes() error {
 	if !m.isTestMode {
 		return nil
@@ -1365,6 +1365,82 @@ func TestSyncNotes_CaseC_LocalNewerWins(t *testing.T) {
 	assert.False(t, ds.syncState.IsDirty())
 }
 
+// setupThreeWayConflict は base を同期済みの状態から、両端末で note1 を編集した状況を作る
+func setupThreeWayConflict(t *testing.T, ds *driveService, ops *syncTestDriveOps, localContent, cloudContent string) {
+	t.Helper()
+	base := &Note{ID: "note1", Title: "note1", Content: "one\ntwo\nthree\nfour\nfive\n", Language: "plaintext"}
+	ds.syncState.UpdateSyncedNoteBase(base)
+	ds.syncState.ClearDirty("2025-01-01T00:00:00Z", map[string]string{"note1": computeContentHash(base)})
+
+	require.NoError(t, ds.noteService.SaveNoteFromSync(&Note{
+		ID:           "note1",
+		Title:        "note1",
+		Content:      localContent,
+		Language:     "plaintext",
+		ModifiedTime: "2025-01-01T00:00:00Z",
+	}))
+	ds.syncState.MarkNoteDirty("note1")
+	ops.fixedModifiedTime = "2025-01-02T00:00:00Z"
+
+	cloud := &Note{ID: "note1", Title: "note1", Content: cloudContent, Language: "plaintext", ModifiedTime: "2025-01-02T00:00:00Z"}
+	putCloudNote(t, ops, cloud)
+	putCloudNoteList(t, ops, ds.auth.GetDriveSync().NoteListID(), &NoteList{
+		Version: CurrentVersion,
+		Notes: []NoteMetadata{{
+			ID:           "note1",
+			Title:        "note1",
+			Language:     "plaintext",
+			ModifiedTime: cloud.ModifiedTime,
+			ContentHash:  computeContentHash(cloud),
+		}},
+	})
+}
+
+func TestSyncNotes_CaseC_NonOverlappingEditsMerged(t *testing.T) {
+	ds, ops, cleanup := newSyncTestDriveService(t)
+	defer cleanup()
+
+	setupThreeWayConflict(t, ds, ops, "ONE\ntwo\nthree\nfour\nfive\n", "one\ntwo\nthree\nfour\nFIVE\n")
+
+	require.NoError(t, ds.SyncNotes())
+
+	merged := mustLoadLocalNote(t, ds, "note1")
+	assert.Equal(t, "ONE\ntwo\nthree\nfour\nFIVE\n", merged.Content)
+	ops.mu.RLock()
+	cloudData := ops.files["test-file-note1.json"]
+	ops.mu.RUnlock()
+	var uploaded Note
+	require.NoError(t, json.Unmarshal(cloudData, &uploaded))
+	assert.Equal(t, merged.Content, uploaded.Content)
+	assert.False(t, ds.syncState.IsDirty())
+
+	// クリーンにマージできたので負けた側のバックアップは作らない
+	_, err := os.Stat(filepath.Join(ds.appDataDir, cloudWinBackupDirName))
+	assert.True(t, os.IsNotExist(err))
+
+	// マージ結果が次回の base になる
+	base, ok := ds.syncState.GetSyncedNoteBase("note1")
+	require.True(t, ok)
+	assert.Equal(t, merged.Content, base.Content)
+}
+
+func TestSyncNotes_CaseC_OverlappingEditsFallBackToNewer(t *testing.T) {
+	ds, ops, cleanup := newSyncTestDriveService(t)
+	defer cleanup()
+
+	setupThreeWayConflict(t, ds, ops, "one\nlocal\nthree\nfour\nfive\n", "one\ncloud\nthree\nfour\nfive\n")
+
+	require.NoError(t, ds.SyncNotes())
+
+	updated := mustLoadLocalNote(t, ds, "note1")
+	assert.Equal(t, "one\ncloud\nthree\nfour\nfive\n", updated.Content)
+	assert.False(t, ds.syncState.IsDirty())
+
+	entries, err := os.ReadDir(filepath.Join(ds.appDataDir, cloudWinBackupDirName))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
 func TestSyncNotes_CaseC_DeleteAndEdit(t *testing.T) {
 	ds, ops, cleanup := newSyncTestDriveService(t)
 	defer cleanup()
@@ -1596,3 +1672,132 @@ func TestSyncNotes_DriveSyncNil_ReturnsErrorInsteadOfPanic(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not yet initialized")
 }
+
+func TestSyncNotes_RecordsSyncHistory(t *testing.T) {
+	ds, ops, cleanup := newSyncTestDriveService(t)
+	defer cleanup()
+	ds.journal = newSyncJournal(ds.appDataDir)
+
+	require.NoError(t, ds.noteService.SaveNote(&Note{ID: "note1", Title: "diary", Content: "local", Language: "plaintext"}))
+	ds.syncState.MarkNoteDirty("note1")
+	ops.fixedModifiedTime = "2025-01-01T00:00:00Z"
+	ds.syncState.LastSyncedDriveTs = "2025-01-01T00:00:00Z"
+	noteListID := ds.auth.GetDriveSync().NoteListID()
+	putCloudNoteList(t, ops, noteListID, &NoteList{Version: CurrentVersion, Notes: []NoteMetadata{}})
+	require.NoError(t, ds.SyncNotesWithTrigger(SyncTriggerSave))
+
+	// 他端末でノートが消された
+	ops.fixedModifiedTime = "2025-01-02T00:00:00Z"
+	putCloudNoteList(t, ops, noteListID, &NoteList{Version: CurrentVersion, Notes: []NoteMetadata{}})
+	require.NoError(t, ds.SyncNotesWithTrigger(SyncTriggerPoll))
+
+	// 何も起きなかった同期は記録しない
+	require.NoError(t, ds.SyncNotes())
+
+	runs, err := ds.journal.list(0)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+
+	pull := runs[0]
+	assert.Equal(t, SyncTriggerPoll, pull.Trigger)
+	assert.Equal(t, syncModePull, pull.Mode)
+	assert.Equal(t, syncResultOK, pull.Result)
+	assert.NotEmpty(t, pull.Device)
+	require.Len(t, pull.Actions, 2)
+	assert.Equal(t, syncActionConflictBackedUp, pull.Actions[0].Action)
+	assert.Equal(t, SyncAction{Time: pull.Actions[1].Time, NoteID: "note1", Title: "diary", Action: syncActionDeleted, Reason: "deleted on another device"}, pull.Actions[1])
+
+	push := runs[1]
+	assert.Equal(t, SyncTriggerSave, push.Trigger)
+	assert.Equal(t, syncModePush, push.Mode)
+	require.Len(t, push.Actions, 1)
+	assert.Equal(t, syncActionUploaded, push.Actions[0].Action)
+	assert.Equal(t, "diary", push.Actions[0].Title)
+	assert.NotEmpty(t, push.FinishedAt)
+}
+
+func TestSyncNotes_LocalOnlyItemsStayOffCloud(t *testing.T) {
+	ds, ops, cleanup := newSyncTestDriveService(t)
+	defer cleanup()
+	ns := ds.noteService
+
+	require.NoError(t, ns.SaveNote(&Note{ID: "shared", Title: "shared", Content: "shared", Language: "plaintext"}))
+	require.NoError(t, ns.SaveNote(&Note{ID: "secret", Title: "secret", Content: "secret", Language: "plaintext"}))
+	require.NoError(t, ns.SaveNote(&Note{ID: "scratch", Title: "scratch", Content: "scratch", Language: "plaintext"}))
+	folder, err := ns.CreateFolder("private")
+	require.NoError(t, err)
+	require.NoError(t, ns.MoveNoteToFolder("scratch", folder.ID))
+	for _, id := range []string{"shared", "secret", "scratch"} {
+		ds.syncState.MarkNoteDirty(id)
+	}
+	ops.fixedModifiedTime = "2025-01-01T00:00:00Z"
+	ds.syncState.LastSyncedDriveTs = "2025-01-01T00:00:00Z"
+	noteListID := ds.auth.GetDriveSync().NoteListID()
+	putCloudNoteList(t, ops, noteListID, &NoteList{Version: CurrentVersion, Notes: []NoteMetadata{}})
+	require.NoError(t, ds.SyncNotes())
+	require.Len(t, cloudNoteListFromMock(t, ops, noteListID).Notes, 3)
+
+	// 同期済みのノートとフォルダに印を付けると、クラウドからだけ消える
+	require.NoError(t, ns.SetNoteLocalOnly("secret", true))
+	require.NoError(t, ns.SetFolderLocalOnly(folder.ID, true))
+	ds.syncState.MarkDirty()
+	require.NoError(t, ds.SyncNotes())
+
+	ops.mu.RLock()
+	_, secretInCloud := ops.files["test-file-secret.json"]
+	_, scratchInCloud := ops.files["test-file-scratch.json"]
+	ops.mu.RUnlock()
+	assert.False(t, secretInCloud)
+	assert.False(t, scratchInCloud)
+	cloudList := cloudNoteListFromMock(t, ops, noteListID)
+	assert.Equal(t, []string{"shared"}, noteIDsOf(cloudList.Notes))
+	assert.Empty(t, cloudList.Folders)
+	assert.Equal(t, "secret", mustLoadLocalNote(t, ds, "secret").Content)
+	assert.False(t, ds.syncState.IsDirty())
+
+	// 他の端末の変更を取り込んでも、クラウドに無い同期しないノートは消さない
+	ops.fixedModifiedTime = "2025-01-02T00:00:00Z"
+	cloudNew := &Note{ID: "cloud-new", Title: "cloud", Content: "from cloud", Language: "plaintext", ModifiedTime: "2025-01-02T00:00:00Z"}
+	putCloudNote(t, ops, cloudNew)
+	cloudList.Notes = append(cloudList.Notes, NoteMetadata{ID: cloudNew.ID, Title: cloudNew.Title, Language: cloudNew.Language, ContentHash: computeContentHash(cloudNew)})
+	putCloudNoteList(t, ops, noteListID, cloudList)
+	require.NoError(t, ds.SyncNotes())
+
+	assert.ElementsMatch(t, []string{"shared", "secret", "scratch", "cloud-new"}, noteIDsOf(ns.noteList.Notes))
+	require.Len(t, ns.noteList.Folders, 1)
+	assert.True(t, ns.noteList.Folders[0].LocalOnly)
+	assert.Equal(t, "scratch", mustLoadLocalNote(t, ds, "scratch").Content)
+
+	// 印を外したフォルダのノートは次の同期で上がる
+	require.NoError(t, ns.SetFolderLocalOnly(folder.ID, false))
+	ds.syncState.MarkDirty()
+	ds.syncState.MarkNoteDirty("scratch")
+	require.NoError(t, ds.SyncNotes())
+	cloudList = cloudNoteListFromMock(t, ops, noteListID)
+	assert.ElementsMatch(t, []string{"shared", "scratch", "cloud-new"}, noteIDsOf(cloudList.Notes))
+	assert.Len(t, cloudList.Folders, 1)
+}
+
+func TestSyncNotes_CaseC_LocalOnlyNoteNotUploadedOrRemoved(t *testing.T) {
+	ds, ops, cleanup := newSyncTestDriveService(t)
+	defer cleanup()
+
+	require.NoError(t, ds.noteService.SaveNote(&Note{ID: "secret", Title: "secret", Content: "local only", Language: "plaintext"}))
+	require.NoError(t, ds.noteService.SetNoteLocalOnly("secret", true))
+	ds.syncState.MarkNoteDirty("secret")
+
+	ops.fixedModifiedTime = "2025-01-02T00:00:00Z"
+	ds.syncState.LastSyncedDriveTs = "2025-01-01T00:00:00Z"
+	noteListID := ds.auth.GetDriveSync().NoteListID()
+	putCloudNoteList(t, ops, noteListID, &NoteList{Version: CurrentVersion, Notes: []NoteMetadata{}})
+	require.NoError(t, ds.SyncNotes())
+
+	ops.mu.RLock()
+	_, uploaded := ops.files["test-file-secret.json"]
+	ops.mu.RUnlock()
+	assert.False(t, uploaded)
+	assert.Empty(t, cloudNoteListFromMock(t, ops, noteListID).Notes)
+	assert.Equal(t, []string{"secret"}, noteIDsOf(ds.noteService.noteList.Notes))
+	assert.Equal(t, "local only", mustLoadLocalNote(t, ds, "secret").Content)
+	assert.False(t, ds.syncState.IsDirty())
+}
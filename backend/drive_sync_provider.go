@@ -0,0 +1,233 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Google Drive 以外の同期プロバイダー (WebDAV / ローカルフォルダ) の接続処理
+// 同期アルゴリズムは Google Drive と共通で、driveService の DriveOperations を
+// パスをIDとして扱う実装 (webdavOperations / localFolderOperations) に差し替えることで動作する。
+
+const (
+	syncProviderGoogleDrive   = "google"
+	syncProviderWebDAV        = "webdav"
+	syncProviderLocalFolder   = "folder"
+	webdavConfigFileName      = "webdav.json"
+	localFolderConfigFileName = "sync_folder.json"
+)
+
+// pathDriveOperations はパスをIDとして扱う DriveOperations
+type pathDriveOperations interface {
+	DriveOperations
+	Ping() error // 同期先に到達できるか確認する
+}
+
+// 同期プロバイダー名を検証して正規化する ("" は Google Drive として扱う)
+func normalizeSyncProvider(provider string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case "", syncProviderGoogleDrive:
+		return syncProviderGoogleDrive, nil
+	case syncProviderWebDAV:
+		return syncProviderWebDAV, nil
+	case syncProviderLocalFolder:
+		return syncProviderLocalFolder, nil
+	}
+	return "", fmt.Errorf("unknown sync provider: %s", provider)
+}
+
+// 保存済みのプロバイダー設定を読み込む (未設定なら false)
+func loadSyncProviderConfig(appDataDir string, fileName string, config interface{}) (bool, error) {
+	data, err := os.ReadFile(filepath.Join(appDataDir, fileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read %s: %w", fileName, err)
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", fileName, err)
+	}
+	return true, nil
+}
+
+// プロバイダー設定を保存する (パスワードを含み得るため所有者のみ読み書き可能にする)
+func saveSyncProviderConfig(appDataDir string, fileName string, config interface{}) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", fileName, err)
+	}
+	if err := os.WriteFile(filepath.Join(appDataDir, fileName), data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", fileName, err)
+	}
+	return nil
+}
+
+func removeSyncProviderConfig(appDataDir string, fileName string) error {
+	err := os.Remove(filepath.Join(appDataDir, fileName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// 保存済みの WebDAV 接続設定を読み込む (未設定なら nil)
+func loadWebDAVConfig(appDataDir string) (*WebDAVConfig, error) {
+	var config WebDAVConfig
+	found, err := loadSyncProviderConfig(appDataDir, webdavConfigFileName, &config)
+	if err != nil || !found || config.URL == "" {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// 保存済みの同期フォルダ設定を読み込む (未設定なら nil)
+func loadLocalFolderConfig(appDataDir string) (*LocalFolderConfig, error) {
+	var config LocalFolderConfig
+	found, err := loadSyncProviderConfig(appDataDir, localFolderConfigFileName, &config)
+	if err != nil || !found || config.Path == "" {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// settings.json から同期プロバイダーを読み取る
+func (s *driveService) syncProvider() string {
+	data, err := os.ReadFile(filepath.Join(s.appDataDir, "settings.json"))
+	if err != nil {
+		return syncProviderGoogleDrive
+	}
+	var payload struct {
+		SyncProvider string `json:"syncProvider"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return syncProviderGoogleDrive
+	}
+	provider, err := normalizeSyncProvider(payload.SyncProvider)
+	if err != nil {
+		return syncProviderGoogleDrive
+	}
+	return provider
+}
+
+// usesPathProvider は WebDAV / ローカルフォルダで同期中か
+// (appDataFolder やマイグレーションなど Drive 固有の処理を行わない)
+func (s *driveService) usesPathProvider() bool {
+	return s.webdavConfig.Load() != nil || s.localFolderConfig.Load() != nil
+}
+
+// newPathOperations は接続中のプロバイダーの DriveOperations を作る (Google Drive なら nil)
+func (s *driveService) newPathOperations() (pathDriveOperations, error) {
+	if config := s.webdavConfig.Load(); config != nil {
+		return NewWebDAVOperations(*config, s.logger)
+	}
+	if config := s.localFolderConfig.Load(); config != nil {
+		return NewLocalFolderOperations(*config, s.logger)
+	}
+	return nil, nil
+}
+
+// pingPathProvider は接続中のプロバイダーに到達できるか確認する
+func (s *driveService) pingPathProvider() error {
+	ops, err := s.newPathOperations()
+	if err != nil {
+		return err
+	}
+	if ops == nil {
+		return fmt.Errorf("no sync provider configured")
+	}
+	return ops.Ping()
+}
+
+// clearPathProvider は接続設定を破棄する。removeFiles なら保存済みの設定ファイルも削除する
+func (s *driveService) clearPathProvider(removeFiles bool) {
+	hadWebDAV := s.webdavConfig.Swap(nil) != nil
+	hadLocalFolder := s.localFolderConfig.Swap(nil) != nil
+	if !removeFiles {
+		return
+	}
+	if hadWebDAV {
+		if err := removeSyncProviderConfig(s.appDataDir, webdavConfigFileName); err != nil {
+			s.logger.Console("Failed to remove WebDAV config: %v", err)
+		}
+	}
+	if hadLocalFolder {
+		if err := removeSyncProviderConfig(s.appDataDir, localFolderConfigFileName); err != nil {
+			s.logger.Console("Failed to remove sync folder config: %v", err)
+		}
+	}
+}
+
+// 保存済みの設定で自動接続する (設定が無ければオフラインのまま)
+func (s *driveService) initializePathProvider(provider string) error {
+	switch provider {
+	case syncProviderWebDAV:
+		config, err := loadWebDAVConfig(s.appDataDir)
+		if err != nil {
+			s.logger.Console("Failed to load WebDAV config: %v", err)
+		}
+		if config == nil {
+			return nil
+		}
+		s.webdavConfig.Store(config)
+	case syncProviderLocalFolder:
+		config, err := loadLocalFolderConfig(s.appDataDir)
+		if err != nil {
+			s.logger.Console("Failed to load sync folder config: %v", err)
+		}
+		if config == nil {
+			return nil
+		}
+		s.localFolderConfig.Store(config)
+	default:
+		return nil
+	}
+
+	if err := s.pingPathProvider(); err != nil {
+		// 一時的な接続断として扱い、設定は保持したまま再接続に任せる
+		return s.auth.HandleOfflineTransition(err)
+	}
+	s.logger.Console("InitializeDrive success (%s)", provider)
+	s.auth.GetDriveSync().SetConnected(true)
+	return s.onConnected()
+}
+
+// connectPathProvider は疎通を確認し、設定を保存してから同期を開始する
+func (s *driveService) connectPathProvider(fileName string, config interface{}) error {
+	if err := s.pingPathProvider(); err != nil {
+		s.clearPathProvider(false)
+		return err
+	}
+	if err := saveSyncProviderConfig(s.appDataDir, fileName, config); err != nil {
+		s.clearPathProvider(false)
+		return err
+	}
+	s.logger.NotifyDriveStatus(s.ctx, "syncing")
+	s.auth.GetDriveSync().SetConnected(true)
+	return s.onConnected()
+}
+
+// WebDAV サーバーに接続して同期を開始 ------------------------------------------------------------
+func (s *driveService) ConnectWebDAV(config WebDAVConfig) error {
+	config.URL = strings.TrimSpace(config.URL)
+	s.clearPathProvider(false)
+	s.webdavConfig.Store(&config)
+	if err := s.connectPathProvider(webdavConfigFileName, config); err != nil {
+		return fmt.Errorf("failed to connect to WebDAV server: %w", err)
+	}
+	return nil
+}
+
+// ローカルフォルダを同期先にして同期を開始 ------------------------------------------------------------
+func (s *driveService) ConnectLocalFolder(config LocalFolderConfig) error {
+	config.Path = strings.TrimSpace(config.Path)
+	s.clearPathProvider(false)
+	s.localFolderConfig.Store(&config)
+	if err := s.connectPathProvider(localFolderConfigFileName, config); err != nil {
+		return fmt.Errorf("failed to use sync folder: %w", err)
+	}
+	return nil
+}
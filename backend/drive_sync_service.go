@@ -52,6 +52,7 @@ type driveSyncServiceImpl struct {
 	cacheMu         sync.RWMutex
 	lastNoteListMd5 string
 	cachedNoteList  *NoteList
+	encryption      *syncEncryption // nil なら暗号化しない
 }
 
 // DriveSyncServiceインスタンスを作成
@@ -60,6 +61,17 @@ func NewDriveSyncService(
 	notesFolderID string,
 	rootFolderID string,
 	logger AppLogger,
+) DriveSyncService {
+	return newDriveSyncService(driveOps, notesFolderID, rootFolderID, logger, nil)
+}
+
+// 同期データの暗号化を使う DriveSyncService を作成
+func newDriveSyncService(
+	driveOps DriveOperations,
+	notesFolderID string,
+	rootFolderID string,
+	logger AppLogger,
+	encryption *syncEncryption,
 ) DriveSyncService {
 	return &driveSyncServiceImpl{
 		driveOps:      driveOps,
@@ -67,6 +79,7 @@ func NewDriveSyncService(
 		rootFolderID:  rootFolderID,
 		logger:        logger,
 		fileIDCache:   make(map[string]string),
+		encryption:    encryption,
 	}
 }
 
@@ -246,14 +259,27 @@ var listOperationRetryConfig = &retryConfig{
 // ノート操作
 // ----------------------------------------------------------------
 
+// アップロードするノートをシリアライズする（暗号化が有効なら暗号化する）
+func (d *driveSyncServiceImpl) marshalNote(note *Note) ([]byte, error) {
+	noteContent, err := json.MarshalIndent(note, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal note content: %w", err)
+	}
+	noteContent, err = d.encryption.seal(noteContent, syncNoteAAD(note.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt note %s: %w", note.ID, err)
+	}
+	return noteContent, nil
+}
+
 // ノートを新規作成 ------------------------------------------------------------
 func (d *driveSyncServiceImpl) CreateNote(
 	ctx context.Context,
 	note *Note,
 ) error {
-	noteContent, err := json.MarshalIndent(note, "", "  ")
+	noteContent, err := d.marshalNote(note)
 	if err != nil {
-		return fmt.Errorf("failed to marshal note content: %w", err)
+		return err
 	}
 
 	fileName := note.ID + ".json"
@@ -285,9 +311,9 @@ func (d *driveSyncServiceImpl) UpdateNote(
 	ctx context.Context,
 	note *Note,
 ) error {
-	noteContent, err := json.MarshalIndent(note, "", "  ")
+	noteContent, err := d.marshalNote(note)
 	if err != nil {
-		return fmt.Errorf("failed to marshal note content: %w", err)
+		return err
 	}
 
 	fileID, err := d.resolveNoteFileID(note.ID)
@@ -361,6 +387,11 @@ func (d *driveSyncServiceImpl) DownloadNote(
 		return nil, fmt.Errorf("failed to download note: %w", err)
 	}
 
+	content, err = d.encryption.open(content, syncNoteAAD(noteID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt note %s: %w", noteID, err)
+	}
+
 	var note Note
 	if err := json.Unmarshal(content, &note); err != nil {
 		return nil, fmt.Errorf("failed to decode note %s: %w", noteID, err)
@@ -481,6 +512,10 @@ func (d *driveSyncServiceImpl) CreateNoteList(
 	if err != nil {
 		return fmt.Errorf("failed to marshal note list: %w", err)
 	}
+	noteListContent, err = d.encryption.seal(noteListContent, syncNoteListAAD)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt note list: %w", err)
+	}
 
 	// ファイル作成をリトライ付きで実行
 	err = d.withRetry(func() error {
@@ -513,6 +548,10 @@ func (d *driveSyncServiceImpl) UpdateNoteList(
 	if err != nil {
 		return fmt.Errorf("failed to marshal note list: %w", err)
 	}
+	noteListContent, err = d.encryption.seal(noteListContent, syncNoteListAAD)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt note list: %w", err)
+	}
 
 	err = d.withRetry(func() error {
 		return d.driveOps.UpdateFile(noteListID, noteListContent)
@@ -571,6 +610,12 @@ func (d *driveSyncServiceImpl) DownloadNoteList(
 		return nil, fmt.Errorf("failed to download note list: %w", err)
 	}
 
+	// 復号できない場合は破損ではないため、キャッシュで代用せずにエラーを返す
+	content, err = d.encryption.open(content, syncNoteListAAD)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt note list: %w", err)
+	}
+
 	var noteList NoteList
 	if err := json.Unmarshal(content, &noteList); err != nil {
 		if d.cachedNoteList != nil {
@@ -0,0 +1,356 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	textunicode "golang.org/x/text/encoding/unicode"
+)
+
+// ------------------------------------------------------------
+// ファイルノートの文字コード・BOM・改行コード
+// ------------------------------------------------------------
+//
+// 開いたときに検出した形式 (TextFormat) を FileNote に持たせ、保存時に同じ形式で書き戻す。
+// - BOM 付きの UTF-8 / UTF-16 は BOM で、ISO-2022-JP はエスケープシーケンスで判定する。
+// - それ以外の UTF-8 でないデータは、Shift_JIS・EUC-JP・GB18030 のうち不正なバイトが無く、
+//   それらしい文字 (日本語ならかな) を含むものを選び、どれでもなければ Windows-1252 とする。
+// - 保存先の文字コードで表せない文字があれば書き込まず、行ごとに報告する (文字化けさせない)。
+
+// 文字コード (TextFormat.Encoding)
+const (
+	textEncodingUTF8        = "UTF-8"
+	textEncodingUTF16LE     = "UTF-16LE"
+	textEncodingUTF16BE     = "UTF-16BE"
+	textEncodingShiftJIS    = "Shift_JIS"
+	textEncodingEUCJP       = "EUC-JP"
+	textEncodingISO2022JP   = "ISO-2022-JP"
+	textEncodingGB18030     = "GB18030"
+	textEncodingWindows1252 = "windows-1252"
+)
+
+// 改行コード (TextFormat.LineEnding)
+const (
+	lineEndingLF    = "LF"
+	lineEndingCRLF  = "CRLF"
+	lineEndingCR    = "CR"
+	lineEndingMixed = "mixed" // 複数の改行コードが混在（保存時もそのまま書く）
+)
+
+// 表せない文字のエラーに並べる件数の上限
+const maxEncodingIssuesInError = 10
+
+// textEncodings は保存に使える文字コード（「文字コードを指定して保存」の選択肢）
+var textEncodings = []string{
+	textEncodingUTF8,
+	textEncodingUTF16LE,
+	textEncodingUTF16BE,
+	textEncodingShiftJIS,
+	textEncodingEUCJP,
+	textEncodingISO2022JP,
+	textEncodingGB18030,
+	textEncodingWindows1252,
+}
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// TextFormat はテキストファイルの文字コード・BOM・改行コード
+type TextFormat struct {
+	Encoding   string `json:"encoding"`             // 文字コード（"UTF-8", "Shift_JIS" など）
+	BOM        bool   `json:"bom,omitempty"`        // 先頭に BOM を付けるか（UTF-8 / UTF-16 のみ）
+	LineEnding string `json:"lineEnding,omitempty"` // "LF" | "CRLF" | "CR" | "mixed"（空=改行なし）
+}
+
+// EncodingIssue は保存先の文字コードで表せない文字の位置
+type EncodingIssue struct {
+	Line   int    `json:"line"`   // 行番号（1 始まり）
+	Column int    `json:"column"` // 行内の文字位置（1 始まり、rune 単位）
+	Char   string `json:"char"`   // 表せない文字
+}
+
+// unencodableTextError は保存先の文字コードで表せない文字があったことを表す
+type unencodableTextError struct {
+	Encoding string
+	Issues   []EncodingIssue
+}
+
+func (e *unencodableTextError) Error() string {
+	parts := make([]string, 0, min(len(e.Issues), maxEncodingIssuesInError))
+	for _, issue := range e.Issues[:min(len(e.Issues), maxEncodingIssuesInError)] {
+		parts = append(parts, fmt.Sprintf("line %d col %d %q", issue.Line, issue.Column, issue.Char))
+	}
+	message := fmt.Sprintf("cannot save in %s: %d characters are not representable (%s",
+		e.Encoding, len(e.Issues), strings.Join(parts, ", "))
+	if len(e.Issues) > maxEncodingIssuesInError {
+		message += fmt.Sprintf(", and %d more", len(e.Issues)-maxEncodingIssuesInError)
+	}
+	return message + ")"
+}
+
+// textEncodingByName は UTF-8 以外の文字コードの encoding.Encoding を返す (UTF-8 は nil)
+func textEncodingByName(name string, bom bool) (encoding.Encoding, error) {
+	bomPolicy := textunicode.IgnoreBOM
+	if bom {
+		bomPolicy = textunicode.UseBOM
+	}
+	switch name {
+	case textEncodingUTF8, "":
+		return nil, nil
+	case textEncodingUTF16LE:
+		return textunicode.UTF16(textunicode.LittleEndian, bomPolicy), nil
+	case textEncodingUTF16BE:
+		return textunicode.UTF16(textunicode.BigEndian, bomPolicy), nil
+	case textEncodingShiftJIS:
+		return japanese.ShiftJIS, nil
+	case textEncodingEUCJP:
+		return japanese.EUCJP, nil
+	case textEncodingISO2022JP:
+		return japanese.ISO2022JP, nil
+	case textEncodingGB18030:
+		return simplifiedchinese.GB18030, nil
+	case textEncodingWindows1252:
+		return charmap.Windows1252, nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding: %s", name)
+	}
+}
+
+// decodeText はバイト列の形式を検出し、UTF-8 の文字列にして返す (改行コードは変えない)
+func decodeText(data []byte) (string, TextFormat) {
+	content, format := decodeTextContent(data)
+	format.LineEnding = detectLineEnding(content)
+	return content, format
+}
+
+func decodeTextContent(data []byte) (string, TextFormat) {
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		return string(data[len(utf8BOM):]), TextFormat{Encoding: textEncodingUTF8, BOM: true}
+	case bytes.HasPrefix(data, utf16LEBOM), bytes.HasPrefix(data, utf16BEBOM):
+		name := textEncodingUTF16LE
+		if bytes.HasPrefix(data, utf16BEBOM) {
+			name = textEncodingUTF16BE
+		}
+		if content, ok := decodeStrict(data, textunicode.UTF16(textunicode.LittleEndian, textunicode.ExpectBOM)); ok {
+			return content, TextFormat{Encoding: name, BOM: true}
+		}
+	case isISO2022JP(data):
+		if content, ok := decodeStrict(data, japanese.ISO2022JP); ok {
+			return content, TextFormat{Encoding: textEncodingISO2022JP}
+		}
+	}
+
+	// 有効なUTF-8ならそのまま返す
+	if utf8.Valid(data) {
+		return string(data), TextFormat{Encoding: textEncodingUTF8}
+	}
+
+	sjis, sjisOK := decodeStrict(data, japanese.ShiftJIS)
+	eucjp, eucjpOK := decodeStrict(data, japanese.EUCJP)
+	gb, gbOK := decodeStrict(data, simplifiedchinese.GB18030)
+	gbOK = gbOK && mostlyMultiByte(data)
+	switch {
+	case sjisOK && countKana(sjis) > 0 && (!eucjpOK || countKana(sjis) >= countKana(eucjp)):
+		return sjis, TextFormat{Encoding: textEncodingShiftJIS}
+	case eucjpOK && countKana(eucjp) > 0:
+		return eucjp, TextFormat{Encoding: textEncodingEUCJP}
+	case gbOK && (!sjisOK || countHalfwidthKana(sjis) > 0):
+		// 中国語の文書を Shift_JIS として読むと半角カナだらけになる
+		return gb, TextFormat{Encoding: textEncodingGB18030}
+	case sjisOK:
+		return sjis, TextFormat{Encoding: textEncodingShiftJIS}
+	case eucjpOK:
+		return eucjp, TextFormat{Encoding: textEncodingEUCJP}
+	case gbOK:
+		return gb, TextFormat{Encoding: textEncodingGB18030}
+	}
+	latin, _ := decodeStrict(data, charmap.Windows1252)
+	return latin, TextFormat{Encoding: textEncodingWindows1252}
+}
+
+// trimTextSample はファイルの先頭部分の末尾で途中で切れた文字を落とす
+// (切れた文字を不正なバイトと見なして、文字コードを取り違えないようにする)
+func trimTextSample(sample []byte) []byte {
+	if bytes.HasPrefix(sample, utf16LEBOM) || bytes.HasPrefix(sample, utf16BEBOM) {
+		return sample[:len(sample)-len(sample)%2]
+	}
+	for cut := 0; cut < utf8.UTFMax && cut < len(sample); cut++ {
+		if utf8.Valid(sample[:len(sample)-cut]) {
+			return sample[:len(sample)-cut]
+		}
+	}
+	// UTF-8 でなければ最後の改行までにする
+	if last := bytes.LastIndexByte(sample, '\n'); last > 0 {
+		return sample[:last+1]
+	}
+	return sample
+}
+
+// decodeStrict は不正なバイトが無い場合だけ ok=true を返す
+func decodeStrict(data []byte, enc encoding.Encoding) (string, bool) {
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", false
+	}
+	return string(decoded), !bytes.ContainsRune(decoded, utf8.RuneError)
+}
+
+// isISO2022JP は 7 ビットのデータに ISO-2022-JP の切り替えシーケンスがあるかを返す
+func isISO2022JP(data []byte) bool {
+	for _, b := range data {
+		if b >= 0x80 {
+			return false
+		}
+	}
+	for _, escape := range []string{"\x1b$B", "\x1b$@", "\x1b(J", "\x1b(I"} {
+		if bytes.Contains(data, []byte(escape)) {
+			return true
+		}
+	}
+	return false
+}
+
+// mostlyMultiByte は 0x80 以上のバイトの半分以上が連続して現れるかを返す
+// (欧文の Windows-1252 はアクセント付きの文字が 1 バイトずつ離れて現れる)
+func mostlyMultiByte(data []byte) bool {
+	high, paired := 0, 0
+	for i, b := range data {
+		if b < 0x80 {
+			continue
+		}
+		high++
+		if (i > 0 && data[i-1] >= 0x80) || (i+1 < len(data) && data[i+1] >= 0x80) {
+			paired++
+		}
+	}
+	return high > 0 && paired*2 >= high
+}
+
+func countKana(text string) int {
+	count := 0
+	for _, r := range text {
+		if unicode.In(r, unicode.Hiragana, unicode.Katakana) && !isHalfwidthKana(r) {
+			count++
+		}
+	}
+	return count
+}
+
+func countHalfwidthKana(text string) int {
+	count := 0
+	for _, r := range text {
+		if isHalfwidthKana(r) {
+			count++
+		}
+	}
+	return count
+}
+
+func isHalfwidthKana(r rune) bool {
+	return r >= 0xFF61 && r <= 0xFF9F
+}
+
+// detectLineEnding は本文の改行コードを返す (改行が無ければ空)
+func detectLineEnding(content string) string {
+	crlf, lf, cr := 0, 0, 0
+	for i := 0; i < len(content); i++ {
+		switch content[i] {
+		case '\r':
+			if i+1 < len(content) && content[i+1] == '\n' {
+				crlf++
+				i++
+			} else {
+				cr++
+			}
+		case '\n':
+			lf++
+		}
+	}
+	kinds := 0
+	result := ""
+	for _, kind := range []struct {
+		count int
+		name  string
+	}{{crlf, lineEndingCRLF}, {lf, lineEndingLF}, {cr, lineEndingCR}} {
+		if kind.count > 0 {
+			kinds++
+			result = kind.name
+		}
+	}
+	if kinds > 1 {
+		return lineEndingMixed
+	}
+	return result
+}
+
+// applyLineEnding は本文の改行を lineEnding にそろえる ("mixed" や空ならそのまま)
+func applyLineEnding(content string, lineEnding string) string {
+	var newline string
+	switch lineEnding {
+	case lineEndingLF:
+		newline = "\n"
+	case lineEndingCRLF:
+		newline = "\r\n"
+	case lineEndingCR:
+		newline = "\r"
+	default:
+		return content
+	}
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	normalized = strings.ReplaceAll(normalized, "\r", "\n")
+	if newline == "\n" {
+		return normalized
+	}
+	return strings.ReplaceAll(normalized, "\n", newline)
+}
+
+// encodeText は本文を format の改行コード・文字コード・BOM で書き出すバイト列にする。
+// 表せない文字があれば *unencodableTextError を返す。
+func encodeText(content string, format TextFormat) ([]byte, error) {
+	content = applyLineEnding(content, format.LineEnding)
+	enc, err := textEncodingByName(format.Encoding, format.BOM)
+	if err != nil {
+		return nil, err
+	}
+	if enc == nil {
+		if format.BOM {
+			return append(append([]byte(nil), utf8BOM...), content...), nil
+		}
+		return []byte(content), nil
+	}
+	data, err := enc.NewEncoder().Bytes([]byte(content))
+	if err != nil {
+		if issues := findUnencodableChars(content, enc); len(issues) > 0 {
+			return nil, &unencodableTextError{Encoding: format.Encoding, Issues: issues}
+		}
+		return nil, fmt.Errorf("failed to encode as %s: %w", format.Encoding, err)
+	}
+	return data, nil
+}
+
+// findUnencodableChars は enc で表せない文字の位置を返す
+func findUnencodableChars(content string, enc encoding.Encoding) []EncodingIssue {
+	var issues []EncodingIssue
+	encoder := enc.NewEncoder()
+	for lineIndex, line := range strings.Split(content, "\n") {
+		column := 0
+		for _, r := range strings.TrimSuffix(line, "\r") {
+			column++
+			if _, err := encoder.String(string(r)); err != nil {
+				issues = append(issues, EncodingIssue{Line: lineIndex + 1, Column: column, Char: string(r)})
+			}
+		}
+	}
+	return issues
+}
@@ -0,0 +1,138 @@
+package backend
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func mustEncode(t *testing.T, enc encoding.Encoding, text string) []byte {
+	t.Helper()
+	data, err := enc.NewEncoder().Bytes([]byte(text))
+	require.NoError(t, err)
+	return data
+}
+
+func TestDecodeText_DetectsEncodings(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		content  string
+		encoding string
+		bom      bool
+	}{
+		{"UTF-8", []byte("こんにちは"), "こんにちは", textEncodingUTF8, false},
+		{"UTF-8 BOM", append([]byte{0xEF, 0xBB, 0xBF}, "abc"...), "abc", textEncodingUTF8, true},
+		{"UTF-16LE BOM", []byte{0xFF, 0xFE, 'h', 0, 'i', 0}, "hi", textEncodingUTF16LE, true},
+		{"UTF-16BE BOM", []byte{0xFE, 0xFF, 0, 'h', 0, 'i'}, "hi", textEncodingUTF16BE, true},
+		{"Shift_JIS", mustEncode(t, japanese.ShiftJIS, "日本語のテキスト"), "日本語のテキスト", textEncodingShiftJIS, false},
+		{"EUC-JP", mustEncode(t, japanese.EUCJP, "日本語のテキスト"), "日本語のテキスト", textEncodingEUCJP, false},
+		{"ISO-2022-JP", mustEncode(t, japanese.ISO2022JP, "日本語のテキスト"), "日本語のテキスト", textEncodingISO2022JP, false},
+		{"GB18030", mustEncode(t, simplifiedchinese.GB18030, "中文文本"), "中文文本", textEncodingGB18030, false},
+		{"windows-1252", mustEncode(t, charmap.Windows1252, "café déjà vu"), "café déjà vu", textEncodingWindows1252, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, format := decodeText(tt.data)
+			assert.Equal(t, tt.content, content)
+			assert.Equal(t, tt.encoding, format.Encoding)
+			assert.Equal(t, tt.bom, format.BOM)
+		})
+	}
+}
+
+func TestDetectLineEnding(t *testing.T) {
+	assert.Equal(t, "", detectLineEnding("single line"))
+	assert.Equal(t, lineEndingLF, detectLineEnding("a\nb\n"))
+	assert.Equal(t, lineEndingCRLF, detectLineEnding("a\r\nb\r\n"))
+	assert.Equal(t, lineEndingCR, detectLineEnding("a\rb\r"))
+	assert.Equal(t, lineEndingMixed, detectLineEnding("a\r\nb\n"))
+
+	assert.Equal(t, "a\r\nb\r\n", applyLineEnding("a\nb\r\n", lineEndingCRLF))
+	assert.Equal(t, "a\nb\n", applyLineEnding("a\r\nb\r", lineEndingLF))
+	assert.Equal(t, "a\r\nb\n", applyLineEnding("a\r\nb\n", lineEndingMixed))
+}
+
+func TestEncodeText_RoundTrip(t *testing.T) {
+	for _, name := range textEncodings {
+		t.Run(name, func(t *testing.T) {
+			// 漢字だけの文は EUC-JP と GB18030 の区別がつかないので、それぞれらしい文にする
+			text := "日本語の\nテキスト\n"
+			switch name {
+			case textEncodingGB18030:
+				text = "中文\n文本\n"
+			case textEncodingWindows1252:
+				text = "café\ndéjà vu\n"
+			}
+			bom := name == textEncodingUTF8 || name == textEncodingUTF16LE || name == textEncodingUTF16BE
+			format := TextFormat{Encoding: name, BOM: bom, LineEnding: lineEndingCRLF}
+			data, err := encodeText(text, format)
+			require.NoError(t, err)
+
+			content, detected := decodeText(data)
+			assert.Equal(t, format, detected)
+			assert.Equal(t, applyLineEnding(text, lineEndingCRLF), content)
+		})
+	}
+}
+
+func TestEncodeText_ReportsUnencodableChars(t *testing.T) {
+	_, err := encodeText("ok\nカタカナ😀と\n", TextFormat{Encoding: textEncodingShiftJIS})
+	var unencodable *unencodableTextError
+	require.True(t, errors.As(err, &unencodable))
+	assert.Equal(t, []EncodingIssue{{Line: 2, Column: 5, Char: "😀"}}, unencodable.Issues)
+	assert.Contains(t, err.Error(), "line 2 col 5")
+}
+
+func TestSaveFile_PreservesExistingFormat(t *testing.T) {
+	fs := NewFileService(&Context{})
+	path := filepath.Join(t.TempDir(), "memo.txt")
+	require.NoError(t, os.WriteFile(path, mustEncode(t, japanese.ShiftJIS, "古い内容\r\n"), 0644))
+
+	// エディタからは LF で来ても、元の Shift_JIS と CRLF のまま書き戻す
+	_, err := fs.SaveFile(path, "新しい内容\n二行目\n")
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, mustEncode(t, japanese.ShiftJIS, "新しい内容\r\n二行目\r\n"), data)
+
+	// 表せない文字があれば書き込まない
+	_, err = fs.SaveFile(path, "絵文字😀\n")
+	require.Error(t, err)
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, data, after)
+
+	// 文字コードを指定すれば変えられる
+	_, err = fs.SaveFileWithFormat(path, "絵文字😀\n", TextFormat{Encoding: textEncodingUTF8, BOM: true, LineEnding: lineEndingLF})
+	require.NoError(t, err)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, append([]byte{0xEF, 0xBB, 0xBF}, "絵文字😀\n"...), data)
+}
+
+func TestFileNoteService_KeepsFormatWhenListOmitsIt(t *testing.T) {
+	notes := NewFileNoteService(t.TempDir())
+	note := FileNote{ID: "file1", FilePath: "/tmp/a.txt", FileName: "a.txt"}
+	withFormat := note
+	withFormat.setTextFormat(TextFormat{Encoding: textEncodingEUCJP, LineEnding: lineEndingCRLF})
+	_, err := notes.SaveFileNotes([]FileNote{withFormat})
+	require.NoError(t, err)
+
+	// フロントエンドから文字コードの無い一覧が来ても引き継ぐ
+	_, err = notes.SaveFileNotes([]FileNote{note})
+	require.NoError(t, err)
+	found, ok := notes.findFileNoteByPath("/tmp/a.txt")
+	require.True(t, ok)
+	format, ok := found.textFormat()
+	require.True(t, ok)
+	assert.Equal(t, TextFormat{Encoding: textEncodingEUCJP, LineEnding: lineEndingCRLF}, format)
+}
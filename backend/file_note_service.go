@@ -15,6 +15,24 @@ type FileNote struct {
 	OriginalContent string `json:"originalContent"`
 	Language        string `json:"language"`
 	ModifiedTime    string `json:"modifiedTime"`
+	Encoding        string `json:"encoding,omitempty"`   // 開いたときの文字コード（空=不明）
+	BOM             bool   `json:"bom,omitempty"`        // 開いたときに BOM があったか
+	LineEnding      string `json:"lineEnding,omitempty"` // 開いたときの改行コード
+}
+
+// textFormat はファイルノートの文字コード・BOM・改行コードを返す (文字コードが不明なら ok=false)
+func (n FileNote) textFormat() (TextFormat, bool) {
+	if n.Encoding == "" {
+		return TextFormat{}, false
+	}
+	return TextFormat{Encoding: n.Encoding, BOM: n.BOM, LineEnding: n.LineEnding}, true
+}
+
+// setTextFormat はファイルノートに文字コード・BOM・改行コードを記録する
+func (n *FileNote) setTextFormat(format TextFormat) {
+	n.Encoding = format.Encoding
+	n.BOM = format.BOM
+	n.LineEnding = format.LineEnding
 }
 
 // ファイルノートの操作
@@ -58,7 +76,23 @@ func (s *fileNoteService) LoadFileNotes() ([]FileNote, error) {
 }
 
 // ファイルノートを保存する
+// 文字コードを持たないノートは、同じ ID・パスの保存済みノートの文字コード・BOM・改行コードを引き継ぐ
 func (s *fileNoteService) SaveFileNotes(list []FileNote) (string, error) {
+	if previous, err := s.LoadFileNotes(); err == nil {
+		type noteKey struct{ id, path string }
+		formats := make(map[noteKey]TextFormat, len(previous))
+		for _, note := range previous {
+			if format, ok := note.textFormat(); ok {
+				formats[noteKey{note.ID, note.FilePath}] = format
+			}
+		}
+		for i := range list {
+			if format, ok := formats[noteKey{list[i].ID, list[i].FilePath}]; ok && list[i].Encoding == "" {
+				list[i].setTextFormat(format)
+			}
+		}
+	}
+
 	data, err := json.MarshalIndent(list, "", "  ")
 	if err != nil {
 		return "", err
@@ -71,3 +105,38 @@ func (s *fileNoteService) SaveFileNotes(list []FileNote) (string, error) {
 
 	return fileNotesPath, nil
 }
+
+// findFileNoteByPath は指定パスのファイルノートを返す
+func (s *fileNoteService) findFileNoteByPath(filePath string) (FileNote, bool) {
+	list, err := s.LoadFileNotes()
+	if err != nil {
+		return FileNote{}, false
+	}
+	for _, note := range list {
+		if filepath.Clean(note.FilePath) == filepath.Clean(filePath) {
+			return note, true
+		}
+	}
+	return FileNote{}, false
+}
+
+// updateFileNoteFormat は指定パスのファイルノートの文字コード・BOM・改行コードと mtime を更新する
+func (s *fileNoteService) updateFileNoteFormat(filePath string, format TextFormat, modifiedTime string) error {
+	list, err := s.LoadFileNotes()
+	if err != nil {
+		return err
+	}
+	updated := false
+	for i := range list {
+		if filepath.Clean(list[i].FilePath) == filepath.Clean(filePath) {
+			list[i].setTextFormat(format)
+			list[i].ModifiedTime = modifiedTime
+			updated = true
+		}
+	}
+	if !updated {
+		return nil
+	}
+	_, err = s.SaveFileNotes(list)
+	return err
+}
@@ -1,18 +1,12 @@
 package backend
 
 import (
-	"bytes"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"runtime"
 	"strings"
 	"time"
-	"unicode/utf8"
-
-	"golang.org/x/text/encoding/japanese"
-	"golang.org/x/text/transform"
 
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -20,7 +14,14 @@ import (
 // OpenFileResult はファイル読み込み結果を表します
 type OpenFileResult struct {
 	Content        string `json:"content"`
-	SourceEncoding string `json:"sourceEncoding"`
+	SourceEncoding string `json:"sourceEncoding"` // UTF-8 以外なら文字コード名（互換用）
+	Encoding       string `json:"encoding"`
+	BOM            bool   `json:"bom"`
+	LineEnding     string `json:"lineEnding"`
+	LargeFile      bool   `json:"largeFile,omitempty"`    // 大きすぎるので内容を返さない（ReadFileLines で読む、読み取り専用）
+	Binary         bool   `json:"binary,omitempty"`       // バイナリなので内容を返さない（ReadHexDump で読む、読み取り専用）
+	BinaryFormat   string `json:"binaryFormat,omitempty"` // 分かればバイナリの形式（"png", "zip" など）
+	Size           int64  `json:"size"`
 }
 
 // FileService はファイル操作関連の機能を提供するインターフェースです
@@ -33,6 +34,8 @@ type FileService interface {
 	// ナノ秒オーダーでずれて CheckFileModified が誤検知するため、ここで返した値を
 	// そのまま FileNote.modifiedTime に格納させる。
 	SaveFile(filePath string, content string) (string, error)
+	// SaveFileWithFormat は文字コード・BOM・改行コードを指定して保存する。
+	SaveFileWithFormat(filePath string, content string, format TextFormat) (string, error)
 	GetModifiedTime(filePath string) (string, error)
 	CheckFileExists(path string) bool
 }
@@ -40,6 +43,8 @@ type FileService interface {
 // fileService はFileServiceの実装です
 type fileService struct {
 	ctx *Context
+	// beforeOverwrite は書き込む直前に呼ばれる (上書き前のバックアップ用、nil なら何もしない)
+	beforeOverwrite func(filePath string)
 }
 
 // NewFileService は新しいfileServiceインスタンスを作成します
@@ -66,38 +71,82 @@ func (s *fileService) SelectFile() (string, error) {
 	return file, nil
 }
 
+// SelectDirectory はフォルダ選択ダイアログを表示し、選択されたフォルダのパスを返します
+func (s *fileService) SelectDirectory(title string) (string, error) {
+	return wailsRuntime.OpenDirectoryDialog(s.ctx.ctx, wailsRuntime.OpenDialogOptions{
+		Title:                title,
+		CanCreateDirectories: true,
+	})
+}
+
 // OpenFile は指定されたパスのファイルの内容を読み込みます
 // UTF-8以外のエンコーディングを検出した場合、自動的にUTF-8に変換します
+// バイナリは内容を読まずに Binary=true を、
+// largeFileThreshold を超えるファイルは内容を読まずに LargeFile=true を返します
 func (s *fileService) OpenFile(filePath string) (*OpenFileResult, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	binary, binaryFormat, err := sniffBinaryFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if binary {
+		return &OpenFileResult{Binary: true, BinaryFormat: binaryFormat, Size: info.Size()}, nil
+	}
+	if isLargeFileSize(info.Size()) {
+		return openLargeFile(filePath, info.Size())
+	}
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
-	content, encoding := detectAndConvertEncoding(data)
-	return &OpenFileResult{Content: content, SourceEncoding: encoding}, nil
+	content, format := decodeText(data)
+	return &OpenFileResult{
+		Content:        content,
+		SourceEncoding: sourceEncodingName(format),
+		Encoding:       format.Encoding,
+		BOM:            format.BOM,
+		LineEnding:     format.LineEnding,
+		Size:           int64(len(data)),
+	}, nil
 }
 
-// detectAndConvertEncoding はバイト列のエンコーディングを検出し、必要に応じてUTF-8に変換します
-func detectAndConvertEncoding(data []byte) (string, string) {
-	// UTF-8 BOM を検出して除去
-	if bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}) {
-		return string(data[3:]), ""
+// openLargeFile は大きなファイルの形式だけを先頭から判定して返します
+func openLargeFile(filePath string, size int64) (*OpenFileResult, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
 	}
-
-	// 有効なUTF-8ならそのまま返す
-	if utf8.Valid(data) {
-		return string(data), ""
+	defer f.Close()
+	format, err := detectLargeFileFormat(f)
+	if err != nil {
+		return nil, err
 	}
+	return &OpenFileResult{
+		SourceEncoding: sourceEncodingName(format),
+		Encoding:       format.Encoding,
+		BOM:            format.BOM,
+		LineEnding:     format.LineEnding,
+		LargeFile:      true,
+		Size:           size,
+	}, nil
+}
 
-	// ShiftJIS (CP932) として変換を試みる
-	reader := transform.NewReader(bytes.NewReader(data), japanese.ShiftJIS.NewDecoder())
-	decoded, err := io.ReadAll(reader)
-	if err == nil {
-		return string(decoded), "Shift_JIS"
-	}
+// detectAndConvertEncoding はバイト列のエンコーディングを検出し、必要に応じてUTF-8に変換します
+// 返すエンコーディング名は UTF-8 なら空文字です
+func detectAndConvertEncoding(data []byte) (string, string) {
+	content, format := decodeText(data)
+	return content, sourceEncodingName(format)
+}
 
-	// 変換に失敗した場合はそのまま返す（文字化けの可能性あり）
-	return string(data), ""
+// sourceEncodingName は UTF-8 以外の文字コード名を返す (UTF-8 は空)
+func sourceEncodingName(format TextFormat) string {
+	if format.Encoding == textEncodingUTF8 {
+		return ""
+	}
+	return format.Encoding
 }
 
 // SelectSaveFileUri は保存ダイアログを表示し、選択された保存先のパスを返します
@@ -150,8 +199,27 @@ func buildSaveDialogDefaults(fileName string, extension string) (string, string)
 // RFC3339Nano 文字列で返す。返した mtime はフロントエンドが FileNote に保存し、
 // 次のフォーカス時 CheckFileModified に渡す。原子的な mtime 取得が
 // 「保存直後フォーカスで外部編集ダイアログが誤表示される」バグの根本対策。
+// 既存のファイルへ上書きする場合は、そのファイルの文字コード・BOM・改行コードを引き継ぐ。
 func (s *fileService) SaveFile(filePath string, content string) (string, error) {
-	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+	format := TextFormat{Encoding: textEncodingUTF8}
+	if data, err := os.ReadFile(filePath); err == nil {
+		_, format = decodeText(data)
+	}
+	return s.SaveFileWithFormat(filePath, content, format)
+}
+
+// SaveFileWithFormat は文字コード・BOM・改行コードを指定して保存し、保存後のディスク mtime を返す。
+// 指定した文字コードで表せない文字があれば、ファイルには書き込まずに *unencodableTextError を返す。
+// 書き込みは一時ファイルと rename で行い、途中で失敗しても元の内容を壊さない。
+func (s *fileService) SaveFileWithFormat(filePath string, content string, format TextFormat) (string, error) {
+	data, err := encodeText(content, format)
+	if err != nil {
+		return "", err
+	}
+	if s.beforeOverwrite != nil {
+		s.beforeOverwrite(filePath)
+	}
+	if err := writeFileAtomic(filePath, data); err != nil {
 		return "", err
 	}
 	info, err := os.Stat(filePath)
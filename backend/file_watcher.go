@@ -0,0 +1,526 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ------------------------------------------------------------
+// 開いているファイル（ファイルノート）の外部での変更の監視
+// ------------------------------------------------------------
+//
+// fileNotes.json にある全てのパスについて、親ディレクトリを fsnotify で監視する
+// (ファイル自体を監視すると、一時ファイルへ書いてから rename で置き換える保存や名前の変更を追えない)。
+// - 変更: 編集していないバッファ (Content == OriginalContent) は fileNotes.json ごと読み直して
+//   file:changed (reloaded) を、編集中なら書き換えずに file:changed (conflict) を送る。
+// - 名前の変更: 同じディレクトリに現れた同一ファイル (os.SameFile か同じ内容) を新しいパスとして file:renamed を送る。
+// - 削除: file:deleted を送る。監視は続け、作り直されたら変更として扱う。
+// 連続したイベント (git checkout や go fmt) はパスごとにまとめてから処理する。
+//
+// ノートにリンクしたファイル (linked_files.go) のパスも同じ watcher で監視する。
+// 変更をまとめた後に onLinked (ノート ID) を呼ぶだけで、ノートとの突き合わせは App 側で行う。
+// ノートが書き込まれたときも ScheduleLinkedNote で同じ確認を予約する。
+// w.mu は他のロックを取らない末端のロックなので、noteService.mu や linkedFiles.mu を
+// 握ったまま RecordSaved / ScheduleLinkedNote を呼んでよい (onLinked は w.mu を離してから呼ぶ)。
+
+// ファイル監視のイベント名
+const (
+	fileEventChanged = "file:changed"
+	fileEventDeleted = "file:deleted"
+	fileEventRenamed = "file:renamed"
+)
+
+const (
+	// パスごとにイベントをまとめる時間
+	fileWatchDebounce = 200 * time.Millisecond
+	// 名前の変更の相手として、監視していないファイルの作成を覚えておく時間
+	fileWatchRenameWindow = 2 * time.Second
+)
+
+// FileWatchEvent は file:changed / file:deleted / file:renamed で送る内容
+type FileWatchEvent struct {
+	ID             string `json:"id"`                       // ファイルノートのID
+	FilePath       string `json:"filePath"`                 // ファイルのパス（名前の変更後は新しいパス）
+	FileName       string `json:"fileName"`                 // ファイル名
+	OldPath        string `json:"oldPath,omitempty"`        // 名前の変更前のパス（file:renamed のみ）
+	Content        string `json:"content,omitempty"`        // ディスク上の新しい内容（file:changed のみ）
+	SourceEncoding string `json:"sourceEncoding,omitempty"` // 読み込んだ内容の元のエンコーディング
+	Encoding       string `json:"encoding,omitempty"`       // 読み込んだ内容の文字コード（file:changed のみ）
+	BOM            bool   `json:"bom,omitempty"`            // 読み込んだ内容に BOM があったか
+	LineEnding     string `json:"lineEnding,omitempty"`     // 読み込んだ内容の改行コード
+	ModifiedTime   string `json:"modifiedTime,omitempty"`   // ディスク上の mtime（RFC3339Nano）
+	Reloaded       bool   `json:"reloaded,omitempty"`       // 編集していなかったので読み直した
+	Conflict       bool   `json:"conflict,omitempty"`       // 編集中のバッファとディスクの内容が食い違う
+}
+
+// watchedFile は監視中のファイルの最後に確認した状態
+type watchedFile struct {
+	info os.FileInfo // 無ければ nil（削除された）
+	hash string      // ディスク上の内容のハッシュ（無ければ空）
+}
+
+// fileWatcher は fileNotes.json のパスを監視する
+type fileWatcher struct {
+	mu       sync.Mutex
+	watcher  *fsnotify.Watcher
+	notes    *fileNoteService
+	emit     func(event string, data interface{})
+	logger   AppLogger
+	debounce time.Duration
+	files    map[string]*watchedFile // 監視中のパス → 状態
+	linked   map[string]string       // 監視中のリンクしたファイルのパス → ノート ID
+	onLinked func(noteID string)     // リンクしたファイルが変わったときの通知先
+	dirs     map[string]int          // 監視中のディレクトリ → その中の監視中のファイル数
+	timers   map[string]*time.Timer  // 処理待ちのパス
+	created  map[string]time.Time    // 監視中のディレクトリに作られた、監視していないファイル（名前の変更の相手の候補）
+	closed   bool
+}
+
+func newFileWatcher(notes *fileNoteService, logger AppLogger, emit func(event string, data interface{}), onLinked func(noteID string)) (*fileWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	w := &fileWatcher{
+		watcher:  watcher,
+		notes:    notes,
+		emit:     emit,
+		logger:   logger,
+		debounce: fileWatchDebounce,
+		files:    make(map[string]*watchedFile),
+		linked:   make(map[string]string),
+		onLinked: onLinked,
+		dirs:     make(map[string]int),
+		timers:   make(map[string]*time.Timer),
+		created:  make(map[string]time.Time),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Close は監視を止める
+func (w *fileWatcher) Close() error {
+	w.mu.Lock()
+	w.closed = true
+	for path, timer := range w.timers {
+		timer.Stop()
+		delete(w.timers, path)
+	}
+	w.mu.Unlock()
+	return w.watcher.Close()
+}
+
+// Sync は監視するパスを list のファイルノートに合わせる
+func (w *fileWatcher) Sync(list []FileNote) {
+	wanted := make(map[string]bool, len(list))
+	for _, note := range list {
+		if note.FilePath != "" {
+			wanted[filepath.Clean(note.FilePath)] = true
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	for path := range w.files {
+		if !wanted[path] {
+			w.unwatchLocked(path)
+		}
+	}
+	for path := range wanted {
+		if _, exists := w.files[path]; !exists {
+			w.watchLocked(path)
+		}
+	}
+}
+
+// SyncLinked は監視するリンクしたファイルを links に合わせる
+func (w *fileWatcher) SyncLinked(links []LinkedFile) {
+	wanted := make(map[string]string, len(links))
+	for _, link := range links {
+		wanted[filepath.Clean(link.FilePath)] = link.NoteID
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	for path := range w.linked {
+		if _, ok := wanted[path]; !ok {
+			delete(w.linked, path)
+			w.removeDirLocked(filepath.Dir(path))
+		}
+	}
+	for path, noteID := range wanted {
+		if _, exists := w.linked[path]; !exists {
+			if !w.addDirLocked(filepath.Dir(path)) {
+				continue
+			}
+		}
+		w.linked[path] = noteID
+	}
+}
+
+// ScheduleLinkedNote はノートが書き込まれたので、リンクしたファイルとの確認を予約する
+// (noteService.mu を握ったまま呼ばれるので、ここでは予約するだけ)
+func (w *fileWatcher) ScheduleLinkedNote(noteID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	for path, id := range w.linked {
+		if id == noteID {
+			w.scheduleLocked(path)
+		}
+	}
+}
+
+// RecordSaved はアプリ自身が保存した内容を既知の状態にし、変更として通知しないようにする
+func (w *fileWatcher) RecordSaved(path string) {
+	path = filepath.Clean(path)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if file, exists := w.files[path]; exists {
+		file.info, file.hash, _ = readWatchedFile(path)
+	}
+}
+
+func (w *fileWatcher) watchLocked(path string) {
+	if !w.addDirLocked(filepath.Dir(path)) {
+		return
+	}
+	file := &watchedFile{}
+	file.info, file.hash, _ = readWatchedFile(path)
+	w.files[path] = file
+}
+
+func (w *fileWatcher) unwatchLocked(path string) {
+	delete(w.files, path)
+	if _, linked := w.linked[path]; !linked {
+		if timer, exists := w.timers[path]; exists {
+			timer.Stop()
+			delete(w.timers, path)
+		}
+	}
+	w.removeDirLocked(filepath.Dir(path))
+}
+
+// addDirLocked はディレクトリの監視を 1 つ増やす (監視を始められなければ false)
+func (w *fileWatcher) addDirLocked(dir string) bool {
+	if w.dirs[dir] == 0 {
+		if err := w.watcher.Add(dir); err != nil {
+			w.logf("Failed to watch %s: %v", dir, err)
+			return false
+		}
+	}
+	w.dirs[dir]++
+	return true
+}
+
+func (w *fileWatcher) removeDirLocked(dir string) {
+	w.dirs[dir]--
+	if w.dirs[dir] <= 0 {
+		delete(w.dirs, dir)
+		_ = w.watcher.Remove(dir)
+	}
+}
+
+func (w *fileWatcher) run() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logf("File watcher error: %v", err)
+		}
+	}
+}
+
+func (w *fileWatcher) handleEvent(event fsnotify.Event) {
+	path := filepath.Clean(event.Name)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	_, watched := w.files[path]
+	_, linked := w.linked[path]
+	if watched || linked {
+		w.scheduleLocked(path)
+		return
+	}
+	// 監視していないファイルの作成は、監視中のファイルの名前の変更かもしれない
+	if event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) {
+		now := time.Now()
+		for candidate, createdAt := range w.created {
+			if now.Sub(createdAt) > fileWatchRenameWindow {
+				delete(w.created, candidate)
+			}
+		}
+		w.created[path] = now
+	}
+}
+
+func (w *fileWatcher) scheduleLocked(path string) {
+	if timer, exists := w.timers[path]; exists {
+		timer.Reset(w.debounce)
+		return
+	}
+	w.timers[path] = time.AfterFunc(w.debounce, func() { w.check(path) })
+}
+
+// check はまとめたイベントのあとのディスク上の状態を調べて通知する
+func (w *fileWatcher) check(path string) {
+	w.mu.Lock()
+	delete(w.timers, path)
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	linkedNoteID, linked := w.linked[path]
+	events := w.checkFileNotesLocked(path)
+	w.mu.Unlock()
+
+	for _, event := range events {
+		w.emit(event.name, event.data)
+	}
+	if linked && w.onLinked != nil {
+		w.onLinked(linkedNoteID)
+	}
+}
+
+// checkFileNotesLocked は path を開いているファイルノートに変更・名前の変更・削除を反映し、送るイベントを返す
+func (w *fileWatcher) checkFileNotesLocked(path string) []pendingFileEvent {
+	file, watched := w.files[path]
+	if !watched {
+		return nil
+	}
+	info, hash, data := readWatchedFile(path)
+	if info != nil && hash == file.hash {
+		file.info = info
+		return nil
+	}
+
+	var events []pendingFileEvent
+	switch {
+	case info != nil && (isLargeFileSize(info.Size()) || isBinaryData(data)):
+		// 大きなファイル・バイナリのバッファは読み直さない
+		file.info, file.hash = info, hash
+	case info != nil:
+		file.info, file.hash = info, hash
+		events = w.applyChangeLocked(path, info, data)
+	case file.info != nil:
+		if newPath := w.findRenamedLocked(file); newPath != "" {
+			events = w.applyRenameLocked(path, newPath)
+		} else {
+			file.info, file.hash = nil, ""
+			events = w.notesEventsLocked(path, fileEventDeleted, func(note *FileNote) *FileWatchEvent {
+				return &FileWatchEvent{ID: note.ID, FilePath: path, FileName: note.FileName}
+			})
+		}
+	}
+	return events
+}
+
+type pendingFileEvent struct {
+	name string
+	data *FileWatchEvent
+}
+
+// applyChangeLocked は内容が変わったファイルのファイルノートを、編集していなければ読み直す
+func (w *fileWatcher) applyChangeLocked(path string, info os.FileInfo, data []byte) []pendingFileEvent {
+	content, format := decodeText(data)
+	modifiedTime := info.ModTime().Format(time.RFC3339Nano)
+	list, err := w.notes.LoadFileNotes()
+	if err != nil {
+		w.logf("Failed to load file notes: %v", err)
+		return nil
+	}
+	var events []pendingFileEvent
+	reloaded := false
+	for i := range list {
+		note := &list[i]
+		if filepath.Clean(note.FilePath) != path {
+			continue
+		}
+		event := &FileWatchEvent{
+			ID:             note.ID,
+			FilePath:       note.FilePath,
+			FileName:       note.FileName,
+			Content:        content,
+			SourceEncoding: sourceEncodingName(format),
+			Encoding:       format.Encoding,
+			BOM:            format.BOM,
+			LineEnding:     format.LineEnding,
+			ModifiedTime:   modifiedTime,
+		}
+		if note.Content == note.OriginalContent {
+			note.Content = content
+			note.OriginalContent = content
+			note.ModifiedTime = modifiedTime
+			note.setTextFormat(format)
+			event.Reloaded = true
+			reloaded = true
+		} else {
+			event.Conflict = true
+		}
+		events = append(events, pendingFileEvent{name: fileEventChanged, data: event})
+	}
+	if reloaded {
+		if _, err := w.notes.SaveFileNotes(list); err != nil {
+			w.logf("Failed to save reloaded file notes: %v", err)
+		}
+	}
+	return events
+}
+
+// findRenamedLocked は path にあったファイルと同一か同じ内容で、最近作られたファイルのパスを返す。
+// Windows では元のパスが消えると os.SameFile で比べられないため、内容のハッシュでも比べる。
+func (w *fileWatcher) findRenamedLocked(old *watchedFile) string {
+	found := ""
+	for candidate, createdAt := range w.created {
+		if time.Since(createdAt) > fileWatchRenameWindow {
+			delete(w.created, candidate)
+			continue
+		}
+		if _, watched := w.files[candidate]; watched || found != "" {
+			continue
+		}
+		info, hash, _ := readWatchedFile(candidate)
+		if info != nil && (os.SameFile(old.info, info) || hash == old.hash) {
+			found = candidate
+		}
+	}
+	if found != "" {
+		delete(w.created, found)
+	}
+	return found
+}
+
+// applyRenameLocked はファイルノートのパスを新しいパスに変え、監視も移す
+func (w *fileWatcher) applyRenameLocked(oldPath, newPath string) []pendingFileEvent {
+	w.unwatchLocked(oldPath)
+	w.watchLocked(newPath)
+
+	list, err := w.notes.LoadFileNotes()
+	if err != nil {
+		w.logf("Failed to load file notes: %v", err)
+		return nil
+	}
+	var events []pendingFileEvent
+	for i := range list {
+		note := &list[i]
+		if filepath.Clean(note.FilePath) != oldPath {
+			continue
+		}
+		note.FilePath = newPath
+		note.FileName = filepath.Base(newPath)
+		events = append(events, pendingFileEvent{name: fileEventRenamed, data: &FileWatchEvent{
+			ID:       note.ID,
+			FilePath: newPath,
+			FileName: note.FileName,
+			OldPath:  oldPath,
+		}})
+	}
+	if len(events) > 0 {
+		if _, err := w.notes.SaveFileNotes(list); err != nil {
+			w.logf("Failed to save renamed file notes: %v", err)
+		}
+	}
+	return events
+}
+
+// notesEventsLocked は path を開いているファイルノートごとにイベントを作る
+func (w *fileWatcher) notesEventsLocked(path string, name string, build func(note *FileNote) *FileWatchEvent) []pendingFileEvent {
+	list, err := w.notes.LoadFileNotes()
+	if err != nil {
+		w.logf("Failed to load file notes: %v", err)
+		return nil
+	}
+	var events []pendingFileEvent
+	for i := range list {
+		if filepath.Clean(list[i].FilePath) == path {
+			events = append(events, pendingFileEvent{name: name, data: build(&list[i])})
+		}
+	}
+	return events
+}
+
+func (w *fileWatcher) logf(format string, args ...interface{}) {
+	if w.logger != nil {
+		w.logger.Console(format, args...)
+	}
+}
+
+// readWatchedFile はファイルの情報、内容のハッシュ、内容を返す (読めなければ全て空、大きなファイルは内容を読まない)
+func readWatchedFile(path string) (os.FileInfo, string, []byte) {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return nil, "", nil
+	}
+	// 大きなファイルは読まずにサイズと mtime で変更を判定する (追記は FollowLargeFile で追う)
+	if isLargeFileSize(info.Size()) {
+		return info, fmt.Sprintf("large:%d:%d", info.Size(), info.ModTime().UnixNano()), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", nil
+	}
+	return info, fmt.Sprintf("%x", sha256.Sum256(data)), data
+}
+
+// startFileWatcher は fileNotes.json とリンクしたファイルのパスの監視を（作り直して）始める
+func (a *App) startFileWatcher() {
+	a.stopFileWatcher()
+	ctx := a.ctx.ctx
+	fileNoteService := a.currentFileNoteService()
+	watcher, err := newFileWatcher(fileNoteService, a.logger, func(event string, data interface{}) {
+		wailsRuntime.EventsEmit(ctx, event, data)
+	}, a.onLinkedFileChanged)
+	if err != nil {
+		a.logger.Console("Warning: %v", err)
+		return
+	}
+	list, err := fileNoteService.LoadFileNotes()
+	if err != nil {
+		a.logger.Console("Warning: failed to load file notes for watching: %v", err)
+	}
+	watcher.Sync(list)
+	if linkedFiles := a.currentLinkedFiles(); linkedFiles != nil {
+		watcher.SyncLinked(linkedFiles.List())
+	}
+	a.servicesMu.Lock()
+	a.fileWatcher = watcher
+	a.servicesMu.Unlock()
+}
+
+// stopFileWatcher はファイルの監視を止める
+func (a *App) stopFileWatcher() {
+	a.servicesMu.Lock()
+	watcher := a.fileWatcher
+	a.fileWatcher = nil
+	a.servicesMu.Unlock()
+	if watcher == nil {
+		return
+	}
+	if err := watcher.Close(); err != nil {
+		a.logger.Console("Failed to stop file watcher: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedFileEvent struct {
+	name string
+	data *FileWatchEvent
+}
+
+// setupFileWatcherTest は 1 件のファイルノートを監視する watcher を作る
+func setupFileWatcherTest(t *testing.T, content string, edited string) (*fileWatcher, *fileNoteService, string, chan recordedFileEvent) {
+	t.Helper()
+	appDataDir := t.TempDir()
+	workDir := t.TempDir()
+	path := filepath.Join(workDir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	notes := NewFileNoteService(appDataDir)
+	list := []FileNote{{ID: "file1", FilePath: path, FileName: "main.go", Content: edited, OriginalContent: content}}
+	_, err := notes.SaveFileNotes(list)
+	require.NoError(t, err)
+
+	events := make(chan recordedFileEvent, 10)
+	watcher, err := newFileWatcher(notes, nil, func(name string, data interface{}) {
+		events <- recordedFileEvent{name: name, data: data.(*FileWatchEvent)}
+	}, nil)
+	require.NoError(t, err)
+	watcher.debounce = 20 * time.Millisecond
+	t.Cleanup(func() { watcher.Close() })
+	watcher.Sync(list)
+	return watcher, notes, path, events
+}
+
+func waitFileEvent(t *testing.T, events chan recordedFileEvent) recordedFileEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for file event")
+		return recordedFileEvent{}
+	}
+}
+
+func TestFileWatcher_ReloadsUnmodifiedBuffer(t *testing.T) {
+	_, notes, path, events := setupFileWatcherTest(t, "package main\n", "package main\n")
+
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nfunc main() {}\n"), 0644))
+	event := waitFileEvent(t, events)
+	assert.Equal(t, fileEventChanged, event.name)
+	assert.True(t, event.data.Reloaded)
+	assert.False(t, event.data.Conflict)
+	assert.Equal(t, "package main\n\nfunc main() {}\n", event.data.Content)
+
+	list, err := notes.LoadFileNotes()
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\nfunc main() {}\n", list[0].Content)
+	assert.Equal(t, list[0].Content, list[0].OriginalContent)
+}
+
+func TestFileWatcher_ReportsConflictForDirtyBuffer(t *testing.T) {
+	_, notes, path, events := setupFileWatcherTest(t, "original\n", "edited in app\n")
+
+	require.NoError(t, os.WriteFile(path, []byte("edited outside\n"), 0644))
+	event := waitFileEvent(t, events)
+	assert.Equal(t, fileEventChanged, event.name)
+	assert.True(t, event.data.Conflict)
+	assert.Equal(t, "edited outside\n", event.data.Content)
+
+	// 編集中のバッファは書き換えない
+	list, err := notes.LoadFileNotes()
+	require.NoError(t, err)
+	assert.Equal(t, "edited in app\n", list[0].Content)
+	assert.Equal(t, "original\n", list[0].OriginalContent)
+}
+
+func TestFileWatcher_IgnoresOwnSave(t *testing.T) {
+	watcher, _, path, events := setupFileWatcherTest(t, "a\n", "a\n")
+
+	require.NoError(t, os.WriteFile(path, []byte("saved by app\n"), 0644))
+	watcher.RecordSaved(path)
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %s", event.name)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestFileWatcher_RenameAndDelete(t *testing.T) {
+	_, notes, path, events := setupFileWatcherTest(t, "a\n", "a\n")
+
+	renamed := filepath.Join(filepath.Dir(path), "renamed.go")
+	require.NoError(t, os.Rename(path, renamed))
+	event := waitFileEvent(t, events)
+	require.Equal(t, fileEventRenamed, event.name)
+	assert.Equal(t, path, event.data.OldPath)
+	assert.Equal(t, renamed, event.data.FilePath)
+	list, err := notes.LoadFileNotes()
+	require.NoError(t, err)
+	assert.Equal(t, renamed, list[0].FilePath)
+	assert.Equal(t, "renamed.go", list[0].FileName)
+
+	// 新しいパスを監視し続ける
+	require.NoError(t, os.Remove(renamed))
+	event = waitFileEvent(t, events)
+	assert.Equal(t, fileEventDeleted, event.name)
+	assert.Equal(t, "file1", event.data.ID)
+}
+
+// プロファイルの切り替えで監視が止まっても、並行する保存は古い watcher か nil のどちらかを見る
+func TestSaveFile_WhileFileWatcherStops(t *testing.T) {
+	watcher, notes, path, _ := setupFileWatcherTest(t, "a\n", "a\n")
+	app := &App{
+		fileService:     NewFileService(&Context{}),
+		fileNoteService: notes,
+		fileWatcher:     watcher,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			_, err := app.SaveFile(path, "saved\n")
+			assert.NoError(t, err)
+		}
+	}()
+	app.stopFileWatcher()
+	<-done
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "saved\n", string(data))
+}
@@ -0,0 +1,312 @@
+package backend
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+// フォルダの木構造
+// ------------------------------------------------------------
+//
+// Folder.ParentID で親フォルダを指す (空文字=トップレベル)。
+// - TopLevelOrder / ArchivedTopLevelOrder は表示上のルートにあるアイテムだけを持つ。
+//   アクティブ側のルートは ParentID が空のフォルダ、アーカイブ側のルートは
+//   親がアーカイブされていない (単独でアーカイブされた) フォルダ。
+// - 同じ親を持つフォルダ同士の並び順は noteList.Folders 内の順序で表す
+//   (フォルダ内のノートの並び順を noteList.Notes の順序で表すのと同じ)。
+// - アーカイブ・復元・削除は配下のフォルダとノートをまとめて扱う。
+//
+// 同期のマージや他端末での移動が重なると、存在しない親や循環が生じ得るため、
+// normalizeFolderTree で親を外してトップレベルに戻す。
+
+// 木構造の修復理由
+const (
+	folderTreeRepairMissingParent  = "missing_parent"  // 親フォルダが存在しない
+	folderTreeRepairCycle          = "cycle"           // 親をたどると自分に戻る
+	folderTreeRepairArchivedParent = "archived_parent" // アクティブなフォルダの親がアーカイブ済み
+)
+
+// 木構造の修復 1 件分
+type folderTreeRepair struct {
+	folderID string
+	reason   string
+}
+
+// normalizeFolderTree は壊れた親子関係を持つフォルダをトップレベルに戻した新しいスライスを返す。
+// 循環はスライス内で先に現れたフォルダの親を外して断ち切る。
+func normalizeFolderTree(folders []Folder) ([]Folder, []folderTreeRepair) {
+	result := append([]Folder(nil), folders...)
+	indexByID := make(map[string]int, len(result))
+	for i, folder := range result {
+		indexByID[folder.ID] = i
+	}
+
+	var repairs []folderTreeRepair
+	detach := func(i int, reason string) {
+		result[i].ParentID = ""
+		repairs = append(repairs, folderTreeRepair{folderID: result[i].ID, reason: reason})
+	}
+
+	for i := range result {
+		if result[i].ParentID == "" {
+			continue
+		}
+		parentIndex, ok := indexByID[result[i].ParentID]
+		switch {
+		case !ok:
+			detach(i, folderTreeRepairMissingParent)
+		case parentIndex == i:
+			detach(i, folderTreeRepairCycle)
+		case !result[i].Archived && result[parentIndex].Archived:
+			detach(i, folderTreeRepairArchivedParent)
+		}
+	}
+
+	for i := range result {
+		// 親をたどって自分に戻れば循環。フォルダ数より深くはならない
+		current := result[i].ParentID
+		for steps := 0; current != "" && steps <= len(result); steps++ {
+			if current == result[i].ID {
+				detach(i, folderTreeRepairCycle)
+				break
+			}
+			current = result[indexByID[current]].ParentID
+		}
+	}
+
+	return result, repairs
+}
+
+// isFolderOrderRoot はフォルダが (Archived)TopLevelOrder に並ぶべきルートかを返す
+func isFolderOrderRoot(folder Folder, folderMap map[string]Folder, archived bool) bool {
+	if folder.Archived != archived {
+		return false
+	}
+	if folder.ParentID == "" {
+		return true
+	}
+	parent, ok := folderMap[folder.ParentID]
+	if !ok {
+		return true
+	}
+	// アーカイブ側では、親ごとアーカイブされたフォルダは親の中に表示する
+	return archived && !parent.Archived
+}
+
+// folderSubtreeIDs は rootID とその子孫のフォルダIDを返す (rootID が無ければ空)
+func folderSubtreeIDs(folders []Folder, rootID string) map[string]bool {
+	children := make(map[string][]string, len(folders))
+	found := false
+	for _, folder := range folders {
+		if folder.ID == rootID {
+			found = true
+		}
+		if folder.ParentID != "" {
+			children[folder.ParentID] = append(children[folder.ParentID], folder.ID)
+		}
+	}
+	subtree := make(map[string]bool)
+	if !found {
+		return subtree
+	}
+	queue := []string{rootID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if subtree[id] {
+			continue
+		}
+		subtree[id] = true
+		queue = append(queue, children[id]...)
+	}
+	return subtree
+}
+
+func (s *noteService) folderMapLocked() map[string]Folder {
+	folderMap := make(map[string]Folder, len(s.noteList.Folders))
+	for _, folder := range s.noteList.Folders {
+		folderMap[folder.ID] = folder
+	}
+	return folderMap
+}
+
+// 親フォルダを指定してフォルダを作成する（parentIDが空文字の場合はトップレベル） ------------------------------------------------------------
+func (s *noteService) CreateSubfolder(parentID string, name string) (*Folder, error) {
+	if name == "" {
+		return nil, fmt.Errorf("folder name is empty")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createFolderLocked(parentID, name)
+}
+
+func (s *noteService) createFolderLocked(parentID string, name string) (*Folder, error) {
+	if parentID != "" {
+		parent, ok := s.folderMapLocked()[parentID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errFolderNotFound, parentID)
+		}
+		if parent.Archived {
+			return nil, fmt.Errorf("%w: %s", errFolderArchived, parentID)
+		}
+	}
+
+	folder := s.appendFolderLocked(parentID, name)
+	if err := s.saveNoteList(); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// appendFolderLocked は noteList にフォルダを追加する (noteList.json は書かない)。
+// トップレベルのフォルダは表示順の先頭に並ぶ。
+func (s *noteService) appendFolderLocked(parentID string, name string) *Folder {
+	folder := &Folder{
+		ID:       uuid.New().String(),
+		Name:     name,
+		ParentID: parentID,
+	}
+
+	s.ensureTopLevelOrder()
+	s.noteList.Folders = append(s.noteList.Folders, *folder)
+	if parentID == "" {
+		s.noteList.TopLevelOrder = append(
+			[]TopLevelItem{{Type: "folder", ID: folder.ID}},
+			s.noteList.TopLevelOrder...,
+		)
+	}
+	return folder
+}
+
+// フォルダを別のフォルダの下へ移動する（parentIDが空文字の場合はトップレベルへ） ------------------------------------------------------------
+// 移動先の兄弟フォルダの末尾に並ぶ。自分自身や子孫の下には移動できない。
+func (s *noteService) MoveFolder(folderID string, parentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folderMap := s.folderMapLocked()
+	folder, ok := folderMap[folderID]
+	if !ok {
+		return fmt.Errorf("%w: %s", errFolderNotFound, folderID)
+	}
+	if folder.Archived {
+		return fmt.Errorf("%w: %s", errFolderArchived, folderID)
+	}
+	if parentID != "" {
+		parent, ok := folderMap[parentID]
+		if !ok {
+			return fmt.Errorf("%w: %s", errFolderNotFound, parentID)
+		}
+		if parent.Archived {
+			return fmt.Errorf("%w: %s", errFolderArchived, parentID)
+		}
+		if folderSubtreeIDs(s.noteList.Folders, folderID)[parentID] {
+			return fmt.Errorf("%w: %s", errFolderIntoDescendant, folderID)
+		}
+	}
+	if folder.ParentID == parentID {
+		return nil
+	}
+
+	// 移動したフォルダは新しい兄弟の末尾に置く
+	remaining := make([]Folder, 0, len(s.noteList.Folders))
+	for _, f := range s.noteList.Folders {
+		if f.ID != folderID {
+			remaining = append(remaining, f)
+		}
+	}
+	folder.ParentID = parentID
+	s.noteList.Folders = append(remaining, folder)
+
+	s.ensureTopLevelOrder()
+	s.removeFromTopLevelOrder(folderID)
+	if parentID == "" {
+		s.noteList.TopLevelOrder = append(s.noteList.TopLevelOrder, TopLevelItem{Type: "folder", ID: folderID})
+	}
+	return s.saveNoteList()
+}
+
+// 同じ親を持つフォルダの並び順を更新する ------------------------------------------------------------
+// folderIDs は parentID 直下のフォルダを新しい順序で並べたもの。含まれないフォルダは後ろに残る。
+// トップレベルの並び順は UpdateTopLevelOrder で更新する。
+func (s *noteService) UpdateFolderOrder(parentID string, folderIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folderMap := s.folderMapLocked()
+	ordered := make([]Folder, 0, len(folderIDs))
+	used := make(map[string]bool, len(folderIDs))
+	for _, id := range folderIDs {
+		folder, ok := folderMap[id]
+		if !ok || folder.ParentID != parentID || used[id] {
+			return fmt.Errorf("folder is not a child of %q: %s", parentID, id)
+		}
+		ordered = append(ordered, folder)
+		used[id] = true
+	}
+	for _, folder := range s.noteList.Folders {
+		if folder.ParentID == parentID && !used[folder.ID] {
+			ordered = append(ordered, folder)
+		}
+	}
+
+	// 兄弟フォルダが元々あった位置に、新しい順序で詰め直す
+	next := 0
+	for i, folder := range s.noteList.Folders {
+		if folder.ParentID == parentID {
+			s.noteList.Folders[i] = ordered[next]
+			next++
+		}
+	}
+	return s.saveNoteList()
+}
+
+// フォルダと配下のフォルダ・ノートのIDを返す ------------------------------------------------------------
+func (s *noteService) CollectFolderSubtree(id string) (folderIDs []string, noteIDs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subtree := folderSubtreeIDs(s.noteList.Folders, id)
+	for _, folder := range s.noteList.Folders {
+		if subtree[folder.ID] {
+			folderIDs = append(folderIDs, folder.ID)
+		}
+	}
+	for _, note := range s.noteList.Notes {
+		if note.FolderID != "" && subtree[note.FolderID] {
+			noteIDs = append(noteIDs, note.ID)
+		}
+	}
+	return folderIDs, noteIDs
+}
+
+// 配下のノートをまとめてアーカイブ状態にする (caller が s.mu を握っている前提)
+func (s *noteService) setSubtreeNotesArchivedLocked(subtree map[string]bool, archived bool) error {
+	now := time.Now().Format(time.RFC3339)
+	for i, metadata := range s.noteList.Notes {
+		if metadata.FolderID == "" || !subtree[metadata.FolderID] || metadata.Archived == archived {
+			continue
+		}
+		note, err := s.loadNoteLocked(metadata.ID)
+		if err != nil {
+			s.logConsole("Skipped changing archive state of note %s due to load failure: %v", metadata.ID, err)
+			continue
+		}
+		note.Archived = archived
+		note.ModifiedTime = now
+		if archived {
+			note.ContentHeader = noteContentHeader(note)
+			s.noteList.Notes[i].ContentHeader = note.ContentHeader
+		}
+		s.noteList.Notes[i].Archived = archived
+		s.noteList.Notes[i].ModifiedTime = now
+		s.noteList.Notes[i].ContentHash = computeContentHash(note)
+		if err := s.saveNoteFromSyncLocked(note); err != nil {
+			return fmt.Errorf("failed to save note %s: %v", note.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func folderIDsInOrder(order []TopLevelItem) []string {
+	var ids []string
+	for _, item := range order {
+		if item.Type == "folder" {
+			ids = append(ids, item.ID)
+		}
+	}
+	return ids
+}
+
+func TestNormalizeFolderTree(t *testing.T) {
+	folders := []Folder{
+		{ID: "root", Name: "root"},
+		{ID: "child", Name: "child", ParentID: "root"},
+		{ID: "lost", Name: "lost", ParentID: "deleted"},
+		{ID: "self", Name: "self", ParentID: "self"},
+		{ID: "a", Name: "a", ParentID: "b"},
+		{ID: "b", Name: "b", ParentID: "a"},
+		{ID: "archived", Name: "archived", Archived: true},
+		{ID: "under-archived", Name: "under-archived", ParentID: "archived"},
+		{ID: "archived-child", Name: "archived-child", ParentID: "archived", Archived: true},
+	}
+
+	normalized, repairs := normalizeFolderTree(folders)
+
+	parents := make(map[string]string)
+	for _, folder := range normalized {
+		parents[folder.ID] = folder.ParentID
+	}
+	assert.Equal(t, map[string]string{
+		"root":           "",
+		"child":          "root",
+		"lost":           "",
+		"self":           "",
+		"a":              "",
+		"b":              "a",
+		"archived":       "",
+		"under-archived": "",
+		"archived-child": "archived",
+	}, parents)
+	assert.ElementsMatch(t, []folderTreeRepair{
+		{folderID: "lost", reason: folderTreeRepairMissingParent},
+		{folderID: "self", reason: folderTreeRepairCycle},
+		{folderID: "a", reason: folderTreeRepairCycle},
+		{folderID: "under-archived", reason: folderTreeRepairArchivedParent},
+	}, repairs)
+
+	// 元のスライスは変更しない
+	assert.Equal(t, "b", folders[4].ParentID)
+}
+
+func TestCreateSubfolderAndMoveFolder(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	parent, err := ns.CreateFolder("parent")
+	require.NoError(t, err)
+	child, err := ns.CreateSubfolder(parent.ID, "child")
+	require.NoError(t, err)
+	grandchild, err := ns.CreateSubfolder(child.ID, "grandchild")
+	require.NoError(t, err)
+	assert.Equal(t, child.ID, grandchild.ParentID)
+
+	// TopLevelOrder にはルートのフォルダだけが並ぶ
+	assert.Equal(t, []string{parent.ID}, folderIDsInOrder(ns.GetTopLevelOrder()))
+
+	_, err = ns.CreateSubfolder("missing", "x")
+	assert.Error(t, err)
+
+	// 自分自身や子孫の下には移動できない
+	assert.Error(t, ns.MoveFolder(parent.ID, grandchild.ID))
+	assert.Error(t, ns.MoveFolder(child.ID, child.ID))
+
+	require.NoError(t, ns.MoveFolder(grandchild.ID, ""))
+	assert.Equal(t, []string{parent.ID, grandchild.ID}, folderIDsInOrder(ns.GetTopLevelOrder()))
+	require.NoError(t, ns.MoveFolder(grandchild.ID, parent.ID))
+	assert.Equal(t, []string{parent.ID}, folderIDsInOrder(ns.GetTopLevelOrder()))
+
+	// 兄弟フォルダの並び替え
+	require.NoError(t, ns.UpdateFolderOrder(parent.ID, []string{grandchild.ID, child.ID}))
+	var siblings []string
+	for _, folder := range ns.ListFolders() {
+		if folder.ParentID == parent.ID {
+			siblings = append(siblings, folder.ID)
+		}
+	}
+	assert.Equal(t, []string{grandchild.ID, child.ID}, siblings)
+	assert.Error(t, ns.UpdateFolderOrder(parent.ID, []string{parent.ID}))
+
+	// 子フォルダがあるフォルダは削除できない
+	assert.Error(t, ns.DeleteFolder(parent.ID))
+	require.NoError(t, ns.DeleteFolder(child.ID))
+}
+
+func TestArchiveFolder_ArchivesSubtree(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	parent, err := ns.CreateFolder("parent")
+	require.NoError(t, err)
+	child, err := ns.CreateSubfolder(parent.ID, "child")
+	require.NoError(t, err)
+	require.NoError(t, ns.SaveNote(&Note{ID: "in-parent", Title: "p", Content: "p"}))
+	require.NoError(t, ns.SaveNote(&Note{ID: "in-child", Title: "c", Content: "c"}))
+	require.NoError(t, ns.MoveNoteToFolder("in-parent", parent.ID))
+	require.NoError(t, ns.MoveNoteToFolder("in-child", child.ID))
+
+	require.NoError(t, ns.ArchiveFolder(parent.ID))
+	for _, folder := range ns.ListFolders() {
+		assert.True(t, folder.Archived, folder.Name)
+	}
+	for _, metadata := range ns.noteList.Notes {
+		assert.True(t, metadata.Archived, metadata.ID)
+	}
+	assert.Empty(t, folderIDsInOrder(ns.GetTopLevelOrder()))
+	assert.Equal(t, []string{parent.ID}, folderIDsInOrder(ns.GetArchivedTopLevelOrder()))
+
+	changed, err := ns.ValidateIntegrity()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	require.NoError(t, ns.UnarchiveFolder(parent.ID))
+	for _, folder := range ns.ListFolders() {
+		assert.False(t, folder.Archived, folder.Name)
+	}
+	for _, metadata := range ns.noteList.Notes {
+		assert.False(t, metadata.Archived, metadata.ID)
+	}
+	assert.Equal(t, []string{parent.ID}, folderIDsInOrder(ns.GetTopLevelOrder()))
+	assert.Empty(t, folderIDsInOrder(ns.GetArchivedTopLevelOrder()))
+
+	// 子フォルダ単独のアーカイブはアーカイブ側のルートになり、復元すると親の中に戻る
+	require.NoError(t, ns.ArchiveFolder(child.ID))
+	assert.Equal(t, []string{child.ID}, folderIDsInOrder(ns.GetArchivedTopLevelOrder()))
+	require.NoError(t, ns.UnarchiveFolder(child.ID))
+	assert.Equal(t, []string{parent.ID}, folderIDsInOrder(ns.GetTopLevelOrder()))
+	assert.Equal(t, parent.ID, ns.folderMapLocked()[child.ID].ParentID)
+
+	// アーカイブ済みフォルダの削除は配下ごと消える
+	require.NoError(t, ns.ArchiveFolder(parent.ID))
+	folderIDs, noteIDs := ns.CollectFolderSubtree(parent.ID)
+	assert.ElementsMatch(t, []string{parent.ID, child.ID}, folderIDs)
+	assert.ElementsMatch(t, []string{"in-parent", "in-child"}, noteIDs)
+	require.NoError(t, ns.DeleteArchivedFolder(parent.ID))
+	assert.Empty(t, ns.ListFolders())
+	assert.Empty(t, ns.noteList.Notes)
+	_, err = ns.LoadNote("in-child")
+	assert.Error(t, err)
+}
+
+func TestValidateIntegrity_RepairsFolderTree(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	ns.noteList.Folders = []Folder{
+		{ID: "a", Name: "a", ParentID: "b"},
+		{ID: "b", Name: "b", ParentID: "a"},
+		{ID: "lost", Name: "lost", ParentID: "deleted"},
+	}
+	ns.noteList.TopLevelOrder = []TopLevelItem{{Type: "folder", ID: "b"}}
+
+	changed, err := ns.ValidateIntegrity()
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	assert.Equal(t, "", ns.folderMapLocked()["a"].ParentID)
+	assert.Equal(t, "a", ns.folderMapLocked()["b"].ParentID)
+	assert.Equal(t, "", ns.folderMapLocked()["lost"].ParentID)
+	// 子フォルダになった b は TopLevelOrder から外れ、ルートになったフォルダが追加される
+	assert.ElementsMatch(t, []string{"a", "lost"}, folderIDsInOrder(ns.noteList.TopLevelOrder))
+	assert.NotEmpty(t, ns.DrainPendingIntegrityRepairs())
+}
+
+// マージ結果に循環や存在しない親が生じても、木構造に戻ること
+func TestMergeFoldersPreferLocal_RepairsTree(t *testing.T) {
+	// ローカルの a は b の下、クラウドの b は a の下にあり、合わせると循環する
+	local := []Folder{
+		{ID: "a", Name: "a", ParentID: "b"},
+	}
+	cloud := []Folder{
+		{ID: "a", Name: "a"},
+		{ID: "b", Name: "b", ParentID: "a"},
+		{ID: "c", Name: "c", ParentID: "gone"},
+	}
+
+	merged := mergeFoldersPreferLocal(local, cloud, map[string]bool{"gone": true})
+	assert.Equal(t, []Folder{
+		{ID: "a", Name: "a"},
+		{ID: "b", Name: "b", ParentID: "a"},
+		{ID: "c", Name: "c"},
+	}, merged)
+
+	order := mergeTopLevelOrderPreferLocal(
+		[]TopLevelItem{{Type: "folder", ID: "c"}},
+		[]TopLevelItem{{Type: "folder", ID: "b"}},
+		nil,
+		merged,
+		false,
+	)
+	assert.Equal(t, []string{"c", "a"}, folderIDsInOrder(order))
+}
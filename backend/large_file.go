@@ -0,0 +1,612 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ------------------------------------------------------------
+// 大きなファイル（数百 MB のログなど）の読み取り専用モード
+// ------------------------------------------------------------
+//
+// largeFileThreshold を超えるファイルは OpenFile で内容を返さず (LargeFile=true)、
+// フロントエンドは ReadFileLines で必要な行だけを読む。
+// - 行の開始位置は largeFileIndexStride 行ごとにだけ覚え、間の行は読み飛ばして探す (500 MB でも索引は数 MB)。
+// - 文字コードは先頭の largeFileSampleSize バイトから判定し、行ごとにデコードする。
+// - 検索 (SearchLargeFile) はファイル全体を先頭から 1 行ずつ読む。
+// - 追従 (FollowLargeFile) は largeFileFollowInterval ごとにサイズを調べ、追記された行を file:appended で送る。
+//   小さくなった・置き換えられたら (ログのローテーション) 索引を作り直し、Reset=true で知らせる。
+// 行番号は Monaco と同じ 1 始まり。末尾が改行で終わるファイルは、最後に空の行がある。
+
+const (
+	// これより大きいファイルは大きなファイルのモードで開く
+	largeFileThreshold = 32 << 20
+	// 開始位置を覚える行の間隔
+	largeFileIndexStride = 256
+	// 文字コードの判定に使う先頭部分の大きさ
+	largeFileSampleSize = 64 << 10
+	// 索引を作るときに一度に読む大きさ（UTF-16 の 2 バイト単位がまたがらないよう偶数）
+	largeFileScanChunk = 1 << 20
+	// ReadFileLines・file:appended で一度に返す最大行数
+	largeFileMaxLines = 10000
+	// 1 行として返す最大バイト数（これより長い行は途中で切る）
+	largeFileMaxLineBytes = 1 << 20
+	// SearchLargeFile の既定の最大一致件数
+	largeFileDefaultSearchLimit = 1000
+	// 追従中のファイルのサイズを調べる間隔
+	largeFileFollowInterval = 500 * time.Millisecond
+)
+
+// 追記された行の通知
+const fileEventAppended = "file:appended"
+
+// LargeFileLines は ReadFileLines の結果
+type LargeFileLines struct {
+	FilePath   string   `json:"filePath"`
+	Start      int      `json:"start"`      // Lines[0] の行番号（1 始まり）
+	Lines      []string `json:"lines"`      // 改行コードを除いた各行
+	TotalLines int      `json:"totalLines"` // ファイル全体の行数
+	Size       int64    `json:"size"`       // ファイルのバイト数
+	Truncated  bool     `json:"truncated"`  // 長すぎる行を途中で切った
+}
+
+// LargeFileSearchOptions は SearchLargeFile の条件
+type LargeFileSearchOptions struct {
+	CaseSensitive bool `json:"caseSensitive,omitempty"` // 大文字と小文字を区別する
+	Regex         bool `json:"regex,omitempty"`         // query を正規表現として扱う
+	Limit         int  `json:"limit,omitempty"`         // 最大一致件数（0=既定値）
+}
+
+// LargeFileSearchResult は SearchLargeFile の結果（Matches の Field は "content"）
+type LargeFileSearchResult struct {
+	Matches   []SearchMatch `json:"matches"`
+	Truncated bool          `json:"truncated"` // 最大一致件数に達して打ち切った
+}
+
+// LargeFileAppendEvent は file:appended で送る内容
+type LargeFileAppendEvent struct {
+	FilePath   string   `json:"filePath"`
+	Start      int      `json:"start"`      // Lines[0] の行番号（この行以降を置き換える。追記前の最後の行も送り直す）
+	Lines      []string `json:"lines"`      // 追記された行（最大 largeFileMaxLines 行、残りは ReadFileLines で読む）
+	TotalLines int      `json:"totalLines"` // 追記後の行数
+	Size       int64    `json:"size"`       // 追記後のバイト数
+	Reset      bool     `json:"reset"`      // ファイルが小さくなった・置き換えられたので先頭から読み直す
+}
+
+// largeFileIndex は 1 ファイル分の行の索引
+type largeFileIndex struct {
+	format        TextFormat
+	dataStart     int64 // BOM を除いた本文の開始位置
+	size          int64 // 索引を作った時点のサイズ
+	modTime       time.Time
+	file          os.FileInfo
+	lineCount     int     // 改行の数 + 1
+	lastLineStart int64   // 最後の行の開始位置
+	checkpoints   []int64 // checkpoints[k] は (k*largeFileIndexStride + 1) 行目の開始位置
+}
+
+// largeFileService は大きなファイルの索引と追従を管理する
+type largeFileService struct {
+	mu       sync.Mutex
+	indexes  map[string]*largeFileIndex
+	follows  map[string]context.CancelFunc
+	emit     func(event string, data interface{})
+	interval time.Duration
+}
+
+func newLargeFileService(emit func(event string, data interface{})) *largeFileService {
+	return &largeFileService{
+		indexes:  make(map[string]*largeFileIndex),
+		follows:  make(map[string]context.CancelFunc),
+		emit:     emit,
+		interval: largeFileFollowInterval,
+	}
+}
+
+// isLargeFileSize は大きなファイルのモードで開くサイズかを返す
+func isLargeFileSize(size int64) bool {
+	return size > largeFileThreshold
+}
+
+// IsOpen は索引を作ったファイル（大きなファイルのモードで開いているファイル）かを返す
+func (s *largeFileService) IsOpen(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, exists := s.indexes[filepath.Clean(path)]
+	return exists
+}
+
+// ReadLines は start 行目（1 始まり）から count 行を返す
+func (s *largeFileService) ReadLines(path string, start int, count int) (*LargeFileLines, error) {
+	if start < 1 {
+		return nil, fmt.Errorf("invalid start line: %d", start)
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("invalid line count: %d", count)
+	}
+	index, err := s.snapshot(path)
+	if err != nil {
+		return nil, err
+	}
+	count = min(count, largeFileMaxLines)
+	lines, truncated, err := index.readLines(path, start, count)
+	if err != nil {
+		return nil, err
+	}
+	return &LargeFileLines{
+		FilePath:   path,
+		Start:      start,
+		Lines:      lines,
+		TotalLines: index.lineCount,
+		Size:       index.size,
+		Truncated:  truncated,
+	}, nil
+}
+
+// Search はファイル全体から query に一致する箇所を探す
+func (s *largeFileService) Search(ctx context.Context, path string, query string, options LargeFileSearchOptions) (*LargeFileSearchResult, error) {
+	if query == "" {
+		return &LargeFileSearchResult{Matches: []SearchMatch{}}, nil
+	}
+	pattern := query
+	if !options.Regex {
+		pattern = regexp.QuoteMeta(query)
+	}
+	if !options.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search pattern: %w", err)
+	}
+	limit := options.Limit
+	if limit <= 0 {
+		limit = largeFileDefaultSearchLimit
+	}
+
+	index, err := s.snapshot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LargeFileSearchResult{Matches: []SearchMatch{}}
+	err = index.eachLine(path, 1, func(lineNumber int, line string) bool {
+		if lineNumber%largeFileIndexStride == 0 && ctx.Err() != nil {
+			return false
+		}
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			if len(result.Matches) >= limit {
+				result.Truncated = true
+				return false
+			}
+			result.Matches = append(result.Matches, buildLargeFileMatch(line, lineNumber, loc[0], loc[1]))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return result, nil
+}
+
+// buildLargeFileMatch は行内のバイト範囲を SearchMatch (列は UTF-16 単位) にする
+func buildLargeFileMatch(line string, lineNumber int, start int, end int) SearchMatch {
+	lineRunes := []rune(line)
+	startRune := utf8.RuneCountInString(line[:start])
+	matchRunes := lineRunes[startRune : startRune+utf8.RuneCountInString(line[start:end])]
+	length := utf16Len(matchRunes)
+	snippet, snippetStart := cropSnippet(lineRunes, startRune)
+	return SearchMatch{
+		Field:              "content",
+		Line:               lineNumber,
+		Column:             utf16Len(lineRunes[:startRune]) + 1,
+		Length:             length,
+		Snippet:            snippet,
+		SnippetMatchStart:  utf16Len(lineRunes[snippetStart:startRune]),
+		SnippetMatchLength: length,
+	}
+}
+
+// Follow はファイルへの追記を追従し、file:appended で送る
+func (s *largeFileService) Follow(path string) error {
+	path = filepath.Clean(path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, following := s.follows[path]; following {
+		return nil
+	}
+	if _, _, err := s.refreshLocked(path); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.follows[path] = cancel
+	go s.followLoop(ctx, path)
+	return nil
+}
+
+// Unfollow は追従をやめる
+func (s *largeFileService) Unfollow(path string) {
+	path = filepath.Clean(path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, following := s.follows[path]; following {
+		cancel()
+		delete(s.follows, path)
+	}
+}
+
+// Close は追従をやめて索引を捨てる（タブを閉じたとき）
+func (s *largeFileService) Close(path string) {
+	s.Unfollow(path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.indexes, filepath.Clean(path))
+}
+
+// CloseAll は全ての追従をやめて索引を捨てる
+func (s *largeFileService) CloseAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for path, cancel := range s.follows {
+		cancel()
+		delete(s.follows, path)
+	}
+	s.indexes = make(map[string]*largeFileIndex)
+}
+
+func (s *largeFileService) followLoop(ctx context.Context, path string) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if event := s.pollAppend(path); event != nil && ctx.Err() == nil {
+				s.emit(fileEventAppended, event)
+			}
+		}
+	}
+}
+
+// pollAppend は前回から追記された行を返す (変わっていなければ nil)
+func (s *largeFileService) pollAppend(path string) *LargeFileAppendEvent {
+	s.mu.Lock()
+	previous := s.indexes[path]
+	var previousLines int
+	var previousSize int64
+	if previous != nil {
+		previousLines, previousSize = previous.lineCount, previous.size
+	}
+	current, reset, err := s.refreshLocked(path)
+	if err != nil {
+		s.mu.Unlock()
+		// 消えた・読めないファイルは、戻ってくるまで待つ
+		return nil
+	}
+	index := *current
+	s.mu.Unlock()
+	event := &LargeFileAppendEvent{FilePath: path, TotalLines: index.lineCount, Size: index.size}
+	if reset || previous == nil {
+		event.Reset = true
+		event.Start = 1
+		event.Lines = []string{}
+		return event
+	}
+	if index.size == previousSize {
+		return nil
+	}
+	// 追記前の最後の行は続きが書かれているかもしれないので送り直す
+	event.Start = previousLines
+	lines, _, err := index.readLines(path, previousLines, largeFileMaxLines)
+	if err != nil {
+		return nil
+	}
+	event.Lines = lines
+	return event
+}
+
+// snapshot は最新の索引の写しを返す (追従中の追記で書き換わらないよう、ロックの外ではこれを読む)
+func (s *largeFileService) snapshot(path string) (*largeFileIndex, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index, _, err := s.refreshLocked(path)
+	if err != nil {
+		return nil, err
+	}
+	view := *index
+	return &view, nil
+}
+
+// refreshLocked はファイルの索引を返す。追記されていれば続きを索引に加え、
+// 小さくなった・置き換えられたときは作り直して reset=true を返す。
+func (s *largeFileService) refreshLocked(path string) (*largeFileIndex, bool, error) {
+	path = filepath.Clean(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if info.IsDir() {
+		return nil, false, fmt.Errorf("%s is a directory", path)
+	}
+	index, exists := s.indexes[path]
+	if exists && info.Size() == index.size && info.ModTime().Equal(index.modTime) {
+		return index, false, nil
+	}
+	if exists && os.SameFile(info, index.file) && info.Size() > index.size {
+		if err := index.extend(path, info); err != nil {
+			return nil, false, err
+		}
+		return index, false, nil
+	}
+	index, err = buildLargeFileIndex(path)
+	if err != nil {
+		return nil, false, err
+	}
+	s.indexes[path] = index
+	return index, exists, nil
+}
+
+// detectLargeFileFormat は先頭部分から文字コード・BOM・改行コードを判定する
+func detectLargeFileFormat(f *os.File) (TextFormat, error) {
+	sample := make([]byte, largeFileSampleSize)
+	n, err := f.ReadAt(sample, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return TextFormat{}, err
+	}
+	sample = sample[:n]
+	if n == largeFileSampleSize {
+		sample = trimTextSample(sample)
+	}
+	_, format := decodeText(sample)
+	return format, nil
+}
+
+func buildLargeFileIndex(path string) (*largeFileIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	format, err := detectLargeFileFormat(f)
+	if err != nil {
+		return nil, err
+	}
+	index := &largeFileIndex{format: format, size: info.Size(), modTime: info.ModTime(), file: info, lineCount: 1}
+	if format.BOM {
+		switch format.Encoding {
+		case textEncodingUTF8:
+			index.dataStart = int64(len(utf8BOM))
+		case textEncodingUTF16LE, textEncodingUTF16BE:
+			index.dataStart = int64(len(utf16LEBOM))
+		}
+	}
+	index.dataStart = min(index.dataStart, info.Size())
+	index.lastLineStart = index.dataStart
+	index.checkpoints = []int64{index.dataStart}
+	if err := index.scan(f, index.dataStart, info.Size()); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// extend は追記された部分を索引に加える
+func (index *largeFileIndex) extend(path string, info os.FileInfo) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := index.scan(f, index.lastLineStart, info.Size()); err != nil {
+		return err
+	}
+	index.size = info.Size()
+	index.modTime = info.ModTime()
+	index.file = info
+	return nil
+}
+
+// scan は from から to までの改行を数え、行の開始位置を記録する (from は行の先頭であること)
+func (index *largeFileIndex) scan(f *os.File, from int64, to int64) error {
+	unit := index.unitSize()
+	bigEndian := index.format.Encoding == textEncodingUTF16BE
+	buf := make([]byte, largeFileScanChunk)
+	for offset := from; offset < to; {
+		n, err := f.ReadAt(buf[:min(int64(len(buf)), to-offset)], offset)
+		if n == 0 && err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		chunk := buf[:n-n%unit]
+		for i := 0; i < len(chunk); {
+			var next int
+			if unit == 1 {
+				pos := bytes.IndexByte(chunk[i:], '\n')
+				if pos < 0 {
+					break
+				}
+				next = i + pos + 1
+			} else {
+				if !isUTF16Newline(chunk[i], chunk[i+1], bigEndian) {
+					i += 2
+					continue
+				}
+				next = i + 2
+			}
+			index.addLine(offset + int64(next))
+			i = next
+		}
+		if len(chunk) == 0 {
+			break
+		}
+		offset += int64(len(chunk))
+	}
+	return nil
+}
+
+func (index *largeFileIndex) addLine(start int64) {
+	index.lineCount++
+	index.lastLineStart = start
+	if (index.lineCount-1)%largeFileIndexStride == 0 {
+		index.checkpoints = append(index.checkpoints, start)
+	}
+}
+
+func (index *largeFileIndex) unitSize() int {
+	if index.format.Encoding == textEncodingUTF16LE || index.format.Encoding == textEncodingUTF16BE {
+		return 2
+	}
+	return 1
+}
+
+func isUTF16Newline(b0 byte, b1 byte, bigEndian bool) bool {
+	if bigEndian {
+		return b0 == 0 && b1 == '\n'
+	}
+	return b0 == '\n' && b1 == 0
+}
+
+// readLines は start 行目から count 行を読む
+func (index *largeFileIndex) readLines(path string, start int, count int) ([]string, bool, error) {
+	lines := []string{}
+	truncated := false
+	if start > index.lineCount || count == 0 {
+		return lines, false, nil
+	}
+	err := index.eachLineRaw(path, start, func(_ int, line string, cut bool) bool {
+		lines = append(lines, line)
+		truncated = truncated || cut
+		return len(lines) < count
+	})
+	return lines, truncated, err
+}
+
+// eachLine は start 行目から順に fn を呼ぶ (fn が false を返すと止める)
+func (index *largeFileIndex) eachLine(path string, start int, fn func(lineNumber int, line string) bool) error {
+	return index.eachLineRaw(path, start, func(lineNumber int, line string, _ bool) bool {
+		return fn(lineNumber, line)
+	})
+}
+
+func (index *largeFileIndex) eachLineRaw(path string, start int, fn func(lineNumber int, line string, truncated bool) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	checkpoint := (start - 1) / largeFileIndexStride
+	lineNumber := checkpoint*largeFileIndexStride + 1
+	offset := index.checkpoints[checkpoint]
+	// 索引を作った時点までだけを読む (その後の追記は次の refresh で索引に加わる)
+	reader := newLargeFileLineReader(io.NewSectionReader(f, offset, index.size-offset), index.unitSize(), index.format.Encoding == textEncodingUTF16BE)
+	decode := index.lineDecoder()
+	for ; lineNumber <= index.lineCount; lineNumber++ {
+		raw, truncated, err := reader.next()
+		if err != nil {
+			return err
+		}
+		if lineNumber < start {
+			continue
+		}
+		if !fn(lineNumber, decode(raw), truncated) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// lineDecoder は 1 行分のバイト列を改行コードを除いた UTF-8 の文字列にする関数を返す
+func (index *largeFileIndex) lineDecoder() func(raw []byte) string {
+	enc, _ := textEncodingByName(index.format.Encoding, false)
+	return func(raw []byte) string {
+		line := string(raw)
+		if enc != nil {
+			if decoded, err := enc.NewDecoder().Bytes(raw); err == nil {
+				line = string(decoded)
+			}
+		}
+		line = strings.TrimSuffix(line, "\n")
+		return strings.TrimSuffix(line, "\r")
+	}
+}
+
+// largeFileLineReader は改行ごとにバイト列を読む（改行を含む、長すぎる行は切る）
+type largeFileLineReader struct {
+	br        *bufio.Reader
+	unit      int
+	bigEndian bool
+}
+
+func newLargeFileLineReader(r io.Reader, unit int, bigEndian bool) *largeFileLineReader {
+	return &largeFileLineReader{br: bufio.NewReaderSize(r, 64<<10), unit: unit, bigEndian: bigEndian}
+}
+
+// next は次の行を返す。ファイルの終わりでは空の行を返す
+func (r *largeFileLineReader) next() ([]byte, bool, error) {
+	if r.unit == 2 {
+		return r.nextUTF16()
+	}
+	var line []byte
+	truncated := false
+	for {
+		chunk, err := r.br.ReadSlice('\n')
+		if room := largeFileMaxLineBytes - len(line); len(chunk) > room {
+			chunk = chunk[:room]
+			truncated = true
+		}
+		line = append(line, chunk...)
+		switch {
+		case err == nil:
+			return line, truncated, nil
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case errors.Is(err, io.EOF):
+			return line, truncated, nil
+		default:
+			return nil, false, err
+		}
+	}
+}
+
+func (r *largeFileLineReader) nextUTF16() ([]byte, bool, error) {
+	var line []byte
+	truncated := false
+	pair := make([]byte, 2)
+	for {
+		if _, err := io.ReadFull(r.br, pair); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return line, truncated, nil
+			}
+			return nil, false, err
+		}
+		if len(line) < largeFileMaxLineBytes {
+			line = append(line, pair...)
+		} else {
+			truncated = true
+		}
+		if isUTF16Newline(pair[0], pair[1], r.bigEndian) {
+			return line, truncated, nil
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/japanese"
+	textunicode "golang.org/x/text/encoding/unicode"
+)
+
+func writeLogLines(t *testing.T, path string, from int, to int, newline string) {
+	t.Helper()
+	var b strings.Builder
+	for i := from; i <= to; i++ {
+		fmt.Fprintf(&b, "line %d%s", i, newline)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(b.String())
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func TestLargeFile_ReadLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writeLogLines(t, path, 1, 1000, "\r\n")
+	s := newLargeFileService(func(string, interface{}) {})
+
+	// 索引の区切り (largeFileIndexStride) をまたいで読める
+	result, err := s.ReadLines(path, largeFileIndexStride-1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"line 255", "line 256", "line 257"}, result.Lines)
+	assert.Equal(t, 1001, result.TotalLines, "a trailing newline leaves an empty last line")
+	assert.True(t, s.IsOpen(path))
+
+	result, err = s.ReadLines(path, 999, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"line 999", "line 1000", ""}, result.Lines)
+
+	result, err = s.ReadLines(path, 2000, 10)
+	require.NoError(t, err)
+	assert.Empty(t, result.Lines)
+
+	_, err = s.ReadLines(path, 0, 10)
+	assert.Error(t, err)
+
+	s.Close(path)
+	assert.False(t, s.IsOpen(path))
+}
+
+func TestLargeFile_ReadLinesInOtherEncodings(t *testing.T) {
+	dir := t.TempDir()
+	s := newLargeFileService(func(string, interface{}) {})
+
+	utf16Path := filepath.Join(dir, "utf16.log")
+	data, err := textunicode.UTF16(textunicode.LittleEndian, textunicode.UseBOM).NewEncoder().Bytes([]byte("一行目\r\n二行目\r\n三行目"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(utf16Path, data, 0644))
+	result, err := s.ReadLines(utf16Path, 2, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"二行目", "三行目"}, result.Lines)
+	assert.Equal(t, 3, result.TotalLines)
+
+	sjisPath := filepath.Join(dir, "sjis.log")
+	require.NoError(t, os.WriteFile(sjisPath, mustEncode(t, japanese.ShiftJIS, "ログ開始\nエラー発生\n"), 0644))
+	result, err = s.ReadLines(sjisPath, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ログ開始", "エラー発生"}, result.Lines)
+}
+
+func TestLargeFile_Search(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("INFO start\n😀 error: disk\nWARN retry\nerror: network\n"), 0644))
+	s := newLargeFileService(func(string, interface{}) {})
+
+	result, err := s.Search(context.Background(), path, "ERROR:", LargeFileSearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Matches, 2)
+	assert.Equal(t, 2, result.Matches[0].Line)
+	assert.Equal(t, 4, result.Matches[0].Column, "columns are UTF-16 units like Monaco")
+	assert.Equal(t, 6, result.Matches[0].Length)
+	assert.Equal(t, "😀 error: disk", result.Matches[0].Snippet)
+	assert.Equal(t, 4, result.Matches[1].Line)
+
+	result, err = s.Search(context.Background(), path, "ERROR:", LargeFileSearchOptions{CaseSensitive: true})
+	require.NoError(t, err)
+	assert.Empty(t, result.Matches)
+
+	result, err = s.Search(context.Background(), path, `^(INFO|WARN) `, LargeFileSearchOptions{Regex: true, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, result.Matches, 1)
+	assert.True(t, result.Truncated)
+
+	_, err = s.Search(context.Background(), path, "(", LargeFileSearchOptions{Regex: true})
+	assert.Error(t, err)
+}
+
+func TestLargeFile_FollowAppendAndTruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("line 1\nline 2 (partial"), 0644))
+
+	events := make(chan *LargeFileAppendEvent, 10)
+	s := newLargeFileService(func(name string, data interface{}) {
+		assert.Equal(t, fileEventAppended, name)
+		events <- data.(*LargeFileAppendEvent)
+	})
+	s.interval = 20 * time.Millisecond
+	require.NoError(t, s.Follow(path))
+	t.Cleanup(s.CloseAll)
+
+	waitAppend := func() *LargeFileAppendEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for file:appended")
+			return nil
+		}
+	}
+
+	// 書きかけだった最後の行も送り直す
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(" done)\nline 3\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	event := waitAppend()
+	assert.False(t, event.Reset)
+	assert.Equal(t, 2, event.Start)
+	assert.Equal(t, []string{"line 2 (partial done)", "line 3", ""}, event.Lines)
+	assert.Equal(t, 4, event.TotalLines)
+
+	// ローテーションで小さくなったら読み直しを求める
+	require.NoError(t, os.WriteFile(path, []byte("new\n"), 0644))
+	event = waitAppend()
+	assert.True(t, event.Reset)
+
+	s.Unfollow(path)
+	// 追従をやめる前に送られた分は捨てる
+	time.Sleep(50 * time.Millisecond)
+	for len(events) > 0 {
+		<-events
+	}
+	writeLogLines(t, path, 1, 3, "\n")
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event after unfollow: %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,437 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ------------------------------------------------------------
+// ノートとディスク上のファイルの結び付け（リンクしたファイル）
+// ------------------------------------------------------------
+//
+// ノート ID → ファイルのパスの対応を appDataDir/linkedFiles.json に置く。
+// パスは端末ごとに違うので同期しない (データのバックアップにも含めない)。
+// 最後に両者をそろえたときの内容のハッシュ・mtime・サイズを覚えておき、どちらが変わったかを判定する。
+// - ノートだけが変わった: ファイルに書き出す (SaveNote の直後と、同期でノートが更新された後の確認時)。
+// - ファイルだけが変わった: ノートを書き換えて dirty にし、note:linked-file-changed を送る。
+//   ファイルはファイルノートと同じ fileWatcher (file_watcher.go) で fsnotify で見張り、
+//   mtime (とサイズ) が変わったときだけ読んでハッシュを比べる。
+// - 両方が変わった: 負けた側の内容を競合バックアップ (linked_file_) に残す。
+//   SaveNote ではノートを、変更の確認ではファイルの内容を優先する。
+// 文字コード・BOM・改行コードはリンクしたときのファイルの形式で書き戻す。
+//
+// ロックの順序は linkedFiles.mu → noteService.mu。syncLinkedFile は linkedFiles.mu を握ったまま
+// ノートを読み書きするので、noteService のコールバック (SetNoteWrittenHandler) は s.mu を握ったまま
+// linkedFiles に触れてはいけない。ノートの書き込みは fileWatcher.ScheduleLinkedNote で確認を予約するだけにし、
+// 確認 (onLinkedFileChanged) はどちらのロックも握っていない fileWatcher のタイマーから行う。
+
+const (
+	linkedFilesFileName = "linkedFiles.json"
+	// ファイルの変更でノートを書き換えたとき・競合したときに送るイベント
+	noteEventLinkedFileChanged = "note:linked-file-changed"
+	linkedFileConflictReason   = "linked-file-conflict"
+)
+
+// LinkedFile はノートとこの端末のファイルの結び付き
+type LinkedFile struct {
+	NoteID       string `json:"noteId"`
+	FilePath     string `json:"filePath"`
+	ModifiedTime string `json:"modifiedTime,omitempty"` // 最後にそろえたときのディスクの mtime（RFC3339Nano）
+	Size         int64  `json:"size"`                   // 最後にそろえたときのディスクのバイト数
+	Hash         string `json:"hash,omitempty"`         // 最後にそろえた内容のハッシュ（リンクした直後は空）
+	Encoding     string `json:"encoding,omitempty"`     // ファイルの文字コード
+	BOM          bool   `json:"bom,omitempty"`          // ファイルに BOM を付けるか
+	LineEnding   string `json:"lineEnding,omitempty"`   // ファイルの改行コード
+}
+
+// LinkedFileEvent は note:linked-file-changed で送る内容
+type LinkedFileEvent struct {
+	NoteID     string `json:"noteId"`
+	FilePath   string `json:"filePath"`
+	Note       *Note  `json:"note,omitempty"`       // ファイルの内容で書き換えた後のノート（ノートを優先したときは nil）
+	Conflict   bool   `json:"conflict,omitempty"`   // 両方が変わっていた
+	BackupFile string `json:"backupFile,omitempty"` // 競合で負けた側を残した競合バックアップのファイル名
+}
+
+func (l *LinkedFile) textFormat() TextFormat {
+	return TextFormat{Encoding: l.Encoding, BOM: l.BOM, LineEnding: l.LineEnding}
+}
+
+// recordDisk はディスク上の今の状態をそろえた時点として覚える
+func (l *LinkedFile) recordDisk(info os.FileInfo, content string) {
+	l.ModifiedTime = info.ModTime().Format(time.RFC3339Nano)
+	l.Size = info.Size()
+	l.Hash = linkedContentHash(content)
+}
+
+// linkedContentHash は改行コードの違いを除いた本文のハッシュ
+func linkedContentHash(content string) string {
+	sum := sha256.Sum256([]byte(applyLineEnding(content, lineEndingLF)))
+	return fmt.Sprintf("%x", sum)
+}
+
+// linkedFileService は linkedFiles.json を管理する
+type linkedFileService struct {
+	mu    sync.Mutex
+	path  string
+	links map[string]*LinkedFile // ノート ID → リンク
+	emit  func(event string, data interface{})
+}
+
+// newLinkedFileService は linkedFiles.json を読み込む (無い・壊れている場合は空で始める)
+func newLinkedFileService(appDataDir string, emit func(event string, data interface{})) *linkedFileService {
+	s := &linkedFileService{
+		path:  filepath.Join(appDataDir, linkedFilesFileName),
+		links: map[string]*LinkedFile{},
+		emit:  emit,
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return s
+	}
+	var list []LinkedFile
+	if err := json.Unmarshal(data, &list); err != nil {
+		return s
+	}
+	for i := range list {
+		if list[i].NoteID != "" && list[i].FilePath != "" {
+			s.links[list[i].NoteID] = &list[i]
+		}
+	}
+	return s
+}
+
+// saveLocked は linkedFiles.json を書き込む (caller が s.mu を握っている前提)
+func (s *linkedFileService) saveLocked() error {
+	list := s.listLocked()
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("failed to save linked files: %w", err)
+	}
+	return nil
+}
+
+func (s *linkedFileService) listLocked() []LinkedFile {
+	list := make([]LinkedFile, 0, len(s.links))
+	for _, link := range s.links {
+		list = append(list, *link)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].FilePath < list[j].FilePath })
+	return list
+}
+
+// List はリンクをパスの順に返す
+func (s *linkedFileService) List() []LinkedFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listLocked()
+}
+
+// Get はノートのリンクを返す
+func (s *linkedFileService) Get(noteID string) (LinkedFile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, ok := s.links[noteID]
+	if !ok {
+		return LinkedFile{}, false
+	}
+	return *link, true
+}
+
+// Unlink はノートのリンクを外す (ファイルとノートはそのまま残す)
+func (s *linkedFileService) Unlink(noteID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.links[noteID]; !ok {
+		return nil
+	}
+	delete(s.links, noteID)
+	return s.saveLocked()
+}
+
+// noteIDs はリンクしているノートの ID を返す
+func (s *linkedFileService) noteIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.links))
+	for id := range s.links {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// linkNoteToFile はノートをファイルにリンクし、最初の同期を行う
+// ファイルが無ければノートの内容で作り、内容が違えばファイルを優先してノートを競合バックアップに残す。
+func (a *App) linkNoteToFile(noteID string, filePath string) (*LinkedFile, error) {
+	if !filepath.IsAbs(filePath) {
+		return nil, fmt.Errorf("linked file path must be absolute: %s", filePath)
+	}
+	filePath = filepath.Clean(filePath)
+	note, err := a.currentNoteService().LoadNote(noteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load note %s: %w", noteID, err)
+	}
+	if note.Encrypted {
+		return nil, fmt.Errorf("cannot link encrypted note %s to a file", noteID)
+	}
+
+	link := &LinkedFile{NoteID: noteID, FilePath: filePath, Encoding: textEncodingUTF8}
+	if info, err := os.Stat(filePath); err == nil {
+		if info.IsDir() {
+			return nil, fmt.Errorf("%s is a directory", filePath)
+		}
+		if isLargeFileSize(info.Size()) {
+			return nil, fmt.Errorf("%s is too large to link to a note", filePath)
+		}
+		if err := a.checkWritableFile(filePath); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		_, format := decodeText(data)
+		link.Encoding, link.BOM, link.LineEnding = format.Encoding, format.BOM, format.LineEnding
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	s := a.currentLinkedFiles()
+	s.mu.Lock()
+	for id, other := range s.links {
+		if id != noteID && other.FilePath == filePath {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("%s is already linked to note %s", filePath, id)
+		}
+	}
+	s.links[noteID] = link
+	err = s.saveLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	a.watchLinkedFiles()
+
+	if err := a.syncLinkedFile(noteID, false); err != nil {
+		return nil, err
+	}
+	linked, _ := s.Get(noteID)
+	return &linked, nil
+}
+
+// syncLinkedFile はリンクしたノートとファイルの変わった側をもう一方に反映する
+// noteWins は両方が変わっていたときにノートを優先するか (SaveNote の直後)。
+func (a *App) syncLinkedFile(noteID string, noteWins bool) error {
+	s := a.currentLinkedFiles()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, ok := s.links[noteID]
+	if !ok {
+		return nil
+	}
+
+	// ゴミ箱にあるノートは同期しない (元に戻したら再開する)
+	listed := false
+	noteService := a.currentNoteService()
+	noteService.WithLock(func() {
+		_, listed = noteService.noteFolderIDLocked(noteID)
+	})
+	if !listed {
+		return nil
+	}
+	note, err := noteService.LoadNote(noteID)
+	if err != nil {
+		return fmt.Errorf("failed to load note %s: %w", noteID, err)
+	}
+	if note.Encrypted {
+		// 暗号化したノートの本文は暗号文なので書き出さない
+		return nil
+	}
+	noteHash := linkedContentHash(note.Content)
+
+	info, err := os.Stat(link.FilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		// 外部で消されたファイルは、ノートを保存したときとリンクした直後だけ作り直す
+		if !noteWins && link.Hash != "" {
+			return nil
+		}
+		return a.writeLinkedFileLocked(s, link, note.Content)
+	}
+	if err != nil {
+		return err
+	}
+
+	diskChanged := link.Hash == "" || info.Size() != link.Size || info.ModTime().Format(time.RFC3339Nano) != link.ModifiedTime
+	if !diskChanged {
+		if noteHash == link.Hash {
+			return nil
+		}
+		return a.writeLinkedFileLocked(s, link, note.Content)
+	}
+
+	if err := a.checkWritableFile(link.FilePath); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(link.FilePath)
+	if err != nil {
+		return err
+	}
+	disk, format := decodeText(data)
+	diskHash := linkedContentHash(disk)
+	switch {
+	case diskHash == noteHash:
+		// 同じ内容 (touch されただけなど)
+		link.recordDisk(info, disk)
+		return s.saveLocked()
+	case diskHash == link.Hash:
+		return a.writeLinkedFileLocked(s, link, note.Content)
+	case noteHash == link.Hash:
+		event, err := a.applyLinkedFileLocked(s, link, note, disk, format, info)
+		if err != nil {
+			return err
+		}
+		s.emitEvent(event)
+		return nil
+	}
+
+	// 両方が変わっていた: 負けた側を競合バックアップに残す
+	diskNote := *note
+	diskNote.Content = disk
+	loser, winner := &diskNote, note
+	if !noteWins {
+		loser, winner = note, &diskNote
+	}
+	record := cloudWinBackupRecord{
+		Reason:            linkedFileConflictReason,
+		BackupCreatedAt:   time.Now().UTC().Format(time.RFC3339Nano),
+		NoteID:            noteID,
+		LocalModifiedTime: note.ModifiedTime,
+		CloudModifiedTime: info.ModTime().UTC().Format(time.RFC3339),
+		LocalNote:         loser,
+		CloudNote:         winner,
+	}
+	backupPath, err := writeConflictBackupRecord(a.currentAppDataDir(), record, linkedFileBackupFilePrefix)
+	if err != nil && backupPath == "" {
+		// 残せなかった内容は上書きしない
+		return fmt.Errorf("failed to back up linked file conflict: %w", err)
+	}
+
+	event := &LinkedFileEvent{NoteID: noteID, FilePath: link.FilePath, Conflict: true, BackupFile: filepath.Base(backupPath)}
+	if noteWins {
+		if err := a.writeLinkedFileLocked(s, link, note.Content); err != nil {
+			return err
+		}
+	} else {
+		applied, err := a.applyLinkedFileLocked(s, link, note, disk, format, info)
+		if err != nil {
+			return err
+		}
+		event.Note = applied.Note
+	}
+	s.emitEvent(event)
+	return nil
+}
+
+// writeLinkedFileLocked はノートの本文をリンクしたファイルに書き出す (caller が s.mu を握っている前提)
+func (a *App) writeLinkedFileLocked(s *linkedFileService, link *LinkedFile, content string) error {
+	if err := a.checkWritableFile(link.FilePath); err != nil {
+		return err
+	}
+	if _, err := a.fileService.SaveFileWithFormat(link.FilePath, content, link.textFormat()); err != nil {
+		return fmt.Errorf("failed to write linked file %s: %w", link.FilePath, err)
+	}
+	// ファイルノートとしても開いていれば、自分で書いた内容を外部での変更として通知しない
+	if watcher := a.currentFileWatcher(); watcher != nil {
+		watcher.RecordSaved(link.FilePath)
+	}
+	info, err := os.Stat(link.FilePath)
+	if err != nil {
+		return err
+	}
+	link.recordDisk(info, content)
+	return s.saveLocked()
+}
+
+// applyLinkedFileLocked はファイルの内容でノートを書き換えて dirty にする (caller が s.mu を握っている前提)
+func (a *App) applyLinkedFileLocked(s *linkedFileService, link *LinkedFile, note *Note, disk string, format TextFormat, info os.FileInfo) (*LinkedFileEvent, error) {
+	noteService, syncState := a.currentNoteServices()
+	updated := *note
+	updated.Content = disk
+	updated.ContentHeader = ""
+	if err := noteService.SaveNote(&updated); err != nil {
+		return nil, err
+	}
+	if syncState != nil {
+		syncState.MarkNoteDirty(note.ID)
+	}
+	a.triggerSyncIfConnected()
+
+	link.Encoding, link.BOM, link.LineEnding = format.Encoding, format.BOM, format.LineEnding
+	link.recordDisk(info, disk)
+	if err := s.saveLocked(); err != nil {
+		return nil, err
+	}
+	return &LinkedFileEvent{NoteID: note.ID, FilePath: link.FilePath, Note: &updated}, nil
+}
+
+func (s *linkedFileService) emitEvent(event *LinkedFileEvent) {
+	if s.emit != nil && event != nil {
+		s.emit(noteEventLinkedFileChanged, event)
+	}
+}
+
+// checkLinkedFiles は全てのリンクを確認し、外部での変更をノートに取り込む
+func (a *App) checkLinkedFiles() error {
+	s := a.currentLinkedFiles()
+	if s == nil {
+		return nil
+	}
+	var errs []error
+	for _, noteID := range s.noteIDs() {
+		if err := a.syncLinkedFile(noteID, false); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// watchLinkedFiles はファイルの監視をこの端末のリンクに合わせる
+func (a *App) watchLinkedFiles() {
+	watcher, s := a.currentFileWatcher(), a.currentLinkedFiles()
+	if watcher == nil || s == nil {
+		return
+	}
+	watcher.SyncLinked(s.List())
+}
+
+// onNoteWritten はノートが書き込まれたとき (同期・タグの変更などを含む) に noteService.mu を握ったまま呼ばれる
+// linkedFiles には触れずに、リンクしたファイルとの確認を fileWatcher に予約する。
+func (a *App) onNoteWritten(noteID string) {
+	if watcher := a.currentFileWatcher(); watcher != nil {
+		watcher.ScheduleLinkedNote(noteID)
+	}
+}
+
+// onLinkedFileChanged はリンクしたファイルかノートの変更をまとめた後に fileWatcher から呼ばれる
+func (a *App) onLinkedFileChanged(noteID string) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.logger.Console(fmt.Sprintf("PANIC in onLinkedFileChanged: %v\n%s", r, string(debug.Stack())))
+		}
+	}()
+	if err := a.syncLinkedFile(noteID, false); err != nil {
+		a.logger.Console("Failed to check linked file: %v", err)
+	}
+}
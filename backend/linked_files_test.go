@@ -0,0 +1,199 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/japanese"
+)
+
+func newLinkedFileTestApp(t *testing.T) (*App, *[]*LinkedFileEvent) {
+	t.Helper()
+	appDataDir := t.TempDir()
+	notesDir := filepath.Join(appDataDir, "notes")
+	require.NoError(t, os.MkdirAll(notesDir, 0755))
+	logger := NewAppLogger(context.Background(), true, appDataDir)
+	ns, err := NewNoteService(notesDir, logger)
+	require.NoError(t, err)
+
+	var events []*LinkedFileEvent
+	app := &App{
+		appDataDir:  appDataDir,
+		notesDir:    notesDir,
+		logger:      logger,
+		noteService: ns,
+		fileService: NewFileService(&Context{}),
+		syncState:   NewSyncState(appDataDir),
+		linkedFiles: newLinkedFileService(appDataDir, func(event string, data interface{}) {
+			assert.Equal(t, noteEventLinkedFileChanged, event)
+			events = append(events, data.(*LinkedFileEvent))
+		}),
+	}
+	return app, &events
+}
+
+// writeExternally は外部のエディタでの保存を真似る (mtime を確実に進める)
+func writeExternally(t *testing.T, path string, data []byte) {
+	t.Helper()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	later := info.ModTime().Add(2 * time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+}
+
+func TestLinkedFile_SaveWritesFileAndExternalChangeUpdatesNote(t *testing.T) {
+	app, events := newLinkedFileTestApp(t)
+	path := filepath.Join(t.TempDir(), ".zshrc")
+	require.NoError(t, os.WriteFile(path, mustEncode(t, japanese.ShiftJIS, "# 設定ファイル\r\nexport A=1\r\n"), 0644))
+
+	note := &Note{ID: "zshrc", Title: ".zshrc", Content: "# 設定ファイル\nexport A=1\n", Language: "shell"}
+	require.NoError(t, app.SaveNote(note, "create"))
+	link, err := app.LinkNoteToFile(note.ID, path)
+	require.NoError(t, err)
+	assert.Equal(t, textEncodingShiftJIS, link.Encoding)
+	assert.Equal(t, lineEndingCRLF, link.LineEnding)
+	assert.Empty(t, *events, "the same content except line endings is not a conflict")
+	assert.Equal(t, []LinkedFile{*link}, newLinkedFileService(app.appDataDir, nil).List(), "links are kept in linkedFiles.json")
+
+	// ノートの保存はファイルの文字コード・改行コードで書き出す
+	note.Content = "# 設定ファイル\nexport A=2\n"
+	require.NoError(t, app.SaveNote(note, "update"))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, mustEncode(t, japanese.ShiftJIS, "# 設定ファイル\r\nexport A=2\r\n"), data)
+
+	// 外部での変更はノートに取り込んで dirty にする
+	app.syncState.ClearDirty("", nil)
+	writeExternally(t, path, mustEncode(t, japanese.ShiftJIS, "# 設定ファイル\r\nexport A=3\r\n"))
+	require.NoError(t, app.CheckLinkedFiles())
+	loaded, err := app.noteService.LoadNote(note.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "# 設定ファイル\r\nexport A=3\r\n", loaded.Content)
+	dirty, _, _ := app.syncState.GetDirtySnapshot()
+	assert.True(t, dirty[note.ID])
+	require.Len(t, *events, 1)
+	assert.False(t, (*events)[0].Conflict)
+	assert.Equal(t, loaded.Content, (*events)[0].Note.Content)
+
+	// 変わっていなければ何もしない
+	require.NoError(t, app.CheckLinkedFiles())
+	assert.Len(t, *events, 1)
+
+	require.NoError(t, app.UnlinkNote(note.ID))
+	assert.Nil(t, app.GetLinkedFile(note.ID))
+	note.Content = "unlinked"
+	require.NoError(t, app.SaveNote(note, "update"))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, mustEncode(t, japanese.ShiftJIS, "# 設定ファイル\r\nexport A=3\r\n"), data)
+}
+
+func TestLinkedFile_ConflictsGoToConflictBackups(t *testing.T) {
+	app, events := newLinkedFileTestApp(t)
+	path := filepath.Join(t.TempDir(), "nginx.conf")
+	note := &Note{ID: "nginx", Title: "nginx.conf", Content: "worker_processes 1;\n"}
+	require.NoError(t, app.SaveNote(note, "create"))
+
+	// ファイルが無ければノートの内容で作る
+	_, err := app.LinkNoteToFile(note.ID, path)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "worker_processes 1;\n", string(data))
+
+	_, err = app.LinkNoteToFile("other", path)
+	assert.Error(t, err, "a note is required")
+
+	// 同期でノートが変わり、ファイルも外部で変わった: 確認ではファイルを優先してノートを残す
+	synced, err := app.noteService.LoadNote(note.ID)
+	require.NoError(t, err)
+	fromCloud := *synced
+	fromCloud.Content = "worker_processes 2;\n"
+	require.NoError(t, app.noteService.SaveNote(&fromCloud))
+	writeExternally(t, path, []byte("worker_processes auto;\n"))
+	require.NoError(t, app.CheckLinkedFiles())
+
+	loaded, err := app.noteService.LoadNote(note.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "worker_processes auto;\n", loaded.Content)
+	require.Len(t, *events, 1)
+	assert.True(t, (*events)[0].Conflict)
+
+	backups, err := app.ListCloudConflictBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, "linked_file", backups[0].Kind)
+	assert.Equal(t, (*events)[0].BackupFile, backups[0].Filename)
+	assert.Equal(t, "worker_processes 2;\n", backups[0].Note.Content)
+
+	// 保存でもファイルが外部で変わっていれば、ノートを優先してファイルの内容を残す
+	writeExternally(t, path, []byte("worker_processes 4;\n"))
+	note.Content = "worker_processes 8;\n"
+	require.NoError(t, app.SaveNote(note, "update"))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "worker_processes 8;\n", string(data))
+	backups, err = app.ListCloudConflictBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	assert.Equal(t, "worker_processes 4;\n", backups[0].Note.Content)
+
+	// 競合バックアップで戻したノートは次の確認でファイルに書き出す
+	_, err = app.ResolveCloudConflictBackup(backups[0].Filename, ConflictResolution{Action: conflictResolutionLocal})
+	require.NoError(t, err)
+	require.NoError(t, app.CheckLinkedFiles())
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "worker_processes 4;\n", string(data))
+}
+
+func TestLinkedFile_WatcherAppliesChangesOnBothSides(t *testing.T) {
+	app, _ := newLinkedFileTestApp(t)
+	app.ctx = NewContext(context.Background())
+	app.fileNoteService = NewFileNoteService(app.appDataDir)
+	app.noteService.SetNoteWrittenHandler(app.onNoteWritten)
+	app.startFileWatcher()
+	t.Cleanup(app.stopFileWatcher)
+
+	path := filepath.Join(t.TempDir(), "hosts")
+	note := &Note{ID: "hosts", Title: "hosts", Content: "127.0.0.1 localhost\n"}
+	require.NoError(t, app.SaveNote(note, "create"))
+	_, err := app.LinkNoteToFile(note.ID, path)
+	require.NoError(t, err)
+
+	noteContent := func() string {
+		loaded, err := app.noteService.LoadNote(note.ID)
+		if err != nil {
+			return ""
+		}
+		return loaded.Content
+	}
+	fileContent := func() string {
+		data, _ := os.ReadFile(path)
+		return string(data)
+	}
+
+	// 外部での変更は CheckLinkedFiles を呼ばなくても fsnotify で取り込む
+	writeExternally(t, path, []byte("127.0.0.1 example\n"))
+	assert.Eventually(t, func() bool { return noteContent() == "127.0.0.1 example\n" }, 5*time.Second, 20*time.Millisecond)
+
+	// 同期で書き込まれたノートはファイルに書き出す
+	synced, err := app.noteService.LoadNote(note.ID)
+	require.NoError(t, err)
+	fromCloud := *synced
+	fromCloud.Content = "10.0.0.1 db\n"
+	require.NoError(t, app.noteService.SaveNoteFromSync(&fromCloud))
+	assert.Eventually(t, func() bool { return fileContent() == "10.0.0.1 db\n" }, 5*time.Second, 20*time.Millisecond)
+
+	// リンクを外したら監視しない
+	require.NoError(t, app.UnlinkNote(note.ID))
+	writeExternally(t, path, []byte("changed after unlink\n"))
+	time.Sleep(fileWatchDebounce + 300*time.Millisecond)
+	assert.Equal(t, "10.0.0.1 db\n", noteContent())
+}
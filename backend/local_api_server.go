@@ -0,0 +1,716 @@
+package backend
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+// ローカル HTTP API（自動化ツール向け）
+// ------------------------------------------------------------
+//
+// 設定で有効にした場合のみ 127.0.0.1 で待ち受け、Authorization: Bearer <token> を要求する。
+// トークンは settings.json に保存され、RegenerateLocalAPIToken で作り直せる。
+//
+//   GET    /api/v1/notes                一覧（?archived=true|all, ?folderId=）
+//   POST   /api/v1/notes                作成
+//   GET    /api/v1/notes/{id}           取得
+//   PATCH  /api/v1/notes/{id}           更新（指定したフィールドのみ）
+//   DELETE /api/v1/notes/{id}           削除
+//   POST   /api/v1/notes/{id}/move      フォルダへ移動（{"folderId": ""} でトップレベル）
+//   GET    /api/v1/folders              フォルダ一覧
+//   POST   /api/v1/folders              作成（parentId 指定でサブフォルダ）
+//   PATCH  /api/v1/folders/{id}         名前変更・親の変更
+//   DELETE /api/v1/folders/{id}         削除（空のフォルダのみ）
+//   GET    /api/v1/search?q=            全文検索（SearchOptions と同名のクエリを受け付ける）
+//   POST   /api/v1/sync                 Google Drive との同期を開始
+//   GET    /api/v1/events               Server-Sent Events（notes:reload / drive:status など）
+//
+// 書き込みは GUI からの操作と同じく noteService のロックを取り、SyncState を dirty にして同期に乗せる。
+// 変更後は notes:reload を発行するため、開いているウィンドウも再読み込みされる。
+
+const (
+	localAPIPrefix          = "/api/v1"
+	localAPIMaxBodyBytes    = 16 << 20
+	localAPIKeepAlive       = 30 * time.Second
+	localAPIEventBufferSize = 32
+)
+
+// localAPIEvent は SSE で流す 1 件分のイベント
+type localAPIEvent struct {
+	name string
+	data interface{}
+}
+
+// localAPIEventHub は AppLogger からのイベントを SSE の購読者へ配る
+type localAPIEventHub struct {
+	mu          sync.Mutex
+	subscribers map[chan localAPIEvent]struct{}
+}
+
+func newLocalAPIEventHub() *localAPIEventHub {
+	return &localAPIEventHub{subscribers: make(map[chan localAPIEvent]struct{})}
+}
+
+func (h *localAPIEventHub) subscribe() chan localAPIEvent {
+	ch := make(chan localAPIEvent, localAPIEventBufferSize)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *localAPIEventHub) unsubscribe(ch chan localAPIEvent) {
+	h.mu.Lock()
+	delete(h.subscribers, ch)
+	h.mu.Unlock()
+}
+
+// publish は AppLogger の SetEventMirror に渡す。
+// 読み取りが追いつかない購読者の分は捨て、通知元（同期処理など）を待たせない。
+func (h *localAPIEventHub) publish(name string, data interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- localAPIEvent{name: name, data: data}:
+		default:
+		}
+	}
+}
+
+// 認証トークンを生成する
+func generateLocalAPIToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate local API token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// localAPIServer はループバックで待ち受ける HTTP サーバー
+type localAPIServer struct {
+	app      *App
+	token    string
+	listener net.Listener
+	server   *http.Server
+}
+
+func newLocalAPIServer(app *App, token string) *localAPIServer {
+	return &localAPIServer{app: app, token: token}
+}
+
+// start は 127.0.0.1:port で待ち受けを開始し、実際のポートを返す。
+// port が 0 または使用中の場合は空いているポートを選ぶ。
+func (s *localAPIServer) start(port int) (int, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil && port != 0 {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to listen on loopback: %w", err)
+	}
+
+	s.listener = listener
+	s.server = &http.Server{
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.app.logger.Console("Local API server stopped: %v", err)
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// stop は SSE の接続も含めて待ち受けを終了する
+func (s *localAPIServer) stop() error {
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Close()
+}
+
+func (s *localAPIServer) baseURL() string {
+	if s.listener == nil {
+		return ""
+	}
+	return "http://" + s.listener.Addr().String() + localAPIPrefix
+}
+
+func (s *localAPIServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+localAPIPrefix+"/notes", s.listNotes)
+	mux.HandleFunc("POST "+localAPIPrefix+"/notes", s.createNote)
+	mux.HandleFunc("GET "+localAPIPrefix+"/notes/{id}", s.getNote)
+	mux.HandleFunc("PATCH "+localAPIPrefix+"/notes/{id}", s.updateNote)
+	mux.HandleFunc("DELETE "+localAPIPrefix+"/notes/{id}", s.deleteNote)
+	mux.HandleFunc("POST "+localAPIPrefix+"/notes/{id}/move", s.moveNote)
+	mux.HandleFunc("GET "+localAPIPrefix+"/folders", s.listFolders)
+	mux.HandleFunc("POST "+localAPIPrefix+"/folders", s.createFolder)
+	mux.HandleFunc("PATCH "+localAPIPrefix+"/folders/{id}", s.updateFolder)
+	mux.HandleFunc("DELETE "+localAPIPrefix+"/folders/{id}", s.deleteFolder)
+	mux.HandleFunc("GET "+localAPIPrefix+"/search", s.search)
+	mux.HandleFunc("POST "+localAPIPrefix+"/sync", s.sync)
+	mux.HandleFunc("GET "+localAPIPrefix+"/events", s.events)
+	return s.authorize(mux)
+}
+
+// authorize はトークンと Host ヘッダーを検証する。
+// Host の検証はブラウザ経由の DNS リバインディングで API が叩かれるのを防ぐため。
+func (s *localAPIServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if host != "127.0.0.1" && host != "localhost" {
+			writeLocalAPIError(w, http.StatusForbidden, fmt.Errorf("invalid host: %s", r.Host))
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="monaco-notepad"`)
+			writeLocalAPIError(w, http.StatusUnauthorized, fmt.Errorf("invalid or missing token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// localAPINoteInput はノートの作成・更新で受け付けるフィールド（省略したものは変更しない）
+type localAPINoteInput struct {
+	Title    *string   `json:"title"`
+	Content  *string   `json:"content"`
+	Language *string   `json:"language"`
+	Tags     *[]string `json:"tags"`
+	Archived *bool     `json:"archived"`
+	FolderID *string   `json:"folderId"`
+}
+
+// apply は指定されたフィールドをノートに反映する（フォルダは別途 MoveNoteToFolder で扱う）
+func (in *localAPINoteInput) apply(note *Note) {
+	if in.Title != nil {
+		note.Title = *in.Title
+	}
+	if in.Content != nil {
+		note.Content = *in.Content
+		note.ContentHeader = ""
+	}
+	if in.Language != nil {
+		note.Language = *in.Language
+	}
+	if in.Tags != nil {
+		note.Tags = *in.Tags
+	}
+	if in.Archived != nil && *in.Archived != note.Archived {
+		note.Archived = *in.Archived
+		if note.Archived {
+			// フロントエンドのアーカイブ操作と同じく、一覧用のプレビューを作り直す
+			note.ContentHeader = noteContentHeader(note)
+		}
+	}
+}
+
+// localAPIFolderInput はフォルダの作成・更新で受け付けるフィールド
+type localAPIFolderInput struct {
+	Name     *string `json:"name"`
+	ParentID *string `json:"parentId"`
+}
+
+// ノート一覧 ------------------------------------------------------------
+func (s *localAPIServer) listNotes(w http.ResponseWriter, r *http.Request) {
+	archived := r.URL.Query().Get("archived")
+	folderID := r.URL.Query().Get("folderId")
+
+	var notes []NoteMetadata
+	ns := s.app.currentNoteService()
+	ns.WithLock(func() {
+		for _, metadata := range ns.noteList.Notes {
+			switch {
+			case archived == "all":
+			case archived == "true" && !metadata.Archived:
+				continue
+			case archived != "true" && metadata.Archived:
+				continue
+			}
+			if folderID != "" && metadata.FolderID != folderID {
+				continue
+			}
+			notes = append(notes, metadata)
+		}
+	})
+	if notes == nil {
+		notes = []NoteMetadata{}
+	}
+	writeLocalAPIJSON(w, http.StatusOK, notes)
+}
+
+// ノート取得 ------------------------------------------------------------
+func (s *localAPIServer) getNote(w http.ResponseWriter, r *http.Request) {
+	note, err := s.loadNote(r.PathValue("id"))
+	if err != nil {
+		writeLocalAPIError(w, localAPIStatusForError(err), err)
+		return
+	}
+	writeLocalAPIJSON(w, http.StatusOK, note)
+}
+
+// ノート作成 ------------------------------------------------------------
+func (s *localAPIServer) createNote(w http.ResponseWriter, r *http.Request) {
+	var input localAPINoteInput
+	if err := readLocalAPIJSON(w, r, &input); err != nil {
+		writeLocalAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	note := &Note{
+		ID:       uuid.New().String(),
+		Language: "plaintext",
+	}
+	input.apply(note)
+	// 移動先のフォルダが無ければノートを作らずにエラーにする
+	ns := s.app.currentNoteService()
+	var err error
+	ns.WithLock(func() {
+		err = checkLocalAPIFolderLocked(ns, input.FolderID)
+	})
+	if err != nil {
+		writeLocalAPIError(w, localAPIStatusForError(err), err)
+		return
+	}
+	if err := s.app.SaveNote(note, "create"); err != nil {
+		writeLocalAPIError(w, localAPIStatusForError(err), err)
+		return
+	}
+	if input.FolderID != nil && *input.FolderID != "" {
+		if err := s.app.MoveNoteToFolder(note.ID, *input.FolderID); err != nil {
+			writeLocalAPIError(w, localAPIStatusForError(err), err)
+			return
+		}
+	}
+	s.notifyChanged()
+
+	created, err := s.loadNote(note.ID)
+	if err != nil {
+		writeLocalAPIError(w, localAPIStatusForError(err), err)
+		return
+	}
+	writeLocalAPIJSON(w, http.StatusCreated, created)
+}
+
+// checkLocalAPIFolderLocked はノートの移動先に指定されたフォルダがあるかを確かめる（空文字はトップレベル）
+func checkLocalAPIFolderLocked(ns *noteService, folderID *string) error {
+	if folderID == nil || *folderID == "" {
+		return nil
+	}
+	if _, ok := ns.folderMapLocked()[*folderID]; !ok {
+		return fmt.Errorf("%w: %s", errFolderNotFound, *folderID)
+	}
+	return nil
+}
+
+// ノート更新 ------------------------------------------------------------
+// 読み込みから保存までを noteService のロック内で行い、同時に来た更新を取りこぼさない。
+// 保存後は App.SaveNote と同じく同期対象に積み、リンクしたファイルにもノートを優先して書き出す。
+func (s *localAPIServer) updateNote(w http.ResponseWriter, r *http.Request) {
+	var input localAPINoteInput
+	if err := readLocalAPIJSON(w, r, &input); err != nil {
+		writeLocalAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	id := r.PathValue("id")
+	ns, syncState := s.app.currentNoteServices()
+	var err error
+	ns.WithLock(func() {
+		var current *Note
+		current, err = ns.loadNoteLocked(id)
+		if err != nil {
+			return
+		}
+		// 移動先のフォルダが無ければ、本文も書き換えずにエラーにする
+		if err = checkLocalAPIFolderLocked(ns, input.FolderID); err != nil {
+			return
+		}
+		// キャッシュ上のノートは変更前の版として履歴に使われるため、コピーを書き換える
+		updated := *current
+		input.apply(&updated)
+		err = ns.saveNoteLocked(&updated, NoteRevisionSourceLocal)
+	})
+	if err != nil {
+		writeLocalAPIError(w, localAPIStatusForError(err), err)
+		return
+	}
+	if input.FolderID != nil {
+		if err := s.app.MoveNoteToFolder(id, *input.FolderID); err != nil {
+			writeLocalAPIError(w, localAPIStatusForError(err), err)
+			return
+		}
+	}
+	err = s.app.noteSaved(syncState, id)
+	s.notifyChanged()
+	if err != nil {
+		writeLocalAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	note, err := s.loadNote(id)
+	if err != nil {
+		writeLocalAPIError(w, localAPIStatusForError(err), err)
+		return
+	}
+	writeLocalAPIJSON(w, http.StatusOK, note)
+}
+
+// ノート削除 ------------------------------------------------------------
+func (s *localAPIServer) deleteNote(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := s.loadNote(id); err != nil {
+		writeLocalAPIError(w, localAPIStatusForError(err), err)
+		return
+	}
+	if err := s.app.DeleteNote(id); err != nil {
+		writeLocalAPIError(w, localAPIStatusForError(err), err)
+		return
+	}
+	s.notifyChanged()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ノートをフォルダへ移動 ------------------------------------------------------------
+func (s *localAPIServer) moveNote(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		FolderID string `json:"folderId"`
+	}
+	if err := readLocalAPIJSON(w, r, &input); err != nil {
+		writeLocalAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	id := r.PathValue("id")
+	if _, err := s.loadNote(id); err != nil {
+		writeLocalAPIError(w, localAPIStatusForError(err), err)
+		return
+	}
+	if err := s.app.MoveNoteToFolder(id, input.FolderID); err != nil {
+		writeLocalAPIError(w, localAPIStatusForError(err), err)
+		return
+	}
+	s.notifyChanged()
+
+	note, err := s.loadNote(id)
+	if err != nil {
+		writeLocalAPIError(w, localAPIStatusForError(err), err)
+		return
+	}
+	writeLocalAPIJSON(w, http.StatusOK, note)
+}
+
+// フォルダ一覧 ------------------------------------------------------------
+func (s *localAPIServer) listFolders(w http.ResponseWriter, r *http.Request) {
+	folders := s.app.currentNoteService().ListFolders()
+	if folders == nil {
+		folders = []Folder{}
+	}
+	writeLocalAPIJSON(w, http.StatusOK, folders)
+}
+
+// フォルダ作成 ------------------------------------------------------------
+func (s *localAPIServer) createFolder(w http.ResponseWriter, r *http.Request) {
+	var input localAPIFolderInput
+	if err := readLocalAPIJSON(w, r, &input); err != nil {
+		writeLocalAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	name, parentID := "", ""
+	if input.Name != nil {
+		name = *input.Name
+	}
+	if input.ParentID != nil {
+		parentID = *input.ParentID
+	}
+
+	folder, err := s.app.CreateSubfolder(parentID, name)
+	if err != nil {
+		writeLocalAPIError(w, localAPIStatusForError(err), err)
+		return
+	}
+	s.notifyChanged()
+	writeLocalAPIJSON(w, http.StatusCreated, folder)
+}
+
+// フォルダ更新 ------------------------------------------------------------
+func (s *localAPIServer) updateFolder(w http.ResponseWriter, r *http.Request) {
+	var input localAPIFolderInput
+	if err := readLocalAPIJSON(w, r, &input); err != nil {
+		writeLocalAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	id := r.PathValue("id")
+	if input.Name != nil {
+		if err := s.app.RenameFolder(id, *input.Name); err != nil {
+			writeLocalAPIError(w, localAPIStatusForError(err), err)
+			return
+		}
+	}
+	if input.ParentID != nil {
+		if err := s.app.MoveFolder(id, *input.ParentID); err != nil {
+			writeLocalAPIError(w, localAPIStatusForError(err), err)
+			return
+		}
+	}
+	s.notifyChanged()
+
+	for _, folder := range s.app.currentNoteService().ListFolders() {
+		if folder.ID == id {
+			writeLocalAPIJSON(w, http.StatusOK, folder)
+			return
+		}
+	}
+	err := fmt.Errorf("%w: %s", errFolderNotFound, id)
+	writeLocalAPIError(w, http.StatusNotFound, err)
+}
+
+// フォルダ削除 ------------------------------------------------------------
+func (s *localAPIServer) deleteFolder(w http.ResponseWriter, r *http.Request) {
+	if err := s.app.DeleteFolder(r.PathValue("id")); err != nil {
+		writeLocalAPIError(w, localAPIStatusForError(err), err)
+		return
+	}
+	s.notifyChanged()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// 全文検索 ------------------------------------------------------------
+func (s *localAPIServer) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	options := SearchOptions{
+		FolderID: query.Get("folderId"),
+		Language: query.Get("language"),
+		Archived: query.Get("archived"),
+	}
+	for name, target := range map[string]*int{"limit": &options.Limit, "maxMatchesPerNote": &options.MaxMatchesPerNote} {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				writeLocalAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid %s: %s", name, value))
+				return
+			}
+			*target = n
+		}
+	}
+
+	hits, err := s.app.currentNoteService().SearchNotes(query.Get("q"), options)
+	if err != nil {
+		writeLocalAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if hits == nil {
+		hits = []SearchHit{}
+	}
+	writeLocalAPIJSON(w, http.StatusOK, hits)
+}
+
+// 同期の開始 ------------------------------------------------------------
+// 同期は非同期で行い、結果は /events の drive:status で通知される。
+func (s *localAPIServer) sync(w http.ResponseWriter, r *http.Request) {
+	driveService := s.app.currentDriveService()
+	if driveService == nil || !driveService.IsConnected() {
+		writeLocalAPIError(w, http.StatusConflict, fmt.Errorf("sync is not connected"))
+		return
+	}
+	s.app.triggerSyncIfConnected()
+	writeLocalAPIJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
+}
+
+// イベントストリーム ------------------------------------------------------------
+func (s *localAPIServer) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok || s.app.apiEvents == nil {
+		writeLocalAPIError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	events := s.app.apiEvents.subscribe()
+	defer s.app.apiEvents.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(localAPIKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event := <-events:
+			data, err := json.Marshal(event.data)
+			if err != nil {
+				data = []byte("null")
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.name, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// loadNote はノートを読み込み、noteList 上のフォルダIDを補って返す
+func (s *localAPIServer) loadNote(id string) (*Note, error) {
+	ns := s.app.currentNoteService()
+	var note *Note
+	var err error
+	ns.WithLock(func() {
+		var found *NoteMetadata
+		for i := range ns.noteList.Notes {
+			if ns.noteList.Notes[i].ID == id {
+				found = &ns.noteList.Notes[i]
+				break
+			}
+		}
+		if found == nil {
+			err = fmt.Errorf("%w: %s", errNoteNotFound, id)
+			return
+		}
+		var loaded *Note
+		loaded, err = ns.loadNoteLocked(id)
+		if err != nil {
+			return
+		}
+		copied := *loaded
+		copied.FolderID = found.FolderID
+		note = &copied
+	})
+	return note, err
+}
+
+// notifyChanged はウィンドウ側の一覧を再読み込みさせる（/events の購読者にも流れる）
+func (s *localAPIServer) notifyChanged() {
+	s.app.logger.NotifyFrontendSyncedAndReload(s.app.ctx.ctx)
+}
+
+func readLocalAPIJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	r.Body = http.MaxBytesReader(w, r.Body, localAPIMaxBodyBytes)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return nil
+}
+
+func writeLocalAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeLocalAPIError(w http.ResponseWriter, status int, err error) {
+	writeLocalAPIJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// localAPIStatusForError は noteService のエラーを HTTP ステータスに変換する
+func localAPIStatusForError(err error) int {
+	switch {
+	case errors.Is(err, os.ErrNotExist), errors.Is(err, errNoteNotFound), errors.Is(err, errFolderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errFolderNotEmpty), errors.Is(err, errFolderArchived), errors.Is(err, errNoteArchived), errors.Is(err, errFolderIntoDescendant):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// startLocalAPIIfEnabled は設定で有効な場合にサーバーを起動する。
+// トークンやポートが未設定なら生成して設定に保存する。
+func (a *App) startLocalAPIIfEnabled() error {
+	settingsService := a.currentSettingsService()
+	a.localAPIMu.Lock()
+	defer a.localAPIMu.Unlock()
+
+	if a.localAPI != nil {
+		return nil
+	}
+	settings, err := settingsService.LoadSettings()
+	if err != nil {
+		return err
+	}
+	if !settings.LocalAPIEnabled {
+		return nil
+	}
+
+	changed := false
+	if settings.LocalAPIToken == "" {
+		token, err := generateLocalAPIToken()
+		if err != nil {
+			return err
+		}
+		settings.LocalAPIToken = token
+		changed = true
+	}
+
+	server := newLocalAPIServer(a, settings.LocalAPIToken)
+	port, err := server.start(settings.LocalAPIPort)
+	if err != nil {
+		return err
+	}
+	if port != settings.LocalAPIPort {
+		settings.LocalAPIPort = port
+		changed = true
+	}
+	if changed {
+		if err := settingsService.SaveSettings(settings); err != nil {
+			server.stop()
+			return err
+		}
+	}
+
+	a.localAPI = server
+	a.logger.Console("Local API listening on %s", server.baseURL())
+	return nil
+}
+
+// stopLocalAPI は起動中のサーバーを停止する
+func (a *App) stopLocalAPI() {
+	a.localAPIMu.Lock()
+	defer a.localAPIMu.Unlock()
+
+	if a.localAPI == nil {
+		return
+	}
+	if err := a.localAPI.stop(); err != nil {
+		a.logger.Console("Failed to stop local API: %v", err)
+	}
+	a.localAPI = nil
+}
+
+// attachLocalAPIEvents はロガーが発行するイベントをハブにも流す（ロガーを作り直したら呼び直す）
+func (a *App) attachLocalAPIEvents() {
+	if a.apiEvents == nil {
+		a.apiEvents = newLocalAPIEventHub()
+	}
+	a.logger.SetEventMirror(a.apiEvents.publish)
+}
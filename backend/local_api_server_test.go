@@ -0,0 +1,280 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupLocalAPITest はローカル API を有効にして起動した App を返す（Drive は未接続）
+func setupLocalAPITest(t *testing.T) (*appTestHelper, LocalAPIInfo) {
+	t.Helper()
+	helper := setupAppTest(t)
+	app := helper.app
+	app.driveService = nil
+	app.syncState = NewSyncState(app.appDataDir)
+	app.attachLocalAPIEvents()
+
+	info, err := app.SetLocalAPIEnabled(true)
+	require.NoError(t, err)
+	require.Empty(t, info.Error)
+	t.Cleanup(app.stopLocalAPI)
+	return helper, info
+}
+
+func localAPIRequest(t *testing.T, info LocalAPIInfo, method string, path string, body string) (*http.Response, []byte) {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, info.URL+path, reader)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+info.Token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, data
+}
+
+func TestLocalAPI_EnableDisableAndAuth(t *testing.T) {
+	helper, info := setupLocalAPITest(t)
+	defer helper.cleanup()
+	app := helper.app
+
+	assert.True(t, info.Enabled)
+	assert.True(t, info.Running)
+	assert.Len(t, info.Token, 64)
+	assert.True(t, strings.HasPrefix(info.URL, "http://127.0.0.1:"))
+
+	// トークンと選ばれたポートは設定に保存され、エディタ設定の保存では消えない
+	settings, err := app.settingsService.LoadSettings()
+	require.NoError(t, err)
+	assert.Equal(t, info.Token, settings.LocalAPIToken)
+	assert.NotZero(t, settings.LocalAPIPort)
+	require.NoError(t, app.SaveSettings(&Settings{FontSize: 16}))
+	settings, err = app.settingsService.LoadSettings()
+	require.NoError(t, err)
+	assert.True(t, settings.LocalAPIEnabled)
+	assert.Equal(t, info.Token, settings.LocalAPIToken)
+
+	// トークンが無い・違う場合は拒否する
+	resp, err := http.Get(info.URL + "/notes")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	wrong := info
+	wrong.Token = "wrong"
+	resp, _ = localAPIRequest(t, wrong, http.MethodGet, "/notes", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// ループバック以外の Host は拒否する
+	req, err := http.NewRequest(http.MethodGet, info.URL+"/notes", nil)
+	require.NoError(t, err)
+	req.Host = "attacker.example"
+	req.Header.Set("Authorization", "Bearer "+info.Token)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// トークンを作り直すと古いトークンは使えない
+	regenerated, err := app.RegenerateLocalAPIToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, info.Token, regenerated.Token)
+	resp, _ = localAPIRequest(t, info, http.MethodGet, "/notes", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp, _ = localAPIRequest(t, regenerated, http.MethodGet, "/notes", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	disabled, err := app.SetLocalAPIEnabled(false)
+	require.NoError(t, err)
+	assert.False(t, disabled.Running)
+	_, err = http.Get(regenerated.URL + "/notes")
+	assert.Error(t, err)
+}
+
+func TestLocalAPI_NotesAndFoldersMarkDirty(t *testing.T) {
+	helper, info := setupLocalAPITest(t)
+	defer helper.cleanup()
+	app := helper.app
+
+	resp, body := localAPIRequest(t, info, http.MethodPost, "/folders", `{"name":"work"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	var folder Folder
+	require.NoError(t, json.Unmarshal(body, &folder))
+
+	resp, body = localAPIRequest(t, info, http.MethodPost, "/notes",
+		`{"title":"memo","content":"hello api","tags":["auto"],"folderId":"`+folder.ID+`"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	var note Note
+	require.NoError(t, json.Unmarshal(body, &note))
+	assert.Equal(t, "plaintext", note.Language)
+	assert.Equal(t, folder.ID, note.FolderID)
+	assert.True(t, app.syncState.DirtyNoteIDs[note.ID])
+
+	// 更新は指定したフィールドだけを変える
+	app.syncState.ClearDirty("", nil)
+	require.False(t, app.syncState.DirtyNoteIDs[note.ID])
+	resp, body = localAPIRequest(t, info, http.MethodPatch, "/notes/"+note.ID, `{"content":"updated body"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	require.NoError(t, json.Unmarshal(body, &note))
+	assert.Equal(t, "memo", note.Title)
+	assert.Equal(t, "updated body", note.Content)
+	assert.Equal(t, folder.ID, note.FolderID)
+	assert.True(t, app.syncState.DirtyNoteIDs[note.ID])
+
+	resp, body = localAPIRequest(t, info, http.MethodGet, "/search?q=updated", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var hits []SearchHit
+	require.NoError(t, json.Unmarshal(body, &hits))
+	require.Len(t, hits, 1)
+	assert.Equal(t, note.ID, hits[0].NoteID)
+
+	resp, body = localAPIRequest(t, info, http.MethodGet, "/notes?folderId="+folder.ID, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var listed []NoteMetadata
+	require.NoError(t, json.Unmarshal(body, &listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, "memo", listed[0].Title)
+
+	// 空でないフォルダは削除できない
+	resp, _ = localAPIRequest(t, info, http.MethodDelete, "/folders/"+folder.ID, "")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, body = localAPIRequest(t, info, http.MethodPost, "/notes/"+note.ID+"/move", `{"folderId":""}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	resp, _ = localAPIRequest(t, info, http.MethodDelete, "/folders/"+folder.ID, "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, _ = localAPIRequest(t, info, http.MethodDelete, "/notes/"+note.ID, "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.True(t, app.syncState.Dirty)
+	require.Len(t, app.noteService.ListTrash(), 2)
+
+	resp, body = localAPIRequest(t, info, http.MethodGet, "/notes/"+note.ID, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Contains(t, string(body), "note not found")
+
+	resp, _ = localAPIRequest(t, info, http.MethodPost, "/notes", `{"unknown":1}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// 同期先に未接続では同期を開始できない (Google Drive に限らない文言で返す)
+	resp, body = localAPIRequest(t, info, http.MethodPost, "/sync", "")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Contains(t, string(body), "sync is not connected")
+}
+
+// API での更新も UI からの保存と同じく、リンクしたファイルにノートを優先して書き出す
+func TestLocalAPI_UpdateNoteWritesLinkedFile(t *testing.T) {
+	helper, info := setupLocalAPITest(t)
+	defer helper.cleanup()
+	app := helper.app
+	app.linkedFiles = newLinkedFileService(app.appDataDir, func(string, interface{}) {})
+
+	resp, body := localAPIRequest(t, info, http.MethodPost, "/notes", `{"title":"hosts","content":"127.0.0.1 localhost\n"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	var note Note
+	require.NoError(t, json.Unmarshal(body, &note))
+	path := filepath.Join(t.TempDir(), "hosts")
+	_, err := app.LinkNoteToFile(note.ID, path)
+	require.NoError(t, err)
+
+	// ファイルも外部で変わっていれば、API の内容で書き出してファイルの内容は競合バックアップに残す
+	writeExternally(t, path, []byte("edited outside\n"))
+	resp, body = localAPIRequest(t, info, http.MethodPatch, "/notes/"+note.ID, `{"content":"edited by api\n"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "edited by api\n", string(data))
+	backups, err := app.ListCloudConflictBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, "edited outside\n", backups[0].Note.Content)
+
+	// 移動先のフォルダが無ければ何も変えない
+	resp, _ = localAPIRequest(t, info, http.MethodPatch, "/notes/"+note.ID, `{"content":"lost","folderId":"missing"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	loaded, err := app.noteService.LoadNote(note.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "edited by api\n", loaded.Content)
+	resp, _ = localAPIRequest(t, info, http.MethodPost, "/notes", `{"title":"orphan","folderId":"missing"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Len(t, app.noteService.noteList.Notes, 1)
+}
+
+func TestLocalAPIStatusForError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{fmt.Errorf("%w: abc", errNoteNotFound), http.StatusNotFound},
+		{fmt.Errorf("failed to move: %w", fmt.Errorf("%w: abc", errFolderNotFound)), http.StatusNotFound},
+		{fmt.Errorf("read note: %w", os.ErrNotExist), http.StatusNotFound},
+		{errFolderNotEmpty, http.StatusConflict},
+		{fmt.Errorf("%w: abc", errFolderArchived), http.StatusConflict},
+		{fmt.Errorf("%w: abc", errFolderIntoDescendant), http.StatusConflict},
+		// 文言だけが似ているエラーでステータスを変えない
+		{fmt.Errorf("backup manifest not found"), http.StatusBadRequest},
+		{fmt.Errorf("folder name is empty"), http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, localAPIStatusForError(c.err), c.err.Error())
+	}
+}
+
+// ロガーがフロントエンドに送るイベントが SSE にも流れること
+func TestLocalAPI_EventsMirrorLogger(t *testing.T) {
+	helper, info := setupLocalAPITest(t)
+	defer helper.cleanup()
+	app := helper.app
+
+	req, err := http.NewRequest(http.MethodGet, info.URL+"/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+info.Token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	lines := make(chan string, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	require.Equal(t, ": connected", <-lines)
+
+	app.logger.NotifyDriveStatus(app.ctx.ctx, "syncing")
+
+	var received []string
+	timeout := time.After(5 * time.Second)
+	for len(received) < 2 {
+		select {
+		case line, ok := <-lines:
+			require.True(t, ok, "stream closed")
+			if line != "" {
+				received = append(received, line)
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for event, got %v", received)
+		}
+	}
+	assert.Equal(t, []string{"event: drive:status", `data: "syncing"`}, received)
+}
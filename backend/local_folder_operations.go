@@ -0,0 +1,377 @@
+package backend
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// ------------------------------------------------------------
+// ローカルフォルダ版 DriveOperations
+// ------------------------------------------------------------
+//
+// 任意のディレクトリ (Syncthing の共有フォルダ、SMB マウント、USB メモリなど) を
+// 同期先として扱う。同期先ルートからの相対パスをIDとして扱う（path_operations.go 参照）。
+// 変更検出は monaco-notepad 以下を走査し、内容の MD5 のスナップショットを比較する。
+// MD5 は更新日時とサイズが変わったファイルだけ計算し直す。
+
+// localFolderOperations はローカルのディレクトリ上で DriveOperations を実装する
+type localFolderOperations struct {
+	root   string
+	logger AppLogger
+
+	mu        sync.Mutex
+	checksums map[string]localFolderChecksum // ID → 計算済みの MD5
+
+	snapshots pathSnapshots
+}
+
+type localFolderChecksum struct {
+	modified time.Time
+	size     int64
+	sum      string
+}
+
+// ローカルフォルダ用の DriveOperations を作成
+func NewLocalFolderOperations(config LocalFolderConfig, logger AppLogger) (*localFolderOperations, error) {
+	root := strings.TrimSpace(config.Path)
+	if root == "" || !filepath.IsAbs(root) {
+		return nil, fmt.Errorf("invalid sync folder: path must be absolute")
+	}
+	return &localFolderOperations{
+		root:      filepath.Clean(root),
+		logger:    logger,
+		checksums: make(map[string]localFolderChecksum),
+	}, nil
+}
+
+// resolve はIDから実際のパスを作る。同期先の外を指すIDは拒否する
+func (l *localFolderOperations) resolve(id string) (string, error) {
+	id = pathCleanID(id)
+	if id == "" {
+		return l.root, nil
+	}
+	rel := filepath.FromSlash(id)
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("invalid file id: %s", id)
+	}
+	return filepath.Join(l.root, rel), nil
+}
+
+// isLocalFolderIgnored は同期対象外のファイルか判定する。
+// 書き込み途中の一時ファイル、隠しファイル (Syncthing の .stfolder など)、
+// Syncthing の競合コピーは noteList に無いノートとして取り込まれないよう除外する。
+func isLocalFolderIgnored(name string) bool {
+	return strings.HasPrefix(name, ".") ||
+		strings.HasPrefix(name, "~") ||
+		strings.HasSuffix(name, ".tmp") ||
+		strings.Contains(name, ".sync-conflict-")
+}
+
+// localFolderNotFoundError は isDriveNotFoundError で判定できるよう "not found" を含める
+func localFolderNotFoundError(id string, err error) error {
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s: file not found", id)
+	}
+	return err
+}
+
+// entry はファイル情報から pathEntry を作る
+func (l *localFolderOperations) entry(id string, info fs.FileInfo) (pathEntry, error) {
+	entry := pathEntry{
+		id:       id,
+		isDir:    info.IsDir(),
+		modified: info.ModTime(),
+		size:     info.Size(),
+	}
+	if entry.isDir {
+		entry.version = "dir"
+		entry.size = 0
+		return entry, nil
+	}
+
+	l.mu.Lock()
+	cached, ok := l.checksums[id]
+	l.mu.Unlock()
+	if ok && cached.modified.Equal(entry.modified) && cached.size == entry.size {
+		entry.version = cached.sum
+		return entry, nil
+	}
+
+	fullPath, err := l.resolve(id)
+	if err != nil {
+		return pathEntry{}, err
+	}
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return pathEntry{}, localFolderNotFoundError(id, err)
+	}
+	sum := md5.Sum(content)
+	entry.version = hex.EncodeToString(sum[:])
+
+	l.mu.Lock()
+	l.checksums[id] = localFolderChecksum{modified: entry.modified, size: entry.size, sum: entry.version}
+	l.mu.Unlock()
+	return entry, nil
+}
+
+func (l *localFolderOperations) stat(id string) (pathEntry, error) {
+	fullPath, err := l.resolve(id)
+	if err != nil {
+		return pathEntry{}, err
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return pathEntry{}, localFolderNotFoundError(id, err)
+	}
+	return l.entry(pathCleanID(id), info)
+}
+
+// list は id 直下のエントリを返す
+func (l *localFolderOperations) list(id string) ([]pathEntry, error) {
+	fullPath, err := l.resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	dirEntries, err := os.ReadDir(fullPath)
+	if err != nil {
+		return nil, localFolderNotFoundError(id, err)
+	}
+	entries := make([]pathEntry, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if isLocalFolderIgnored(dirEntry.Name()) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			// 走査中に削除されたファイルは無視する
+			continue
+		}
+		entry, err := l.entry(pathJoinID(id, dirEntry.Name()), info)
+		if err != nil {
+			if isDriveNotFoundError(err) {
+				continue
+			}
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ------------------------------------------------------------
+// ファイル操作
+// ------------------------------------------------------------
+
+// 接続確認 (同期先フォルダが存在するか) ------------------------------------------------------------
+// USB メモリの取り外しやマウント解除で消えた場合にローカルディスクへ書き込まないよう、
+// 同期先フォルダ自体は作成しない。
+func (l *localFolderOperations) Ping() error {
+	info, err := os.Stat(l.root)
+	if err != nil {
+		return fmt.Errorf("failed to access sync folder: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("failed to access sync folder: %s is not a directory", l.root)
+	}
+	return nil
+}
+
+// ファイルを作成 ------------------------------------------------------------
+func (l *localFolderOperations) CreateFile(name string, content []byte, parentID string, mimeType string) (string, error) {
+	id := pathJoinID(parentID, name)
+	l.logger.Console("[Folder] Creating file: %s", id)
+	if err := l.write(id, content); err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	return id, nil
+}
+
+// ファイルを更新 ------------------------------------------------------------
+func (l *localFolderOperations) UpdateFile(fileID string, content []byte) error {
+	l.logger.Console("[Folder] Updating file: %s", fileID)
+	if err := l.write(fileID, content); err != nil {
+		return fmt.Errorf("failed to update file: %w", err)
+	}
+	return nil
+}
+
+// write は writeFileAtomic で一時ファイルに書き込み、fsync してから rename する。
+// 同期ツールや他の端末が書き込み途中のファイルを読まないようにするため。
+// 一時ファイルは書き込みごとに別の名前 (隠しファイル) になるので、共有フォルダで同時に書いても互いに上書きしない。
+func (l *localFolderOperations) write(id string, content []byte) error {
+	fullPath, err := l.resolve(id)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Dir(fullPath)); err != nil {
+		return localFolderNotFoundError(id, err)
+	}
+	return writeFileAtomic(fullPath, content)
+}
+
+// ファイル・フォルダを削除 ------------------------------------------------------------
+func (l *localFolderOperations) DeleteFile(fileID string) error {
+	l.logger.Console("[Folder] Deleting file: %s", fileID)
+	fullPath, err := l.resolve(fileID)
+	if err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	if fullPath == l.root {
+		return fmt.Errorf("failed to delete file: refusing to delete the sync folder itself")
+	}
+	if _, err := os.Lstat(fullPath); err != nil {
+		return fmt.Errorf("failed to delete file: %w", localFolderNotFoundError(fileID, err))
+	}
+	if err := os.RemoveAll(fullPath); err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// ファイルを読み込み ------------------------------------------------------------
+func (l *localFolderOperations) DownloadFile(fileID string) ([]byte, error) {
+	l.logger.Console("[Folder] Reading file: %s", fileID)
+	fullPath, err := l.resolve(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", localFolderNotFoundError(fileID, err))
+	}
+	return content, nil
+}
+
+func (l *localFolderOperations) GetFileMetadata(fileID string) (*drive.File, error) {
+	l.logger.Console("[Folder] Getting metadata: %s", fileID)
+	entry, err := l.stat(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file metadata: %w", err)
+	}
+	return entry.driveFile(), nil
+}
+
+// フォルダを作成 (既にあればそのまま使う) ------------------------------------------------------------
+func (l *localFolderOperations) CreateFolder(name string, parentID string) (string, error) {
+	id := pathJoinID(parentID, name)
+	l.logger.Console("[Folder] Creating folder: %s", id)
+	fullPath, err := l.resolve(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to create folder: %w", err)
+	}
+	if err := os.Mkdir(fullPath, 0755); err != nil && !os.IsExist(err) {
+		return "", fmt.Errorf("failed to create folder: %w", localFolderNotFoundError(id, err))
+	}
+	return id, nil
+}
+
+// Drive のクエリ文字列でファイルを検索 ------------------------------------------------------------
+func (l *localFolderOperations) ListFiles(query string) ([]*drive.File, error) {
+	l.logger.Console("[Folder] Listing files: %s", query)
+	q := parsePathFileQuery(query)
+	entries, err := l.list(q.parentID)
+	if err != nil {
+		if isDriveNotFoundError(err) {
+			return []*drive.File{}, nil
+		}
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	files := make([]*drive.File, 0, len(entries))
+	for _, entry := range entries {
+		if q.matches(entry) {
+			files = append(files, entry.driveFile())
+		}
+	}
+	return files, nil
+}
+
+// ファイル名からファイルIDを取得 (パスがIDなので存在確認だけ行う) ------------------------------------------------------------
+func (l *localFolderOperations) GetFileID(fileName string, noteFolderID string, rootFolderID string) (string, error) {
+	id, err := pathFileIDFor(fileName, noteFolderID, rootFolderID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := l.stat(id); err != nil {
+		if isDriveNotFoundError(err) {
+			return "", pathFileNotFoundError(fileName, noteFolderID, rootFolderID)
+		}
+		return "", err
+	}
+	return id, nil
+}
+
+func (l *localFolderOperations) FindLatestFile(files []*drive.File) *drive.File {
+	return findLatestDriveFile(files)
+}
+
+// 重複ファイルの整理 (パスがIDのため通常は重複しない) ------------------------------------------------------------
+func (l *localFolderOperations) CleanupDuplicates(files []*drive.File, keepLatest bool) error {
+	if len(files) <= 1 {
+		return nil
+	}
+	targetFiles := files
+	if keepLatest {
+		targetFiles = files[1:]
+	}
+	for _, file := range targetFiles {
+		if err := l.DeleteFile(file.Id); err != nil {
+			return fmt.Errorf("failed to delete file %s: %w", file.Name, err)
+		}
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+// 変更検出 (Changes API の代替)
+// ------------------------------------------------------------
+
+// 現在のスナップショットを記録し、そのトークンを返す ------------------------------------------------------------
+func (l *localFolderOperations) GetStartPageToken() (string, error) {
+	snapshot, err := l.snapshot()
+	if err != nil {
+		return "", fmt.Errorf("failed to get start page token: %w", err)
+	}
+	return l.snapshots.store(snapshot), nil
+}
+
+// トークンの時点から変わったファイルを返す ------------------------------------------------------------
+func (l *localFolderOperations) ListChanges(pageToken string) (*ChangesResult, error) {
+	return l.snapshots.listChanges(pageToken, l.snapshot)
+}
+
+// snapshot は monaco-notepad フォルダ以下のファイルを MD5 付きで集める
+func (l *localFolderOperations) snapshot() (map[string]pathEntry, error) {
+	if err := l.Ping(); err != nil {
+		return nil, err
+	}
+	snapshot := make(map[string]pathEntry)
+	queue := []string{pathRootFolderName}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		entries, err := l.list(id)
+		if err != nil {
+			if isDriveNotFoundError(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, entry := range entries {
+			snapshot[entry.id] = entry
+			if entry.isDir {
+				queue = append(queue, entry.id)
+			}
+		}
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,227 @@
+package backend
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLocalFolderOperations(t *testing.T, root string) *localFolderOperations {
+	t.Helper()
+	ops, err := NewLocalFolderOperations(LocalFolderConfig{Path: root}, NewAppLogger(context.Background(), true, t.TempDir()))
+	require.NoError(t, err)
+	return ops
+}
+
+func TestLocalFolderOperations_FilesAndQueries(t *testing.T) {
+	root := t.TempDir()
+	ops := newTestLocalFolderOperations(t, root)
+	require.NoError(t, ops.Ping())
+
+	_, err := NewLocalFolderOperations(LocalFolderConfig{Path: "relative/dir"}, ops.logger)
+	assert.Error(t, err)
+	assert.Error(t, newTestLocalFolderOperations(t, filepath.Join(root, "missing")).Ping())
+
+	rootID, err := ops.CreateFolder("monaco-notepad", "appDataFolder")
+	require.NoError(t, err)
+	notesID, err := ops.CreateFolder("notes", rootID)
+	require.NoError(t, err)
+	assert.Equal(t, "monaco-notepad/notes", notesID)
+	_, err = ops.CreateFolder("notes", rootID)
+	require.NoError(t, err)
+
+	noteID, err := ops.CreateFile("n1.json", []byte(`{"id":"n1"}`), notesID, "application/json")
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(root, "monaco-notepad", "notes", "n1.json"))
+	assert.NoFileExists(t, filepath.Join(root, "monaco-notepad", "notes", "n1.json.tmp"))
+	entries, err := os.ReadDir(filepath.Join(root, "monaco-notepad", "notes"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "no temp files are left behind")
+
+	// 同期ツールの一時ファイルや競合コピーは一覧に出さない
+	notesDir := filepath.Join(root, "monaco-notepad", "notes")
+	require.NoError(t, os.WriteFile(filepath.Join(notesDir, ".syncthing.n2.json.tmp"), []byte("x"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(notesDir, "n1.sync-conflict-20250101-000000-ABCDEFG.json"), []byte("x"), 0644))
+
+	files, err := ops.ListFiles("'" + notesID + "' in parents and trashed=false")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, noteID, files[0].Id)
+	assert.Equal(t, []string{notesID}, files[0].Parents)
+	sum := md5.Sum([]byte(`{"id":"n1"}`))
+	assert.Equal(t, hex.EncodeToString(sum[:]), files[0].Md5Checksum)
+
+	folders, err := ops.ListFiles("name='monaco-notepad' and mimeType='application/vnd.google-apps.folder' and trashed=false")
+	require.NoError(t, err)
+	require.Len(t, folders, 1)
+	assert.Equal(t, rootID, folders[0].Id)
+
+	require.NoError(t, ops.UpdateFile(noteID, []byte(`{"id":"n1","title":"updated"}`)))
+	content, err := ops.DownloadFile(noteID)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":"n1","title":"updated"}`, string(content))
+
+	id, err := ops.GetFileID("n1.json", notesID, rootID)
+	require.NoError(t, err)
+	assert.Equal(t, noteID, id)
+	_, err = ops.GetFileID("n2.json", notesID, rootID)
+	assert.ErrorContains(t, err, "note file n2.json not found")
+
+	// 同期先の外を指すIDは拒否する
+	_, err = ops.DownloadFile("../outside.json")
+	assert.ErrorContains(t, err, "invalid file id")
+	assert.Error(t, ops.DeleteFile(""))
+
+	require.NoError(t, ops.DeleteFile(noteID))
+	_, err = ops.DownloadFile(noteID)
+	assert.True(t, isDriveNotFoundError(err))
+	assert.True(t, isDriveNotFoundError(ops.DeleteFile(noteID)))
+}
+
+// 内容の MD5 のスナップショット比較で追加・更新・削除を検出すること
+// 共有フォルダの同じファイルに 2 台が同時に書いても、一時ファイルを取り合わずにどちらかの内容がそのまま残る
+func TestLocalFolderOperations_ConcurrentWritesToSameFile(t *testing.T) {
+	root := t.TempDir()
+	first := newTestLocalFolderOperations(t, root)
+	second := newTestLocalFolderOperations(t, root)
+	id, err := first.CreateFile("noteList_v2.json", []byte("{}"), "", "application/json")
+	require.NoError(t, err)
+
+	contents := []string{strings.Repeat("a", 64*1024), strings.Repeat("b", 64*1024)}
+	var wg sync.WaitGroup
+	for i, ops := range []*localFolderOperations{first, second} {
+		wg.Add(1)
+		go func(ops *localFolderOperations, content string) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				assert.NoError(t, ops.UpdateFile(id, []byte(content)))
+			}
+		}(ops, contents[i])
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(filepath.Join(root, "noteList_v2.json"))
+	require.NoError(t, err)
+	assert.Contains(t, contents, string(data))
+	entries, err := os.ReadDir(root)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temp files are left behind")
+}
+
+func TestLocalFolderOperations_ListChanges(t *testing.T) {
+	root := t.TempDir()
+	ops := newTestLocalFolderOperations(t, root)
+	rootID, err := ops.CreateFolder("monaco-notepad", "")
+	require.NoError(t, err)
+	notesID, err := ops.CreateFolder("notes", rootID)
+	require.NoError(t, err)
+	keepID, err := ops.CreateFile("keep.json", []byte("keep"), notesID, "application/json")
+	require.NoError(t, err)
+	goneID, err := ops.CreateFile("gone.json", []byte("gone"), notesID, "application/json")
+	require.NoError(t, err)
+
+	token, err := ops.GetStartPageToken()
+	require.NoError(t, err)
+	result, err := ops.ListChanges(token)
+	require.NoError(t, err)
+	assert.Empty(t, result.Changes)
+
+	// 別の端末（同期ツール経由）からの変更
+	notesDir := filepath.Join(root, "monaco-notepad", "notes")
+	require.NoError(t, os.WriteFile(filepath.Join(notesDir, "keep.json"), []byte("keep, but longer"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(notesDir, "gone.json")))
+	require.NoError(t, os.WriteFile(filepath.Join(notesDir, "added.json"), []byte("added"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(notesDir, "added.json.tmp"), []byte("partial"), 0644))
+
+	result, err = ops.ListChanges(token)
+	require.NoError(t, err)
+	byID := map[string]bool{}
+	for _, change := range result.Changes {
+		byID[change.FileId] = change.Removed
+	}
+	assert.Equal(t, map[string]bool{keepID: false, goneID: true, notesID + "/added.json": false}, byID)
+	assert.True(t, hasRelevantChanges(result.Changes, rootID, notesID))
+
+	result, err = ops.ListChanges(result.NewStartToken)
+	require.NoError(t, err)
+	assert.Empty(t, result.Changes)
+
+	// 同期先が外れた（USB メモリの取り外しなど）場合はエラーにする
+	require.NoError(t, os.RemoveAll(root))
+	_, err = ops.ListChanges(result.NewStartToken)
+	assert.Error(t, err)
+}
+
+// 2 台の端末が共有フォルダ経由でノートを同期し、競合時はバックアップを残すこと
+func TestLocalFolderSync_TwoDevicesWithConflictBackup(t *testing.T) {
+	shared := t.TempDir()
+	connect := func(ds *driveService) error { return ds.ConnectLocalFolder(LocalFolderConfig{Path: shared}) }
+	deviceA := newProviderTestDriveService(t, connect)
+	deviceB := newProviderTestDriveService(t, connect)
+
+	note := &Note{ID: "folder-note", Title: "shared", Content: "hello from A", Language: "plaintext"}
+	require.NoError(t, deviceA.noteService.SaveNote(note))
+	deviceA.syncState.MarkNoteDirty(note.ID)
+	require.NoError(t, deviceA.SyncNotes())
+	assert.FileExists(t, filepath.Join(shared, "monaco-notepad", "notes", note.ID+".json"))
+
+	require.NoError(t, deviceB.SyncNotes())
+	received, err := deviceB.noteService.LoadNote(note.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "hello from A", received.Content)
+
+	// B がオフラインで古い編集を持ったまま、A の新しい編集が先に同期される
+	require.NoError(t, deviceB.noteService.SaveNoteFromSync(&Note{
+		ID:           note.ID,
+		Title:        "shared",
+		Content:      "stale edit on B",
+		Language:     "plaintext",
+		ModifiedTime: "2025-01-01T00:00:00Z",
+	}))
+	deviceB.syncState.MarkNoteDirty(note.ID)
+
+	note.Content = "newer edit on A"
+	require.NoError(t, deviceA.noteService.SaveNote(note))
+	deviceA.syncState.MarkNoteDirty(note.ID)
+	require.NoError(t, deviceA.SyncNotes())
+
+	require.NoError(t, deviceB.SyncNotes())
+	updated, err := deviceB.noteService.LoadNote(note.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "newer edit on A", updated.Content)
+
+	entries, err := os.ReadDir(filepath.Join(deviceB.appDataDir, cloudWinBackupDirName))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	data, err := os.ReadFile(filepath.Join(deviceB.appDataDir, cloudWinBackupDirName, entries[0].Name()))
+	require.NoError(t, err)
+	var backup cloudWinBackupRecord
+	require.NoError(t, json.Unmarshal(data, &backup))
+	require.NotNil(t, backup.LocalNote)
+	assert.Equal(t, "stale edit on B", backup.LocalNote.Content)
+}
+
+// 存在しないフォルダには接続せず、設定も保存しないこと
+func TestConnectLocalFolder_MissingFolder(t *testing.T) {
+	ds := newProviderTestDriveService(t, func(ds *driveService) error {
+		return ds.ConnectLocalFolder(LocalFolderConfig{Path: t.TempDir()})
+	})
+	require.NoError(t, ds.LogoutDrive())
+
+	err := ds.ConnectLocalFolder(LocalFolderConfig{Path: filepath.Join(t.TempDir(), "unmounted")})
+	assert.Error(t, err)
+	assert.False(t, ds.IsConnected())
+	assert.False(t, ds.usesPathProvider())
+	config, err := loadLocalFolderConfig(ds.appDataDir)
+	require.NoError(t, err)
+	assert.Nil(t, config)
+}
@@ -0,0 +1,264 @@
+package backend
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// ------------------------------------------------------------
+// 同期しないノート・フォルダ（この端末だけに置く）
+// ------------------------------------------------------------
+//
+// Note / NoteMetadata / Folder の LocalOnly で表す。フォルダに付けた印は配下のフォルダと
+// ノートすべてに及ぶ。ノート自身の印はノートファイルにも書き、noteList が失われて
+// 孤立ファイルから復元したときも同期しないままにする。
+// - クラウドへ上げる noteList からは取り除く (cloudNoteListFrom)。
+// - クラウドの noteList を取り込むときは、手元のものを残す (restoreLocalOnlyItems)。
+// - 以前に同期したノートに印を付けると、次の同期でクラウドから消す (ローカルには残す)。
+
+// localOnlyIDs は同期しないノートとフォルダの ID を返す (ゴミ箱の中のものも含む)
+func localOnlyIDs(list *NoteList) (noteIDs map[string]bool, folderIDs map[string]bool) {
+	noteIDs = make(map[string]bool)
+	folderIDs = make(map[string]bool)
+	if list == nil {
+		return noteIDs, folderIDs
+	}
+
+	folders := make(map[string]Folder, len(list.Folders))
+	for _, folder := range list.Folders {
+		folders[folder.ID] = folder
+	}
+	notes := list.Notes
+	for _, item := range list.Trash {
+		for _, folder := range item.Folders {
+			if _, exists := folders[folder.ID]; !exists {
+				folders[folder.ID] = folder
+			}
+		}
+		notes = append(slices.Clip(notes), item.Notes...)
+	}
+
+	for id := range folders {
+		// 親をたどって印の付いたフォルダがあれば同期しない。循環していてもフォルダ数より深くはならない
+		current := id
+		for steps := 0; current != "" && steps <= len(folders); steps++ {
+			folder, exists := folders[current]
+			if !exists {
+				break
+			}
+			if folder.LocalOnly {
+				folderIDs[id] = true
+				break
+			}
+			current = folder.ParentID
+		}
+	}
+	for _, metadata := range notes {
+		if metadata.LocalOnly || (metadata.FolderID != "" && folderIDs[metadata.FolderID]) {
+			noteIDs[metadata.ID] = true
+		}
+	}
+	return noteIDs, folderIDs
+}
+
+// isLocalOnlyOrderItem は並び順の項目が同期しないノート・フォルダを指すかを返す
+func isLocalOnlyOrderItem(item TopLevelItem, noteIDs map[string]bool, folderIDs map[string]bool) bool {
+	return (item.Type == "note" && noteIDs[item.ID]) || (item.Type == "folder" && folderIDs[item.ID])
+}
+
+// isLocalOnlyTrashItem はゴミ箱の項目が同期しないノート・フォルダかを返す
+func isLocalOnlyTrashItem(item TrashItem, noteIDs map[string]bool, folderIDs map[string]bool) bool {
+	return (item.Type == trashItemNote && noteIDs[item.ID]) || (item.Type == trashItemFolder && folderIDs[item.ID])
+}
+
+// cloudNoteListFrom はクラウドへ上げる noteList (同期しないノート・フォルダを取り除いたもの) を返す。
+// list は変更しない。
+func cloudNoteListFrom(list *NoteList) *NoteList {
+	if list == nil {
+		return nil
+	}
+	noteIDs, folderIDs := localOnlyIDs(list)
+	if len(noteIDs) == 0 && len(folderIDs) == 0 {
+		return list
+	}
+
+	cloud := &NoteList{Version: list.Version}
+	for _, metadata := range list.Notes {
+		if !noteIDs[metadata.ID] {
+			cloud.Notes = append(cloud.Notes, metadata)
+		}
+	}
+	if cloud.Notes == nil {
+		cloud.Notes = []NoteMetadata{}
+	}
+	for _, folder := range list.Folders {
+		if !folderIDs[folder.ID] {
+			cloud.Folders = append(cloud.Folders, folder)
+		}
+	}
+	for _, item := range list.TopLevelOrder {
+		if !isLocalOnlyOrderItem(item, noteIDs, folderIDs) {
+			cloud.TopLevelOrder = append(cloud.TopLevelOrder, item)
+		}
+	}
+	for _, item := range list.ArchivedTopLevelOrder {
+		if !isLocalOnlyOrderItem(item, noteIDs, folderIDs) {
+			cloud.ArchivedTopLevelOrder = append(cloud.ArchivedTopLevelOrder, item)
+		}
+	}
+	for _, id := range list.CollapsedFolderIDs {
+		if !folderIDs[id] {
+			cloud.CollapsedFolderIDs = append(cloud.CollapsedFolderIDs, id)
+		}
+	}
+	for _, item := range list.Trash {
+		if isLocalOnlyTrashItem(item, noteIDs, folderIDs) {
+			continue
+		}
+		// 同期するフォルダの中で、ノート単体に印を付けていたものは取り除く
+		item.Notes = slices.DeleteFunc(slices.Clone(item.Notes), func(metadata NoteMetadata) bool { return noteIDs[metadata.ID] })
+		cloud.Trash = append(cloud.Trash, item)
+	}
+	return cloud
+}
+
+// restoreLocalOnlyItems はクラウドから取り込んだ noteList (target) に、取り込む前の local にあった
+// 同期しないノート・フォルダを戻す。クラウド側に同じ ID のものがあっても手元のものを優先する。
+func restoreLocalOnlyItems(local *NoteList, target *NoteList) {
+	noteIDs, folderIDs := localOnlyIDs(local)
+	if len(noteIDs) == 0 && len(folderIDs) == 0 {
+		return
+	}
+
+	notes := slices.DeleteFunc(slices.Clone(target.Notes), func(metadata NoteMetadata) bool { return noteIDs[metadata.ID] })
+	for _, metadata := range local.Notes {
+		if noteIDs[metadata.ID] {
+			notes = append(notes, metadata)
+		}
+	}
+	target.Notes = notes
+
+	folders := slices.DeleteFunc(slices.Clone(target.Folders), func(folder Folder) bool { return folderIDs[folder.ID] })
+	for _, folder := range local.Folders {
+		if folderIDs[folder.ID] {
+			folders = append(folders, folder)
+		}
+	}
+	target.Folders = folders
+
+	isLocalOnly := func(item TopLevelItem) bool { return isLocalOnlyOrderItem(item, noteIDs, folderIDs) }
+	target.TopLevelOrder = restoreLocalOnlyOrder(local.TopLevelOrder, target.TopLevelOrder, isLocalOnly)
+	target.ArchivedTopLevelOrder = restoreLocalOnlyOrder(local.ArchivedTopLevelOrder, target.ArchivedTopLevelOrder, isLocalOnly)
+
+	collapsed := slices.DeleteFunc(slices.Clone(target.CollapsedFolderIDs), func(id string) bool { return folderIDs[id] })
+	for _, id := range local.CollapsedFolderIDs {
+		if folderIDs[id] {
+			collapsed = append(collapsed, id)
+		}
+	}
+	target.CollapsedFolderIDs = collapsed
+
+	trash := slices.DeleteFunc(cloneTrashItems(target.Trash), func(item TrashItem) bool {
+		return isLocalOnlyTrashItem(item, noteIDs, folderIDs)
+	})
+	for _, item := range local.Trash {
+		if isLocalOnlyTrashItem(item, noteIDs, folderIDs) {
+			trash = append(trash, item)
+		}
+	}
+	target.Trash = trash
+}
+
+// restoreLocalOnlyOrder は target から同期しない項目を外したうえで、local の並び順にある
+// 同期しない項目を、local で直前にあった項目の後ろへ入れ直す
+func restoreLocalOnlyOrder(local []TopLevelItem, target []TopLevelItem, isLocalOnly func(TopLevelItem) bool) []TopLevelItem {
+	result := slices.DeleteFunc(slices.Clone(target), isLocalOnly)
+	for i, item := range local {
+		if !isLocalOnly(item) {
+			continue
+		}
+		position := 0
+		for j := i - 1; j >= 0; j-- {
+			if index := topLevelItemIndex(result, local[j]); index >= 0 {
+				position = index + 1
+				break
+			}
+		}
+		result = slices.Insert(result, position, item)
+	}
+	return result
+}
+
+// 同期しないノートとフォルダの ID を返す ------------------------------------------------------------
+func (s *noteService) LocalOnlyIDs() (noteIDs map[string]bool, folderIDs map[string]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return localOnlyIDs(s.noteList)
+}
+
+// クラウドへ上げる noteList のスナップショットを返す ------------------------------------------------------------
+func (s *noteService) SnapshotCloudNoteList() *NoteList {
+	return cloudNoteListFrom(s.SnapshotNoteList())
+}
+
+// ノートを同期しない（この端末だけに置く）かどうかを切り替える ------------------------------------------------------------
+func (s *noteService) SetNoteLocalOnly(noteID string, localOnly bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := slices.IndexFunc(s.noteList.Notes, func(metadata NoteMetadata) bool { return metadata.ID == noteID })
+	if index < 0 {
+		return fmt.Errorf("%w: %s", errNoteNotFound, noteID)
+	}
+	note, err := s.loadNoteLocked(noteID)
+	if err != nil {
+		return fmt.Errorf("failed to load note %s: %w", noteID, err)
+	}
+	updated := *note
+	updated.LocalOnly = localOnly
+	// noteList が失われても印が残るよう、ノートファイルにも書く
+	if err := s.saveNoteFromSyncLocked(&updated); err != nil {
+		return err
+	}
+	s.noteList.Notes[index].LocalOnly = localOnly
+	return s.saveNoteList()
+}
+
+// フォルダ（配下のフォルダとノートを含む）を同期しないかどうかを切り替える ------------------------------------------------------------
+func (s *noteService) SetFolderLocalOnly(folderID string, localOnly bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := slices.IndexFunc(s.noteList.Folders, func(folder Folder) bool { return folder.ID == folderID })
+	if index < 0 {
+		return fmt.Errorf("%w: %s", errFolderNotFound, folderID)
+	}
+	s.noteList.Folders[index].LocalOnly = localOnly
+	return s.saveNoteList()
+}
+
+// removeLocalOnlyNotesFromCloud は inCloud が true を返す同期しないノートをクラウドから消し
+// (ローカルには残す)、失敗した件数を返す
+func (s *driveService) removeLocalOnlyNotesFromCloud(localOnlyNoteIDs map[string]bool, inCloud func(id string) bool) int {
+	ids := make([]string, 0, len(localOnlyNoteIDs))
+	for id := range localOnlyNoteIDs {
+		if inCloud(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	failures := 0
+	for _, id := range ids {
+		s.logger.InfoCode(MsgDriveSyncDeleteNote, map[string]interface{}{"noteId": id})
+		if err := s.driveSync.DeleteNote(s.ctx, id); err != nil && !isDriveNotFoundError(err) {
+			s.logger.ErrorCode(err, MsgDriveErrorDeleteNote, map[string]interface{}{"noteId": id})
+			s.recordSyncAction(syncActionFailed, id, "", "failed to remove local-only note from cloud: "+err.Error())
+			failures++
+			continue
+		}
+		s.recordSyncAction(syncActionDeleted, id, "", "marked local-only on this device; removed from cloud and kept locally")
+	}
+	return failures
+}
@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudNoteListFromAndRestoreLocalOnlyItems(t *testing.T) {
+	local := &NoteList{
+		Version: CurrentVersion,
+		Notes: []NoteMetadata{
+			{ID: "shared"},
+			{ID: "secret", LocalOnly: true},
+			{ID: "scratch", FolderID: "child"},
+		},
+		Folders: []Folder{
+			{ID: "private", LocalOnly: true},
+			{ID: "child", ParentID: "private"},
+			{ID: "public"},
+		},
+		TopLevelOrder: []TopLevelItem{
+			{Type: "note", ID: "shared"},
+			{Type: "folder", ID: "private"},
+			{Type: "note", ID: "secret"},
+			{Type: "folder", ID: "public"},
+		},
+		CollapsedFolderIDs: []string{"private", "public"},
+		Trash: []TrashItem{
+			{ID: "trashed-secret", Type: trashItemNote, Notes: []NoteMetadata{{ID: "trashed-secret", FolderID: "private"}}},
+			{ID: "trashed-shared", Type: trashItemNote, Notes: []NoteMetadata{{ID: "trashed-shared"}}},
+		},
+	}
+
+	noteIDs, folderIDs := localOnlyIDs(local)
+	assert.Equal(t, map[string]bool{"secret": true, "scratch": true, "trashed-secret": true}, noteIDs)
+	assert.Equal(t, map[string]bool{"private": true, "child": true}, folderIDs)
+
+	cloud := cloudNoteListFrom(local)
+	assert.Equal(t, []NoteMetadata{{ID: "shared"}}, cloud.Notes)
+	assert.Equal(t, []Folder{{ID: "public"}}, cloud.Folders)
+	assert.Equal(t, []TopLevelItem{{Type: "note", ID: "shared"}, {Type: "folder", ID: "public"}}, cloud.TopLevelOrder)
+	assert.Equal(t, []string{"public"}, cloud.CollapsedFolderIDs)
+	require.Len(t, cloud.Trash, 1)
+	assert.Equal(t, "trashed-shared", cloud.Trash[0].ID)
+	// 元の noteList は変えない
+	assert.Len(t, local.Notes, 3)
+
+	// 他の端末がノートを追加したクラウドの noteList を取り込んでも、同期しないものは元の位置に残る
+	pulled := cloudNoteListFrom(local)
+	pulled.Notes = append(pulled.Notes, NoteMetadata{ID: "from-cloud"})
+	pulled.TopLevelOrder = append([]TopLevelItem{{Type: "note", ID: "from-cloud"}}, pulled.TopLevelOrder...)
+	restoreLocalOnlyItems(local, pulled)
+	assert.ElementsMatch(t, []string{"shared", "from-cloud", "secret", "scratch"}, noteIDsOf(pulled.Notes))
+	assert.Len(t, pulled.Folders, 3)
+	assert.Equal(t, []TopLevelItem{
+		{Type: "note", ID: "from-cloud"},
+		{Type: "note", ID: "shared"},
+		{Type: "folder", ID: "private"},
+		{Type: "note", ID: "secret"},
+		{Type: "folder", ID: "public"},
+	}, pulled.TopLevelOrder)
+	assert.ElementsMatch(t, []string{"public", "private"}, pulled.CollapsedFolderIDs)
+	assert.Len(t, pulled.Trash, 2)
+}
+
+func noteIDsOf(notes []NoteMetadata) []string {
+	ids := make([]string, 0, len(notes))
+	for _, metadata := range notes {
+		ids = append(ids, metadata.ID)
+	}
+	return ids
+}
+
+func TestSetNoteLocalOnly_SurvivesSaveAndOrphanRecovery(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	require.NoError(t, ns.SaveNote(&Note{ID: "secret", Title: "secret", Content: "customer data", Language: "plaintext"}))
+	require.NoError(t, ns.SetNoteLocalOnly("secret", true))
+
+	// フロントエンドから印の無いノートが届いても外れない
+	require.NoError(t, ns.SaveNote(&Note{ID: "secret", Title: "secret", Content: "edited", Language: "plaintext"}))
+	noteIDs, _ := ns.LocalOnlyIDs()
+	assert.True(t, noteIDs["secret"])
+
+	// noteList からエントリが消えても、ノートファイルの印から復元される
+	ns.WithLock(func() {
+		ns.noteList.Notes = nil
+		ns.noteList.TopLevelOrder = nil
+	})
+	_, err := ns.ValidateIntegrity()
+	require.NoError(t, err)
+	noteIDs, _ = ns.LocalOnlyIDs()
+	assert.True(t, noteIDs["secret"])
+
+	assert.Error(t, ns.SetNoteLocalOnly("missing", true))
+	assert.Error(t, ns.SetFolderLocalOnly("missing", true))
+}
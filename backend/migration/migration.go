@@ -9,7 +9,7 @@ func RunIfNeeded(appDataDir string, notesDir string) (bool, error) {
 	localV2Path := filepath.Join(filepath.Dir(notesDir), "noteList_v2.json")
 
 	if _, err := os.Stat(localV2Path); err == nil {
-		return false, nil
+		return migrateV2Minor(localV2Path, notesDir)
 	}
 
 	localV1Path := filepath.Join(filepath.Dir(notesDir), "noteList.json")
@@ -20,5 +20,21 @@ func RunIfNeeded(appDataDir string, notesDir string) (bool, error) {
 	if err := migrateV1ToV2(localV1Path, localV2Path); err != nil {
 		return false, err
 	}
+	if _, err := migrateV2Minor(localV2Path, notesDir); err != nil {
+		return true, err
+	}
 	return true, nil
 }
+
+// migrateV2Minor は v2 形式の noteList を古い順に最新バージョンまで上げる
+func migrateV2Minor(v2Path, notesDir string) (bool, error) {
+	tagsMigrated, err := migrateV2Tags(v2Path, notesDir)
+	if err != nil {
+		return false, err
+	}
+	treeMigrated, err := migrateV2ToV3(v2Path)
+	if err != nil {
+		return tagsMigrated, err
+	}
+	return tagsMigrated || treeMigrated, nil
+}
@@ -9,19 +9,20 @@ import (
 
 const snapshotDir = "migration_snapshots"
 
-func saveSnapshot(v1Path string) error {
-	dir := filepath.Join(filepath.Dir(v1Path), snapshotDir)
+// saveSnapshot は移行前の noteList を noteList_<label>_<timestamp>.json として退避する
+func saveSnapshot(noteListPath string, label string) error {
+	dir := filepath.Join(filepath.Dir(noteListPath), snapshotDir)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return err
 	}
 
-	data, err := os.ReadFile(v1Path)
+	data, err := os.ReadFile(noteListPath)
 	if err != nil {
 		return err
 	}
 
 	timestamp := time.Now().Format("20060102_150405")
-	snapshotPath := filepath.Join(dir, fmt.Sprintf("noteList_v1_%s.json", timestamp))
+	snapshotPath := filepath.Join(dir, fmt.Sprintf("noteList_%s_%s.json", label, timestamp))
 	return os.WriteFile(snapshotPath, data, 0o644)
 }
 
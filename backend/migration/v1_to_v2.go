@@ -17,20 +17,22 @@ type v2NoteList struct {
 }
 
 type v2NoteMetadata struct {
-	ID            string `json:"id"`
-	Title         string `json:"title"`
-	ContentHeader string `json:"contentHeader"`
-	Language      string `json:"language"`
-	ModifiedTime  string `json:"modifiedTime"`
-	Archived      bool   `json:"archived"`
-	ContentHash   string `json:"contentHash"`
-	FolderID      string `json:"folderId,omitempty"`
+	ID            string   `json:"id"`
+	Title         string   `json:"title"`
+	ContentHeader string   `json:"contentHeader"`
+	Language      string   `json:"language"`
+	ModifiedTime  string   `json:"modifiedTime"`
+	Archived      bool     `json:"archived"`
+	ContentHash   string   `json:"contentHash"`
+	FolderID      string   `json:"folderId,omitempty"`
+	Tags          []string `json:"tags,omitempty"` // 2.1 以降
 }
 
 type v2Folder struct {
 	ID       string `json:"id"`
 	Name     string `json:"name"`
 	Archived bool   `json:"archived,omitempty"`
+	ParentID string `json:"parentId,omitempty"` // 3.0 以降
 }
 
 type v2TopLevelItem struct {
@@ -48,7 +50,7 @@ func migrateV1ToV2(v1Path, v2Path string) error {
 		return fmt.Errorf("failed to parse v1 noteList: %w", err)
 	}
 
-	if err := saveSnapshot(v1Path); err != nil {
+	if err := saveSnapshot(v1Path, "v1"); err != nil {
 		return fmt.Errorf("failed to save snapshot: %w", err)
 	}
 
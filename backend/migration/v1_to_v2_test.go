@@ -172,7 +172,7 @@ func TestMigration_RunIfNeeded_AlreadyMigrated(t *testing.T) {
 	}
 	writeV1NoteList(t, v1Path, v1)
 
-	originalV2 := `{"version":"2.0","notes":[{"id":"keep","title":"keep","contentHeader":"h","language":"markdown","modifiedTime":"2026-01-01T00:00:00Z","archived":false,"contentHash":"keep-hash"}]}`
+	originalV2 := `{"version":"3.0","notes":[{"id":"keep","title":"keep","contentHeader":"h","language":"markdown","modifiedTime":"2026-01-01T00:00:00Z","archived":false,"contentHash":"keep-hash"}]}`
 	require.NoError(t, os.WriteFile(v2Path, []byte(originalV2), 0o644))
 
 	migrated, err := RunIfNeeded(tempDir, notesDir)
@@ -0,0 +1,82 @@
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// TagsVersion はノートのタグを noteList に持つようになったバージョン
+const TagsVersion = "2.1"
+
+// migrateV2Tags は 2.0 の noteList を 2.1 (タグ対応) に上げる。
+// ノートファイル側に既にタグがある場合 (新しいクライアントから同期されたノートなど) は
+// noteList のメタデータにも写す。既に 2.1 以降なら何もせず false を返す。
+func migrateV2Tags(v2Path, notesDir string) (bool, error) {
+	data, err := os.ReadFile(v2Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read v2 noteList: %w", err)
+	}
+	var list v2NoteList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return false, fmt.Errorf("failed to parse v2 noteList: %w", err)
+	}
+	if !VersionLess(list.Version, TagsVersion) {
+		return false, nil
+	}
+
+	if err := saveSnapshot(v2Path, "v2"); err != nil {
+		return false, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	for i, n := range list.Notes {
+		if len(n.Tags) > 0 {
+			continue
+		}
+		noteData, err := os.ReadFile(filepath.Join(notesDir, n.ID+".json"))
+		if err != nil {
+			// 未ダウンロードのノートは次回同期でメタデータごと更新される
+			continue
+		}
+		var note struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.Unmarshal(noteData, &note); err != nil {
+			continue
+		}
+		list.Notes[i].Tags = note.Tags
+	}
+	list.Version = TagsVersion
+
+	v2Data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal v2 noteList: %w", err)
+	}
+	if err := atomicWrite(v2Path, v2Data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// VersionLess は "2.0" 形式のバージョン文字列を数値として比較し、a < b なら true を返す。
+// 解釈できない部分は 0 とみなす。
+func VersionLess(a, b string) bool {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var av, bv int
+		if i < len(aParts) {
+			av, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			bv, _ = strconv.Atoi(bParts[i])
+		}
+		if av != bv {
+			return av < bv
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package migration
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigration_V2Tags_CopiesTagsFromNoteFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	notesDir := filepath.Join(tempDir, "notes")
+	require.NoError(t, os.MkdirAll(notesDir, 0o755))
+	v2Path := filepath.Join(tempDir, "noteList_v2.json")
+
+	original := `{"version":"2.0","notes":[` +
+		`{"id":"tagged","title":"a","contentHeader":"h","language":"markdown","modifiedTime":"2026-01-01T00:00:00Z","archived":false,"contentHash":"h1","folderId":"f1"},` +
+		`{"id":"plain","title":"b","contentHeader":"h","language":"markdown","modifiedTime":"2026-01-01T00:00:00Z","archived":false,"contentHash":"h2"},` +
+		`{"id":"missing","title":"c","contentHeader":"h","language":"markdown","modifiedTime":"2026-01-01T00:00:00Z","archived":false,"contentHash":"h3"}` +
+		`],"folders":[{"id":"f1","name":"folder"}]}`
+	require.NoError(t, os.WriteFile(v2Path, []byte(original), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(notesDir, "tagged.json"), []byte(`{"id":"tagged","tags":["go","work"]}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(notesDir, "plain.json"), []byte(`{"id":"plain"}`), 0o644))
+
+	migrated, err := migrateV2Tags(v2Path, notesDir)
+	require.NoError(t, err)
+	assert.True(t, migrated)
+
+	var list v2NoteList
+	require.NoError(t, json.Unmarshal(readJSONFile(t, v2Path), &list))
+	assert.Equal(t, TagsVersion, list.Version)
+	require.Len(t, list.Notes, 3)
+	assert.Equal(t, []string{"go", "work"}, list.Notes[0].Tags)
+	assert.Equal(t, "f1", list.Notes[0].FolderID)
+	assert.Equal(t, "h1", list.Notes[0].ContentHash)
+	assert.Empty(t, list.Notes[1].Tags)
+	assert.Empty(t, list.Notes[2].Tags)
+	require.Len(t, list.Folders, 1)
+
+	matches, err := filepath.Glob(filepath.Join(tempDir, snapshotDir, "noteList_v2_*.json"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, matches)
+
+	// 2 回目は何もしない
+	migrated, err = migrateV2Tags(v2Path, notesDir)
+	require.NoError(t, err)
+	assert.False(t, migrated)
+}
+
+func TestVersionLess(t *testing.T) {
+	assert.True(t, VersionLess("2.0", "2.1"))
+	assert.True(t, VersionLess("2.9", "2.10"))
+	assert.True(t, VersionLess("", "2.0"))
+	assert.False(t, VersionLess("2.1", "2.1"))
+	assert.False(t, VersionLess("3", "2.1"))
+}
@@ -0,0 +1,43 @@
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// FolderTreeVersion はフォルダの入れ子 (Folder.ParentID) に対応したバージョン
+const FolderTreeVersion = "3.0"
+
+// migrateV2ToV3 は 2.x の noteList を 3.0 (フォルダの木構造対応) に上げる。
+// 2.x のフォルダは全てトップレベルなので、データはそのままでバージョンだけを上げる。
+// 旧バージョンのクライアントが 3.0 の noteList を読み込めないため、スナップショットを残しておく。
+// 既に 3.0 以降なら何もせず false を返す。
+func migrateV2ToV3(v2Path string) (bool, error) {
+	data, err := os.ReadFile(v2Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read v2 noteList: %w", err)
+	}
+	var list v2NoteList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return false, fmt.Errorf("failed to parse v2 noteList: %w", err)
+	}
+	if !VersionLess(list.Version, FolderTreeVersion) {
+		return false, nil
+	}
+
+	if err := saveSnapshot(v2Path, "v"+list.Version); err != nil {
+		return false, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	list.Version = FolderTreeVersion
+
+	v3Data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal v2 noteList: %w", err)
+	}
+	if err := atomicWrite(v2Path, v3Data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,49 @@
+package migration
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 2.0 の noteList はタグ対応を経て 3.0 まで一度に上がり、フォルダ等はそのまま残ること
+func TestMigration_RunIfNeeded_V2ToV3(t *testing.T) {
+	tempDir := t.TempDir()
+	notesDir := filepath.Join(tempDir, "notes")
+	require.NoError(t, os.MkdirAll(notesDir, 0o755))
+	v2Path := filepath.Join(tempDir, "noteList_v2.json")
+
+	original := `{"version":"2.0","notes":[` +
+		`{"id":"n1","title":"a","contentHeader":"h","language":"markdown","modifiedTime":"2026-01-01T00:00:00Z","archived":false,"contentHash":"h1","folderId":"f1"}` +
+		`],"folders":[{"id":"f1","name":"folder"},{"id":"f2","name":"archived","archived":true}],` +
+		`"topLevelOrder":[{"type":"folder","id":"f1"}],"archivedTopLevelOrder":[{"type":"folder","id":"f2"}]}`
+	require.NoError(t, os.WriteFile(v2Path, []byte(original), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(notesDir, "n1.json"), []byte(`{"id":"n1","tags":["go"]}`), 0o644))
+
+	migrated, err := RunIfNeeded(tempDir, notesDir)
+	require.NoError(t, err)
+	assert.True(t, migrated)
+
+	var list v2NoteList
+	require.NoError(t, json.Unmarshal(readJSONFile(t, v2Path), &list))
+	assert.Equal(t, FolderTreeVersion, list.Version)
+	require.Len(t, list.Notes, 1)
+	assert.Equal(t, []string{"go"}, list.Notes[0].Tags)
+	assert.Equal(t, []v2Folder{{ID: "f1", Name: "folder"}, {ID: "f2", Name: "archived", Archived: true}}, list.Folders)
+	assert.Equal(t, []v2TopLevelItem{{Type: "folder", ID: "f1"}}, list.TopLevelOrder)
+	assert.Equal(t, []v2TopLevelItem{{Type: "folder", ID: "f2"}}, list.ArchivedTopLevelOrder)
+
+	for _, label := range []string{"v2", "v2.1"} {
+		matches, err := filepath.Glob(filepath.Join(tempDir, snapshotDir, "noteList_"+label+"_*.json"))
+		require.NoError(t, err)
+		assert.Len(t, matches, 1, label)
+	}
+
+	migrated, err = RunIfNeeded(tempDir, notesDir)
+	require.NoError(t, err)
+	assert.False(t, migrated)
+}
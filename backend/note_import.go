@@ -0,0 +1,722 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+// ディレクトリ・Obsidian 保管庫・Evernote (ENEX) からの一括取り込み
+// ------------------------------------------------------------
+//
+// - ディレクトリは取り込み元と同じ名前のフォルダの下に、同じ構造のフォルダとして取り込む。
+//   ENEX (1 ノートブック 1 ファイル) はファイル名のフォルダ 1 つにまとめる。
+//   同じ親の下に同名のアクティブなフォルダがあれば新しく作らずにそれを使う。
+// - 言語は拡張子から決める。Markdown の front matter の title / tags / language と
+//   ENEX のタグはメタデータに移し、本文からは取り除く。
+// - 文字コードは detectAndConvertEncoding で UTF-8 にそろえる。
+// - 既存のノート、または取り込み中の他のファイルと computeConflictCopyDedupHash が
+//   一致するものは取り込まない (同じディレクトリを何度取り込んでも増えない)。
+// - noteList.json の書き込みは最後の 1 回にまとめ、同期も App 側で 1 回だけ起こす。
+// - 隠しファイル・隠しディレクトリ (.obsidian, .git など) は一覧にも載せない。
+
+// 取り込み 1 件分の状態
+const (
+	importStatusImport    = "import"
+	importStatusDuplicate = "duplicate"
+	importStatusSkipped   = "skipped"
+)
+
+const (
+	importMaxFileSize     = 10 << 20 // これより大きいファイルはノートにしない
+	importBinarySniffSize = 8000     // 先頭のこのバイト数に NUL があればバイナリとみなす
+	importDefaultRootName = "Imported"
+)
+
+// vaultExportExtensions に無い拡張子と、複数の言語が同じ拡張子を使うものの既定
+var importExtraExtensions = map[string]string{
+	".bash":     "shell",
+	".cc":       "cpp",
+	".cjs":      "javascript",
+	".cts":      "typescript",
+	".cxx":      "cpp",
+	".h":        "c",
+	".hpp":      "cpp",
+	".htm":      "html",
+	".jsonc":    "json",
+	".jsx":      "javascript",
+	".log":      "plaintext",
+	".markdown": "markdown",
+	".mdown":    "markdown",
+	".mjs":      "javascript",
+	".mkd":      "markdown",
+	".mts":      "typescript",
+	".sql":      "sql",
+	".svg":      "xml",
+	".text":     "plaintext",
+	".tsx":      "typescript",
+	".yml":      "yaml",
+	".zsh":      "shell",
+}
+
+// 拡張子 → 言語。書き出しと同じ対応を逆引きし、importExtraExtensions で補う
+var importLanguageByExtension = func() map[string]string {
+	languages := make(map[string]string, len(vaultExportExtensions)+len(importExtraExtensions))
+	for language, ext := range vaultExportExtensions {
+		languages[ext] = language
+	}
+	for ext, language := range importExtraExtensions {
+		languages[ext] = language
+	}
+	return languages
+}()
+
+// 取り込み候補 1 件分
+type importCandidate struct {
+	item       ImportItem
+	note       *Note    // skipped では nil
+	folderPath []string // 取り込み先のフォルダ名 (ルートから順に)
+}
+
+// Markdown の front matter から取り出したメタデータ
+type importFrontMatter struct {
+	title    string
+	language string
+	tags     []string
+}
+
+// importLanguageForName はファイル名から言語を返す (知らない拡張子なら plaintext)
+func importLanguageForName(name string) string {
+	if strings.EqualFold(name, "Dockerfile") {
+		return "dockerfile"
+	}
+	if language, ok := importLanguageByExtension[strings.ToLower(filepath.Ext(name))]; ok {
+		return language
+	}
+	return "plaintext"
+}
+
+// isImportBinary は先頭に NUL を含むデータをバイナリとみなす
+func isImportBinary(data []byte) bool {
+	if len(data) > importBinarySniffSize {
+		data = data[:importBinarySniffSize]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// unquoteFrontMatterValue は YAML のスカラー値の引用符を外す
+func unquoteFrontMatterValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 {
+		switch {
+		case value[0] == '"' && value[len(value)-1] == '"':
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				return unquoted
+			}
+			return value[1 : len(value)-1]
+		case value[0] == '\'' && value[len(value)-1] == '\'':
+			return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+		}
+	}
+	return value
+}
+
+// parseImportFrontMatter は Markdown 先頭の front matter ("---" で囲まれた YAML) を読み、
+// メタデータと front matter を除いた本文を返す。front matter が無ければ false を返す。
+// YAML 全体は解釈せず、"key: value"、"key: [a, b]"、"key:" に続く "- a" の形だけを扱う。
+func parseImportFrontMatter(content string) (importFrontMatter, string, bool) {
+	lines := strings.Split(content, "\n")
+	if len(lines) < 2 || strings.TrimRight(lines[0], "\r") != "---" {
+		return importFrontMatter{}, content, false
+	}
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if line := strings.TrimRight(lines[i], "\r"); line == "---" || line == "..." {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return importFrontMatter{}, content, false
+	}
+
+	values := make(map[string][]string)
+	listKey := ""
+	for _, line := range lines[1:end] {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if listKey != "" && (trimmed == "-" || strings.HasPrefix(trimmed, "- ")) {
+			values[listKey] = append(values[listKey], unquoteFrontMatterValue(strings.TrimPrefix(trimmed, "-")))
+			continue
+		}
+		listKey = ""
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+			listKey = key
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			for _, element := range strings.Split(value[1:len(value)-1], ",") {
+				values[key] = append(values[key], unquoteFrontMatterValue(element))
+			}
+		default:
+			// "tags: a, b" や "tags: a b" (Obsidian) のような 1 行の列挙はタグのときだけ分ける
+			if key == "tags" || key == "tag" {
+				values[key] = append(values[key], strings.FieldsFunc(unquoteFrontMatterValue(value), func(r rune) bool {
+					return r == ',' || unicode.IsSpace(r)
+				})...)
+				continue
+			}
+			values[key] = []string{unquoteFrontMatterValue(value)}
+		}
+	}
+
+	var fm importFrontMatter
+	if title := values["title"]; len(title) > 0 {
+		fm.title = strings.TrimSpace(title[0])
+	}
+	if language := values["language"]; len(language) > 0 {
+		fm.language = strings.ToLower(strings.TrimSpace(language[0]))
+	}
+	fm.tags = append(values["tags"], values["tag"]...)
+
+	body := strings.Join(lines[end+1:], "\n")
+	// front matter の直後の空行は区切りとみなして取り除く
+	body = strings.TrimPrefix(strings.TrimPrefix(body, "\r"), "\n")
+	return fm, body, true
+}
+
+// readImportFile は 1 ファイルを取り込み候補にする
+func readImportFile(filePath string, rel string, folderPath []string, entry fs.DirEntry) importCandidate {
+	name := entry.Name()
+	candidate := importCandidate{
+		item: ImportItem{
+			Source: rel,
+			Title:  strings.TrimSuffix(name, filepath.Ext(name)),
+			Folder: strings.Join(folderPath, "/"),
+		},
+		folderPath: folderPath,
+	}
+	skip := func(reason string) importCandidate {
+		candidate.item.Status = importStatusSkipped
+		candidate.item.Reason = reason
+		return candidate
+	}
+	if candidate.item.Title == "" {
+		candidate.item.Title = name
+	}
+	language := importLanguageForName(name)
+	candidate.item.Language = language
+
+	info, err := entry.Info()
+	if err != nil {
+		return skip(err.Error())
+	}
+	if !info.Mode().IsRegular() {
+		return skip("not a regular file")
+	}
+	if info.Size() > importMaxFileSize {
+		return skip("file is too large")
+	}
+	if rel == vaultExportIndexName {
+		return skip("vault export index")
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return skip(err.Error())
+	}
+	if isImportBinary(data) {
+		return skip("binary file")
+	}
+	content, _ := detectAndConvertEncoding(data)
+	if isLockedNoteContent(content) {
+		return skip("encrypted note")
+	}
+
+	var tags []string
+	if language == "markdown" {
+		if fm, body, ok := parseImportFrontMatter(content); ok {
+			content = body
+			tags = fm.tags
+			if fm.title != "" {
+				candidate.item.Title = fm.title
+			}
+			if _, known := vaultExportExtensions[fm.language]; known {
+				candidate.item.Language = fm.language
+			}
+		}
+	}
+	if strings.TrimSpace(content) == "" {
+		return skip("empty file")
+	}
+
+	candidate.item.Status = importStatusImport
+	candidate.note = &Note{
+		Title:    candidate.item.Title,
+		Content:  content,
+		Language: candidate.item.Language,
+		Tags:     tags,
+	}
+	return candidate
+}
+
+// collectDirectoryImport はディレクトリ以下のファイルを取り込み候補にする (名前順)
+func collectDirectoryImport(root string) ([]importCandidate, error) {
+	rootName := filepath.Base(root)
+	if rootName == "." || rootName == string(filepath.Separator) || rootName == filepath.VolumeName(root) {
+		rootName = importDefaultRootName
+	}
+
+	var candidates []importCandidate
+	err := filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+		if p == root {
+			return err
+		}
+		rel, relErr := filepath.Rel(root, p)
+		if relErr != nil {
+			return relErr
+		}
+		rel = filepath.ToSlash(rel)
+		folderPath := []string{rootName}
+		if dir := path.Dir(rel); dir != "." {
+			folderPath = append(folderPath, strings.Split(dir, "/")...)
+		}
+		// 読めないサブディレクトリは報告して続ける
+		if err != nil {
+			candidates = append(candidates, importCandidate{
+				item:       ImportItem{Source: rel, Folder: strings.Join(folderPath, "/"), Status: importStatusSkipped, Reason: err.Error()},
+				folderPath: folderPath,
+			})
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		candidates = append(candidates, readImportFile(p, rel, folderPath, entry))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import directory: %w", err)
+	}
+	return candidates, nil
+}
+
+// ENEX の <note> のうち取り込みに使う部分
+type enexNote struct {
+	Title   string   `xml:"title"`
+	Content string   `xml:"content"`
+	Tags    []string `xml:"tag"`
+}
+
+// collectENEXImport は Evernote の ENEX ファイルのノートを取り込み候補にする。
+// 添付ファイル (<resource>) は取り込まない。
+func collectENEXImport(filePath string) ([]importCandidate, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	base := filepath.Base(filePath)
+	rootName := strings.TrimSuffix(base, filepath.Ext(base))
+	if rootName == "" {
+		rootName = importDefaultRootName
+	}
+
+	decoder := xml.NewDecoder(file)
+	var candidates []importCandidate
+	seenRoot := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ENEX: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if !seenRoot {
+			if start.Name.Local != "en-export" {
+				return nil, fmt.Errorf("not an ENEX file: root element is <%s>", start.Name.Local)
+			}
+			seenRoot = true
+			continue
+		}
+		if start.Name.Local != "note" {
+			continue
+		}
+
+		var parsed enexNote
+		if err := decoder.DecodeElement(&parsed, &start); err != nil {
+			return nil, fmt.Errorf("failed to parse ENEX: %w", err)
+		}
+		candidate := importCandidate{
+			item: ImportItem{
+				Source:   fmt.Sprintf("%s#%d", base, len(candidates)+1),
+				Title:    strings.TrimSpace(parsed.Title),
+				Language: "markdown",
+				Folder:   rootName,
+				Status:   importStatusImport,
+			},
+			folderPath: []string{rootName},
+		}
+		content, err := enmlToMarkdown(parsed.Content)
+		switch {
+		case err != nil:
+			candidate.item.Status = importStatusSkipped
+			candidate.item.Reason = err.Error()
+		case content == "":
+			candidate.item.Status = importStatusSkipped
+			candidate.item.Reason = "empty note"
+		default:
+			candidate.note = &Note{Title: candidate.item.Title, Content: content, Language: "markdown", Tags: parsed.Tags}
+		}
+		candidates = append(candidates, candidate)
+	}
+	if !seenRoot {
+		return nil, fmt.Errorf("not an ENEX file: %s", base)
+	}
+	return candidates, nil
+}
+
+// enmlToMarkdown は ENEX のノート本文 (ENML = XHTML のサブセット) を Markdown 風のテキストにする。
+// 見出し・リスト・チェックボックス・改行だけを置き換え、それ以外のタグは外して文字だけ残す。
+func enmlToMarkdown(enml string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(enml))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var b strings.Builder
+	var lists []string // 開いている ul / ol
+	var counters []int // ol の番号
+	preDepth := 0
+	atLineStart := func() bool {
+		s := b.String()
+		return s == "" || strings.HasSuffix(s, "\n")
+	}
+	newline := func() {
+		if !atLineStart() {
+			b.WriteString("\n")
+		}
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse note content: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch name := strings.ToLower(t.Name.Local); name {
+			case "br":
+				b.WriteString("\n")
+			case "div", "p", "blockquote", "table", "tr":
+				newline()
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				newline()
+				b.WriteString(strings.Repeat("#", int(name[1]-'0')) + " ")
+			case "hr":
+				newline()
+				b.WriteString("---\n")
+			case "pre":
+				newline()
+				preDepth++
+			case "ul", "ol":
+				newline()
+				lists = append(lists, name)
+				counters = append(counters, 0)
+			case "li":
+				newline()
+				if len(lists) > 1 {
+					b.WriteString(strings.Repeat("  ", len(lists)-1))
+				}
+				if len(lists) > 0 && lists[len(lists)-1] == "ol" {
+					counters[len(counters)-1]++
+					fmt.Fprintf(&b, "%d. ", counters[len(counters)-1])
+				} else {
+					b.WriteString("- ")
+				}
+			case "td", "th":
+				if !atLineStart() {
+					b.WriteString(" | ")
+				}
+			case "en-todo":
+				mark := "[ ] "
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "checked" && attr.Value == "true" {
+						mark = "[x] "
+					}
+				}
+				if atLineStart() {
+					mark = "- " + mark
+				}
+				b.WriteString(mark)
+			}
+		case xml.EndElement:
+			switch strings.ToLower(t.Name.Local) {
+			case "div", "p", "blockquote", "table", "tr", "li", "h1", "h2", "h3", "h4", "h5", "h6":
+				newline()
+			case "pre":
+				newline()
+				if preDepth > 0 {
+					preDepth--
+				}
+			case "ul", "ol":
+				newline()
+				if len(lists) > 0 {
+					lists = lists[:len(lists)-1]
+					counters = counters[:len(counters)-1]
+				}
+			}
+		case xml.CharData:
+			text := string(t)
+			if preDepth > 0 {
+				b.WriteString(text)
+				continue
+			}
+			// HTML と同じく連続する空白は 1 つにまとめる
+			collapsed := strings.Join(strings.Fields(text), " ")
+			if collapsed == "" {
+				if text != "" && !atLineStart() && !strings.HasSuffix(b.String(), " ") {
+					b.WriteString(" ")
+				}
+				continue
+			}
+			if first, _ := utf8.DecodeRuneInString(text); unicode.IsSpace(first) && !atLineStart() && !strings.HasSuffix(b.String(), " ") {
+				b.WriteString(" ")
+			}
+			b.WriteString(collapsed)
+			if last, _ := utf8.DecodeLastRuneInString(text); unicode.IsSpace(last) {
+				b.WriteString(" ")
+			}
+		}
+	}
+
+	// 行末の空白を除き、3 行以上続く空行を 1 行にする
+	lines := strings.Split(b.String(), "\n")
+	result := make([]string, 0, len(lines))
+	blank := 0
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			blank++
+			if blank > 1 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		result = append(result, line)
+	}
+	return strings.TrimSpace(strings.Join(result, "\n")), nil
+}
+
+// 取り込み先フォルダの解決 (DryRun では作らずに数えるだけ)
+type importFolderResolver struct {
+	s       *noteService
+	dryRun  bool
+	ids     map[string]string // フォルダ名を "\x00" でつないだパス → フォルダID (DryRun で新しく作る予定なら空)
+	created int
+}
+
+// resolveLocked は folderPath のフォルダの ID を返し、無ければ作る (caller が s.mu を握っている前提)
+func (r *importFolderResolver) resolveLocked(folderPath []string) string {
+	parentID := ""
+	planned := false
+	for i, name := range folderPath {
+		key := strings.Join(folderPath[:i+1], "\x00")
+		id, ok := r.ids[key]
+		if !ok {
+			if !planned {
+				id = r.s.findActiveFolderLocked(parentID, name)
+			}
+			if id == "" {
+				r.created++
+				if !r.dryRun {
+					id = r.s.appendFolderLocked(parentID, name).ID
+				}
+			}
+			r.ids[key] = id
+		}
+		parentID = id
+		planned = id == ""
+	}
+	return parentID
+}
+
+// findActiveFolderLocked は親と名前が一致するアクティブなフォルダの ID を返す (無ければ空)
+func (s *noteService) findActiveFolderLocked(parentID string, name string) string {
+	for _, folder := range s.noteList.Folders {
+		if !folder.Archived && folder.ParentID == parentID && folder.Name == name {
+			return folder.ID
+		}
+	}
+	return ""
+}
+
+// writeImportedNoteLocked は取り込んだノートのファイルを書き、noteList に載せるメタデータを返す。
+// noteList.json は書かない (caller がまとめて保存する)。
+func (s *noteService) writeImportedNoteLocked(note *Note) (NoteMetadata, error) {
+	note.ID = uuid.New().String()
+	note.ModifiedTime = time.Now().Format(time.RFC3339)
+	note.Tags = normalizeTags(note.Tags)
+	note.ContentHeader = noteContentHeader(note)
+
+	// FolderIDはnoteList.jsonのみで管理するため、ノートファイルには書き込まない
+	folderID := note.FolderID
+	note.FolderID = ""
+	data, err := json.MarshalIndent(note, "", "  ")
+	note.FolderID = folderID
+	if err != nil {
+		return NoteMetadata{}, err
+	}
+	if err := os.WriteFile(filepath.Join(s.notesDir, note.ID+".json"), data, 0644); err != nil {
+		return NoteMetadata{}, err
+	}
+
+	s.noteCache[note.ID] = note
+	s.indexNoteLocked(note)
+	s.recordRevisionLocked(nil, note, NoteRevisionSourceImport)
+
+	return NoteMetadata{
+		ID:            note.ID,
+		Title:         note.Title,
+		ContentHeader: note.ContentHeader,
+		Language:      note.Language,
+		ModifiedTime:  note.ModifiedTime,
+		ContentHash:   computeContentHash(note),
+		FolderID:      folderID,
+		Tags:          note.Tags,
+	}, nil
+}
+
+// ディレクトリまたは ENEX ファイルからノートを一括で取り込む ------------------------------------------------------------
+// 途中でノートの書き込みに失敗した場合も、それまでに取り込んだ分は noteList に反映し、
+// その時点の結果をエラーと一緒に返す。
+func (s *noteService) ImportNotes(sourcePath string, options ImportOptions) (*ImportReport, error) {
+	if !filepath.IsAbs(sourcePath) {
+		return nil, fmt.Errorf("import source must be an absolute path: %s", sourcePath)
+	}
+	sourcePath = filepath.Clean(sourcePath)
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// ファイルの読み込みはロックの外で済ませる
+	var candidates []importCandidate
+	switch {
+	case info.IsDir():
+		candidates, err = collectDirectoryImport(sourcePath)
+	case strings.EqualFold(filepath.Ext(sourcePath), ".enex"):
+		candidates, err = collectENEXImport(sourcePath)
+	default:
+		return nil, fmt.Errorf("unsupported import source (expected a directory or an .enex file): %s", sourcePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 内容のハッシュ → 同じ内容のノート (取り込み中のものは Source も覚えておく)
+	type dedupEntry struct {
+		noteID string
+		source string
+	}
+	known := make(map[string]dedupEntry, len(s.noteList.Notes)+len(candidates))
+	for _, metadata := range s.noteList.Notes {
+		note, err := s.readNoteForIndexLocked(metadata.ID)
+		if err != nil {
+			continue
+		}
+		known[computeConflictCopyDedupHash(note)] = dedupEntry{noteID: metadata.ID}
+	}
+
+	report := &ImportReport{DryRun: options.DryRun, Items: make([]ImportItem, 0, len(candidates))}
+	resolver := &importFolderResolver{s: s, dryRun: options.DryRun, ids: make(map[string]string)}
+	var imported []NoteMetadata
+	var writeErr error
+	for _, candidate := range candidates {
+		item := candidate.item
+		if item.Status == importStatusSkipped {
+			report.SkippedCount++
+			report.Items = append(report.Items, item)
+			continue
+		}
+
+		hash := computeConflictCopyDedupHash(candidate.note)
+		if entry, ok := known[hash]; ok {
+			item.Status = importStatusDuplicate
+			item.NoteID = entry.noteID
+			item.Reason = "same content as an existing note"
+			if entry.source != "" {
+				item.Reason = "same content as " + entry.source
+			}
+			report.DuplicateCount++
+			report.Items = append(report.Items, item)
+			continue
+		}
+
+		folderID := resolver.resolveLocked(candidate.folderPath)
+		if !options.DryRun {
+			candidate.note.FolderID = folderID
+			metadata, err := s.writeImportedNoteLocked(candidate.note)
+			if err != nil {
+				writeErr = fmt.Errorf("failed to import %s: %w", item.Source, err)
+				break
+			}
+			imported = append(imported, metadata)
+			item.NoteID = metadata.ID
+		}
+		known[hash] = dedupEntry{noteID: item.NoteID, source: item.Source}
+		report.ImportedCount++
+		report.Items = append(report.Items, item)
+	}
+	report.FolderCount = resolver.created
+
+	if options.DryRun || (len(imported) == 0 && resolver.created == 0) {
+		return report, writeErr
+	}
+	// 取り込んだノートはアクティブリストの先頭に取り込み元の順で並べる
+	s.noteList.Notes = append(imported, s.noteList.Notes...)
+	if err := s.saveNoteList(); err != nil {
+		return report, err
+	}
+	return report, writeErr
+}
@@ -0,0 +1,182 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/japanese"
+)
+
+func writeImportFile(t *testing.T, root string, rel string, data []byte) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, data, 0644))
+}
+
+func TestParseImportFrontMatter(t *testing.T) {
+	fm, body, ok := parseImportFrontMatter("---\r\ntitle: \"Daily: log\"\r\ntags: [work, 'it''s']\r\nlanguage: Markdown\r\naliases:\r\n  - other\r\n---\r\n\r\nbody\r\n")
+	require.True(t, ok)
+	assert.Equal(t, "Daily: log", fm.title)
+	assert.Equal(t, "markdown", fm.language)
+	assert.Equal(t, []string{"work", "it's"}, fm.tags)
+	assert.Equal(t, "body\r\n", body)
+
+	fm, body, ok = parseImportFrontMatter("---\ntags:\n  - a\n  - \"b c\"\ntag: d, e f\n---\ntext")
+	require.True(t, ok)
+	assert.Equal(t, []string{"a", "b c", "d", "e", "f"}, fm.tags)
+	assert.Equal(t, "text", body)
+
+	_, body, ok = parseImportFrontMatter("---\nnot closed")
+	assert.False(t, ok)
+	assert.Equal(t, "---\nnot closed", body)
+}
+
+func TestImportNotes_DirectoryDryRunAndDedup(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	require.NoError(t, ns.SaveNote(&Note{ID: "existing", Title: "existing", Content: "already here", Language: "markdown"}))
+
+	root := filepath.Join(t.TempDir(), "vault")
+	sjis, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte("日本語のメモ"))
+	require.NoError(t, err)
+	writeImportFile(t, root, ".obsidian/app.json", []byte("{}"))
+	writeImportFile(t, root, "Daily/2024-01-01.md", []byte("---\ntitle: New Year\ntags: [journal, '#plans']\n---\n\n# Goals"))
+	writeImportFile(t, root, "Daily/Deep/nested.md", []byte("deep note"))
+	writeImportFile(t, root, "code/main.go", []byte("package main"))
+	writeImportFile(t, root, "memo.txt", sjis)
+	writeImportFile(t, root, "image.png", []byte{0x89, 'P', 'N', 'G', 0, 0, 0})
+	writeImportFile(t, root, "again.md", []byte("already here"))
+	writeImportFile(t, root, "copy.go", []byte("package main"))
+	writeImportFile(t, root, "empty.md", []byte("---\ntitle: nothing\n---\n"))
+
+	_, err = ns.ImportNotes("relative", ImportOptions{})
+	assert.Error(t, err)
+
+	dryRun, err := ns.ImportNotes(root, ImportOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.True(t, dryRun.DryRun)
+	assert.Equal(t, 4, dryRun.ImportedCount)
+	assert.Equal(t, 2, dryRun.DuplicateCount)
+	assert.Equal(t, 2, dryRun.SkippedCount)
+	assert.Equal(t, 4, dryRun.FolderCount) // vault, vault/Daily, vault/Daily/Deep, vault/code
+	assert.Len(t, ns.noteList.Notes, 1)
+	assert.Empty(t, ns.ListFolders())
+
+	status := make(map[string]ImportItem)
+	for _, item := range dryRun.Items {
+		status[item.Source] = item
+	}
+	assert.NotContains(t, status, ".obsidian/app.json")
+	assert.Equal(t, importStatusDuplicate, status["again.md"].Status)
+	assert.Equal(t, "existing", status["again.md"].NoteID)
+	assert.Equal(t, importStatusDuplicate, status["copy.go"].Status)
+	assert.Equal(t, "same content as code/main.go", status["copy.go"].Reason)
+	assert.Equal(t, "binary file", status["image.png"].Reason)
+	assert.Equal(t, "empty file", status["empty.md"].Reason)
+	assert.Equal(t, "New Year", status["Daily/2024-01-01.md"].Title)
+	assert.Equal(t, "vault/Daily/Deep", status["Daily/Deep/nested.md"].Folder)
+
+	report, err := ns.ImportNotes(root, ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.ImportedCount)
+	assert.Equal(t, 4, report.FolderCount)
+	assert.Len(t, ns.noteList.Notes, 5)
+
+	folderByName := make(map[string]Folder)
+	for _, folder := range ns.ListFolders() {
+		folderByName[folder.Name] = folder
+	}
+	require.Len(t, folderByName, 4)
+	assert.Empty(t, folderByName["vault"].ParentID)
+	assert.Equal(t, folderByName["vault"].ID, folderByName["Daily"].ParentID)
+	assert.Equal(t, folderByName["Daily"].ID, folderByName["Deep"].ParentID)
+	assert.Contains(t, ns.GetTopLevelOrder(), TopLevelItem{Type: "folder", ID: folderByName["vault"].ID})
+
+	notes := make(map[string]Note)
+	for _, item := range report.Items {
+		if item.Status != importStatusImport {
+			continue
+		}
+		note, err := ns.LoadNote(item.NoteID)
+		require.NoError(t, err)
+		notes[item.Source] = *note
+	}
+	assert.Equal(t, "# Goals", notes["Daily/2024-01-01.md"].Content)
+	assert.Equal(t, []string{"journal", "plans"}, notes["Daily/2024-01-01.md"].Tags)
+	assert.Equal(t, "go", notes["code/main.go"].Language)
+	assert.Equal(t, "main", notes["code/main.go"].Title)
+	assert.Equal(t, "日本語のメモ", notes["memo.txt"].Content)
+	assert.Equal(t, "plaintext", notes["memo.txt"].Language)
+	for _, metadata := range ns.noteList.Notes {
+		if metadata.ID == notes["Daily/Deep/nested.md"].ID {
+			assert.Equal(t, folderByName["Deep"].ID, metadata.FolderID)
+		}
+	}
+
+	// 検索インデックスにも入り、再度取り込んでも増えない
+	hits, err := ns.SearchNotes("Goals", SearchOptions{})
+	require.NoError(t, err)
+	assert.Len(t, hits, 1)
+	again, err := ns.ImportNotes(root, ImportOptions{})
+	require.NoError(t, err)
+	assert.Zero(t, again.ImportedCount)
+	assert.Zero(t, again.FolderCount)
+	assert.Equal(t, 6, again.DuplicateCount)
+	assert.Len(t, ns.ListFolders(), 4)
+}
+
+func TestImportNotes_ENEX(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	enex := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE en-export SYSTEM "http://xml.evernote.com/pub/evernote-export3.dtd">
+<en-export export-date="20240101T000000Z" application="Evernote">
+  <note>
+    <title>Shopping</title>
+    <content><![CDATA[<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<!DOCTYPE en-note SYSTEM "http://xml.evernote.com/pub/enml2.dtd">
+<en-note><h2>List</h2><div><en-todo checked="true"/>milk</div><div><en-todo/>eggs &amp; bread</div><ul><li>one</li><li>two<ol><li>nested</li></ol></li></ul><div>line<br/>break&nbsp;here</div><en-media type="image/png" hash="abc"/></en-note>]]></content>
+    <created>20240101T000000Z</created>
+    <tag>home</tag>
+    <tag>todo</tag>
+    <resource><data encoding="base64">iVBORw0KGgo=</data></resource>
+  </note>
+  <note>
+    <title>Blank</title>
+    <content><![CDATA[<en-note><div><br/></div></en-note>]]></content>
+  </note>
+</en-export>`
+	path := filepath.Join(t.TempDir(), "My Notebook.enex")
+	require.NoError(t, os.WriteFile(path, []byte(enex), 0644))
+
+	report, err := ns.ImportNotes(path, ImportOptions{})
+	require.NoError(t, err)
+	require.Len(t, report.Items, 2)
+	assert.Equal(t, 1, report.ImportedCount)
+	assert.Equal(t, 1, report.SkippedCount)
+	assert.Equal(t, "My Notebook.enex#1", report.Items[0].Source)
+	assert.Equal(t, "empty note", report.Items[1].Reason)
+
+	note, err := ns.LoadNote(report.Items[0].NoteID)
+	require.NoError(t, err)
+	assert.Equal(t, "Shopping", note.Title)
+	assert.Equal(t, "markdown", note.Language)
+	assert.Equal(t, []string{"home", "todo"}, note.Tags)
+	assert.Equal(t, "## List\n- [x] milk\n- [ ] eggs & bread\n- one\n- two\n  1. nested\nline\nbreak here", note.Content)
+	folders := ns.ListFolders()
+	require.Len(t, folders, 1)
+	assert.Equal(t, "My Notebook", folders[0].Name)
+
+	notENEX := filepath.Join(t.TempDir(), "other.enex")
+	require.NoError(t, os.WriteFile(notENEX, []byte("<html></html>"), 0644))
+	_, err = ns.ImportNotes(notENEX, ImportOptions{})
+	assert.ErrorContains(t, err, "not an ENEX file")
+}
@@ -0,0 +1,339 @@
+package backend
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ------------------------------------------------------------
+// ノート単位の暗号化（ロック付きノート）
+// ------------------------------------------------------------
+//
+// パスワードなどを書いたノートだけを個別のパスフレーズで保護する。
+// 同期データ全体の暗号化 (sync_encryption.go) とは独立していて、併用もできる。
+//
+// - Encrypted=true のノートは、notes/<id>.json・クラウドとも Content に暗号文
+//   (lockedNoteContent の JSON) を保存する。noteCache・ListNotes・同期処理が扱うのも暗号文で、
+//   平文は UnlockNote の戻り値としてだけ渡す。
+// - UnlockNote で導出した鍵はメモリ上のセッションとして一定時間だけ保持し、その間に
+//   平文で SaveNote されたノートは保存時に暗号化し直す。セッションが切れた後の平文の保存は
+//   errNoteLocked で拒否する。
+// - 暗号化したノートの ContentHeader は常に空にし、検索インデックスには本文を入れず、
+//   変更履歴も残さない（暗号化前の平文の履歴は暗号化時に削除する）。
+// - 暗号化するのは本文だけで、タイトル・言語・タグ・フォルダ・更新日時は平文のまま
+//   notes/<id>.json・noteList_v2.json・クラウドに保存する。タイトルに秘密を書かないこと。
+// - モバイル版は暗号化したノートを読み取り専用で表示するだけで、復号も編集もしない。
+//   encrypted を知らない旧クライアントが暗号文のまま書き戻してフラグだけ落とした場合は、
+//   保存時に本文の形式から Encrypted を戻す (restoreEncryptedFlag)。
+
+const (
+	lockedNoteFormat         = "monaco-notepad-locked-note-v1"
+	defaultNoteUnlockTimeout = 5 * time.Minute
+)
+
+var (
+	errNoteLocked            = errors.New("note is locked")
+	errNoteIncorrectPassword = errors.New("incorrect passphrase")
+)
+
+// 暗号化したノート本文 (Note.Content に JSON 文字列として保存する)
+type lockedNoteContent struct {
+	Format string `json:"format"`
+	Salt   []byte `json:"salt"`
+	N      int    `json:"n"`
+	R      int    `json:"r"`
+	P      int    `json:"p"`
+	Nonce  []byte `json:"nonce"`
+	Data   []byte `json:"data"`
+}
+
+// ロック解除中のノートの鍵
+type noteUnlockSession struct {
+	key     []byte
+	salt    []byte
+	n, r, p int
+	timer   *time.Timer
+}
+
+// isLockedNoteContent は Content が暗号化済みか判定する
+func isLockedNoteContent(content string) bool {
+	return strings.HasPrefix(content, `{"format":"`+lockedNoteFormat+`"`)
+}
+
+// restoreEncryptedFlag は暗号文なのに Encrypted が落ちたノートのフラグを戻す
+func restoreEncryptedFlag(note *Note) {
+	if !note.Encrypted && isLockedNoteContent(note.Content) {
+		note.Encrypted = true
+	}
+}
+
+// ノートの暗号化に使う追加認証データ (別のノートの本文と入れ替えられても検出できる)
+func lockedNoteAAD(noteID string) []byte {
+	return []byte("locked-note:" + noteID)
+}
+
+// noteContentHeader は一覧に表示するプレビューを作る。暗号化したノートは平文を漏らさないよう空にする
+func noteContentHeader(note *Note) string {
+	if note.Encrypted {
+		return ""
+	}
+	return generateContentHeader(note.Content)
+}
+
+// パスフレーズと新しいソルトから鍵を作る
+func newNoteUnlockSession(passphrase string) (*noteUnlockSession, error) {
+	salt := make([]byte, syncEncryptionSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return deriveNoteUnlockSession(passphrase, salt, syncScryptN, syncScryptR, syncScryptP)
+}
+
+func deriveNoteUnlockSession(passphrase string, salt []byte, n, r, p int) (*noteUnlockSession, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, n, r, p, syncEncryptionKeyLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return &noteUnlockSession{key: key, salt: salt, n: n, r: r, p: p}, nil
+}
+
+func (k *noteUnlockSession) seal(noteID string, plain string) (string, error) {
+	aead, err := newSyncAEAD(k.key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	data, err := json.Marshal(lockedNoteContent{
+		Format: lockedNoteFormat,
+		Salt:   k.salt,
+		N:      k.n,
+		R:      k.r,
+		P:      k.p,
+		Nonce:  nonce,
+		Data:   aead.Seal(nil, nonce, []byte(plain), lockedNoteAAD(noteID)),
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// parseLockedNoteContent は暗号文を解析する
+func parseLockedNoteContent(content string) (*lockedNoteContent, error) {
+	var locked lockedNoteContent
+	if !isLockedNoteContent(content) {
+		return nil, fmt.Errorf("note content is not encrypted")
+	}
+	if err := json.Unmarshal([]byte(content), &locked); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted note: %w", err)
+	}
+	return &locked, nil
+}
+
+func (k *noteUnlockSession) open(noteID string, locked *lockedNoteContent) (string, error) {
+	aead, err := newSyncAEAD(k.key)
+	if err != nil {
+		return "", err
+	}
+	plain, err := aead.Open(nil, locked.Nonce, locked.Data, lockedNoteAAD(noteID))
+	if err != nil {
+		return "", errNoteIncorrectPassword
+	}
+	return string(plain), nil
+}
+
+// ------------------------------------------------------------
+// noteService からの利用
+// ------------------------------------------------------------
+
+// SetNoteLockedHandler はセッション切れでノートがロックされたときの通知先を設定する
+func (s *noteService) SetNoteLockedHandler(handler func(noteID string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onNoteLocked = handler
+}
+
+// ロック解除中のセッションを登録する (caller が s.mu を握っている前提)。
+// 期限が来たら破棄し、フロントエンドに通知する。
+func (s *noteService) startUnlockSessionLocked(noteID string, session *noteUnlockSession) {
+	s.endUnlockSessionLocked(noteID)
+	if s.unlockedNotes == nil {
+		s.unlockedNotes = make(map[string]*noteUnlockSession)
+	}
+	s.unlockedNotes[noteID] = session
+	session.timer = time.AfterFunc(s.noteUnlockTimeout(), func() {
+		s.mu.Lock()
+		current, ok := s.unlockedNotes[noteID]
+		if !ok || current != session {
+			s.mu.Unlock()
+			return
+		}
+		delete(s.unlockedNotes, noteID)
+		handler := s.onNoteLocked
+		s.mu.Unlock()
+		if handler != nil {
+			handler(noteID)
+		}
+	})
+}
+
+func (s *noteService) noteUnlockTimeout() time.Duration {
+	if s.unlockTimeout <= 0 {
+		return defaultNoteUnlockTimeout
+	}
+	return s.unlockTimeout
+}
+
+// セッションを破棄する (caller が s.mu を握っている前提)
+func (s *noteService) endUnlockSessionLocked(noteID string) {
+	if session, ok := s.unlockedNotes[noteID]; ok {
+		session.timer.Stop()
+		delete(s.unlockedNotes, noteID)
+	}
+}
+
+// sealNoteLocked は保存前のノートを暗号化する (caller が s.mu を握っている前提)。
+// 既に暗号文ならそのまま、平文ならロック解除中のセッションの鍵で暗号化する。
+func (s *noteService) sealNoteLocked(note *Note) error {
+	restoreEncryptedFlag(note)
+	if !note.Encrypted {
+		return nil
+	}
+	note.ContentHeader = ""
+	if isLockedNoteContent(note.Content) {
+		return nil
+	}
+	session, ok := s.unlockedNotes[note.ID]
+	if !ok {
+		return fmt.Errorf("failed to save note %s: %w", note.ID, errNoteLocked)
+	}
+	sealed, err := session.seal(note.ID, note.Content)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt note %s: %w", note.ID, err)
+	}
+	note.Content = sealed
+	// 編集を続けている間はロックしない
+	session.timer.Reset(s.noteUnlockTimeout())
+	return nil
+}
+
+// unlockNote はパスフレーズでノートを復号し、平文の本文を返す。
+// scrypt は重いため、s.mu を握らずに呼ぶ。
+func (s *noteService) unlockNote(noteID string, passphrase string) (*Note, *noteUnlockSession, error) {
+	s.mu.Lock()
+	var note Note
+	stored, err := s.loadNoteLocked(noteID)
+	if err == nil {
+		note = *stored
+		if !note.Encrypted {
+			err = fmt.Errorf("note %s is not encrypted", noteID)
+		}
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	locked, err := parseLockedNoteContent(note.Content)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 同期で届いた内容のパラメーターで大量のメモリを確保しない
+	if err := checkSyncScryptParams(locked.N, locked.R, locked.P); err != nil {
+		return nil, nil, fmt.Errorf("failed to unlock note %s: %w", noteID, err)
+	}
+	session, err := deriveNoteUnlockSession(passphrase, locked.Salt, locked.N, locked.R, locked.P)
+	if err != nil {
+		return nil, nil, err
+	}
+	plain, err := session.open(noteID, locked)
+	if err != nil {
+		return nil, nil, err
+	}
+	note.Content = plain
+	return &note, session, nil
+}
+
+// ノートをパスフレーズで暗号化する ------------------------------------------------------------
+// 暗号化前の平文の変更履歴は削除する。暗号化直後はロック解除された状態になる。
+func (s *noteService) EncryptNote(noteID string, passphrase string) error {
+	if len([]rune(passphrase)) < syncEncryptionMinPassLength {
+		return fmt.Errorf("passphrase must be at least %d characters", syncEncryptionMinPassLength)
+	}
+	session, err := newNoteUnlockSession(passphrase)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, err := s.loadNoteLocked(noteID)
+	if err != nil {
+		return err
+	}
+	if stored.Encrypted {
+		return fmt.Errorf("note %s is already encrypted", noteID)
+	}
+	note := *stored
+	note.Encrypted = true
+	s.startUnlockSessionLocked(noteID, session)
+	if err := s.saveNoteLocked(&note, NoteRevisionSourceLocal); err != nil {
+		s.endUnlockSessionLocked(noteID)
+		return err
+	}
+	return nil
+}
+
+// ノートのロックを解除し、平文の本文を返す ------------------------------------------------------------
+// 解除は unlockTimeout の間だけ有効で、その間は平文の本文で SaveNote できる。
+func (s *noteService) UnlockNote(noteID string, passphrase string) (*Note, error) {
+	note, session, err := s.unlockNote(noteID, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.startUnlockSessionLocked(noteID, session)
+	return note, nil
+}
+
+// ノートをただちにロックする ------------------------------------------------------------
+func (s *noteService) LockNote(noteID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endUnlockSessionLocked(noteID)
+}
+
+// ノートの暗号化を解除して平文に戻す ------------------------------------------------------------
+func (s *noteService) DecryptNote(noteID string, passphrase string) (*Note, error) {
+	note, _, err := s.unlockNote(noteID, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	note.Encrypted = false
+	note.ContentHeader = ""
+	if err := s.saveNoteLocked(note, NoteRevisionSourceLocal); err != nil {
+		return nil, err
+	}
+	s.endUnlockSessionLocked(noteID)
+	return note, nil
+}
+
+// ロック解除中のノートか ------------------------------------------------------------
+func (s *noteService) IsNoteUnlocked(noteID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.unlockedNotes[noteID]
+	return ok
+}
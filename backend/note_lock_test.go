@@ -0,0 +1,190 @@
+package backend
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNotePassphrase = "correct horse battery"
+
+func TestEncryptNote_NoPlaintextOnDiskOrInIndex(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	note := &Note{ID: "locked-1", Title: "bank", Content: "pin 4242 and password hunter2", Language: "plaintext"}
+	require.NoError(t, ns.SaveNote(note))
+	assert.Error(t, ns.EncryptNote(note.ID, "short"))
+	require.NoError(t, ns.EncryptNote(note.ID, testNotePassphrase))
+
+	for _, path := range []string{
+		filepath.Join(helper.notesDir, note.ID+".json"),
+		ns.noteListPath(),
+	} {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "hunter2", path)
+	}
+
+	stored, err := ns.LoadNote(note.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.Encrypted)
+	assert.True(t, isLockedNoteContent(stored.Content))
+	assert.Empty(t, stored.ContentHeader)
+	require.Len(t, ns.noteList.Notes, 1)
+	assert.True(t, ns.noteList.Notes[0].Encrypted)
+	assert.Empty(t, ns.noteList.Notes[0].ContentHeader)
+
+	// 暗号化前の平文の履歴は残さない
+	revisions, err := ns.ListNoteRevisions(note.ID)
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+
+	// 本文は検索できず、タイトルだけが一致する
+	hits, err := ns.SearchNotes("hunter2", SearchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+	hits, err = ns.SearchNotes("bank", SearchOptions{})
+	require.NoError(t, err)
+	assert.Len(t, hits, 1)
+
+	// ヘッダーを生成する経路も平文を使わない
+	assert.Empty(t, noteContentHeader(&Note{Content: "secret", Encrypted: true}))
+}
+
+func TestUnlockNote_SaveWhileUnlockedAndLock(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	require.NoError(t, ns.SaveNote(&Note{ID: "locked-2", Title: "keys", Content: "ssh passphrase", Language: "plaintext"}))
+	require.NoError(t, ns.EncryptNote("locked-2", testNotePassphrase))
+	ns.LockNote("locked-2")
+	assert.False(t, ns.IsNoteUnlocked("locked-2"))
+
+	_, err := ns.UnlockNote("locked-2", "wrong passphrase")
+	assert.ErrorIs(t, err, errNoteIncorrectPassword)
+
+	unlocked, err := ns.UnlockNote("locked-2", testNotePassphrase)
+	require.NoError(t, err)
+	assert.Equal(t, "ssh passphrase", unlocked.Content)
+	assert.True(t, ns.IsNoteUnlocked("locked-2"))
+
+	// ロック解除中は平文で保存でき、保存時に暗号化される
+	unlocked.Content = "ssh passphrase v2"
+	unlocked.ContentHeader = "ssh passphrase v2"
+	require.NoError(t, ns.SaveNote(unlocked))
+	stored, err := ns.LoadNote("locked-2")
+	require.NoError(t, err)
+	assert.True(t, isLockedNoteContent(stored.Content))
+	assert.Empty(t, stored.ContentHeader)
+
+	reopened, err := ns.UnlockNote("locked-2", testNotePassphrase)
+	require.NoError(t, err)
+	assert.Equal(t, "ssh passphrase v2", reopened.Content)
+
+	// ロック後は平文の保存を拒否し、暗号文のままのメタデータ変更は受け付ける
+	ns.LockNote("locked-2")
+	reopened.Content = "should not be written"
+	assert.ErrorIs(t, ns.SaveNote(reopened), errNoteLocked)
+
+	renamed := *stored
+	renamed.Title = "keys (old)"
+	require.NoError(t, ns.SaveNote(&renamed))
+
+	decrypted, err := ns.DecryptNote("locked-2", testNotePassphrase)
+	require.NoError(t, err)
+	assert.False(t, decrypted.Encrypted)
+	assert.Equal(t, "ssh passphrase v2", decrypted.Content)
+	assert.Equal(t, "ssh passphrase v2", decrypted.ContentHeader)
+	assert.False(t, ns.noteList.Notes[0].Encrypted)
+}
+
+func TestUnlockNote_SessionTimeout(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+	ns.unlockTimeout = 50 * time.Millisecond
+	locked := make(chan string, 1)
+	ns.SetNoteLockedHandler(func(noteID string) { locked <- noteID })
+
+	require.NoError(t, ns.SaveNote(&Note{ID: "locked-3", Title: "t", Content: "secret", Language: "plaintext"}))
+	require.NoError(t, ns.EncryptNote("locked-3", testNotePassphrase))
+	assert.True(t, ns.IsNoteUnlocked("locked-3"))
+
+	select {
+	case id := <-locked:
+		assert.Equal(t, "locked-3", id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("note was not locked after the session timeout")
+	}
+	assert.False(t, ns.IsNoteUnlocked("locked-3"))
+}
+
+func TestEncryptedFlagRestoredFromLockedContent(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	require.NoError(t, ns.SaveNote(&Note{ID: "locked-4", Title: "t", Content: "secret", Language: "plaintext"}))
+	require.NoError(t, ns.EncryptNote("locked-4", testNotePassphrase))
+	ns.LockNote("locked-4")
+	stored, err := ns.LoadNote("locked-4")
+	require.NoError(t, err)
+
+	// encrypted を知らない旧クライアントがタイトルだけ変えて書き戻した
+	fromOldClient := *stored
+	fromOldClient.Encrypted = false
+	fromOldClient.Title = "renamed"
+	fromOldClient.ContentHeader = fromOldClient.Content
+	require.NoError(t, ns.SaveNoteFromSync(&fromOldClient))
+	synced, err := ns.LoadNote("locked-4")
+	require.NoError(t, err)
+	assert.True(t, synced.Encrypted)
+	assert.Empty(t, synced.ContentHeader)
+
+	fromOldClient.Encrypted = false
+	require.NoError(t, ns.SaveNote(&fromOldClient))
+	saved, err := ns.LoadNote("locked-4")
+	require.NoError(t, err)
+	assert.True(t, saved.Encrypted)
+	assert.Equal(t, "renamed", saved.Title)
+	require.Len(t, ns.noteList.Notes, 1)
+	assert.True(t, ns.noteList.Notes[0].Encrypted)
+	assert.Empty(t, ns.noteList.Notes[0].ContentHeader)
+
+	unlocked, err := ns.UnlockNote("locked-4", testNotePassphrase)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", unlocked.Content)
+}
+
+// 同期で届いた暗号化ノートの scrypt のパラメーターが範囲外なら、鍵を導出せずに断る
+func TestUnlockNote_RejectsHostileScryptParams(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	require.NoError(t, ns.SaveNote(&Note{ID: "locked-5", Title: "t", Content: "secret", Language: "plaintext"}))
+	require.NoError(t, ns.EncryptNote("locked-5", testNotePassphrase))
+	ns.LockNote("locked-5")
+	stored, err := ns.LoadNote("locked-5")
+	require.NoError(t, err)
+
+	locked, err := parseLockedNoteContent(stored.Content)
+	require.NoError(t, err)
+	locked.N = 1 << 30
+	data, err := json.Marshal(locked)
+	require.NoError(t, err)
+	hostile := *stored
+	hostile.Content = string(data)
+	require.NoError(t, ns.SaveNoteFromSync(&hostile))
+
+	_, err = ns.UnlockNote("locked-5", testNotePassphrase)
+	assert.ErrorContains(t, err, "unsupported scrypt parameters")
+}
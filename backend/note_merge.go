@@ -0,0 +1,210 @@
+package backend
+
+import (
+	"strings"
+	"time"
+)
+
+// ------------------------------------------------------------
+// 両端末で編集されたノートの 3-way マージ
+// ------------------------------------------------------------
+
+// 両側が同じ範囲を別々に変更していたときに残すマーカー (git と同じ形式)
+const (
+	mergeConflictLocalMarker = "<<<<<<< local\n"
+	mergeConflictSeparator   = "=======\n"
+	mergeConflictCloudMarker = ">>>>>>> cloud\n"
+)
+
+// lineHunk は base の [baseStart, baseEnd) 行を lines に置き換える変更のまとまり。
+// 挿入だけの変更は baseStart == baseEnd になる。
+type lineHunk struct {
+	baseStart int
+	baseEnd   int
+	lines     []string
+}
+
+// lineHunks は base → other の差分を、連続した変更ごとの hunk にまとめる
+func lineHunks(base, other []string) []lineHunk {
+	var hunks []lineHunk
+	var current *lineHunk
+	pos := 0
+	for _, e := range diffLines(base, other) {
+		if e.kind == lineEditEqual {
+			if current != nil {
+				hunks = append(hunks, *current)
+				current = nil
+			}
+			pos = e.aIndex + 1
+			continue
+		}
+		if current == nil {
+			current = &lineHunk{baseStart: pos, baseEnd: pos}
+		}
+		if e.kind == lineEditDelete {
+			pos = e.aIndex + 1
+			current.baseEnd = pos
+		} else {
+			current.lines = append(current.lines, other[e.bIndex])
+		}
+	}
+	if current != nil {
+		hunks = append(hunks, *current)
+	}
+	return hunks
+}
+
+// applyLineHunks は base の [start, end) に hunks (同じ範囲内に収まるもの) を適用した結果を返す
+func applyLineHunks(base []string, start, end int, hunks []lineHunk) string {
+	var sb strings.Builder
+	pos := start
+	for _, h := range hunks {
+		sb.WriteString(strings.Join(base[pos:h.baseStart], ""))
+		sb.WriteString(strings.Join(h.lines, ""))
+		pos = h.baseEnd
+	}
+	sb.WriteString(strings.Join(base[pos:end], ""))
+	return sb.String()
+}
+
+// mergeLines3Way は base から local / cloud への変更を行単位で合わせる。
+// 両側の変更が重なる (接する場合を含む) 範囲は、同じ変更でなければ衝突として
+// マーカー付きで両方を残し、conflicts に数える。
+func mergeLines3Way(base, local, cloud string) (merged string, conflicts int) {
+	baseLines := splitLinesKeepEnds(base)
+	localHunks := lineHunks(baseLines, splitLinesKeepEnds(local))
+	cloudHunks := lineHunks(baseLines, splitLinesKeepEnds(cloud))
+
+	var sb strings.Builder
+	pos, li, ci := 0, 0, 0
+	for li < len(localHunks) || ci < len(cloudHunks) {
+		// 先に始まる hunk から、範囲が重なる hunk を両側から取り込んで 1 つの領域にする
+		var start int
+		if ci >= len(cloudHunks) || (li < len(localHunks) && localHunks[li].baseStart <= cloudHunks[ci].baseStart) {
+			start = localHunks[li].baseStart
+		} else {
+			start = cloudHunks[ci].baseStart
+		}
+		end := start
+		localFrom, cloudFrom := li, ci
+		for {
+			if li < len(localHunks) && localHunks[li].baseStart <= end {
+				end = max(end, localHunks[li].baseEnd)
+				li++
+				continue
+			}
+			if ci < len(cloudHunks) && cloudHunks[ci].baseStart <= end {
+				end = max(end, cloudHunks[ci].baseEnd)
+				ci++
+				continue
+			}
+			break
+		}
+
+		sb.WriteString(strings.Join(baseLines[pos:start], ""))
+		localPart := applyLineHunks(baseLines, start, end, localHunks[localFrom:li])
+		cloudPart := applyLineHunks(baseLines, start, end, cloudHunks[cloudFrom:ci])
+		switch {
+		case ci == cloudFrom:
+			sb.WriteString(localPart)
+		case li == localFrom, localPart == cloudPart:
+			sb.WriteString(cloudPart)
+		default:
+			conflicts++
+			writeLineConflict(&sb, localPart, cloudPart)
+		}
+		pos = end
+	}
+	sb.WriteString(strings.Join(baseLines[pos:], ""))
+	return sb.String(), conflicts
+}
+
+// markLineConflicts は共通の base が分からない 2 つの版について、異なる部分をすべて
+// 競合マーカーで並べた本文を返す (手でマージする際のたたき台)
+func markLineConflicts(local, cloud string) (marked string, conflicts int) {
+	cloudLines := splitLinesKeepEnds(cloud)
+	var sb strings.Builder
+	pos := 0
+	for _, h := range lineHunks(cloudLines, splitLinesKeepEnds(local)) {
+		sb.WriteString(strings.Join(cloudLines[pos:h.baseStart], ""))
+		conflicts++
+		writeLineConflict(&sb, strings.Join(h.lines, ""), strings.Join(cloudLines[h.baseStart:h.baseEnd], ""))
+		pos = h.baseEnd
+	}
+	sb.WriteString(strings.Join(cloudLines[pos:], ""))
+	return sb.String(), conflicts
+}
+
+// hasConflictMarkers は本文に競合マーカーの行が残っているかを返す
+func hasConflictMarkers(text string) bool {
+	for _, line := range splitLinesKeepEnds(text) {
+		line = strings.TrimRight(line, "\r\n") + "\n"
+		if line == mergeConflictLocalMarker || line == mergeConflictCloudMarker {
+			return true
+		}
+	}
+	return false
+}
+
+func writeLineConflict(sb *strings.Builder, localPart, cloudPart string) {
+	sb.WriteString(mergeConflictLocalMarker)
+	writeConflictSide(sb, localPart)
+	sb.WriteString(mergeConflictSeparator)
+	writeConflictSide(sb, cloudPart)
+	sb.WriteString(mergeConflictCloudMarker)
+}
+
+// writeConflictSide はマーカーが行頭に来るよう、末尾に改行が無ければ補って書く
+func writeConflictSide(sb *strings.Builder, part string) {
+	sb.WriteString(part)
+	if part != "" && !strings.HasSuffix(part, "\n") {
+		sb.WriteString("\n")
+	}
+}
+
+// mergeValue3Way は単一の値の 3-way マージ。両側が base から別々の値に変えていれば ok=false
+func mergeValue3Way[T comparable](base, local, cloud T) (merged T, ok bool) {
+	switch {
+	case local == cloud, cloud == base:
+		return local, true
+	case local == base:
+		return cloud, true
+	default:
+		return local, false
+	}
+}
+
+// mergeNoteForSync は前回同期時点の base から local / cloud それぞれの変更を合わせたノートを返す。
+// 本文の変更が重なる、タイトルなどを両側で別々に変えている、暗号化ノートのいずれかなら ok=false
+// (呼び出し側は従来どおり新しい方を残し、負けた側をバックアップする)。タグは呼び出し側でマージ済みのものを渡す。
+func mergeNoteForSync(base *syncedNoteBase, local *Note, cloud *Note, tags []string) (*Note, bool) {
+	if base == nil || local.Encrypted || cloud.Encrypted {
+		return nil, false
+	}
+	title, ok := mergeValue3Way(base.Title, local.Title, cloud.Title)
+	if !ok {
+		return nil, false
+	}
+	language, ok := mergeValue3Way(base.Language, local.Language, cloud.Language)
+	if !ok {
+		return nil, false
+	}
+	archived, ok := mergeValue3Way(base.Archived, local.Archived, cloud.Archived)
+	if !ok {
+		return nil, false
+	}
+	content, conflicts := mergeLines3Way(base.Content, local.Content, cloud.Content)
+	if conflicts > 0 {
+		return nil, false
+	}
+
+	merged := *local
+	merged.Title = title
+	merged.Language = language
+	merged.Archived = archived
+	merged.Content = content
+	merged.ContentHeader = generateContentHeader(content)
+	merged.Tags = tags
+	merged.ModifiedTime = time.Now().Format(time.RFC3339)
+	return &merged, true
+}
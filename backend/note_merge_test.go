@@ -0,0 +1,104 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeLines3Way(t *testing.T) {
+	base := "one\ntwo\nthree\nfour\nfive\n"
+	cases := []struct {
+		name      string
+		local     string
+		cloud     string
+		want      string
+		conflicts int
+	}{
+		{"only local", "one\nTWO\nthree\nfour\nfive\n", base, "one\nTWO\nthree\nfour\nfive\n", 0},
+		{"only cloud", base, "one\ntwo\nthree\nfour\nfive\nsix\n", "one\ntwo\nthree\nfour\nfive\nsix\n", 0},
+		{"separate lines", "ONE\ntwo\nthree\nfour\nfive\n", "one\ntwo\nthree\nfour\nFIVE\n", "ONE\ntwo\nthree\nfour\nFIVE\n", 0},
+		{"insert and delete", "zero\none\ntwo\nthree\nfour\nfive\n", "one\ntwo\nfour\nfive\n", "zero\none\ntwo\nfour\nfive\n", 0},
+		{"same change", "one\nTWO\nthree\nfour\nfive\n", "one\nTWO\nthree\nfour\nFIVE\n", "one\nTWO\nthree\nfour\nFIVE\n", 0},
+		{
+			"overlap",
+			"one\nlocal\nthree\nfour\nfive\n",
+			"one\ncloud\nthree\nfour\nfive\n",
+			"one\n<<<<<<< local\nlocal\n=======\ncloud\n>>>>>>> cloud\nthree\nfour\nfive\n",
+			1,
+		},
+		{
+			"adjacent lines conflict",
+			"one\nTWO\nthree\nfour\nfive\n",
+			"one\ntwo\nTHREE\nfour\nfive\n",
+			"one\n<<<<<<< local\nTWO\nthree\n=======\ntwo\nTHREE\n>>>>>>> cloud\nfour\nfive\n",
+			1,
+		},
+		{
+			"both append without trailing newline",
+			"one\ntwo\nthree\nfour\nfive\nlocal",
+			"one\ntwo\nthree\nfour\nfive\ncloud",
+			"one\ntwo\nthree\nfour\nfive\n<<<<<<< local\nlocal\n=======\ncloud\n>>>>>>> cloud\n",
+			1,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			merged, conflicts := mergeLines3Way(base, tc.local, tc.cloud)
+			assert.Equal(t, tc.want, merged)
+			assert.Equal(t, tc.conflicts, conflicts)
+		})
+	}
+}
+
+func TestMergeNoteForSync(t *testing.T) {
+	baseNote := &Note{ID: "n", Title: "title", Language: "markdown", Content: "a\nb\nc\nd\n"}
+	base := &syncedNoteBase{Hash: computeContentHash(baseNote), Title: baseNote.Title, Language: baseNote.Language, Content: baseNote.Content}
+
+	local := *baseNote
+	local.Content = "A\nb\nc\nd\n"
+	local.FolderID = "folder"
+	cloud := *baseNote
+	cloud.Content = "a\nb\nc\nD\n"
+	cloud.Title = "renamed"
+
+	merged, ok := mergeNoteForSync(base, &local, &cloud, []string{"tag"})
+	require.True(t, ok)
+	assert.Equal(t, "A\nb\nc\nD\n", merged.Content)
+	assert.Equal(t, "renamed", merged.Title)
+	assert.Equal(t, "folder", merged.FolderID)
+	assert.Equal(t, []string{"tag"}, merged.Tags)
+	assert.NotEmpty(t, merged.ContentHeader)
+
+	// タイトルを両側で別々に変えた、暗号化されている場合はマージしない
+	local.Title = "local title"
+	_, ok = mergeNoteForSync(base, &local, &cloud, nil)
+	assert.False(t, ok)
+	local.Title = baseNote.Title
+	cloud.Encrypted = true
+	_, ok = mergeNoteForSync(base, &local, &cloud, nil)
+	assert.False(t, ok)
+}
+
+func TestSyncState_NoteBase(t *testing.T) {
+	state := NewSyncState(t.TempDir())
+	note := &Note{ID: "note1", Title: "t", Content: "synced", Language: "plaintext"}
+	state.UpdateSyncedNoteBase(note)
+
+	// LastSyncedNoteHash と一致するまでは base として使わない
+	_, ok := state.GetSyncedNoteBase("note1")
+	assert.False(t, ok)
+	state.ClearDirty("ts", map[string]string{"note1": computeContentHash(note)})
+	base, ok := state.GetSyncedNoteBase("note1")
+	require.True(t, ok)
+	assert.Equal(t, "synced", base.Content)
+
+	state.UpdateSyncedNoteBase(&Note{ID: "note1", Title: "t", Content: "uploaded but not committed", Language: "plaintext"})
+	_, ok = state.GetSyncedNoteBase("note1")
+	assert.False(t, ok)
+
+	// 同期対象から外れたノートの base は消える
+	state.ClearDirty("ts", map[string]string{})
+	assert.NoFileExists(t, state.baseDir+"/note1.json")
+}
@@ -0,0 +1,397 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ------------------------------------------------------------
+// ノートの変更履歴 (ローカル専用、同期しない)
+// ------------------------------------------------------------
+//
+// appDataDir/note_revisions/<noteID>/<revisionID>.json に 1 リビジョン 1 ファイルで保存する。
+// revisionID は作成時刻 (UTC) で、ファイル名の辞書順がそのまま時系列になる。
+//
+// 自動保存は数秒おきに走るため、同じ経路 (source) の保存が
+// noteRevisionCoalesceWindow 以内に続いた場合は最新リビジョンを上書きする。
+// 件数と経過日数の上限を超えたリビジョンは書き込みのたびに古い順に削除する
+// (最新の 1 件は常に残す)。
+
+const (
+	noteRevisionDirName        = "note_revisions"
+	noteRevisionCoalesceWindow = 5 * time.Minute
+	maxNoteRevisionsPerNote    = 100
+	noteRevisionMaxAge         = 90 * 24 * time.Hour
+	noteRevisionIDLayout       = "20060102T150405.000000000Z"
+)
+
+// リビジョンの作成経路
+const (
+	NoteRevisionSourceLocal    = "local"    // UI からの保存
+	NoteRevisionSourceSync     = "sync"     // クラウドから取り込んだ版
+	NoteRevisionSourceBaseline = "baseline" // 履歴が無いノートの上書き前の版
+	NoteRevisionSourceRestore  = "restore"  // 過去のリビジョンからの復元
+	NoteRevisionSourceImport   = "import"   // 一括取り込みで作成した版
+)
+
+type noteRevisionStore struct {
+	dir string
+	now func() time.Time
+}
+
+func newNoteRevisionStore(appDataDir string) *noteRevisionStore {
+	return &noteRevisionStore{
+		dir: filepath.Join(appDataDir, noteRevisionDirName),
+		now: time.Now,
+	}
+}
+
+// リビジョン比較用のハッシュ (タイトル・本文・言語のみ。アーカイブ状態の変化では履歴を作らない)
+func computeRevisionHash(note *Note) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s", note.Title, note.Content, note.Language)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// ノートIDやリビジョンIDをパスに使う前に、パストラバーサル等の不正な値を弾く
+func validateRevisionPathElement(kind, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is empty", kind)
+	}
+	if strings.ContainsAny(value, "/\\") || strings.Contains(value, "..") {
+		return fmt.Errorf("invalid %s: %s", kind, value)
+	}
+	return nil
+}
+
+func (r *noteRevisionStore) noteDir(noteID string) (string, error) {
+	if err := validateRevisionPathElement("note id", noteID); err != nil {
+		return "", err
+	}
+	return filepath.Join(r.dir, noteID), nil
+}
+
+// リビジョンIDを古い順に返す
+func (r *noteRevisionStore) revisionIDs(noteID string) ([]string, error) {
+	dir, err := r.noteDir(noteID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, ".json"))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (r *noteRevisionStore) read(noteID, revisionID string) (*NoteRevision, error) {
+	dir, err := r.noteDir(noteID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateRevisionPathElement("revision id", revisionID); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, revisionID+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var revision NoteRevision
+	if err := json.Unmarshal(data, &revision); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+func (r *noteRevisionStore) write(revision *NoteRevision) error {
+	dir, err := r.noteDir(revision.NoteID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create revision directory: %w", err)
+	}
+	data, err := json.MarshalIndent(revision, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal revision: %w", err)
+	}
+	path := filepath.Join(dir, revision.ID+".json")
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write revision temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to finalize revision file: %w", err)
+	}
+	return nil
+}
+
+// hasRevisions はノートに 1 件以上のリビジョンがあるかを返す
+func (r *noteRevisionStore) hasRevisions(noteID string) bool {
+	ids, err := r.revisionIDs(noteID)
+	return err == nil && len(ids) > 0
+}
+
+// record はノートの現在の内容をリビジョンとして記録する。
+// 直前のリビジョンと内容が同じなら何もしない。
+func (r *noteRevisionStore) record(note *Note, source string) error {
+	if note == nil {
+		return fmt.Errorf("note is nil")
+	}
+	ids, err := r.revisionIDs(note.ID)
+	if err != nil {
+		return err
+	}
+
+	now := r.now().UTC()
+	hash := computeRevisionHash(note)
+	revision := &NoteRevision{
+		ID:          now.Format(noteRevisionIDLayout),
+		NoteID:      note.ID,
+		Title:       note.Title,
+		Language:    note.Language,
+		Content:     note.Content,
+		ContentHash: hash,
+		Size:        len(note.Content),
+		Source:      source,
+		CreatedAt:   now.Format(time.RFC3339Nano),
+		UpdatedAt:   now.Format(time.RFC3339Nano),
+	}
+
+	if len(ids) > 0 {
+		latest, err := r.read(note.ID, ids[len(ids)-1])
+		if err == nil {
+			if latest.ContentHash == hash {
+				return nil
+			}
+			createdAt, parseErr := time.Parse(time.RFC3339Nano, latest.CreatedAt)
+			if parseErr == nil && latest.Source == source && now.Sub(createdAt) < noteRevisionCoalesceWindow {
+				// 連続した保存は最新リビジョンにまとめる
+				revision.ID = latest.ID
+				revision.CreatedAt = latest.CreatedAt
+			} else if revision.ID <= latest.ID {
+				// 時計の分解能が粗い環境で baseline と同じ ID にならないよう、最新より後ろにずらす
+				if latestTime, err := time.Parse(noteRevisionIDLayout, latest.ID); err == nil {
+					revision.ID = latestTime.Add(time.Nanosecond).Format(noteRevisionIDLayout)
+				}
+			}
+		}
+	}
+
+	if err := r.write(revision); err != nil {
+		return err
+	}
+	return r.prune(note.ID, now)
+}
+
+// prune は件数・経過日数の上限を超えたリビジョンを古い順に削除する
+func (r *noteRevisionStore) prune(noteID string, now time.Time) error {
+	ids, err := r.revisionIDs(noteID)
+	if err != nil {
+		return err
+	}
+	dir, err := r.noteDir(noteID)
+	if err != nil {
+		return err
+	}
+	cutoff := now.Add(-noteRevisionMaxAge)
+	for i, id := range ids {
+		if i == len(ids)-1 {
+			break
+		}
+		expired := len(ids)-i > maxNoteRevisionsPerNote
+		if createdAt, parseErr := time.Parse(noteRevisionIDLayout, id); parseErr == nil && createdAt.Before(cutoff) {
+			expired = true
+		}
+		if !expired {
+			break
+		}
+		if err := os.Remove(filepath.Join(dir, id+".json")); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// list はリビジョンを新しい順に返す (Content は含めない)
+func (r *noteRevisionStore) list(noteID string) ([]NoteRevision, error) {
+	ids, err := r.revisionIDs(noteID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]NoteRevision, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		revision, err := r.read(noteID, ids[i])
+		if err != nil {
+			// 読み取り不能・パース不能なファイルはスキップする
+			continue
+		}
+		revision.Content = ""
+		result = append(result, *revision)
+	}
+	return result, nil
+}
+
+// deleteAll はノートのリビジョンを全て削除する
+func (r *noteRevisionStore) deleteAll(noteID string) error {
+	dir, err := r.noteDir(noteID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// ------------------------------------------------------------
+// noteService からの利用
+// ------------------------------------------------------------
+
+// 上書き前のノートを baseline 用に読む (caller が s.mu を握っている前提)。
+// 既に履歴があるノートでは不要なので nil を返し、保存のたびのディスク読み込みを避ける。
+func (s *noteService) baselineForRevisionLocked(noteID string) *Note {
+	if s.revisions == nil || s.revisions.hasRevisions(noteID) {
+		return nil
+	}
+	previous, err := s.loadNoteLocked(noteID)
+	if err != nil {
+		return nil
+	}
+	cp := *previous
+	return &cp
+}
+
+// 保存したノートをリビジョンとして記録する (caller が s.mu を握っている前提)。
+// baseline は baselineForRevisionLocked で取得した上書き前のノートで、
+// 上書きで失われる版を最初のリビジョンとして残す。
+// 履歴の書き込みに失敗してもノートの保存自体は失敗させない。
+func (s *noteService) recordRevisionLocked(baseline *Note, note *Note, source string) {
+	if s.revisions == nil {
+		return
+	}
+	// 暗号化したノートは履歴を残さず、暗号化前の平文の履歴も消す
+	if note.Encrypted {
+		s.deleteRevisionsLocked(note.ID)
+		return
+	}
+	if baseline != nil && computeRevisionHash(baseline) != computeRevisionHash(note) {
+		if err := s.revisions.record(baseline, NoteRevisionSourceBaseline); err != nil {
+			s.logConsole("Failed to record baseline revision for %s: %v", note.ID, err)
+		}
+	}
+	if err := s.revisions.record(note, source); err != nil {
+		s.logConsole("Failed to record revision for %s: %v", note.ID, err)
+	}
+}
+
+func (s *noteService) deleteRevisionsLocked(id string) {
+	if s.revisions == nil {
+		return
+	}
+	if err := s.revisions.deleteAll(id); err != nil {
+		s.logConsole("Failed to delete revisions for %s: %v", id, err)
+	}
+}
+
+// ノートの変更履歴を新しい順に返す ------------------------------------------------------------
+func (s *noteService) ListNoteRevisions(noteID string) ([]NoteRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.revisions == nil {
+		return []NoteRevision{}, nil
+	}
+	return s.revisions.list(noteID)
+}
+
+// 指定されたリビジョンを本文付きで返す ------------------------------------------------------------
+func (s *noteService) GetNoteRevision(noteID string, revisionID string) (*NoteRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.revisions == nil {
+		return nil, fmt.Errorf("revision store is not available")
+	}
+	return s.revisions.read(noteID, revisionID)
+}
+
+// 2 つのリビジョンの unified diff を返す (toRevisionID が空なら現在のノートと比較) ------------------------------------------------------------
+func (s *noteService) DiffNoteRevisions(noteID string, fromRevisionID string, toRevisionID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.revisions == nil {
+		return "", fmt.Errorf("revision store is not available")
+	}
+	from, err := s.revisions.read(noteID, fromRevisionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read revision %s: %w", fromRevisionID, err)
+	}
+
+	toName := "current"
+	var toContent string
+	if toRevisionID == "" {
+		current, err := s.loadNoteLocked(noteID)
+		if err != nil {
+			return "", fmt.Errorf("failed to load note %s: %w", noteID, err)
+		}
+		toContent = current.Content
+	} else {
+		to, err := s.revisions.read(noteID, toRevisionID)
+		if err != nil {
+			return "", fmt.Errorf("failed to read revision %s: %w", toRevisionID, err)
+		}
+		toName = to.ID
+		toContent = to.Content
+	}
+	return unifiedDiff(from.ID, toName, from.Content, toContent, 3), nil
+}
+
+// 指定されたリビジョンの内容でノートを上書きする ------------------------------------------------------------
+// タイトル・本文・言語のみを戻し、アーカイブ状態やフォルダは現在のものを維持する。
+// 同期への反映 (MarkNoteDirty) は呼び出し側で行う。
+func (s *noteService) RestoreNoteRevision(noteID string, revisionID string) (*Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.revisions == nil {
+		return nil, fmt.Errorf("revision store is not available")
+	}
+	revision, err := s.revisions.read(noteID, revisionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revision %s: %w", revisionID, err)
+	}
+	current, err := s.loadNoteLocked(noteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load note %s: %w", noteID, err)
+	}
+
+	restored := *current
+	restored.Title = revision.Title
+	restored.Content = revision.Content
+	restored.Language = revision.Language
+	restored.ContentHeader = ""
+	for _, metadata := range s.noteList.Notes {
+		if metadata.ID == noteID {
+			restored.FolderID = metadata.FolderID
+			break
+		}
+	}
+	if err := s.saveNoteLocked(&restored, NoteRevisionSourceRestore); err != nil {
+		return nil, err
+	}
+	return &restored, nil
+}
@@ -0,0 +1,182 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// テスト用に時計を差し替える
+func setRevisionClock(ns *noteService, now *time.Time) {
+	ns.revisions.now = func() time.Time { return *now }
+}
+
+// 連続した自動保存は 1 リビジョンにまとめ、時間が空けば新しいリビジョンを作ること
+func TestNoteRevisions_CoalescesAutosave(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	setRevisionClock(ns, &now)
+
+	note := &Note{ID: "n1", Title: "t", Content: "v1"}
+	require.NoError(t, ns.SaveNote(note))
+	for i := 0; i < 10; i++ {
+		now = now.Add(3 * time.Second)
+		note.Content = "v1" + string(rune('a'+i))
+		require.NoError(t, ns.SaveNote(note))
+	}
+
+	revisions, err := ns.ListNoteRevisions("n1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, NoteRevisionSourceLocal, revisions[0].Source)
+	assert.Empty(t, revisions[0].Content, "一覧には本文を含めない")
+	assert.NotEqual(t, revisions[0].CreatedAt, revisions[0].UpdatedAt)
+
+	latest, err := ns.GetNoteRevision("n1", revisions[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "v1j", latest.Content)
+
+	now = now.Add(noteRevisionCoalesceWindow)
+	note.Content = "v2"
+	require.NoError(t, ns.SaveNote(note))
+	revisions, err = ns.ListNoteRevisions("n1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Greater(t, revisions[0].ID, revisions[1].ID, "新しい順に並ぶ")
+
+	// 内容が変わらない保存 (アーカイブ等) では履歴を作らない
+	now = now.Add(time.Hour)
+	note.Archived = true
+	require.NoError(t, ns.SaveNote(note))
+	revisions, err = ns.ListNoteRevisions("n1")
+	require.NoError(t, err)
+	assert.Len(t, revisions, 2)
+}
+
+// 同期で上書きされる前の、履歴を持たないノートの内容が baseline として残ること
+func TestNoteRevisions_BaselineBeforeFirstOverwrite(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	require.NoError(t, ns.SaveNote(&Note{ID: "n1", Title: "t", Content: "local"}))
+	require.NoError(t, ns.revisions.deleteAll("n1"))
+
+	require.NoError(t, ns.SaveNoteFromSync(&Note{ID: "n1", Title: "t", Content: "cloud"}))
+	revisions, err := ns.ListNoteRevisions("n1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, NoteRevisionSourceSync, revisions[0].Source)
+	assert.Equal(t, NoteRevisionSourceBaseline, revisions[1].Source)
+
+	baseline, err := ns.GetNoteRevision("n1", revisions[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "local", baseline.Content)
+}
+
+// 件数と経過日数の上限を超えた古いリビジョンが削除されること
+func TestNoteRevisions_Retention(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	setRevisionClock(ns, &now)
+
+	note := &Note{ID: "n1", Title: "t"}
+	for i := 0; i < maxNoteRevisionsPerNote+5; i++ {
+		now = now.Add(noteRevisionCoalesceWindow)
+		note.Content = time.Duration(i).String()
+		require.NoError(t, ns.SaveNote(note))
+	}
+	revisions, err := ns.ListNoteRevisions("n1")
+	require.NoError(t, err)
+	assert.Len(t, revisions, maxNoteRevisionsPerNote)
+
+	// 期限切れのものは消えるが、最新の 1 件は残る
+	now = now.Add(noteRevisionMaxAge + time.Hour)
+	note.Content = "latest"
+	require.NoError(t, ns.SaveNote(note))
+	revisions, err = ns.ListNoteRevisions("n1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, NoteRevisionSourceLocal, revisions[0].Source)
+}
+
+// 復元すると内容だけが戻り、フォルダは維持され、履歴に restore が残ること
+func TestNoteRevisions_RestoreAndDiff(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	setRevisionClock(ns, &now)
+
+	folder, err := ns.CreateFolder("f")
+	require.NoError(t, err)
+	note := &Note{ID: "n1", Title: "old title", Content: "line1\nline2\n", Language: "markdown"}
+	require.NoError(t, ns.SaveNote(note))
+	require.NoError(t, ns.MoveNoteToFolder("n1", folder.ID))
+	revisions, err := ns.ListNoteRevisions("n1")
+	require.NoError(t, err)
+	oldID := revisions[0].ID
+
+	now = now.Add(time.Hour)
+	note.Title = "new title"
+	note.Content = "line1\nchanged\n"
+	require.NoError(t, ns.SaveNote(note))
+
+	diff, err := ns.DiffNoteRevisions("n1", oldID, "")
+	require.NoError(t, err)
+	assert.Contains(t, diff, "-line2\n+changed\n")
+
+	now = now.Add(time.Hour)
+	restored, err := ns.RestoreNoteRevision("n1", oldID)
+	require.NoError(t, err)
+	assert.Equal(t, "old title", restored.Title)
+	assert.Equal(t, "line1\nline2\n", restored.Content)
+	assert.Equal(t, folder.ID, restored.FolderID)
+
+	loaded, err := ns.LoadNote("n1")
+	require.NoError(t, err)
+	assert.Equal(t, "line1\nline2\n", loaded.Content)
+	for _, metadata := range ns.noteList.Notes {
+		if metadata.ID == "n1" {
+			assert.Equal(t, folder.ID, metadata.FolderID)
+			assert.Equal(t, computeContentHash(loaded), metadata.ContentHash)
+		}
+	}
+
+	revisions, err = ns.ListNoteRevisions("n1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	assert.Equal(t, NoteRevisionSourceRestore, revisions[0].Source)
+
+	diff, err = ns.DiffNoteRevisions("n1", oldID, revisions[0].ID)
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+}
+
+// 削除したノートの履歴は消え、不正な ID はパスとして使われないこと
+func TestNoteRevisions_DeleteAndValidation(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	require.NoError(t, ns.SaveNote(&Note{ID: "n1", Title: "t", Content: "c"}))
+	_, err := os.Stat(filepath.Join(helper.tempDir, noteRevisionDirName, "n1"))
+	require.NoError(t, err)
+
+	require.NoError(t, ns.DeleteNote("n1"))
+	_, err = os.Stat(filepath.Join(helper.tempDir, noteRevisionDirName, "n1"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = ns.GetNoteRevision("n1", "../../noteList_v2")
+	assert.Error(t, err)
+	_, err = ns.ListNoteRevisions("..")
+	assert.Error(t, err)
+}
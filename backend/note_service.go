@@ -3,9 +3,11 @@ package backend
 import (
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -14,7 +16,17 @@ import (
 	"github.com/google/uuid"
 )
 
-const CurrentVersion = "2.0"
+const CurrentVersion = "3.0"
+
+// ノート・フォルダの操作が失敗した理由 (ローカル API は errors.Is で HTTP ステータスを選ぶ)
+var (
+	errNoteNotFound         = errors.New("note not found")
+	errNoteArchived         = errors.New("note is archived")
+	errFolderNotFound       = errors.New("folder not found")
+	errFolderArchived       = errors.New("folder is archived")
+	errFolderNotEmpty       = errors.New("folder is not empty")
+	errFolderIntoDescendant = errors.New("cannot move folder into itself or its descendant")
+)
 
 // computeContentHash はノートの安定フィールドのみからハッシュを計算する
 func computeContentHash(note *Note) string {
@@ -22,6 +34,11 @@ func computeContentHash(note *Note) string {
 	// FolderIDはnoteListのみで管理され、ノートファイルには保存されないためハッシュに含めない
 	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%v",
 		note.ID, note.Title, note.Content, note.Language, note.Archived)
+	// タグ導入前のノートのハッシュを変えない (全ノートの再アップロードを避ける) よう、
+	// タグがある場合のみ追記する
+	if len(note.Tags) > 0 {
+		fmt.Fprintf(h, "\n%s", strings.Join(note.Tags, "\x00"))
+	}
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
@@ -71,6 +88,8 @@ type NoteService interface {
 //     `XxxLocked` という no-lock バリアントを別途用意し、内部からはこちらを使う。
 //   - drive_service.go のように noteList を直接触る外部コードは WithLock(fn) で
 //     クリティカルセクションを宣言する。
+//   - ロックの順序は linkedFiles.mu → mu (linked_files.go)。mu を握ったまま呼ぶコールバック
+//     (onNoteWritten など) から linkedFiles に触れない。
 type noteService struct {
 	notesDir                string
 	noteList                *NoteList
@@ -79,7 +98,13 @@ type noteService struct {
 	pendingIntegrityIssues  []IntegrityIssue
 	pendingIntegrityRepairs []string
 	pendingOrphanRecoveries []OrphanRecoveryInfo
-	recoveryApplied         string // 復旧方法: "", "backup", "rebuild"
+	recoveryApplied         string                        // 復旧方法: "", "backup", "rebuild"
+	searchIndex             *searchIndex                  // 全文検索インデックス（初回検索時に構築）
+	revisions               *noteRevisionStore            // ノートの変更履歴
+	unlockedNotes           map[string]*noteUnlockSession // ロック解除中の暗号化ノートの鍵
+	unlockTimeout           time.Duration                 // ロック解除の有効期間（0 なら defaultNoteUnlockTimeout）
+	onNoteLocked            func(noteID string)           // セッション切れでロックされたときの通知先
+	onNoteWritten           func(noteID string)           // ノートを書き込んだときの通知先（s.mu を握ったまま呼ぶ）
 	mu                      sync.Mutex
 }
 
@@ -120,6 +145,7 @@ func (s *noteService) SnapshotNoteList() *NoteList {
 		TopLevelOrder:         append([]TopLevelItem(nil), s.noteList.TopLevelOrder...),
 		ArchivedTopLevelOrder: append([]TopLevelItem(nil), s.noteList.ArchivedTopLevelOrder...),
 		CollapsedFolderIDs:    append([]string(nil), s.noteList.CollapsedFolderIDs...),
+		Trash:                 cloneTrashItems(s.noteList.Trash),
 	}
 	return cp
 }
@@ -140,6 +166,7 @@ func NewEmptyNoteService(notesDir string, logger AppLogger) *noteService {
 		},
 		logger:          logger,
 		noteCache:       make(map[string]*Note),
+		revisions:       newNoteRevisionStore(filepath.Dir(notesDir)),
 		recoveryApplied: "rebuild",
 	}
 }
@@ -154,6 +181,7 @@ func NewNoteService(notesDir string, logger AppLogger) (*noteService, error) {
 		},
 		logger:    logger,
 		noteCache: make(map[string]*Note),
+		revisions: newNoteRevisionStore(filepath.Dir(notesDir)),
 	}
 
 	// ノートリストの読み込み ※内部で物理ファイルとの不整合解決を行う
@@ -190,6 +218,8 @@ func (s *noteService) listNotesLocked() ([]Note, error) {
 				ModifiedTime:  metadata.ModifiedTime,
 				Archived:      true,
 				FolderID:      metadata.FolderID,
+				Tags:          metadata.Tags,
+				Encrypted:     metadata.Encrypted,
 			})
 		} else {
 			// アクティブなノートはコンテンツを読み込む
@@ -206,6 +236,8 @@ func (s *noteService) listNotesLocked() ([]Note, error) {
 					Archived:      false,
 					FolderID:      metadata.FolderID,
 					Syncing:       true,
+					Tags:          metadata.Tags,
+					Encrypted:     metadata.Encrypted,
 				})
 				continue
 			}
@@ -251,12 +283,28 @@ func (s *noteService) loadNoteLocked(id string) (*Note, error) {
 func (s *noteService) SaveNote(note *Note) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.saveNoteLocked(note, NoteRevisionSourceLocal)
+}
+
+// saveNoteLocked はロックを取らない (caller が s.mu を握っている前提)。
+// revisionSource は変更履歴に記録する作成経路。
+func (s *noteService) saveNoteLocked(note *Note, revisionSource string) error {
+	// 暗号化したノートは平文のまま書き込まない
+	if err := s.sealNoteLocked(note); err != nil {
+		return err
+	}
 	note.ModifiedTime = time.Now().Format(time.RFC3339)
+	note.Tags = normalizeTags(note.Tags)
 
 	// contentHeader が未設定かつ content が存在する場合、自動生成する。
 	// 空タイトルのノートでも一覧で本文プレビューを見せるため（モバイル側の救済処理と揃える）。
 	if strings.TrimSpace(note.ContentHeader) == "" {
-		note.ContentHeader = generateContentHeader(note.Content)
+		note.ContentHeader = noteContentHeader(note)
+	}
+
+	// LocalOnly は SetNoteLocalOnly でだけ切り替える（フロントエンドから届いたノートの値は使わない）
+	if index := slices.IndexFunc(s.noteList.Notes, func(metadata NoteMetadata) bool { return metadata.ID == note.ID }); index >= 0 {
+		note.LocalOnly = s.noteList.Notes[index].LocalOnly
 	}
 
 	// FolderIDはnoteList.jsonのみで管理するため、ノートファイルには書き込まない
@@ -269,14 +317,18 @@ func (s *noteService) SaveNote(note *Note) error {
 	}
 
 	contentHash := computeContentHash(note)
+	baseline := s.baselineForRevisionLocked(note.ID)
 
 	notePath := filepath.Join(s.notesDir, note.ID+".json")
 	if err := os.WriteFile(notePath, data, 0644); err != nil {
 		return err
 	}
 
-	// キャッシュを更新
+	// キャッシュと検索インデックス、変更履歴を更新
 	s.noteCache[note.ID] = note
+	s.indexNoteLocked(note)
+	s.recordRevisionLocked(baseline, note, revisionSource)
+	s.notifyNoteWrittenLocked(note.ID)
 
 	found := false
 
@@ -300,6 +352,9 @@ func (s *noteService) SaveNote(note *Note) error {
 				Archived:      note.Archived,
 				ContentHash:   contentHash,
 				FolderID:      updatedFolderID,
+				Tags:          note.Tags,
+				Encrypted:     note.Encrypted,
+				LocalOnly:     note.LocalOnly,
 			}
 
 			// archived状態が変化した場合は順序リストも同期する
@@ -360,6 +415,9 @@ func (s *noteService) SaveNote(note *Note) error {
 			ModifiedTime:  note.ModifiedTime,
 			Archived:      note.Archived,
 			ContentHash:   contentHash,
+			Tags:          note.Tags,
+			Encrypted:     note.Encrypted,
+			LocalOnly:     note.LocalOnly,
 		}
 
 		// 新規ノートはアクティブリスト先頭に追加して、UIの表示順と揃える
@@ -398,8 +456,10 @@ func (s *noteService) DeleteNote(id string) error {
 		return err
 	}
 
-	// キャッシュから削除
+	// キャッシュと検索インデックス、変更履歴から削除
 	delete(s.noteCache, id)
+	s.unindexNoteLocked(id)
+	s.deleteRevisionsLocked(id)
 
 	// ノートリストから削除
 	var updatedNotes []NoteMetadata
@@ -426,22 +486,44 @@ func (s *noteService) SaveNoteFromSync(note *Note) error {
 
 // saveNoteFromSyncLocked はロックを取らない (caller が s.mu を握っている前提)。
 func (s *noteService) saveNoteFromSyncLocked(note *Note) error {
+	restoreEncryptedFlag(note)
 	// contentHeader が未設定なら生成（古いクライアントが作ったノートへの救済）
-	if strings.TrimSpace(note.ContentHeader) == "" {
+	if note.Encrypted {
+		note.ContentHeader = ""
+	} else if strings.TrimSpace(note.ContentHeader) == "" {
 		note.ContentHeader = generateContentHeader(note.Content)
 	}
 	data, err := json.MarshalIndent(note, "", "  ")
 	if err != nil {
 		return err
 	}
+	baseline := s.baselineForRevisionLocked(note.ID)
 	notePath := filepath.Join(s.notesDir, note.ID+".json")
 	if err := os.WriteFile(notePath, data, 0644); err != nil {
 		return err
 	}
 	s.noteCache[note.ID] = note
+	s.indexNoteLocked(note)
+	s.recordRevisionLocked(baseline, note, NoteRevisionSourceSync)
+	s.notifyNoteWrittenLocked(note.ID)
 	return nil
 }
 
+// SetNoteWrittenHandler はノートを書き込んだとき (ローカルの保存・同期・タグの変更など) の通知先を設定する
+// handler は s.mu を握ったまま呼ぶので、他のロック (linkedFiles.mu など) を待たずにすぐ戻ること。
+func (s *noteService) SetNoteWrittenHandler(handler func(noteID string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onNoteWritten = handler
+}
+
+// notifyNoteWrittenLocked は書き込んだノートを通知する (caller が s.mu を握っている前提)
+func (s *noteService) notifyNoteWrittenLocked(noteID string) {
+	if s.onNoteWritten != nil {
+		s.onNoteWritten(noteID)
+	}
+}
+
 // 同期パスから呼ばれるノート削除（LastSync を更新しない、noteList.json も書かない）
 //
 // drive_service が WithLock 内から呼ぶ場合は deleteNoteFromSyncLocked を使う。
@@ -458,6 +540,8 @@ func (s *noteService) deleteNoteFromSyncLocked(id string) error {
 		return err
 	}
 	delete(s.noteCache, id)
+	s.unindexNoteLocked(id)
+	s.deleteRevisionsLocked(id)
 	return nil
 }
 
@@ -471,6 +555,9 @@ func (s *noteService) buildNoteMetadata(note *Note) NoteMetadata {
 		Archived:      note.Archived,
 		ContentHash:   computeContentHash(note),
 		FolderID:      note.FolderID,
+		Tags:          note.Tags,
+		Encrypted:     note.Encrypted,
+		LocalOnly:     note.LocalOnly,
 	}
 }
 
@@ -507,7 +594,7 @@ func (s *noteService) UpdateNoteOrder(noteID string, newIndex int) error {
 	}
 
 	if oldIndex == -1 {
-		return fmt.Errorf("note not found: %s", noteID)
+		return fmt.Errorf("%w: %s", errNoteNotFound, noteID)
 	}
 
 	// ノートを新しい位置に移動
@@ -531,23 +618,7 @@ func (s *noteService) CreateFolder(name string) (*Folder, error) {
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-
-	folder := &Folder{
-		ID:   uuid.New().String(),
-		Name: name,
-	}
-
-	s.ensureTopLevelOrder()
-	s.noteList.Folders = append(s.noteList.Folders, *folder)
-	s.noteList.TopLevelOrder = append(
-		[]TopLevelItem{{Type: "folder", ID: folder.ID}},
-		s.noteList.TopLevelOrder...,
-	)
-
-	if err := s.saveNoteList(); err != nil {
-		return nil, err
-	}
-	return folder, nil
+	return s.createFolderLocked("", name)
 }
 
 // フォルダ名を変更する ------------------------------------------------------------
@@ -564,16 +635,21 @@ func (s *noteService) RenameFolder(id string, name string) error {
 			return s.saveNoteList()
 		}
 	}
-	return fmt.Errorf("folder not found: %s", id)
+	return fmt.Errorf("%w: %s", errFolderNotFound, id)
 }
 
-// フォルダを削除する（空の場合のみ） ------------------------------------------------------------
+// フォルダを削除する（ノートも子フォルダも無い場合のみ） ------------------------------------------------------------
 func (s *noteService) DeleteFolder(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	for _, note := range s.noteList.Notes {
 		if note.FolderID == id {
-			return fmt.Errorf("folder is not empty")
+			return errFolderNotEmpty
+		}
+	}
+	for _, folder := range s.noteList.Folders {
+		if folder.ParentID == id {
+			return errFolderNotEmpty
 		}
 	}
 
@@ -588,7 +664,7 @@ func (s *noteService) DeleteFolder(id string) error {
 	}
 
 	if !found {
-		return fmt.Errorf("folder not found: %s", id)
+		return fmt.Errorf("%w: %s", errFolderNotFound, id)
 	}
 
 	s.noteList.Folders = updatedFolders
@@ -610,7 +686,7 @@ func (s *noteService) MoveNoteToFolder(noteID string, folderID string) error {
 			}
 		}
 		if !folderFound {
-			return fmt.Errorf("folder not found: %s", folderID)
+			return fmt.Errorf("%w: %s", errFolderNotFound, folderID)
 		}
 	}
 
@@ -629,7 +705,7 @@ func (s *noteService) MoveNoteToFolder(noteID string, folderID string) error {
 			return s.saveNoteList()
 		}
 	}
-	return fmt.Errorf("note not found: %s", noteID)
+	return fmt.Errorf("%w: %s", errNoteNotFound, noteID)
 }
 
 // フォルダのリストを返す ------------------------------------------------------------
@@ -703,55 +779,43 @@ func (s *noteService) buildTopLevelOrder() []TopLevelItem {
 			order = append(order, TopLevelItem{Type: "note", ID: note.ID})
 		}
 	}
+	folderMap := s.folderMapLocked()
 	for _, folder := range s.noteList.Folders {
+		// 子フォルダは親フォルダの中に、アーカイブ済みフォルダはアーカイブ側に表示する
+		if !isFolderOrderRoot(folder, folderMap, false) {
+			continue
+		}
 		order = append(order, TopLevelItem{Type: "folder", ID: folder.ID})
 	}
 	return order
 }
 
-// フォルダをアーカイブする（中のノートも全てアーカイブ） ------------------------------------------------------------
+// フォルダをアーカイブする（配下のフォルダとノートも全てアーカイブ） ------------------------------------------------------------
 func (s *noteService) ArchiveFolder(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	folderIdx := -1
+	subtree := folderSubtreeIDs(s.noteList.Folders, id)
+	if len(subtree) == 0 {
+		return fmt.Errorf("%w: %s", errFolderNotFound, id)
+	}
+
 	for i, folder := range s.noteList.Folders {
-		if folder.ID == id {
-			folderIdx = i
-			break
+		if subtree[folder.ID] {
+			s.noteList.Folders[i].Archived = true
 		}
 	}
-	if folderIdx == -1 {
-		return fmt.Errorf("folder not found: %s", id)
-	}
-
-	s.noteList.Folders[folderIdx].Archived = true
 
-	now := time.Now().Format(time.RFC3339)
-	for i, metadata := range s.noteList.Notes {
-		if metadata.FolderID != id {
-			continue
-		}
-		note, err := s.loadNoteLocked(metadata.ID)
-		if err != nil {
-			s.logConsole("Skipped archiving note %s due to load failure: %v", metadata.ID, err)
-			continue
-		}
-		note.Archived = true
-		note.ModifiedTime = now
-		note.ContentHeader = generateContentHeader(note.Content)
-		s.noteList.Notes[i].Archived = true
-		s.noteList.Notes[i].ModifiedTime = now
-		s.noteList.Notes[i].ContentHash = computeContentHash(note)
-		s.noteList.Notes[i].ContentHeader = note.ContentHeader
-		if err := s.saveNoteFromSyncLocked(note); err != nil {
-			return fmt.Errorf("failed to save note %s: %v", note.ID, err)
-		}
+	if err := s.setSubtreeNotesArchivedLocked(subtree, true); err != nil {
+		return err
 	}
 
+	// アーカイブ側では配下のフォルダは親の中に表示するため、ルートだけを並べる
 	s.ensureTopLevelOrder()
-	s.removeFromTopLevelOrder(id)
-
 	s.ensureArchivedTopLevelOrder()
+	for folderID := range subtree {
+		s.removeFromTopLevelOrder(folderID)
+		s.removeFromArchivedTopLevelOrder(folderID)
+	}
 	s.noteList.ArchivedTopLevelOrder = append(
 		[]TopLevelItem{{Type: "folder", ID: id}},
 		s.noteList.ArchivedTopLevelOrder...,
@@ -760,76 +824,68 @@ func (s *noteService) ArchiveFolder(id string) error {
 	return s.saveNoteList()
 }
 
-// アーカイブされたフォルダを復元する（中のノートも全て復元） ------------------------------------------------------------
+// アーカイブされたフォルダを復元する（配下のフォルダとノートも全て復元） ------------------------------------------------------------
+// 親フォルダがアーカイブ済み・削除済みの場合はトップレベルに復元する。
 func (s *noteService) UnarchiveFolder(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	folderIdx := -1
-	for i, folder := range s.noteList.Folders {
-		if folder.ID == id {
-			folderIdx = i
-			break
-		}
+	folderMap := s.folderMapLocked()
+	folder, ok := folderMap[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", errFolderNotFound, id)
 	}
-	if folderIdx == -1 {
-		return fmt.Errorf("folder not found: %s", id)
-	}
-	if !s.noteList.Folders[folderIdx].Archived {
+	if !folder.Archived {
 		return fmt.Errorf("folder is not archived: %s", id)
 	}
 
-	s.noteList.Folders[folderIdx].Archived = false
+	parentID := folder.ParentID
+	if parent, ok := folderMap[parentID]; parentID != "" && (!ok || parent.Archived) {
+		parentID = ""
+	}
 
-	now := time.Now().Format(time.RFC3339)
-	for i, metadata := range s.noteList.Notes {
-		if metadata.FolderID != id || !metadata.Archived {
-			continue
-		}
-		note, err := s.loadNoteLocked(metadata.ID)
-		if err != nil {
-			s.logConsole("Skipped restoring note %s due to load failure: %v", metadata.ID, err)
+	subtree := folderSubtreeIDs(s.noteList.Folders, id)
+	for i, f := range s.noteList.Folders {
+		if !subtree[f.ID] {
 			continue
 		}
-		note.Archived = false
-		note.ModifiedTime = now
-		s.noteList.Notes[i].Archived = false
-		s.noteList.Notes[i].ModifiedTime = now
-		s.noteList.Notes[i].ContentHash = computeContentHash(note)
-		if err := s.saveNoteFromSyncLocked(note); err != nil {
-			return fmt.Errorf("failed to save note %s: %v", note.ID, err)
+		s.noteList.Folders[i].Archived = false
+		if f.ID == id {
+			s.noteList.Folders[i].ParentID = parentID
 		}
 	}
 
-	s.removeFromArchivedTopLevelOrder(id)
+	if err := s.setSubtreeNotesArchivedLocked(subtree, false); err != nil {
+		return err
+	}
 
-	s.ensureTopLevelOrder()
-	s.noteList.TopLevelOrder = append(
-		s.noteList.TopLevelOrder,
-		TopLevelItem{Type: "folder", ID: id},
-	)
+	for folderID := range subtree {
+		s.removeFromArchivedTopLevelOrder(folderID)
+	}
+
+	if parentID == "" {
+		s.ensureTopLevelOrder()
+		s.noteList.TopLevelOrder = append(
+			s.noteList.TopLevelOrder,
+			TopLevelItem{Type: "folder", ID: id},
+		)
+	}
 
 	return s.saveNoteList()
 }
 
-// アーカイブされたフォルダを削除する（中のノートファイルも全て削除） ------------------------------------------------------------
+// アーカイブされたフォルダを削除する（配下のフォルダとノートファイルも全て削除） ------------------------------------------------------------
 func (s *noteService) DeleteArchivedFolder(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	found := false
-	for _, folder := range s.noteList.Folders {
-		if folder.ID == id {
-			found = true
-			break
-		}
-	}
-	if !found {
-		return fmt.Errorf("folder not found: %s", id)
+	subtree := folderSubtreeIDs(s.noteList.Folders, id)
+	if len(subtree) == 0 {
+		return fmt.Errorf("%w: %s", errFolderNotFound, id)
 	}
 
 	var remainingNotes []NoteMetadata
 	deletedCount := 0
 	for _, metadata := range s.noteList.Notes {
-		if metadata.FolderID == id {
+		if metadata.FolderID != "" && subtree[metadata.FolderID] {
 			notePath := filepath.Join(s.notesDir, metadata.ID+".json")
 			if err := os.Remove(notePath); err == nil {
 				deletedCount++
@@ -837,6 +893,9 @@ func (s *noteService) DeleteArchivedFolder(id string) error {
 			} else if !os.IsNotExist(err) {
 				s.logConsole("Failed to delete archived note \"%s\": %v", metadata.Title, err)
 			}
+			delete(s.noteCache, metadata.ID)
+			s.unindexNoteLocked(metadata.ID)
+			s.deleteRevisionsLocked(metadata.ID)
 		} else {
 			remainingNotes = append(remainingNotes, metadata)
 		}
@@ -857,14 +916,16 @@ func (s *noteService) DeleteArchivedFolder(id string) error {
 
 	var remainingFolders []Folder
 	for _, folder := range s.noteList.Folders {
-		if folder.ID != id {
+		if !subtree[folder.ID] {
 			remainingFolders = append(remainingFolders, folder)
 		}
 	}
 	s.noteList.Folders = remainingFolders
 
-	s.removeFromArchivedTopLevelOrder(id)
-	s.removeFromTopLevelOrder(id)
+	for folderID := range subtree {
+		s.removeFromArchivedTopLevelOrder(folderID)
+		s.removeFromTopLevelOrder(folderID)
+	}
 
 	return s.saveNoteList()
 }
@@ -894,6 +955,7 @@ func (s *noteService) ensureArchivedTopLevelOrder() {
 }
 
 func (s *noteService) buildArchivedTopLevelOrder() []TopLevelItem {
+	folderMap := s.folderMapLocked()
 	archivedFolderIDs := make(map[string]bool)
 	for _, folder := range s.noteList.Folders {
 		if folder.Archived {
@@ -903,7 +965,7 @@ func (s *noteService) buildArchivedTopLevelOrder() []TopLevelItem {
 
 	var order []TopLevelItem
 	for _, folder := range s.noteList.Folders {
-		if folder.Archived {
+		if isFolderOrderRoot(folder, folderMap, true) {
 			order = append(order, TopLevelItem{Type: "folder", ID: folder.ID})
 		}
 	}
@@ -1137,6 +1199,9 @@ func (s *noteService) rebuildFromPhysicalFiles() error {
 			ModifiedTime:  note.ModifiedTime,
 			Archived:      note.Archived,
 			ContentHash:   computeContentHash(note),
+			Tags:          note.Tags,
+			Encrypted:     note.Encrypted,
+			LocalOnly:     note.LocalOnly,
 		})
 		recoveredCount++
 	}
@@ -1186,7 +1251,8 @@ func (s *noteService) isNoteListEqual(a, b []NoteMetadata) bool {
 			sortedA[i].ModifiedTime != sortedB[i].ModifiedTime ||
 			sortedA[i].Archived != sortedB[i].Archived ||
 			sortedA[i].ContentHash != sortedB[i].ContentHash ||
-			sortedA[i].FolderID != sortedB[i].FolderID {
+			sortedA[i].FolderID != sortedB[i].FolderID ||
+			!slices.Equal(sortedA[i].Tags, sortedB[i].Tags) {
 			return false
 		}
 	}
@@ -1225,6 +1291,9 @@ func (s *noteService) resolveMetadataConflicts() error {
 			Archived:      note.Archived,
 			ContentHash:   listMetadata.ContentHash,
 			FolderID:      listMetadata.FolderID,
+			Tags:          note.Tags,
+			Encrypted:     note.Encrypted,
+			LocalOnly:     listMetadata.LocalOnly || note.LocalOnly,
 		}
 
 		// メタデータの競合を解決
@@ -1327,6 +1396,7 @@ func (s *noteService) logConsole(format string, args ...interface{}) {
 // 起動時および同期完了後に呼ばれ、以下を行う:
 // 1. リストに無い孤立物理ファイル → noteListに復活
 // 2. 物理ファイルが無いリストエントリ → noteListから除去
+// 2.5. フォルダの親子関係の循環・存在しない親 → トップレベルへ戻す
 // 3. TopLevelOrder / ArchivedTopLevelOrder の無効参照を除去
 func (s *noteService) ValidateIntegrity() (changed bool, err error) {
 	s.mu.Lock()
@@ -1356,6 +1426,10 @@ func (s *noteService) validateIntegrityLocked() (changed bool, err error) {
 	for _, metadata := range s.noteList.Notes {
 		noteIDSet[metadata.ID] = true
 	}
+	// ゴミ箱にあるノートのファイルは孤立扱いしない
+	for id := range trashNoteIDs(s.noteList.Trash) {
+		noteIDSet[id] = true
+	}
 
 	// 1. 孤立物理ファイルを自動復元（復元フォルダに追加）
 	physicalNotes := make(map[string]bool)
@@ -1400,6 +1474,10 @@ func (s *noteService) validateIntegrityLocked() (changed bool, err error) {
 			Archived:      false,
 			ContentHash:   computeContentHash(note),
 			FolderID:      recoveryFolderID,
+			Tags:          note.Tags,
+			Encrypted:     note.Encrypted,
+			// 同期しないノートは復元しても同期しない（印はノートファイルにも残している）
+			LocalOnly: note.LocalOnly,
 		})
 		noteIDSet[noteID] = true
 		recoveredOrphanCount++
@@ -1441,6 +1519,18 @@ func (s *noteService) validateIntegrityLocked() (changed bool, err error) {
 		}
 	}
 
+	// 2.5. フォルダの木構造を修復（存在しない親・循環・アーカイブ済みの親を外してトップレベルへ）
+	{
+		normalized, repairs := normalizeFolderTree(s.noteList.Folders)
+		if len(repairs) > 0 {
+			s.noteList.Folders = normalized
+			for _, repair := range repairs {
+				logRepair("Folder tree: detached folder %s (%s)", repair.folderID, repair.reason)
+			}
+			changed = true
+		}
+	}
+
 	// 有効なノートID・フォルダIDのセットを構築（archived状態別）
 	activeNoteIDs := make(map[string]bool)
 	archivedNoteIDs := make(map[string]bool)
@@ -1453,15 +1543,26 @@ func (s *noteService) validateIntegrityLocked() (changed bool, err error) {
 	}
 	activeFolderIDs := make(map[string]bool)
 	archivedFolderIDs := make(map[string]bool)
+	// 表示順序に並ぶのはルートのフォルダのみ
+	activeRootFolderIDs := make(map[string]bool)
+	archivedRootFolderIDs := make(map[string]bool)
+	folderMap := s.folderMapLocked()
 	for _, f := range s.noteList.Folders {
 		if f.Archived {
 			archivedFolderIDs[f.ID] = true
 		} else {
 			activeFolderIDs[f.ID] = true
 		}
+		if isFolderOrderRoot(f, folderMap, f.Archived) {
+			if f.Archived {
+				archivedRootFolderIDs[f.ID] = true
+			} else {
+				activeRootFolderIDs[f.ID] = true
+			}
+		}
 	}
 
-	// 3. TopLevelOrder: アクティブなノート/フォルダのみ保持
+	// 3. TopLevelOrder: アクティブなノート/ルートのフォルダのみ保持
 	topLevelSeen := make(map[string]bool)
 	{
 		var cleaned []TopLevelItem
@@ -1474,7 +1575,7 @@ func (s *noteService) validateIntegrityLocked() (changed bool, err error) {
 				continue
 			}
 			isValid := (item.Type == "note" && activeNoteIDs[item.ID]) ||
-				(item.Type == "folder" && activeFolderIDs[item.ID])
+				(item.Type == "folder" && activeRootFolderIDs[item.ID])
 			if isValid {
 				topLevelSeen[key] = true
 				cleaned = append(cleaned, item)
@@ -1489,7 +1590,7 @@ func (s *noteService) validateIntegrityLocked() (changed bool, err error) {
 		}
 	}
 
-	// 4. ArchivedTopLevelOrder: アーカイブ済みノート/フォルダのみ保持
+	// 4. ArchivedTopLevelOrder: アーカイブ済みノート/ルートのフォルダのみ保持
 	archivedSeen := make(map[string]bool)
 	{
 		var cleaned []TopLevelItem
@@ -1502,7 +1603,7 @@ func (s *noteService) validateIntegrityLocked() (changed bool, err error) {
 				continue
 			}
 			isValid := (item.Type == "note" && archivedNoteIDs[item.ID]) ||
-				(item.Type == "folder" && archivedFolderIDs[item.ID])
+				(item.Type == "folder" && archivedRootFolderIDs[item.ID])
 			if isValid {
 				archivedSeen[key] = true
 				cleaned = append(cleaned, item)
@@ -1537,7 +1638,7 @@ func (s *noteService) validateIntegrityLocked() (changed bool, err error) {
 			}
 		}
 	}
-	for id := range activeFolderIDs {
+	for id := range activeRootFolderIDs {
 		if !topLevelSeen["folder:"+id] {
 			logRepair("TopLevelOrder: added missing folder %s", id)
 			s.noteList.TopLevelOrder = append(s.noteList.TopLevelOrder, TopLevelItem{Type: "folder", ID: id})
@@ -1565,7 +1666,7 @@ func (s *noteService) validateIntegrityLocked() (changed bool, err error) {
 			}
 		}
 	}
-	for id := range archivedFolderIDs {
+	for id := range archivedRootFolderIDs {
 		if !archivedSeen["folder:"+id] {
 			logRepair("ArchivedTopLevelOrder: added missing folder %s", id)
 			s.noteList.ArchivedTopLevelOrder = append(s.noteList.ArchivedTopLevelOrder, TopLevelItem{Type: "folder", ID: id})
@@ -1788,6 +1889,9 @@ func (s *noteService) RecoverOrphanNote(note *Note, recoveryFolderName string) e
 		Archived:      false,
 		ContentHash:   computeContentHash(note),
 		FolderID:      folderID,
+		Tags:          note.Tags,
+		Encrypted:     note.Encrypted,
+		LocalOnly:     note.LocalOnly,
 	})
 
 	return s.saveNoteList()
@@ -2003,6 +2107,9 @@ func (s *noteService) ApplyIntegrityFixes(selections []IntegrityFixSelection) (I
 					Archived:      note.Archived,
 					ContentHash:   computeContentHash(note),
 					FolderID:      note.FolderID,
+					Tags:          note.Tags,
+					Encrypted:     note.Encrypted,
+					LocalOnly:     note.LocalOnly,
 				})
 
 				if note.FolderID == "" && !note.Archived {
@@ -0,0 +1,210 @@
+package backend
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ------------------------------------------------------------
+// ノートのタグ
+// ------------------------------------------------------------
+//
+// タグはノートファイルと noteList の NoteMetadata の両方に持つ。
+// noteList にも載せるのは、タグ一覧や絞り込みでノート本文を読まずに済ませるため。
+// 保存時に正規化 (前後の空白と先頭の '#' を除去、重複除去、昇順) してから
+// ContentHash に含めるので、同じタグ集合なら端末に依らず同じハッシュになる。
+//
+// 2 台の端末で同じノートのタグを編集した場合は、前回同期時のタグを base に
+// ノート単位の 3-way マージを行う (mergeNoteTags)。ノートリスト全体を
+// last-writer-wins で置き換えると、片方の端末で付けたタグが消えるため。
+
+// normalizeTags はタグを正規化した新しいスライスを返す (空なら nil)
+func normalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(tags))
+	var result []string
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// normalizeTag は 1 つのタグを正規化する。空白だけのタグは空文字になる
+func normalizeTag(tag string) string {
+	tag = strings.TrimSpace(tag)
+	tag = strings.TrimLeft(tag, "#")
+	// 改行やタブはタグ名として扱えないので空白 1 つにまとめる
+	return strings.Join(strings.Fields(tag), " ")
+}
+
+// mergeNoteTags はノート 1 件分のタグを 3-way マージする。
+// 両方に残っているタグ、片方で追加されたタグは残し、片方で削除されたタグは消す。
+// base が無い (前回同期時のタグが不明な) 場合は和集合を返す。
+func mergeNoteTags(base []string, hasBase bool, local []string, cloud []string) []string {
+	if !hasBase {
+		return normalizeTags(append(append([]string(nil), local...), cloud...))
+	}
+	inBase := make(map[string]bool, len(base))
+	for _, tag := range base {
+		inBase[tag] = true
+	}
+	inLocal := make(map[string]bool, len(local))
+	for _, tag := range local {
+		inLocal[tag] = true
+	}
+	inCloud := make(map[string]bool, len(cloud))
+	for _, tag := range cloud {
+		inCloud[tag] = true
+	}
+
+	var merged []string
+	for _, tag := range local {
+		// 両方にある、またはローカルで追加された
+		if inCloud[tag] || !inBase[tag] {
+			merged = append(merged, tag)
+		}
+	}
+	for _, tag := range cloud {
+		// クラウドでのみ追加された
+		if !inLocal[tag] && !inBase[tag] {
+			merged = append(merged, tag)
+		}
+	}
+	return normalizeTags(merged)
+}
+
+// 全てのタグを使用ノート数付きで返す ------------------------------------------------------------
+func (s *noteService) ListTags() []TagCount {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]*TagCount)
+	for _, metadata := range s.noteList.Notes {
+		for _, tag := range metadata.Tags {
+			entry, ok := counts[tag]
+			if !ok {
+				entry = &TagCount{Tag: tag}
+				counts[tag] = entry
+			}
+			entry.Count++
+			if metadata.Archived {
+				entry.ArchivedCount++
+			}
+		}
+	}
+
+	result := make([]TagCount, 0, len(counts))
+	for _, entry := range counts {
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Tag < result[j].Tag
+	})
+	return result
+}
+
+// 指定タグが付いたノートのリストを返す ------------------------------------------------------------
+func (s *noteService) ListNotesByTag(tag string) ([]Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag = normalizeTag(tag)
+	notes, err := s.listNotesLocked()
+	if err != nil {
+		return nil, err
+	}
+	var filtered []Note
+	for _, note := range notes {
+		if slices.Contains(note.Tags, tag) {
+			filtered = append(filtered, note)
+		}
+	}
+	return filtered, nil
+}
+
+// タグを全ノートで改名する ------------------------------------------------------------
+// newTag が既に付いているノートでは 2 つのタグが 1 つにまとまる (マージ)。
+// 変更したノートIDを返す (呼び出し側で同期対象に積む)。
+func (s *noteService) RenameTag(oldTag string, newTag string) ([]string, error) {
+	oldTag = normalizeTag(oldTag)
+	newTag = normalizeTag(newTag)
+	if oldTag == "" || newTag == "" {
+		return nil, fmt.Errorf("tag name must not be empty")
+	}
+	if oldTag == newTag {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().Format(time.RFC3339)
+	var changedIDs []string
+	for i, metadata := range s.noteList.Notes {
+		if !slices.Contains(metadata.Tags, oldTag) {
+			continue
+		}
+		note, err := s.loadNoteLocked(metadata.ID)
+		if err != nil {
+			s.logConsole("Skipped renaming tag on note %s due to load failure: %v", metadata.ID, err)
+			continue
+		}
+
+		renamed := make([]string, 0, len(note.Tags))
+		for _, tag := range note.Tags {
+			if tag == oldTag {
+				tag = newTag
+			}
+			renamed = append(renamed, tag)
+		}
+
+		// キャッシュ上のノートを途中で書き換えないよう、コピーを保存する
+		updated := *note
+		updated.Tags = normalizeTags(renamed)
+		updated.ModifiedTime = now
+		if err := s.saveNoteFromSyncLocked(&updated); err != nil {
+			return changedIDs, fmt.Errorf("failed to save note %s: %v", note.ID, err)
+		}
+		s.noteList.Notes[i].Tags = updated.Tags
+		s.noteList.Notes[i].ModifiedTime = now
+		s.noteList.Notes[i].ContentHash = computeContentHash(&updated)
+		changedIDs = append(changedIDs, note.ID)
+	}
+
+	if len(changedIDs) == 0 {
+		return nil, nil
+	}
+	return changedIDs, s.saveNoteList()
+}
+
+// 同期時のタグのマージ結果をローカルのノートと noteList に反映する ------------------------------------------------------------
+// ローカル編集ではないので ModifiedTime は変えず、dirty にも積まない。反映後のノートを返す。
+func (s *noteService) SaveTagsFromSync(note *Note, tags []string) (*Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated := *note
+	updated.Tags = tags
+	if err := s.saveNoteFromSyncLocked(&updated); err != nil {
+		return nil, err
+	}
+	for i, metadata := range s.noteList.Notes {
+		if metadata.ID == updated.ID {
+			s.noteList.Notes[i].Tags = updated.Tags
+			s.noteList.Notes[i].ContentHash = computeContentHash(&updated)
+			break
+		}
+	}
+	return &updated, s.saveNoteList()
+}
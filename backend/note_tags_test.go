@@ -0,0 +1,155 @@
+package backend
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	assert.Equal(t, []string{"go", "two words", "仕事"}, normalizeTags([]string{" #仕事", "go", "", "two\t words ", "go", "##"}))
+	assert.Nil(t, normalizeTags([]string{" ", "#"}))
+	assert.Nil(t, normalizeTags(nil))
+}
+
+// 片方で追加・削除したタグがそれぞれ反映され、base が無ければ和集合になること
+func TestMergeNoteTags(t *testing.T) {
+	base := []string{"a", "b"}
+	local := []string{"a", "c"}
+	cloud := []string{"a", "b", "d"}
+	assert.Equal(t, []string{"a", "c", "d"}, mergeNoteTags(base, true, local, cloud))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, mergeNoteTags(nil, false, local, cloud))
+	// 両方で削除したタグは消える
+	assert.Nil(t, mergeNoteTags([]string{"x"}, true, nil, nil))
+}
+
+// タグの無いノートのハッシュはタグ導入前と変わらないこと
+func TestComputeContentHash_TagsOnlyWhenPresent(t *testing.T) {
+	note := &Note{ID: "n1", Title: "t", Content: "c", Language: "go"}
+	withoutTags := computeContentHash(note)
+	note.Tags = []string{}
+	assert.Equal(t, withoutTags, computeContentHash(note))
+	note.Tags = []string{"x"}
+	assert.NotEqual(t, withoutTags, computeContentHash(note))
+}
+
+func TestTags_ListRenameAndFilter(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	require.NoError(t, ns.SaveNote(&Note{ID: "n1", Title: "1", Content: "c", Tags: []string{"#work", "todo"}}))
+	require.NoError(t, ns.SaveNote(&Note{ID: "n2", Title: "2", Content: "c", Tags: []string{"job", "todo"}}))
+	require.NoError(t, ns.SaveNote(&Note{ID: "n3", Title: "3", Content: "c", Tags: []string{"work"}, Archived: true}))
+
+	assert.Equal(t, []TagCount{
+		{Tag: "job", Count: 1},
+		{Tag: "todo", Count: 2},
+		{Tag: "work", Count: 2, ArchivedCount: 1},
+	}, ns.ListTags())
+
+	notes, err := ns.ListNotesByTag("work")
+	require.NoError(t, err)
+	require.Len(t, notes, 2)
+
+	// 既存のタグ名への改名は 2 つのタグのマージになる
+	changed, err := ns.RenameTag("job", "work")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"n2"}, changed)
+	assert.Equal(t, []TagCount{
+		{Tag: "todo", Count: 2},
+		{Tag: "work", Count: 3, ArchivedCount: 1},
+	}, ns.ListTags())
+
+	loaded, err := ns.LoadNote("n2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"todo", "work"}, loaded.Tags)
+	for _, metadata := range ns.noteList.Notes {
+		if metadata.ID == "n2" {
+			assert.Equal(t, computeContentHash(loaded), metadata.ContentHash)
+		}
+	}
+
+	// アーカイブ済みノートも改名対象になり、フォルダ等の構造は変えない
+	changed, err = ns.RenameTag("work", "仕事")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"n1", "n2", "n3"}, changed)
+	notes, err = ns.ListNotesByTag("#仕事")
+	require.NoError(t, err)
+	assert.Len(t, notes, 3)
+
+	_, err = ns.RenameTag("todo", " ")
+	assert.Error(t, err)
+}
+
+// 両端末で同じノートのタグを編集した場合、本文の勝敗に関わらずタグはノート単位でマージされること
+func TestSyncNotes_CaseC_TagsMergedPerNote(t *testing.T) {
+	cases := []struct {
+		name              string
+		localModifiedTime string
+		expectedContent   string
+	}{
+		{"local wins", "", "local-edit"},
+		{"cloud wins", "2025-01-01T00:00:00Z", "cloud-edit"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ds, ops, cleanup := newSyncTestDriveService(t)
+			defer cleanup()
+
+			local := &Note{ID: "note1", Title: "note1", Content: "local-edit", Language: "plaintext", Tags: []string{"a", "c"}}
+			require.NoError(t, ds.noteService.SaveNote(local))
+			if tc.localModifiedTime != "" {
+				local.ModifiedTime = tc.localModifiedTime
+				require.NoError(t, ds.noteService.SaveNoteFromSync(local))
+			}
+			ds.syncState.MarkNoteDirty("note1")
+			ds.syncState.LastSyncedNoteHash["note1"] = "original-hash"
+			ds.syncState.LastSyncedNoteTags = map[string][]string{"note1": {"a", "b"}}
+			ops.fixedModifiedTime = "2025-01-02T00:00:00Z"
+			ds.syncState.LastSyncedDriveTs = "2025-01-01T00:00:00Z"
+
+			cloud := &Note{ID: "note1", Title: "note1", Content: "cloud-edit", Language: "plaintext", ModifiedTime: "2025-01-02T00:00:00Z", Tags: []string{"a", "b", "d"}}
+			putCloudNote(t, ops, cloud)
+			putCloudNoteList(t, ops, ds.auth.GetDriveSync().NoteListID(), &NoteList{
+				Version: CurrentVersion,
+				Notes: []NoteMetadata{{
+					ID:           "note1",
+					Title:        "note1",
+					Language:     "plaintext",
+					ModifiedTime: "2025-01-02T00:00:00Z",
+					ContentHash:  computeContentHash(cloud),
+					Tags:         cloud.Tags,
+				}},
+			})
+
+			require.NoError(t, ds.SyncNotes())
+
+			merged := []string{"a", "c", "d"}
+			updated := mustLoadLocalNote(t, ds, "note1")
+			assert.Equal(t, tc.expectedContent, updated.Content)
+			assert.Equal(t, merged, updated.Tags)
+			assert.False(t, ds.syncState.IsDirty())
+
+			ops.mu.RLock()
+			cloudData := ops.files["test-file-note1.json"]
+			ops.mu.RUnlock()
+			var uploaded Note
+			require.NoError(t, json.Unmarshal(cloudData, &uploaded))
+			assert.Equal(t, tc.expectedContent, uploaded.Content)
+			assert.Equal(t, merged, uploaded.Tags)
+
+			cloudList := cloudNoteListFromMock(t, ops, ds.auth.GetDriveSync().NoteListID())
+			require.Len(t, cloudList.Notes, 1)
+			assert.Equal(t, merged, cloudList.Notes[0].Tags)
+			assert.Equal(t, computeContentHash(updated), cloudList.Notes[0].ContentHash)
+
+			// 次回のマージの base として記録される
+			baseTags, hasBase := ds.syncState.GetSyncedNoteTags()
+			assert.True(t, hasBase)
+			assert.Equal(t, merged, baseTags["note1"])
+		})
+	}
+}
@@ -0,0 +1,504 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+// ------------------------------------------------------------
+// ゴミ箱
+// ------------------------------------------------------------
+//
+// 削除したノートとフォルダはすぐには消さず、noteList.Trash に移して同期する。
+// - ゴミ箱に移したノートとフォルダは Notes / Folders / (Archived)TopLevelOrder から外すが、
+//   ノートファイルはローカル・クラウドとも残す。
+// - 元に戻すと元のフォルダ (無くなっていればトップレベル) と TopLevelOrder 内の位置に戻す。
+//   ModifiedTime を更新し、他端末のゴミ箱とのマージで復元が優先されるようにする。
+// - ゴミ箱を空にしたときと保持期間 (Settings.TrashRetentionDays) を過ぎたときにファイルを削除する。
+//   クラウドからの削除は呼び出し側が SyncState.MarkNoteDeleted / MarkFolderDeleted で行う。
+// - noteList の trash は空でも常に書き出す。trash キーの無い noteList はゴミ箱を知らない
+//   クライアントが書いたものなので、pull・マージでは手元のゴミ箱を残し、ファイルも削除しない。
+
+const (
+	trashItemNote             = "note"
+	trashItemFolder           = "folder"
+	defaultTrashRetentionDays = 30
+)
+
+// trashRetention は保持日数の設定値から保持期間を返す (0 以下は既定値)
+func trashRetention(days int) time.Duration {
+	if days <= 0 {
+		days = defaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func cloneTrashItems(items []TrashItem) []TrashItem {
+	if items == nil {
+		return nil
+	}
+	cp := make([]TrashItem, len(items))
+	for i, item := range items {
+		cp[i] = item
+		cp[i].Notes = append([]NoteMetadata(nil), item.Notes...)
+		cp[i].Folders = append([]Folder(nil), item.Folders...)
+	}
+	return cp
+}
+
+// MarshalJSON はゴミ箱が空でも "trash": [] を書き出す (キーが無い noteList と区別するため)
+func (l NoteList) MarshalJSON() ([]byte, error) {
+	type noteListJSON NoteList
+	v := noteListJSON(l)
+	if v.Trash == nil {
+		v.Trash = []TrashItem{}
+	}
+	return json.Marshal(v)
+}
+
+// trashNoteIDs はゴミ箱にある全ノートのIDを返す
+func trashNoteIDs(items []TrashItem) map[string]bool {
+	ids := make(map[string]bool)
+	for _, item := range items {
+		for _, note := range item.Notes {
+			ids[note.ID] = true
+		}
+	}
+	return ids
+}
+
+// (Archived)TopLevelOrder 内の位置を返す (caller が s.mu を握っている前提)
+func (s *noteService) topLevelPositionLocked(item TopLevelItem, archived bool) int {
+	if archived {
+		s.ensureArchivedTopLevelOrder()
+		return topLevelItemIndex(s.noteList.ArchivedTopLevelOrder, item)
+	}
+	s.ensureTopLevelOrder()
+	return topLevelItemIndex(s.noteList.TopLevelOrder, item)
+}
+
+// ノートをゴミ箱に移す ------------------------------------------------------------
+func (s *noteService) TrashNote(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := slices.IndexFunc(s.noteList.Notes, func(metadata NoteMetadata) bool { return metadata.ID == id })
+	if index == -1 {
+		return fmt.Errorf("%w: %s", errNoteNotFound, id)
+	}
+	metadata := s.noteList.Notes[index]
+	order := -1
+	if metadata.FolderID == "" {
+		order = s.topLevelPositionLocked(TopLevelItem{Type: "note", ID: id}, metadata.Archived)
+	}
+
+	remaining := make([]NoteMetadata, 0, len(s.noteList.Notes)-1)
+	remaining = append(remaining, s.noteList.Notes[:index]...)
+	s.noteList.Notes = append(remaining, s.noteList.Notes[index+1:]...)
+	s.ensureTopLevelOrder()
+	s.removeFromTopLevelOrder(id)
+	s.ensureArchivedTopLevelOrder()
+	s.removeFromArchivedTopLevelOrder(id)
+	s.unindexNoteLocked(id)
+
+	s.noteList.Trash = append(s.noteList.Trash, TrashItem{
+		ID:        id,
+		Type:      trashItemNote,
+		DeletedAt: time.Now().Format(time.RFC3339),
+		Order:     order,
+		Notes:     []NoteMetadata{metadata},
+	})
+	return s.saveNoteList()
+}
+
+// フォルダを配下のフォルダ・ノートごとゴミ箱に移す ------------------------------------------------------------
+func (s *noteService) TrashFolder(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subtree := folderSubtreeIDs(s.noteList.Folders, id)
+	if len(subtree) == 0 {
+		return fmt.Errorf("%w: %s", errFolderNotFound, id)
+	}
+	folderMap := s.folderMapLocked()
+	root := folderMap[id]
+	order := -1
+	if isFolderOrderRoot(root, folderMap, root.Archived) {
+		order = s.topLevelPositionLocked(TopLevelItem{Type: "folder", ID: id}, root.Archived)
+	}
+
+	item := TrashItem{
+		ID:        id,
+		Type:      trashItemFolder,
+		DeletedAt: time.Now().Format(time.RFC3339),
+		Order:     order,
+		Folders:   []Folder{root},
+	}
+	var remainingFolders []Folder
+	for _, folder := range s.noteList.Folders {
+		switch {
+		case folder.ID == id:
+		case subtree[folder.ID]:
+			item.Folders = append(item.Folders, folder)
+		default:
+			remainingFolders = append(remainingFolders, folder)
+		}
+	}
+	var remainingNotes []NoteMetadata
+	for _, metadata := range s.noteList.Notes {
+		if metadata.FolderID != "" && subtree[metadata.FolderID] {
+			item.Notes = append(item.Notes, metadata)
+			s.unindexNoteLocked(metadata.ID)
+		} else {
+			remainingNotes = append(remainingNotes, metadata)
+		}
+	}
+	s.noteList.Folders = remainingFolders
+	s.noteList.Notes = remainingNotes
+
+	s.ensureTopLevelOrder()
+	s.ensureArchivedTopLevelOrder()
+	for folderID := range subtree {
+		s.removeFromTopLevelOrder(folderID)
+		s.removeFromArchivedTopLevelOrder(folderID)
+	}
+
+	s.noteList.Trash = append(s.noteList.Trash, item)
+	return s.saveNoteList()
+}
+
+// ゴミ箱の中身を新しく削除した順に返す ------------------------------------------------------------
+func (s *noteService) ListTrash() []TrashItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := cloneTrashItems(s.noteList.Trash)
+	if items == nil {
+		items = []TrashItem{}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return isModifiedTimeAfter(items[i].DeletedAt, items[j].DeletedAt)
+	})
+	return items
+}
+
+// ゴミ箱から元の場所に戻す ------------------------------------------------------------
+// 戻したアイテムを返す (呼び出し側が配下のノートを同期対象にする)。
+func (s *noteService) RestoreFromTrash(id string) (*TrashItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := slices.IndexFunc(s.noteList.Trash, func(item TrashItem) bool { return item.ID == id })
+	if index == -1 {
+		return nil, fmt.Errorf("trash item not found: %s", id)
+	}
+	item := cloneTrashItems(s.noteList.Trash[index : index+1])[0]
+	now := time.Now().Format(time.RFC3339)
+
+	if item.Type == trashItemNote {
+		if _, err := s.loadNoteLocked(item.ID); err != nil {
+			return nil, fmt.Errorf("failed to load note %s: %w", item.ID, err)
+		}
+	}
+
+	folderMap := s.folderMapLocked()
+	for i, folder := range item.Folders {
+		if _, exists := folderMap[folder.ID]; exists {
+			continue
+		}
+		// 親フォルダが無くなっていればトップレベルに戻す
+		if i == 0 && folder.ParentID != "" {
+			if _, ok := folderMap[folder.ParentID]; !ok {
+				folder.ParentID = ""
+				item.Folders[0] = folder
+			}
+		}
+		s.noteList.Folders = append(s.noteList.Folders, folder)
+		folderMap[folder.ID] = folder
+	}
+	if item.Type == trashItemFolder && len(item.Folders) > 0 {
+		root := item.Folders[0]
+		if isFolderOrderRoot(root, folderMap, root.Archived) {
+			s.insertTopLevelItemLocked(TopLevelItem{Type: "folder", ID: root.ID}, root.Archived, item.Order)
+		}
+	}
+
+	var restoredNotes []NoteMetadata
+	for _, metadata := range item.Notes {
+		if slices.ContainsFunc(s.noteList.Notes, func(m NoteMetadata) bool { return m.ID == metadata.ID }) {
+			continue
+		}
+		note, err := s.loadNoteLocked(metadata.ID)
+		if err != nil {
+			s.logConsole("Skipped restoring note %s from trash: %v", metadata.ID, err)
+			continue
+		}
+		if _, ok := folderMap[metadata.FolderID]; metadata.FolderID != "" && !ok {
+			metadata.FolderID = ""
+		}
+		// 復元を最新の変更として扱う (他端末のゴミ箱とのマージで復元が勝つように)
+		restored := *note
+		restored.ModifiedTime = now
+		if err := s.saveNoteFromSyncLocked(&restored); err != nil {
+			s.logConsole("Skipped restoring note %s from trash: %v", metadata.ID, err)
+			continue
+		}
+		metadata.ModifiedTime = now
+		s.insertRestoredNoteLocked(metadata)
+		if metadata.FolderID == "" {
+			position := -1
+			if item.Type == trashItemNote {
+				position = item.Order
+			}
+			s.insertTopLevelItemLocked(TopLevelItem{Type: "note", ID: metadata.ID}, metadata.Archived, position)
+		}
+		restoredNotes = append(restoredNotes, metadata)
+	}
+	item.Notes = restoredNotes
+
+	s.noteList.Trash = slices.Delete(slices.Clone(s.noteList.Trash), index, index+1)
+	if err := s.saveNoteList(); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// 戻したノートのメタデータを入れる (caller が s.mu を握っている前提)。
+// 新規ノートと同様に、アクティブなノートはアーカイブ済みのノートより前に置く。
+func (s *noteService) insertRestoredNoteLocked(metadata NoteMetadata) {
+	if metadata.Archived {
+		s.noteList.Notes = append(s.noteList.Notes, metadata)
+		return
+	}
+	index := slices.IndexFunc(s.noteList.Notes, func(m NoteMetadata) bool { return m.Archived })
+	if index == -1 {
+		index = len(s.noteList.Notes)
+	}
+	s.noteList.Notes = slices.Insert(slices.Clone(s.noteList.Notes), index, metadata)
+}
+
+// (Archived)TopLevelOrder の position の位置に入れる (caller が s.mu を握っている前提)。
+// position が -1 (元の位置が無い) なら先頭に入れる。
+func (s *noteService) insertTopLevelItemLocked(item TopLevelItem, archived bool, position int) {
+	order := &s.noteList.TopLevelOrder
+	if archived {
+		s.ensureArchivedTopLevelOrder()
+		order = &s.noteList.ArchivedTopLevelOrder
+	} else {
+		s.ensureTopLevelOrder()
+	}
+	if topLevelItemIndex(*order, item) != -1 {
+		return
+	}
+	*order = insertTopLevelItemAt(slices.Clone(*order), position, item)
+}
+
+// ゴミ箱を空にする ------------------------------------------------------------
+// 削除したノートとフォルダのIDを返す (呼び出し側がクラウドからの削除を登録する)。
+func (s *noteService) EmptyTrash() (noteIDs []string, folderIDs []string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.purgeTrashLocked(func(TrashItem) bool { return true })
+}
+
+// 保持期間を過ぎたアイテムを完全に削除する ------------------------------------------------------------
+func (s *noteService) PurgeExpiredTrash(retentionDays int) (noteIDs []string, folderIDs []string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := time.Now().Add(-trashRetention(retentionDays))
+	return s.purgeTrashLocked(func(item TrashItem) bool {
+		deletedAt, err := time.Parse(time.RFC3339, item.DeletedAt)
+		return err == nil && deletedAt.Before(cutoff)
+	})
+}
+
+// match に一致するアイテムのノートファイルを削除してゴミ箱から外す (caller が s.mu を握っている前提)
+func (s *noteService) purgeTrashLocked(match func(TrashItem) bool) (noteIDs []string, folderIDs []string, err error) {
+	var remaining []TrashItem
+	for _, item := range s.noteList.Trash {
+		if !match(item) {
+			remaining = append(remaining, item)
+			continue
+		}
+		for _, metadata := range item.Notes {
+			if err := s.deleteNoteFromSyncLocked(metadata.ID); err != nil {
+				s.logConsole("Failed to delete note %s from trash: %v", metadata.ID, err)
+			}
+			noteIDs = append(noteIDs, metadata.ID)
+		}
+		for _, folder := range item.Folders {
+			folderIDs = append(folderIDs, folder.ID)
+		}
+	}
+	if len(remaining) == len(s.noteList.Trash) {
+		return nil, nil, nil
+	}
+	s.noteList.Trash = remaining
+	return noteIDs, folderIDs, s.saveNoteList()
+}
+
+// ゴミ箱にあるのにノートファイルが無いノートのIDを返す ------------------------------------------------------------
+// 他端末でゴミ箱に移したノートを、この端末がまだダウンロードしていない場合に使う。
+func (s *noteService) MissingTrashNoteIDs(items []TrashItem) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var missing []string
+	for id := range trashNoteIDs(items) {
+		if _, cached := s.noteCache[id]; cached {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(s.notesDir, id+".json")); os.IsNotExist(err) {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// ------------------------------------------------------------
+// 同期時のマージ
+// ------------------------------------------------------------
+
+// mergeTrash はローカルとクラウドのゴミ箱をマージし、マージ済みのノート・フォルダから
+// ゴミ箱にあるものを外す。
+//   - 両方にあるアイテムは DeletedAt が新しい方を採用する。
+//   - ローカルにしか無いアイテムは、この端末で新しく削除したもの (クラウドにまだ本体があるか
+//     未アップロード) だけ残す。それ以外は他端末で完全に削除済み。
+//   - deletedIDs / deletedFolderIDs (この端末で完全に削除したもの) はクラウドのゴミ箱からも外す。
+//   - ゴミ箱に移した後に編集・復元されたノート (ModifiedTime が DeletedAt より新しい) を
+//     含むアイテムは復元されたものとしてゴミ箱から外す。
+//   - 他端末が削除済みのフォルダに追加したノートやフォルダは、そのフォルダと一緒にゴミ箱に入れる。
+func mergeTrash(
+	localTrash []TrashItem,
+	cloudTrash []TrashItem,
+	notes []NoteMetadata,
+	folders []Folder,
+	knownInCloud map[string]bool,
+	deletedIDs map[string]bool,
+	deletedFolderIDs map[string]bool,
+) ([]TrashItem, []NoteMetadata, []Folder) {
+	mergedByID := make(map[string]TrashItem, len(localTrash)+len(cloudTrash))
+	var order []string
+	add := func(item TrashItem) {
+		if existing, ok := mergedByID[item.ID]; ok {
+			if isModifiedTimeAfter(item.DeletedAt, existing.DeletedAt) {
+				mergedByID[item.ID] = item
+			}
+			return
+		}
+		mergedByID[item.ID] = item
+		order = append(order, item.ID)
+	}
+	for _, item := range cloudTrash {
+		if (item.Type == trashItemNote && deletedIDs[item.ID]) || (item.Type == trashItemFolder && deletedFolderIDs[item.ID]) {
+			continue
+		}
+		add(item)
+	}
+	cloudItemIDs := make(map[string]bool, len(cloudTrash))
+	for _, item := range cloudTrash {
+		cloudItemIDs[item.ID] = true
+	}
+	for _, item := range localTrash {
+		if !cloudItemIDs[item.ID] && !knownInCloud[item.ID] {
+			continue
+		}
+		add(item)
+	}
+
+	noteMap := make(map[string]NoteMetadata, len(notes))
+	for _, note := range notes {
+		noteMap[note.ID] = note
+	}
+	trashedNotes := make(map[string]bool)
+	trashedFolders := make(map[string]bool)
+	var result []TrashItem
+	for _, id := range order {
+		item := cloneTrashItems([]TrashItem{mergedByID[id]})[0]
+		restored := slices.ContainsFunc(item.Notes, func(metadata NoteMetadata) bool {
+			current, ok := noteMap[metadata.ID]
+			return ok && isModifiedTimeAfter(current.ModifiedTime, item.DeletedAt)
+		})
+		if restored {
+			continue
+		}
+
+		if item.Type == trashItemFolder {
+			subtree := folderSubtreeIDs(folders, item.ID)
+			for _, folder := range item.Folders {
+				subtree[folder.ID] = true
+			}
+			for _, folder := range folders {
+				if subtree[folder.ID] && !slices.ContainsFunc(item.Folders, func(f Folder) bool { return f.ID == folder.ID }) {
+					item.Folders = append(item.Folders, folder)
+				}
+			}
+			for _, note := range notes {
+				if note.FolderID != "" && subtree[note.FolderID] && !slices.ContainsFunc(item.Notes, func(m NoteMetadata) bool { return m.ID == note.ID }) {
+					item.Notes = append(item.Notes, note)
+				}
+			}
+		}
+		for i, metadata := range item.Notes {
+			if current, ok := noteMap[metadata.ID]; ok {
+				item.Notes[i] = current
+			}
+			trashedNotes[metadata.ID] = true
+		}
+		for _, folder := range item.Folders {
+			trashedFolders[folder.ID] = true
+		}
+		result = append(result, item)
+	}
+
+	remainingNotes := make([]NoteMetadata, 0, len(notes))
+	for _, note := range notes {
+		if !trashedNotes[note.ID] {
+			remainingNotes = append(remainingNotes, note)
+		}
+	}
+	remainingFolders := make([]Folder, 0, len(folders))
+	for _, folder := range folders {
+		if !trashedFolders[folder.ID] {
+			remainingFolders = append(remainingFolders, folder)
+		}
+	}
+	return result, remainingNotes, remainingFolders
+}
+
+// downloadMissingTrashNotes は他端末でゴミ箱に移されたノートのうち、
+// この端末にファイルが無いものをダウンロードする (元に戻せるようにするため)
+func (s *driveService) downloadMissingTrashNotes(trash []TrashItem) {
+	for _, id := range s.noteService.MissingTrashNoteIDs(trash) {
+		s.logger.InfoCode(MsgDriveSyncDownloadNote, map[string]interface{}{"noteId": id})
+		note, err := s.driveSync.DownloadNote(s.ctx, id)
+		if err != nil {
+			s.logger.ErrorCode(err, MsgDriveErrorDownloadNote, map[string]interface{}{"noteId": id})
+			continue
+		}
+		if err := s.noteService.SaveNoteFromSync(note); err != nil {
+			s.logger.ErrorCode(err, MsgDriveErrorSaveDownloadedNote, map[string]interface{}{"noteId": id})
+		}
+	}
+}
+
+// ゴミ箱から外れたのにノートにも戻っていないノートのファイルを削除する (caller が s.mu を握っている前提)。
+// 他端末でゴミ箱を空にした・保持期間が過ぎたノートが該当する。
+func (s *noteService) deleteUnlistedTrashNotesLocked(previousTrash []TrashItem) {
+	listed := trashNoteIDs(s.noteList.Trash)
+	for _, metadata := range s.noteList.Notes {
+		listed[metadata.ID] = true
+	}
+	for id := range trashNoteIDs(previousTrash) {
+		if listed[id] {
+			continue
+		}
+		if err := s.deleteNoteFromSyncLocked(id); err != nil {
+			s.logConsole("Failed to delete purged trash note %s: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package backend
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashNote_RestoreToOriginalPosition(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	for _, id := range []string{"c", "b", "a"} {
+		require.NoError(t, ns.SaveNote(&Note{ID: id, Title: id, Content: "content " + id, Language: "plaintext"}))
+	}
+	folder, err := ns.CreateFolder("work")
+	require.NoError(t, err)
+	require.NoError(t, ns.SaveNote(&Note{ID: "in-folder", Title: "in folder", Content: "x", Language: "plaintext"}))
+	require.NoError(t, ns.MoveNoteToFolder("in-folder", folder.ID))
+	orderBefore := ns.GetTopLevelOrder()
+
+	require.NoError(t, ns.TrashNote("b"))
+	require.NoError(t, ns.TrashNote("in-folder"))
+	assert.Error(t, ns.TrashNote("missing"))
+	assert.NotContains(t, ns.GetTopLevelOrder(), TopLevelItem{Type: "note", ID: "b"})
+	notes, err := ns.ListNotes()
+	require.NoError(t, err)
+	assert.Len(t, notes, 2)
+	// ファイルは完全に削除するまで残り、整合性チェックで孤立ファイル扱いされない
+	assert.FileExists(t, filepath.Join(helper.notesDir, "b.json"))
+	_, err = ns.ValidateIntegrity()
+	require.NoError(t, err)
+	assert.Len(t, ns.noteList.Notes, 2)
+
+	hits, err := ns.SearchNotes("content b", SearchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+
+	trash := ns.ListTrash()
+	require.Len(t, trash, 2)
+	assert.ElementsMatch(t, []string{"b", "in-folder"}, []string{trash[0].ID, trash[1].ID})
+
+	_, err = ns.RestoreFromTrash("b")
+	require.NoError(t, err)
+	_, err = ns.RestoreFromTrash("in-folder")
+	require.NoError(t, err)
+	_, err = ns.RestoreFromTrash("b")
+	assert.Error(t, err)
+
+	assert.Equal(t, orderBefore, ns.GetTopLevelOrder())
+	restored, err := ns.LoadNote("b")
+	require.NoError(t, err)
+	assert.Equal(t, "content b", restored.Content)
+	for _, metadata := range ns.noteList.Notes {
+		if metadata.ID == "in-folder" {
+			assert.Equal(t, folder.ID, metadata.FolderID)
+		}
+	}
+	assert.Empty(t, ns.ListTrash())
+}
+
+func TestTrashFolder_SubtreeRestoreAndPurge(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	parent, err := ns.CreateFolder("parent")
+	require.NoError(t, err)
+	child, err := ns.CreateSubfolder(parent.ID, "child")
+	require.NoError(t, err)
+	require.NoError(t, ns.SaveNote(&Note{ID: "deep", Title: "deep", Content: "x", Language: "plaintext"}))
+	require.NoError(t, ns.MoveNoteToFolder("deep", child.ID))
+
+	require.NoError(t, ns.TrashFolder(parent.ID))
+	assert.Empty(t, ns.ListFolders())
+	assert.Empty(t, ns.noteList.Notes)
+	trash := ns.ListTrash()
+	require.Len(t, trash, 1)
+	assert.Equal(t, trashItemFolder, trash[0].Type)
+	assert.Len(t, trash[0].Folders, 2)
+	assert.Equal(t, parent.ID, trash[0].Folders[0].ID)
+
+	item, err := ns.RestoreFromTrash(parent.ID)
+	require.NoError(t, err)
+	assert.Len(t, item.Notes, 1)
+	assert.Len(t, ns.ListFolders(), 2)
+	require.Len(t, ns.noteList.Notes, 1)
+	assert.Equal(t, child.ID, ns.noteList.Notes[0].FolderID)
+	assert.Contains(t, ns.GetTopLevelOrder(), TopLevelItem{Type: "folder", ID: parent.ID})
+
+	// 保持期間内のものは残し、過ぎたものだけファイルごと削除する
+	require.NoError(t, ns.TrashFolder(parent.ID))
+	noteIDs, folderIDs, err := ns.PurgeExpiredTrash(30)
+	require.NoError(t, err)
+	assert.Empty(t, noteIDs)
+	assert.Empty(t, folderIDs)
+
+	ns.noteList.Trash[0].DeletedAt = time.Now().Add(-31 * 24 * time.Hour).Format(time.RFC3339)
+	noteIDs, folderIDs, err = ns.PurgeExpiredTrash(0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deep"}, noteIDs)
+	assert.ElementsMatch(t, []string{parent.ID, child.ID}, folderIDs)
+	assert.Empty(t, ns.ListTrash())
+	assert.NoFileExists(t, filepath.Join(helper.notesDir, "deep.json"))
+}
+
+func TestMergeTrash(t *testing.T) {
+	deletedAt := "2025-01-02T00:00:00Z"
+	notes := []NoteMetadata{
+		{ID: "trashed-here", ModifiedTime: "2025-01-01T00:00:00Z"},
+		{ID: "edited-later", ModifiedTime: "2025-01-03T00:00:00Z"},
+		{ID: "added-to-folder", FolderID: "f1", ModifiedTime: "2025-01-01T00:00:00Z"},
+		{ID: "kept", ModifiedTime: "2025-01-01T00:00:00Z"},
+	}
+	folders := []Folder{{ID: "f1", Name: "old"}, {ID: "keep-folder", Name: "keep"}}
+	localTrash := []TrashItem{
+		{ID: "trashed-here", Type: trashItemNote, DeletedAt: deletedAt, Notes: []NoteMetadata{notes[0]}},
+		// 他端末で完全に削除済み (クラウドに本体もゴミ箱のアイテムも無い)
+		{ID: "purged-elsewhere", Type: trashItemNote, DeletedAt: deletedAt, Notes: []NoteMetadata{{ID: "purged-elsewhere"}}},
+	}
+	cloudTrash := []TrashItem{
+		{ID: "edited-later", Type: trashItemNote, DeletedAt: deletedAt, Notes: []NoteMetadata{{ID: "edited-later"}}},
+		{ID: "f1", Type: trashItemFolder, DeletedAt: deletedAt, Folders: []Folder{{ID: "f1", Name: "old"}}},
+		// この端末でゴミ箱を空にした
+		{ID: "emptied-here", Type: trashItemNote, DeletedAt: deletedAt, Notes: []NoteMetadata{{ID: "emptied-here"}}},
+	}
+	knownInCloud := map[string]bool{"trashed-here": true, "edited-later": true, "added-to-folder": true, "kept": true, "f1": true}
+
+	trash, mergedNotes, mergedFolders := mergeTrash(
+		localTrash, cloudTrash, notes, folders, knownInCloud,
+		map[string]bool{"emptied-here": true}, map[string]bool{},
+	)
+
+	ids := make([]string, 0, len(trash))
+	for _, item := range trash {
+		ids = append(ids, item.ID)
+	}
+	assert.ElementsMatch(t, []string{"f1", "trashed-here"}, ids)
+	for _, item := range trash {
+		if item.ID == "f1" {
+			require.Len(t, item.Notes, 1)
+			assert.Equal(t, "added-to-folder", item.Notes[0].ID)
+		}
+	}
+	noteIDs := make([]string, 0, len(mergedNotes))
+	for _, note := range mergedNotes {
+		noteIDs = append(noteIDs, note.ID)
+	}
+	assert.ElementsMatch(t, []string{"edited-later", "kept"}, noteIDs)
+	assert.Equal(t, []Folder{{ID: "keep-folder", Name: "keep"}}, mergedFolders)
+}
+
+// ゴミ箱は同期され、別の端末で元に戻したり空にしたりできること
+func TestTrash_SyncBetweenDevices(t *testing.T) {
+	shared := t.TempDir()
+	connect := func(ds *driveService) error { return ds.ConnectLocalFolder(LocalFolderConfig{Path: shared}) }
+	deviceA := newProviderTestDriveService(t, connect)
+	deviceB := newProviderTestDriveService(t, connect)
+
+	note := &Note{ID: "trash-note", Title: "draft", Content: "keep me", Language: "plaintext"}
+	require.NoError(t, deviceA.noteService.SaveNote(note))
+	deviceA.syncState.MarkNoteDirty(note.ID)
+	require.NoError(t, deviceA.SyncNotes())
+	require.NoError(t, deviceB.SyncNotes())
+
+	require.NoError(t, deviceA.noteService.TrashNote(note.ID))
+	deviceA.syncState.MarkDirty()
+	require.NoError(t, deviceA.SyncNotes())
+	assert.FileExists(t, filepath.Join(shared, "monaco-notepad", "notes", note.ID+".json"))
+
+	require.NoError(t, deviceB.SyncNotes())
+	assert.Empty(t, deviceB.noteService.noteList.Notes)
+	require.Len(t, deviceB.noteService.ListTrash(), 1)
+	assert.FileExists(t, filepath.Join(deviceB.notesDir, note.ID+".json"))
+
+	// B で元に戻すと A にも戻る
+	item, err := deviceB.noteService.RestoreFromTrash(note.ID)
+	require.NoError(t, err)
+	for _, restored := range item.Notes {
+		deviceB.syncState.MarkNoteDirty(restored.ID)
+	}
+	deviceB.syncState.MarkDirty()
+	require.NoError(t, deviceB.SyncNotes())
+	require.NoError(t, deviceA.SyncNotes())
+	restored, err := deviceA.noteService.LoadNote(note.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "keep me", restored.Content)
+	assert.Empty(t, deviceA.noteService.ListTrash())
+
+	// A で空にすると B のファイルも消える
+	require.NoError(t, deviceA.noteService.TrashNote(note.ID))
+	noteIDs, _, err := deviceA.noteService.EmptyTrash()
+	require.NoError(t, err)
+	for _, id := range noteIDs {
+		deviceA.syncState.MarkNoteDeleted(id)
+	}
+	require.NoError(t, deviceA.SyncNotes())
+	require.NoError(t, deviceB.SyncNotes())
+	assert.Empty(t, deviceB.noteService.noteList.Notes)
+	assert.Empty(t, deviceB.noteService.ListTrash())
+	assert.NoFileExists(t, filepath.Join(deviceB.notesDir, note.ID+".json"))
+}
+
+// ゴミ箱を知らないクライアント（旧モバイル版）が trash の無い noteList を上げても、手元のゴミ箱とファイルが消えないこと
+func TestTrash_KeepsLocalTrashWhenCloudListHasNoTrash(t *testing.T) {
+	shared := t.TempDir()
+	connect := func(ds *driveService) error { return ds.ConnectLocalFolder(LocalFolderConfig{Path: shared}) }
+	deviceA := newProviderTestDriveService(t, connect)
+
+	note := &Note{ID: "trash-note", Title: "draft", Content: "keep me", Language: "plaintext"}
+	require.NoError(t, deviceA.noteService.SaveNote(note))
+	deviceA.syncState.MarkNoteDirty(note.ID)
+	require.NoError(t, deviceA.SyncNotes())
+	require.NoError(t, deviceA.noteService.TrashNote(note.ID))
+	deviceA.syncState.MarkDirty()
+	require.NoError(t, deviceA.SyncNotes())
+
+	// 空のゴミ箱も "trash": [] として書き出す
+	data, err := json.Marshal(NoteList{Version: CurrentVersion, Notes: []NoteMetadata{}})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"trash":[]`)
+
+	// 旧クライアントが noteList を読み直して trash キーを落として書き戻す
+	matches, err := filepath.Glob(filepath.Join(shared, "*", "noteList_v2.json"))
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	raw, err := os.ReadFile(matches[0])
+	require.NoError(t, err)
+	var cloud map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &cloud))
+	require.Contains(t, cloud, "trash")
+	delete(cloud, "trash")
+	cloud["notes"] = []interface{}{}
+	raw, err = json.Marshal(cloud)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(matches[0], raw, 0644))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(matches[0], later, later))
+
+	require.NoError(t, deviceA.SyncNotes())
+	require.Len(t, deviceA.noteService.ListTrash(), 1)
+	assert.FileExists(t, filepath.Join(deviceA.notesDir, note.ID+".json"))
+
+	// 競合（両方が変更）のマージでも手元のゴミ箱を残す
+	other := &Note{ID: "other-note", Title: "other", Content: "local edit", Language: "plaintext"}
+	require.NoError(t, deviceA.noteService.SaveNote(other))
+	deviceA.syncState.MarkNoteDirty(other.ID)
+	raw, err = json.Marshal(cloud)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(matches[0], raw, 0644))
+	later = later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(matches[0], later, later))
+
+	require.NoError(t, deviceA.SyncNotes())
+	require.Len(t, deviceA.noteService.ListTrash(), 1)
+	assert.FileExists(t, filepath.Join(deviceA.notesDir, note.ID+".json"))
+}
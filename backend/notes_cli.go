@@ -0,0 +1,682 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"monaco-notepad/backend/migration"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+// notes サブコマンド（ウィンドウを開かずにノートを操作する CLI）
+// ------------------------------------------------------------
+//
+// GUI と同じ appDataDir を noteService / SyncState 経由で操作し、変更は dirty として記録する。
+// 次に GUI を起動したときの同期で Google Drive に反映される。
+// 読み取りだけのコマンドは共有ロックを取り、ノートリストを書き換えずに読む（スナップショット）。
+// GUI の起動中はロックが取れないため、コマンドを GUI に渡して実行してもらう (notes_cli_handoff.go)。
+
+const notesCLIUsage = `usage: monaco-notepad notes <command> [options]
+
+commands:
+  list [--archived] [--folder <id|name>] [--json]
+      list notes (id, modified time, folder, title)
+  show <id> [--json]
+      print the content of a note (alias: cat)
+  new [--title <title>] [--language <lang>] [--folder <id|name>] [--tag <tag>]...
+      create a note from stdin and print its id
+  edit <id> [--title <title>] [--language <lang>] [--tag <tag>]... [--stdin]
+      update a note (--stdin replaces the content with stdin)
+  append <id>
+      append stdin to the end of a note
+  mv <id> <folder id|name>
+      move a note to a folder ("/" moves it back to the top level)
+  archive <id> / unarchive <id>
+      archive or restore a note
+  rm <id>
+      move a note to the trash
+  grep <query> [--archived|--all] [--folder <id|name>] [--limit <n>] [--json]
+      full-text search
+
+Changes are synced by the app the next time it connects to Google Drive.
+While the app is running, commands are handed to it and applied there.
+`
+
+// errNotesCLIUsage は引数の誤り（終了コード 2）を表す
+var errNotesCLIUsage = errors.New("invalid arguments")
+
+type notesCLI struct {
+	app         *App // GUI に渡されたコマンドを実行する場合のアプリ（CLI 単体では nil）
+	noteService *noteService
+	syncState   *SyncState // 読み取りだけのコマンドでは nil
+	lock        *dataDirLock
+	stdin       io.Reader
+	stdout      io.Writer
+	stderr      io.Writer
+}
+
+type notesCLICommand func(cli *notesCLI, args []string) error
+
+var notesCLICommands = map[string]notesCLICommand{
+	"list":      (*notesCLI).list,
+	"ls":        (*notesCLI).list,
+	"show":      (*notesCLI).show,
+	"cat":       (*notesCLI).show,
+	"new":       (*notesCLI).create,
+	"edit":      (*notesCLI).edit,
+	"append":    (*notesCLI).appendContent,
+	"mv":        (*notesCLI).move,
+	"archive":   (*notesCLI).archive,
+	"unarchive": (*notesCLI).unarchive,
+	"rm":        (*notesCLI).remove,
+	"grep":      (*notesCLI).grep,
+}
+
+// notesCLIReadOnlyCommands はノートを書き換えないコマンド（共有ロックで実行する）
+var notesCLIReadOnlyCommands = map[string]bool{
+	"list": true,
+	"ls":   true,
+	"show": true,
+	"cat":  true,
+	"grep": true,
+}
+
+// RunNotesCLI は `monaco-notepad notes ...` を使用中のプロファイルに対して実行し、プロセスの終了コードを返す ------------------------------------------------------------
+func RunNotesCLI(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	return runNotesCLI(activeProfileDataDir(defaultAppDataDir()), args, stdin, stdout, stderr)
+}
+
+func runNotesCLI(appDataDir string, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, notesCLIUsage)
+		return 2
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(stdout, notesCLIUsage)
+		return 0
+	}
+	if _, ok := notesCLICommands[args[0]]; !ok {
+		fmt.Fprintf(stderr, "unknown command: %s\n\n%s", args[0], notesCLIUsage)
+		return 2
+	}
+
+	// GUI に渡すことがあるため、標準入力はロックを取る前に読み切っておく
+	var input []byte
+	if notesCLIReadsStdin(args) {
+		var err error
+		input, err = io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: failed to read stdin: %v\n", err)
+			return 1
+		}
+	}
+
+	cli, err := openNotesCLI(appDataDir, notesCLIReadOnlyCommands[args[0]], bytes.NewReader(input), stdout, stderr)
+	if errors.Is(err, errDataDirLocked) {
+		// GUI の起動中はコマンドを GUI に渡して実行してもらう
+		code, handOffErr := handOffNotesCLI(appDataDir, notesCLIRequest{Args: args, Stdin: input}, notesCLIHandoffWait, stdout, stderr)
+		if handOffErr == nil {
+			return code
+		}
+		err = handOffErr
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	defer cli.close()
+	return cli.execute(args)
+}
+
+// execute は args[0] のコマンドを実行し、終了コードを返す
+func (c *notesCLI) execute(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(c.stderr, notesCLIUsage)
+		return 2
+	}
+	command, ok := notesCLICommands[args[0]]
+	if !ok {
+		fmt.Fprintf(c.stderr, "unknown command: %s\n\n%s", args[0], notesCLIUsage)
+		return 2
+	}
+	if err := command(c, args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(c.stderr, "error: %v\n", err)
+		if errors.Is(err, errNotesCLIUsage) {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+// notesCLIReadsStdin はコマンドが標準入力を読むか（new / append / edit --stdin）
+func notesCLIReadsStdin(args []string) bool {
+	switch args[0] {
+	case "new", "append":
+		return true
+	case "edit":
+		for _, arg := range args[1:] {
+			if arg == "--" {
+				break
+			}
+			if arg == "--stdin" || arg == "-stdin" || strings.HasPrefix(arg, "--stdin=") || strings.HasPrefix(arg, "-stdin=") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// openNotesCLI は appDataDir をロックし、GUI の起動時と同じ手順でサービスを初期化する。
+// readOnly なら共有ロックを取り、既存のノートリストを書き換えずに読む。
+func openNotesCLI(appDataDir string, readOnly bool, stdin io.Reader, stdout io.Writer, stderr io.Writer) (*notesCLI, error) {
+	notesDir := filepath.Join(appDataDir, "notes")
+	wait := notesCLILockWait(appDataDir)
+	if readOnly {
+		if _, err := os.Stat(filepath.Join(appDataDir, "noteList_v2.json")); err == nil {
+			lock, err := acquireDataDirLockShared(appDataDir, wait)
+			if err != nil {
+				return nil, err
+			}
+			ns, err := openNoteServiceSnapshot(notesDir)
+			if err != nil {
+				lock.Release()
+				return nil, err
+			}
+			return &notesCLI{
+				noteService: ns,
+				lock:        lock,
+				stdin:       stdin,
+				stdout:      stdout,
+				stderr:      stderr,
+			}, nil
+		}
+		// まだノートリストが無い（マイグレーション前・初回）場合は書き込みと同じ手順で作る
+	}
+
+	lock, err := acquireDataDirLock(appDataDir, wait)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(notesDir, 0755); err != nil {
+		lock.Release()
+		return nil, fmt.Errorf("failed to create notes directory: %w", err)
+	}
+	if _, err := migration.RunIfNeeded(appDataDir, notesDir); err != nil {
+		lock.Release()
+		return nil, fmt.Errorf("migration failed: %w", err)
+	}
+
+	ns, err := NewNoteService(notesDir, nil)
+	if err != nil {
+		lock.Release()
+		return nil, err
+	}
+	syncState := NewSyncState(appDataDir)
+	if err := syncState.Load(); err != nil {
+		lock.Release()
+		return nil, err
+	}
+
+	return &notesCLI{
+		noteService: ns,
+		syncState:   syncState,
+		lock:        lock,
+		stdin:       stdin,
+		stdout:      stdout,
+		stderr:      stderr,
+	}, nil
+}
+
+// notesCLILockWait はロックを待つ時間を返す。GUI の起動中（inbox がある）はロックが空かないので待たない
+func notesCLILockWait(appDataDir string) time.Duration {
+	if info, err := os.Stat(notesCLIInboxDir(appDataDir)); err == nil && info.IsDir() {
+		return 0
+	}
+	return dataDirLockCLIWait
+}
+
+// openNoteServiceSnapshot は noteList_v2.json をそのまま読み込む。
+// NewNoteService と違い、バックアップや不整合の修復で何も書き込まない（共有ロックで並行して読むため）。
+func openNoteServiceSnapshot(notesDir string) (*noteService, error) {
+	service := NewEmptyNoteService(notesDir, nil)
+	service.recoveryApplied = ""
+	data, err := os.ReadFile(service.noteListPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read note list: %w", err)
+	}
+	if err := json.Unmarshal(data, &service.noteList); err != nil {
+		return nil, fmt.Errorf("failed to parse note list: %w", err)
+	}
+	return service, nil
+}
+
+func (c *notesCLI) close() {
+	c.lock.Release()
+}
+
+// ノート一覧 ------------------------------------------------------------
+func (c *notesCLI) list(args []string) error {
+	fs := c.newFlagSet("list")
+	archived := fs.Bool("archived", false, "list archived notes instead of active ones")
+	folder := fs.String("folder", "", "only notes in this folder (id or name)")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	if _, err := parseNotesCLIArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	noteList := c.noteService.SnapshotNoteList()
+	folderID := ""
+	if *folder != "" {
+		id, err := resolveNotesCLIFolder(noteList.Folders, *folder)
+		if err != nil {
+			return err
+		}
+		folderID = id
+	}
+
+	notes := []NoteMetadata{}
+	for _, metadata := range noteList.Notes {
+		if metadata.Archived != *archived || (folderID != "" && metadata.FolderID != folderID) {
+			continue
+		}
+		notes = append(notes, metadata)
+	}
+	if *asJSON {
+		return c.printJSON(notes)
+	}
+
+	folderNames := make(map[string]string, len(noteList.Folders))
+	for _, f := range noteList.Folders {
+		folderNames[f.ID] = f.Name
+	}
+	for _, metadata := range notes {
+		fmt.Fprintf(c.stdout, "%s\t%s\t%s\t%s\n", metadata.ID, metadata.ModifiedTime, folderNames[metadata.FolderID], notesCLITitle(metadata))
+	}
+	return nil
+}
+
+// ノート本文の表示 ------------------------------------------------------------
+func (c *notesCLI) show(args []string) error {
+	fs := c.newFlagSet("show")
+	asJSON := fs.Bool("json", false, "print the whole note as JSON")
+	positional, err := parseNotesCLIArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	note, err := c.loadNote(positional[0])
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return c.printJSON(note)
+	}
+	_, err = io.WriteString(c.stdout, note.Content)
+	return err
+}
+
+// 標準入力からノートを作成 ------------------------------------------------------------
+func (c *notesCLI) create(args []string) error {
+	fs := c.newFlagSet("new")
+	title := fs.String("title", "", "note title")
+	language := fs.String("language", "plaintext", "editor language")
+	folder := fs.String("folder", "", "folder to create the note in (id or name)")
+	var tags notesCLIStringList
+	fs.Var(&tags, "tag", "tag (repeatable)")
+	if _, err := parseNotesCLIArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	folderID := ""
+	if *folder != "" {
+		id, err := c.resolveActiveFolder(*folder)
+		if err != nil {
+			return err
+		}
+		folderID = id
+	}
+	content, err := io.ReadAll(c.stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read stdin: %w", err)
+	}
+
+	note := &Note{
+		ID:       uuid.New().String(),
+		Title:    *title,
+		Content:  string(content),
+		Language: *language,
+		Tags:     tags,
+	}
+	if err := c.saveNote(note); err != nil {
+		return err
+	}
+
+	if folderID != "" {
+		if err := c.noteService.MoveNoteToFolder(note.ID, folderID); err != nil {
+			return err
+		}
+		c.syncState.MarkDirty()
+	}
+
+	fmt.Fprintln(c.stdout, note.ID)
+	return nil
+}
+
+// タイトル・言語・タグ・本文の変更 ------------------------------------------------------------
+func (c *notesCLI) edit(args []string) error {
+	fs := c.newFlagSet("edit")
+	title := fs.String("title", "", "new title")
+	language := fs.String("language", "", "new editor language")
+	replaceContent := fs.Bool("stdin", false, "replace the content with stdin")
+	var tags notesCLIStringList
+	fs.Var(&tags, "tag", "replace tags (repeatable)")
+	positional, err := parseNotesCLIArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	note, err := c.loadNote(positional[0])
+	if err != nil {
+		return err
+	}
+	changed := false
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "title":
+			note.Title = *title
+		case "language":
+			note.Language = *language
+		case "tag":
+			note.Tags = tags
+		}
+		changed = true
+	})
+	if *replaceContent {
+		content, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+		note.Content = string(content)
+		note.ContentHeader = ""
+	}
+	if !changed {
+		return fmt.Errorf("%w: nothing to change", errNotesCLIUsage)
+	}
+	return c.saveNote(note)
+}
+
+// 本文の末尾に標準入力を追記 ------------------------------------------------------------
+func (c *notesCLI) appendContent(args []string) error {
+	positional, err := parseNotesCLIArgs(c.newFlagSet("append"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	note, err := c.loadNote(positional[0])
+	if err != nil {
+		return err
+	}
+	content, err := io.ReadAll(c.stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read stdin: %w", err)
+	}
+	if len(content) == 0 {
+		return nil
+	}
+	if note.Content != "" && !strings.HasSuffix(note.Content, "\n") {
+		note.Content += "\n"
+	}
+	note.Content += string(content)
+	note.ContentHeader = ""
+	return c.saveNote(note)
+}
+
+// フォルダの移動 ------------------------------------------------------------
+func (c *notesCLI) move(args []string) error {
+	positional, err := parseNotesCLIArgs(c.newFlagSet("mv"), args, 2)
+	if err != nil {
+		return err
+	}
+
+	note, err := c.loadNote(positional[0])
+	if err != nil {
+		return err
+	}
+	if note.Archived {
+		return fmt.Errorf("%w: %s", errNoteArchived, note.ID)
+	}
+	folderID := ""
+	if positional[1] != "/" && positional[1] != "" {
+		folderID, err = c.resolveActiveFolder(positional[1])
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := c.noteService.MoveNoteToFolder(note.ID, folderID); err != nil {
+		return err
+	}
+	c.syncState.MarkDirty()
+	c.syncState.MarkNoteDirty(note.ID)
+	return nil
+}
+
+// アーカイブ ------------------------------------------------------------
+func (c *notesCLI) archive(args []string) error {
+	return c.setArchived("archive", args, true)
+}
+
+// アーカイブからの復元 ------------------------------------------------------------
+func (c *notesCLI) unarchive(args []string) error {
+	return c.setArchived("unarchive", args, false)
+}
+
+func (c *notesCLI) setArchived(name string, args []string, archived bool) error {
+	positional, err := parseNotesCLIArgs(c.newFlagSet(name), args, 1)
+	if err != nil {
+		return err
+	}
+
+	note, err := c.loadNote(positional[0])
+	if err != nil {
+		return err
+	}
+	if note.Archived == archived {
+		return nil
+	}
+	note.Archived = archived
+	if archived {
+		// フロントエンドのアーカイブ操作と同じく、一覧用のプレビューを作り直す
+		note.ContentHeader = noteContentHeader(note)
+	}
+	return c.saveNote(note)
+}
+
+// ゴミ箱に移す ------------------------------------------------------------
+func (c *notesCLI) remove(args []string) error {
+	positional, err := parseNotesCLIArgs(c.newFlagSet("rm"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	note, err := c.loadNote(positional[0])
+	if err != nil {
+		return err
+	}
+	if err := c.noteService.TrashNote(note.ID); err != nil {
+		return err
+	}
+	c.syncState.MarkDirty()
+	return nil
+}
+
+// 全文検索 ------------------------------------------------------------
+func (c *notesCLI) grep(args []string) error {
+	fs := c.newFlagSet("grep")
+	archived := fs.Bool("archived", false, "search archived notes only")
+	all := fs.Bool("all", false, "search both active and archived notes")
+	folder := fs.String("folder", "", "only notes in this folder (id or name)")
+	limit := fs.Int("limit", 0, "maximum number of notes")
+	asJSON := fs.Bool("json", false, "print hits as JSON")
+	positional, err := parseNotesCLIArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	options := SearchOptions{Limit: *limit}
+	switch {
+	case *all:
+		options.Archived = SearchArchivedAll
+	case *archived:
+		options.Archived = SearchArchivedOnly
+	}
+	if *folder != "" {
+		options.FolderID, err = resolveNotesCLIFolder(c.noteService.ListFolders(), *folder)
+		if err != nil {
+			return err
+		}
+	}
+
+	hits, err := c.noteService.SearchNotes(positional[0], options)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return c.printJSON(hits)
+	}
+	for _, hit := range hits {
+		for _, match := range hit.Matches {
+			location := "title"
+			if match.Field == SearchFieldContent {
+				location = fmt.Sprint(match.Line)
+			}
+			fmt.Fprintf(c.stdout, "%s:%s:%s\n", hit.NoteID, location, match.Snippet)
+		}
+	}
+	return nil
+}
+
+func (c *notesCLI) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("notes "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+func (c *notesCLI) loadNote(id string) (*Note, error) {
+	for _, metadata := range c.noteService.SnapshotNoteList().Notes {
+		if metadata.ID == id {
+			return c.noteService.LoadNote(id)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errNoteNotFound, id)
+}
+
+// saveNote は GUI の SaveNote と同じくノートを保存して dirty にする
+// GUI に渡されたコマンドでは App.SaveNote を通し、リンクしたファイルにも反映する。
+func (c *notesCLI) saveNote(note *Note) error {
+	if c.app != nil {
+		return c.app.SaveNote(note, "update")
+	}
+	if err := c.noteService.SaveNote(note); err != nil {
+		return err
+	}
+	c.syncState.MarkNoteDirty(note.ID)
+	return nil
+}
+
+func (c *notesCLI) resolveActiveFolder(nameOrID string) (string, error) {
+	folders := c.noteService.ListFolders()
+	id, err := resolveNotesCLIFolder(folders, nameOrID)
+	if err != nil {
+		return "", err
+	}
+	for _, folder := range folders {
+		if folder.ID == id && folder.Archived {
+			return "", fmt.Errorf("%w: %s", errFolderArchived, nameOrID)
+		}
+	}
+	return id, nil
+}
+
+func (c *notesCLI) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// resolveNotesCLIFolder はフォルダIDまたはフォルダ名からフォルダIDを返す（同名が複数あればエラー）
+func resolveNotesCLIFolder(folders []Folder, nameOrID string) (string, error) {
+	var matches []string
+	for _, folder := range folders {
+		if folder.ID == nameOrID {
+			return folder.ID, nil
+		}
+		if folder.Name == nameOrID {
+			matches = append(matches, folder.ID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", errFolderNotFound, nameOrID)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("folder name is ambiguous, use the folder id: %s (%s)", nameOrID, strings.Join(matches, ", "))
+	}
+}
+
+// parseNotesCLIArgs はフラグと位置引数が混在した引数を解析し、ちょうど n 個の位置引数を返す
+func parseNotesCLIArgs(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", errNotesCLIUsage, err)
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(positional) != n {
+		return nil, fmt.Errorf("%w: %s expects %d argument(s), got %d", errNotesCLIUsage, fs.Name(), n, len(positional))
+	}
+	return positional, nil
+}
+
+func notesCLITitle(metadata NoteMetadata) string {
+	if metadata.Title != "" {
+		return metadata.Title
+	}
+	firstLine, _, _ := strings.Cut(metadata.ContentHeader, "\n")
+	return firstLine
+}
+
+// notesCLIStringList は繰り返し指定できる文字列フラグ
+type notesCLIStringList []string
+
+func (l *notesCLIStringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *notesCLIStringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
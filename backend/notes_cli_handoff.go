@@ -0,0 +1,269 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+// notes サブコマンドの GUI への受け渡し
+// ------------------------------------------------------------
+//
+// GUI は起動中ずっと appDataDir の排他ロックを保持するため、CLI は自分では書き込めない。
+// 代わりに GUI は appDataDir/cli_inbox を fsnotify で監視し、CLI は引数と標準入力を
+// <id>.request.json として置いて <id>.response.json を待つ。GUI は自分の noteService /
+// SyncState に対してコマンドを実行するので、メモリ上のノートリストと食い違わない。
+// inbox が無い（GUI ではなく別の CLI がロックを持っている）場合は受け渡さずにエラーで終了する。
+
+const (
+	notesCLIInboxDirName        = "cli_inbox"
+	notesCLIRequestSuffix       = ".request.json"
+	notesCLIResponseSuffix      = ".response.json"
+	notesCLIHandoffWait         = 15 * time.Second // GUI の応答を待つ時間
+	notesCLIHandoffPollInterval = 50 * time.Millisecond
+)
+
+// notesCLIRequest は CLI から GUI に渡すコマンド
+type notesCLIRequest struct {
+	Args  []string `json:"args"`
+	Stdin []byte   `json:"stdin,omitempty"` // 事前に読み込んだ標準入力
+}
+
+// notesCLIResponse は GUI が実行したコマンドの結果
+type notesCLIResponse struct {
+	ExitCode int    `json:"exitCode"`
+	Stdout   []byte `json:"stdout,omitempty"`
+	Stderr   []byte `json:"stderr,omitempty"`
+}
+
+func notesCLIInboxDir(appDataDir string) string {
+	return filepath.Join(appDataDir, notesCLIInboxDirName)
+}
+
+// handOffNotesCLI は起動中の GUI にコマンドを渡し、結果を stdout / stderr に書いて終了コードを返す
+func handOffNotesCLI(appDataDir string, request notesCLIRequest, wait time.Duration, stdout io.Writer, stderr io.Writer) (int, error) {
+	inboxDir := notesCLIInboxDir(appDataDir)
+	if info, err := os.Stat(inboxDir); err != nil || !info.IsDir() {
+		return 0, fmt.Errorf("%w; quit the app and try again", errDataDirLocked)
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode request: %w", err)
+	}
+	id := uuid.New().String()
+	requestPath := filepath.Join(inboxDir, id+notesCLIRequestSuffix)
+	responsePath := filepath.Join(inboxDir, id+notesCLIResponseSuffix)
+	if err := writeFileAtomic(requestPath, data); err != nil {
+		return 0, fmt.Errorf("failed to hand the command to the app: %w", err)
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		data, err := os.ReadFile(responsePath)
+		if err == nil {
+			os.Remove(responsePath)
+			var response notesCLIResponse
+			if err := json.Unmarshal(data, &response); err != nil {
+				return 0, fmt.Errorf("failed to decode response: %w", err)
+			}
+			stdout.Write(response.Stdout)
+			stderr.Write(response.Stderr)
+			return response.ExitCode, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return 0, fmt.Errorf("failed to read response: %w", err)
+		}
+		if time.Now().After(deadline) {
+			// 取り出される前なら取り下げる（取り出し済みなら実行された可能性がある）
+			if os.Remove(requestPath) == nil {
+				return 0, fmt.Errorf("%w; the running app did not respond", errDataDirLocked)
+			}
+			return 0, fmt.Errorf("%w; the running app did not respond in time, the command may have been applied", errDataDirLocked)
+		}
+		time.Sleep(notesCLIHandoffPollInterval)
+	}
+}
+
+// notesCLIInbox は GUI 側で CLI から渡されたコマンドを受け取って実行する
+type notesCLIInbox struct {
+	mu      sync.Mutex
+	dir     string
+	watcher *fsnotify.Watcher
+	handle  func(notesCLIRequest) notesCLIResponse
+	logger  AppLogger
+	closed  bool
+	done    chan struct{}
+}
+
+// newNotesCLIInbox は appDataDir/cli_inbox を作って監視を始める
+func newNotesCLIInbox(appDataDir string, logger AppLogger, handle func(notesCLIRequest) notesCLIResponse) (*notesCLIInbox, error) {
+	dir := notesCLIInboxDir(appDataDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create CLI inbox: %w", err)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CLI inbox watcher: %w", err)
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch CLI inbox: %w", err)
+	}
+	inbox := &notesCLIInbox{
+		dir:     dir,
+		watcher: watcher,
+		handle:  handle,
+		logger:  logger,
+		done:    make(chan struct{}),
+	}
+	go inbox.run()
+	return inbox, nil
+}
+
+// Close は監視を止めて inbox を削除する（以後の CLI は GUI が起動していないものとして扱う）
+func (i *notesCLIInbox) Close() error {
+	if i == nil {
+		return nil
+	}
+	i.mu.Lock()
+	if i.closed {
+		i.mu.Unlock()
+		return nil
+	}
+	i.closed = true
+	i.mu.Unlock()
+
+	err := i.watcher.Close()
+	<-i.done
+	os.RemoveAll(i.dir)
+	return err
+}
+
+func (i *notesCLIInbox) run() {
+	defer close(i.done)
+	// 監視を始める前に置かれていた依頼を先に処理する
+	i.processPending()
+	for {
+		select {
+		case event, ok := <-i.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Write) != 0 && isNotesCLIRequestFile(event.Name) {
+				i.process(event.Name)
+			}
+		case err, ok := <-i.watcher.Errors:
+			if !ok {
+				return
+			}
+			i.logf("CLI inbox watcher error: %v", err)
+		}
+	}
+}
+
+func (i *notesCLIInbox) processPending() {
+	entries, err := os.ReadDir(i.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if isNotesCLIRequestFile(entry.Name()) {
+			i.process(filepath.Join(i.dir, entry.Name()))
+		}
+	}
+}
+
+// process は依頼を取り出して実行し、結果を書く。取り出せなかった（処理済み・取り下げ済み）依頼は無視する
+func (i *notesCLIInbox) process(requestPath string) {
+	data, err := os.ReadFile(requestPath)
+	if err != nil {
+		return
+	}
+	if err := os.Remove(requestPath); err != nil {
+		return
+	}
+
+	var response notesCLIResponse
+	var request notesCLIRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		response = notesCLIResponse{ExitCode: 1, Stderr: []byte(fmt.Sprintf("error: invalid request: %v\n", err))}
+	} else {
+		response = i.handle(request)
+	}
+
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		i.logf("Failed to encode CLI response: %v", err)
+		return
+	}
+	responsePath := strings.TrimSuffix(requestPath, notesCLIRequestSuffix) + notesCLIResponseSuffix
+	if err := writeFileAtomic(responsePath, encoded); err != nil {
+		i.logf("Failed to write CLI response: %v", err)
+	}
+}
+
+func (i *notesCLIInbox) logf(format string, args ...interface{}) {
+	if i.logger != nil {
+		i.logger.Console(format, args...)
+	}
+}
+
+// isNotesCLIRequestFile は書き込み途中の一時ファイル（.xxx.tmp-*）を除いた依頼ファイルか
+func isNotesCLIRequestFile(path string) bool {
+	name := filepath.Base(path)
+	return !strings.HasPrefix(name, ".") && strings.HasSuffix(name, notesCLIRequestSuffix)
+}
+
+// startNotesCLIInbox は appDataDir の CLI inbox の監視を（作り直して）始める
+func (a *App) startNotesCLIInbox() {
+	a.stopNotesCLIInbox()
+	inbox, err := newNotesCLIInbox(a.currentAppDataDir(), a.logger, a.runHandedOffNotesCLI)
+	if err != nil {
+		a.logger.Console("Warning: %v", err)
+		return
+	}
+	a.notesCLIInbox = inbox
+}
+
+// stopNotesCLIInbox は CLI inbox の監視を止めて削除する
+func (a *App) stopNotesCLIInbox() {
+	if a.notesCLIInbox == nil {
+		return
+	}
+	if err := a.notesCLIInbox.Close(); err != nil {
+		a.logger.Console("Failed to stop CLI inbox: %v", err)
+	}
+	a.notesCLIInbox = nil
+}
+
+// runHandedOffNotesCLI は CLI から渡されたコマンドを起動中のサービスに対して実行する
+func (a *App) runHandedOffNotesCLI(request notesCLIRequest) notesCLIResponse {
+	var stdout, stderr bytes.Buffer
+	cli := &notesCLI{
+		app:         a,
+		noteService: a.currentNoteService(),
+		syncState:   a.currentSyncState(),
+		stdin:       bytes.NewReader(request.Stdin),
+		stdout:      &stdout,
+		stderr:      &stderr,
+	}
+	code := cli.execute(request.Args)
+	if code == 0 && len(request.Args) > 0 && !notesCLIReadOnlyCommands[request.Args[0]] {
+		a.logger.Console("Applied notes command from CLI: %s", request.Args[0])
+		a.triggerSyncIfConnected()
+		a.logger.NotifyFrontendSyncedAndReload(a.ctx.ctx)
+	}
+	return notesCLIResponse{ExitCode: code, Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}
+}
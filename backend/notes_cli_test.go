@@ -0,0 +1,189 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runNotesCLIForTest は CLI を実行し、終了コードと標準出力・標準エラーを返す
+func runNotesCLIForTest(t *testing.T, appDataDir string, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := runNotesCLI(appDataDir, args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestNotesCLI_CreateEditMoveArchiveDelete(t *testing.T) {
+	appDataDir := t.TempDir()
+
+	code, out, errOut := runNotesCLIForTest(t, appDataDir, "first line\nsecond", "new", "--title", "memo", "--tag", "cli")
+	require.Equal(t, 0, code, errOut)
+	noteID := strings.TrimSpace(out)
+	require.NotEmpty(t, noteID)
+
+	code, out, _ = runNotesCLIForTest(t, appDataDir, "", "show", noteID)
+	require.Equal(t, 0, code)
+	assert.Equal(t, "first line\nsecond", out)
+
+	code, _, errOut = runNotesCLIForTest(t, appDataDir, "third", "append", noteID)
+	require.Equal(t, 0, code, errOut)
+	code, _, errOut = runNotesCLIForTest(t, appDataDir, "", "edit", noteID, "--title", "renamed")
+	require.Equal(t, 0, code, errOut)
+
+	code, out, _ = runNotesCLIForTest(t, appDataDir, "", "show", noteID, "--json")
+	require.Equal(t, 0, code)
+	var note Note
+	require.NoError(t, json.Unmarshal([]byte(out), &note))
+	assert.Equal(t, "renamed", note.Title)
+	assert.Equal(t, "first line\nsecond\nthird", note.Content)
+	assert.Equal(t, []string{"cli"}, note.Tags)
+
+	code, out, _ = runNotesCLIForTest(t, appDataDir, "", "grep", "second")
+	require.Equal(t, 0, code)
+	assert.Equal(t, noteID+":2:second\n", out)
+
+	// フォルダは名前でも指定できる
+	ns, err := NewNoteService(filepath.Join(appDataDir, "notes"), nil)
+	require.NoError(t, err)
+	folder, err := ns.CreateFolder("work")
+	require.NoError(t, err)
+
+	code, _, errOut = runNotesCLIForTest(t, appDataDir, "", "mv", noteID, "work")
+	require.Equal(t, 0, code, errOut)
+	code, out, _ = runNotesCLIForTest(t, appDataDir, "", "list", "--folder", folder.ID)
+	require.Equal(t, 0, code)
+	assert.Equal(t, noteID+"\t"+note.ModifiedTime+"\twork\trenamed\n", out)
+
+	code, _, errOut = runNotesCLIForTest(t, appDataDir, "", "archive", noteID)
+	require.Equal(t, 0, code, errOut)
+	code, out, _ = runNotesCLIForTest(t, appDataDir, "", "list")
+	require.Equal(t, 0, code)
+	assert.Empty(t, out)
+	code, out, _ = runNotesCLIForTest(t, appDataDir, "", "list", "--archived", "--json")
+	require.Equal(t, 0, code)
+	var archived []NoteMetadata
+	require.NoError(t, json.Unmarshal([]byte(out), &archived))
+	require.Len(t, archived, 1)
+	assert.Equal(t, noteID, archived[0].ID)
+	assert.Empty(t, archived[0].FolderID)
+
+	// 変更は次回 GUI 起動時に同期されるよう dirty として記録される
+	syncState := NewSyncState(appDataDir)
+	require.NoError(t, syncState.Load())
+	assert.True(t, syncState.Dirty)
+	assert.True(t, syncState.DirtyNoteIDs[noteID])
+
+	code, _, errOut = runNotesCLIForTest(t, appDataDir, "", "rm", noteID)
+	require.Equal(t, 0, code, errOut)
+	require.NoError(t, syncState.Load())
+	assert.True(t, syncState.Dirty)
+	ns, err = NewNoteService(filepath.Join(appDataDir, "notes"), nil)
+	require.NoError(t, err)
+	trash := ns.ListTrash()
+	require.Len(t, trash, 1)
+	assert.Equal(t, noteID, trash[0].ID)
+
+	code, _, errOut = runNotesCLIForTest(t, appDataDir, "", "show", noteID)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "note not found")
+}
+
+func TestNotesCLI_UsageErrors(t *testing.T) {
+	appDataDir := t.TempDir()
+
+	code, _, errOut := runNotesCLIForTest(t, appDataDir, "")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "usage:")
+
+	code, _, _ = runNotesCLIForTest(t, appDataDir, "", "unknown")
+	assert.Equal(t, 2, code)
+
+	code, _, errOut = runNotesCLIForTest(t, appDataDir, "", "mv", "only-one-arg")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "expects 2 argument(s)")
+}
+
+// GUI などがロックを保持している間は何も書き込まずに失敗すること
+func TestNotesCLI_RefusesWhileDataDirLocked(t *testing.T) {
+	appDataDir := t.TempDir()
+	lock, err := acquireDataDirLock(appDataDir, 0)
+	require.NoError(t, err)
+
+	code, _, errOut := runNotesCLIForTest(t, appDataDir, "content", "new")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, errDataDirLocked.Error())
+	assert.NoFileExists(t, filepath.Join(appDataDir, "sync_state.json"))
+
+	require.NoError(t, lock.Release())
+	code, _, errOut = runNotesCLIForTest(t, appDataDir, "content", "new")
+	assert.Equal(t, 0, code, errOut)
+}
+
+// 読み取りだけのコマンドは共有ロックで実行され、他の読み取りと重なっても失敗しないこと
+func TestNotesCLI_ReadOnlyCommandsShareTheLock(t *testing.T) {
+	appDataDir := t.TempDir()
+	code, out, errOut := runNotesCLIForTest(t, appDataDir, "shared", "new", "--title", "memo")
+	require.Equal(t, 0, code, errOut)
+	noteID := strings.TrimSpace(out)
+
+	reader, err := acquireDataDirLockShared(appDataDir, 0)
+	require.NoError(t, err)
+	defer reader.Release()
+
+	code, out, errOut = runNotesCLIForTest(t, appDataDir, "", "show", noteID)
+	require.Equal(t, 0, code, errOut)
+	assert.Equal(t, "shared", out)
+	code, out, _ = runNotesCLIForTest(t, appDataDir, "", "grep", "shared")
+	require.Equal(t, 0, code)
+	assert.Equal(t, noteID+":1:shared\n", out)
+
+	// 書き込むコマンドは共有ロックとも重ならない（受け取る GUI がいないのでエラー）
+	code, _, errOut = runNotesCLIForTest(t, appDataDir, "more", "append", noteID)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, errDataDirLocked.Error())
+}
+
+// GUI の起動中はコマンドが GUI に渡され、GUI のサービスに対して実行されること
+func TestNotesCLI_HandsOffToRunningApp(t *testing.T) {
+	app, _ := newLinkedFileTestApp(t)
+	app.ctx = &Context{ctx: context.Background()}
+	lock, err := acquireDataDirLock(app.appDataDir, 0)
+	require.NoError(t, err)
+	defer lock.Release()
+	app.startNotesCLIInbox()
+	defer app.stopNotesCLIInbox()
+
+	code, out, errOut := runNotesCLIForTest(t, app.appDataDir, "from cli", "new", "--title", "handed off")
+	require.Equal(t, 0, code, errOut)
+	noteID := strings.TrimSpace(out)
+
+	// GUI のメモリ上のノートリストにそのまま反映され、同期対象になる
+	note, err := app.noteService.LoadNote(noteID)
+	require.NoError(t, err)
+	assert.Equal(t, "handed off", note.Title)
+	assert.Equal(t, "from cli", note.Content)
+	dirtyNoteIDs, _, _ := app.syncState.GetDirtySnapshot()
+	assert.True(t, dirtyNoteIDs[noteID])
+
+	code, out, _ = runNotesCLIForTest(t, app.appDataDir, "", "list")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, noteID)
+
+	code, _, errOut = runNotesCLIForTest(t, app.appDataDir, "", "show", "missing")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "note not found: missing")
+
+	// 終了後は inbox が無くなり、受け渡さずに失敗する
+	app.stopNotesCLIInbox()
+	assert.NoDirExists(t, notesCLIInboxDir(app.appDataDir))
+	code, _, errOut = runNotesCLIForTest(t, app.appDataDir, "", "list")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, errDataDirLocked.Error())
+}
@@ -0,0 +1,230 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// ------------------------------------------------------------
+// パスをファイルIDとして扱う DriveOperations の共通部品
+// ------------------------------------------------------------
+//
+// WebDAV・ローカルフォルダのプロバイダーは、Google Drive のファイルIDの代わりに
+// 同期先ルートからの相対パス (例: "monaco-notepad/notes/<id>.json") をIDとして扱う。
+// これにより driveService / driveSyncService の同期処理は Drive と同じコードのまま動く。
+//
+// - ListFiles は driveService が使う Drive のクエリ
+//   (name='...' / '<id>' in parents / mimeType=フォルダ / trashed=false) だけを解釈する。
+//   親の指定が無いクエリは同期先ルート直下を探す。
+// - 変更検出は Changes API の代わりに monaco-notepad 以下のスナップショットを比較する。
+//   ページトークンはスナップショットのハッシュ。
+
+const (
+	pathRootFolderName  = "monaco-notepad"
+	pathMaxSnapshots    = 4
+	driveFolderMimeType = "application/vnd.google-apps.folder"
+)
+
+var (
+	pathQueryNamePattern   = regexp.MustCompile(`name='([^']*)'`)
+	pathQueryParentPattern = regexp.MustCompile(`'([^']*)' in parents`)
+)
+
+// pathEntry はファイル・フォルダ 1 件分
+type pathEntry struct {
+	id       string
+	isDir    bool
+	version  string // 内容が変わると変わる値 (ETag やハッシュ)。Md5Checksum として返す
+	modified time.Time
+	size     int64
+}
+
+func (e pathEntry) name() string {
+	return path.Base(e.id)
+}
+
+func (e pathEntry) parent() string {
+	parent := path.Dir(e.id)
+	if parent == "." || parent == "/" {
+		return ""
+	}
+	return parent
+}
+
+// driveFile は Drive のファイル情報に変換する。
+// 更新日時は秒単位でしか取れない環境 (HTTP の Last-Modified や FAT など) があるため、
+// 同じ秒に 2 回書き込まれても SyncNotes が変更を検出できるよう、
+// version から決まるミリ秒を加える（どの端末でも同じ値になる）。
+func (e pathEntry) driveFile() *drive.File {
+	hash := fnv.New32a()
+	hash.Write([]byte(e.version))
+	modified := e.modified.UTC().Truncate(time.Second).Add(time.Duration(hash.Sum32()%1000) * time.Millisecond)
+	file := &drive.File{
+		Id:           e.id,
+		Name:         e.name(),
+		ModifiedTime: modified.Format("2006-01-02T15:04:05.000Z07:00"),
+		Md5Checksum:  e.version,
+		Size:         e.size,
+	}
+	if e.parent() != "" {
+		file.Parents = []string{e.parent()}
+	}
+	if e.isDir {
+		file.MimeType = driveFolderMimeType
+	}
+	return file
+}
+
+// pathFileQuery は ListFiles のクエリのうち解釈する条件
+type pathFileQuery struct {
+	parentID    string
+	name        string
+	hasName     bool
+	foldersOnly bool
+}
+
+func parsePathFileQuery(query string) pathFileQuery {
+	var q pathFileQuery
+	if m := pathQueryParentPattern.FindStringSubmatch(query); m != nil {
+		q.parentID = m[1]
+	}
+	if m := pathQueryNamePattern.FindStringSubmatch(query); m != nil {
+		q.name, q.hasName = m[1], true
+	}
+	q.foldersOnly = strings.Contains(query, "mimeType='"+driveFolderMimeType+"'")
+	return q
+}
+
+func (q pathFileQuery) matches(entry pathEntry) bool {
+	if q.hasName && entry.name() != q.name {
+		return false
+	}
+	return !q.foldersOnly || entry.isDir
+}
+
+// pathJoinID は親フォルダのIDと名前からIDを作る。Drive の "appDataFolder" はルートとして扱う
+func pathJoinID(parentID string, name string) string {
+	parentID = pathCleanID(parentID)
+	if parentID == "" {
+		return name
+	}
+	return parentID + "/" + name
+}
+
+func pathCleanID(id string) string {
+	id = strings.Trim(id, "/")
+	if id == "appDataFolder" {
+		return ""
+	}
+	return id
+}
+
+// pathFileIDFor は GetFileID の引数から、ファイルがあるべきパスを返す
+func pathFileIDFor(fileName string, noteFolderID string, rootFolderID string) (string, error) {
+	if rootFolderID == "" {
+		return "", fmt.Errorf("rootFolderID is empty")
+	}
+	switch {
+	case strings.Contains(fileName, "noteList_v2.json"):
+		return pathJoinID(rootFolderID, "noteList_v2.json"), nil
+	case strings.Contains(fileName, ".json"):
+		if noteFolderID == "" {
+			return "", fmt.Errorf("noteFolderID is empty for note file")
+		}
+		return pathJoinID(noteFolderID, fileName), nil
+	}
+	return pathJoinID(rootFolderID, fileName), nil
+}
+
+// pathFileNotFoundError は Drive 版の GetFileID と同じ文言の not found エラーを返す
+func pathFileNotFoundError(fileName string, noteFolderID string, rootFolderID string) error {
+	if strings.Contains(fileName, ".json") && !strings.Contains(fileName, "noteList_v2.json") {
+		return fmt.Errorf("note file %s not found in folder %s", fileName, noteFolderID)
+	}
+	return fmt.Errorf("file %s not found in folder %s", fileName, rootFolderID)
+}
+
+// pathSnapshots はページトークンごとのスナップショットを保持する。古いものから捨てる
+type pathSnapshots struct {
+	mu    sync.Mutex
+	byID  map[string]map[string]pathEntry
+	order []string
+}
+
+func (p *pathSnapshots) store(snapshot map[string]pathEntry) string {
+	ids := make([]string, 0, len(snapshot))
+	for id := range snapshot {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	hash := sha256.New()
+	for _, id := range ids {
+		fmt.Fprintf(hash, "%s\x00%s\n", id, snapshot[id].version)
+	}
+	token := hex.EncodeToString(hash.Sum(nil))[:32]
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.byID == nil {
+		p.byID = make(map[string]map[string]pathEntry)
+	}
+	if _, exists := p.byID[token]; exists {
+		p.order = slices.DeleteFunc(p.order, func(t string) bool { return t == token })
+	}
+	p.order = append(p.order, token)
+	p.byID[token] = snapshot
+	for len(p.order) > pathMaxSnapshots {
+		delete(p.byID, p.order[0])
+		p.order = p.order[1:]
+	}
+	return token
+}
+
+// listChanges はトークンの時点から変わったファイルを返す。
+// 削除されたファイルは Trashed=true の File 付きで返し、hasRelevantChanges で親フォルダを判定できるようにする。
+func (p *pathSnapshots) listChanges(pageToken string, take func() (map[string]pathEntry, error)) (*ChangesResult, error) {
+	p.mu.Lock()
+	previous, ok := p.byID[pageToken]
+	p.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("failed to list changes: unknown page token %s", pageToken)
+	}
+
+	current, err := take()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changes: %w", err)
+	}
+
+	var changes []*drive.Change
+	for id, entry := range current {
+		if old, exists := previous[id]; exists && old.version == entry.version {
+			continue
+		}
+		changes = append(changes, &drive.Change{FileId: id, File: entry.driveFile()})
+	}
+	for id, entry := range previous {
+		if _, exists := current[id]; exists {
+			continue
+		}
+		file := entry.driveFile()
+		file.Trashed = true
+		changes = append(changes, &drive.Change{FileId: id, Removed: true, File: file})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].FileId < changes[j].FileId })
+
+	return &ChangesResult{
+		Changes:       changes,
+		NewStartToken: p.store(current),
+	}, nil
+}
@@ -0,0 +1,176 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+// プロファイル（データディレクトリの切り替え）
+// ------------------------------------------------------------
+//
+// プロファイルごとに notes、noteList_v2.json、sync_state.json、token.json、settings.json などを
+// 別々のデータディレクトリに置き、同期先のアカウントも別々にする。
+// - 既定のプロファイル ("default") は従来どおりルート (UserConfigDir()/monaco-notepad) を使う。
+// - それ以外はルートの profiles/<id> を使う。
+// - 一覧と使用中のプロファイルはルートの profiles.json に保存する (profiles.json が無ければ既定のみ)。
+
+const (
+	profilesFileName = "profiles.json"
+	profilesDirName  = "profiles"
+	defaultProfileID = "default"
+	// 既定のプロファイルの表示名
+	defaultProfileName = "Default"
+)
+
+// profileDataDir はプロファイルのデータディレクトリを返す
+func profileDataDir(rootDir string, profileID string) string {
+	if profileID == defaultProfileID {
+		return rootDir
+	}
+	return filepath.Join(rootDir, profilesDirName, profileID)
+}
+
+// loadProfiles は profiles.json を読み込む。無ければ既定のプロファイルだけの一覧を返す
+func loadProfiles(rootDir string) (*ProfileList, error) {
+	data, err := os.ReadFile(filepath.Join(rootDir, profilesFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &ProfileList{
+				ActiveID: defaultProfileID,
+				Profiles: []Profile{{ID: defaultProfileID, Name: defaultProfileName}},
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+	var list ProfileList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles: %w", err)
+	}
+	// 既定のプロファイルは消せないので、一覧から欠けていても先頭に戻す
+	if !slices.ContainsFunc(list.Profiles, func(p Profile) bool { return p.ID == defaultProfileID }) {
+		list.Profiles = slices.Insert(list.Profiles, 0, Profile{ID: defaultProfileID, Name: defaultProfileName})
+	}
+	if !slices.ContainsFunc(list.Profiles, func(p Profile) bool { return p.ID == list.ActiveID }) {
+		list.ActiveID = defaultProfileID
+	}
+	return &list, nil
+}
+
+func saveProfiles(rootDir string, list *ProfileList) error {
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal profiles: %w", err)
+	}
+	if err := os.MkdirAll(rootDir, 0755); err != nil {
+		return fmt.Errorf("failed to create app data directory: %w", err)
+	}
+	path := filepath.Join(rootDir, profilesFileName)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write profiles: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace profiles: %w", err)
+	}
+	return nil
+}
+
+// activeProfileDataDir は使用中のプロファイルのデータディレクトリを返す。
+// profiles.json が読めない場合は既定のプロファイル (ルート) を使う。
+func activeProfileDataDir(rootDir string) string {
+	list, err := loadProfiles(rootDir)
+	if err != nil {
+		return rootDir
+	}
+	return profileDataDir(rootDir, list.ActiveID)
+}
+
+// findProfile は ID が一致するプロファイルを返す
+func findProfile(list *ProfileList, profileID string) (Profile, bool) {
+	for _, profile := range list.Profiles {
+		if profile.ID == profileID {
+			return profile, true
+		}
+	}
+	return Profile{}, false
+}
+
+// createProfile は新しいプロファイルとそのデータディレクトリを作る (使用中のプロファイルは変えない)。
+// 表示名は前後の空白を除き、既存のものと大文字小文字を区別せずに重複してはならない。
+// 見た目の設定は fromDataDir (作成時に使っていたプロファイル) から引き継ぎ、同期先や最後に開いたノートは引き継がない。
+func createProfile(rootDir string, name string, fromDataDir string) (*Profile, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("profile name is empty")
+	}
+	list, err := loadProfiles(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, profile := range list.Profiles {
+		if strings.EqualFold(profile.Name, name) {
+			return nil, fmt.Errorf("profile already exists: %s", name)
+		}
+	}
+
+	profile := Profile{
+		ID:        uuid.New().String(),
+		Name:      name,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	dataDir := profileDataDir(rootDir, profile.ID)
+	if err := os.MkdirAll(filepath.Join(dataDir, "notes"), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	if fromDataDir != "" {
+		if settings, err := NewSettingsService(fromDataDir).LoadSettings(); err == nil {
+			settings.SyncProvider = ""
+			settings.LastActiveNoteId = ""
+			settings.LastActiveNoteIsFile = false
+			if err := NewSettingsService(dataDir).SaveSettings(settings); err != nil {
+				_ = os.RemoveAll(dataDir)
+				return nil, err
+			}
+		}
+	}
+
+	list.Profiles = append(list.Profiles, profile)
+	if err := saveProfiles(rootDir, list); err != nil {
+		_ = os.RemoveAll(dataDir)
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// removeProfileData はプロファイルのデータを全て削除する。
+// 既定のプロファイルはルートを使うため、profiles.json と他のプロファイルのディレクトリは残す。
+func removeProfileData(rootDir string, dataDir string) error {
+	if filepath.Clean(dataDir) != filepath.Clean(rootDir) {
+		return os.RemoveAll(dataDir)
+	}
+	entries, err := os.ReadDir(rootDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name() == profilesFileName || entry.Name() == profilesDirName {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(rootDir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfiles_CreateAndActivate(t *testing.T) {
+	rootDir := t.TempDir()
+
+	// profiles.json が無ければ既定のプロファイル (ルート) だけ
+	list, err := loadProfiles(rootDir)
+	require.NoError(t, err)
+	assert.Equal(t, defaultProfileID, list.ActiveID)
+	require.Len(t, list.Profiles, 1)
+	assert.Equal(t, rootDir, activeProfileDataDir(rootDir))
+
+	require.NoError(t, NewSettingsService(rootDir).SaveSettings(&Settings{FontSize: 18, SyncProvider: syncProviderWebDAV, LastActiveNoteId: "note1"}))
+	work, err := createProfile(rootDir, " Work ", rootDir)
+	require.NoError(t, err)
+	assert.Equal(t, "Work", work.Name)
+	_, err = createProfile(rootDir, "work", rootDir)
+	assert.Error(t, err)
+	_, err = createProfile(rootDir, "  ", rootDir)
+	assert.Error(t, err)
+
+	// 見た目の設定は引き継ぎ、同期先と最後に開いたノートは引き継がない
+	workDir := profileDataDir(rootDir, work.ID)
+	assert.DirExists(t, filepath.Join(workDir, "notes"))
+	settings, err := NewSettingsService(workDir).LoadSettings()
+	require.NoError(t, err)
+	assert.Equal(t, 18, settings.FontSize)
+	assert.Empty(t, settings.SyncProvider)
+	assert.Empty(t, settings.LastActiveNoteId)
+
+	list, err = loadProfiles(rootDir)
+	require.NoError(t, err)
+	require.Len(t, list.Profiles, 2)
+	assert.Equal(t, defaultProfileID, list.ActiveID, "creating a profile does not switch to it")
+
+	list.ActiveID = work.ID
+	require.NoError(t, saveProfiles(rootDir, list))
+	assert.Equal(t, workDir, activeProfileDataDir(rootDir))
+
+	// 一覧から消えたプロファイルが使用中になっていれば既定に戻す
+	list.Profiles = list.Profiles[:1]
+	require.NoError(t, saveProfiles(rootDir, list))
+	assert.Equal(t, rootDir, activeProfileDataDir(rootDir))
+}
+
+func TestRemoveProfileData_KeepsOtherProfiles(t *testing.T) {
+	rootDir := t.TempDir()
+	work, err := createProfile(rootDir, "Work", "")
+	require.NoError(t, err)
+	workDir := profileDataDir(rootDir, work.ID)
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "token.json"), []byte("{}"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(rootDir, "notes"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(rootDir, "token.json"), []byte("{}"), 0644))
+
+	// 既定のプロファイルのデータを消しても、profiles.json と他のプロファイルは残る
+	require.NoError(t, removeProfileData(rootDir, rootDir))
+	assert.NoFileExists(t, filepath.Join(rootDir, "token.json"))
+	assert.NoDirExists(t, filepath.Join(rootDir, "notes"))
+	assert.FileExists(t, filepath.Join(rootDir, profilesFileName))
+	assert.FileExists(t, filepath.Join(workDir, "token.json"))
+
+	require.NoError(t, removeProfileData(rootDir, workDir))
+	assert.NoDirExists(t, workDir)
+	list, err := loadProfiles(rootDir)
+	require.NoError(t, err)
+	assert.Len(t, list.Profiles, 2)
+}
+
+// プロファイルの切り替えでサービスを作り直す間も、バインディングとバックグラウンドの処理が
+// 切り替え前か後のサービスを使って動き続けること (go test -race で確認する)
+func TestProfiles_ReopenDataDirWhileBindingsRun(t *testing.T) {
+	rootDir := t.TempDir()
+	work, err := createProfile(rootDir, "Work", "")
+	require.NoError(t, err)
+	workDir := profileDataDir(rootDir, work.ID)
+	require.NoError(t, os.MkdirAll(filepath.Join(rootDir, "notes"), 0755))
+
+	app := &App{
+		ctx:         NewContext(context.Background()),
+		rootDataDir: rootDir,
+		appDataDir:  rootDir,
+		notesDir:    filepath.Join(rootDir, "notes"),
+		logger:      NewAppLogger(context.Background(), true, rootDir),
+		fileService: NewFileService(&Context{}),
+	}
+	app.openDataServices()
+	app.startBackgroundLoops()
+	t.Cleanup(app.stopBackgroundLoops)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				id := fmt.Sprintf("note-%d-%d", worker, i%3)
+				assert.NoError(t, app.SaveNote(&Note{ID: id, Title: id, Content: "body", Language: "plaintext"}, "update"))
+				_, err := app.ListNotes()
+				assert.NoError(t, err)
+				app.SetLastActiveNote(id, false)
+				_, err = app.LoadSettings()
+				assert.NoError(t, err)
+				app.ListLinkedFiles()
+				assert.NoError(t, app.checkLinkedFiles())
+			}
+		}(worker)
+	}
+
+	for i := 0; i < 6; i++ {
+		dataDir, previousDir := workDir, rootDir
+		if i%2 == 1 {
+			dataDir, previousDir = rootDir, workDir
+		}
+		app.stopBackgroundLoops()
+		app.reopenDataDir(dataDir)
+		assert.Equal(t, dataDir, app.currentAppDataDir())
+		// CLI inbox も切り替え先に移る
+		assert.DirExists(t, notesCLIInboxDir(dataDir))
+		assert.NoDirExists(t, notesCLIInboxDir(previousDir))
+	}
+	close(stop)
+	wg.Wait()
+
+	// 切り替え後の保存は切り替え先のノートディレクトリに書く
+	require.NoError(t, app.SaveNote(&Note{ID: "after-switch", Title: "after", Content: "body", Language: "plaintext"}, "create"))
+	assert.FileExists(t, filepath.Join(rootDir, "notes", "after-switch.json"))
+	assert.NoFileExists(t, filepath.Join(workDir, "notes", "after-switch.json"))
+}
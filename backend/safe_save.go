@@ -0,0 +1,244 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// ------------------------------------------------------------
+// ファイルノートの安全な保存
+// ------------------------------------------------------------
+//
+// 利用者のファイルへの書き込みは writeFileAtomic で行う。
+// - 同じディレクトリの一時ファイルに書いて fsync し、rename で置き換える (途中で落ちても元の内容が残る)。
+// - 元のパーミッションを引き継ぎ、シンボリックリンクはリンク先を置き換える (リンク自体は残す)。
+// 設定 (FileBackupMode) に応じて、上書きする前のディスクの内容を残す。
+// - "bak": 同じ場所に <名前>.bak を 1 つ残す。
+// - "rotate": appDataDir/file_backups/<パスのハッシュ>/ に時刻付きで残し、古いものから消す。
+// SaveFileChecked は開いた後にディスクの内容が変わっていれば書き込まず、
+// 開いた時点・ディスク・バッファの 3 つの版の差分 (FileSaveConflict) を返す。
+
+// ファイル保存時のバックアップ (Settings.FileBackupMode)
+const (
+	fileBackupModeOff    = ""
+	fileBackupModeBak    = "bak"
+	fileBackupModeRotate = "rotate"
+)
+
+const (
+	fileBackupDirName      = "file_backups"
+	fileBackupSourceName   = "source.txt" // 世代のディレクトリに置く、元のファイルのパス
+	fileBackupTimeLayout   = "20060102-150405.000"
+	defaultFileBackupKeep  = 5
+	fileBackupExtension    = ".bak"
+	atomicWriteTempPattern = ".%s.tmp-*"
+	maxSymlinkHops         = 40 // Linux の MAXSYMLINKS と同じ
+)
+
+// FileSaveResult は SaveFileChecked の結果（Conflict があれば保存していない）
+type FileSaveResult struct {
+	ModifiedTime string            `json:"modifiedTime,omitempty"` // 保存後のディスク mtime（RFC3339Nano）
+	Conflict     *FileSaveConflict `json:"conflict,omitempty"`     // 開いた後に外部で変更されていたときの 3 つの版
+}
+
+// FileSaveConflict は外部での変更と保存しようとした内容の 3-way の比較
+type FileSaveConflict struct {
+	FilePath         string     `json:"filePath"`
+	Original         string     `json:"original"`         // 開いた（前回保存した）時点の内容
+	Disk             string     `json:"disk"`             // ディスク上の今の内容
+	Buffer           string     `json:"buffer"`           // 保存しようとした内容
+	DiskModifiedTime string     `json:"diskModifiedTime"` // ディスク上の mtime（RFC3339Nano）
+	DiskLines        []DiffLine `json:"diskLines"`        // 開いた時点 → ディスクの差分
+	BufferLines      []DiffLine `json:"bufferLines"`      // 開いた時点 → バッファの差分
+	Merged           string     `json:"merged"`           // 3-way マージの結果（重なる変更は local=バッファ / cloud=ディスクのマーカー付き）
+	Conflicts        int        `json:"conflicts"`        // マーカーを付けた箇所の数
+}
+
+// writeFileAtomic は一時ファイルへ書いてから rename で置き換える
+func writeFileAtomic(path string, data []byte) error {
+	target, err := resolveSymlinkTarget(path)
+	if err != nil {
+		return err
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(target); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(target)
+	tmp, err := os.CreateTemp(dir, fmt.Sprintf(atomicWriteTempPattern, filepath.Base(target)))
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmpPath)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		cleanup()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		cleanup()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %w", target, err)
+	}
+	syncDir(dir)
+	return nil
+}
+
+// resolveSymlinkTarget は path がシンボリックリンクならリンク先のパスを返す
+// リンク先がまだ無い (dangling) 場合もリンク先を返し、リンクを通常のファイルで置き換えない。
+func resolveSymlinkTarget(path string) (string, error) {
+	target := path
+	for i := 0; i < maxSymlinkHops; i++ {
+		info, err := os.Lstat(target)
+		if errors.Is(err, os.ErrNotExist) {
+			return target, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return target, nil
+		}
+		link, err := os.Readlink(target)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(target), link)
+		}
+		target = link
+	}
+	return "", fmt.Errorf("too many levels of symbolic links: %s", path)
+}
+
+// syncDir は rename をディスクに反映させる (Windows ではディレクトリを開けないので何もしない)
+func syncDir(dir string) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+}
+
+// backupFileBeforeSave は上書きする前のディスクの内容を mode に応じて残す (ファイルが無ければ何もしない)
+func backupFileBeforeSave(appDataDir string, path string, mode string, keep int, now time.Time) error {
+	if mode == fileBackupModeOff {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// 元のファイルより緩いパーミッションで中身を残さない
+	perm := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	switch mode {
+	case fileBackupModeBak:
+		if err := writeFileAtomic(path+fileBackupExtension, data); err != nil {
+			return err
+		}
+		return os.Chmod(path+fileBackupExtension, perm)
+	case fileBackupModeRotate:
+		dir := fileBackupDir(appDataDir, path)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, fileBackupSourceName), []byte(path), 0600); err != nil {
+			return err
+		}
+		backupPath := filepath.Join(dir, now.Format(fileBackupTimeLayout)+"-"+filepath.Base(path))
+		if err := os.WriteFile(backupPath, data, perm); err != nil {
+			return err
+		}
+		// 同じ名前が既にあると WriteFile はパーミッションを変えない
+		if err := os.Chmod(backupPath, perm); err != nil {
+			return err
+		}
+		return rotateFileBackups(dir, keep)
+	default:
+		return fmt.Errorf("unknown file backup mode: %s", mode)
+	}
+}
+
+// fileBackupDir は path の世代を残すディレクトリ
+func fileBackupDir(appDataDir string, path string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(path)))
+	return filepath.Join(appDataDir, fileBackupDirName, fmt.Sprintf("%x", sum[:8]))
+}
+
+// rotateFileBackups は新しい keep 件を残して古い世代を消す
+func rotateFileBackups(dir string, keep int) error {
+	if keep <= 0 {
+		keep = defaultFileBackupKeep
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && entry.Name() != fileBackupSourceName {
+			names = append(names, entry.Name())
+		}
+	}
+	// 名前は時刻で始まるので、降順に並べれば新しい順になる
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	for _, name := range names[min(keep, len(names)):] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildFileSaveConflict は開いた時点・ディスク・バッファの 3 つの版を比べる
+func buildFileSaveConflict(path string, original string, disk string, buffer string, diskModifiedTime string) *FileSaveConflict {
+	// 改行コードの違いは差分にしない
+	normalize := func(text string) string { return applyLineEnding(text, lineEndingLF) }
+	original, disk, buffer = normalize(original), normalize(disk), normalize(buffer)
+	merged, conflicts := mergeLines3Way(original, buffer, disk)
+	return &FileSaveConflict{
+		FilePath:         path,
+		Original:         original,
+		Disk:             disk,
+		Buffer:           buffer,
+		DiskModifiedTime: diskModifiedTime,
+		DiskLines:        diffLineRecords(original, disk),
+		BufferLines:      diffLineRecords(original, buffer),
+		Merged:           merged,
+		Conflicts:        conflicts,
+	}
+}
+
+// sameTextIgnoringLineEndings は改行コードの違いを除いて同じ内容かを返す
+func sameTextIgnoringLineEndings(a string, b string) bool {
+	return applyLineEnding(a, lineEndingLF) == applyLineEnding(b, lineEndingLF)
+}
@@ -0,0 +1,176 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic_KeepsPermissionsAndSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions and symlinks need a Unix filesystem")
+	}
+	dir := t.TempDir()
+	target := filepath.Join(dir, "script.sh")
+	require.NoError(t, os.WriteFile(target, []byte("old"), 0755))
+	link := filepath.Join(dir, "link.sh")
+	require.NoError(t, os.Symlink(target, link))
+
+	require.NoError(t, writeFileAtomic(link, []byte("new")))
+
+	info, err := os.Lstat(link)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink, "the symlink itself is kept")
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+	info, err = os.Stat(target)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "no temp files are left behind")
+}
+
+func TestWriteFileAtomic_WritesThroughDanglingSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need a Unix filesystem")
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "real"), 0755))
+	link := filepath.Join(dir, "link.txt")
+	require.NoError(t, os.Symlink(filepath.Join("real", "memo.txt"), link))
+
+	require.NoError(t, writeFileAtomic(link, []byte("new")))
+
+	info, err := os.Lstat(link)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink, "the symlink itself is kept")
+	data, err := os.ReadFile(filepath.Join(dir, "real", "memo.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+}
+
+func TestBackupFileBeforeSave(t *testing.T) {
+	appDataDir := t.TempDir()
+	path := filepath.Join(t.TempDir(), "memo.txt")
+
+	// ファイルがまだ無ければ何もしない
+	require.NoError(t, backupFileBeforeSave(appDataDir, path, fileBackupModeBak, 0, time.Now()))
+	assert.NoFileExists(t, path+fileBackupExtension)
+
+	require.NoError(t, os.WriteFile(path, []byte("v1"), 0600))
+	require.NoError(t, backupFileBeforeSave(appDataDir, path, fileBackupModeBak, 0, time.Now()))
+	data, err := os.ReadFile(path + fileBackupExtension)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(data))
+
+	// rotate は新しい keep 件だけを残す
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	for i, content := range []string{"v1", "v2", "v3"} {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		require.NoError(t, backupFileBeforeSave(appDataDir, path, fileBackupModeRotate, 2, base.Add(time.Duration(i)*time.Minute)))
+	}
+	dir := fileBackupDir(appDataDir, path)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var kept []string
+	for _, entry := range entries {
+		if entry.Name() == fileBackupSourceName {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		require.NoError(t, err)
+		kept = append(kept, string(data))
+	}
+	assert.Equal(t, []string{"v2", "v3"}, kept)
+	source, err := os.ReadFile(filepath.Join(dir, fileBackupSourceName))
+	require.NoError(t, err)
+	assert.Equal(t, path, string(source))
+
+	assert.Error(t, backupFileBeforeSave(appDataDir, path, "unknown", 0, time.Now()))
+}
+
+func TestSaveFileChecked_ReturnsThreeWayDiffOnExternalChange(t *testing.T) {
+	appDataDir := t.TempDir()
+	path := filepath.Join(t.TempDir(), "memo.txt")
+	require.NoError(t, os.WriteFile(path, []byte("a\nb\nc\n"), 0644))
+	openedAt := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(path, openedAt, openedAt))
+
+	app := &App{
+		appDataDir:      appDataDir,
+		fileService:     NewFileService(&Context{}),
+		fileNoteService: NewFileNoteService(appDataDir),
+		settingsService: NewSettingsService(appDataDir),
+	}
+	app.fileService.beforeOverwrite = app.backupFileBeforeSave
+	require.NoError(t, app.settingsService.SaveSettings(&Settings{FileBackupMode: fileBackupModeBak}))
+	_, err := app.fileNoteService.SaveFileNotes([]FileNote{{ID: "file1", FilePath: path, FileName: "memo.txt", Content: "a\nb\nc\n", OriginalContent: "a\nb\nc\n"}})
+	require.NoError(t, err)
+	lastModified := openedAt.Format(time.RFC3339Nano)
+
+	// 外部で 1 行目が、バッファで 3 行目が変わった
+	require.NoError(t, os.WriteFile(path, []byte("A\nb\nc\n"), 0644))
+	result, err := app.SaveFileChecked(path, "a\nb\nC\n", lastModified)
+	require.NoError(t, err)
+	require.NotNil(t, result.Conflict)
+	assert.Empty(t, result.ModifiedTime)
+	assert.Equal(t, "a\nb\nc\n", result.Conflict.Original)
+	assert.Equal(t, "A\nb\nc\n", result.Conflict.Disk)
+	assert.Equal(t, "a\nb\nC\n", result.Conflict.Buffer)
+	assert.Equal(t, "A\nb\nC\n", result.Conflict.Merged)
+	assert.Equal(t, 0, result.Conflict.Conflicts)
+	assert.NotEmpty(t, result.Conflict.DiskLines)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "A\nb\nc\n", string(data), "the external change is not overwritten")
+
+	// 差分を確かめてからなら上書きでき、上書き前の内容が .bak に残る
+	modifiedTime, err := app.SaveFile(path, result.Conflict.Merged)
+	require.NoError(t, err)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "A\nb\nC\n", string(data))
+	backup, err := os.ReadFile(path + fileBackupExtension)
+	require.NoError(t, err)
+	assert.Equal(t, "A\nb\nc\n", string(backup))
+
+	// 変更が無ければそのまま保存する
+	result, err = app.SaveFileChecked(path, "A\nb\nC\nd\n", modifiedTime)
+	require.NoError(t, err)
+	assert.Nil(t, result.Conflict)
+	assert.NotEmpty(t, result.ModifiedTime)
+}
+
+func TestBackupFileBeforeSave_KeepsPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions need a Unix filesystem")
+	}
+	appDataDir := t.TempDir()
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte("secret"), 0600))
+
+	for _, mode := range []string{fileBackupModeBak, fileBackupModeRotate} {
+		require.NoError(t, backupFileBeforeSave(appDataDir, path, mode, 0, time.Now()))
+	}
+
+	info, err := os.Stat(path + fileBackupExtension)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	dir := fileBackupDir(appDataDir, path)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), entry.Name())
+	}
+}
@@ -0,0 +1,492 @@
+package backend
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// ------------------------------------------------------------
+// ノート全文検索インデックス
+// ------------------------------------------------------------
+//
+// フロントエンドは ListNotes の全件 Content を受け取って検索していたため、
+// ノート数が増えると webview が固まる。ここでは Go 側に転置インデックスを持ち、
+// 候補の絞り込みだけをインデックスで行い、最終的な一致判定と位置計算は
+// 候補ノートの本文に対して行う。
+//
+// - 正規化: 1 ルーン単位で全角英数→半角、半角カナ→全角、大文字→小文字に畳み込む。
+//   1:1 の写像なので、正規化後のルーン位置がそのまま元テキストの位置になる。
+// - トークン: 日本語には語の区切りが無いため、空白以外のルーン列から
+//   文字 bigram を作る (英数字も同じ扱いにして部分一致検索を可能にする)。
+//   1 文字だけのクエリは bigram が作れないので、フィルタ後の全件を走査する。
+// - インデックスは noteService.mu の保護下にあり、自身はロックを持たない。
+//   初回検索時に noteList から構築し、以降は SaveNote / DeleteNote /
+//   SaveNoteFromSync / DeleteNoteFromSync から差分更新する。それ以外の経路
+//   (整合性修復など) で書き換わったノートは、検索時に ContentHash を
+//   noteList と突き合わせて取り込み直す。
+
+const (
+	searchDefaultLimit        = 50  // 既定の最大ヒット件数
+	searchDefaultMatchLimit   = 20  // 1 ノートあたりの既定の最大一致件数
+	searchSnippetMaxRunes     = 120 // スニペットの最大長 (ルーン数)
+	searchSnippetContextRunes = 40  // 一致箇所の前に残す文脈の長さ (ルーン数)
+	searchTitleWeight         = 3.0 // タイトル一致の重み
+)
+
+// インデックス済みのノート本文
+type searchDocument struct {
+	id      string
+	hash    string // 取り込み時点の computeContentHash
+	title   string
+	content string
+}
+
+// 転置インデックス本体 (bigram → ノートIDの集合)
+type searchIndex struct {
+	docs     map[string]*searchDocument
+	postings map[string]map[string]struct{}
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		docs:     make(map[string]*searchDocument),
+		postings: make(map[string]map[string]struct{}),
+	}
+}
+
+// ノートを登録または更新する
+func (idx *searchIndex) upsert(note *Note) {
+	if note == nil || note.ID == "" {
+		return
+	}
+	idx.remove(note.ID)
+	doc := &searchDocument{
+		id:      note.ID,
+		hash:    computeContentHash(note),
+		title:   note.Title,
+		content: note.Content,
+	}
+	// 暗号化したノートは本文を索引しない (タイトルは一覧と同じく平文で扱う)
+	if note.Encrypted {
+		doc.content = ""
+	}
+	idx.docs[note.ID] = doc
+	for gram := range documentBigrams(doc) {
+		ids, ok := idx.postings[gram]
+		if !ok {
+			ids = make(map[string]struct{})
+			idx.postings[gram] = ids
+		}
+		ids[note.ID] = struct{}{}
+	}
+}
+
+// ノートをインデックスから外す
+func (idx *searchIndex) remove(id string) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for gram := range documentBigrams(doc) {
+		if ids, ok := idx.postings[gram]; ok {
+			delete(ids, id)
+			if len(ids) == 0 {
+				delete(idx.postings, gram)
+			}
+		}
+	}
+	delete(idx.docs, id)
+}
+
+// 全 term の bigram を含むノートIDを返す。bigram を作れない term しか無い場合は ok=false
+func (idx *searchIndex) candidates(terms [][]rune) (ids map[string]struct{}, ok bool) {
+	for _, term := range terms {
+		for _, gram := range bigramsOf(term) {
+			postings := idx.postings[gram]
+			if ids == nil {
+				ids = make(map[string]struct{}, len(postings))
+				for id := range postings {
+					ids[id] = struct{}{}
+				}
+			} else {
+				for id := range ids {
+					if _, hit := postings[id]; !hit {
+						delete(ids, id)
+					}
+				}
+			}
+			ok = true
+			if len(ids) == 0 {
+				return ids, true
+			}
+		}
+	}
+	return ids, ok
+}
+
+func documentBigrams(doc *searchDocument) map[string]struct{} {
+	grams := make(map[string]struct{})
+	for _, text := range []string{doc.title, doc.content} {
+		for _, field := range strings.FieldsFunc(normalizeSearchText(text), unicode.IsSpace) {
+			for _, gram := range bigramsOf([]rune(field)) {
+				grams[gram] = struct{}{}
+			}
+		}
+	}
+	return grams
+}
+
+func bigramsOf(runes []rune) []string {
+	if len(runes) < 2 {
+		return nil
+	}
+	grams := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		grams = append(grams, string(runes[i:i+2]))
+	}
+	return grams
+}
+
+// 検索用に 1 ルーンを畳み込む (ルーン数を変えないこと)
+func foldSearchRune(r rune) rune {
+	if folded := width.LookupRune(r).Folded(); folded != 0 {
+		r = folded
+	}
+	return unicode.ToLower(r)
+}
+
+func normalizeSearchText(text string) string {
+	return strings.Map(foldSearchRune, text)
+}
+
+// クエリを空白区切りの term に分解する (重複は除く)
+func parseSearchQuery(query string) [][]rune {
+	var terms [][]rune
+	seen := make(map[string]bool)
+	for _, field := range strings.Fields(normalizeSearchText(query)) {
+		if seen[field] {
+			continue
+		}
+		seen[field] = true
+		terms = append(terms, []rune(field))
+	}
+	return terms
+}
+
+// ------------------------------------------------------------
+// 一致位置の計算
+// ------------------------------------------------------------
+
+// ルーン単位の一致範囲
+type runeSpan struct {
+	start int
+	end   int
+}
+
+// normalized 中の term の出現位置を重ならないように全て返す
+func findRuneMatches(normalized []rune, term []rune) []runeSpan {
+	var spans []runeSpan
+	if len(term) == 0 {
+		return spans
+	}
+	for i := 0; i+len(term) <= len(normalized); {
+		matched := true
+		for j := range term {
+			if normalized[i+j] != term[j] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			spans = append(spans, runeSpan{start: i, end: i + len(term)})
+			i += len(term)
+			continue
+		}
+		i++
+	}
+	return spans
+}
+
+// 1 フィールド分の検索結果
+type fieldMatches struct {
+	spans      []runeSpan
+	termCounts []int // term ごとの出現回数
+}
+
+func matchField(text string, terms [][]rune) fieldMatches {
+	normalized := []rune(normalizeSearchText(text))
+	result := fieldMatches{termCounts: make([]int, len(terms))}
+	for i, term := range terms {
+		spans := findRuneMatches(normalized, term)
+		result.termCounts[i] = len(spans)
+		result.spans = append(result.spans, spans...)
+	}
+	sort.Slice(result.spans, func(i, j int) bool {
+		if result.spans[i].start != result.spans[j].start {
+			return result.spans[i].start < result.spans[j].start
+		}
+		return result.spans[i].end > result.spans[j].end
+	})
+	return result
+}
+
+func utf16Len(runes []rune) int {
+	n := 0
+	for _, r := range runes {
+		if r >= 0x10000 {
+			n += 2 // サロゲートペア
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// 一致範囲を行・列 (Monaco と同じ 1 始まり、列は UTF-16 単位) とスニペットに変換する。
+// spans は開始位置の昇順であること。
+func buildSearchMatches(field string, text string, spans []runeSpan, limit int) []SearchMatch {
+	if len(spans) == 0 {
+		return nil
+	}
+	runes := []rune(text)
+	matches := make([]SearchMatch, 0, min(len(spans), limit))
+	line, lineStart := 1, 0
+	pos := 0
+	for _, span := range spans {
+		if len(matches) >= limit {
+			break
+		}
+		for ; pos < span.start; pos++ {
+			if runes[pos] == '\n' {
+				line++
+				lineStart = pos + 1
+			}
+		}
+		lineEnd := lineStart
+		for lineEnd < len(runes) && runes[lineEnd] != '\n' {
+			lineEnd++
+		}
+		// term は空白を含まないので、一致が行をまたぐことはない
+		lineRunes := runes[lineStart:lineEnd]
+		if n := len(lineRunes); n > 0 && lineRunes[n-1] == '\r' {
+			lineRunes = lineRunes[:n-1]
+		}
+		snippet, snippetStart := cropSnippet(lineRunes, span.start-lineStart)
+		length := utf16Len(runes[span.start:span.end])
+		matches = append(matches, SearchMatch{
+			Field:              field,
+			Line:               line,
+			Column:             utf16Len(runes[lineStart:span.start]) + 1,
+			Length:             length,
+			Snippet:            snippet,
+			SnippetMatchStart:  utf16Len(lineRunes[snippetStart : span.start-lineStart]),
+			SnippetMatchLength: length,
+		})
+	}
+	return matches
+}
+
+// 行が長い場合は一致位置の周辺だけを切り出す。戻り値の start は切り出し開始位置 (行内ルーン位置)
+func cropSnippet(line []rune, matchStart int) (string, int) {
+	if len(line) <= searchSnippetMaxRunes {
+		return string(line), 0
+	}
+	start := max(matchStart-searchSnippetContextRunes, 0)
+	end := min(start+searchSnippetMaxRunes, len(line))
+	if end-start < searchSnippetMaxRunes {
+		start = max(end-searchSnippetMaxRunes, 0)
+	}
+	return string(line[start:end]), start
+}
+
+// ------------------------------------------------------------
+// noteService からの利用
+// ------------------------------------------------------------
+
+// 検索インデックスを差分更新する (caller が s.mu を握っている前提)。
+// 初回検索前はインデックスを持たないので何もしない。
+func (s *noteService) indexNoteLocked(note *Note) {
+	if s.searchIndex != nil {
+		s.searchIndex.upsert(note)
+	}
+}
+
+func (s *noteService) unindexNoteLocked(id string) {
+	if s.searchIndex != nil {
+		s.searchIndex.remove(id)
+	}
+}
+
+// インデックス用にノート本文を読む。アーカイブ済みノートで noteCache を
+// 膨らませないよう、キャッシュに無いものはディスクから直接読む。
+func (s *noteService) readNoteForIndexLocked(id string) (*Note, error) {
+	if cached, ok := s.noteCache[id]; ok {
+		return cached, nil
+	}
+	data, err := os.ReadFile(filepath.Join(s.notesDir, id+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var note Note
+	if err := json.Unmarshal(data, &note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// インデックスを noteList に追従させる (caller が s.mu を握っている前提)
+func (s *noteService) syncSearchIndexLocked() {
+	if s.searchIndex == nil {
+		s.searchIndex = newSearchIndex()
+	}
+	listed := make(map[string]bool, len(s.noteList.Notes))
+	for _, metadata := range s.noteList.Notes {
+		listed[metadata.ID] = true
+		doc, ok := s.searchIndex.docs[metadata.ID]
+		if ok && (metadata.ContentHash == "" || doc.hash == metadata.ContentHash) {
+			continue
+		}
+		note, err := s.readNoteForIndexLocked(metadata.ID)
+		if err != nil {
+			// 未ダウンロードのノートは次回の検索で取り込む
+			continue
+		}
+		s.searchIndex.upsert(note)
+	}
+	for id := range s.searchIndex.docs {
+		if !listed[id] {
+			s.searchIndex.remove(id)
+		}
+	}
+}
+
+func searchMetadataMatchesOptions(metadata NoteMetadata, options SearchOptions) bool {
+	switch options.Archived {
+	case SearchArchivedAll:
+	case SearchArchivedOnly:
+		if !metadata.Archived {
+			return false
+		}
+	default:
+		if metadata.Archived {
+			return false
+		}
+	}
+	if options.FolderID != "" && metadata.FolderID != options.FolderID {
+		return false
+	}
+	if options.Language != "" && !strings.EqualFold(metadata.Language, options.Language) {
+		return false
+	}
+	return true
+}
+
+// ノートを全文検索する ------------------------------------------------------------
+func (s *noteService) SearchNotes(query string, options SearchOptions) ([]SearchHit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hits := []SearchHit{}
+	terms := parseSearchQuery(query)
+	if len(terms) == 0 {
+		return hits, nil
+	}
+	limit := options.Limit
+	if limit <= 0 {
+		limit = searchDefaultLimit
+	}
+	matchLimit := options.MaxMatchesPerNote
+	if matchLimit <= 0 {
+		matchLimit = searchDefaultMatchLimit
+	}
+
+	s.syncSearchIndexLocked()
+	candidateIDs, pruned := s.searchIndex.candidates(terms)
+
+	type scoredHit struct {
+		hit          SearchHit
+		termCounts   []int
+		titleCounts  []int
+		modifiedTime string
+	}
+	var scored []scoredHit
+	docFreq := make([]int, len(terms))
+	for _, metadata := range s.noteList.Notes {
+		if pruned {
+			if _, ok := candidateIDs[metadata.ID]; !ok {
+				continue
+			}
+		}
+		if !searchMetadataMatchesOptions(metadata, options) {
+			continue
+		}
+		doc, ok := s.searchIndex.docs[metadata.ID]
+		if !ok {
+			continue
+		}
+
+		title := matchField(doc.title, terms)
+		content := matchField(doc.content, terms)
+		allMatched := true
+		for i := range terms {
+			if title.termCounts[i]+content.termCounts[i] == 0 {
+				allMatched = false
+				break
+			}
+		}
+		if !allMatched {
+			continue
+		}
+		for i := range terms {
+			docFreq[i]++
+		}
+
+		matches := buildSearchMatches(SearchFieldTitle, doc.title, title.spans, matchLimit)
+		matches = append(matches, buildSearchMatches(SearchFieldContent, doc.content, content.spans, matchLimit-len(matches))...)
+		scored = append(scored, scoredHit{
+			hit: SearchHit{
+				NoteID:       metadata.ID,
+				Title:        metadata.Title,
+				FolderID:     metadata.FolderID,
+				Language:     metadata.Language,
+				Archived:     metadata.Archived,
+				ModifiedTime: metadata.ModifiedTime,
+				MatchCount:   len(title.spans) + len(content.spans),
+				Matches:      matches,
+			},
+			termCounts:   content.termCounts,
+			titleCounts:  title.termCounts,
+			modifiedTime: metadata.ModifiedTime,
+		})
+	}
+
+	// tf-idf 風のスコア。タイトル一致を重く、長いノートでの多数一致は対数で抑える
+	total := float64(len(s.searchIndex.docs))
+	for i := range scored {
+		score := 0.0
+		for t := range terms {
+			idf := math.Log(1 + total/float64(docFreq[t]))
+			tf := searchTitleWeight*float64(scored[i].titleCounts[t]) + float64(scored[i].termCounts[t])
+			score += (1 + math.Log(tf)) * idf
+		}
+		scored[i].hit.Score = score
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].hit.Score != scored[j].hit.Score {
+			return scored[i].hit.Score > scored[j].hit.Score
+		}
+		return isModifiedTimeAfter(scored[i].modifiedTime, scored[j].modifiedTime)
+	})
+
+	for i := 0; i < len(scored) && i < limit; i++ {
+		hits = append(hits, scored[i].hit)
+	}
+	return hits, nil
+}
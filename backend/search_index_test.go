@@ -0,0 +1,197 @@
+package backend
+
+import (
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 日本語は bigram で部分一致し、行・列は 1 始まりで返ること
+func TestSearchNotes_JapaneseSubstringWithPosition(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+
+	require.NoError(t, helper.noteService.SaveNote(&Note{
+		ID:       "ja-note",
+		Title:    "議事録",
+		Content:  "一行目\n今日は全文検索の設計について話した",
+		Language: "markdown",
+	}))
+	require.NoError(t, helper.noteService.SaveNote(&Note{
+		ID:       "other-note",
+		Title:    "買い物",
+		Content:  "牛乳と卵",
+		Language: "plaintext",
+	}))
+
+	hits, err := helper.noteService.SearchNotes("検索", SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "ja-note", hits[0].NoteID)
+	require.Len(t, hits[0].Matches, 1)
+	match := hits[0].Matches[0]
+	assert.Equal(t, SearchFieldContent, match.Field)
+	assert.Equal(t, 2, match.Line)
+	assert.Equal(t, 6, match.Column)
+	assert.Equal(t, 2, match.Length)
+	assert.Equal(t, "今日は全文検索の設計について話した", match.Snippet)
+	assert.Equal(t, 5, match.SnippetMatchStart)
+}
+
+// 大文字小文字と全角半角を区別せず、全 term を含むノートだけがヒットすること
+func TestSearchNotes_NormalizationAndAndSemantics(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+
+	require.NoError(t, helper.noteService.SaveNote(&Note{ID: "n1", Title: "Go", Content: "ＧＯＬＡＮＧ tips and tricks"}))
+	require.NoError(t, helper.noteService.SaveNote(&Note{ID: "n2", Title: "Rust", Content: "golang を少しだけ"}))
+
+	hits, err := helper.noteService.SearchNotes("golang", SearchOptions{})
+	require.NoError(t, err)
+	assert.Len(t, hits, 2)
+
+	hits, err = helper.noteService.SearchNotes("golang tips", SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "n1", hits[0].NoteID)
+
+	// 1 文字クエリはインデックスを使わず全件走査で一致する
+	hits, err = helper.noteService.SearchNotes("を", SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "n2", hits[0].NoteID)
+}
+
+// タイトル一致が本文一致より上位に来ること
+func TestSearchNotes_RanksTitleMatchesHigher(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+
+	require.NoError(t, helper.noteService.SaveNote(&Note{ID: "body", Title: "メモ", Content: "設計の話"}))
+	require.NoError(t, helper.noteService.SaveNote(&Note{ID: "title", Title: "設計ノート", Content: "中身"}))
+
+	hits, err := helper.noteService.SearchNotes("設計", SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	assert.Equal(t, "title", hits[0].NoteID)
+	assert.Equal(t, SearchFieldTitle, hits[0].Matches[0].Field)
+	assert.Greater(t, hits[0].Score, hits[1].Score)
+}
+
+// フォルダ・言語・アーカイブ状態で絞り込めること
+func TestSearchNotes_Filters(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	folder, err := ns.CreateFolder("仕事")
+	require.NoError(t, err)
+	require.NoError(t, ns.SaveNote(&Note{ID: "in-folder", Title: "a", Content: "keyword", Language: "go"}))
+	require.NoError(t, ns.MoveNoteToFolder("in-folder", folder.ID))
+	require.NoError(t, ns.SaveNote(&Note{ID: "top", Title: "b", Content: "keyword", Language: "markdown"}))
+	require.NoError(t, ns.SaveNote(&Note{ID: "archived", Title: "c", Content: "keyword", Language: "go", Archived: true}))
+
+	ids := func(options SearchOptions) []string {
+		hits, err := ns.SearchNotes("keyword", options)
+		require.NoError(t, err)
+		var result []string
+		for _, hit := range hits {
+			result = append(result, hit.NoteID)
+		}
+		return result
+	}
+
+	assert.ElementsMatch(t, []string{"in-folder", "top"}, ids(SearchOptions{}))
+	assert.ElementsMatch(t, []string{"in-folder"}, ids(SearchOptions{FolderID: folder.ID}))
+	assert.ElementsMatch(t, []string{"in-folder"}, ids(SearchOptions{Language: "Go"}))
+	assert.ElementsMatch(t, []string{"archived"}, ids(SearchOptions{Archived: SearchArchivedOnly}))
+	assert.ElementsMatch(t, []string{"in-folder", "top", "archived"}, ids(SearchOptions{Archived: SearchArchivedAll}))
+	assert.Len(t, ids(SearchOptions{Archived: SearchArchivedAll, Limit: 1}), 1)
+}
+
+// 保存・削除・同期経路の更新がインデックスに反映されること
+func TestSearchNotes_IndexFollowsUpdates(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	note := &Note{ID: "n1", Title: "t", Content: "before"}
+	require.NoError(t, ns.SaveNote(note))
+	hits, err := ns.SearchNotes("before", SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+
+	note.Content = "after"
+	require.NoError(t, ns.SaveNote(note))
+	hits, err = ns.SearchNotes("before", SearchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+
+	// 同期経路: ノートファイルとインデックスだけが更新され、noteList は呼び出し側が更新する
+	synced := &Note{ID: "n1", Title: "t", Content: "from cloud"}
+	require.NoError(t, ns.SaveNoteFromSync(synced))
+	ns.WithLock(func() {
+		ns.noteList.Notes[0].ContentHash = computeContentHash(synced)
+	})
+	hits, err = ns.SearchNotes("cloud", SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+
+	require.NoError(t, ns.DeleteNoteFromSync("n1"))
+	ns.WithLock(func() {
+		_, ok := ns.searchIndex.docs["n1"]
+		assert.False(t, ok)
+	})
+
+	require.NoError(t, ns.SaveNote(&Note{ID: "n2", Title: "t", Content: "gone soon"}))
+	require.NoError(t, ns.DeleteNote("n2"))
+	hits, err = ns.SearchNotes("gone", SearchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+}
+
+// インデックス構築前に保存されたノートや、別経路で書き換わったノートも検索時に取り込むこと
+func TestSearchNotes_ResyncsWithNoteList(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+
+	require.NoError(t, helper.noteService.SaveNote(&Note{ID: "n1", Title: "t", Content: "alpha"}))
+
+	reloaded, err := NewNoteService(helper.notesDir, helper.noteService.logger)
+	require.NoError(t, err)
+	hits, err := reloaded.SearchNotes("alpha", SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+
+	// インデックスを経由しない書き換え (整合性修復など) を ContentHash の差分で検出する
+	changed := &Note{ID: "n1", Title: "t", Content: "beta"}
+	reloaded.WithLock(func() {
+		reloaded.noteCache["n1"] = changed
+		reloaded.noteList.Notes[0].ContentHash = computeContentHash(changed)
+	})
+	hits, err = reloaded.SearchNotes("beta", SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+}
+
+// 長い行は一致位置の周辺だけがスニペットになること
+func TestBuildSearchMatches_CropsLongLine(t *testing.T) {
+	prefix := make([]rune, 300)
+	for i := range prefix {
+		prefix[i] = 'x'
+	}
+	text := string(prefix) + "🎉needle"
+	spans := findRuneMatches([]rune(normalizeSearchText(text)), []rune("needle"))
+	require.Len(t, spans, 1)
+
+	matches := buildSearchMatches(SearchFieldContent, text, spans, 10)
+	require.Len(t, matches, 1)
+	// 絵文字はサロゲートペアなので UTF-16 では 2 単位
+	assert.Equal(t, 303, matches[0].Column)
+	assert.LessOrEqual(t, len([]rune(matches[0].Snippet)), searchSnippetMaxRunes)
+	snippet := utf16.Encode([]rune(matches[0].Snippet))
+	start := matches[0].SnippetMatchStart
+	assert.Equal(t, "needle", string(utf16.Decode(snippet[start:start+matches[0].SnippetMatchLength])))
+}
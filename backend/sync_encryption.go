@@ -0,0 +1,561 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+	"golang.org/x/crypto/scrypt"
+)
+
+// ------------------------------------------------------------
+// 同期データのエンドツーエンド暗号化
+// ------------------------------------------------------------
+//
+// ノートファイルと noteList_v2.json を、アップロード前にパスフレーズから導出した鍵
+// (scrypt) で AES-256-GCM 暗号化する。暗号化はシリアライズ後のバイト列に対して行うため、
+// computeContentHash などの比較は従来どおり平文の Note に対して行われる。
+//
+// - クラウドの monaco-notepad フォルダに鍵確認用ファイル (encryption_key.json) を置き、
+//   scrypt のソルトとパラメータ、既知の平文を暗号化したデータを保存する。
+//   2 台目の端末はこれを使ってパスフレーズが正しいかを確認する。
+// - 導出した鍵は appDataDir/sync_encryption.json に保存する（token.json と同じ扱い）。
+// - ダウンロード時は暗号化されていないデータもそのまま読めるため、有効化・無効化の途中でも
+//   同期は止まらない。既存データの再暗号化は MarkForFullReupload で全ノートを再アップロードする。
+// - クラウドが暗号化されているのにこの端末に鍵が無い場合は「ロック中」として同期を止め、
+//   平文で上書きしないようにする。
+// - モバイル版は復号できない（暗号化が有効なら同期を止めるだけ。旧モバイル版は暗号文を
+//   空のノートリストと見なしてしまう）。モバイル版は接続のたびに mobile_client.json へ
+//   日時を書くので、直近 syncMobileClientWindow 以内に接続していれば有効化を断る。
+
+const (
+	syncEncryptionFormat        = "monaco-notepad-e2e-v1"
+	syncKeyCheckFormat          = "monaco-notepad-keycheck-v1"
+	syncKeyCheckFileName        = "encryption_key.json"
+	syncEncryptionLocalFile     = "sync_encryption.json"
+	syncKeyCheckPlaintext       = "monaco-notepad key check"
+	syncKeyCheckAAD             = "keycheck"
+	syncNoteListAAD             = "noteList"
+	syncScryptN                 = 1 << 15
+	syncScryptR                 = 8
+	syncScryptP                 = 1
+	syncEncryptionKeyLength     = 32
+	syncEncryptionSaltLength    = 16
+	syncEncryptionMinPassLength = 8
+	syncMobileClientFileName    = "mobile_client.json"
+	syncMobileClientWindow      = 30 * 24 * time.Hour // この期間内に接続したモバイル版があれば暗号化を有効にしない
+)
+
+// クラウドの鍵確認データで受け入れる scrypt のパラメーターの範囲
+// (壊れた・細工されたファイルで何 GB ものメモリを確保したり、弱すぎる鍵導出を受け入れたりしない)
+const (
+	syncScryptMinN = syncScryptN >> 1
+	syncScryptMaxN = syncScryptN << 2
+	syncScryptMaxR = syncScryptR * 2
+	syncScryptMaxP = syncScryptP * 4
+)
+
+var (
+	errSyncEncryptionLocked    = errors.New("sync data is encrypted: passphrase required")
+	errSyncIncorrectPassphrase = errors.New("incorrect passphrase")
+	errSyncMobileClient        = errors.New("the mobile app syncs with this folder and cannot read encrypted sync data")
+)
+
+// ノートの暗号化に使う追加認証データ (別のノートのファイルと入れ替えられても検出できる)
+func syncNoteAAD(noteID string) string {
+	return "note:" + noteID
+}
+
+// 暗号化したペイロード (JSON としてアップロードする)
+type encryptedPayload struct {
+	Format string `json:"format"`
+	KeyID  string `json:"keyId"`
+	Nonce  []byte `json:"nonce"`
+	Data   []byte `json:"data"`
+}
+
+// syncKeyCheck はクラウドに置く鍵確認用データ。無効化した場合も Enabled=false で残し、
+// 他の端末に暗号化の停止を伝える
+type syncKeyCheck struct {
+	Format  string            `json:"format"`
+	Enabled bool              `json:"enabled"`
+	KeyID   string            `json:"keyId,omitempty"`
+	Salt    []byte            `json:"salt,omitempty"`
+	N       int               `json:"n,omitempty"`
+	R       int               `json:"r,omitempty"`
+	P       int               `json:"p,omitempty"`
+	Check   *encryptedPayload `json:"check,omitempty"`
+}
+
+// この端末に保存する鍵。無効化後も古いデータを読めるよう鍵は残す
+type syncEncryptionLocal struct {
+	KeyCheck syncKeyCheck `json:"keyCheck"`
+	Key      []byte       `json:"key"`
+}
+
+// syncEncryption は同期データの暗号化状態を保持する。nil の場合は暗号化しない
+type syncEncryption struct {
+	mu         sync.RWMutex
+	appDataDir string
+	local      *syncEncryptionLocal
+	aead       cipher.AEAD
+	locked     bool // クラウドが暗号化されているが、この端末に鍵が無い
+}
+
+func newSyncEncryption(appDataDir string) *syncEncryption {
+	e := &syncEncryption{appDataDir: appDataDir}
+	data, err := os.ReadFile(filepath.Join(appDataDir, syncEncryptionLocalFile))
+	if err != nil {
+		return e
+	}
+	var local syncEncryptionLocal
+	if err := json.Unmarshal(data, &local); err != nil {
+		return e
+	}
+	if aead, err := newSyncAEAD(local.Key); err == nil {
+		e.local = &local
+		e.aead = aead
+	}
+	return e
+}
+
+func newSyncAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// 鍵IDは鍵そのものを推測できないようハッシュの先頭だけを使う
+func syncKeyID(key []byte) string {
+	sum := sha256.Sum256(append([]byte("monaco-notepad key id\x00"), key...))
+	return hex.EncodeToString(sum[:8])
+}
+
+func sealSyncPayload(aead cipher.AEAD, keyID string, plain []byte, aad string) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return json.Marshal(encryptedPayload{
+		Format: syncEncryptionFormat,
+		KeyID:  keyID,
+		Nonce:  nonce,
+		Data:   aead.Seal(nil, nonce, plain, []byte(aad)),
+	})
+}
+
+// isEncryptedSyncPayload は暗号化したペイロードか判定する（sealSyncPayload が format を先頭に書く）
+func isEncryptedSyncPayload(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte(`{"format":"`+syncEncryptionFormat+`"`))
+}
+
+// パスフレーズから新しい鍵と鍵確認データを作る
+func newSyncKeyCheck(passphrase string) (syncKeyCheck, []byte, error) {
+	salt := make([]byte, syncEncryptionSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return syncKeyCheck{}, nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	check := syncKeyCheck{
+		Format:  syncKeyCheckFormat,
+		Enabled: true,
+		Salt:    salt,
+		N:       syncScryptN,
+		R:       syncScryptR,
+		P:       syncScryptP,
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, check.N, check.R, check.P, syncEncryptionKeyLength)
+	if err != nil {
+		return syncKeyCheck{}, nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	aead, err := newSyncAEAD(key)
+	if err != nil {
+		return syncKeyCheck{}, nil, err
+	}
+	check.KeyID = syncKeyID(key)
+	sealed, err := sealSyncPayload(aead, check.KeyID, []byte(syncKeyCheckPlaintext), syncKeyCheckAAD)
+	if err != nil {
+		return syncKeyCheck{}, nil, err
+	}
+	var payload encryptedPayload
+	if err := json.Unmarshal(sealed, &payload); err != nil {
+		return syncKeyCheck{}, nil, err
+	}
+	check.Check = &payload
+	return check, key, nil
+}
+
+// 鍵確認データでパスフレーズを検証し、鍵を返す
+func verifySyncKeyCheck(check syncKeyCheck, passphrase string) ([]byte, error) {
+	if check.Check == nil || len(check.Salt) == 0 {
+		return nil, fmt.Errorf("invalid key check data")
+	}
+	if err := checkSyncScryptParams(check.N, check.R, check.P); err != nil {
+		return nil, fmt.Errorf("invalid key check data: %w", err)
+	}
+	key, err := scrypt.Key([]byte(passphrase), check.Salt, check.N, check.R, check.P, syncEncryptionKeyLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	aead, err := newSyncAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, check.Check.Nonce, check.Check.Data, []byte(syncKeyCheckAAD))
+	if err != nil || string(plain) != syncKeyCheckPlaintext || syncKeyID(key) != check.KeyID {
+		return nil, errSyncIncorrectPassphrase
+	}
+	return key, nil
+}
+
+// checkSyncScryptParams は同期で受け取った scrypt のパラメーターが許容範囲にあるかを確かめる
+func checkSyncScryptParams(n, r, p int) error {
+	if n < syncScryptMinN || n > syncScryptMaxN || r < 1 || r > syncScryptMaxR || p < 1 || p > syncScryptMaxP {
+		return fmt.Errorf("unsupported scrypt parameters N=%d r=%d p=%d", n, r, p)
+	}
+	return nil
+}
+
+// seal はアップロードするデータを暗号化する。暗号化が無効なら平文のまま返す
+func (e *syncEncryption) seal(plain []byte, aad string) ([]byte, error) {
+	if e == nil {
+		return plain, nil
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.locked {
+		return nil, errSyncEncryptionLocked
+	}
+	if e.local == nil || !e.local.KeyCheck.Enabled {
+		return plain, nil
+	}
+	return sealSyncPayload(e.aead, e.local.KeyCheck.KeyID, plain, aad)
+}
+
+// open はダウンロードしたデータを復号する。暗号化されていなければそのまま返す
+func (e *syncEncryption) open(data []byte, aad string) ([]byte, error) {
+	if !isEncryptedSyncPayload(data) {
+		return data, nil
+	}
+	var payload encryptedPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted payload: %w", err)
+	}
+	if e == nil {
+		return nil, errSyncEncryptionLocked
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.local == nil || e.local.KeyCheck.KeyID != payload.KeyID {
+		e.locked = true
+		return nil, errSyncEncryptionLocked
+	}
+	plain, err := e.aead.Open(nil, payload.Nonce, payload.Data, []byte(aad))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt payload: %w", err)
+	}
+	return plain, nil
+}
+
+// setKey は鍵を保存して暗号化を有効にする
+func (e *syncEncryption) setKey(check syncKeyCheck, key []byte) error {
+	aead, err := newSyncAEAD(key)
+	if err != nil {
+		return err
+	}
+	local := &syncEncryptionLocal{KeyCheck: check, Key: key}
+	if err := saveSyncProviderConfig(e.appDataDir, syncEncryptionLocalFile, local); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.local = local
+	e.aead = aead
+	e.locked = false
+	return nil
+}
+
+// retireKey は暗号化を止める。既に暗号化されたデータを読めるよう鍵は残す
+func (e *syncEncryption) retireKey() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.local == nil || !e.local.KeyCheck.Enabled {
+		return nil
+	}
+	retired := *e.local
+	retired.KeyCheck.Enabled = false
+	if err := saveSyncProviderConfig(e.appDataDir, syncEncryptionLocalFile, &retired); err != nil {
+		return err
+	}
+	e.local = &retired
+	return nil
+}
+
+func (e *syncEncryption) setLocked(locked bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.locked = locked
+}
+
+func (e *syncEncryption) isLocked() bool {
+	if e == nil {
+		return false
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.locked
+}
+
+// enabledKeyCheck は有効な鍵の確認データを返す (暗号化していなければ nil)
+func (e *syncEncryption) enabledKeyCheck() *syncKeyCheck {
+	if e == nil {
+		return nil
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.local == nil || !e.local.KeyCheck.Enabled {
+		return nil
+	}
+	check := e.local.KeyCheck
+	return &check
+}
+
+func (e *syncEncryption) status() SyncEncryptionStatus {
+	if e == nil {
+		return SyncEncryptionStatus{}
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	status := SyncEncryptionStatus{Locked: e.locked}
+	if e.local != nil && e.local.KeyCheck.Enabled {
+		status.Enabled = true
+		status.KeyID = e.local.KeyCheck.KeyID
+	}
+	return status
+}
+
+// ------------------------------------------------------------
+// driveService との連携
+// ------------------------------------------------------------
+
+// クラウドの鍵確認データを取得する (無ければ nil)
+func (s *driveService) downloadSyncKeyCheck() (*syncKeyCheck, string, error) {
+	rootID, _ := s.auth.GetDriveSync().FolderIDs()
+	files, err := s.driveOps.ListFiles(
+		fmt.Sprintf("name='%s' and '%s' in parents and trashed=false", syncKeyCheckFileName, rootID))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to look up key check: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, "", nil
+	}
+	file := s.driveOps.FindLatestFile(files)
+	content, err := s.driveOps.DownloadFile(file.Id)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download key check: %w", err)
+	}
+	var check syncKeyCheck
+	if err := json.Unmarshal(content, &check); err != nil || check.Format != syncKeyCheckFormat {
+		return nil, "", fmt.Errorf("invalid key check data")
+	}
+	return &check, file.Id, nil
+}
+
+// lastMobileClientSeen はモバイル版が最後に接続した日時を返す (記録が無ければゼロ値)
+func (s *driveService) lastMobileClientSeen() (time.Time, error) {
+	rootID, _ := s.auth.GetDriveSync().FolderIDs()
+	files, err := s.driveOps.ListFiles(
+		fmt.Sprintf("name='%s' and '%s' in parents and trashed=false", syncMobileClientFileName, rootID))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to look up mobile clients: %w", err)
+	}
+	if len(files) == 0 {
+		return time.Time{}, nil
+	}
+	content, err := s.driveOps.DownloadFile(s.driveOps.FindLatestFile(files).Id)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to download mobile client info: %w", err)
+	}
+	var client struct {
+		LastSeen time.Time `json:"lastSeen"`
+	}
+	if err := json.Unmarshal(content, &client); err != nil {
+		return time.Time{}, fmt.Errorf("invalid mobile client info: %w", err)
+	}
+	return client.LastSeen, nil
+}
+
+// 鍵確認データをクラウドに保存する
+func (s *driveService) uploadSyncKeyCheck(check syncKeyCheck, fileID string) error {
+	content, err := json.MarshalIndent(check, "", "  ")
+	if err != nil {
+		return err
+	}
+	if fileID != "" {
+		return s.driveOps.UpdateFile(fileID, content)
+	}
+	rootID, _ := s.auth.GetDriveSync().FolderIDs()
+	_, err = s.driveOps.CreateFile(syncKeyCheckFileName, content, rootID, "application/json")
+	return err
+}
+
+// refreshEncryptionState はクラウドの鍵確認データとこの端末の鍵を突き合わせる。接続時に呼ぶ
+func (s *driveService) refreshEncryptionState() error {
+	if s.encryption == nil {
+		return nil
+	}
+	check, _, err := s.downloadSyncKeyCheck()
+	if err != nil {
+		return err
+	}
+	local := s.encryption.enabledKeyCheck()
+	switch {
+	case check == nil:
+		// クラウドのデータが削除された後など。この端末で暗号化しているなら確認データを置き直す
+		s.encryption.setLocked(false)
+		if local != nil {
+			return s.uploadSyncKeyCheck(*local, "")
+		}
+	case !check.Enabled:
+		// 別の端末で暗号化が無効にされた
+		s.encryption.setLocked(false)
+		if local != nil {
+			s.logger.Console("Sync encryption was disabled on another device")
+			return s.encryption.retireKey()
+		}
+	case local != nil && local.KeyID == check.KeyID:
+		s.encryption.setLocked(false)
+	default:
+		s.markEncryptionLocked()
+	}
+	return nil
+}
+
+// markEncryptionLocked は鍵が無いため同期できないことを通知する
+func (s *driveService) markEncryptionLocked() {
+	if s.encryption.isLocked() {
+		return
+	}
+	s.encryption.setLocked(true)
+	s.logger.InfoCode(MsgDriveEncryptionRequired, nil)
+	if !s.IsTestMode() {
+		wailsRuntime.EventsEmit(s.ctx, "drive:encryption-required")
+	}
+}
+
+// reuploadAllNotesLocked は全ノートを再アップロードする。syncMu を保持して呼ぶ
+func (s *driveService) reuploadAllNotesLocked() error {
+	var allIDs []string
+	s.noteService.WithLock(func() {
+		allIDs = make([]string, 0, len(s.noteService.noteList.Notes))
+		for _, meta := range s.noteService.noteList.Notes {
+			allIDs = append(allIDs, meta.ID)
+		}
+	})
+	s.syncState.MarkForFullReupload(allIDs)
+	s.logger.Console("Sync encryption: re-uploading %d notes", len(allIDs))
+	return s.pushLocalChanges()
+}
+
+// 同期データの暗号化を有効にする ------------------------------------------------------------
+// クラウドが既に暗号化されていればパスフレーズを検証して鍵を取り込み（2 台目以降の端末）、
+// そうでなければ新しい鍵を作って既存のデータをすべて暗号化し直す。
+func (s *driveService) EnableSyncEncryption(passphrase string) error {
+	if !s.IsConnected() || s.driveSync == nil {
+		return fmt.Errorf("not connected to sync provider")
+	}
+	check, fileID, err := s.downloadSyncKeyCheck()
+	if err != nil {
+		return err
+	}
+
+	if check != nil && check.Enabled {
+		key, err := verifySyncKeyCheck(*check, passphrase)
+		if err != nil {
+			return err
+		}
+		if err := s.encryption.setKey(*check, key); err != nil {
+			return err
+		}
+		s.logger.Console("Sync encryption unlocked (key %s)", check.KeyID)
+		return s.SyncNotesWithTrigger(SyncTriggerEncryption)
+	}
+
+	if len([]rune(passphrase)) < syncEncryptionMinPassLength {
+		return fmt.Errorf("passphrase must be at least %d characters", syncEncryptionMinPassLength)
+	}
+	lastSeen, err := s.lastMobileClientSeen()
+	if err != nil {
+		return err
+	}
+	if !lastSeen.IsZero() && time.Since(lastSeen) < syncMobileClientWindow {
+		return fmt.Errorf("%w (last synced %s); stop syncing the mobile app and try again after %s",
+			errSyncMobileClient, lastSeen.Local().Format("2006-01-02"), lastSeen.Add(syncMobileClientWindow).Local().Format("2006-01-02"))
+	}
+	// 先に最新のクラウドの状態を取り込んでから、全ノートを暗号化して上げ直す
+	if err := s.SyncNotesWithTrigger(SyncTriggerEncryption); err != nil {
+		return err
+	}
+	newCheck, key, err := newSyncKeyCheck(passphrase)
+	if err != nil {
+		return err
+	}
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	if err := s.uploadSyncKeyCheck(newCheck, fileID); err != nil {
+		return fmt.Errorf("failed to upload key check: %w", err)
+	}
+	if err := s.encryption.setKey(newCheck, key); err != nil {
+		return err
+	}
+	s.logger.Console("Sync encryption enabled (key %s)", newCheck.KeyID)
+	return s.reuploadAllNotesLocked()
+}
+
+// 同期データの暗号化を無効にする ------------------------------------------------------------
+// 全ノートを平文で再アップロードし、他の端末にも無効化を伝える。
+func (s *driveService) DisableSyncEncryption() error {
+	if !s.IsConnected() || s.driveSync == nil {
+		return fmt.Errorf("not connected to sync provider")
+	}
+	if s.encryption.enabledKeyCheck() == nil {
+		return nil
+	}
+	if err := s.SyncNotesWithTrigger(SyncTriggerEncryption); err != nil {
+		return err
+	}
+	_, fileID, err := s.downloadSyncKeyCheck()
+	if err != nil {
+		return err
+	}
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	if err := s.uploadSyncKeyCheck(syncKeyCheck{Format: syncKeyCheckFormat, Enabled: false}, fileID); err != nil {
+		return fmt.Errorf("failed to upload key check: %w", err)
+	}
+	if err := s.encryption.retireKey(); err != nil {
+		return err
+	}
+	s.logger.Console("Sync encryption disabled")
+	return s.reuploadAllNotesLocked()
+}
+
+// 同期データの暗号化の状態を返す ------------------------------------------------------------
+func (s *driveService) GetSyncEncryptionStatus() SyncEncryptionStatus {
+	return s.encryption.status()
+}
@@ -0,0 +1,167 @@
+package backend
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncEncryption_SealAndOpen(t *testing.T) {
+	dir := t.TempDir()
+	enc := newSyncEncryption(dir)
+
+	// 鍵が無ければ平文のまま通す
+	plain := []byte(`{"id":"n1","content":"secret"}`)
+	sealed, err := enc.seal(plain, syncNoteAAD("n1"))
+	require.NoError(t, err)
+	assert.Equal(t, plain, sealed)
+	opened, err := enc.open(plain, syncNoteAAD("n1"))
+	require.NoError(t, err)
+	assert.Equal(t, plain, opened)
+
+	check, key, err := newSyncKeyCheck("correct horse battery")
+	require.NoError(t, err)
+	require.NoError(t, enc.setKey(check, key))
+
+	sealed, err = enc.seal(plain, syncNoteAAD("n1"))
+	require.NoError(t, err)
+	assert.True(t, isEncryptedSyncPayload(sealed))
+	assert.NotContains(t, string(sealed), "secret")
+
+	opened, err = enc.open(sealed, syncNoteAAD("n1"))
+	require.NoError(t, err)
+	assert.Equal(t, plain, opened)
+
+	// 別のノートのファイルとして読ませると失敗する
+	_, err = enc.open(sealed, syncNoteAAD("n2"))
+	assert.Error(t, err)
+
+	// 保存した鍵は再起動後も使える
+	opened, err = newSyncEncryption(dir).open(sealed, syncNoteAAD("n1"))
+	require.NoError(t, err)
+	assert.Equal(t, plain, opened)
+
+	// 鍵を持たない端末はロックされ、平文でのアップロードも止まる
+	other := newSyncEncryption(t.TempDir())
+	_, err = other.open(sealed, syncNoteAAD("n1"))
+	assert.ErrorIs(t, err, errSyncEncryptionLocked)
+	assert.True(t, other.status().Locked)
+	_, err = other.seal(plain, syncNoteAAD("n1"))
+	assert.ErrorIs(t, err, errSyncEncryptionLocked)
+
+	// 無効化後は平文でアップロードするが、暗号化済みのデータは読める
+	require.NoError(t, enc.retireKey())
+	assert.False(t, enc.status().Enabled)
+	resealed, err := enc.seal(plain, syncNoteAAD("n1"))
+	require.NoError(t, err)
+	assert.Equal(t, plain, resealed)
+	opened, err = enc.open(sealed, syncNoteAAD("n1"))
+	require.NoError(t, err)
+	assert.Equal(t, plain, opened)
+}
+
+func TestVerifySyncKeyCheck(t *testing.T) {
+	check, key, err := newSyncKeyCheck("correct horse battery")
+	require.NoError(t, err)
+	assert.Equal(t, syncKeyID(key), check.KeyID)
+
+	verified, err := verifySyncKeyCheck(check, "correct horse battery")
+	require.NoError(t, err)
+	assert.Equal(t, key, verified)
+
+	_, err = verifySyncKeyCheck(check, "wrong passphrase")
+	assert.ErrorIs(t, err, errSyncIncorrectPassphrase)
+
+	// 範囲外の scrypt のパラメーターは鍵を導出する前に断る (N=1<<30 だと 1 回で 128 GB を確保する)
+	for _, params := range [][3]int{{1 << 30, 8, 1}, {1 << 10, 8, 1}, {syncScryptN, 1 << 20, 1}, {syncScryptN, 8, 1 << 10}, {syncScryptN, 0, 1}} {
+		hostile := check
+		hostile.N, hostile.R, hostile.P = params[0], params[1], params[2]
+		_, err = verifySyncKeyCheck(hostile, "correct horse battery")
+		assert.ErrorContains(t, err, "unsupported scrypt parameters", params)
+	}
+}
+
+// 暗号化したクラウドのデータは平文を含まず、2 台目の端末はパスフレーズを入力するまで同期しないこと
+func TestSyncEncryption_TwoDevices(t *testing.T) {
+	shared := t.TempDir()
+	connect := func(ds *driveService) error { return ds.ConnectLocalFolder(LocalFolderConfig{Path: shared}) }
+	deviceA := newProviderTestDriveService(t, connect)
+
+	note := &Note{ID: "e2e-note", Title: "diary", Content: "top secret content", Language: "plaintext"}
+	require.NoError(t, deviceA.noteService.SaveNote(note))
+	deviceA.syncState.MarkNoteDirty(note.ID)
+	require.NoError(t, deviceA.SyncNotes())
+
+	assert.Error(t, deviceA.EnableSyncEncryption("short"))
+	require.NoError(t, deviceA.EnableSyncEncryption("correct horse battery"))
+	assert.True(t, deviceA.GetSyncEncryptionStatus().Enabled)
+
+	cloudFiles := []string{
+		filepath.Join(shared, "monaco-notepad", "notes", note.ID+".json"),
+		filepath.Join(shared, "monaco-notepad", "noteList_v2.json"),
+	}
+	for _, path := range cloudFiles {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.True(t, isEncryptedSyncPayload(data), path)
+		assert.NotContains(t, string(data), "top secret content")
+		assert.NotContains(t, string(data), "diary")
+	}
+	assert.FileExists(t, filepath.Join(shared, "monaco-notepad", syncKeyCheckFileName))
+
+	deviceB := newProviderTestDriveService(t, connect)
+	assert.True(t, deviceB.GetSyncEncryptionStatus().Locked)
+	require.NoError(t, deviceB.SyncNotes())
+	_, err := deviceB.noteService.LoadNote(note.ID)
+	assert.Error(t, err)
+
+	assert.ErrorIs(t, deviceB.EnableSyncEncryption("wrong passphrase"), errSyncIncorrectPassphrase)
+	require.NoError(t, deviceB.EnableSyncEncryption("correct horse battery"))
+	status := deviceB.GetSyncEncryptionStatus()
+	assert.False(t, status.Locked)
+	assert.Equal(t, deviceA.GetSyncEncryptionStatus().KeyID, status.KeyID)
+	received, err := deviceB.noteService.LoadNote(note.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "top secret content", received.Content)
+
+	// A で無効化すると平文に戻り、B も暗号化をやめる
+	require.NoError(t, deviceA.DisableSyncEncryption())
+	data, err := os.ReadFile(cloudFiles[0])
+	require.NoError(t, err)
+	assert.False(t, isEncryptedSyncPayload(data))
+	assert.Contains(t, string(data), "top secret content")
+
+	require.NoError(t, deviceB.SyncNotes())
+	assert.False(t, deviceB.GetSyncEncryptionStatus().Enabled)
+}
+
+func TestEnableSyncEncryption_RefusedWhileMobileAppSyncs(t *testing.T) {
+	shared := t.TempDir()
+	connect := func(ds *driveService) error { return ds.ConnectLocalFolder(LocalFolderConfig{Path: shared}) }
+	ds := newProviderTestDriveService(t, connect)
+	markerPath := filepath.Join(shared, "monaco-notepad", syncMobileClientFileName)
+
+	writeMarker := func(lastSeen time.Time) {
+		data, err := json.Marshal(map[string]string{
+			"format":   "monaco-notepad-mobile-client-v1",
+			"lastSeen": lastSeen.UTC().Format(time.RFC3339),
+		})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(markerPath, data, 0644))
+	}
+
+	writeMarker(time.Now().Add(-time.Hour))
+	assert.ErrorIs(t, ds.EnableSyncEncryption("correct horse battery"), errSyncMobileClient)
+	assert.False(t, ds.GetSyncEncryptionStatus().Enabled)
+	assert.NoFileExists(t, filepath.Join(shared, "monaco-notepad", syncKeyCheckFileName))
+
+	// しばらく接続していないモバイル版は妨げない
+	writeMarker(time.Now().Add(-syncMobileClientWindow - time.Hour))
+	require.NoError(t, ds.EnableSyncEncryption("correct horse battery"))
+	assert.True(t, ds.GetSyncEncryptionStatus().Enabled)
+}
@@ -0,0 +1,226 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+// 同期履歴（同期 1 回ごとのノート単位の処理の記録）
+// ------------------------------------------------------------
+
+// 同期のきっかけ (SyncRun.Trigger)
+const (
+	SyncTriggerStartup    = "startup"    // 起動直後の同期
+	SyncTriggerPoll       = "poll"       // ポーリングでクラウドの変更を検出
+	SyncTriggerManual     = "manual"     // 「今すぐ同期」
+	SyncTriggerSave       = "save"       // ローカルでの保存・削除などの変更
+	SyncTriggerEncryption = "encryption" // 同期データの暗号化の設定変更
+)
+
+// 同期の種類 (SyncRun.Mode)
+const (
+	syncModeFirstPush = "firstPush"
+	syncModePush      = "push"
+	syncModePull      = "pull"
+	syncModeMerge     = "merge"
+)
+
+// 同期の結果 (SyncRun.Result)
+const (
+	syncResultOK       = "ok"
+	syncResultDeferred = "deferred"
+	syncResultError    = "error"
+)
+
+// ノートごとの処理 (SyncAction.Action)
+const (
+	syncActionUploaded         = "uploaded"
+	syncActionDownloaded       = "downloaded"
+	syncActionMerged           = "merged"
+	syncActionConflictBackedUp = "conflictBackedUp"
+	syncActionDeleted          = "deleted"
+	syncActionDeferred         = "deferred"
+	syncActionFailed           = "failed"
+)
+
+const (
+	syncJournalFileName = "sync_history.json"
+	// 保持する同期の回数。古いものから消す
+	maxSyncJournalRuns = 200
+	// 1 回の同期で記録する処理の上限 (全ノートの再アップロードで履歴が肥大しないように)
+	maxSyncJournalActions = 1000
+)
+
+// syncJournal は同期履歴を appDataDir/sync_history.json に保存する（Driveにはアップロードしない）
+type syncJournal struct {
+	mu       sync.Mutex
+	filePath string
+	device   string
+}
+
+func newSyncJournal(appDataDir string) *syncJournal {
+	device, err := os.Hostname()
+	if err != nil {
+		device = "unknown"
+	}
+	return &syncJournal{
+		filePath: filepath.Join(appDataDir, syncJournalFileName),
+		device:   device,
+	}
+}
+
+// begin は新しい同期の記録を始める (保存は finish で行う)
+func (j *syncJournal) begin(trigger string) *SyncRun {
+	return &SyncRun{
+		ID:        uuid.New().String(),
+		Device:    j.device,
+		Trigger:   trigger,
+		StartedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		Actions:   []SyncAction{},
+	}
+}
+
+// finish は同期の結果を確定して保存する。
+// 何も変化が無かった同期 (mode 未設定でエラーも無い) は記録しない。
+func (j *syncJournal) finish(run *SyncRun, err error) error {
+	if run.Mode == "" && err == nil && len(run.Actions) == 0 {
+		return nil
+	}
+	run.FinishedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	switch {
+	case err != nil:
+		run.Result = syncResultError
+		run.Error = err.Error()
+	case run.Result == "":
+		run.Result = syncResultOK
+	}
+	return j.append(*run)
+}
+
+func (j *syncJournal) append(run SyncRun) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	runs, err := j.loadLocked()
+	if err != nil {
+		// 壊れた履歴は捨てて記録を続ける (履歴のために同期を止めない)
+		runs = nil
+	}
+	runs = append(runs, run)
+	if len(runs) > maxSyncJournalRuns {
+		runs = runs[len(runs)-maxSyncJournalRuns:]
+	}
+
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal sync history: %w", err)
+	}
+	tmpPath := j.filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write sync history: %w", err)
+	}
+	if err := os.Rename(tmpPath, j.filePath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace sync history: %w", err)
+	}
+	return nil
+}
+
+// list は新しい順に最大 limit 件 (0 以下なら全件) の同期の記録を返す
+func (j *syncJournal) list(limit int) ([]SyncRun, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	runs, err := j.loadLocked()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]SyncRun, 0, len(runs))
+	for i := len(runs) - 1; i >= 0; i-- {
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		result = append(result, runs[i])
+	}
+	return result, nil
+}
+
+func (j *syncJournal) loadLocked() ([]SyncRun, error) {
+	data, err := os.ReadFile(j.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read sync history: %w", err)
+	}
+	var runs []SyncRun
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, fmt.Errorf("failed to parse sync history: %w", err)
+	}
+	return runs, nil
+}
+
+// recordSyncAction は進行中の同期の記録にノート 1 件分の処理を追加する (syncMu を握っている前提)。
+// title が空ならローカルのノートリストから引く。s.noteService のロック内からは呼ばないこと。
+func (s *driveService) recordSyncAction(action string, noteID string, title string, reason string) {
+	run := s.currentSyncRun
+	if run == nil {
+		return
+	}
+	if len(run.Actions) >= maxSyncJournalActions {
+		run.DroppedActions++
+		return
+	}
+	if title == "" {
+		s.noteService.WithLock(func() {
+			for _, metadata := range s.noteService.noteList.Notes {
+				if metadata.ID == noteID {
+					title = metadata.Title
+					break
+				}
+			}
+		})
+	}
+	run.Actions = append(run.Actions, SyncAction{
+		Time:   time.Now().UTC().Format(time.RFC3339Nano),
+		NoteID: noteID,
+		Title:  title,
+		Action: action,
+		Reason: reason,
+	})
+}
+
+// setSyncRunMode は進行中の同期の種類を記録する
+func (s *driveService) setSyncRunMode(mode string) {
+	if s.currentSyncRun != nil {
+		s.currentSyncRun.Mode = mode
+	}
+}
+
+// deferSyncRun は同期の一部を次回に回したことを記録する
+func (s *driveService) deferSyncRun(reason string) {
+	if s.currentSyncRun != nil {
+		s.currentSyncRun.Result = syncResultDeferred
+		s.currentSyncRun.Error = reason
+	}
+}
+
+// recordDeferredDownloads は取得済みだが適用を次回に回したノートを記録する
+func (s *driveService) recordDeferredDownloads(staged map[string]*Note) {
+	ids := make([]string, 0, len(staged))
+	for id := range staged {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		s.recordSyncAction(syncActionDeferred, id, staged[id].Title, "local changes arrived during sync")
+	}
+}
@@ -0,0 +1,55 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncJournal_FinishAndList(t *testing.T) {
+	journal := newSyncJournal(t.TempDir())
+
+	// 何もしなかった同期は記録しない
+	require.NoError(t, journal.finish(journal.begin(SyncTriggerPoll), nil))
+	runs, err := journal.list(0)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+
+	failed := journal.begin(SyncTriggerManual)
+	require.NoError(t, journal.finish(failed, errors.New("offline")))
+	pushed := journal.begin(SyncTriggerSave)
+	pushed.Mode = syncModePush
+	require.NoError(t, journal.finish(pushed, nil))
+
+	runs, err = journal.list(0)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, SyncTriggerSave, runs[0].Trigger)
+	assert.Equal(t, syncResultOK, runs[0].Result)
+	assert.Equal(t, syncResultError, runs[1].Result)
+	assert.Equal(t, "offline", runs[1].Error)
+
+	runs, err = journal.list(1)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, pushed.ID, runs[0].ID)
+}
+
+func TestSyncJournal_KeepsLatestRuns(t *testing.T) {
+	journal := newSyncJournal(t.TempDir())
+	for i := 0; i < maxSyncJournalRuns+5; i++ {
+		run := journal.begin(SyncTriggerPoll)
+		run.Mode = syncModePull
+		run.ID = fmt.Sprintf("run-%d", i)
+		require.NoError(t, journal.finish(run, nil))
+	}
+
+	runs, err := journal.list(0)
+	require.NoError(t, err)
+	require.Len(t, runs, maxSyncJournalRuns)
+	assert.Equal(t, fmt.Sprintf("run-%d", maxSyncJournalRuns+4), runs[0].ID)
+	assert.Equal(t, "run-5", runs[len(runs)-1].ID)
+}
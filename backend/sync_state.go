@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 )
 
@@ -18,10 +19,29 @@ type SyncState struct {
 	DeletedFolderIDs    map[string]bool   `json:"deletedFolderIDs"`
 	LastSyncedNoteHash  map[string]string `json:"lastSyncedNoteHash"`
 	FullReuploadPending bool              `json:"fullReuploadPending"`
+	// LastSyncedNoteTags は前回同期時点のノートごとのタグ。
+	// 両端末でタグを編集した場合の 3-way マージの base に使う。
+	LastSyncedNoteTags map[string][]string `json:"lastSyncedNoteTags"`
 
 	mu       sync.Mutex `json:"-"`
 	filePath string     `json:"-"`
 	revision uint64     `json:"-"`
+	// baseDir は前回同期時点のノート本文 (本文の 3-way マージの base) の保存先。
+	// sync_state.json は編集のたびに書き直すので、本文はノートごとの別ファイルに置く。
+	baseDir string `json:"-"`
+}
+
+// 前回同期時点のノート本文を置くディレクトリ (appDataDir 直下)
+const syncBaseDirName = "sync_base"
+
+// syncedNoteBase は前回同期時点のノートの内容。
+// Hash が LastSyncedNoteHash と一致するときだけ base として使う。
+type syncedNoteBase struct {
+	Hash     string `json:"hash"`
+	Title    string `json:"title"`
+	Language string `json:"language"`
+	Archived bool   `json:"archived"`
+	Content  string `json:"content"`
 }
 
 func NewSyncState(appDataDir string) *SyncState {
@@ -34,6 +54,7 @@ func NewSyncState(appDataDir string) *SyncState {
 		LastSyncedNoteHash:  make(map[string]string),
 		FullReuploadPending: false,
 		filePath:            filepath.Join(appDataDir, "sync_state.json"),
+		baseDir:             filepath.Join(appDataDir, syncBaseDirName),
 	}
 }
 
@@ -63,6 +84,7 @@ func (s *SyncState) Load() error {
 	s.DeletedFolderIDs = loaded.DeletedFolderIDs
 	s.LastSyncedNoteHash = loaded.LastSyncedNoteHash
 	s.FullReuploadPending = loaded.FullReuploadPending
+	s.LastSyncedNoteTags = loaded.LastSyncedNoteTags
 	s.ensureMapsLocked()
 
 	return nil
@@ -86,6 +108,20 @@ func (s *SyncState) MarkNoteDirty(noteID string) {
 	_ = s.saveLocked()
 }
 
+// MarkNotesDirty は複数のノートをまとめて dirty にする (状態ファイルの書き込みは 1 回)
+func (s *SyncState) MarkNotesDirty(noteIDs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revision++
+	s.Dirty = true
+	s.ensureMapsLocked()
+	for _, noteID := range noteIDs {
+		s.DirtyNoteIDs[noteID] = true
+	}
+	_ = s.saveLocked()
+}
+
 func (s *SyncState) MarkNoteDeleted(noteID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -152,6 +188,7 @@ func (s *SyncState) clearDirtyLocked(driveTs string, noteHashes map[string]strin
 	for k, v := range noteHashes {
 		s.LastSyncedNoteHash[k] = v
 	}
+	s.pruneNoteBasesLocked()
 }
 
 // UpdateSyncedNoteHash は 1 ノートの「Drive 側に上がった状態」を即時に永続化する。
@@ -180,9 +217,128 @@ func (s *SyncState) UpdateSyncedState(driveTs string, noteHashes map[string]stri
 	for k, v := range noteHashes {
 		s.LastSyncedNoteHash[k] = v
 	}
+	s.pruneNoteBasesLocked()
 	_ = s.saveLocked()
 }
 
+// UpdateSyncedNoteTags は同期完了時点のノートごとのタグを記録する。
+// UpdateSyncedNoteHash と同じく同期側の内部記録なので revision はインクリメントしない。
+func (s *SyncState) UpdateSyncedNoteTags(noteTags map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.LastSyncedNoteTags = make(map[string][]string, len(noteTags))
+	for id, tags := range noteTags {
+		if len(tags) > 0 {
+			s.LastSyncedNoteTags[id] = append([]string(nil), tags...)
+		}
+	}
+	_ = s.saveLocked()
+}
+
+// GetSyncedNoteTags は前回同期時点のタグのコピーを返す。
+// 前回同期時点のタグが分からない (一度もタグ付きで同期していない) 場合は hasBase=false
+func (s *SyncState) GetSyncedNoteTags() (noteTags map[string][]string, hasBase bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.LastSyncedNoteTags == nil {
+		return map[string][]string{}, false
+	}
+	noteTags = make(map[string][]string, len(s.LastSyncedNoteTags))
+	for id, tags := range s.LastSyncedNoteTags {
+		noteTags[id] = append([]string(nil), tags...)
+	}
+	return noteTags, true
+}
+
+// UpdateSyncedNoteBase は端末とクラウドで内容が一致したノートを、次回の本文の 3-way マージの
+// base として記録する。暗号化ノートは本文が暗号文でマージできないので記録を消す。
+// UpdateSyncedNoteHash と同じく同期側の内部記録なので revision はインクリメントしない。
+func (s *SyncState) UpdateSyncedNoteBase(note *Note) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.noteBasePathLocked(note.ID)
+	if err != nil {
+		return
+	}
+	if note.Encrypted {
+		_ = os.Remove(path)
+		return
+	}
+	data, err := json.Marshal(syncedNoteBase{
+		Hash:     computeContentHash(note),
+		Title:    note.Title,
+		Language: note.Language,
+		Archived: note.Archived,
+		Content:  note.Content,
+	})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(s.baseDir, 0755); err != nil {
+		return
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+	}
+}
+
+// GetSyncedNoteBase は前回同期時点のノートの内容を返す。
+// 記録が無い、または LastSyncedNoteHash と食い違う (同期の途中で終わった) 場合は ok=false
+func (s *SyncState) GetSyncedNoteBase(noteID string) (base *syncedNoteBase, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lastHash, synced := s.LastSyncedNoteHash[noteID]
+	if !synced {
+		return nil, false
+	}
+	path, err := s.noteBasePathLocked(noteID)
+	if err != nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var loaded syncedNoteBase
+	if err := json.Unmarshal(data, &loaded); err != nil || loaded.Hash != lastHash {
+		return nil, false
+	}
+	return &loaded, true
+}
+
+func (s *SyncState) noteBasePathLocked(noteID string) (string, error) {
+	if s.baseDir == "" {
+		return "", fmt.Errorf("sync base directory is not set")
+	}
+	if err := validateRevisionPathElement("note id", noteID); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.baseDir, noteID+".json"), nil
+}
+
+// pruneNoteBasesLocked は同期済みでなくなったノート (削除など) の base を消す
+func (s *SyncState) pruneNoteBasesLocked() {
+	entries, err := os.ReadDir(s.baseDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		noteID := strings.TrimSuffix(entry.Name(), ".json")
+		if _, ok := s.LastSyncedNoteHash[noteID]; ok && noteID != entry.Name() {
+			continue
+		}
+		_ = os.Remove(filepath.Join(s.baseDir, entry.Name()))
+	}
+}
+
 func (s *SyncState) IsDirty() bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -244,6 +400,7 @@ func (s *SyncState) resetLocked(dirty bool) {
 	s.DeletedFolderIDs = make(map[string]bool)
 	s.LastSyncedNoteHash = make(map[string]string)
 	s.FullReuploadPending = false
+	s.LastSyncedNoteTags = nil
 }
 
 // MarkForFullReupload は Drive 上のデータ削除などで、全ノートを再アップロードする必要が
@@ -259,6 +416,8 @@ func (s *SyncState) MarkForFullReupload(noteIDs []string) {
 	s.Dirty = true
 	s.LastSyncedDriveTs = ""
 	s.LastSyncedNoteHash = make(map[string]string)
+	s.LastSyncedNoteTags = nil
+	_ = os.RemoveAll(s.baseDir)
 	s.DeletedNoteIDs = make(map[string]bool)
 	s.DeletedFolderIDs = make(map[string]bool)
 	s.DirtyNoteIDs = make(map[string]bool, len(noteIDs))
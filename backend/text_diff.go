@@ -0,0 +1,244 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ------------------------------------------------------------
+// 行単位のテキスト差分
+// ------------------------------------------------------------
+
+// 差分の編集種別
+type lineEditKind int
+
+const (
+	lineEditEqual lineEditKind = iota
+	lineEditDelete
+	lineEditInsert
+)
+
+// 1 行分の編集。aIndex / bIndex は編集前後それぞれのテキストでの行位置。
+// 削除では bIndex、挿入では aIndex が「その時点の相手側の位置」を表す。
+type lineEdit struct {
+	kind   lineEditKind
+	aIndex int
+	bIndex int
+}
+
+// 編集距離がこれを超える場合は全行置換として扱う (Myers の trace がメモリを食い過ぎるため)
+const maxLineDiffEditDistance = 4000
+
+// splitLinesKeepEnds は改行を含めたまま行に分割する。
+// 末尾改行の有無も差分として扱えるよう、最終行だけは改行を持たないことがある。
+func splitLinesKeepEnds(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines は a → b の行単位の編集スクリプトを返す (Myers の O(ND) アルゴリズム)
+func diffLines(a, b []string) []lineEdit {
+	// 共通の先頭・末尾を除いてから本体を計算する
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]lineEdit, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, lineEdit{kind: lineEditEqual, aIndex: i, bIndex: i})
+	}
+	middle := myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	for _, e := range middle {
+		e.aIndex += prefix
+		e.bIndex += prefix
+		edits = append(edits, e)
+	}
+	for i := 0; i < suffix; i++ {
+		edits = append(edits, lineEdit{
+			kind:   lineEditEqual,
+			aIndex: len(a) - suffix + i,
+			bIndex: len(b) - suffix + i,
+		})
+	}
+	return edits
+}
+
+func myersDiff(a, b []string) []lineEdit {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replaceAllLines(n, m)
+	}
+
+	limit := n + m
+	if limit > maxLineDiffEditDistance {
+		limit = maxLineDiffEditDistance
+	}
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	// snapshots[d] は d 手目終了時点の v[-d..d]
+	var snapshots [][]int
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				snapshots = append(snapshots, append([]int(nil), v[offset-d:offset+d+1]...))
+				return backtrackMyers(snapshots, n, m)
+			}
+		}
+		snapshots = append(snapshots, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+	return replaceAllLines(n, m)
+}
+
+func backtrackMyers(snapshots [][]int, n, m int) []lineEdit {
+	var reversed []lineEdit
+	x, y := n, m
+	for d := len(snapshots) - 1; d > 0; d-- {
+		prev := snapshots[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, lineEdit{kind: lineEditEqual, aIndex: x, bIndex: y})
+		}
+		if x == prevX {
+			y--
+			reversed = append(reversed, lineEdit{kind: lineEditInsert, aIndex: x, bIndex: y})
+		} else {
+			x--
+			reversed = append(reversed, lineEdit{kind: lineEditDelete, aIndex: x, bIndex: y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		reversed = append(reversed, lineEdit{kind: lineEditEqual, aIndex: x, bIndex: y})
+	}
+
+	edits := make([]lineEdit, len(reversed))
+	for i, e := range reversed {
+		edits[len(reversed)-1-i] = e
+	}
+	return edits
+}
+
+func replaceAllLines(n, m int) []lineEdit {
+	edits := make([]lineEdit, 0, n+m)
+	for i := 0; i < n; i++ {
+		edits = append(edits, lineEdit{kind: lineEditDelete, aIndex: i, bIndex: 0})
+	}
+	for i := 0; i < m; i++ {
+		edits = append(edits, lineEdit{kind: lineEditInsert, aIndex: n, bIndex: i})
+	}
+	return edits
+}
+
+// unifiedDiff は a → b の unified diff 形式の文字列を返す。差分が無ければ空文字
+func unifiedDiff(fromName, toName, a, b string, contextLines int) string {
+	aLines := splitLinesKeepEnds(a)
+	bLines := splitLinesKeepEnds(b)
+	edits := diffLines(aLines, bLines)
+
+	var changes []int
+	for i, e := range edits {
+		if e.kind != lineEditEqual {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for gi := 0; gi < len(changes); {
+		// 間の共通行が context の 2 倍以下なら同じ hunk にまとめる
+		last := changes[gi]
+		gj := gi + 1
+		for gj < len(changes) && changes[gj]-last <= 2*contextLines+1 {
+			last = changes[gj]
+			gj++
+		}
+		start := max(changes[gi]-contextLines, 0)
+		end := min(last+contextLines+1, len(edits))
+		writeUnifiedHunk(&sb, edits[start:end], aLines, bLines)
+		gi = gj
+	}
+	return sb.String()
+}
+
+func writeUnifiedHunk(sb *strings.Builder, hunk []lineEdit, aLines, bLines []string) {
+	aCount, bCount := 0, 0
+	for _, e := range hunk {
+		if e.kind != lineEditInsert {
+			aCount++
+		}
+		if e.kind != lineEditDelete {
+			bCount++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n",
+		unifiedRange(hunk[0].aIndex, aCount), unifiedRange(hunk[0].bIndex, bCount))
+	for _, e := range hunk {
+		switch e.kind {
+		case lineEditEqual:
+			writeUnifiedLine(sb, ' ', aLines[e.aIndex])
+		case lineEditDelete:
+			writeUnifiedLine(sb, '-', aLines[e.aIndex])
+		case lineEditInsert:
+			writeUnifiedLine(sb, '+', bLines[e.bIndex])
+		}
+	}
+}
+
+// GNU diff と同じく、1 行なら行数を省略し、0 行なら直前の行番号を出す
+func unifiedRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+func writeUnifiedLine(sb *strings.Builder, prefix byte, line string) {
+	sb.WriteByte(prefix)
+	sb.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		sb.WriteString("\n\\ No newline at end of file\n")
+	}
+}
@@ -0,0 +1,68 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 編集スクリプトを適用すると b が復元できること
+func TestDiffLines_ReconstructsTarget(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b string
+	}{
+		{"identical", "a\nb\nc\n", "a\nb\nc\n"},
+		{"insert", "a\nc\n", "a\nb\nc\n"},
+		{"delete", "a\nb\nc\n", "a\nc\n"},
+		{"replace", "a\nb\nc\n", "a\nx\nc\n"},
+		{"from empty", "", "a\nb\n"},
+		{"to empty", "a\nb\n", ""},
+		{"interleaved", "a\nb\nc\nd\ne\nf\n", "b\nx\nd\ny\nf\nz\n"},
+		{"trailing newline", "a\nb", "a\nb\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			aLines := splitLinesKeepEnds(tc.a)
+			bLines := splitLinesKeepEnds(tc.b)
+			var rebuilt strings.Builder
+			for _, e := range diffLines(aLines, bLines) {
+				switch e.kind {
+				case lineEditEqual:
+					assert.Equal(t, aLines[e.aIndex], bLines[e.bIndex])
+					rebuilt.WriteString(aLines[e.aIndex])
+				case lineEditInsert:
+					rebuilt.WriteString(bLines[e.bIndex])
+				}
+			}
+			assert.Equal(t, tc.b, rebuilt.String())
+		})
+	}
+}
+
+// 最小の編集になること（共通部分を削除・挿入し直さない）
+func TestDiffLines_MinimalEdits(t *testing.T) {
+	a := splitLinesKeepEnds("a\nb\nc\na\nb\nb\na\n")
+	b := splitLinesKeepEnds("c\nb\na\nb\na\nc\n")
+	changes := 0
+	for _, e := range diffLines(a, b) {
+		if e.kind != lineEditEqual {
+			changes++
+		}
+	}
+	assert.Equal(t, 5, changes)
+}
+
+func TestUnifiedDiff_Format(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\n3\nfour\n5\n6\n7\n8\n9\n10\n11\n12\neleven"
+
+	expected := "--- old\n+++ new\n" +
+		"@@ -1,7 +1,7 @@\n 1\n 2\n 3\n-4\n+four\n 5\n 6\n 7\n" +
+		"@@ -10,3 +10,4 @@\n 10\n 11\n 12\n+eleven\n\\ No newline at end of file\n"
+	assert.Equal(t, expected, unifiedDiff("old", "new", a, b, 3))
+
+	assert.Equal(t, "", unifiedDiff("old", "new", a, a, 3))
+	assert.Equal(t, "--- old\n+++ new\n@@ -0,0 +1 @@\n+x\n", unifiedDiff("old", "new", "", "x\n", 3))
+}
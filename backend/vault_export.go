@@ -0,0 +1,438 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ------------------------------------------------------------
+// Markdown 保管庫形式での書き出し
+// ------------------------------------------------------------
+//
+// 全ノートを 1 ノート 1 ファイルで書き出す (バックアップ・他アプリへの移行用)。
+// - 拡張子は Note.Language から決める (markdown → .md, go → .go, 不明なものは .txt)。
+// - フォルダはディレクトリ、アーカイブ済みのものは _archive 以下に同じ構造で書き出す。
+// - 表示順は _index.json に上から順に記録する。OrderPrefix を指定するとファイル名の連番でも表す。
+// - 暗号化したノートは平文を書き出さず、暗号文 (lockedNoteContent の JSON) を .locked.json に書く。
+// - ファイル名は Windows・macOS・Linux のどれでも作れる名前に置き換え、大文字小文字と
+//   Unicode 正規化の違いだけの重複も連番で避ける。
+
+const (
+	vaultExportFormat       = "monaco-notepad-vault-v1"
+	vaultExportIndexName    = "_index.json"
+	vaultExportArchiveDir   = "_archive"
+	vaultExportMaxNameRunes = 100
+	vaultExportUntitled     = "Untitled"
+)
+
+// Monaco の言語IDと拡張子の対応 (Monaco の各言語の先頭の拡張子に合わせる)
+var vaultExportExtensions = map[string]string{
+	"bat":          ".bat",
+	"c":            ".c",
+	"clojure":      ".clj",
+	"coffeescript": ".coffee",
+	"cpp":          ".cpp",
+	"csharp":       ".cs",
+	"css":          ".css",
+	"dart":         ".dart",
+	"dockerfile":   ".dockerfile",
+	"elixir":       ".ex",
+	"fsharp":       ".fs",
+	"go":           ".go",
+	"graphql":      ".graphql",
+	"handlebars":   ".handlebars",
+	"hcl":          ".tf",
+	"html":         ".html",
+	"ini":          ".ini",
+	"java":         ".java",
+	"javascript":   ".js",
+	"json":         ".json",
+	"julia":        ".jl",
+	"kotlin":       ".kt",
+	"less":         ".less",
+	"lua":          ".lua",
+	"markdown":     ".md",
+	"mysql":        ".sql",
+	"objective-c":  ".m",
+	"perl":         ".pl",
+	"pgsql":        ".sql",
+	"php":          ".php",
+	"plaintext":    ".txt",
+	"powershell":   ".ps1",
+	"proto":        ".proto",
+	"python":       ".py",
+	"r":            ".r",
+	"razor":        ".cshtml",
+	"ruby":         ".rb",
+	"rust":         ".rs",
+	"scala":        ".scala",
+	"scss":         ".scss",
+	"shell":        ".sh",
+	"sql":          ".sql",
+	"swift":        ".swift",
+	"typescript":   ".ts",
+	"vb":           ".vb",
+	"xml":          ".xml",
+	"yaml":         ".yaml",
+}
+
+// Windows で使えないファイル名 (拡張子が付いていても不可)
+var vaultExportReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// _index.json の 1 件
+type vaultIndexEntry struct {
+	Path         string   `json:"path"` // 書き出し先からの相対パス ("/" 区切り)
+	Type         string   `json:"type"` // "note" or "folder"
+	ID           string   `json:"id"`
+	Title        string   `json:"title"` // ノートのタイトルまたはフォルダ名 (置き換える前の名前)
+	Language     string   `json:"language,omitempty"`
+	ModifiedTime string   `json:"modifiedTime,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Archived     bool     `json:"archived,omitempty"`
+	Encrypted    bool     `json:"encrypted,omitempty"`
+}
+
+// _index.json
+type vaultIndex struct {
+	Format     string            `json:"format"`
+	ExportedAt string            `json:"exportedAt"`
+	Items      []vaultIndexEntry `json:"items"` // アプリでの表示順 (ディレクトリごとに上から順)
+}
+
+// vaultExtension はノートの言語から拡張子を返す
+func vaultExtension(language string) string {
+	if ext, ok := vaultExportExtensions[strings.ToLower(language)]; ok {
+		return ext
+	}
+	return ".txt"
+}
+
+// sanitizeVaultName はタイトルをどの OS でも作れるファイル名に置き換える (空なら空文字を返す)
+func sanitizeVaultName(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFC.String(name) {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			b.WriteRune(' ')
+		case strings.ContainsRune(`<>:"/\|?*`, r):
+			b.WriteRune('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	result := strings.Join(strings.Fields(b.String()), " ")
+	if runes := []rune(result); len(runes) > vaultExportMaxNameRunes {
+		result = string(runes[:vaultExportMaxNameRunes])
+	}
+	// Windows は末尾のドットと空白を落とすため、別のファイルと衝突しないよう先に落とす
+	result = strings.TrimRight(result, ". ")
+	if result == "" {
+		return ""
+	}
+	stem, _, _ := strings.Cut(result, ".")
+	if vaultExportReservedNames[strings.ToUpper(strings.TrimSpace(stem))] {
+		result = "_" + result
+	}
+	return result
+}
+
+// vaultNoteName はノートのファイル名の元になる名前を返す (タイトルが空なら本文の先頭行)
+func vaultNoteName(metadata NoteMetadata) string {
+	if name := sanitizeVaultName(metadata.Title); name != "" {
+		return name
+	}
+	if !metadata.Encrypted {
+		firstLine, _, _ := strings.Cut(metadata.ContentHeader, "\n")
+		if name := sanitizeVaultName(firstLine); name != "" {
+			return name
+		}
+	}
+	return vaultExportUntitled
+}
+
+// vaultFrontMatter は Markdown の先頭に付ける YAML front matter を作る。
+// 文字列は JSON 形式 (YAML のダブルクォート文字列として読める) で書く。
+func vaultFrontMatter(note *Note, metadata NoteMetadata) string {
+	quote := func(value string) string {
+		data, _ := json.Marshal(value)
+		return string(data)
+	}
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "id: %s\n", quote(note.ID))
+	fmt.Fprintf(&b, "title: %s\n", quote(note.Title))
+	fmt.Fprintf(&b, "modifiedTime: %s\n", quote(metadata.ModifiedTime))
+	fmt.Fprintf(&b, "language: %s\n", quote(note.Language))
+	if len(metadata.Tags) > 0 {
+		b.WriteString("tags:\n")
+		for _, tag := range metadata.Tags {
+			fmt.Fprintf(&b, "  - %s\n", quote(tag))
+		}
+	}
+	b.WriteString("---\n\n")
+	return b.String()
+}
+
+// 書き出し先の 1 ディレクトリ
+type vaultDir struct {
+	rel   string          // 書き出し先からの相対パス ("/" 区切り、ルートは "")
+	used  map[string]bool // 使用済みの名前 (大文字小文字・正規化の違いを無視して比較する)
+	count int             // 連番の桁数を決めるための項目数
+	next  int             // 次の連番
+}
+
+func newVaultDir(rel string, count int, reserved ...string) *vaultDir {
+	dir := &vaultDir{rel: rel, used: make(map[string]bool), count: count}
+	for _, name := range reserved {
+		dir.used[vaultNameKey(name)] = true
+	}
+	return dir
+}
+
+func vaultNameKey(name string) string {
+	return strings.ToLower(norm.NFC.String(name))
+}
+
+// allocate は base + ext の名前をディレクトリ内で一意にして返す
+func (d *vaultDir) allocate(base string, ext string, prefix bool) string {
+	d.next++
+	if prefix {
+		width := max(len(fmt.Sprint(d.count)), 2)
+		base = fmt.Sprintf("%0*d %s", width, d.next, base)
+	}
+	name := base + ext
+	for i := 2; d.used[vaultNameKey(name)]; i++ {
+		name = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	d.used[vaultNameKey(name)] = true
+	return name
+}
+
+// 書き出し処理の状態
+type vaultExporter struct {
+	s         *noteService
+	root      string
+	options   VaultExportOptions
+	notes     []NoteMetadata
+	folders   []Folder
+	folderMap map[string]Folder
+	index     []vaultIndexEntry
+	result    VaultExportResult
+}
+
+// 全ノートを destDir に Markdown 保管庫形式で書き出す ------------------------------------------------------------
+// destDir は絶対パスで、存在しないか空のディレクトリであること (既存のファイルを上書きしない)。
+func (s *noteService) ExportVault(destDir string, options VaultExportOptions) (*VaultExportResult, error) {
+	if !filepath.IsAbs(destDir) {
+		return nil, fmt.Errorf("export directory must be an absolute path: %s", destDir)
+	}
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	entries, err := os.ReadDir(destDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export directory: %w", err)
+	}
+	if len(entries) > 0 {
+		return nil, fmt.Errorf("export directory is not empty: %s", destDir)
+	}
+
+	// 並び順とメタデータはロック中に写し取り、ファイルの書き込みはロックの外で行う
+	var activeOrder, archivedOrder []TopLevelItem
+	e := &vaultExporter{s: s, root: destDir, options: options}
+	s.mu.Lock()
+	e.notes = append([]NoteMetadata(nil), s.noteList.Notes...)
+	e.folders = append([]Folder(nil), s.noteList.Folders...)
+	activeOrder = append([]TopLevelItem(nil), s.noteList.TopLevelOrder...)
+	if s.noteList.TopLevelOrder == nil {
+		activeOrder = s.buildTopLevelOrder()
+	}
+	archivedOrder = append([]TopLevelItem(nil), s.noteList.ArchivedTopLevelOrder...)
+	if s.noteList.ArchivedTopLevelOrder == nil {
+		archivedOrder = s.buildArchivedTopLevelOrder()
+	}
+	s.mu.Unlock()
+	e.folderMap = make(map[string]Folder, len(e.folders))
+	for _, folder := range e.folders {
+		e.folderMap[folder.ID] = folder
+	}
+
+	activeRoot := e.rootItems(activeOrder, false)
+	if err := e.exportItems(newVaultDir("", len(activeRoot), vaultExportIndexName, vaultExportArchiveDir), activeRoot, false); err != nil {
+		return nil, err
+	}
+	if options.IncludeArchived {
+		archivedRoot := e.rootItems(archivedOrder, true)
+		if len(archivedRoot) > 0 {
+			if err := os.Mkdir(filepath.Join(destDir, vaultExportArchiveDir), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create archive directory: %w", err)
+			}
+			if err := e.exportItems(newVaultDir(vaultExportArchiveDir, len(archivedRoot)), archivedRoot, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(vaultIndex{
+		Format:     vaultExportFormat,
+		ExportedAt: time.Now().Format(time.RFC3339),
+		Items:      e.index,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(destDir, vaultExportIndexName), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write export index: %w", err)
+	}
+	return &e.result, nil
+}
+
+// rootItems は並び順に、並び順から漏れているルートのノートとフォルダを足して返す
+func (e *vaultExporter) rootItems(order []TopLevelItem, archived bool) []TopLevelItem {
+	var items []TopLevelItem
+	seen := make(map[TopLevelItem]bool)
+	add := func(item TopLevelItem) {
+		if !seen[item] {
+			seen[item] = true
+			items = append(items, item)
+		}
+	}
+	isRootNote := func(id string) bool {
+		for _, note := range e.notes {
+			if note.ID == id {
+				return note.FolderID == "" && note.Archived == archived
+			}
+		}
+		return false
+	}
+	for _, item := range order {
+		switch item.Type {
+		case "note":
+			if isRootNote(item.ID) {
+				add(item)
+			}
+		case "folder":
+			if folder, ok := e.folderMap[item.ID]; ok && isFolderOrderRoot(folder, e.folderMap, archived) {
+				add(item)
+			}
+		}
+	}
+	for _, folder := range e.folders {
+		if isFolderOrderRoot(folder, e.folderMap, archived) {
+			add(TopLevelItem{Type: "folder", ID: folder.ID})
+		}
+	}
+	for _, note := range e.notes {
+		if note.FolderID == "" && note.Archived == archived {
+			add(TopLevelItem{Type: "note", ID: note.ID})
+		}
+	}
+	return items
+}
+
+// folderItems はフォルダ直下のフォルダ (Folders の順) とノート (Notes の順) を返す
+func (e *vaultExporter) folderItems(folderID string, archived bool) []TopLevelItem {
+	var items []TopLevelItem
+	for _, folder := range e.folders {
+		if folder.ParentID == folderID && folder.Archived == archived {
+			items = append(items, TopLevelItem{Type: "folder", ID: folder.ID})
+		}
+	}
+	for _, note := range e.notes {
+		if note.FolderID == folderID {
+			items = append(items, TopLevelItem{Type: "note", ID: note.ID})
+		}
+	}
+	return items
+}
+
+func (e *vaultExporter) exportItems(dir *vaultDir, items []TopLevelItem, archived bool) error {
+	for _, item := range items {
+		switch item.Type {
+		case "folder":
+			if err := e.exportFolder(dir, e.folderMap[item.ID], archived); err != nil {
+				return err
+			}
+		case "note":
+			for _, metadata := range e.notes {
+				if metadata.ID == item.ID {
+					if err := e.exportNote(dir, metadata); err != nil {
+						return err
+					}
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (e *vaultExporter) exportFolder(parent *vaultDir, folder Folder, archived bool) error {
+	base := sanitizeVaultName(folder.Name)
+	if base == "" {
+		base = vaultExportUntitled
+	}
+	name := parent.allocate(base, "", e.options.OrderPrefix)
+	rel := path.Join(parent.rel, name)
+	if err := os.Mkdir(filepath.Join(e.root, filepath.FromSlash(rel)), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for folder %s: %w", folder.ID, err)
+	}
+	e.result.FolderCount++
+	e.index = append(e.index, vaultIndexEntry{
+		Path:     rel,
+		Type:     "folder",
+		ID:       folder.ID,
+		Title:    folder.Name,
+		Archived: folder.Archived,
+	})
+
+	items := e.folderItems(folder.ID, archived)
+	return e.exportItems(newVaultDir(rel, len(items)), items, archived)
+}
+
+func (e *vaultExporter) exportNote(dir *vaultDir, metadata NoteMetadata) error {
+	e.s.mu.Lock()
+	note, err := e.s.readNoteForIndexLocked(metadata.ID)
+	e.s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to load note %s: %w", metadata.ID, err)
+	}
+
+	ext := vaultExtension(note.Language)
+	content := note.Content
+	if metadata.Encrypted {
+		ext = ".locked.json"
+		e.result.EncryptedCount++
+	} else if e.options.FrontMatter && ext == ".md" {
+		content = vaultFrontMatter(note, metadata) + content
+	}
+	name := dir.allocate(vaultNoteName(metadata), ext, e.options.OrderPrefix)
+	rel := path.Join(dir.rel, name)
+	if err := os.WriteFile(filepath.Join(e.root, filepath.FromSlash(rel)), []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write note %s: %w", metadata.ID, err)
+	}
+	e.result.NoteCount++
+	e.index = append(e.index, vaultIndexEntry{
+		Path:         rel,
+		Type:         "note",
+		ID:           metadata.ID,
+		Title:        metadata.Title,
+		Language:     note.Language,
+		ModifiedTime: metadata.ModifiedTime,
+		Tags:         metadata.Tags,
+		Archived:     metadata.Archived,
+		Encrypted:    metadata.Encrypted,
+	})
+	return nil
+}
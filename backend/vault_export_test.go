@@ -0,0 +1,139 @@
+package backend
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeVaultName(t *testing.T) {
+	cases := map[string]string{
+		"plain title":        "plain title",
+		`a/b\c:d*e?f"g<h>i|`: "a_b_c_d_e_f_g_h_i_",
+		"line1\nline2\t end": "line1 line2 end",
+		"trailing dots...":   "trailing dots",
+		"...":                "",
+		"   ":                "",
+		"CON":                "_CON",
+		"con.backup":         "_con.backup",
+		"lpt9":               "_lpt9",
+		"console":            "console",
+		"été":               "été",
+	}
+	for input, expected := range cases {
+		assert.Equal(t, expected, sanitizeVaultName(input), input)
+	}
+	assert.Len(t, []rune(sanitizeVaultName(strings.Repeat("長", 300))), vaultExportMaxNameRunes)
+	assert.Equal(t, ".md", vaultExtension("markdown"))
+	assert.Equal(t, ".sql", vaultExtension("pgsql"))
+	assert.Equal(t, ".txt", vaultExtension("unknown-language"))
+}
+
+func TestExportVault(t *testing.T) {
+	helper := setupNoteTest(t)
+	defer helper.cleanup()
+	ns := helper.noteService
+
+	work, err := ns.CreateFolder("work/notes")
+	require.NoError(t, err)
+	sub, err := ns.CreateSubfolder(work.ID, "sub")
+	require.NoError(t, err)
+	old, err := ns.CreateFolder("old")
+	require.NoError(t, err)
+
+	// SaveNote は新しいノートを先頭に置くので、表示順は逆順でフォルダーが最後になる
+	saves := []*Note{
+		{ID: "query", Title: "query", Content: "SELECT 1;", Language: "sql"},
+		{ID: "same-1", Title: "Same", Content: "first", Language: "markdown"},
+		{ID: "same-2", Title: "same", Content: "second", Language: "markdown"},
+		{ID: "untitled", Title: "", Content: "first line\nsecond", Language: "plaintext"},
+		{ID: "md", Title: "Read: me?", Content: "# hello", Language: "markdown", Tags: []string{"b", "a"}},
+		{ID: "in-sub", Title: "main", Content: "package main", Language: "go"},
+		{ID: "archived-note", Title: "gone", Content: "bye", Language: "plaintext"},
+		{ID: "in-old", Title: "kept", Content: "old", Language: "markdown"},
+		{ID: "secret", Title: "secret", Content: "hunter2", Language: "markdown"},
+	}
+	for _, note := range saves {
+		require.NoError(t, ns.SaveNote(note))
+	}
+	require.NoError(t, ns.MoveNoteToFolder("in-sub", sub.ID))
+	require.NoError(t, ns.MoveNoteToFolder("in-old", old.ID))
+	archived, err := ns.LoadNote("archived-note")
+	require.NoError(t, err)
+	archived.Archived = true
+	require.NoError(t, ns.SaveNote(archived))
+	require.NoError(t, ns.ArchiveFolder(old.ID))
+	require.NoError(t, ns.EncryptNote("secret", testNotePassphrase))
+
+	_, err = ns.ExportVault("relative/dir", VaultExportOptions{})
+	assert.Error(t, err)
+	nonEmpty := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(nonEmpty, "existing.txt"), []byte("x"), 0644))
+	_, err = ns.ExportVault(nonEmpty, VaultExportOptions{})
+	assert.ErrorContains(t, err, "not empty")
+
+	dest := filepath.Join(t.TempDir(), "vault")
+	result, err := ns.ExportVault(dest, VaultExportOptions{FrontMatter: true, IncludeArchived: true, OrderPrefix: true})
+	require.NoError(t, err)
+	assert.Equal(t, 9, result.NoteCount)
+	assert.Equal(t, 3, result.FolderCount)
+	assert.Equal(t, 1, result.EncryptedCount)
+
+	data, err := os.ReadFile(filepath.Join(dest, vaultExportIndexName))
+	require.NoError(t, err)
+	var index vaultIndex
+	require.NoError(t, json.Unmarshal(data, &index))
+	assert.Equal(t, vaultExportFormat, index.Format)
+	paths := make(map[string]string, len(index.Items))
+	var order []string
+	for _, item := range index.Items {
+		paths[item.ID] = item.Path
+		order = append(order, item.Path)
+	}
+
+	// ルートの表示順 (TopLevelOrder) が連番と _index.json の両方に残る
+	assert.Equal(t, []string{
+		"01 secret.locked.json",
+		"02 Read_ me_.md",
+		"03 first line.txt",
+		"04 same.md",
+		"05 Same.md",
+		"06 query.sql",
+		"07 work_notes",
+		"07 work_notes/01 sub",
+		"07 work_notes/01 sub/01 main.go",
+	}, order[:9])
+	assert.Equal(t, "_archive/01 old", paths[old.ID])
+	assert.Equal(t, "_archive/01 old/01 kept.md", paths["in-old"])
+	assert.Equal(t, "_archive/02 gone.txt", paths["archived-note"])
+
+	md, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(paths["md"])))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(md), "---\nid: \"md\"\ntitle: \"Read: me?\"\n"))
+	assert.Contains(t, string(md), "language: \"markdown\"\ntags:\n  - \"a\"\n  - \"b\"\n---\n\n# hello")
+
+	code, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(paths["in-sub"])))
+	require.NoError(t, err)
+	assert.Equal(t, "package main", string(code))
+
+	locked, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(paths["secret"])))
+	require.NoError(t, err)
+	assert.True(t, isLockedNoteContent(string(locked)))
+	assert.NotContains(t, string(locked), "hunter2")
+
+	// 連番なしでも大文字小文字だけ違うタイトルは別のファイルになる
+	plain := filepath.Join(t.TempDir(), "plain")
+	_, err = ns.ExportVault(plain, VaultExportOptions{})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(plain, "same.md"))
+	assert.FileExists(t, filepath.Join(plain, "Same (2).md"))
+	assert.NoDirExists(t, filepath.Join(plain, vaultExportArchiveDir))
+	md, err = os.ReadFile(filepath.Join(plain, "Read_ me_.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# hello", string(md))
+}
@@ -0,0 +1,391 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// ------------------------------------------------------------
+// WebDAV 版 DriveOperations
+// ------------------------------------------------------------
+//
+// ベース URL からの相対パスをIDとして扱う（path_operations.go 参照）。
+// 変更検出では monaco-notepad 以下を PROPFIND して ETag のスナップショットを比較する。
+// ETag を返さないサーバーでは getlastmodified とサイズで代用する。
+
+const (
+	webdavRequestTimeout   = 60 * time.Second
+	webdavPropfindBodyText = `<?xml version="1.0" encoding="utf-8"?>` +
+		`<d:propfind xmlns:d="DAV:"><d:prop>` +
+		`<d:resourcetype/><d:getetag/><d:getlastmodified/><d:getcontentlength/>` +
+		`</d:prop></d:propfind>`
+)
+
+// webdavOperations は WebDAV サーバー上で DriveOperations を実装する
+type webdavOperations struct {
+	baseURL  *url.URL
+	username string
+	password string
+	client   *http.Client
+	logger   AppLogger
+
+	snapshots pathSnapshots
+}
+
+// WebDAV 用の DriveOperations を作成
+func NewWebDAVOperations(config WebDAVConfig, logger AppLogger) (*webdavOperations, error) {
+	baseURL, err := url.Parse(strings.TrimSpace(config.URL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WebDAV URL: %w", err)
+	}
+	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
+		return nil, fmt.Errorf("invalid WebDAV URL: scheme must be http or https")
+	}
+	if !strings.HasSuffix(baseURL.Path, "/") {
+		baseURL.Path += "/"
+	}
+	baseURL.RawPath = ""
+
+	return &webdavOperations{
+		baseURL:  baseURL,
+		username: config.Username,
+		password: config.Password,
+		client:   &http.Client{Timeout: webdavRequestTimeout},
+		logger:   logger,
+	}, nil
+}
+
+// ------------------------------------------------------------
+// HTTP ヘルパー
+// ------------------------------------------------------------
+
+// resolve はIDから URL を作る。Drive の "appDataFolder" はベース URL として扱う
+func (w *webdavOperations) resolve(id string, isDir bool) string {
+	id = pathCleanID(id)
+	target := *w.baseURL
+	if id != "" {
+		target = *w.baseURL.JoinPath(strings.Split(id, "/")...)
+	}
+	if isDir && !strings.HasSuffix(target.Path, "/") {
+		target.Path += "/"
+	}
+	return target.String()
+}
+
+func (w *webdavOperations) do(method string, id string, isDir bool, body []byte, header map[string]string) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, w.resolve(id, isDir), reader)
+	if err != nil {
+		return nil, err
+	}
+	if w.username != "" || w.password != "" {
+		req.SetBasicAuth(w.username, w.password)
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	return w.client.Do(req)
+}
+
+// webdavStatusError はステータスコードからエラーを作る。
+// 404 は isDriveNotFoundError で判定できるよう "not found" を含める。
+func webdavStatusError(method string, id string, resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s %s: file not found", method, id)
+	}
+	return fmt.Errorf("%s %s: unexpected status %s", method, id, resp.Status)
+}
+
+// PROPFIND の応答
+type webdavMultistatus struct {
+	Responses []struct {
+		Href      string `xml:"DAV: href"`
+		Propstats []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+				ETag          string `xml:"DAV: getetag"`
+				LastModified  string `xml:"DAV: getlastmodified"`
+				ContentLength int64  `xml:"DAV: getcontentlength"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// propfind は id と（depth=1 なら）その直下のエントリを返す。先頭が id 自身。
+func (w *webdavOperations) propfind(id string, depth int) ([]pathEntry, error) {
+	resp, err := w.do("PROPFIND", id, depth > 0, []byte(webdavPropfindBodyText), map[string]string{
+		"Depth":        fmt.Sprint(depth),
+		"Content-Type": "application/xml; charset=utf-8",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, webdavStatusError("PROPFIND", id, resp)
+	}
+
+	var multistatus webdavMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&multistatus); err != nil {
+		return nil, fmt.Errorf("failed to parse PROPFIND response: %w", err)
+	}
+
+	basePath := strings.TrimSuffix(w.baseURL.Path, "/")
+	var entries []pathEntry
+	for _, response := range multistatus.Responses {
+		href, err := url.Parse(response.Href)
+		if err != nil {
+			continue
+		}
+		entryPath := strings.TrimSuffix(href.Path, "/")
+		if !strings.HasPrefix(entryPath+"/", basePath+"/") {
+			continue
+		}
+		entry := pathEntry{id: strings.Trim(strings.TrimPrefix(entryPath, basePath), "/")}
+		for _, propstat := range response.Propstats {
+			if !strings.Contains(propstat.Status, " 200 ") {
+				continue
+			}
+			entry.isDir = propstat.Prop.ResourceType.Collection != nil
+			entry.version = strings.Trim(strings.TrimPrefix(propstat.Prop.ETag, "W/"), `"`)
+			entry.size = propstat.Prop.ContentLength
+			if modified, err := http.ParseTime(propstat.Prop.LastModified); err == nil {
+				entry.modified = modified
+			}
+		}
+		if entry.version == "" {
+			entry.version = fmt.Sprintf("%d:%d", entry.modified.UnixNano(), entry.size)
+		}
+		entries = append(entries, entry)
+	}
+
+	// サーバーによって自身の位置が異なるため、id 自身を先頭に並べ直す
+	target := pathCleanID(id)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].id == target && entries[j].id != target
+	})
+	if len(entries) == 0 || entries[0].id != target {
+		return nil, fmt.Errorf("PROPFIND %s: missing entry in response", id)
+	}
+	return entries, nil
+}
+
+// ------------------------------------------------------------
+// ファイル操作
+// ------------------------------------------------------------
+
+// 接続確認 (ベース URL が WebDAV のコレクションか) ------------------------------------------------------------
+func (w *webdavOperations) Ping() error {
+	entries, err := w.propfind("", 0)
+	if err != nil {
+		return fmt.Errorf("failed to connect to WebDAV server: %w", err)
+	}
+	if !entries[0].isDir {
+		return fmt.Errorf("failed to connect to WebDAV server: %s is not a collection", w.baseURL.Redacted())
+	}
+	return nil
+}
+
+// ファイルを作成 ------------------------------------------------------------
+func (w *webdavOperations) CreateFile(name string, content []byte, parentID string, mimeType string) (string, error) {
+	id := pathJoinID(parentID, name)
+	w.logger.Console("[WebDAV] Creating file: %s", id)
+	if err := w.put(id, content); err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	return id, nil
+}
+
+// ファイルを更新 ------------------------------------------------------------
+func (w *webdavOperations) UpdateFile(fileID string, content []byte) error {
+	w.logger.Console("[WebDAV] Updating file: %s", fileID)
+	if err := w.put(fileID, content); err != nil {
+		return fmt.Errorf("failed to update file: %w", err)
+	}
+	return nil
+}
+
+func (w *webdavOperations) put(id string, content []byte) error {
+	resp, err := w.do(http.MethodPut, id, false, content, map[string]string{"Content-Type": "application/json"})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return webdavStatusError("PUT", id, resp)
+	}
+	return nil
+}
+
+// ファイル・フォルダを削除 ------------------------------------------------------------
+func (w *webdavOperations) DeleteFile(fileID string) error {
+	w.logger.Console("[WebDAV] Deleting file: %s", fileID)
+	resp, err := w.do(http.MethodDelete, fileID, false, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to delete file: %w", webdavStatusError("DELETE", fileID, resp))
+	}
+	return nil
+}
+
+// ファイルをダウンロード ------------------------------------------------------------
+func (w *webdavOperations) DownloadFile(fileID string) ([]byte, error) {
+	w.logger.Console("[WebDAV] Downloading file: %s", fileID)
+	resp, err := w.do(http.MethodGet, fileID, false, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download file: %w", webdavStatusError("GET", fileID, resp))
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file content: %w", err)
+	}
+	return content, nil
+}
+
+func (w *webdavOperations) GetFileMetadata(fileID string) (*drive.File, error) {
+	w.logger.Console("[WebDAV] Getting metadata: %s", fileID)
+	entries, err := w.propfind(fileID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file metadata: %w", err)
+	}
+	return entries[0].driveFile(), nil
+}
+
+// フォルダを作成 (既にあればそのまま使う) ------------------------------------------------------------
+func (w *webdavOperations) CreateFolder(name string, parentID string) (string, error) {
+	id := pathJoinID(parentID, name)
+	w.logger.Console("[WebDAV] Creating folder: %s", id)
+	resp, err := w.do("MKCOL", id, true, nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create folder: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusMethodNotAllowed {
+		return "", fmt.Errorf("failed to create folder: %w", webdavStatusError("MKCOL", id, resp))
+	}
+	return id, nil
+}
+
+// Drive のクエリ文字列でファイルを検索 ------------------------------------------------------------
+func (w *webdavOperations) ListFiles(query string) ([]*drive.File, error) {
+	w.logger.Console("[WebDAV] Listing files: %s", query)
+	q := parsePathFileQuery(query)
+	entries, err := w.propfind(q.parentID, 1)
+	if err != nil {
+		if isDriveNotFoundError(err) {
+			return []*drive.File{}, nil
+		}
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	files := make([]*drive.File, 0, len(entries))
+	for _, entry := range entries[1:] {
+		if q.matches(entry) {
+			files = append(files, entry.driveFile())
+		}
+	}
+	return files, nil
+}
+
+// ファイル名からファイルIDを取得 (パスがIDなので存在確認だけ行う) ------------------------------------------------------------
+func (w *webdavOperations) GetFileID(fileName string, noteFolderID string, rootFolderID string) (string, error) {
+	id, err := pathFileIDFor(fileName, noteFolderID, rootFolderID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.propfind(id, 0); err != nil {
+		if isDriveNotFoundError(err) {
+			return "", pathFileNotFoundError(fileName, noteFolderID, rootFolderID)
+		}
+		return "", err
+	}
+	w.logger.Console("GetFileID done: %s id: %s", fileName, id)
+	return id, nil
+}
+
+func (w *webdavOperations) FindLatestFile(files []*drive.File) *drive.File {
+	return findLatestDriveFile(files)
+}
+
+// 重複ファイルの整理 (パスがIDのため通常は重複しない) ------------------------------------------------------------
+func (w *webdavOperations) CleanupDuplicates(files []*drive.File, keepLatest bool) error {
+	if len(files) <= 1 {
+		return nil
+	}
+	targetFiles := files
+	if keepLatest {
+		targetFiles = files[1:]
+	}
+	for _, file := range targetFiles {
+		if err := w.DeleteFile(file.Id); err != nil {
+			return fmt.Errorf("failed to delete file %s: %w", file.Name, err)
+		}
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+// 変更検出 (Changes API の代替)
+// ------------------------------------------------------------
+
+// 現在のスナップショットを記録し、そのトークンを返す ------------------------------------------------------------
+func (w *webdavOperations) GetStartPageToken() (string, error) {
+	w.logger.Console("[WebDAV] GetStartPageToken")
+	snapshot, err := w.snapshot()
+	if err != nil {
+		return "", fmt.Errorf("failed to get start page token: %w", err)
+	}
+	return w.snapshots.store(snapshot), nil
+}
+
+// トークンの時点から変わったファイルを返す ------------------------------------------------------------
+func (w *webdavOperations) ListChanges(pageToken string) (*ChangesResult, error) {
+	w.logger.Console("[WebDAV] ListChanges pageToken=%s", pageToken)
+	return w.snapshots.listChanges(pageToken, w.snapshot)
+}
+
+// snapshot は monaco-notepad フォルダ以下のファイルを ETag 付きで集める
+func (w *webdavOperations) snapshot() (map[string]pathEntry, error) {
+	snapshot := make(map[string]pathEntry)
+	queue := []string{pathRootFolderName}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		entries, err := w.propfind(id, 1)
+		if err != nil {
+			if isDriveNotFoundError(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, entry := range entries[1:] {
+			snapshot[entry.id] = entry
+			if entry.isDir {
+				queue = append(queue, entry.id)
+			}
+		}
+	}
+	return snapshot, nil
+}
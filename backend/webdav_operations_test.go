@@ -0,0 +1,230 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/webdav"
+)
+
+// newTestWebDAVServer はメモリ上の WebDAV サーバーを起動する（Basic 認証付き）
+func newTestWebDAVServer(t *testing.T) WebDAVConfig {
+	t.Helper()
+	handler := &webdav.Handler{
+		Prefix:     "/dav",
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return WebDAVConfig{URL: server.URL + "/dav", Username: "alice", Password: "secret"}
+}
+
+func newTestWebDAVOperations(t *testing.T, config WebDAVConfig) *webdavOperations {
+	t.Helper()
+	ops, err := NewWebDAVOperations(config, NewAppLogger(context.Background(), true, t.TempDir()))
+	require.NoError(t, err)
+	return ops
+}
+
+// newProviderTestDriveService は connect で同期先に接続した端末 1 台分の driveService を作る
+func newProviderTestDriveService(t *testing.T, connect func(ds *driveService) error) *driveService {
+	t.Helper()
+	ctx := context.Background()
+	appDataDir := t.TempDir()
+	notesDir := filepath.Join(appDataDir, "notes")
+	require.NoError(t, os.MkdirAll(notesDir, 0755))
+	logger := NewAppLogger(ctx, true, appDataDir)
+	noteService, err := NewNoteService(notesDir, logger)
+	require.NoError(t, err)
+	auth := NewAuthService(ctx, appDataDir, notesDir, noteService, nil, logger, true)
+	ds := NewDriveService(ctx, appDataDir, notesDir, noteService, nil, logger, auth, NewSyncState(appDataDir))
+	t.Cleanup(func() {
+		// LogoutDrive 済みならキューは既に停止している
+		if ds.operationsQueue != nil && ds.IsConnected() {
+			ds.operationsQueue.Cleanup()
+		}
+	})
+
+	require.NoError(t, connect(ds))
+	require.True(t, ds.IsConnected())
+	return ds
+}
+
+func TestNewWebDAVOperations_RejectsInvalidURL(t *testing.T) {
+	logger := NewAppLogger(context.Background(), true, t.TempDir())
+	_, err := NewWebDAVOperations(WebDAVConfig{URL: "ftp://example.com/dav"}, logger)
+	assert.Error(t, err)
+	_, err = NewWebDAVOperations(WebDAVConfig{URL: "://"}, logger)
+	assert.Error(t, err)
+}
+
+func TestWebDAVOperations_FilesAndQueries(t *testing.T) {
+	config := newTestWebDAVServer(t)
+	ops := newTestWebDAVOperations(t, config)
+	require.NoError(t, ops.Ping())
+
+	wrong := config
+	wrong.Password = "wrong"
+	assert.Error(t, newTestWebDAVOperations(t, wrong).Ping())
+
+	rootID, err := ops.CreateFolder("monaco-notepad", "appDataFolder")
+	require.NoError(t, err)
+	assert.Equal(t, "monaco-notepad", rootID)
+	notesID, err := ops.CreateFolder("notes", rootID)
+	require.NoError(t, err)
+	assert.Equal(t, "monaco-notepad/notes", notesID)
+	// 既存フォルダの作成はそのまま成功する
+	_, err = ops.CreateFolder("notes", rootID)
+	require.NoError(t, err)
+
+	noteID, err := ops.CreateFile("n1.json", []byte(`{"id":"n1"}`), notesID, "application/json")
+	require.NoError(t, err)
+	listID, err := ops.CreateFile("noteList_v2.json", []byte(`{}`), rootID, "application/json")
+	require.NoError(t, err)
+
+	folders, err := ops.ListFiles("name='monaco-notepad' and mimeType='application/vnd.google-apps.folder' and trashed=false")
+	require.NoError(t, err)
+	require.Len(t, folders, 1)
+	assert.Equal(t, rootID, folders[0].Id)
+
+	files, err := ops.ListFiles("'" + notesID + "' in parents and trashed=false")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, noteID, files[0].Id)
+	assert.Equal(t, []string{notesID}, files[0].Parents)
+	assert.NotEmpty(t, files[0].Md5Checksum)
+
+	files, err = ops.ListFiles("name='noteList_v2.json' and '" + rootID + "' in parents and trashed=false")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, listID, files[0].Id)
+
+	files, err = ops.ListFiles("'missing' in parents")
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	require.NoError(t, ops.UpdateFile(noteID, []byte(`{"id":"n1","title":"updated"}`)))
+	content, err := ops.DownloadFile(noteID)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":"n1","title":"updated"}`, string(content))
+
+	id, err := ops.GetFileID("n1.json", notesID, rootID)
+	require.NoError(t, err)
+	assert.Equal(t, noteID, id)
+	id, err = ops.GetFileID("noteList_v2.json", notesID, rootID)
+	require.NoError(t, err)
+	assert.Equal(t, listID, id)
+	_, err = ops.GetFileID("n2.json", notesID, rootID)
+	assert.ErrorContains(t, err, "note file n2.json not found")
+
+	require.NoError(t, ops.DeleteFile(noteID))
+	_, err = ops.DownloadFile(noteID)
+	assert.True(t, isDriveNotFoundError(err))
+	_, err = ops.GetFileMetadata(noteID)
+	assert.True(t, isDriveNotFoundError(err))
+}
+
+// ETag のスナップショット比較で追加・更新・削除を検出すること
+func TestWebDAVOperations_ListChanges(t *testing.T) {
+	config := newTestWebDAVServer(t)
+	ops := newTestWebDAVOperations(t, config)
+	rootID, err := ops.CreateFolder("monaco-notepad", "")
+	require.NoError(t, err)
+	notesID, err := ops.CreateFolder("notes", rootID)
+	require.NoError(t, err)
+	keepID, err := ops.CreateFile("keep.json", []byte("keep"), notesID, "application/json")
+	require.NoError(t, err)
+	goneID, err := ops.CreateFile("gone.json", []byte("gone"), notesID, "application/json")
+	require.NoError(t, err)
+	// ベース URL 直下の無関係なファイルは対象外
+	_, err = ops.CreateFile("unrelated.txt", []byte("x"), "", "text/plain")
+	require.NoError(t, err)
+
+	token, err := ops.GetStartPageToken()
+	require.NoError(t, err)
+
+	result, err := ops.ListChanges(token)
+	require.NoError(t, err)
+	assert.Empty(t, result.Changes)
+	assert.Equal(t, token, result.NewStartToken)
+
+	// 別の端末からの変更
+	other := newTestWebDAVOperations(t, config)
+	require.NoError(t, other.UpdateFile(keepID, []byte("keep, but longer")))
+	require.NoError(t, other.DeleteFile(goneID))
+	addedID, err := other.CreateFile("added.json", []byte("added"), notesID, "application/json")
+	require.NoError(t, err)
+
+	result, err = ops.ListChanges(token)
+	require.NoError(t, err)
+	require.Len(t, result.Changes, 3)
+	assert.NotEqual(t, token, result.NewStartToken)
+	byID := map[string]bool{}
+	for _, change := range result.Changes {
+		byID[change.FileId] = change.Removed
+		assert.Equal(t, change.Removed, change.File.Trashed)
+	}
+	assert.Equal(t, map[string]bool{keepID: false, goneID: true, addedID: false}, byID)
+	assert.True(t, hasRelevantChanges(result.Changes, rootID, notesID))
+
+	result, err = ops.ListChanges(result.NewStartToken)
+	require.NoError(t, err)
+	assert.Empty(t, result.Changes)
+
+	_, err = ops.ListChanges("unknown-token")
+	assert.Error(t, err)
+}
+
+// 2 台の端末が WebDAV 経由でノートを同期できること
+func TestWebDAVSync_TwoDevices(t *testing.T) {
+	config := newTestWebDAVServer(t)
+	connect := func(ds *driveService) error { return ds.ConnectWebDAV(config) }
+	deviceA := newProviderTestDriveService(t, connect)
+	deviceB := newProviderTestDriveService(t, connect)
+
+	// 接続情報は次回起動時の自動接続のために保存される
+	saved, err := loadWebDAVConfig(deviceA.appDataDir)
+	require.NoError(t, err)
+	assert.Equal(t, config, *saved)
+
+	note := &Note{ID: "webdav-note", Title: "shared", Content: "hello from A", Language: "plaintext"}
+	require.NoError(t, deviceA.noteService.SaveNote(note))
+	deviceA.syncState.MarkNoteDirty(note.ID)
+	require.NoError(t, deviceA.SyncNotes())
+
+	require.NoError(t, deviceB.SyncNotes())
+	received, err := deviceB.noteService.LoadNote(note.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "hello from A", received.Content)
+
+	// B の編集が A に戻ること
+	received.Content = "edited on B"
+	require.NoError(t, deviceB.noteService.SaveNote(received))
+	deviceB.syncState.MarkNoteDirty(note.ID)
+	require.NoError(t, deviceB.SyncNotes())
+
+	require.NoError(t, deviceA.SyncNotes())
+	updated, err := deviceA.noteService.LoadNote(note.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "edited on B", updated.Content)
+
+	// ログアウトすると接続情報も削除される
+	require.NoError(t, deviceA.LogoutDrive())
+	assert.False(t, deviceA.IsConnected())
+	saved, err = loadWebDAVConfig(deviceA.appDataDir)
+	require.NoError(t, err)
+	assert.Nil(t, saved)
+}
@@ -186,6 +186,7 @@ vi.mock('../../../wailsjs/go/backend/App', () => ({
   SelectFile: vi.fn(),
   OpenFile: vi.fn(),
   SaveFile: vi.fn(),
+  SaveFileChecked: vi.fn().mockResolvedValue({}),
   SelectSaveFileUri: vi.fn(),
   GetModifiedTime: vi.fn(),
   GetSettings: vi.fn().mockResolvedValue({
@@ -13,6 +13,7 @@ vi.mock('../../../wailsjs/go/backend/App', () => ({
   SelectFile: vi.fn(),
   OpenFile: vi.fn(),
   SaveFile: vi.fn(),
+  SaveFileChecked: vi.fn(),
   SaveNote: vi.fn().mockResolvedValue(undefined),
   SelectSaveFileUri: vi.fn(),
   GetModifiedTime: vi.fn(),
@@ -49,6 +50,7 @@ import {
   GetModifiedTime,
   OpenFile,
   SaveFile,
+  SaveFileChecked,
   SaveNote,
   SelectFile,
   SelectSaveFileUri,
@@ -100,6 +102,9 @@ describe('useFileOperations', () => {
     vi.useFakeTimers();
     consoleErrorSpy = vi.spyOn(console, 'error').mockImplementation(() => {});
     mockShowMessage.mockResolvedValue(true);
+    (SaveFileChecked as unknown as Mock).mockResolvedValue({
+      modifiedTime: new Date().toISOString(),
+    });
     (GetModifiedTime as unknown as Mock).mockResolvedValue(
       new Date().toISOString(),
     );
@@ -231,14 +236,93 @@ describe('useFileOperations', () => {
         await result.current.handleSaveFile(mockFileNote);
       });
 
-      expect(SaveFile).toHaveBeenCalledWith(
+      expect(SaveFileChecked).toHaveBeenCalledWith(
         mockFileNote.filePath,
         mockFileNote.content,
+        mockFileNote.modifiedTime,
       );
+      expect(SaveFile).not.toHaveBeenCalled();
       expect(mockSetFileNotes).toHaveBeenCalled();
       expect(mockHandleSaveFileNotes).toHaveBeenCalled();
     });
 
+    it('アプリ外で変更されていたら、上書きを断ると保存しないこと', async () => {
+      const editedFileNote = { ...mockFileNote, content: 'Edited in app' };
+      useFileNotesStore.setState({ fileNotes: [editedFileNote] });
+      (SaveFileChecked as unknown as Mock).mockResolvedValueOnce({
+        conflict: { filePath: mockFileNote.filePath, conflicts: 1 },
+      });
+      mockShowMessage.mockResolvedValueOnce(false);
+
+      const { result } = renderHook(() =>
+        useFileOperations(
+          mockHandleSelecAnyNote,
+          mockShowMessage,
+          mockHandleSaveFileNotes,
+          mockOpenNoteInPaneRef,
+          mockAddRecentFileRef,
+          mockPendingContentRef,
+        ),
+      );
+
+      await act(async () => {
+        await result.current.handleSaveFile(editedFileNote);
+      });
+
+      expect(mockShowMessage).toHaveBeenCalledWith(
+        'File changed externally',
+        expect.stringContaining('file.txt'),
+        true,
+      );
+      expect(SaveFile).not.toHaveBeenCalled();
+      expect(mockHandleSaveFileNotes).not.toHaveBeenCalled();
+      // 未保存のまま残す
+      const stored = useFileNotesStore
+        .getState()
+        .fileNotes.find((n) => n.id === mockFileNote.id);
+      expect(stored?.originalContent).toBe(mockFileNote.originalContent);
+      expect(vi.mocked(runtime.EventsEmit)).toHaveBeenCalledWith(
+        'logMessage',
+        expect.stringContaining('Not saved'),
+      );
+    });
+
+    it('アプリ外で変更されていても、上書きを選ぶと SaveFile で保存すること', async () => {
+      const editedFileNote = { ...mockFileNote, content: 'Edited in app' };
+      useFileNotesStore.setState({ fileNotes: [editedFileNote] });
+      const diskMtime = '2026-05-09T12:00:00.123456789Z';
+      (SaveFileChecked as unknown as Mock).mockResolvedValueOnce({
+        conflict: { filePath: mockFileNote.filePath, conflicts: 1 },
+      });
+      (SaveFile as unknown as Mock).mockResolvedValueOnce(diskMtime);
+      mockShowMessage.mockResolvedValueOnce(true);
+
+      const { result } = renderHook(() =>
+        useFileOperations(
+          mockHandleSelecAnyNote,
+          mockShowMessage,
+          mockHandleSaveFileNotes,
+          mockOpenNoteInPaneRef,
+          mockAddRecentFileRef,
+          mockPendingContentRef,
+        ),
+      );
+
+      await act(async () => {
+        await result.current.handleSaveFile(editedFileNote);
+      });
+
+      expect(SaveFile).toHaveBeenCalledWith(
+        mockFileNote.filePath,
+        'Edited in app',
+      );
+      const stored = useFileNotesStore
+        .getState()
+        .fileNotes.find((n) => n.id === mockFileNote.id);
+      expect(stored?.originalContent).toBe('Edited in app');
+      expect(stored?.modifiedTime).toBe(diskMtime);
+    });
+
     // 「保存直後にフォーカスが戻ると外部編集ダイアログが誤表示される」バグの回帰テスト。
     //
     // 旧実装は SaveFile 完了後に new Date().toISOString() を modifiedTime として保存
@@ -249,8 +333,10 @@ describe('useFileOperations', () => {
     it('SaveFile が返したディスク mtime を modifiedTime として保存すること (回帰: 外部編集ダイアログ誤表示)', async () => {
       useFileNotesStore.setState({ fileNotes: [mockFileNote] });
       const diskMtime = '2026-05-09T12:00:00.123456789Z';
-      // SaveFile を「ディスク mtime を返す」新 API として mock。
-      (SaveFile as unknown as Mock).mockResolvedValueOnce(diskMtime);
+      // SaveFileChecked を「ディスク mtime を返す」API として mock。
+      (SaveFileChecked as unknown as Mock).mockResolvedValueOnce({
+        modifiedTime: diskMtime,
+      });
 
       const { result } = renderHook(() =>
         useFileOperations(
@@ -271,7 +357,7 @@ describe('useFileOperations', () => {
         .getState()
         .fileNotes.find((n) => n.id === mockFileNote.id);
       expect(stored).toBeTruthy();
-      // SaveFile が返した値をそのまま使うこと。new Date().toISOString() を使ってはいけない。
+      // SaveFileChecked が返した値をそのまま使うこと。new Date().toISOString() を使ってはいけない。
       expect(stored?.modifiedTime).toBe(diskMtime);
       // GetModifiedTime を別途呼ぶことなく一往復で完結すること。
       expect(GetModifiedTime).not.toHaveBeenCalledWith(mockFileNote.filePath);
@@ -290,7 +376,9 @@ describe('useFileOperations', () => {
         ),
       );
 
-      (SaveFile as unknown as Mock).mockRejectedValue(new Error('Save failed'));
+      (SaveFileChecked as unknown as Mock).mockRejectedValue(
+        new Error('Save failed'),
+      );
 
       await act(async () => {
         await result.current.handleSaveFile(mockFileNote);
@@ -4,6 +4,7 @@ import {
   GetModifiedTime,
   OpenFile,
   SaveFile,
+  SaveFileChecked,
   SaveNote,
   SelectFile,
   SelectSaveFileUri,
@@ -41,7 +42,7 @@ const setFileNotes = (
 
 export function useFileOperations(
   handleSelecAnyNote: (note: Note | FileNote) => Promise<void>,
-  _showMessage: (
+  showMessage: (
     title: string,
     message: string,
     isTwoButton?: boolean,
@@ -252,11 +253,32 @@ export function useFileOperations(
   const handleSaveFile = async (fileNote: FileNote) => {
     try {
       if (!fileNote.content) return;
-      // SaveFile はバックエンド側で os.Stat した実ディスク mtime (RFC3339Nano) を返す。
+      // SaveFileChecked / SaveFile はバックエンド側で os.Stat した実ディスク mtime (RFC3339Nano) を返す。
       // 旧実装は JS の new Date().toISOString() を mtime として保存していたが、
       // kernel が write 中に記録するナノ秒精度の mtime とずれて、直後のフォーカス時
       // CheckFileModified が「外部編集された」と誤検知していた (Bug 1 の根本原因)。
-      const savedTime = await SaveFile(fileNote.filePath, fileNote.content);
+      // 開いた後にアプリ外で変更されていれば書き込まずに conflict を返すので、上書きするかを確かめる。
+      const result = await SaveFileChecked(
+        fileNote.filePath,
+        fileNote.content,
+        fileNote.modifiedTime,
+      );
+      let savedTime = result.modifiedTime ?? '';
+      if (result.conflict) {
+        const overwrite = await showMessage(
+          i18n.t('file.saveConflictTitle'),
+          i18n.t('file.saveConflictMessage', {
+            fileName: fileNote.fileName,
+            filePath: fileNote.filePath,
+          }),
+          true,
+        );
+        if (!overwrite) {
+          runtime.EventsEmit('logMessage', i18n.t('file.saveConflictKept'));
+          return;
+        }
+        savedTime = await SaveFile(fileNote.filePath, fileNote.content);
+      }
       const savedContent = fileNote.content;
       // refから最新のfileNotesを取得して更新する（await中にユーザーが編集した場合、
       // クロージャの古いfileNotesで上書きしてしまうのを防ぐ）
@@ -20,10 +20,26 @@ export function CheckFileExists(arg1:string):Promise<boolean>;
 
 export function CheckFileModified(arg1:string,arg2:string):Promise<boolean>;
 
+export function CheckLinkedFiles():Promise<void>;
+
+export function CloseLargeFile(arg1:string):Promise<void>;
+
+export function ConnectLocalFolder(arg1:backend.LocalFolderConfig):Promise<void>;
+
+export function ConnectWebDAV(arg1:backend.WebDAVConfig):Promise<void>;
+
 export function Console(arg1:string,arg2:Array<any>):Promise<void>;
 
+export function CreateBackup(arg1:string):Promise<backend.BackupInfo>;
+
 export function CreateFolder(arg1:string):Promise<backend.Folder>;
 
+export function CreateProfile(arg1:string):Promise<backend.Profile>;
+
+export function CreateSubfolder(arg1:string,arg2:string):Promise<backend.Folder>;
+
+export function DecryptNote(arg1:string,arg2:string):Promise<backend.Note>;
+
 export function DeleteAllCloudConflictBackups():Promise<void>;
 
 export function DeleteAllDriveData():Promise<void>;
@@ -40,34 +56,86 @@ export function DeleteNote(arg1:string):Promise<void>;
 
 export function DestroyApp():Promise<void>;
 
+export function DiffCloudConflictBackup(arg1:string):Promise<backend.ConflictBackupDiff>;
+
+export function DiffNoteRevisions(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function DisableSyncEncryption():Promise<void>;
+
 export function DomReady(arg1:context.Context):Promise<void>;
 
+export function EmptyTrash():Promise<void>;
+
+export function EnableSyncEncryption(arg1:string):Promise<void>;
+
+export function EncryptNote(arg1:string,arg2:string):Promise<void>;
+
+export function ExportVault(arg1:string,arg2:backend.VaultExportOptions):Promise<backend.VaultExportResult>;
+
+export function FindUnencodableChars(arg1:string,arg2:string):Promise<Array<backend.EncodingIssue>>;
+
+export function FollowLargeFile(arg1:string):Promise<void>;
+
 export function GetAppVersion():Promise<string>;
 
 export function GetArchivedTopLevelOrder():Promise<Array<backend.TopLevelItem>>;
 
 export function GetCollapsedFolderIDs():Promise<Array<string>>;
 
+export function GetLinkedFile(arg1:string):Promise<backend.LinkedFile>;
+
+export function GetLocalAPIInfo():Promise<backend.LocalAPIInfo>;
+
+export function GetLocalFolderConfig():Promise<backend.LocalFolderConfig>;
+
 export function GetModifiedTime(arg1:string):Promise<time.Time>;
 
 export function GetNativeSystemLocale():Promise<string>;
 
+export function GetNoteRevision(arg1:string,arg2:string):Promise<backend.NoteRevision>;
+
 export function GetReleaseInfo():Promise<backend.ReleaseInfo>;
 
+export function GetSyncEncryptionStatus():Promise<backend.SyncEncryptionStatus>;
+
+export function GetSyncHistory(arg1:number):Promise<Array<backend.SyncRun>>;
+
 export function GetSystemLocale():Promise<string>;
 
 export function GetTopLevelOrder():Promise<Array<backend.TopLevelItem>>;
 
+export function GetWebDAVConfig():Promise<backend.WebDAVConfig>;
+
+export function ImportNotes(arg1:string,arg2:backend.ImportOptions):Promise<backend.ImportReport>;
+
 export function InitializeDrive():Promise<void>;
 
 export function IsWindowPositionValid(arg1:number,arg2:number,arg3:number,arg4:number):Promise<boolean>;
 
+export function LinkNoteToFile(arg1:string,arg2:string):Promise<backend.LinkedFile>;
+
+export function ListBackups():Promise<Array<backend.BackupInfo>>;
+
 export function ListCloudConflictBackups():Promise<Array<backend.ConflictBackupEntry>>;
 
 export function ListFolders():Promise<Array<backend.Folder>>;
 
+export function ListLinkedFiles():Promise<Array<backend.LinkedFile>>;
+
+export function ListNoteRevisions(arg1:string):Promise<Array<backend.NoteRevision>>;
+
 export function ListNotes():Promise<Array<backend.Note>>;
 
+export function ListNotesByTag(arg1:string):Promise<Array<backend.Note>>;
+
+export function ListProfiles():Promise<backend.ProfileList>;
+
+export function ListTags():Promise<Array<backend.TagCount>>;
+
+export function ListTextEncodings():Promise<Array<string>>;
+
+export function ListTrash():Promise<Array<backend.TrashItem>>;
+
 export function LoadArchivedNote(arg1:string):Promise<backend.Note>;
 
 export function LoadFileNotes():Promise<Array<backend.FileNote>>;
@@ -78,8 +146,12 @@ export function LoadRecentFiles():Promise<Array<string>>;
 
 export function LoadSettings():Promise<backend.Settings>;
 
+export function LockNote(arg1:string):Promise<void>;
+
 export function LogoutDrive():Promise<void>;
 
+export function MoveFolder(arg1:string,arg2:string):Promise<void>;
+
 export function MoveNoteToFolder(arg1:string,arg2:string):Promise<void>;
 
 export function NotifyFrontendReady():Promise<void>;
@@ -96,14 +168,34 @@ export function OpenURL(arg1:string):Promise<void>;
 
 export function PerformUpdate(arg1:string,arg2:string):Promise<void>;
 
+export function ReadFileLines(arg1:string,arg2:number,arg3:number):Promise<backend.LargeFileLines>;
+
+export function ReadHexDump(arg1:string,arg2:number,arg3:number):Promise<backend.HexDump>;
+
+export function RegenerateLocalAPIToken():Promise<backend.LocalAPIInfo>;
+
 export function RenameFolder(arg1:string,arg2:string):Promise<void>;
 
+export function RenameTag(arg1:string,arg2:string):Promise<void>;
+
+export function ResolveCloudConflictBackup(arg1:string,arg2:backend.ConflictResolution):Promise<backend.Note>;
+
 export function RespondToMigration(arg1:string):Promise<void>;
 
+export function RestoreBackup(arg1:string):Promise<void>;
+
+export function RestoreFromTrash(arg1:string):Promise<void>;
+
+export function RestoreNoteRevision(arg1:string,arg2:string):Promise<backend.Note>;
+
 export function SaveFile(arg1:string,arg2:string):Promise<string>;
 
+export function SaveFileChecked(arg1:string,arg2:string,arg3:string):Promise<backend.FileSaveResult>;
+
 export function SaveFileNotes(arg1:Array<backend.FileNote>):Promise<string>;
 
+export function SaveFileWithEncoding(arg1:string,arg2:string,arg3:backend.TextFormat):Promise<string>;
+
 export function SaveNote(arg1:backend.Note,arg2:string):Promise<void>;
 
 export function SaveNoteList():Promise<void>;
@@ -114,20 +206,48 @@ export function SaveSettings(arg1:backend.Settings):Promise<void>;
 
 export function SaveWindowState(arg1:backend.Context):Promise<void>;
 
+export function SearchLargeFile(arg1:string,arg2:string,arg3:backend.LargeFileSearchOptions):Promise<backend.LargeFileSearchResult>;
+
+export function SearchNotes(arg1:string,arg2:backend.SearchOptions):Promise<Array<backend.SearchHit>>;
+
+export function SelectExportDirectory():Promise<string>;
+
 export function SelectFile():Promise<string>;
 
+export function SelectImportDirectory():Promise<string>;
+
 export function SelectSaveFileUri(arg1:string,arg2:string):Promise<string>;
 
+export function SelectSyncFolder():Promise<string>;
+
+export function SetFolderLocalOnly(arg1:string,arg2:boolean):Promise<void>;
+
 export function SetLastActiveNote(arg1:string,arg2:boolean):Promise<void>;
 
+export function SetLocalAPIEnabled(arg1:boolean):Promise<backend.LocalAPIInfo>;
+
+export function SetNoteLocalOnly(arg1:string,arg2:boolean):Promise<void>;
+
+export function SetSyncProvider(arg1:string):Promise<void>;
+
+export function SwitchProfile(arg1:string):Promise<void>;
+
 export function SyncNow():Promise<void>;
 
 export function UnarchiveFolder(arg1:string):Promise<void>;
 
+export function UnfollowLargeFile(arg1:string):Promise<void>;
+
+export function UnlinkNote(arg1:string):Promise<void>;
+
+export function UnlockNote(arg1:string,arg2:string):Promise<backend.Note>;
+
 export function UpdateArchivedTopLevelOrder(arg1:Array<backend.TopLevelItem>):Promise<void>;
 
 export function UpdateCollapsedFolderIDs(arg1:Array<string>):Promise<void>;
 
+export function UpdateFolderOrder(arg1:string,arg2:Array<string>):Promise<void>;
+
 export function UpdateNoteOrder(arg1:string,arg2:number):Promise<void>;
 
 export function UpdateTopLevelOrder(arg1:Array<backend.TopLevelItem>):Promise<void>;
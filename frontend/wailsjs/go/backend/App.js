@@ -34,14 +34,46 @@ export function CheckFileModified(arg1, arg2) {
   return window['go']['backend']['App']['CheckFileModified'](arg1, arg2);
 }
 
+export function CheckLinkedFiles() {
+  return window['go']['backend']['App']['CheckLinkedFiles']();
+}
+
+export function CloseLargeFile(arg1) {
+  return window['go']['backend']['App']['CloseLargeFile'](arg1);
+}
+
+export function ConnectLocalFolder(arg1) {
+  return window['go']['backend']['App']['ConnectLocalFolder'](arg1);
+}
+
+export function ConnectWebDAV(arg1) {
+  return window['go']['backend']['App']['ConnectWebDAV'](arg1);
+}
+
 export function Console(arg1, arg2) {
   return window['go']['backend']['App']['Console'](arg1, arg2);
 }
 
+export function CreateBackup(arg1) {
+  return window['go']['backend']['App']['CreateBackup'](arg1);
+}
+
 export function CreateFolder(arg1) {
   return window['go']['backend']['App']['CreateFolder'](arg1);
 }
 
+export function CreateProfile(arg1) {
+  return window['go']['backend']['App']['CreateProfile'](arg1);
+}
+
+export function CreateSubfolder(arg1, arg2) {
+  return window['go']['backend']['App']['CreateSubfolder'](arg1, arg2);
+}
+
+export function DecryptNote(arg1, arg2) {
+  return window['go']['backend']['App']['DecryptNote'](arg1, arg2);
+}
+
 export function DeleteAllCloudConflictBackups() {
   return window['go']['backend']['App']['DeleteAllCloudConflictBackups']();
 }
@@ -74,10 +106,46 @@ export function DestroyApp() {
   return window['go']['backend']['App']['DestroyApp']();
 }
 
+export function DiffCloudConflictBackup(arg1) {
+  return window['go']['backend']['App']['DiffCloudConflictBackup'](arg1);
+}
+
+export function DiffNoteRevisions(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['DiffNoteRevisions'](arg1, arg2, arg3);
+}
+
+export function DisableSyncEncryption() {
+  return window['go']['backend']['App']['DisableSyncEncryption']();
+}
+
 export function DomReady(arg1) {
   return window['go']['backend']['App']['DomReady'](arg1);
 }
 
+export function EmptyTrash() {
+  return window['go']['backend']['App']['EmptyTrash']();
+}
+
+export function EnableSyncEncryption(arg1) {
+  return window['go']['backend']['App']['EnableSyncEncryption'](arg1);
+}
+
+export function EncryptNote(arg1, arg2) {
+  return window['go']['backend']['App']['EncryptNote'](arg1, arg2);
+}
+
+export function ExportVault(arg1, arg2) {
+  return window['go']['backend']['App']['ExportVault'](arg1, arg2);
+}
+
+export function FindUnencodableChars(arg1, arg2) {
+  return window['go']['backend']['App']['FindUnencodableChars'](arg1, arg2);
+}
+
+export function FollowLargeFile(arg1) {
+  return window['go']['backend']['App']['FollowLargeFile'](arg1);
+}
+
 export function GetAppVersion() {
   return window['go']['backend']['App']['GetAppVersion']();
 }
@@ -90,6 +158,18 @@ export function GetCollapsedFolderIDs() {
   return window['go']['backend']['App']['GetCollapsedFolderIDs']();
 }
 
+export function GetLinkedFile(arg1) {
+  return window['go']['backend']['App']['GetLinkedFile'](arg1);
+}
+
+export function GetLocalAPIInfo() {
+  return window['go']['backend']['App']['GetLocalAPIInfo']();
+}
+
+export function GetLocalFolderConfig() {
+  return window['go']['backend']['App']['GetLocalFolderConfig']();
+}
+
 export function GetModifiedTime(arg1) {
   return window['go']['backend']['App']['GetModifiedTime'](arg1);
 }
@@ -98,10 +178,22 @@ export function GetNativeSystemLocale() {
   return window['go']['backend']['App']['GetNativeSystemLocale']();
 }
 
+export function GetNoteRevision(arg1, arg2) {
+  return window['go']['backend']['App']['GetNoteRevision'](arg1, arg2);
+}
+
 export function GetReleaseInfo() {
   return window['go']['backend']['App']['GetReleaseInfo']();
 }
 
+export function GetSyncEncryptionStatus() {
+  return window['go']['backend']['App']['GetSyncEncryptionStatus']();
+}
+
+export function GetSyncHistory(arg1) {
+  return window['go']['backend']['App']['GetSyncHistory'](arg1);
+}
+
 export function GetSystemLocale() {
   return window['go']['backend']['App']['GetSystemLocale']();
 }
@@ -110,6 +202,14 @@ export function GetTopLevelOrder() {
   return window['go']['backend']['App']['GetTopLevelOrder']();
 }
 
+export function GetWebDAVConfig() {
+  return window['go']['backend']['App']['GetWebDAVConfig']();
+}
+
+export function ImportNotes(arg1, arg2) {
+  return window['go']['backend']['App']['ImportNotes'](arg1, arg2);
+}
+
 export function InitializeDrive() {
   return window['go']['backend']['App']['InitializeDrive']();
 }
@@ -118,6 +218,14 @@ export function IsWindowPositionValid(arg1, arg2, arg3, arg4) {
   return window['go']['backend']['App']['IsWindowPositionValid'](arg1, arg2, arg3, arg4);
 }
 
+export function LinkNoteToFile(arg1, arg2) {
+  return window['go']['backend']['App']['LinkNoteToFile'](arg1, arg2);
+}
+
+export function ListBackups() {
+  return window['go']['backend']['App']['ListBackups']();
+}
+
 export function ListCloudConflictBackups() {
   return window['go']['backend']['App']['ListCloudConflictBackups']();
 }
@@ -126,10 +234,38 @@ export function ListFolders() {
   return window['go']['backend']['App']['ListFolders']();
 }
 
+export function ListLinkedFiles() {
+  return window['go']['backend']['App']['ListLinkedFiles']();
+}
+
+export function ListNoteRevisions(arg1) {
+  return window['go']['backend']['App']['ListNoteRevisions'](arg1);
+}
+
 export function ListNotes() {
   return window['go']['backend']['App']['ListNotes']();
 }
 
+export function ListNotesByTag(arg1) {
+  return window['go']['backend']['App']['ListNotesByTag'](arg1);
+}
+
+export function ListProfiles() {
+  return window['go']['backend']['App']['ListProfiles']();
+}
+
+export function ListTags() {
+  return window['go']['backend']['App']['ListTags']();
+}
+
+export function ListTextEncodings() {
+  return window['go']['backend']['App']['ListTextEncodings']();
+}
+
+export function ListTrash() {
+  return window['go']['backend']['App']['ListTrash']();
+}
+
 export function LoadArchivedNote(arg1) {
   return window['go']['backend']['App']['LoadArchivedNote'](arg1);
 }
@@ -150,10 +286,18 @@ export function LoadSettings() {
   return window['go']['backend']['App']['LoadSettings']();
 }
 
+export function LockNote(arg1) {
+  return window['go']['backend']['App']['LockNote'](arg1);
+}
+
 export function LogoutDrive() {
   return window['go']['backend']['App']['LogoutDrive']();
 }
 
+export function MoveFolder(arg1, arg2) {
+  return window['go']['backend']['App']['MoveFolder'](arg1, arg2);
+}
+
 export function MoveNoteToFolder(arg1, arg2) {
   return window['go']['backend']['App']['MoveNoteToFolder'](arg1, arg2);
 }
@@ -186,22 +330,62 @@ export function PerformUpdate(arg1, arg2) {
   return window['go']['backend']['App']['PerformUpdate'](arg1, arg2);
 }
 
+export function ReadFileLines(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['ReadFileLines'](arg1, arg2, arg3);
+}
+
+export function ReadHexDump(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['ReadHexDump'](arg1, arg2, arg3);
+}
+
+export function RegenerateLocalAPIToken() {
+  return window['go']['backend']['App']['RegenerateLocalAPIToken']();
+}
+
 export function RenameFolder(arg1, arg2) {
   return window['go']['backend']['App']['RenameFolder'](arg1, arg2);
 }
 
+export function RenameTag(arg1, arg2) {
+  return window['go']['backend']['App']['RenameTag'](arg1, arg2);
+}
+
+export function ResolveCloudConflictBackup(arg1, arg2) {
+  return window['go']['backend']['App']['ResolveCloudConflictBackup'](arg1, arg2);
+}
+
 export function RespondToMigration(arg1) {
   return window['go']['backend']['App']['RespondToMigration'](arg1);
 }
 
+export function RestoreBackup(arg1) {
+  return window['go']['backend']['App']['RestoreBackup'](arg1);
+}
+
+export function RestoreFromTrash(arg1) {
+  return window['go']['backend']['App']['RestoreFromTrash'](arg1);
+}
+
+export function RestoreNoteRevision(arg1, arg2) {
+  return window['go']['backend']['App']['RestoreNoteRevision'](arg1, arg2);
+}
+
 export function SaveFile(arg1, arg2) {
   return window['go']['backend']['App']['SaveFile'](arg1, arg2);
 }
 
+export function SaveFileChecked(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['SaveFileChecked'](arg1, arg2, arg3);
+}
+
 export function SaveFileNotes(arg1) {
   return window['go']['backend']['App']['SaveFileNotes'](arg1);
 }
 
+export function SaveFileWithEncoding(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['SaveFileWithEncoding'](arg1, arg2, arg3);
+}
+
 export function SaveNote(arg1, arg2) {
   return window['go']['backend']['App']['SaveNote'](arg1, arg2);
 }
@@ -222,18 +406,58 @@ export function SaveWindowState(arg1) {
   return window['go']['backend']['App']['SaveWindowState'](arg1);
 }
 
+export function SearchLargeFile(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['SearchLargeFile'](arg1, arg2, arg3);
+}
+
+export function SearchNotes(arg1, arg2) {
+  return window['go']['backend']['App']['SearchNotes'](arg1, arg2);
+}
+
+export function SelectExportDirectory() {
+  return window['go']['backend']['App']['SelectExportDirectory']();
+}
+
 export function SelectFile() {
   return window['go']['backend']['App']['SelectFile']();
 }
 
+export function SelectImportDirectory() {
+  return window['go']['backend']['App']['SelectImportDirectory']();
+}
+
 export function SelectSaveFileUri(arg1, arg2) {
   return window['go']['backend']['App']['SelectSaveFileUri'](arg1, arg2);
 }
 
+export function SelectSyncFolder() {
+  return window['go']['backend']['App']['SelectSyncFolder']();
+}
+
+export function SetFolderLocalOnly(arg1, arg2) {
+  return window['go']['backend']['App']['SetFolderLocalOnly'](arg1, arg2);
+}
+
 export function SetLastActiveNote(arg1, arg2) {
   return window['go']['backend']['App']['SetLastActiveNote'](arg1, arg2);
 }
 
+export function SetLocalAPIEnabled(arg1) {
+  return window['go']['backend']['App']['SetLocalAPIEnabled'](arg1);
+}
+
+export function SetNoteLocalOnly(arg1, arg2) {
+  return window['go']['backend']['App']['SetNoteLocalOnly'](arg1, arg2);
+}
+
+export function SetSyncProvider(arg1) {
+  return window['go']['backend']['App']['SetSyncProvider'](arg1);
+}
+
+export function SwitchProfile(arg1) {
+  return window['go']['backend']['App']['SwitchProfile'](arg1);
+}
+
 export function SyncNow() {
   return window['go']['backend']['App']['SyncNow']();
 }
@@ -242,6 +466,18 @@ export function UnarchiveFolder(arg1) {
   return window['go']['backend']['App']['UnarchiveFolder'](arg1);
 }
 
+export function UnfollowLargeFile(arg1) {
+  return window['go']['backend']['App']['UnfollowLargeFile'](arg1);
+}
+
+export function UnlinkNote(arg1) {
+  return window['go']['backend']['App']['UnlinkNote'](arg1);
+}
+
+export function UnlockNote(arg1, arg2) {
+  return window['go']['backend']['App']['UnlockNote'](arg1, arg2);
+}
+
 export function UpdateArchivedTopLevelOrder(arg1) {
   return window['go']['backend']['App']['UpdateArchivedTopLevelOrder'](arg1);
 }
@@ -250,6 +486,10 @@ export function UpdateCollapsedFolderIDs(arg1) {
   return window['go']['backend']['App']['UpdateCollapsedFolderIDs'](arg1);
 }
 
+export function UpdateFolderOrder(arg1, arg2) {
+  return window['go']['backend']['App']['UpdateFolderOrder'](arg1, arg2);
+}
+
 export function UpdateNoteOrder(arg1, arg2) {
   return window['go']['backend']['App']['UpdateNoteOrder'](arg1, arg2);
 }
@@ -10,6 +10,9 @@ export namespace backend {
 	    archived: boolean;
 	    folderId?: string;
 	    syncing?: boolean;
+	    tags?: string[];
+	    encrypted?: boolean;
+	    localOnly?: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new Note(source);
@@ -26,8 +29,91 @@ export namespace backend {
 	        this.archived = source["archived"];
 	        this.folderId = source["folderId"];
 	        this.syncing = source["syncing"];
+	        this.tags = source["tags"];
+	        this.encrypted = source["encrypted"];
+	        this.localOnly = source["localOnly"];
 	    }
 	}
+	export class BackupInfo {
+	    path: string;
+	    createdAt: string;
+	    size: number;
+	    noteCount: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.createdAt = source["createdAt"];
+	        this.size = source["size"];
+	        this.noteCount = source["noteCount"];
+	    }
+	}
+	export class DiffLine {
+	    kind: string;
+	    text: string;
+	    oldLine: number;
+	    newLine: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DiffLine(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.text = source["text"];
+	        this.oldLine = source["oldLine"];
+	        this.newLine = source["newLine"];
+	    }
+	}
+	export class ConflictBackupDiff {
+	    filename: string;
+	    kind: string;
+	    backup?: Note;
+	    current?: Note;
+	    cloud?: Note;
+	    lines: DiffLine[];
+	    merged: string;
+	    identical: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConflictBackupDiff(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.filename = source["filename"];
+	        this.kind = source["kind"];
+	        this.backup = this.convertValues(source["backup"], Note);
+	        this.current = this.convertValues(source["current"], Note);
+	        this.cloud = this.convertValues(source["cloud"], Note);
+	        this.lines = this.convertValues(source["lines"], DiffLine);
+	        this.merged = source["merged"];
+	        this.identical = source["identical"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class ConflictBackupEntry {
 	    id: string;
 	    filename: string;
@@ -66,6 +152,20 @@ export namespace backend {
 		    return a;
 		}
 	}
+	export class ConflictResolution {
+	    action: string;
+	    mergedText: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConflictResolution(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.action = source["action"];
+	        this.mergedText = source["mergedText"];
+	    }
+	}
 	export class Context {
 	
 	
@@ -78,6 +178,22 @@ export namespace backend {
 	
 	    }
 	}
+	export class EncodingIssue {
+	    line: number;
+	    column: number;
+	    char: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new EncodingIssue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.line = source["line"];
+	        this.column = source["column"];
+	        this.char = source["char"];
+	    }
+	}
 	export class FileNote {
 	    id: string;
 	    filePath: string;
@@ -86,6 +202,9 @@ export namespace backend {
 	    originalContent: string;
 	    language: string;
 	    modifiedTime: string;
+	    encoding?: string;
+	    bom?: boolean;
+	    lineEnding?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new FileNote(source);
@@ -100,12 +219,95 @@ export namespace backend {
 	        this.originalContent = source["originalContent"];
 	        this.language = source["language"];
 	        this.modifiedTime = source["modifiedTime"];
+	        this.encoding = source["encoding"];
+	        this.bom = source["bom"];
+	        this.lineEnding = source["lineEnding"];
+	    }
+	}
+	export class FileSaveConflict {
+	    filePath: string;
+	    original: string;
+	    disk: string;
+	    buffer: string;
+	    diskModifiedTime: string;
+	    diskLines: DiffLine[];
+	    bufferLines: DiffLine[];
+	    merged: string;
+	    conflicts: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileSaveConflict(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.filePath = source["filePath"];
+	        this.original = source["original"];
+	        this.disk = source["disk"];
+	        this.buffer = source["buffer"];
+	        this.diskModifiedTime = source["diskModifiedTime"];
+	        this.diskLines = this.convertValues(source["diskLines"], DiffLine);
+	        this.bufferLines = this.convertValues(source["bufferLines"], DiffLine);
+	        this.merged = source["merged"];
+	        this.conflicts = source["conflicts"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FileSaveResult {
+	    modifiedTime?: string;
+	    conflict?: FileSaveConflict;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileSaveResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.modifiedTime = source["modifiedTime"];
+	        this.conflict = this.convertValues(source["conflict"], FileSaveConflict);
 	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class Folder {
 	    id: string;
 	    name: string;
 	    archived?: boolean;
+	    parentId?: string;
+	    localOnly?: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new Folder(source);
@@ -116,7 +318,137 @@ export namespace backend {
 	        this.id = source["id"];
 	        this.name = source["name"];
 	        this.archived = source["archived"];
+	        this.parentId = source["parentId"];
+	        this.localOnly = source["localOnly"];
+	    }
+	}
+	export class HexDumpRow {
+	    offset: number;
+	    hex: string;
+	    ascii: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new HexDumpRow(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.offset = source["offset"];
+	        this.hex = source["hex"];
+	        this.ascii = source["ascii"];
+	    }
+	}
+	export class HexDump {
+	    filePath: string;
+	    offset: number;
+	    size: number;
+	    rows: HexDumpRow[];
+	
+	    static createFrom(source: any = {}) {
+	        return new HexDump(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.filePath = source["filePath"];
+	        this.offset = source["offset"];
+	        this.size = source["size"];
+	        this.rows = this.convertValues(source["rows"], HexDumpRow);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ImportItem {
+	    source: string;
+	    title: string;
+	    language: string;
+	    folder: string;
+	    status: string;
+	    reason?: string;
+	    noteId?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.source = source["source"];
+	        this.title = source["title"];
+	        this.language = source["language"];
+	        this.folder = source["folder"];
+	        this.status = source["status"];
+	        this.reason = source["reason"];
+	        this.noteId = source["noteId"];
+	    }
+	}
+	export class ImportOptions {
+	    dryRun: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.dryRun = source["dryRun"];
+	    }
+	}
+	export class ImportReport {
+	    dryRun: boolean;
+	    items: ImportItem[];
+	    importedCount: number;
+	    duplicateCount: number;
+	    skippedCount: number;
+	    folderCount: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.dryRun = source["dryRun"];
+	        this.items = this.convertValues(source["items"], ImportItem);
+	        this.importedCount = source["importedCount"];
+	        this.duplicateCount = source["duplicateCount"];
+	        this.skippedCount = source["skippedCount"];
+	        this.folderCount = source["folderCount"];
 	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class IntegrityFixSelection {
 	    issueId: string;
@@ -150,73 +482,462 @@ export namespace backend {
 	        this.messages = source["messages"];
 	    }
 	}
+	export class LargeFileLines {
+	    filePath: string;
+	    start: number;
+	    lines: string[];
+	    totalLines: number;
+	    size: number;
+	    truncated: boolean;
 	
-	export class OpenFileResult {
-	    content: string;
-	    sourceEncoding: string;
+	    static createFrom(source: any = {}) {
+	        return new LargeFileLines(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.filePath = source["filePath"];
+	        this.start = source["start"];
+	        this.lines = source["lines"];
+	        this.totalLines = source["totalLines"];
+	        this.size = source["size"];
+	        this.truncated = source["truncated"];
+	    }
+	}
+	export class LargeFileSearchOptions {
+	    caseSensitive?: boolean;
+	    regex?: boolean;
+	    limit?: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new OpenFileResult(source);
+	        return new LargeFileSearchOptions(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.content = source["content"];
-	        this.sourceEncoding = source["sourceEncoding"];
+	        this.caseSensitive = source["caseSensitive"];
+	        this.regex = source["regex"];
+	        this.limit = source["limit"];
 	    }
 	}
-	export class ReleaseInfo {
-	    version: string;
-	    body: string;
-	    downloadUrl: string;
-	    assetName: string;
+	export class SearchMatch {
+	    field: string;
+	    line: number;
+	    column: number;
+	    length: number;
+	    snippet: string;
+	    snippetMatchStart: number;
+	    snippetMatchLength: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new ReleaseInfo(source);
+	        return new SearchMatch(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.version = source["version"];
-	        this.body = source["body"];
-	        this.downloadUrl = source["downloadUrl"];
-	        this.assetName = source["assetName"];
+	        this.field = source["field"];
+	        this.line = source["line"];
+	        this.column = source["column"];
+	        this.length = source["length"];
+	        this.snippet = source["snippet"];
+	        this.snippetMatchStart = source["snippetMatchStart"];
+	        this.snippetMatchLength = source["snippetMatchLength"];
 	    }
 	}
-	export class Settings {
-	    fontFamily: string;
-	    fontSize: number;
-	    isDarkMode: boolean;
-	    editorTheme: string;
-	    wordWrap: string;
-	    minimap: boolean;
-	    windowWidth: number;
-	    windowHeight: number;
-	    windowX: number;
-	    windowY: number;
-	    isMaximized: boolean;
-	    isDebug: boolean;
-	    enableConflictBackup: boolean;
-	    markdownPreviewOnLeft: boolean;
-	    sidebarWidth?: number;
-	    splitPaneSize?: number;
-	    markdownPreviewPaneSize?: number;
-	    markdownPreviewVisible?: boolean;
-	    isSplit?: boolean;
-	    uiLanguage?: string;
-	    lastActiveNoteId?: string;
-	    lastActiveNoteIsFile?: boolean;
+	export class LargeFileSearchResult {
+	    matches: SearchMatch[];
+	    truncated: boolean;
 	
 	    static createFrom(source: any = {}) {
-	        return new Settings(source);
+	        return new LargeFileSearchResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.fontFamily = source["fontFamily"];
-	        this.fontSize = source["fontSize"];
-	        this.isDarkMode = source["isDarkMode"];
-	        this.editorTheme = source["editorTheme"];
+	        this.matches = this.convertValues(source["matches"], SearchMatch);
+	        this.truncated = source["truncated"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LinkedFile {
+	    noteId: string;
+	    filePath: string;
+	    modifiedTime?: string;
+	    size: number;
+	    hash?: string;
+	    encoding?: string;
+	    bom?: boolean;
+	    lineEnding?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LinkedFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.noteId = source["noteId"];
+	        this.filePath = source["filePath"];
+	        this.modifiedTime = source["modifiedTime"];
+	        this.size = source["size"];
+	        this.hash = source["hash"];
+	        this.encoding = source["encoding"];
+	        this.bom = source["bom"];
+	        this.lineEnding = source["lineEnding"];
+	    }
+	}
+	export class LinkedFileEvent {
+	    noteId: string;
+	    filePath: string;
+	    note?: Note;
+	    conflict?: boolean;
+	    backupFile?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LinkedFileEvent(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.noteId = source["noteId"];
+	        this.filePath = source["filePath"];
+	        this.note = this.convertValues(source["note"], Note);
+	        this.conflict = source["conflict"];
+	        this.backupFile = source["backupFile"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LocalAPIInfo {
+	    enabled: boolean;
+	    running: boolean;
+	    url?: string;
+	    token?: string;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LocalAPIInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.running = source["running"];
+	        this.url = source["url"];
+	        this.token = source["token"];
+	        this.error = source["error"];
+	    }
+	}
+	export class LocalFolderConfig {
+	    path: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LocalFolderConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	    }
+	}
+	export class NoteMetadata {
+	    id: string;
+	    title: string;
+	    contentHeader: string;
+	    language: string;
+	    modifiedTime: string;
+	    archived: boolean;
+	    contentHash: string;
+	    folderId?: string;
+	    tags?: string[];
+	    encrypted?: boolean;
+	    localOnly?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new NoteMetadata(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	        this.contentHeader = source["contentHeader"];
+	        this.language = source["language"];
+	        this.modifiedTime = source["modifiedTime"];
+	        this.archived = source["archived"];
+	        this.contentHash = source["contentHash"];
+	        this.folderId = source["folderId"];
+	        this.tags = source["tags"];
+	        this.encrypted = source["encrypted"];
+	        this.localOnly = source["localOnly"];
+	    }
+	}
+	export class NoteRevision {
+	    id: string;
+	    noteId: string;
+	    title: string;
+	    language: string;
+	    content?: string;
+	    contentHash: string;
+	    size: number;
+	    source: string;
+	    createdAt: string;
+	    updatedAt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new NoteRevision(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.noteId = source["noteId"];
+	        this.title = source["title"];
+	        this.language = source["language"];
+	        this.content = source["content"];
+	        this.contentHash = source["contentHash"];
+	        this.size = source["size"];
+	        this.source = source["source"];
+	        this.createdAt = source["createdAt"];
+	        this.updatedAt = source["updatedAt"];
+	    }
+	}
+	
+	export class OpenFileResult {
+	    content: string;
+	    sourceEncoding: string;
+	    encoding: string;
+	    bom: boolean;
+	    lineEnding: string;
+	    largeFile?: boolean;
+	    binary?: boolean;
+	    binaryFormat?: string;
+	    size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new OpenFileResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.content = source["content"];
+	        this.sourceEncoding = source["sourceEncoding"];
+	        this.encoding = source["encoding"];
+	        this.bom = source["bom"];
+	        this.lineEnding = source["lineEnding"];
+	        this.largeFile = source["largeFile"];
+	        this.binary = source["binary"];
+	        this.binaryFormat = source["binaryFormat"];
+	        this.size = source["size"];
+	    }
+	}
+	export class Profile {
+	    id: string;
+	    name: string;
+	    createdAt?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Profile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.createdAt = source["createdAt"];
+	    }
+	}
+	export class ProfileList {
+	    activeId: string;
+	    profiles: Profile[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProfileList(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.activeId = source["activeId"];
+	        this.profiles = this.convertValues(source["profiles"], Profile);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ReleaseInfo {
+	    version: string;
+	    body: string;
+	    downloadUrl: string;
+	    assetName: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReleaseInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.body = source["body"];
+	        this.downloadUrl = source["downloadUrl"];
+	        this.assetName = source["assetName"];
+	    }
+	}
+	export class SearchHit {
+	    noteId: string;
+	    title: string;
+	    folderId?: string;
+	    language: string;
+	    archived: boolean;
+	    modifiedTime: string;
+	    score: number;
+	    matchCount: number;
+	    matches: SearchMatch[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchHit(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.noteId = source["noteId"];
+	        this.title = source["title"];
+	        this.folderId = source["folderId"];
+	        this.language = source["language"];
+	        this.archived = source["archived"];
+	        this.modifiedTime = source["modifiedTime"];
+	        this.score = source["score"];
+	        this.matchCount = source["matchCount"];
+	        this.matches = this.convertValues(source["matches"], SearchMatch);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SearchOptions {
+	    folderId?: string;
+	    language?: string;
+	    archived?: string;
+	    limit?: number;
+	    maxMatchesPerNote?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.folderId = source["folderId"];
+	        this.language = source["language"];
+	        this.archived = source["archived"];
+	        this.limit = source["limit"];
+	        this.maxMatchesPerNote = source["maxMatchesPerNote"];
+	    }
+	}
+	export class Settings {
+	    fontFamily: string;
+	    fontSize: number;
+	    isDarkMode: boolean;
+	    editorTheme: string;
+	    wordWrap: string;
+	    minimap: boolean;
+	    windowWidth: number;
+	    windowHeight: number;
+	    windowX: number;
+	    windowY: number;
+	    isMaximized: boolean;
+	    isDebug: boolean;
+	    enableConflictBackup: boolean;
+	    markdownPreviewOnLeft: boolean;
+	    sidebarWidth?: number;
+	    splitPaneSize?: number;
+	    markdownPreviewPaneSize?: number;
+	    markdownPreviewVisible?: boolean;
+	    isSplit?: boolean;
+	    uiLanguage?: string;
+	    lastActiveNoteId?: string;
+	    lastActiveNoteIsFile?: boolean;
+	    localApiEnabled?: boolean;
+	    localApiPort?: number;
+	    localApiToken?: string;
+	    syncProvider?: string;
+	    trashRetentionDays?: number;
+	    autoBackupIntervalHours?: number;
+	    autoBackupKeep?: number;
+	    fileBackupMode?: string;
+	    fileBackupKeep?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Settings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.fontFamily = source["fontFamily"];
+	        this.fontSize = source["fontSize"];
+	        this.isDarkMode = source["isDarkMode"];
+	        this.editorTheme = source["editorTheme"];
 	        this.wordWrap = source["wordWrap"];
 	        this.minimap = source["minimap"];
 	        this.windowWidth = source["windowWidth"];
@@ -235,6 +956,131 @@ export namespace backend {
 	        this.uiLanguage = source["uiLanguage"];
 	        this.lastActiveNoteId = source["lastActiveNoteId"];
 	        this.lastActiveNoteIsFile = source["lastActiveNoteIsFile"];
+	        this.localApiEnabled = source["localApiEnabled"];
+	        this.localApiPort = source["localApiPort"];
+	        this.localApiToken = source["localApiToken"];
+	        this.syncProvider = source["syncProvider"];
+	        this.trashRetentionDays = source["trashRetentionDays"];
+	        this.autoBackupIntervalHours = source["autoBackupIntervalHours"];
+	        this.autoBackupKeep = source["autoBackupKeep"];
+	        this.fileBackupMode = source["fileBackupMode"];
+	        this.fileBackupKeep = source["fileBackupKeep"];
+	    }
+	}
+	export class SyncAction {
+	    time: string;
+	    noteId: string;
+	    title: string;
+	    action: string;
+	    reason?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SyncAction(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.time = source["time"];
+	        this.noteId = source["noteId"];
+	        this.title = source["title"];
+	        this.action = source["action"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class SyncEncryptionStatus {
+	    enabled: boolean;
+	    locked: boolean;
+	    keyId?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SyncEncryptionStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.locked = source["locked"];
+	        this.keyId = source["keyId"];
+	    }
+	}
+	export class SyncRun {
+	    id: string;
+	    device: string;
+	    trigger: string;
+	    mode: string;
+	    startedAt: string;
+	    finishedAt: string;
+	    result: string;
+	    error?: string;
+	    actions: SyncAction[];
+	    droppedActions?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SyncRun(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.device = source["device"];
+	        this.trigger = source["trigger"];
+	        this.mode = source["mode"];
+	        this.startedAt = source["startedAt"];
+	        this.finishedAt = source["finishedAt"];
+	        this.result = source["result"];
+	        this.error = source["error"];
+	        this.actions = this.convertValues(source["actions"], SyncAction);
+	        this.droppedActions = source["droppedActions"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TagCount {
+	    tag: string;
+	    count: number;
+	    archivedCount: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TagCount(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.tag = source["tag"];
+	        this.count = source["count"];
+	        this.archivedCount = source["archivedCount"];
+	    }
+	}
+	export class TextFormat {
+	    encoding: string;
+	    bom?: boolean;
+	    lineEnding?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TextFormat(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.encoding = source["encoding"];
+	        this.bom = source["bom"];
+	        this.lineEnding = source["lineEnding"];
 	    }
 	}
 	export class TopLevelItem {
@@ -251,6 +1097,94 @@ export namespace backend {
 	        this.id = source["id"];
 	    }
 	}
+	export class TrashItem {
+	    id: string;
+	    type: string;
+	    deletedAt: string;
+	    order: number;
+	    notes?: NoteMetadata[];
+	    folders?: Folder[];
+	
+	    static createFrom(source: any = {}) {
+	        return new TrashItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.type = source["type"];
+	        this.deletedAt = source["deletedAt"];
+	        this.order = source["order"];
+	        this.notes = this.convertValues(source["notes"], NoteMetadata);
+	        this.folders = this.convertValues(source["folders"], Folder);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class VaultExportOptions {
+	    frontMatter: boolean;
+	    includeArchived: boolean;
+	    orderPrefix: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new VaultExportOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.frontMatter = source["frontMatter"];
+	        this.includeArchived = source["includeArchived"];
+	        this.orderPrefix = source["orderPrefix"];
+	    }
+	}
+	export class VaultExportResult {
+	    noteCount: number;
+	    folderCount: number;
+	    encryptedCount: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new VaultExportResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.noteCount = source["noteCount"];
+	        this.folderCount = source["folderCount"];
+	        this.encryptedCount = source["encryptedCount"];
+	    }
+	}
+	export class WebDAVConfig {
+	    url: string;
+	    username?: string;
+	    password?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new WebDAVConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	    }
+	}
 
 }
 
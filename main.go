@@ -20,6 +20,11 @@ import (
 var assets embed.FS
 
 func main() {
+	// `monaco-notepad notes ...` はウィンドウを開かずにノートを操作して終了する
+	if len(os.Args) > 1 && os.Args[1] == "notes" {
+		os.Exit(backend.RunNotesCLI(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	// macOS のウィンドウクローズ挙動パッチを適用する。
 	// 実装は backend パッケージに集約し、main は起動シーケンスのみを担当する。
 	backend.ApplyMacWindowClosePatch()
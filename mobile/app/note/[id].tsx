@@ -498,6 +498,9 @@ export default function NoteEditorScreen() {
 		);
 	}
 
+	// デスクトップ版でロックしたノートは本文が暗号文なので、表示も編集もしない（アーカイブ・削除は可）
+	const locked = note.encrypted === true;
+
 	const handleDelete = async () => {
 		await driveService.deleteNoteAndSync(note.id);
 		router.back();
@@ -589,6 +592,7 @@ export default function NoteEditorScreen() {
 				<Appbar.Content title="" />
 				<Pressable
 					onPress={() => setLangMenuOpen(true)}
+					disabled={locked}
 					android_ripple={{ color: theme.colors.surfaceVariant }}
 					style={[
 						styles.langButton,
@@ -612,7 +616,7 @@ export default function NoteEditorScreen() {
 						color={theme.colors.onSurfaceVariant}
 					/>
 				</Pressable>
-				{mode === 'view' && (
+				{mode === 'view' && !locked && (
 					<Appbar.Action icon="content-copy" onPress={handleCopyAll} />
 				)}
 				<Appbar.Action
@@ -640,6 +644,7 @@ export default function NoteEditorScreen() {
 						label={t('editor.titleLabel')}
 						placeholder={t('editor.titlePlaceholder')}
 						value={note.title}
+						editable={!locked}
 						onChangeText={(title) =>
 							scheduleSave({
 								...note,
@@ -664,7 +669,24 @@ export default function NoteEditorScreen() {
 					/>
 				</>
 			)}
-			{mode === 'view' ? (
+			{locked ? (
+				<View style={styles.lockedNotice}>
+					<Icon
+						source="lock"
+						size={32}
+						color={theme.colors.onSurfaceVariant}
+					/>
+					<Text
+						variant="bodyMedium"
+						style={[
+							styles.lockedNoticeText,
+							{ color: theme.colors.onSurfaceVariant },
+						]}
+					>
+						{t('editor.lockedNote')}
+					</Text>
+				</View>
+			) : mode === 'view' ? (
 				<ScrollView
 					style={styles.viewer}
 					contentContainerStyle={styles.viewerContent}
@@ -775,41 +797,43 @@ export default function NoteEditorScreen() {
 				}}
 				onDismiss={() => setLangMenuOpen(false)}
 			/>
-			<Animated.View
-				style={[
-					styles.floatingModeBar,
-					{
-						backgroundColor: theme.colors.background,
-						shadowColor: '#000',
-					},
-					floatingBarStyle,
-				]}
-				pointerEvents="box-none"
-			>
-				<SegmentedButtons
-					value={mode}
-					onValueChange={(v) => {
-						// 編集中 TextInput がフォーカス状態のまま unmount されると
-						// Android が次の TextInput（= タイトル）にフォーカスを移してしまう。
-						// 明示的に keyboard を dismiss してフォーカスを外す。
-						Keyboard.dismiss();
-						setMode(v as Mode);
-					}}
-					buttons={[
-						{ value: 'view', label: t('editor.view'), icon: 'eye' },
+			{!locked && (
+				<Animated.View
+					style={[
+						styles.floatingModeBar,
 						{
-							value: 'edit',
-							label: t('editor.edit'),
-							icon: 'pencil',
-							checkedColor: theme.colors.onPrimary,
-							style:
-								mode === 'edit'
-									? { backgroundColor: theme.colors.primary }
-									: undefined,
+							backgroundColor: theme.colors.background,
+							shadowColor: '#000',
 						},
+						floatingBarStyle,
 					]}
-				/>
-			</Animated.View>
+					pointerEvents="box-none"
+				>
+					<SegmentedButtons
+						value={mode}
+						onValueChange={(v) => {
+							// 編集中 TextInput がフォーカス状態のまま unmount されると
+							// Android が次の TextInput（= タイトル）にフォーカスを移してしまう。
+							// 明示的に keyboard を dismiss してフォーカスを外す。
+							Keyboard.dismiss();
+							setMode(v as Mode);
+						}}
+						buttons={[
+							{ value: 'view', label: t('editor.view'), icon: 'eye' },
+							{
+								value: 'edit',
+								label: t('editor.edit'),
+								icon: 'pencil',
+								checkedColor: theme.colors.onPrimary,
+								style:
+									mode === 'edit'
+										? { backgroundColor: theme.colors.primary }
+										: undefined,
+							},
+						]}
+					/>
+				</Animated.View>
+			)}
 			<Snackbar
 				visible={snackbarVisible}
 				onDismiss={() => setSnackbarVisible(false)}
@@ -961,4 +985,14 @@ const styles = StyleSheet.create({
 		justifyContent: 'center',
 		padding: 24,
 	},
+	lockedNotice: {
+		flex: 1,
+		alignItems: 'center',
+		justifyContent: 'center',
+		padding: 24,
+		gap: 12,
+	},
+	lockedNoticeText: {
+		textAlign: 'center',
+	},
 });
@@ -6,10 +6,14 @@ import { Icon, IconButton, Text, useTheme } from 'react-native-paper';
 import { runOnJS } from 'react-native-reanimated';
 import type { Folder } from '@/services/sync/types';
 
+const DEPTH_INDENT = 16;
+
 interface Props {
 	folder: Folder;
 	noteCount: number;
 	collapsed: boolean;
+	/** 親フォルダの数。入れ子のフォルダは深さに応じて字下げする */
+	depth?: number;
 	onToggle: (folderId: string) => void;
 	onMorePress?: (e: GestureResponderEvent, folderId: string) => void;
 }
@@ -29,6 +33,7 @@ function FolderListItemImpl({
 	folder,
 	noteCount,
 	collapsed,
+	depth = 0,
 	onToggle,
 	onMorePress,
 }: Props) {
@@ -44,7 +49,9 @@ function FolderListItemImpl({
 		[folder.id, onToggle],
 	);
 	return (
-		<View style={styles.row}>
+		<View
+			style={[styles.row, depth > 0 && { paddingLeft: depth * DEPTH_INDENT }]}
+		>
 			{/* 折り畳みトグル: chevron 部分のみ。
 			    注: pressed state による bg 切替は意図的に外している。tap → pressed=true →
 			    pressed=false の遷移で React 再レンダリング → folder-header 全体の View が
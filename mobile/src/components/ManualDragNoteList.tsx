@@ -112,7 +112,8 @@ function flatRowsEqual(a: FlatRow, b: FlatRow): boolean {
 			a.folder === b.folder &&
 			a.noteCount === b.noteCount &&
 			a.collapsed === b.collapsed &&
-			a.inGroupEnd === b.inGroupEnd
+			a.inGroupEnd === b.inGroupEnd &&
+			a.depth === b.depth
 		);
 	}
 	if (a.kind === 'folder-child' && b.kind === 'folder-child') {
@@ -237,7 +238,11 @@ function buildRowMetrics(
 function countTopLevelBeforeSlot(rows: FlatRow[], slot: number) {
 	let count = 0;
 	for (let i = 0; i < Math.min(slot, rows.length); i++) {
-		if (rows[i].kind !== 'folder-child') count++;
+		const row = rows[i];
+		if (row.kind === 'folder-child') continue;
+		// 入れ子のフォルダの見出しは topLevelOrder に載らない
+		if (row.kind === 'folder-header' && row.depth > 0) continue;
+		count++;
 	}
 	return count;
 }
@@ -1011,6 +1016,7 @@ export function ManualDragNoteList({
 							folder={item.folder}
 							noteCount={item.noteCount}
 							collapsed={item.collapsed}
+							depth={item.depth}
 							onToggle={
 								overlayFolderId === undefined
 									? onToggleFolder
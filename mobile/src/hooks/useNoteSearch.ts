@@ -82,7 +82,9 @@ export function useNoteSearch(
 				try {
 					const note = await noteService.readNote(meta.id);
 					if (note && !cancelled) {
-						const text = `${note.title}\n${note.content}`.toLowerCase();
+						// 暗号化したノートは暗号文を検索対象にせず、タイトルだけで探す（デスクトップ版と同じ）
+						const body = note.encrypted ? '' : note.content;
+						const text = `${note.title}\n${body}`.toLowerCase();
 						sharedIndex.set(meta.id, { hash: meta.contentHash, text });
 					}
 				} catch (e) {
@@ -3,7 +3,7 @@ import { describe, expect, it } from 'vitest';
 import { ensureDir } from '@/services/storage/atomicFile';
 import { NOTES_DIR, noteFilePath } from '@/services/storage/paths';
 import { makeNote } from '@/test/helpers';
-import { NoteService } from '../noteService';
+import { LockedNoteError, NoteService } from '../noteService';
 
 async function fresh(): Promise<NoteService> {
 	const s = new NoteService();
@@ -47,6 +47,42 @@ describe('NoteService', () => {
 		expect(got?.content).toBe('hello');
 	});
 
+	it('デスクトップ版で付けた tags は保存・読込・メタデータで保持される', async () => {
+		const s = await fresh();
+		await s.saveNote(makeNote({ id: 'a', tags: ['go', 'memo'] }));
+		expect((await s.readNote('a'))?.tags).toEqual(['go', 'memo']);
+		expect(s.getNoteList().notes[0].tags).toEqual(['go', 'memo']);
+	});
+
+	it('デスクトップ版でロックしたノートは encrypted を保持し、平文での上書きを拒否する', async () => {
+		const s = await fresh();
+		const ciphertext =
+			'{"format":"monaco-notepad-locked-note-v1","salt":"","nonce":"","data":""}';
+		await s.saveNoteFromSync(
+			makeNote({ id: 'a', content: ciphertext, encrypted: true }),
+		);
+
+		const stored = await s.readNote('a');
+		expect(stored?.encrypted).toBe(true);
+		expect(s.getNoteList().notes[0]).toMatchObject({
+			encrypted: true,
+			contentHeader: '',
+		});
+
+		// アーカイブなど暗号文のままの保存はできる
+		await s.saveNote({ ...stored!, archived: true });
+		expect(s.getNoteList().notes[0].archived).toBe(true);
+
+		// 本文の編集・フラグの落ちた保存は拒否する
+		await expect(
+			s.saveNote({ ...stored!, content: 'plain' }),
+		).rejects.toBeInstanceOf(LockedNoteError);
+		await expect(
+			s.saveNote({ ...stored!, content: 'plain', encrypted: undefined }),
+		).rejects.toBeInstanceOf(LockedNoteError);
+		expect((await s.readNote('a'))?.content).toBe(ciphertext);
+	});
+
 	it('deleteNote で notes/{id}.json と noteList から消える', async () => {
 		const s = await fresh();
 		await s.saveNote(makeNote({ id: 'a' }));
@@ -127,6 +163,142 @@ describe('NoteService', () => {
 		expect(s.getNoteList().notes).toHaveLength(0);
 	});
 
+	it('デスクトップ版の 3.0 形式（入れ子のフォルダ）を保存して読み直せる', async () => {
+		const s = await fresh();
+		await s.replaceNoteList({
+			version: '3.0',
+			notes: [],
+			folders: [
+				{ id: 'p', name: 'Parent', archived: false },
+				{ id: 'c', name: 'Child', archived: false, parentId: 'p' },
+			],
+			topLevelOrder: [{ type: 'folder', id: 'p' }],
+			archivedTopLevelOrder: [],
+			collapsedFolderIds: [],
+		});
+
+		const reloaded = new NoteService();
+		await reloaded.load();
+		const list = reloaded.getNoteList();
+		expect(list.version).toBe('3.0');
+		expect(list.folders.find((f) => f.id === 'c')?.parentId).toBe('p');
+	});
+
+	it('フォルダのアーカイブ・復元・削除は入れ子のフォルダにも及ぶ', async () => {
+		const s = await fresh();
+		await s.replaceNoteList({
+			version: '3.0',
+			notes: [],
+			folders: [
+				{ id: 'p', name: 'Parent', archived: false },
+				{ id: 'c', name: 'Child', archived: false, parentId: 'p' },
+			],
+			topLevelOrder: [{ type: 'folder', id: 'p' }],
+			archivedTopLevelOrder: [],
+			collapsedFolderIds: [],
+		});
+		await s.saveNote(makeNote({ id: 'n', folderId: 'c' }));
+
+		expect(await s.archiveFolder('p')).toEqual(['n']);
+		let list = s.getNoteList();
+		expect(list.folders.every((f) => f.archived)).toBe(true);
+		expect(list.archivedTopLevelOrder).toEqual([{ type: 'folder', id: 'p' }]);
+
+		expect(await s.restoreFolder('p')).toEqual(['n']);
+		list = s.getNoteList();
+		expect(list.folders.some((f) => f.archived)).toBe(false);
+		expect(list.topLevelOrder).toEqual([{ type: 'folder', id: 'p' }]);
+
+		// 親を削除すると子フォルダはトップレベルへ繰り上がる
+		await s.deleteFolder('p');
+		list = s.getNoteList();
+		expect(list.folders).toEqual([{ id: 'c', name: 'Child', archived: false }]);
+		expect(list.topLevelOrder).toEqual([{ type: 'folder', id: 'c' }]);
+	});
+
+	it('trashNote はノートをゴミ箱に移し、本文ファイルは残す', async () => {
+		const s = await fresh();
+		await s.saveNote(makeNote({ id: 'a', title: 'A' }));
+		await s.trashNote('a');
+
+		const list = s.getNoteList();
+		expect(list.notes).toHaveLength(0);
+		expect(list.topLevelOrder).toEqual([]);
+		expect(list.trash).toHaveLength(1);
+		expect(list.trash?.[0]).toMatchObject({
+			id: 'a',
+			type: 'note',
+			order: 0,
+		});
+		expect(list.trash?.[0].notes?.[0].title).toBe('A');
+		expect(s.trashedNoteIds().has('a')).toBe(true);
+		expect(await s.readNote('a')).not.toBeNull();
+	});
+
+	it('trashFolder は入れ子のフォルダと配下のノートをまとめてゴミ箱に移す', async () => {
+		const s = await fresh();
+		await s.replaceNoteList({
+			version: '3.0',
+			notes: [],
+			folders: [
+				{ id: 'p', name: 'Parent', archived: false },
+				{ id: 'c', name: 'Child', archived: false, parentId: 'p' },
+			],
+			topLevelOrder: [{ type: 'folder', id: 'p' }],
+			archivedTopLevelOrder: [],
+			collapsedFolderIds: [],
+		});
+		await s.saveNote(makeNote({ id: 'n', folderId: 'c' }));
+
+		expect(await s.trashFolder('p')).toEqual(['n']);
+		const list = s.getNoteList();
+		expect(list.folders).toEqual([]);
+		expect(list.notes).toEqual([]);
+		expect(list.topLevelOrder).toEqual([]);
+		expect(list.trash).toHaveLength(1);
+		expect(list.trash?.[0]).toMatchObject({
+			id: 'p',
+			type: 'folder',
+			order: 0,
+		});
+		expect(list.trash?.[0].folders?.map((f) => f.id)).toEqual(['p', 'c']);
+		expect(list.trash?.[0].notes?.map((n) => n.id)).toEqual(['n']);
+		expect(await s.readNote('n')).not.toBeNull();
+	});
+
+	it('deleteUnlistedTrashNotes はゴミ箱から外れたノートの本文ファイルだけを消す', async () => {
+		const s = await fresh();
+		await s.saveNote(makeNote({ id: 'a' }));
+		await s.saveNote(makeNote({ id: 'b' }));
+		await s.trashNote('a');
+		await s.trashNote('b');
+		const previousTrash = s.getNoteList().trash ?? [];
+
+		// 他端末で a だけゴミ箱から完全に削除された
+		await s.replaceNoteList({
+			...s.getNoteList(),
+			trash: previousTrash.filter((i) => i.id !== 'a'),
+		});
+		expect(await s.deleteUnlistedTrashNotes(previousTrash)).toEqual(['a']);
+		expect(await s.readNote('a')).toBeNull();
+		expect(await s.readNote('b')).not.toBeNull();
+	});
+
+	it('trash を持たない旧形式の noteList を読むとゴミ箱は空になる', async () => {
+		const s = await fresh();
+		await s.replaceNoteList({
+			version: '3.0',
+			notes: [],
+			folders: [],
+			topLevelOrder: [],
+			archivedTopLevelOrder: [],
+			collapsedFolderIds: [],
+		});
+		const reloaded = new NoteService();
+		await reloaded.load();
+		expect(reloaded.getNoteList().trash).toEqual([]);
+	});
+
 	it('replaceNoteListInMemory は永続化せずメモリだけ差し替える', async () => {
 		const s = await fresh();
 		await s.saveNote(makeNote({ id: 'a', title: 'persisted' }));
@@ -12,12 +12,24 @@ import { computeContentHash } from '../sync/hash';
 import {
 	EMPTY_NOTE_LIST,
 	type Folder,
+	isLockedNoteContent,
+	isSupportedNoteListVersion,
 	type Note,
 	type NoteList,
 	type NoteMetadata,
 	ORPHAN_FOLDER_NAME,
+	type TrashItem,
+	withEncrypted,
+	withTags,
 } from '../sync/types';
 
+/** デスクトップ版で暗号化したノートを平文で保存しようとした（モバイル版では読み取り専用）。 */
+export class LockedNoteError extends Error {
+	constructor(readonly noteId: string) {
+		super(`note ${noteId} is locked`);
+	}
+}
+
 /**
  * ノート本文から contentHeader を生成する。
  * デスクトップ版 backend/note_service.go の generateContentHeader と同じ挙動：
@@ -37,6 +49,20 @@ export function generateContentHeader(content: string): string {
 	return header.length > 200 ? header.slice(0, 200) : header;
 }
 
+/** ゴミ箱のアイテムに含まれる全ノートの ID を返す。 */
+export function trashNoteIds(trash: TrashItem[]): Set<string> {
+	const ids = new Set<string>();
+	for (const item of trash) {
+		for (const n of item.notes ?? []) ids.add(n.id);
+	}
+	return ids;
+}
+
+/** デスクトップ版 time.RFC3339 と同じ秒精度の UTC 日時。 */
+function rfc3339Now(): string {
+	return new Date().toISOString().replace(/\.\d{3}Z$/, 'Z');
+}
+
 /**
  * ローカルノートの CRUD とメタデータ管理。
  *
@@ -55,10 +81,12 @@ export class NoteService {
 		if (raw) {
 			try {
 				const parsed = JSON.parse(raw) as NoteList;
-				if (parsed.version === 'v2') {
+				if (isSupportedNoteListVersion(parsed.version)) {
 					this.list = {
 						...EMPTY_NOTE_LIST,
 						...parsed,
+						// 旧モバイル版の noteList.json には trash が無い（EMPTY_NOTE_LIST の配列を共有しない）
+						trash: Array.isArray(parsed.trash) ? parsed.trash : [],
 					};
 				}
 			} catch (e) {
@@ -255,6 +283,8 @@ export class NoteService {
 				modifiedTime: parsed.modifiedTime ?? new Date().toISOString(),
 				archived: parsed.archived ?? false,
 				folderId: parsed.folderId ?? '',
+				...withTags(parsed.tags),
+				...withEncrypted(parsed.encrypted, parsed.content ?? ''),
 			};
 		} catch {
 			return null;
@@ -265,15 +295,27 @@ export class NoteService {
 		note: Note,
 		opts?: { prependToOrder?: boolean },
 	): Promise<void> {
-		await ensureDir(NOTES_DIR);
-		const { syncing: _s, ...persist } = note;
-		await writeAtomic(noteFilePath(note.id), JSON.stringify(persist));
-		await this.upsertMetadata(note, opts?.prependToOrder ?? false);
+		// 暗号化したノートはモバイル版では編集できない。暗号文を平文で上書きしないよう拒否する
+		const current = this.list.notes.find((n) => n.id === note.id);
+		if (
+			(note.encrypted || current?.encrypted) &&
+			!isLockedNoteContent(note.content)
+		) {
+			throw new LockedNoteError(note.id);
+		}
+		await this.writeNote(note, opts?.prependToOrder ?? false);
 	}
 
 	/** クラウドから降ってきたノートをローカルへ上書き保存（dirty にはしない）。 */
 	async saveNoteFromSync(note: Note): Promise<void> {
-		await this.saveNote(note);
+		await this.writeNote(note, false);
+	}
+
+	private async writeNote(note: Note, prependToOrder: boolean): Promise<void> {
+		await ensureDir(NOTES_DIR);
+		const { syncing: _s, ...persist } = note;
+		await writeAtomic(noteFilePath(note.id), JSON.stringify(persist));
+		await this.upsertMetadata(note, prependToOrder);
 	}
 
 	async deleteNote(noteId: string): Promise<void> {
@@ -288,6 +330,70 @@ export class NoteService {
 		await this.persistList();
 	}
 
+	/**
+	 * ノートをゴミ箱に移す（デスクトップ版 TrashNote）。一覧と order からは外すが、
+	 * 本文ファイルはローカル・クラウドとも残す。完全な削除（ゴミ箱を空にする・保持期間切れ）は
+	 * デスクトップ版が行い、同期で noteList.trash から外れたときに deleteUnlistedTrashNotes で消える。
+	 */
+	async trashNote(noteId: string): Promise<void> {
+		const meta = this.list.notes.find((n) => n.id === noteId);
+		if (!meta) return;
+		const item: TrashItem = {
+			id: noteId,
+			type: 'note',
+			deletedAt: rfc3339Now(),
+			order: meta.folderId
+				? -1
+				: this.topLevelPosition('note', noteId, meta.archived),
+			notes: [{ ...meta }],
+		};
+		this.list.notes = this.list.notes.filter((n) => n.id !== noteId);
+		this.list.topLevelOrder = this.list.topLevelOrder.filter(
+			(i) => !(i.type === 'note' && i.id === noteId),
+		);
+		this.list.archivedTopLevelOrder = this.list.archivedTopLevelOrder.filter(
+			(i) => !(i.type === 'note' && i.id === noteId),
+		);
+		(this.list.trash ??= []).push(item);
+		await this.persistList();
+	}
+
+	/** ゴミ箱にある全ノートの ID。 */
+	trashedNoteIds(): Set<string> {
+		return trashNoteIds(this.list.trash ?? []);
+	}
+
+	/**
+	 * ゴミ箱から外れたのに一覧にも戻っていないノートの本文ファイルを削除する。
+	 * 他端末（デスクトップ版）でゴミ箱を空にした・保持期間が過ぎたノートが該当する。
+	 * previousTrash は noteList を置き換える前のゴミ箱。
+	 */
+	async deleteUnlistedTrashNotes(
+		previousTrash: TrashItem[],
+	): Promise<string[]> {
+		const listed = trashNoteIds(this.list.trash ?? []);
+		for (const n of this.list.notes) listed.add(n.id);
+		const removed: string[] = [];
+		for (const id of trashNoteIds(previousTrash)) {
+			if (listed.has(id)) continue;
+			await deleteIfExists(noteFilePath(id));
+			removed.push(id);
+		}
+		return removed;
+	}
+
+	/** (archived)TopLevelOrder 内の位置（無ければ -1）。 */
+	private topLevelPosition(
+		type: 'note' | 'folder',
+		id: string,
+		archived: boolean,
+	): number {
+		const order = archived
+			? this.list.archivedTopLevelOrder
+			: this.list.topLevelOrder;
+		return order.findIndex((i) => i.type === type && i.id === id);
+	}
+
 	/** フォルダの折りたたみ状態を切り替え、noteList.json に永続化する。 */
 	async setFolderCollapsed(
 		folderId: string,
@@ -313,7 +419,24 @@ export class NoteService {
 	}
 
 	async deleteFolder(folderId: string): Promise<void> {
+		const deleted = this.list.folders.find((f) => f.id === folderId);
 		this.list.folders = this.list.folders.filter((f) => f.id !== folderId);
+		// 入れ子のフォルダは削除したフォルダの親へ付け替える
+		const parentId = deleted?.parentId ?? '';
+		for (const f of this.list.folders) {
+			if (f.parentId !== folderId) continue;
+			if (parentId) {
+				f.parentId = parentId;
+				continue;
+			}
+			delete f.parentId;
+			const order = f.archived
+				? this.list.archivedTopLevelOrder
+				: this.list.topLevelOrder;
+			if (!order.some((i) => i.type === 'folder' && i.id === f.id)) {
+				order.push({ type: 'folder', id: f.id });
+			}
+		}
 		// フォルダ内のノートはトップレベルへ繰り上げる（同期で整合させる）
 		const promotedIds: string[] = [];
 		for (const n of this.list.notes) {
@@ -355,18 +478,27 @@ export class NoteService {
 		const folder = this.list.folders.find((f) => f.id === folderId);
 		if (!folder) return [];
 
+		const subtree = this.folderSubtreeIds(folderId);
 		const targetNoteIds = this.list.notes
-			.filter((n) => n.folderId === folderId && n.archived)
+			.filter((n) => subtree.has(n.folderId) && n.archived)
 			.map((n) => n.id);
 		for (const id of targetNoteIds) {
 			await this.setNoteArchived(id, false);
 		}
 
-		folder.archived = false;
+		// 親がアーカイブ済み・削除済みならトップレベルへ戻す（デスクトップ版 UnarchiveFolder と同じ）
+		const parent = this.list.folders.find((f) => f.id === folder.parentId);
+		if (folder.parentId && (!parent || parent.archived)) {
+			delete folder.parentId;
+		}
+		for (const f of this.list.folders) {
+			if (subtree.has(f.id)) f.archived = false;
+		}
 		this.list.archivedTopLevelOrder = this.list.archivedTopLevelOrder.filter(
-			(i) => !(i.type === 'folder' && i.id === folderId),
+			(i) => !(i.type === 'folder' && subtree.has(i.id)),
 		);
 		if (
+			!folder.parentId &&
 			!this.list.topLevelOrder.some(
 				(i) => i.type === 'folder' && i.id === folderId,
 			)
@@ -379,33 +511,46 @@ export class NoteService {
 	}
 
 	/**
-	 * archived フォルダを完全削除する。配下の archived ノートも本文ファイルごと
-	 * 削除する（同期側のクラウド削除は呼出側で `driveService.deleteNoteAndSync` を
-	 * 各 ID に対して回す前提）。
+	 * フォルダを入れ子のフォルダ・配下のノートごとゴミ箱に移す（デスクトップ版 TrashFolder）。
+	 * 本文ファイルはローカル・クラウドとも残し、デスクトップ版のゴミ箱から元に戻せるようにする。
 	 *
-	 * 戻り値は削除したノート ID 一覧（呼出側でクラウド削除＋ markNoteDeleted する）。
+	 * 戻り値はゴミ箱に移したノート ID 一覧。
 	 */
-	async deleteFolderHard(folderId: string): Promise<string[]> {
+	async trashFolder(folderId: string): Promise<string[]> {
 		const folder = this.list.folders.find((f) => f.id === folderId);
 		if (!folder) return [];
 
-		const targetNoteIds = this.list.notes
-			.filter((n) => n.folderId === folderId)
-			.map((n) => n.id);
-
-		// ノート本文ファイルとメタデータを削除
-		for (const id of targetNoteIds) {
-			await deleteIfExists(noteFilePath(id));
-		}
-		this.list.notes = this.list.notes.filter((n) => n.folderId !== folderId);
+		const subtree = this.folderSubtreeIds(folderId);
+		const parent = this.list.folders.find((f) => f.id === folder.parentId);
+		// 親が無い・アーカイブ状態が違う入れ子のフォルダは (archived)TopLevelOrder に並ぶ
+		const isOrderRoot =
+			!folder.parentId || !parent || parent.archived !== folder.archived;
+		const item: TrashItem = {
+			id: folderId,
+			type: 'folder',
+			deletedAt: rfc3339Now(),
+			order: isOrderRoot
+				? this.topLevelPosition('folder', folderId, folder.archived)
+				: -1,
+			notes: this.list.notes
+				.filter((n) => subtree.has(n.folderId))
+				.map((n) => ({ ...n })),
+			folders: [
+				{ ...folder },
+				...this.list.folders
+					.filter((f) => f.id !== folderId && subtree.has(f.id))
+					.map((f) => ({ ...f })),
+			],
+		};
+		const targetNoteIds = (item.notes ?? []).map((n) => n.id);
 
-		// フォルダ自体を削除
-		this.list.folders = this.list.folders.filter((f) => f.id !== folderId);
+		this.list.notes = this.list.notes.filter((n) => !subtree.has(n.folderId));
+		this.list.folders = this.list.folders.filter((f) => !subtree.has(f.id));
 		this.list.topLevelOrder = this.list.topLevelOrder.filter(
-			(i) => !(i.type === 'folder' && i.id === folderId),
+			(i) => !(i.type === 'folder' && subtree.has(i.id)),
 		);
 		this.list.archivedTopLevelOrder = this.list.archivedTopLevelOrder.filter(
-			(i) => !(i.type === 'folder' && i.id === folderId),
+			(i) => !(i.type === 'folder' && subtree.has(i.id)),
 		);
 		// 念のため: ノート ID も order から外す（本来 folder-child は order に
 		// 載らないが、データ破損保険）
@@ -416,13 +561,14 @@ export class NoteService {
 		this.list.archivedTopLevelOrder = this.list.archivedTopLevelOrder.filter(
 			(i) => !(i.type === 'note' && removedIds.has(i.id)),
 		);
+		(this.list.trash ??= []).push(item);
 		await this.persistList();
 
 		return targetNoteIds;
 	}
 
 	/**
-	 * フォルダごとアーカイブする。フォルダ配下の active なノートを全て archived にし、
+	 * フォルダごとアーカイブする。フォルダ配下（入れ子のフォルダを含む）の active なノートを全て archived にし、
 	 * フォルダ自体も archived として `archivedTopLevelOrder` へ移す。
 	 *
 	 * 戻り値は archived にしたノート ID 一覧（呼出側で `markNoteDirty` するため）。
@@ -433,17 +579,24 @@ export class NoteService {
 
 		// 1. 配下の active ノートをアーカイブ。setNoteArchived は本文ファイルも
 		//    書き戻すので順次実行する。folder-child のままで archived=true を持つ。
+		const subtree = this.folderSubtreeIds(folderId);
 		const targetNoteIds = this.list.notes
-			.filter((n) => n.folderId === folderId && !n.archived)
+			.filter((n) => subtree.has(n.folderId) && !n.archived)
 			.map((n) => n.id);
 		for (const id of targetNoteIds) {
 			await this.setNoteArchived(id, true);
 		}
 
-		// 2. フォルダ自体を archived 化し、active 側 order から外す。
-		folder.archived = true;
+		// 2. フォルダ自体（入れ子のフォルダも）を archived 化し、active 側 order から外す。
+		//    アーカイブ側では入れ子のフォルダは親の中に出すので、order にはルートだけを並べる。
+		for (const f of this.list.folders) {
+			if (subtree.has(f.id)) f.archived = true;
+		}
 		this.list.topLevelOrder = this.list.topLevelOrder.filter(
-			(i) => !(i.type === 'folder' && i.id === folderId),
+			(i) => !(i.type === 'folder' && subtree.has(i.id)),
+		);
+		this.list.archivedTopLevelOrder = this.list.archivedTopLevelOrder.filter(
+			(i) => !(i.type === 'folder' && i.id !== folderId && subtree.has(i.id)),
 		);
 		if (
 			!this.list.archivedTopLevelOrder.some(
@@ -567,6 +720,27 @@ export class NoteService {
 		return orphans;
 	}
 
+	/**
+	 * フォルダと、parentId をたどってその配下にある入れ子のフォルダの ID 集合を返す。
+	 * フォルダが存在しなければ空集合。
+	 */
+	private folderSubtreeIds(folderId: string): Set<string> {
+		const subtree = new Set<string>();
+		if (!this.list.folders.some((f) => f.id === folderId)) return subtree;
+		subtree.add(folderId);
+		let grew = true;
+		while (grew) {
+			grew = false;
+			for (const f of this.list.folders) {
+				if (f.parentId && subtree.has(f.parentId) && !subtree.has(f.id)) {
+					subtree.add(f.id);
+					grew = true;
+				}
+			}
+		}
+		return subtree;
+	}
+
 	private async upsertMetadata(
 		note: Note,
 		prependToOrder = false,
@@ -603,9 +777,11 @@ export class NoteService {
 		note: Note,
 		folderId: string,
 	): Promise<NoteMetadata> {
-		// contentHeader が未設定なら本文から即生成する（空タイトルノートでも一覧で本文プレビューを出すため）
-		const contentHeader =
-			note.contentHeader || generateContentHeader(note.content);
+		// contentHeader が未設定なら本文から即生成する（空タイトルノートでも一覧で本文プレビューを出すため）。
+		// 暗号化したノートは暗号文をプレビューに出さないよう常に空にする（デスクトップ版と同じ）
+		const contentHeader = note.encrypted
+			? ''
+			: note.contentHeader || generateContentHeader(note.content);
 		return {
 			id: note.id,
 			title: note.title,
@@ -615,6 +791,8 @@ export class NoteService {
 			archived: note.archived,
 			folderId,
 			contentHash: await computeContentHash(note),
+			...withTags(note.tags),
+			...withEncrypted(note.encrypted),
 		};
 	}
 
@@ -0,0 +1,122 @@
+import { describe, expect, it } from 'vitest';
+import type { DriveClient, DriveFile } from '../driveClient';
+import { ensureDriveLayout } from '../driveLayout';
+import {
+	assertNotSyncEncrypted,
+	DRIVE_KEY_CHECK_FILENAME,
+	DRIVE_MOBILE_CLIENT_FILENAME,
+	SyncEncryptedError,
+} from '../types';
+
+/**
+ * ensureDriveLayout とデスクトップ版の同期データ暗号化の検出。
+ *
+ * モバイル版は monaco-notepad-e2e-v1 を復号できないので、暗号化が有効なら接続を止め、
+ * 暗号文を空のノートリストとして扱ってローカルのノートを消さないことを確認する。
+ */
+
+/** listFiles の name 条件だけを見て返す、appDataFolder の in-memory 偽クライアント。 */
+class FakeDriveClient {
+	files: (DriveFile & { content: string })[] = [];
+	private seq = 1;
+
+	async listFiles(query: string): Promise<DriveFile[]> {
+		const name = /name='([^']+)'/.exec(query)?.[1];
+		return this.files.filter((f) => f.name === name);
+	}
+
+	async downloadText(fileId: string): Promise<string> {
+		return this.files.find((f) => f.id === fileId)?.content ?? '';
+	}
+
+	async createFolder(name: string): Promise<DriveFile> {
+		return this.add(name, '');
+	}
+
+	async createFile(
+		name: string,
+		_parents: string[] | null,
+		content: string,
+	): Promise<DriveFile> {
+		return this.add(name, content);
+	}
+
+	async updateFile(fileId: string, content: string): Promise<DriveFile> {
+		const file = this.files.find((f) => f.id === fileId)!;
+		file.content = content;
+		return file;
+	}
+
+	add(name: string, content: string): DriveFile {
+		const file = {
+			id: `id-${this.seq++}`,
+			name,
+			modifiedTime: '2026-01-01T00:00:00.000Z',
+			content,
+		};
+		this.files.push(file);
+		return file;
+	}
+}
+
+describe('ensureDriveLayout', () => {
+	it('初回はフォルダと noteList を作り、mobile_client.json に接続日時を書く', async () => {
+		const client = new FakeDriveClient();
+		const layout = await ensureDriveLayout(client as unknown as DriveClient);
+
+		expect(layout.noteListFileId).toBeTruthy();
+		const marker = client.files.find(
+			(f) => f.name === DRIVE_MOBILE_CLIENT_FILENAME,
+		);
+		expect(JSON.parse(marker!.content).lastSeen).toBeTruthy();
+
+		// 2 回目は同じファイルを更新する
+		await ensureDriveLayout(client as unknown as DriveClient);
+		expect(
+			client.files.filter((f) => f.name === DRIVE_MOBILE_CLIENT_FILENAME),
+		).toHaveLength(1);
+	});
+
+	it('デスクトップ版で同期データの暗号化が有効なら SyncEncryptedError で止める', async () => {
+		const client = new FakeDriveClient();
+		client.add('monaco-notepad', '');
+		client.add(
+			DRIVE_KEY_CHECK_FILENAME,
+			JSON.stringify({ format: 'monaco-notepad-keycheck-v1', enabled: true }),
+		);
+
+		await expect(
+			ensureDriveLayout(client as unknown as DriveClient),
+		).rejects.toBeInstanceOf(SyncEncryptedError);
+	});
+
+	it('暗号化を無効にした鍵確認ファイルなら接続する', async () => {
+		const client = new FakeDriveClient();
+		client.add('monaco-notepad', '');
+		client.add(
+			DRIVE_KEY_CHECK_FILENAME,
+			JSON.stringify({ format: 'monaco-notepad-keycheck-v1', enabled: false }),
+		);
+
+		await expect(
+			ensureDriveLayout(client as unknown as DriveClient),
+		).resolves.toBeDefined();
+	});
+});
+
+describe('assertNotSyncEncrypted', () => {
+	it('暗号化したペイロードだけを拒否する', () => {
+		expect(() =>
+			assertNotSyncEncrypted(
+				'{"format":"monaco-notepad-e2e-v1","keyId":"k","nonce":"","data":""}',
+			),
+		).toThrow(SyncEncryptedError);
+		expect(() =>
+			assertNotSyncEncrypted('{"version":"3.0","notes":[]}'),
+		).not.toThrow();
+		// ノート単位のロック (monaco-notepad-locked-note-v1) は本文の中なので対象外
+		expect(() =>
+			assertNotSyncEncrypted('{"id":"a","content":"{\\"format\\":\\"x\\"}"}'),
+		).not.toThrow();
+	});
+});
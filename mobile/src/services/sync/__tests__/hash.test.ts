@@ -30,6 +30,30 @@ describe('computeContentHash', () => {
 		);
 	});
 
+	it('tags が空ならタグ導入前と同じハッシュ、あれば末尾に追記される', async () => {
+		const base = makeNote({ id: 'a', title: 't', content: 'c' });
+		const baseH = await computeContentHash(base);
+		expect(await computeContentHash({ ...base, tags: [] })).toBe(baseH);
+		expect(await computeContentHash({ ...base, tags: ['go'] })).not.toBe(
+			baseH,
+		);
+	});
+
+	it('デスクトップ版 computeContentHash と同じ値になる', async () => {
+		const note = makeNote({
+			id: 'a',
+			title: 't',
+			content: 'c',
+			language: 'plaintext',
+		});
+		expect(await computeContentHash(note)).toBe(
+			'ec32755b9ba3753c9cad8394c83a5a525aa710c0c365d318b77010d41974d776',
+		);
+		expect(await computeContentHash({ ...note, tags: ['go', 'memo'] })).toBe(
+			'd0615006a9b4f7fa80cdfab147331c0b46c06deae39fc20515c0fcc51ba2350d',
+		);
+	});
+
 	it('folderId の変更はハッシュに影響しない（ローカルメタデータ扱い）', async () => {
 		const base = makeNote({ id: 'a', folderId: '' });
 		const h1 = await computeContentHash(base);
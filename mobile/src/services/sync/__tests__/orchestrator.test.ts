@@ -3,7 +3,7 @@ import { beforeEach, describe, expect, it } from 'vitest';
 import { NoteService } from '@/services/notes/noteService';
 import { CONFLICT_BACKUP_DIR } from '@/services/storage/paths';
 import { FakeCloud } from '@/test/fakeCloud';
-import { iso, makeNote } from '@/test/helpers';
+import { iso, makeNote, makeNoteList } from '@/test/helpers';
 import type { DriveSyncService } from '../driveSyncService';
 import { computeContentHash } from '../hash';
 import { SyncOrchestrator } from '../orchestrator';
@@ -250,6 +250,57 @@ describe('SyncOrchestrator.syncNotes: pull only (cloud changed, local clean)', (
 		// b/c は未完なので hash 無し
 		expect(state.lastSyncedHash('c')).toBeUndefined();
 	});
+
+	it('trash を持たない noteList（旧クライアント）ではローカルのゴミ箱を残す', async () => {
+		await notes.saveNote(makeNote({ id: 'a' }));
+		await notes.trashNote('a');
+		cloud.setCloudNote(makeNote({ id: 'b' }));
+		await cloud.rebuildNoteListFromCloud(iso(10_000));
+		expect(cloud.noteList.trash).toBeUndefined();
+
+		await orch.syncNotes();
+
+		expect(notes.trashedNoteIds().has('a')).toBe(true);
+		expect(await notes.readNote('a')).not.toBeNull();
+	});
+
+	it('デスクトップ版でゴミ箱に移されたノートは一覧から外れ、本文ファイルは残る', async () => {
+		const a = makeNote({ id: 'a' });
+		await notes.saveNote(a);
+		const meta = notes.getNoteList().notes[0];
+		cloud.setCloudNoteList(
+			makeNoteList({
+				trash: [
+					{
+						id: 'a',
+						type: 'note',
+						deletedAt: iso(5_000),
+						order: 0,
+						notes: [meta],
+					},
+				],
+			}),
+			iso(10_000),
+		);
+
+		await orch.syncNotes();
+
+		expect(notes.getNoteList().notes).toHaveLength(0);
+		expect(notes.trashedNoteIds().has('a')).toBe(true);
+		expect(await notes.readNote('a')).not.toBeNull();
+	});
+
+	it('クラウドのゴミ箱から外れたノートは本文ファイルも削除', async () => {
+		await notes.saveNote(makeNote({ id: 'a' }));
+		await notes.trashNote('a');
+		// デスクトップ版でゴミ箱を空にした
+		cloud.setCloudNoteList(makeNoteList({ trash: [] }), iso(10_000));
+
+		await orch.syncNotes();
+
+		expect(notes.trashedNoteIds().size).toBe(0);
+		expect(await notes.readNote('a')).toBeNull();
+	});
 });
 
 describe('SyncOrchestrator.syncNotes: 構造先行コミット (pull 中の UI 整合性)', () => {
@@ -1,9 +1,14 @@
 import type { DriveClient } from './driveClient';
-import { RETRY_LIST, withRetry } from './retry';
+import { RETRY_DOWNLOAD, RETRY_LIST, RETRY_UPLOAD, withRetry } from './retry';
 import {
+	DRIVE_KEY_CHECK_FILENAME,
+	DRIVE_MOBILE_CLIENT_FILENAME,
 	DRIVE_NOTE_LIST_FILENAME,
 	DRIVE_NOTES_FOLDER,
 	DRIVE_ROOT_FOLDER,
+	MOBILE_CLIENT_FORMAT,
+	NOTE_LIST_VERSION,
+	SyncEncryptedError,
 } from './types';
 
 /**
@@ -12,9 +17,15 @@ import {
  * appDataFolder/
  *   └── monaco-notepad/             (rootFolderId)
  *       ├── noteList_v2.json        (noteListFileId)
+ *       ├── encryption_key.json     (デスクトップ版の同期データ暗号化。有効なら同期しない)
+ *       ├── mobile_client.json      (モバイル版が最後に接続した日時)
  *       └── notes/                  (notesFolderId)
  *           ├── <noteId>.json
  *           └── ...
+ *
+ * デスクトップ版の同期データ暗号化 (monaco-notepad-e2e-v1) には対応していない。
+ * 暗号化が有効なら SyncEncryptedError で接続を止め、ノートを消したり平文で上書きしたりしない。
+ * mobile_client.json はデスクトップ版が暗号化を有効にする前にモバイル版の存在を確認するためのもの。
  */
 
 export interface DriveLayout {
@@ -74,6 +85,9 @@ export async function ensureDriveLayout(
 		rootFolderId = f.id;
 	}
 
+	await assertSyncNotEncrypted(client, rootFolderId);
+	await touchMobileClientMarker(client, rootFolderId);
+
 	let notesFolderId = await findFolder(
 		client,
 		DRIVE_NOTES_FOLDER,
@@ -96,12 +110,13 @@ export async function ensureDriveLayout(
 		noteListModifiedTime = existing.modifiedTime;
 	} else {
 		const emptyList = JSON.stringify({
-			version: 'v2',
+			version: NOTE_LIST_VERSION,
 			notes: [],
 			folders: [],
 			topLevelOrder: [],
 			archivedTopLevelOrder: [],
 			collapsedFolderIds: [],
+			trash: [],
 		});
 		const created = await client.createFile(
 			DRIVE_NOTE_LIST_FILENAME,
@@ -115,6 +130,60 @@ export async function ensureDriveLayout(
 	return { rootFolderId, notesFolderId, noteListFileId, noteListModifiedTime };
 }
 
+/** デスクトップ版で同期データの暗号化が有効なら SyncEncryptedError を投げる。 */
+async function assertSyncNotEncrypted(
+	client: DriveClient,
+	rootFolderId: string,
+): Promise<void> {
+	const keyCheck = await findFile(
+		client,
+		DRIVE_KEY_CHECK_FILENAME,
+		rootFolderId,
+	);
+	if (!keyCheck) return;
+	const text = await withRetry(
+		() => client.downloadText(keyCheck.id),
+		'downloadKeyCheck',
+		RETRY_DOWNLOAD,
+	);
+	let enabled = false;
+	try {
+		enabled = (JSON.parse(text) as { enabled?: unknown }).enabled === true;
+	} catch {
+		// 読めない鍵確認ファイルは暗号化されているものとして扱う（安全側）
+		enabled = true;
+	}
+	if (enabled) throw new SyncEncryptedError();
+}
+
+/** mobile_client.json に接続日時を書く。デスクトップ版はこれを見て暗号化の有効化を断る。 */
+async function touchMobileClientMarker(
+	client: DriveClient,
+	rootFolderId: string,
+): Promise<void> {
+	const body = JSON.stringify({
+		format: MOBILE_CLIENT_FORMAT,
+		lastSeen: new Date().toISOString(),
+	});
+	const existing = await findFile(
+		client,
+		DRIVE_MOBILE_CLIENT_FILENAME,
+		rootFolderId,
+	);
+	await withRetry(
+		() =>
+			existing
+				? client.updateFile(existing.id, body)
+				: client.createFile(
+						DRIVE_MOBILE_CLIENT_FILENAME,
+						[rootFolderId],
+						body,
+					),
+		'touchMobileClientMarker',
+		RETRY_UPLOAD,
+	);
+}
+
 function escapeQuery(s: string): string {
 	return s.replace(/'/g, "\\'");
 }
@@ -330,25 +330,23 @@ export class DriveService {
 		}
 	}
 
+	/**
+	 * ノートをゴミ箱に移す。本文ファイルはクラウドにも残し、noteList の変更として同期する
+	 * （デスクトップ版のゴミ箱から元に戻せる。完全な削除はデスクトップ版で行う）。
+	 */
 	async deleteNoteAndSync(noteId: string): Promise<void> {
-		await noteService.deleteNote(noteId);
-		await syncStateManager.markNoteDeleted(noteId);
-		await operationQueue.enqueue('DELETE', `note:${noteId}`, { noteId });
+		await noteService.trashNote(noteId);
+		await syncStateManager.markDirty();
 		syncEvents.emit('notes:reload', undefined);
 	}
 
 	/**
-	 * archived フォルダを完全削除する。配下の archived ノートも本文ファイル・
-	 * クラウド両方から消える。デスクトップ版のフォルダ削除と異なり、
+	 * archived フォルダを配下のノートごとゴミ箱に移す。
 	 * 「フォルダごとアーカイブ → アーカイブ画面で削除」という 2 段階フローで使う。
 	 */
 	async deleteFolderAndSync(folderId: string): Promise<void> {
-		const deletedNoteIds = await noteService.deleteFolderHard(folderId);
-		await syncStateManager.markFolderDeleted(folderId);
-		for (const noteId of deletedNoteIds) {
-			await syncStateManager.markNoteDeleted(noteId);
-			await operationQueue.enqueue('DELETE', `note:${noteId}`, { noteId });
-		}
+		await noteService.trashFolder(folderId);
+		await syncStateManager.markDirty();
 	}
 
 	/**
@@ -7,7 +7,17 @@ import {
 	RETRY_UPLOAD,
 	withRetry,
 } from './retry';
-import type { Note, NoteList } from './types';
+import {
+	assertNotSyncEncrypted,
+	type Folder,
+	NOTE_LIST_VERSION,
+	type Note,
+	type NoteList,
+	type NoteMetadata,
+	type TrashItem,
+	withEncrypted,
+	withTags,
+} from './types';
 
 /**
  * Drive に対する中レベル操作（ノート単位の CRUD + noteList の操作）。
@@ -132,6 +142,7 @@ export class DriveSyncService {
 			'downloadNote',
 			RETRY_DOWNLOAD,
 		);
+		assertNotSyncEncrypted(text);
 		return parseNote(text);
 	}
 
@@ -141,6 +152,7 @@ export class DriveSyncService {
 			'downloadNoteByFileId',
 			RETRY_DOWNLOAD,
 		);
+		assertNotSyncEncrypted(text);
 		return parseNote(text);
 	}
 
@@ -150,6 +162,7 @@ export class DriveSyncService {
 			'downloadNoteList',
 			RETRY_DOWNLOAD,
 		);
+		assertNotSyncEncrypted(text);
 		return normalizeNoteList(JSON.parse(text));
 	}
 
@@ -190,6 +203,8 @@ function parseNote(text: string): Note | null {
 			modifiedTime: parsed.modifiedTime ?? new Date().toISOString(),
 			archived: parsed.archived ?? false,
 			folderId: parsed.folderId ?? '',
+			...withTags(parsed.tags),
+			...withEncrypted(parsed.encrypted, parsed.content ?? ''),
 		};
 	} catch {
 		return null;
@@ -232,24 +247,71 @@ function normalizeNoteList(raw: unknown): NoteList {
 		: [];
 
 	return {
-		version: 'v2',
-		notes: notes.map((n) => ({
-			id: String(n.id ?? ''),
-			title: String(n.title ?? ''),
-			contentHeader: String(n.contentHeader ?? ''),
-			language: String(n.language ?? 'plaintext'),
-			modifiedTime: String(n.modifiedTime ?? ''),
-			archived: Boolean(n.archived ?? false),
-			contentHash: String(n.contentHash ?? ''),
-			folderId: String(n.folderId ?? ''),
-		})),
-		folders: folders.map((f) => ({
-			id: String(f.id ?? ''),
-			name: String(f.name ?? ''),
-			archived: Boolean(f.archived ?? false),
-		})),
+		// デスクトップ版の "3.0" などはそのまま保持する（'v2' に落とすと新しい形式の
+		// クライアントが旧形式と誤認する）
+		version: typeof r.version === 'string' ? r.version : NOTE_LIST_VERSION,
+		notes: notes.map(normalizeNoteMetadata),
+		folders: folders.map(normalizeFolder),
 		topLevelOrder,
 		archivedTopLevelOrder,
 		collapsedFolderIds,
+		// ゴミ箱はモバイル版では表示しないが、落とすとデスクトップ版のゴミ箱が消えるので保持する。
+		// キーが無い（旧クライアントが書いた）場合は省略のままにし、orchestrator が手元のゴミ箱を残す
+		...(Array.isArray(r.trash)
+			? {
+					trash: (r.trash as Array<Record<string, unknown>>).map(
+						normalizeTrashItem,
+					),
+				}
+			: {}),
+	};
+}
+
+function normalizeTrashItem(item: Record<string, unknown>): TrashItem {
+	return {
+		id: String(item.id ?? ''),
+		type: item.type === 'folder' ? 'folder' : 'note',
+		deletedAt: String(item.deletedAt ?? ''),
+		order: typeof item.order === 'number' ? item.order : -1,
+		...(Array.isArray(item.notes)
+			? {
+					notes: (item.notes as Array<Record<string, unknown>>).map(
+						normalizeNoteMetadata,
+					),
+				}
+			: {}),
+		...(Array.isArray(item.folders)
+			? {
+					folders: (item.folders as Array<Record<string, unknown>>).map(
+						normalizeFolder,
+					),
+				}
+			: {}),
+	};
+}
+
+function normalizeNoteMetadata(n: Record<string, unknown>): NoteMetadata {
+	return {
+		id: String(n.id ?? ''),
+		title: String(n.title ?? ''),
+		contentHeader: String(n.contentHeader ?? ''),
+		language: String(n.language ?? 'plaintext'),
+		modifiedTime: String(n.modifiedTime ?? ''),
+		archived: Boolean(n.archived ?? false),
+		contentHash: String(n.contentHash ?? ''),
+		folderId: String(n.folderId ?? ''),
+		...withTags(n.tags),
+		...withEncrypted(n.encrypted),
+	};
+}
+
+function normalizeFolder(f: Record<string, unknown>): Folder {
+	return {
+		id: String(f.id ?? ''),
+		name: String(f.name ?? ''),
+		archived: Boolean(f.archived ?? false),
+		...(typeof f.parentId === 'string' && f.parentId
+			? { parentId: f.parentId }
+			: {}),
 	};
 }
@@ -3,11 +3,17 @@ import type { Note } from './types';
 
 /**
  * ノートの内容ハッシュ。デスクトップ版 backend/domain.go の computeContentHash と一致。
- * 含める: id, title, content, language, archived
+ * 含める: id, title, content, language, archived, tags（ある場合のみ）
  * 除外: folderId（ローカルメタ）, modifiedTime（タイムスタンプ）
+ *
+ * tags はタグ導入前のノートのハッシュを変えないよう、空でないときだけ
+ * `\n` + NUL 区切りで末尾に追記する（デスクトップ版と同じ）。
  */
 export async function computeContentHash(note: Note): Promise<string> {
-	const payload = `${note.id}\n${note.title}\n${note.content}\n${note.language}\n${note.archived}`;
+	let payload = `${note.id}\n${note.title}\n${note.content}\n${note.language}\n${note.archived}`;
+	if (note.tags && note.tags.length > 0) {
+		payload += `\n${note.tags.join('\x00')}`;
+	}
 	return Crypto.digestStringAsync(
 		Crypto.CryptoDigestAlgorithm.SHA256,
 		payload,
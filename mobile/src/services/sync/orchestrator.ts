@@ -1,11 +1,17 @@
-import type { NoteService } from '../notes/noteService';
+import { type NoteService, trashNoteIds } from '../notes/noteService';
 import { AsyncLock } from './asyncLock';
 import { backupLocalNote } from './conflictBackup';
 import type { DriveSyncService } from './driveSyncService';
 import { syncEvents } from './events';
 import { computeContentHash } from './hash';
 import type { SyncStateManager } from './syncState';
-import { type Folder, MessageCode, type Note, type NoteList } from './types';
+import {
+	type Folder,
+	MessageCode,
+	type Note,
+	type NoteList,
+	type TrashItem,
+} from './types';
 
 export interface SyncOrchestratorOptions {
 	/** 競合時にローカル版をバックアップするか（デフォルト true）。 */
@@ -234,6 +240,8 @@ export class SyncOrchestrator {
 		syncEvents.emit('sync:phase', { phase: 'fetching-notelist' });
 		const cloudList = await this.driveSync.downloadNoteList();
 		const localList = this.noteService.getNoteList();
+		// trash の無い noteList（ゴミ箱を知らない旧クライアントが書いたもの）では手元のゴミ箱を残す
+		cloudList.trash ??= localList.trash;
 
 		// 事前にダウンロード対象を決定して総数を把握する（進捗表示のため）。
 		// Resume 最適化: 前回 session で既にローカルへ書き終えているノート (= local の
@@ -265,12 +273,13 @@ export class SyncOrchestrator {
 		// で同じ結果になる）。
 		if (toDownload.length > 0) {
 			const structurePreCommit: NoteList = {
-				version: 'v2',
+				version: cloudList.version,
 				notes: localList.notes,
 				folders: cloudList.folders,
 				topLevelOrder: cloudList.topLevelOrder,
 				archivedTopLevelOrder: cloudList.archivedTopLevelOrder,
 				collapsedFolderIds: cloudList.collapsedFolderIds,
+				trash: cloudList.trash,
 			};
 			await this.noteService.replaceNoteList(structurePreCommit);
 			syncEvents.emit('notes:reload', undefined);
@@ -328,8 +337,10 @@ export class SyncOrchestrator {
 		syncEvents.emit('sync:progress', { current: 0, total: 0 });
 		syncEvents.emit('sync:phase', { phase: 'merging' });
 
-		// クラウドから消えたノートをローカルからも削除
+		// クラウドから消えたノートをローカルからも削除（ゴミ箱に移ったノートは本文ファイルを残す）
+		const cloudTrashedIds = trashNoteIds(cloudList.trash ?? []);
 		for (const local of localList.notes) {
+			if (cloudTrashedIds.has(local.id)) continue;
 			if (!cloudList.notes.some((n) => n.id === local.id)) {
 				await this.noteService.deleteNote(local.id);
 				await this.syncState.forgetNoteHash(local.id);
@@ -380,6 +391,12 @@ export class SyncOrchestrator {
 
 		if (!userMutated) {
 			await this.noteService.replaceNoteList(cloudList);
+			// 他端末でゴミ箱を空にした・保持期間が過ぎたノートの本文ファイルを消す
+			for (const id of await this.noteService.deleteUnlistedTrashNotes(
+				localList.trash ?? [],
+			)) {
+				await this.syncState.forgetNoteHash(id);
+			}
 		}
 
 		await this.syncState.updateSyncedState(cloudTs, mergedHashes);
@@ -397,6 +414,15 @@ export class SyncOrchestrator {
 		// ローカルで dirty なノートごとに判定
 		const dirtySet = new Set(snap.dirtyIds);
 
+		// ゴミ箱のマージ。この端末でゴミ箱に移したノートをクラウドから取り込み直さないよう先に決める
+		const mergedTrash = mergeTrash(
+			localList.trash ?? [],
+			cloudList.trash ?? localList.trash ?? [],
+			cloudList,
+			dirtySet,
+		);
+		const trashedIds = trashNoteIds(mergedTrash);
+
 		// 事前パス: 「cloud 未存在 & hash 不一致 = 実際に新規 push するノート」の件数を確定
 		// させ、進捗メッセージを 1..M の連番で出せるようにする。Resume 時にスキップされる
 		// 分はカウント対象から外れるので UX 上もジャンプしない。
@@ -491,6 +517,7 @@ export class SyncOrchestrator {
 		// クラウドにあってローカルで触ってないノート: ローカル側も追従
 		for (const cloudMeta of cloudList.notes) {
 			if (dirtySet.has(cloudMeta.id)) continue;
+			if (trashedIds.has(cloudMeta.id)) continue;
 			const local = localList.notes.find((n) => n.id === cloudMeta.id);
 			if (!local || local.contentHash !== cloudMeta.contentHash) {
 				const fresh = await this.driveSync.downloadNote(cloudMeta.id);
@@ -514,8 +541,21 @@ export class SyncOrchestrator {
 
 		// メタ情報をマージ（折りたたみ状態・順序はローカル優先、notes は最新反映）
 		const merged: NoteList = mergeNoteListMeta(localList, cloudList);
-		merged.notes = this.noteService.getNoteList().notes; // 最新を使う
+		merged.notes = this.noteService
+			.getNoteList()
+			.notes.filter((n) => !trashedIds.has(n.id)); // 最新を使う
+		merged.trash = mergedTrash;
+		removeNoteEntries(merged, trashedIds);
+		const trashedFolderIds = new Set(
+			mergedTrash.flatMap((item) => (item.folders ?? []).map((f) => f.id)),
+		);
+		removeFolderEntries(merged, trashedFolderIds);
 		await this.noteService.replaceNoteList(merged);
+		for (const id of await this.noteService.deleteUnlistedTrashNotes(
+			localList.trash ?? [],
+		)) {
+			await this.syncState.forgetNoteHash(id);
+		}
 
 		// クラウドへ統合済みの noteList を書き戻す
 		const updated = await this.driveSync.updateNoteList(merged);
@@ -554,7 +594,7 @@ function mergeNoteListMeta(local: NoteList, cloud: NoteList): NoteList {
 	for (const f of cloud.folders) folderMap.set(f.id, f);
 	for (const f of local.folders) folderMap.set(f.id, f);
 	return {
-		version: 'v2',
+		version: cloud.version,
 		notes: [],
 		folders: [...folderMap.values()],
 		topLevelOrder: mergeOrder(local.topLevelOrder, cloud.topLevelOrder),
@@ -566,6 +606,45 @@ function mergeNoteListMeta(local: NoteList, cloud: NoteList): NoteList {
 	};
 }
 
+/**
+ * ゴミ箱をマージする（デスクトップ版 note_trash.go の mergeTrash を簡略化したもの）。
+ * - クラウドのアイテムは全て残す。
+ * - ローカルにしか無いアイテムは、この端末で新しくゴミ箱に移したもの（中のノートがまだクラウドの
+ *   一覧にあるか未アップロード）だけ残す。それ以外は他端末で完全に削除済み。
+ * - ゴミ箱に移した後に他端末で編集・復元されたノートを含むアイテムは、復元されたものとして外す。
+ */
+function mergeTrash(
+	localTrash: TrashItem[],
+	cloudTrash: TrashItem[],
+	cloudList: NoteList,
+	dirtyIds: Set<string>,
+): TrashItem[] {
+	const cloudItemIds = new Set(cloudTrash.map((item) => item.id));
+	const cloudNotes = new Map(cloudList.notes.map((n) => [n.id, n]));
+	const merged = [...cloudTrash];
+	for (const item of localTrash) {
+		if (cloudItemIds.has(item.id)) continue;
+		const known = (item.notes ?? []).some(
+			(n) => cloudNotes.has(n.id) || dirtyIds.has(n.id),
+		);
+		const knownFolder =
+			item.type === 'folder' &&
+			cloudList.folders.some((f) => f.id === item.id);
+		if (known || knownFolder) merged.push(item);
+	}
+	return merged.filter(
+		(item) =>
+			!(item.notes ?? []).some((n) => {
+				const current = cloudNotes.get(n.id);
+				return (
+					current !== undefined &&
+					!dirtyIds.has(n.id) &&
+					isModifiedTimeAfter(current.modifiedTime, item.deletedAt)
+				);
+			}),
+	);
+}
+
 function mergeOrder<T extends { type: string; id: string }>(
 	localOrder: T[],
 	cloudOrder: T[],
@@ -592,6 +671,16 @@ function removeNoteEntries(list: NoteList, noteIds: Set<string>): void {
 	);
 }
 
+function removeFolderEntries(list: NoteList, folderIds: Set<string>): void {
+	list.folders = list.folders.filter((f) => !folderIds.has(f.id));
+	list.topLevelOrder = list.topLevelOrder.filter(
+		(i) => !(i.type === 'folder' && folderIds.has(i.id)),
+	);
+	list.archivedTopLevelOrder = list.archivedTopLevelOrder.filter(
+		(i) => !(i.type === 'folder' && folderIds.has(i.id)),
+	);
+}
+
 function copyContentHeaders(from: NoteList, to: NoteList): void {
 	const headers = new Map(
 		from.notes
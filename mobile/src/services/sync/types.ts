@@ -13,6 +13,8 @@ export interface Note {
 	modifiedTime: string; // RFC3339
 	archived: boolean;
 	folderId: string; // 空文字 = トップレベル
+	tags?: string[]; // デスクトップ版で付けたタグ（正規化済み・昇順）。無ければ省略
+	encrypted?: boolean; // デスクトップ版でロックしたノート（content は暗号文）。モバイル版では読み取り専用
 	syncing?: boolean; // 一時フラグ（永続化しない）
 }
 
@@ -26,12 +28,15 @@ export interface NoteMetadata {
 	archived: boolean;
 	contentHash: string;
 	folderId: string;
+	tags?: string[];
+	encrypted?: boolean;
 }
 
 export interface Folder {
 	id: string;
 	name: string;
 	archived: boolean;
+	parentId?: string; // 親フォルダ ID（空 or 省略 = トップレベル）。noteList 3.0 以降
 }
 
 export type TopLevelItemType = 'note' | 'folder';
@@ -41,14 +46,55 @@ export interface TopLevelItem {
 	id: string;
 }
 
+/**
+ * モバイル版が書き出す noteList のバージョン（デスクトップ版 note_service.go の CurrentVersion）。
+ * 3.0 でフォルダの入れ子（Folder.parentId）が入った。
+ */
+export const NOTE_LIST_VERSION = '3.0';
+
+/**
+ * 読み込める noteList のバージョンか判定する。旧モバイル版の 'v2' と、
+ * デスクトップ版の "2.x"（タグ）/ "3.x"（フォルダの入れ子）を受け付ける。
+ */
+export function isSupportedNoteListVersion(version: unknown): boolean {
+	if (version === 'v2') return true;
+	if (typeof version !== 'string' || !/^\d+(\.\d+)*$/.test(version)) {
+		return false;
+	}
+	const major = Number(version.split('.')[0]);
+	return major >= 2 && major <= 3;
+}
+
+export type TrashItemType = 'note' | 'folder';
+
+/**
+ * ゴミ箱に移したノートまたはフォルダ（デスクトップ版 domain.go の TrashItem）。
+ * ノートファイルは完全に削除されるまでローカル・クラウドとも残す。
+ */
+export interface TrashItem {
+	id: string; // ノートまたはフォルダの ID
+	type: TrashItemType;
+	deletedAt: string; // ゴミ箱に移した日時（RFC3339）
+	order: number; // 削除前の (archived)TopLevelOrder 内の位置（-1 = ルートに無かった）
+	notes?: NoteMetadata[]; // 削除したノート（フォルダの場合は配下のノート全て）
+	folders?: Folder[]; // 削除したフォルダと配下のフォルダ（先頭が削除したフォルダ）
+}
+
 /** noteList_v2.json のスキーマ。 */
 export interface NoteList {
-	version: 'v2';
+	version: string; // 'v2'（旧モバイル版）または "3.0" 形式
+
 	notes: NoteMetadata[];
 	folders: Folder[];
 	topLevelOrder: TopLevelItem[];
 	archivedTopLevelOrder: TopLevelItem[];
 	collapsedFolderIds: string[];
+	/**
+	 * ゴミ箱。モバイル版は空でも必ず書き出す（cloneNoteList が [] にする）。
+	 * 省略 = ゴミ箱を知らない旧クライアントが書いた noteList。デスクトップ版もモバイル版も
+	 * その場合は手元のゴミ箱を残し、ファイルを削除しない。
+	 */
+	trash?: TrashItem[];
 }
 
 /** sync_state.json のスキーマ。ローカル専用、Drive には上げない。 */
@@ -66,6 +112,62 @@ export interface SyncStateSnapshot {
  * ミュータブルに扱うこと。直接 `{ ...EMPTY_SYNC_STATE }` すると内部の
  * dirtyNoteIds 等が共有参照になり壊れる。
  */
+/**
+ * JSON から読んだ tags を `{ tags }` として返す（スプレッドで使う）。デスクトップ版は
+ * 空のタグを `omitempty` で書かないため、空配列や不正な値はキーごと省略してハッシュを変えない。
+ */
+export function withTags(value: unknown): { tags?: string[] } {
+	if (!Array.isArray(value) || value.length === 0) return {};
+	return { tags: value.map(String) };
+}
+
+/** デスクトップ版 note_lock.go の lockedNoteFormat。暗号化したノートの content はこの形式の JSON。 */
+export const LOCKED_NOTE_FORMAT = 'monaco-notepad-locked-note-v1';
+
+/** content がデスクトップ版で暗号化した本文か（デスクトップ版 isLockedNoteContent）。 */
+export function isLockedNoteContent(content: string): boolean {
+	return content.startsWith(`{"format":"${LOCKED_NOTE_FORMAT}"`);
+}
+
+/** デスクトップ版 sync_encryption.go の syncEncryptionFormat。同期データ全体を暗号化したときの形式。 */
+export const SYNC_ENCRYPTION_FORMAT = 'monaco-notepad-e2e-v1';
+
+/**
+ * デスクトップ版で同期データの暗号化が有効になっている。モバイル版は復号できないため同期を止める。
+ * 暗号文を空のノートリスト・壊れたノートとして扱うと、ローカルのノートを消したり
+ * 平文で上書きしたりするので、読めないデータを見つけた時点で必ずこれを投げる。
+ */
+export class SyncEncryptedError extends Error {
+	readonly syncEncrypted = true;
+	constructor() {
+		super(
+			'Sync data is encrypted by the desktop app and cannot be read on mobile. Turn off sync encryption on the desktop to sync this device.',
+		);
+	}
+}
+
+/** Drive から読んだテキストがデスクトップ版で暗号化したペイロードなら SyncEncryptedError を投げる。 */
+export function assertNotSyncEncrypted(text: string): void {
+	if (text.trimStart().startsWith(`{"format":"${SYNC_ENCRYPTION_FORMAT}"`)) {
+		throw new SyncEncryptedError();
+	}
+}
+
+/**
+ * JSON から読んだ encrypted を `{ encrypted }` として返す（スプレッドで使う）。デスクトップ版は
+ * `omitempty` で書くので false はキーごと省略する。フラグが落ちていても本文が暗号文なら付け直す。
+ */
+export function withEncrypted(
+	value: unknown,
+	content?: string,
+): { encrypted?: true } {
+	if (value === true) return { encrypted: true };
+	if (content !== undefined && isLockedNoteContent(content)) {
+		return { encrypted: true };
+	}
+	return {};
+}
+
 export const EMPTY_SYNC_STATE: Readonly<SyncStateSnapshot> = Object.freeze({
 	dirty: false,
 	lastSyncedDriveTs: '',
@@ -76,12 +178,13 @@ export const EMPTY_SYNC_STATE: Readonly<SyncStateSnapshot> = Object.freeze({
 });
 
 export const EMPTY_NOTE_LIST: NoteList = {
-	version: 'v2',
+	version: NOTE_LIST_VERSION,
 	notes: [],
 	folders: [],
 	topLevelOrder: [],
 	archivedTopLevelOrder: [],
 	collapsedFolderIds: [],
+	trash: [],
 };
 
 /** 復元先の固定フォルダ名（デスクトップ版と一致）。 */
@@ -92,6 +195,11 @@ export const DRIVE_ROOT_FOLDER = 'monaco-notepad';
 export const DRIVE_NOTES_FOLDER = 'notes';
 export const DRIVE_NOTE_LIST_FILENAME = 'noteList_v2.json';
 export const DRIVE_MIGRATION_MARKER = 'migration_complete.json';
+/** デスクトップ版の同期データ暗号化の鍵確認ファイル（sync_encryption.go の syncKeyCheckFileName）。 */
+export const DRIVE_KEY_CHECK_FILENAME = 'encryption_key.json';
+/** モバイル版が同期に参加していることをデスクトップ版に知らせるファイル。 */
+export const DRIVE_MOBILE_CLIENT_FILENAME = 'mobile_client.json';
+export const MOBILE_CLIENT_FORMAT = 'monaco-notepad-mobile-client-v1';
 
 /** OAuth2 スコープ（appDataFolder のみ）。 */
 export const DRIVE_SCOPES = ['https://www.googleapis.com/auth/drive.appdata'];
@@ -1,7 +1,12 @@
 import { create } from 'zustand';
 import { noteService } from '@/services/notes/noteService';
 import { syncEvents } from '@/services/sync/events';
-import type { Note, NoteList, NoteMetadata } from '@/services/sync/types';
+import {
+	NOTE_LIST_VERSION,
+	type Note,
+	type NoteList,
+	type NoteMetadata,
+} from '@/services/sync/types';
 
 interface NotesStoreState {
 	noteList: NoteList;
@@ -14,12 +19,13 @@ interface NotesStoreState {
 
 export const useNotesStore = create<NotesStoreState>((set, get) => ({
 	noteList: {
-		version: 'v2',
+		version: NOTE_LIST_VERSION,
 		notes: [],
 		folders: [],
 		topLevelOrder: [],
 		archivedTopLevelOrder: [],
 		collapsedFolderIds: [],
+		trash: [],
 	},
 	loading: true,
 
@@ -1,7 +1,7 @@
 import type { DriveFile } from '@/services/sync/driveClient';
 import type { DriveSyncService } from '@/services/sync/driveSyncService';
 import { computeContentHash } from '@/services/sync/hash';
-import type { Note, NoteList } from '@/services/sync/types';
+import { type Note, type NoteList, withTags } from '@/services/sync/types';
 import { makeNoteList } from './helpers';
 
 /**
@@ -79,6 +79,7 @@ export class FakeCloud
 				archived: note.archived,
 				folderId: note.folderId,
 				contentHash: await computeContentHash(note),
+				...withTags(note.tags),
 			});
 		}
 		this.noteListModifiedTime = modifiedTime;
@@ -1,4 +1,9 @@
-import type { Note, NoteList, NoteMetadata } from '@/services/sync/types';
+import {
+	NOTE_LIST_VERSION,
+	type Note,
+	type NoteList,
+	type NoteMetadata,
+} from '@/services/sync/types';
 
 /** テストでよく使うノート生成ヘルパー。 */
 export function makeNote(overrides: Partial<Note> = {}): Note {
@@ -13,6 +18,8 @@ export function makeNote(overrides: Partial<Note> = {}): Note {
 		modifiedTime: overrides.modifiedTime ?? '2026-01-01T00:00:00.000Z',
 		archived: overrides.archived ?? false,
 		folderId: overrides.folderId ?? '',
+		...(overrides.tags ? { tags: overrides.tags } : {}),
+		...(overrides.encrypted ? { encrypted: true } : {}),
 	};
 }
 
@@ -33,12 +40,14 @@ export function makeMetadata(
 
 export function makeNoteList(overrides: Partial<NoteList> = {}): NoteList {
 	return {
-		version: 'v2',
+		version: NOTE_LIST_VERSION,
 		notes: overrides.notes ?? [],
 		folders: overrides.folders ?? [],
 		topLevelOrder: overrides.topLevelOrder ?? [],
 		archivedTopLevelOrder: overrides.archivedTopLevelOrder ?? [],
 		collapsedFolderIds: overrides.collapsedFolderIds ?? [],
+		// trash は省略 = ゴミ箱を知らない旧クライアントの noteList なので、指定時だけ付ける
+		...(overrides.trash ? { trash: overrides.trash } : {}),
 	};
 }
 
@@ -179,6 +179,50 @@ describe('flattenNoteList', () => {
 		expect(ids).toContain('topLevel-note:b');
 		expect(ids).toContain('folder-header:f1');
 	});
+
+	it('入れ子のフォルダは親の子ノートの直後に depth 付きで出す', () => {
+		const list = buildList(
+			[meta('a', { folderId: 'f1' }), meta('b', { folderId: 'f2' })],
+			[folder('f1'), { ...folder('f2'), parentId: 'f1' }, folder('f3')],
+			[
+				{ type: 'folder', id: 'f1' },
+				{ type: 'folder', id: 'f3' },
+			],
+		);
+		const rows = flattenNoteList(list);
+		expect(rows.map((r) => `${r.kind}:${r.id}`)).toEqual([
+			'folder-header:f1',
+			'folder-child:a',
+			'folder-header:f2',
+			'folder-child:b',
+			'folder-header:f3',
+		]);
+		const depths = rows.flatMap((r) =>
+			r.kind === 'folder-header' ? [r.depth] : [],
+		);
+		expect(depths).toEqual([0, 1, 0]);
+	});
+
+	it('折りたたんだフォルダの配下のフォルダは末尾のフォールバックにも出さない', () => {
+		const list = buildList(
+			[meta('b', { folderId: 'f2' })],
+			[folder('f1'), { ...folder('f2'), parentId: 'f1' }],
+			[{ type: 'folder', id: 'f1' }],
+			['f1'],
+		);
+		const rows = flattenNoteList(list);
+		expect(rows.map((r) => `${r.kind}:${r.id}`)).toEqual(['folder-header:f1']);
+	});
+
+	it('親が存在しない入れ子のフォルダはトップレベル扱いで出す', () => {
+		const list = buildList(
+			[],
+			[{ ...folder('f2'), parentId: 'missing' }],
+			[],
+		);
+		const rows = flattenNoteList(list);
+		expect(rows.map((r) => `${r.kind}:${r.id}`)).toEqual(['folder-header:f2']);
+	});
 });
 
 describe('applyReorder', () => {
@@ -510,6 +554,22 @@ describe('applyDropIntent', () => {
 			'folder:f1',
 		]);
 	});
+
+	it('入れ子のフォルダは topLevelOrder に移動しない', () => {
+		const list = buildList(
+			[],
+			[folder('f1'), { ...folder('f2'), parentId: 'f1' }],
+			[{ type: 'folder', id: 'f1' }],
+		);
+		const rows = flattenNoteList(list);
+		const { list: result } = applyDropIntent(list, rows, 1, {
+			type: 'top-level',
+			index: 0,
+		});
+
+		expect(result.topLevelOrder).toEqual([{ type: 'folder', id: 'f1' }]);
+		expect(result.folders[1].parentId).toBe('f1');
+	});
 });
 
 describe('moveNoteToFolder', () => {
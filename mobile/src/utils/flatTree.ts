@@ -12,7 +12,7 @@ import { cloneNoteList } from '@/utils/noteListClone';
  *
  * 行の種類：
  * - `topLevel-note`: トップレベルのノート（depth 0）
- * - `folder-header`: フォルダの見出し
+ * - `folder-header`: フォルダの見出し（入れ子のフォルダは親の子ノートの直後に depth を増やして出す）
  * - `folder-child`: 展開されたフォルダ配下のノート（depth 1）
  *
  * 折りたたみ時は子は出さない（visual に隠れる）。DnD 中は常に折りたたみ状態を尊重し、
@@ -34,6 +34,8 @@ export type FlatRow =
 			noteCount: number;
 			collapsed: boolean;
 			inGroupEnd: boolean;
+			/** 親フォルダの数（トップレベルのフォルダは 0） */
+			depth: number;
 	  }
 	| {
 			kind: 'folder-child';
@@ -84,59 +86,19 @@ export function flattenNoteList(
 	const seenNotes = new Set<string>();
 	const seenFolders = new Set<string>();
 
-	for (const item of order) {
-		if (item.type === 'note') {
-			if (seenNotes.has(item.id)) continue; // order 重複ガード
-			const note = notesById.get(item.id);
-			if (!note || (note.archived ?? false) !== archived) continue;
-			if (note.folderId) continue; // フォルダ配下のノートは folder-child として後で出す
-			out.push({
-				kind: 'topLevel-note',
-				key: `t:${note.id}`,
-				id: note.id,
-				note,
-			});
-			seenNotes.add(note.id);
-		} else if (item.type === 'folder') {
-			if (seenFolders.has(item.id)) continue; // order 重複ガード
-			const folder = folderById.get(item.id);
-			if (!folder || (folder.archived ?? false) !== archived) continue;
-			seenFolders.add(folder.id);
-			const children = notesByFolder.get(folder.id) ?? [];
-			const isCollapsed = collapsedSet.has(folder.id);
-			const headerIsEnd = isCollapsed || children.length === 0;
-			out.push({
-				kind: 'folder-header',
-				key: `f:${folder.id}`,
-				id: folder.id,
-				folder,
-				noteCount: children.length,
-				collapsed: isCollapsed,
-				inGroupEnd: headerIsEnd,
-			});
-			if (!isCollapsed || includeCollapsedChildren) {
-				children.forEach((child, idx) => {
-					if (seenNotes.has(child.id)) return; // notes 配列重複ガード
-					out.push({
-						kind: 'folder-child',
-						key: `c:${child.id}`,
-						id: child.id,
-						note: child,
-						folderId: folder.id,
-						inGroupEnd: idx === children.length - 1,
-						parentCollapsed: isCollapsed,
-					});
-					seenNotes.add(child.id);
-				});
-			}
-		}
-	}
-
-	// topLevelOrder に現れていない要素のフォールバック（データ破損保険）。
-	// 過度に挿入すると DnD で順序が壊れるので末尾に最小限追加する。
+	// 同じビューの親を持つフォルダは、親の見出しと子ノートの直後に出す（Folder.parentId）。
+	// 子フォルダ同士の並び順は `folders` 配列の順序（デスクトップ版と同じ）。
+	const subfoldersByParent = new Map<string, Folder[]>();
 	for (const folder of list.folders) {
 		if ((folder.archived ?? false) !== archived) continue;
-		if (seenFolders.has(folder.id)) continue;
+		if (isFolderRootInView(folder, folderById, archived)) continue;
+		const arr = subfoldersByParent.get(folder.parentId ?? '') ?? [];
+		arr.push(folder);
+		subfoldersByParent.set(folder.parentId ?? '', arr);
+	}
+
+	const pushFolder = (folder: Folder, depth: number) => {
+		seenFolders.add(folder.id);
 		const children = notesByFolder.get(folder.id) ?? [];
 		const isCollapsed = collapsedSet.has(folder.id);
 		const headerIsEnd = isCollapsed || children.length === 0;
@@ -148,10 +110,11 @@ export function flattenNoteList(
 			noteCount: children.length,
 			collapsed: isCollapsed,
 			inGroupEnd: headerIsEnd,
+			depth,
 		});
 		if (!isCollapsed || includeCollapsedChildren) {
 			children.forEach((child, idx) => {
-				if (seenNotes.has(child.id)) return;
+				if (seenNotes.has(child.id)) return; // notes 配列重複ガード
 				out.push({
 					kind: 'folder-child',
 					key: `c:${child.id}`,
@@ -164,6 +127,46 @@ export function flattenNoteList(
 				seenNotes.add(child.id);
 			});
 		}
+		for (const sub of subfoldersByParent.get(folder.id) ?? []) {
+			if (seenFolders.has(sub.id)) continue;
+			if (isCollapsed) {
+				// 折りたたみ中は配下のフォルダごと隠す（末尾のフォールバックにも出さない）
+				markSubtreeSeen(sub.id, subfoldersByParent, seenFolders);
+				continue;
+			}
+			pushFolder(sub, depth + 1);
+		}
+	};
+
+	for (const item of order) {
+		if (item.type === 'note') {
+			if (seenNotes.has(item.id)) continue; // order 重複ガード
+			const note = notesById.get(item.id);
+			if (!note || (note.archived ?? false) !== archived) continue;
+			if (note.folderId) continue; // フォルダ配下のノートは folder-child として後で出す
+			out.push({
+				kind: 'topLevel-note',
+				key: `t:${note.id}`,
+				id: note.id,
+				note,
+			});
+			seenNotes.add(note.id);
+		} else if (item.type === 'folder') {
+			if (seenFolders.has(item.id)) continue; // order 重複ガード
+			const folder = folderById.get(item.id);
+			if (!folder || (folder.archived ?? false) !== archived) continue;
+			if (!isFolderRootInView(folder, folderById, archived)) continue; // 親の下に出す
+			pushFolder(folder, 0);
+		}
+	}
+
+	// topLevelOrder に現れていない要素のフォールバック（データ破損保険）。
+	// 過度に挿入すると DnD で順序が壊れるので末尾に最小限追加する。
+	for (const folder of list.folders) {
+		if ((folder.archived ?? false) !== archived) continue;
+		if (seenFolders.has(folder.id)) continue;
+		if (!isFolderRootInView(folder, folderById, archived)) continue;
+		pushFolder(folder, 0);
 	}
 	for (const n of list.notes) {
 		if ((n.archived ?? false) !== archived) continue;
@@ -182,6 +185,42 @@ export function flattenNoteList(
 	return out;
 }
 
+/**
+ * フォルダがこのビューのルート（見出しを depth 0 で出す）かを判定する。
+ * 親が無い・別ビュー（アーカイブ状態が違う）・親をたどると循環する場合はルート扱い。
+ * デスクトップ版 folder_tree.go の isFolderOrderRoot と normalizeFolderTree に相当。
+ */
+function isFolderRootInView(
+	folder: Folder,
+	folderById: Map<string, Folder>,
+	archived: boolean,
+): boolean {
+	const visited = new Set<string>([folder.id]);
+	let current = folder;
+	while (current.parentId) {
+		const parent = folderById.get(current.parentId);
+		if (!parent || (parent.archived ?? false) !== archived) {
+			return current === folder;
+		}
+		if (visited.has(parent.id)) return true;
+		visited.add(parent.id);
+		current = parent;
+	}
+	return current === folder;
+}
+
+function markSubtreeSeen(
+	folderId: string,
+	subfoldersByParent: Map<string, Folder[]>,
+	seen: Set<string>,
+) {
+	if (seen.has(folderId)) return;
+	seen.add(folderId);
+	for (const sub of subfoldersByParent.get(folderId) ?? []) {
+		markSubtreeSeen(sub.id, subfoldersByParent, seen);
+	}
+}
+
 /**
  * DnD 後の新しい行配列を NoteList へ反映する。
  *
@@ -193,7 +232,8 @@ export function flattenNoteList(
  *   展開済み folder-header** で決まる。無ければ top-level。
  * - 折り畳み中 folder-header は body 無いので folder context に入らない (= 直下の
  *   ノートは top-level 扱い)。
- * - フォルダ自体はネストしない (folder-header は常に top-level)。
+ * - フォルダの見出しは depth 0 のものだけ topLevelOrder に並べる (入れ子のフォルダは
+ *   親の中に並ぶため)。
  *
  * @param opts.movedIndex drax の onReorder が返す toIndex。指定しない場合は
  *   どのアイテムも再分類しない (= 単なる順序変更扱い)。
@@ -266,7 +306,8 @@ export function applyReorder(
 	for (let i = 0; i < newRows.length; i++) {
 		const row = newRows[i];
 		if (row.kind === 'folder-header') {
-			newTopLevelOrder.push({ type: 'folder', id: row.id });
+			// 入れ子のフォルダは親の中に並ぶので topLevelOrder には載せない
+			if (row.depth === 0) newTopLevelOrder.push({ type: 'folder', id: row.id });
 			continue;
 		}
 		// note 系 (topLevel-note / folder-child)
@@ -339,7 +380,10 @@ export function applyDropIntent(
 	if (!movedRow) return { list, movedNoteIds: [] };
 
 	if (movedRow.kind === 'folder-header') {
-		if (intent.type !== 'top-level') return { list, movedNoteIds: [] };
+		// 入れ子のフォルダの移動はデスクトップ版で行う（モバイルはルートの並べ替えのみ）
+		if (intent.type !== 'top-level' || movedRow.depth > 0) {
+			return { list, movedNoteIds: [] };
+		}
 		list[orderField] = insertTopLevelItem(
 			list[orderField],
 			'folder',
@@ -1,4 +1,8 @@
-import type { NoteList, TopLevelItem } from '@/services/sync/types';
+import type {
+	NoteList,
+	TopLevelItem,
+	TrashItem,
+} from '@/services/sync/types';
 
 /**
  * NoteList を deep clone する。注意点:
@@ -7,6 +11,7 @@ import type { NoteList, TopLevelItem } from '@/services/sync/types';
  *   過去ロジックの残滓で重複が混入した場合に、UI 描画 (key 重複) や DnD ロジックが
  *   壊れないようにする防御層。
  * - topLevelOrder / archivedTopLevelOrder も `(type, id)` の組で重複除去する。
+ * - trash は旧形式の noteList（キー無し）でも空配列にする。
  */
 export function cloneNoteList(list: NoteList): NoteList {
 	const seenNotes = new Set<string>();
@@ -34,9 +39,19 @@ export function cloneNoteList(list: NoteList): NoteList {
 		topLevelOrder: dedupOrder(list.topLevelOrder),
 		archivedTopLevelOrder: dedupOrder(list.archivedTopLevelOrder),
 		collapsedFolderIds: [...list.collapsedFolderIds],
+		trash: cloneTrash(list.trash ?? []),
 	};
 }
 
+/** ゴミ箱のアイテムを deep clone する（中の notes / folders も複製する）。 */
+export function cloneTrash(trash: TrashItem[]): TrashItem[] {
+	return trash.map((item) => ({
+		...item,
+		...(item.notes ? { notes: item.notes.map((n) => ({ ...n })) } : {}),
+		...(item.folders ? { folders: item.folders.map((f) => ({ ...f })) } : {}),
+	}));
+}
+
 function dedupOrder(order: TopLevelItem[]): TopLevelItem[] {
 	const seen = new Set<string>();
 	const out: TopLevelItem[] = [];